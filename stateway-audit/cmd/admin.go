@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"os/signal"
+	"syscall"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-audit/entry/server"
+	"github.com/urfave/cli/v2"
+)
+
+var adminCMD = cli.Command{
+	Name:  "admin",
+	Usage: "Manage admin tasks.",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "debug",
+			Usage: "Enable debug logging.",
+		},
+	},
+	Subcommands: []*cli.Command{
+		{
+			Name:  "purge-app",
+			Usage: "Delete the entity states of an app, e.g. after it has been deleted while the audit server was down.",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "app",
+					Usage:    "The ID of the app to purge.",
+					Required: true,
+				},
+				&cli.BoolFlag{
+					Name:  "configs",
+					Usage: "Also delete the audit configs of the app, only do this once the app has been deleted.",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				ctx, cancel := signal.NotifyContext(c.Context, syscall.SIGINT, syscall.SIGTERM)
+				defer cancel()
+
+				env, err := setupEnv(ctx, c.Bool("debug"))
+				if err != nil {
+					return fmt.Errorf("failed to setup environment: %w", err)
+				}
+
+				appID, err := snowflake.Parse(c.String("app"))
+				if err != nil {
+					return fmt.Errorf("failed to parse app ID: %w", err)
+				}
+
+				err = server.PurgeApp(ctx, env.pg, env.pg, appID, c.Bool("configs"))
+				if err != nil {
+					return fmt.Errorf("failed to purge app: %w", err)
+				}
+				return nil
+			},
+		},
+	},
+}
@@ -46,6 +46,7 @@ var CLI = cli.App{
 			},
 		},
 		&databaseCMD,
+		&adminCMD,
 	},
 }
 
@@ -11,6 +11,18 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const deleteAppAuditConfigs = `-- name: DeleteAppAuditConfigs :execrows
+DELETE FROM audit.config WHERE app_id = $1
+`
+
+func (q *Queries) DeleteAppAuditConfigs(ctx context.Context, appID int64) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteAppAuditConfigs, appID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getAuditConfig = `-- name: GetAuditConfig :one
 SELECT app_id, guild_id, enabled, created_at, updated_at FROM audit.config WHERE app_id = $1 AND guild_id = $2
 `
@@ -11,6 +11,25 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const deleteAppEntityStates = `-- name: DeleteAppEntityStates :execrows
+DELETE FROM audit.entity_states WHERE (app_id, guild_id, entity_type, entity_id) IN (
+    SELECT app_id, guild_id, entity_type, entity_id FROM audit.entity_states WHERE app_id = $1 LIMIT $2
+)
+`
+
+type DeleteAppEntityStatesParams struct {
+	AppID     int64
+	BatchSize int32
+}
+
+func (q *Queries) DeleteAppEntityStates(ctx context.Context, arg DeleteAppEntityStatesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteAppEntityStates, arg.AppID, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getEntityState = `-- name: GetEntityState :one
 SELECT app_id, guild_id, entity_type, entity_id, data, deleted, created_at, updated_at FROM audit.entity_states WHERE app_id = $1 AND guild_id = $2 AND entity_type = $3 AND entity_id = $4
 `
@@ -6,3 +6,6 @@ DO UPDATE SET enabled = $3, updated_at = $5;
 
 -- name: GetAuditConfig :one
 SELECT * FROM audit.config WHERE app_id = $1 AND guild_id = $2;
+
+-- name: DeleteAppAuditConfigs :execrows
+DELETE FROM audit.config WHERE app_id = $1;
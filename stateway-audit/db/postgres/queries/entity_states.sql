@@ -8,3 +8,8 @@ ON CONFLICT (app_id, guild_id, entity_type, entity_id) DO UPDATE SET
 
 -- name: GetEntityState :one
 SELECT * FROM audit.entity_states WHERE app_id = $1 AND guild_id = $2 AND entity_type = $3 AND entity_id = $4;
+
+-- name: DeleteAppEntityStates :execrows
+DELETE FROM audit.entity_states WHERE (app_id, guild_id, entity_type, entity_id) IN (
+    SELECT app_id, guild_id, entity_type, entity_id FROM audit.entity_states WHERE app_id = $1 LIMIT @batch_size
+);
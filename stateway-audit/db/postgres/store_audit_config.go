@@ -30,6 +30,14 @@ func (c *Client) UpsertAuditConfig(ctx context.Context, auditConfig model.AuditC
 	})
 }
 
+func (c *Client) DeleteAppAuditConfigs(ctx context.Context, appID snowflake.ID) (int, error) {
+	n, err := c.Q.DeleteAppAuditConfigs(ctx, int64(appID))
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
 func rowToAuditConfig(row pgmodel.AuditConfig) (*model.AuditConfig, error) {
 	return &model.AuditConfig{
 		AppID:     snowflake.ID(row.AppID),
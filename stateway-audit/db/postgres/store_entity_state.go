@@ -52,6 +52,27 @@ func (c *Client) UpsertEntityState(ctx context.Context, entityState model.Entity
 	return err
 }
 
+// deleteAppBatchSize is the number of entity states deleted per statement when purging an app.
+// Deleting in batches keeps the locks short, so the other apps can still be audited while an app is purged.
+const deleteAppBatchSize = 10_000
+
+func (c *Client) DeleteAppEntityStates(ctx context.Context, appID snowflake.ID) (int, error) {
+	total := 0
+	for {
+		n, err := c.Q.DeleteAppEntityStates(ctx, pgmodel.DeleteAppEntityStatesParams{
+			AppID:     int64(appID),
+			BatchSize: deleteAppBatchSize,
+		})
+		if err != nil {
+			return total, err
+		}
+		total += int(n)
+		if n < deleteAppBatchSize {
+			return total, nil
+		}
+	}
+}
+
 func rowToEntityState(row pgmodel.AuditEntityState) (*model.EntityState, error) {
 	return &model.EntityState{
 		AppID:      snowflake.ID(row.AppID),
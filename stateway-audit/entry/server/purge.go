@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-audit/store"
+)
+
+// PurgeApp deletes the entity states of the app and logs how many have been deleted.
+// The audit configs are settings chosen for the guilds, they are only deleted with deleteConfigs, e.g. once the app has been deleted.
+// The recorded entity changes are kept, they are the audit history of the app.
+func PurgeApp(
+	ctx context.Context,
+	entityStateStore store.EntityStateStore,
+	auditConfigStore store.AuditConfigStore,
+	appID snowflake.ID,
+	deleteConfigs bool,
+) error {
+	entityStates, err := entityStateStore.DeleteAppEntityStates(ctx, appID)
+	if err != nil {
+		return fmt.Errorf("failed to delete app entity states: %w", err)
+	}
+
+	var auditConfigs int
+	if deleteConfigs {
+		auditConfigs, err = auditConfigStore.DeleteAppAuditConfigs(ctx, appID)
+		if err != nil {
+			return fmt.Errorf("failed to delete app audit configs: %w", err)
+		}
+	}
+
+	slog.Info(
+		"Purged audit data of app",
+		slog.String("app_id", appID.String()),
+		slog.Int("entity_states", entityStates),
+		slog.Int("audit_configs", auditConfigs),
+	)
+	return nil
+}
@@ -49,6 +49,7 @@ func Run(ctx context.Context, pg *postgres.Client, ch *clickhouse.Client, cfg *c
 	err = broker.Listen(ctx, br, NewAuditWorker(
 		auditLogMatcher,
 		pg,
+		pg,
 		batcher,
 		AuditWorkerConfig{
 			GatewayIDs: cfg.Audit.GatewayIDs,
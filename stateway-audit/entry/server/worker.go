@@ -29,6 +29,7 @@ type AuditWorkerConfig struct {
 
 type AuditWorker struct {
 	entityStateStore store.EntityStateStore
+	auditConfigStore store.AuditConfigStore
 	batcher          batcher.Batcher
 	auditLogMatcher  *AuditLogMatcher
 
@@ -38,11 +39,13 @@ type AuditWorker struct {
 func NewAuditWorker(
 	auditLogMatcher *AuditLogMatcher,
 	entityStateStore store.EntityStateStore,
+	auditConfigStore store.AuditConfigStore,
 	batcher batcher.Batcher,
 	config AuditWorkerConfig,
 ) *AuditWorker {
 	return &AuditWorker{
 		entityStateStore: entityStateStore,
+		auditConfigStore: auditConfigStore,
 		batcher:          batcher,
 		auditLogMatcher:  auditLogMatcher,
 		config:           config,
@@ -76,6 +79,8 @@ func (l *AuditWorker) EventFilter() broker.EventFilter {
 			"guild.role.delete",
 			"invite.create",
 			"invite.delete",
+			"app.deleted",
+			"app.disabled",
 		},
 	}
 }
@@ -91,6 +96,14 @@ func (l *AuditWorker) ConsumerConfig() broker.ConsumerConfig {
 func (l *AuditWorker) HandleEvent(ctx context.Context, event *event.GatewayEvent) (bool, error) {
 	slog.Debug("Received event:", slog.String("type", event.Type))
 
+	if isAppPurgeEvent(event.Type) {
+		err := PurgeApp(ctx, l.entityStateStore, l.auditConfigStore, event.AppID, isAppDeletedEvent(event.Type))
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
 	data, err := gateway.UnmarshalEventData(event.Data, gateway.EventType(event.Type))
 	if err != nil {
 		return false, fmt.Errorf("failed to unmarshal event data: %w", err)
@@ -533,6 +546,18 @@ func (l *AuditWorker) handleEntityChange(
 	return nil
 }
 
+// isAppPurgeEvent reports whether the event announces that the app has been deleted or disabled.
+// Its entities won't be audited anymore, so their states are purged.
+func isAppPurgeEvent(eventType string) bool {
+	return eventType == event.GatewayEventTypeAppDeleted || eventType == event.GatewayEventTypeAppDisabled
+}
+
+// isAppDeletedEvent reports whether the event announces that the app has been deleted.
+// Disabled apps can be enabled again, so their audit configs are only purged once the app is deleted.
+func isAppDeletedEvent(eventType string) bool {
+	return eventType == event.GatewayEventTypeAppDeleted
+}
+
 func formatDiffPath(path jd.Path) (string, error) {
 	formattedPath := ""
 	for _, element := range path {
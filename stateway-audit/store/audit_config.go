@@ -10,4 +10,6 @@ import (
 type AuditConfigStore interface {
 	GetAuditConfig(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (*model.AuditConfig, error)
 	UpsertAuditConfig(ctx context.Context, auditConfig model.AuditConfig) error
+	// DeleteAppAuditConfigs deletes the audit configs of all guilds of the app and returns how many were deleted.
+	DeleteAppAuditConfigs(ctx context.Context, appID snowflake.ID) (int, error)
 }
@@ -11,4 +11,6 @@ import (
 type EntityStateStore interface {
 	GetEntityState(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, entityType audit.EntityType, entityID snowflake.ID) (*model.EntityState, error)
 	UpsertEntityState(ctx context.Context, entityState model.EntityState) error
+	// DeleteAppEntityStates deletes the entity states of all guilds of the app and returns how many were deleted.
+	DeleteAppEntityStates(ctx context.Context, appID snowflake.ID) (int, error)
 }
@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/entry/admin"
+	"github.com/merlinfuchs/stateway/stateway-cache/entry/server"
+	"github.com/merlinfuchs/stateway/stateway-lib/broker"
+	"github.com/merlinfuchs/stateway/stateway-lib/cache"
+	"github.com/urfave/cli/v2"
+)
+
+var adminCMD = cli.Command{
+	Name:  "admin",
+	Usage: "Manage admin tasks.",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "debug",
+			Usage: "Enable debug logging.",
+		},
+	},
+	Subcommands: []*cli.Command{
+		{
+			Name:  "stats",
+			Usage: "Show the stats of the cached entities, as reported by the running cache server.",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "app-id",
+					Usage: "The ID of the app to show the stats of. Leave empty to show the stats of all apps.",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				ctx, cancel := signal.NotifyContext(c.Context, syscall.SIGINT, syscall.SIGTERM)
+				defer cancel()
+
+				env, err := setupEnv(ctx, c.Bool("debug"))
+				if err != nil {
+					return fmt.Errorf("failed to setup environment: %w", err)
+				}
+
+				var appID snowflake.ID
+				if c.IsSet("app-id") {
+					appID, err = snowflake.Parse(c.String("app-id"))
+					if err != nil {
+						return fmt.Errorf("failed to parse app ID: %w", err)
+					}
+				}
+
+				br, err := broker.NewNATSBroker(env.cfg.Broker.NATS.URL)
+				if err != nil {
+					return fmt.Errorf("failed to create NATS broker: %w", err)
+				}
+
+				caches := cache.NewCacheClient(br, cache.WithPartitionCount(env.cfg.Cache.PartitionCount))
+				err = admin.PrintStats(ctx, caches, appID)
+				if err != nil {
+					return fmt.Errorf("failed to print cache stats: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Name:  "resync",
+			Usage: "Refetch the guilds of an app from Discord and reconcile the cache with them.",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "app",
+					Usage:    "The ID of the app to resync.",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:  "guild",
+					Usage: "The ID of the guild to resync. Leave empty to resync all guilds of the app.",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				ctx, cancel := signal.NotifyContext(c.Context, syscall.SIGINT, syscall.SIGTERM)
+				defer cancel()
+
+				env, err := setupEnv(ctx, c.Bool("debug"))
+				if err != nil {
+					return fmt.Errorf("failed to setup environment: %w", err)
+				}
+
+				appID, err := snowflake.Parse(c.String("app"))
+				if err != nil {
+					return fmt.Errorf("failed to parse app ID: %w", err)
+				}
+
+				var guildID snowflake.ID
+				if c.IsSet("guild") {
+					guildID, err = snowflake.Parse(c.String("guild"))
+					if err != nil {
+						return fmt.Errorf("failed to parse guild ID: %w", err)
+					}
+				}
+
+				br, err := broker.NewNATSBroker(env.cfg.Broker.NATS.URL)
+				if err != nil {
+					return fmt.Errorf("failed to create NATS broker: %w", err)
+				}
+
+				caches := cache.NewCacheClient(br, cache.WithPartitionCount(env.cfg.Cache.PartitionCount))
+				err = admin.Resync(ctx, caches, appID, guildID)
+				if err != nil {
+					return fmt.Errorf("failed to resync cache: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Name:  "purge-app",
+			Usage: "Delete all cached entities of an app, e.g. after it has been deleted while the cache server was down.",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "app",
+					Usage:    "The ID of the app to purge.",
+					Required: true,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				ctx, cancel := signal.NotifyContext(c.Context, syscall.SIGINT, syscall.SIGTERM)
+				defer cancel()
+
+				env, err := setupEnv(ctx, c.Bool("debug"))
+				if err != nil {
+					return fmt.Errorf("failed to setup environment: %w", err)
+				}
+
+				appID, err := snowflake.Parse(c.String("app"))
+				if err != nil {
+					return fmt.Errorf("failed to parse app ID: %w", err)
+				}
+
+				br, err := broker.NewNATSBroker(env.cfg.Broker.NATS.URL)
+				if err != nil {
+					return fmt.Errorf("failed to create NATS broker: %w", err)
+				}
+
+				caches := cache.NewCacheClient(br, cache.WithPartitionCount(env.cfg.Cache.PartitionCount))
+				err = admin.PurgeApp(ctx, caches, appID)
+				if err != nil {
+					return fmt.Errorf("failed to purge app: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Name:  "export",
+			Usage: "Export the cached entities of an app as JSONL, one record per entity.",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "app",
+					Usage:    "The ID of the app to export.",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:  "guild",
+					Usage: "The ID of the guild to export. Leave empty to export all guilds of the app.",
+				},
+				&cli.StringFlag{
+					Name:  "output",
+					Usage: "The file to write the records to. Leave empty to write them to stdout.",
+				},
+				&cli.StringFlag{
+					Name:  "store",
+					Usage: "The cache store to use instead of the configured one (postgres, redis, bolt, map or memdb).",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				ctx, cancel := signal.NotifyContext(c.Context, syscall.SIGINT, syscall.SIGTERM)
+				defer cancel()
+
+				env, err := setupEnv(ctx, c.Bool("debug"))
+				if err != nil {
+					return fmt.Errorf("failed to setup environment: %w", err)
+				}
+
+				appID, err := snowflake.Parse(c.String("app"))
+				if err != nil {
+					return fmt.Errorf("failed to parse app ID: %w", err)
+				}
+
+				var guildID snowflake.ID
+				if c.IsSet("guild") {
+					guildID, err = snowflake.Parse(c.String("guild"))
+					if err != nil {
+						return fmt.Errorf("failed to parse guild ID: %w", err)
+					}
+				}
+
+				if c.IsSet("store") {
+					env.cfg.Cache.Store = c.String("store")
+					env.cfg.Cache.InMemory = false
+				}
+
+				cacheStore, closeStore, err := server.OpenCacheStore(ctx, env.pg, &env.cfg.Cache, &env.cfg.Database)
+				if err != nil {
+					return fmt.Errorf("failed to open cache store: %w", err)
+				}
+				defer closeStore()
+
+				out := os.Stdout
+				if c.IsSet("output") {
+					out, err = os.Create(c.String("output"))
+					if err != nil {
+						return fmt.Errorf("failed to create output file: %w", err)
+					}
+					defer out.Close()
+				}
+
+				err = admin.Export(ctx, cacheStore, env.cfg.Cache.SnapshotPath, out, appID, guildID)
+				if err != nil {
+					return fmt.Errorf("failed to export cache: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Name:  "import",
+			Usage: "Import JSONL records created by export into the cache store, in-memory stores can only be imported while the cache server is stopped.",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "input",
+					Usage:    "The file to read the records from.",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:  "store",
+					Usage: "The cache store to use instead of the configured one (postgres, redis, bolt, map or memdb).",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				ctx, cancel := signal.NotifyContext(c.Context, syscall.SIGINT, syscall.SIGTERM)
+				defer cancel()
+
+				env, err := setupEnv(ctx, c.Bool("debug"))
+				if err != nil {
+					return fmt.Errorf("failed to setup environment: %w", err)
+				}
+
+				if c.IsSet("store") {
+					env.cfg.Cache.Store = c.String("store")
+					env.cfg.Cache.InMemory = false
+				}
+
+				cacheStore, closeStore, err := server.OpenCacheStore(ctx, env.pg, &env.cfg.Cache, &env.cfg.Database)
+				if err != nil {
+					return fmt.Errorf("failed to open cache store: %w", err)
+				}
+				defer closeStore()
+
+				in, err := os.Open(c.String("input"))
+				if err != nil {
+					return fmt.Errorf("failed to open input file: %w", err)
+				}
+				defer in.Close()
+
+				err = admin.Import(ctx, cacheStore, env.cfg.Cache.SnapshotPath, in)
+				if err != nil {
+					return fmt.Errorf("failed to import cache: %w", err)
+				}
+				return nil
+			},
+		},
+	},
+}
@@ -53,6 +53,7 @@ var CLI = cli.App{
 			},
 		},
 		&databaseCMD,
+		&adminCMD,
 	},
 }
 
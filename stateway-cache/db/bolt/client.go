@@ -0,0 +1,117 @@
+package bolt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"go.etcd.io/bbolt"
+)
+
+var (
+	guildsBucket       = []byte("guilds")
+	rolesBucket        = []byte("roles")
+	rolesByIDBucket    = []byte("roles_by_id")
+	channelsBucket     = []byte("channels")
+	channelsByIDBucket = []byte("channels_by_id")
+	emojisBucket       = []byte("emojis")
+	emojisByIDBucket   = []byte("emojis_by_id")
+	stickersBucket     = []byte("stickers")
+	stickersByIDBucket = []byte("stickers_by_id")
+	// The collection buckets map app_id+guild_id to when the entities of the guild have last been replaced
+	emojiCollectionsBucket   = []byte("emoji_collections")
+	stickerCollectionsBucket = []byte("sticker_collections")
+	allBuckets               = [][]byte{
+		guildsBucket,
+		rolesBucket,
+		rolesByIDBucket,
+		channelsBucket,
+		channelsByIDBucket,
+		emojisBucket,
+		emojisByIDBucket,
+		stickersBucket,
+		stickersByIDBucket,
+		emojiCollectionsBucket,
+		stickerCollectionsBucket,
+	}
+)
+
+// Client is a cache store backed by an embedded bbolt database file.
+//
+// Entities are stored as JSON in one bucket per entity type. Keys are big-endian encoded IDs:
+// app_id+guild_id for guilds and app_id+guild_id+entity_id for guild entities, so guild-wide
+// listings are prefix scans. A second bucket per entity type maps app_id+entity_id to the
+// guild_id, like the (app_id, channel_id) indexes of the Postgres tables.
+type Client struct {
+	db *bbolt.DB
+}
+
+type ClientConfig struct {
+	Path string
+}
+
+func New(config ClientConfig) (*Client, error) {
+	db, err := bbolt.Open(config.Path, 0o600, &bbolt.Options{
+		Timeout: 5 * time.Second,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt db: %w", err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, bucket := range allBuckets {
+			_, err := tx.CreateBucketIfNotExists(bucket)
+			if err != nil {
+				return fmt.Errorf("failed to create bucket %s: %w", bucket, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Client{
+		db: db,
+	}, nil
+}
+
+func (c *Client) Close() error {
+	return c.db.Close()
+}
+
+func appKey(appID snowflake.ID) []byte {
+	return binary.BigEndian.AppendUint64(make([]byte, 0, 8), uint64(appID))
+}
+
+func guildKey(appID snowflake.ID, guildID snowflake.ID) []byte {
+	key := make([]byte, 0, 16)
+	key = binary.BigEndian.AppendUint64(key, uint64(appID))
+	return binary.BigEndian.AppendUint64(key, uint64(guildID))
+}
+
+func entityKey(appID snowflake.ID, guildID snowflake.ID, entityID snowflake.ID) []byte {
+	key := make([]byte, 0, 24)
+	key = binary.BigEndian.AppendUint64(key, uint64(appID))
+	key = binary.BigEndian.AppendUint64(key, uint64(guildID))
+	return binary.BigEndian.AppendUint64(key, uint64(entityID))
+}
+
+func idKey(appID snowflake.ID, entityID snowflake.ID) []byte {
+	return guildKey(appID, entityID)
+}
+
+func encodeTime(t time.Time) []byte {
+	return binary.BigEndian.AppendUint64(make([]byte, 0, 8), uint64(t.UnixNano()))
+}
+
+func decodeTime(b []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(b))).UTC()
+}
+
+// decodeID decodes the ID at the given byte offset of a key or value.
+func decodeID(b []byte, offset int) snowflake.ID {
+	return snowflake.ID(binary.BigEndian.Uint64(b[offset : offset+8]))
+}
@@ -0,0 +1,588 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"go.etcd.io/bbolt"
+)
+
+var _ store.CacheStore = (*Client)(nil)
+
+func (c *Client) MarkShardEntitiesTainted(ctx context.Context, params store.MarkShardEntitiesTaintedParams) error {
+	return c.db.Update(func(tx *bbolt.Tx) error {
+		inShard := params.InShard
+
+		if err := guildTable.markTainted(tx, params.AppID, inShard); err != nil {
+			return err
+		}
+		if err := roleTable.markTainted(tx, params.AppID, inShard); err != nil {
+			return err
+		}
+		if err := channelTable.markTainted(tx, params.AppID, inShard); err != nil {
+			return err
+		}
+		if err := emojiTable.markTainted(tx, params.AppID, inShard); err != nil {
+			return err
+		}
+		return stickerTable.markTainted(tx, params.AppID, inShard)
+	})
+}
+
+func (c *Client) DeleteShardTaintedEntities(ctx context.Context, params store.DeleteShardTaintedEntitiesParams) error {
+	keep := make(map[snowflake.ID]struct{}, len(params.KeepGuildIDs))
+	for _, guildID := range params.KeepGuildIDs {
+		keep[guildID] = struct{}{}
+	}
+
+	return c.db.Update(func(tx *bbolt.Tx) error {
+		removedGuildIDs := make(map[snowflake.ID]struct{})
+
+		covers := func(guildID snowflake.ID) bool {
+			if !params.InShard(guildID) {
+				return false
+			}
+			_, ok := keep[guildID]
+			return !ok
+		}
+
+		err := guildTable.deleteWhere(tx, params.AppID, func(guildID snowflake.ID, tainted bool) bool {
+			if tainted && covers(guildID) {
+				removedGuildIDs[guildID] = struct{}{}
+				return true
+			}
+			return false
+		})
+		if err != nil {
+			return err
+		}
+
+		shouldDelete := func(guildID snowflake.ID, tainted bool) bool {
+			if !covers(guildID) {
+				return false
+			}
+			_, removed := removedGuildIDs[guildID]
+			return tainted || removed
+		}
+
+		if err := roleTable.deleteWhere(tx, params.AppID, shouldDelete); err != nil {
+			return err
+		}
+		if err := channelTable.deleteWhere(tx, params.AppID, shouldDelete); err != nil {
+			return err
+		}
+		if err := emojiTable.deleteWhere(tx, params.AppID, shouldDelete); err != nil {
+			return err
+		}
+		return stickerTable.deleteWhere(tx, params.AppID, shouldDelete)
+	})
+}
+
+func (c *Client) MassUpsertEntities(ctx context.Context, params store.MassUpsertEntitiesParams) error {
+	return c.db.Update(func(tx *bbolt.Tx) error {
+		for _, guild := range params.Guilds {
+			if _, err := guildTable.upsert(tx, guildFromParams(guild)); err != nil {
+				return err
+			}
+		}
+		for _, role := range params.Roles {
+			if _, err := roleTable.upsert(tx, roleFromParams(role)); err != nil {
+				return err
+			}
+		}
+		for _, channel := range params.Channels {
+			if _, err := channelTable.upsert(tx, channelFromParams(channel)); err != nil {
+				return err
+			}
+		}
+		for _, emoji := range params.Emojis {
+			if _, err := emojiTable.upsert(tx, emojiFromParams(emoji)); err != nil {
+				return err
+			}
+		}
+		for _, sticker := range params.Stickers {
+			if _, err := stickerTable.upsert(tx, stickerFromParams(sticker)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (c *Client) DeleteAppEntities(ctx context.Context, appID snowflake.ID) (store.DeletedEntities, error) {
+	var deleted store.DeletedEntities
+	err := c.db.Update(func(tx *bbolt.Tx) error {
+		var err error
+		if deleted.Guilds, err = guildTable.deleteApp(tx, appID); err != nil {
+			return err
+		}
+		if deleted.Roles, err = roleTable.deleteApp(tx, appID); err != nil {
+			return err
+		}
+		if deleted.Channels, err = channelTable.deleteApp(tx, appID); err != nil {
+			return err
+		}
+		if deleted.Emojis, err = emojiTable.deleteApp(tx, appID); err != nil {
+			return err
+		}
+		deleted.Stickers, err = stickerTable.deleteApp(tx, appID)
+		return err
+	})
+	if err != nil {
+		return store.DeletedEntities{}, err
+	}
+	return deleted, nil
+}
+
+func (c *Client) GetCacheStats(ctx context.Context, params store.GetCacheStatsParams) ([]*model.CacheStats, error) {
+	collector := store.NewStatsCollector()
+
+	var prefix []byte
+	if params.AppID != 0 {
+		prefix = appKey(params.AppID)
+		collector.App(params.AppID)
+	}
+
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		err := guildTable.collectStats(tx, prefix, collector, func(stats *model.CacheStats) *model.EntityStats {
+			return &stats.Guilds
+		})
+		if err != nil {
+			return err
+		}
+		err = roleTable.collectStats(tx, prefix, collector, func(stats *model.CacheStats) *model.EntityStats {
+			return &stats.Roles
+		})
+		if err != nil {
+			return err
+		}
+		err = channelTable.collectStats(tx, prefix, collector, func(stats *model.CacheStats) *model.EntityStats {
+			return &stats.Channels
+		})
+		if err != nil {
+			return err
+		}
+		err = emojiTable.collectStats(tx, prefix, collector, func(stats *model.CacheStats) *model.EntityStats {
+			return &stats.Emojis
+		})
+		if err != nil {
+			return err
+		}
+		return stickerTable.collectStats(tx, prefix, collector, func(stats *model.CacheStats) *model.EntityStats {
+			return &stats.Stickers
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return collector.Stats(), nil
+}
+
+// entityTable describes how an entity type is laid out in its buckets.
+// Guilds have no index bucket because they are already keyed by app_id+guild_id.
+type entityTable[T any] struct {
+	name        string
+	bucket      []byte
+	indexBucket []byte
+	ids         func(*T) (appID snowflake.ID, guildID snowflake.ID, entityID snowflake.ID)
+	tainted     func(*T) *bool
+	updatedAt   func(*T) time.Time
+	data        func(*T) any
+
+	// collectionBucket is set for entity types whose guild entities are replaced as a whole, see replaceGuild
+	collectionBucket []byte
+}
+
+func (t *entityTable[T]) key(appID snowflake.ID, guildID snowflake.ID, entityID snowflake.ID) []byte {
+	if t.indexBucket == nil {
+		return guildKey(appID, guildID)
+	}
+	return entityKey(appID, guildID, entityID)
+}
+
+func (t *entityTable[T]) decode(value []byte) (*T, error) {
+	var entity T
+	err := json.Unmarshal(value, &entity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", t.name, err)
+	}
+	return &entity, nil
+}
+
+func (t *entityTable[T]) get(tx *bbolt.Tx, appID snowflake.ID, guildID snowflake.ID, entityID snowflake.ID) (*T, error) {
+	value := tx.Bucket(t.bucket).Get(t.key(appID, guildID, entityID))
+	if value == nil {
+		return nil, store.ErrNotFound
+	}
+	return t.decode(value)
+}
+
+// getByID looks up the guild of the entity in the index bucket and loads the entity.
+func (t *entityTable[T]) getByID(tx *bbolt.Tx, appID snowflake.ID, entityID snowflake.ID) (*T, error) {
+	if t.indexBucket == nil {
+		return t.get(tx, appID, entityID, entityID)
+	}
+
+	value := tx.Bucket(t.indexBucket).Get(idKey(appID, entityID))
+	if value == nil {
+		return nil, store.ErrNotFound
+	}
+	return t.get(tx, appID, decodeID(value, 0), entityID)
+}
+
+// getByIDs loads the entities that exist and skips the missing ones.
+func (t *entityTable[T]) getByIDs(tx *bbolt.Tx, appID snowflake.ID, entityIDs []snowflake.ID) ([]*T, error) {
+	entities := make([]*T, 0, len(entityIDs))
+	for _, entityID := range entityIDs {
+		entity, err := t.getByID(tx, appID, entityID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		entities = append(entities, entity)
+	}
+	return entities, nil
+}
+
+// list returns one page of entities whose primary key starts with the prefix.
+func (t *entityTable[T]) list(tx *bbolt.Tx, prefix []byte, opts store.ListOptions) ([]*T, error) {
+	return t.listFunc(tx, prefix, opts, nil)
+}
+
+// search returns one page of entities of the prefix that match the data and filter of a search.
+func (t *entityTable[T]) search(tx *bbolt.Tx, prefix []byte, data json.RawMessage, filter *model.Filter, opts store.ListOptions) ([]*T, error) {
+	entities, err := t.list(tx, prefix, store.ListOptions{ExcludeTainted: opts.ExcludeTainted, After: opts.After})
+	if err != nil {
+		return nil, err
+	}
+	return store.SearchEntities(entities, data, filter, opts, t.data)
+}
+
+// listFunc is like list but skips entities for which the match function returns false.
+func (t *entityTable[T]) listFunc(tx *bbolt.Tx, prefix []byte, opts store.ListOptions, match func(*T) bool) ([]*T, error) {
+	entities := make([]*T, 0)
+	currentOffset := 0
+
+	err := t.scan(tx, prefix, opts.After, func(key []byte, value []byte) (bool, error) {
+		entity, err := t.decode(value)
+		if err != nil {
+			return false, err
+		}
+
+		if opts.ExcludeTainted && *t.tainted(entity) {
+			return true, nil
+		}
+		if match != nil && !match(entity) {
+			return true, nil
+		}
+
+		if currentOffset < opts.Offset {
+			currentOffset++
+			return true, nil
+		}
+
+		entities = append(entities, entity)
+		return opts.Limit <= 0 || len(entities) < opts.Limit, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
+// scan calls fn for every entity of the prefix with an ID higher than after until fn returns false.
+// App-wide scans of guild entities walk the index bucket so they are ordered by entity ID.
+func (t *entityTable[T]) scan(tx *bbolt.Tx, prefix []byte, after snowflake.ID, fn func(key []byte, value []byte) (bool, error)) error {
+	bucket := tx.Bucket(t.bucket)
+
+	// The entity ID always directly follows the prefix, so the cursor can seek directly past the after ID
+	start := prefix
+	if after != 0 {
+		start = binary.BigEndian.AppendUint64(slices.Clone(prefix), uint64(after)+1)
+	}
+
+	if t.indexBucket == nil || len(prefix) != 8 {
+		cursor := bucket.Cursor()
+		for key, value := cursor.Seek(start); key != nil && bytes.HasPrefix(key, prefix); key, value = cursor.Next() {
+			ok, err := fn(key, value)
+			if err != nil || !ok {
+				return err
+			}
+		}
+		return nil
+	}
+
+	appID := decodeID(prefix, 0)
+	cursor := tx.Bucket(t.indexBucket).Cursor()
+	for key, guildID := cursor.Seek(start); key != nil && bytes.HasPrefix(key, prefix); key, guildID = cursor.Next() {
+		primaryKey := entityKey(appID, decodeID(guildID, 0), decodeID(key, 8))
+		value := bucket.Get(primaryKey)
+		if value == nil {
+			continue
+		}
+
+		ok, err := fn(primaryKey, value)
+		if err != nil || !ok {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *entityTable[T]) count(tx *bbolt.Tx, prefix []byte) int {
+	bucket := tx.Bucket(t.bucket)
+	if t.indexBucket != nil && len(prefix) == 8 {
+		bucket = tx.Bucket(t.indexBucket)
+	}
+
+	count := 0
+	cursor := bucket.Cursor()
+	for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+		count++
+	}
+	return count
+}
+
+// collectStats adds all entities whose primary key starts with the prefix to the stats.
+// Only the fields the stats depend on are decoded, the Discord data is skipped.
+func (t *entityTable[T]) collectStats(
+	tx *bbolt.Tx,
+	prefix []byte,
+	collector *store.StatsCollector,
+	entityStats func(*model.CacheStats) *model.EntityStats,
+) error {
+	cursor := tx.Bucket(t.bucket).Cursor()
+	for key, value := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, value = cursor.Next() {
+		var entity store.StatsEntity
+		err := json.Unmarshal(value, &entity)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal %s: %w", t.name, err)
+		}
+		store.AddEntityStats(entityStats(collector.App(entity.AppID)), entity)
+	}
+	return nil
+}
+
+func (t *entityTable[T]) put(tx *bbolt.Tx, entity *T) error {
+	appID, guildID, entityID := t.ids(entity)
+
+	value, err := json.Marshal(entity)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", t.name, err)
+	}
+
+	err = tx.Bucket(t.bucket).Put(t.key(appID, guildID, entityID), value)
+	if err != nil {
+		return fmt.Errorf("failed to put %s: %w", t.name, err)
+	}
+
+	if t.indexBucket != nil {
+		err = tx.Bucket(t.indexBucket).Put(idKey(appID, entityID), appKey(guildID))
+		if err != nil {
+			return fmt.Errorf("failed to put %s index: %w", t.name, err)
+		}
+	}
+	return nil
+}
+
+// upsert puts the entity unless the stored one has been updated more recently and reports whether it has been written.
+// Events can be redelivered or arrive out of order, so older data must never overwrite newer data.
+func (t *entityTable[T]) upsert(tx *bbolt.Tx, entity *T) (bool, error) {
+	appID, guildID, entityID := t.ids(entity)
+
+	existing, err := t.get(tx, appID, guildID, entityID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return false, err
+	}
+	if existing != nil && t.updatedAt(existing).After(t.updatedAt(entity)) {
+		return false, nil
+	}
+
+	if err := t.put(tx, entity); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (t *entityTable[T]) delete(tx *bbolt.Tx, appID snowflake.ID, guildID snowflake.ID, entityID snowflake.ID) error {
+	err := tx.Bucket(t.bucket).Delete(t.key(appID, guildID, entityID))
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", t.name, err)
+	}
+
+	if t.indexBucket != nil {
+		err = tx.Bucket(t.indexBucket).Delete(idKey(appID, entityID))
+		if err != nil {
+			return fmt.Errorf("failed to delete %s index: %w", t.name, err)
+		}
+	}
+	return nil
+}
+
+// replaceGuild replaces the entities of the guild with the given ones and reports whether they have been replaced.
+// Nothing is replaced when the guild entities have been replaced by a more recent event,
+// and entities that have been updated after updatedAt are neither overwritten nor deleted.
+func (t *entityTable[T]) replaceGuild(tx *bbolt.Tx, appID snowflake.ID, guildID snowflake.ID, updatedAt time.Time, entities []*T) (bool, error) {
+	collections := tx.Bucket(t.collectionBucket)
+	prefix := guildKey(appID, guildID)
+	if value := collections.Get(prefix); value != nil && decodeTime(value).After(updatedAt) {
+		return false, nil
+	}
+	if err := collections.Put(prefix, encodeTime(updatedAt)); err != nil {
+		return false, fmt.Errorf("failed to put %s collection: %w", t.name, err)
+	}
+
+	replaced := make(map[snowflake.ID]bool, len(entities))
+	for _, entity := range entities {
+		_, _, entityID := t.ids(entity)
+		replaced[entityID] = true
+	}
+
+	entityIDs := make([]snowflake.ID, 0)
+	err := t.scan(tx, prefix, 0, func(key []byte, value []byte) (bool, error) {
+		entityID := decodeID(key, 16)
+		if replaced[entityID] {
+			return true, nil
+		}
+
+		entity, err := t.decode(value)
+		if err != nil {
+			return false, err
+		}
+		if !t.updatedAt(entity).After(updatedAt) {
+			entityIDs = append(entityIDs, entityID)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, entityID := range entityIDs {
+		if err := t.delete(tx, appID, guildID, entityID); err != nil {
+			return false, err
+		}
+	}
+
+	for _, entity := range entities {
+		if _, err := t.upsert(tx, entity); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// markTainted flags all entities of the app whose guild matches as tainted.
+// The updates are collected first because bbolt cursors must not be used while the bucket is modified.
+func (t *entityTable[T]) markTainted(tx *bbolt.Tx, appID snowflake.ID, inShard func(guildID snowflake.ID) bool) error {
+	updates := make([]*T, 0)
+	bucket := tx.Bucket(t.bucket)
+	prefix := appKey(appID)
+
+	cursor := bucket.Cursor()
+	for key, value := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, value = cursor.Next() {
+		if !inShard(decodeID(key, 8)) {
+			continue
+		}
+
+		entity, err := t.decode(value)
+		if err != nil {
+			return err
+		}
+		*t.tainted(entity) = true
+		updates = append(updates, entity)
+	}
+
+	for _, entity := range updates {
+		if err := t.put(tx, entity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteWhere deletes all entities of the app for which shouldDelete returns true.
+func (t *entityTable[T]) deleteWhere(tx *bbolt.Tx, appID snowflake.ID, shouldDelete func(guildID snowflake.ID, tainted bool) bool) error {
+	deletes := make([]*T, 0)
+	bucket := tx.Bucket(t.bucket)
+	prefix := appKey(appID)
+
+	cursor := bucket.Cursor()
+	for key, value := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, value = cursor.Next() {
+		entity, err := t.decode(value)
+		if err != nil {
+			return err
+		}
+
+		if shouldDelete(decodeID(key, 8), *t.tainted(entity)) {
+			deletes = append(deletes, entity)
+		}
+	}
+
+	for _, entity := range deletes {
+		appID, guildID, entityID := t.ids(entity)
+		if err := t.delete(tx, appID, guildID, entityID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteApp deletes all entities of the app and returns how many were deleted.
+// All keys of the app share its prefix, so the entities don't have to be decoded.
+func (t *entityTable[T]) deleteApp(tx *bbolt.Tx, appID snowflake.ID) (int, error) {
+	deleted, err := deletePrefix(tx.Bucket(t.bucket), appKey(appID))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete %ss: %w", t.name, err)
+	}
+
+	if t.indexBucket != nil {
+		_, err = deletePrefix(tx.Bucket(t.indexBucket), appKey(appID))
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete %s index: %w", t.name, err)
+		}
+	}
+
+	if t.collectionBucket != nil {
+		_, err = deletePrefix(tx.Bucket(t.collectionBucket), appKey(appID))
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete %s collections: %w", t.name, err)
+		}
+	}
+	return deleted, nil
+}
+
+// deletePrefix deletes all keys of the bucket with the prefix and returns how many were deleted.
+// The keys are collected first because bbolt cursors must not be used while the bucket is modified.
+func deletePrefix(bucket *bbolt.Bucket, prefix []byte) (int, error) {
+	keys := make([][]byte, 0)
+	cursor := bucket.Cursor()
+	for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+		keys = append(keys, bytes.Clone(key))
+	}
+
+	for _, key := range keys {
+		if err := bucket.Delete(key); err != nil {
+			return 0, err
+		}
+	}
+	return len(keys), nil
+}
+
+func timestampOrNow(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Now().UTC()
+	}
+	return t
+}
@@ -0,0 +1,167 @@
+package bolt
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"go.etcd.io/bbolt"
+)
+
+var channelTable = &entityTable[model.Channel]{
+	name:        "channel",
+	bucket:      channelsBucket,
+	indexBucket: channelsByIDBucket,
+	ids: func(channel *model.Channel) (snowflake.ID, snowflake.ID, snowflake.ID) {
+		return channel.AppID, channel.GuildID, channel.ChannelID
+	},
+	tainted: func(channel *model.Channel) *bool {
+		return &channel.Tainted
+	},
+	updatedAt: func(channel *model.Channel) time.Time {
+		return channel.UpdatedAt
+	},
+	data: func(channel *model.Channel) any {
+		return channel.Data
+	},
+}
+
+func (c *Client) GetGuildChannel(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, channelID snowflake.ID) (*model.Channel, error) {
+	var channel *model.Channel
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		channel, err = channelTable.get(tx, appID, guildID, channelID)
+		return err
+	})
+	return channel, err
+}
+
+func (c *Client) GetChannel(ctx context.Context, appID snowflake.ID, channelID snowflake.ID) (*model.Channel, error) {
+	var channel *model.Channel
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		channel, err = channelTable.getByID(tx, appID, channelID)
+		return err
+	})
+	return channel, err
+}
+
+func (c *Client) GetGuildChannels(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Channel, error) {
+	var channels []*model.Channel
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		channels, err = channelTable.list(tx, guildKey(appID, guildID), opts)
+		return err
+	})
+	return channels, err
+}
+
+func (c *Client) GetChannels(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Channel, error) {
+	var channels []*model.Channel
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		channels, err = channelTable.list(tx, appKey(appID), opts)
+		return err
+	})
+	return channels, err
+}
+
+func (c *Client) GetChannelsByIDs(ctx context.Context, appID snowflake.ID, channelIDs []snowflake.ID) ([]*model.Channel, error) {
+	var channels []*model.Channel
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		channels, err = channelTable.getByIDs(tx, appID, channelIDs)
+		return err
+	})
+	return channels, err
+}
+
+func (c *Client) GetChannelsByType(ctx context.Context, appID snowflake.ID, types []int, opts store.ListOptions) ([]*model.Channel, error) {
+	var channels []*model.Channel
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		channels, err = channelTable.listFunc(tx, appKey(appID), opts, func(channel *model.Channel) bool {
+			return slices.Contains(types, int(channel.Data.Type()))
+		})
+		return err
+	})
+	return channels, err
+}
+
+func (c *Client) SearchGuildChannels(ctx context.Context, params store.SearchGuildChannelsParams) ([]*model.Channel, error) {
+	var channels []*model.Channel
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		channels, err = channelTable.search(tx, guildKey(params.AppID, params.GuildID), params.Data, params.Filter, params.ListOptions)
+		return err
+	})
+	return channels, err
+}
+
+func (c *Client) SearchChannels(ctx context.Context, params store.SearchChannelsParams) ([]*model.Channel, error) {
+	var channels []*model.Channel
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		channels, err = channelTable.search(tx, appKey(params.AppID), params.Data, params.Filter, params.ListOptions)
+		return err
+	})
+	return channels, err
+}
+
+func (c *Client) CountGuildChannels(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (int, error) {
+	var count int
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		count = channelTable.count(tx, guildKey(appID, guildID))
+		return nil
+	})
+	return count, err
+}
+
+func (c *Client) CountChannels(ctx context.Context, appID snowflake.ID) (int, error) {
+	var count int
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		count = channelTable.count(tx, appKey(appID))
+		return nil
+	})
+	return count, err
+}
+
+func (c *Client) UpsertChannels(ctx context.Context, channels ...store.UpsertChannelParams) (int, error) {
+	written := 0
+	err := c.db.Update(func(tx *bbolt.Tx) error {
+		for _, channel := range channels {
+			ok, err := channelTable.upsert(tx, channelFromParams(channel))
+			if err != nil {
+				return err
+			}
+			if ok {
+				written++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return written, nil
+}
+
+func (c *Client) DeleteChannel(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, channelID snowflake.ID) error {
+	return c.db.Update(func(tx *bbolt.Tx) error {
+		return channelTable.delete(tx, appID, guildID, channelID)
+	})
+}
+
+func channelFromParams(channel store.UpsertChannelParams) *model.Channel {
+	return &model.Channel{
+		AppID:     channel.AppID,
+		GuildID:   channel.GuildID,
+		ChannelID: channel.ChannelID,
+		Data:      channel.Data,
+		CreatedAt: timestampOrNow(channel.CreatedAt),
+		UpdatedAt: timestampOrNow(channel.UpdatedAt),
+	}
+}
@@ -0,0 +1,173 @@
+package bolt
+
+import (
+	"context"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"go.etcd.io/bbolt"
+)
+
+var emojiTable = &entityTable[model.Emoji]{
+	name:             "emoji",
+	bucket:           emojisBucket,
+	indexBucket:      emojisByIDBucket,
+	collectionBucket: emojiCollectionsBucket,
+	ids: func(emoji *model.Emoji) (snowflake.ID, snowflake.ID, snowflake.ID) {
+		return emoji.AppID, emoji.GuildID, emoji.EmojiID
+	},
+	tainted: func(emoji *model.Emoji) *bool {
+		return &emoji.Tainted
+	},
+	updatedAt: func(emoji *model.Emoji) time.Time {
+		return emoji.UpdatedAt
+	},
+	data: func(emoji *model.Emoji) any {
+		return emoji.Data
+	},
+}
+
+func (c *Client) GetGuildEmoji(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, emojiID snowflake.ID) (*model.Emoji, error) {
+	var emoji *model.Emoji
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		emoji, err = emojiTable.get(tx, appID, guildID, emojiID)
+		return err
+	})
+	return emoji, err
+}
+
+func (c *Client) GetEmoji(ctx context.Context, appID snowflake.ID, emojiID snowflake.ID) (*model.Emoji, error) {
+	var emoji *model.Emoji
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		emoji, err = emojiTable.getByID(tx, appID, emojiID)
+		return err
+	})
+	return emoji, err
+}
+
+func (c *Client) GetGuildEmojis(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Emoji, error) {
+	var emojis []*model.Emoji
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		emojis, err = emojiTable.list(tx, guildKey(appID, guildID), opts)
+		return err
+	})
+	return emojis, err
+}
+
+func (c *Client) GetEmojis(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Emoji, error) {
+	var emojis []*model.Emoji
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		emojis, err = emojiTable.list(tx, appKey(appID), opts)
+		return err
+	})
+	return emojis, err
+}
+
+func (c *Client) GetEmojisByIDs(ctx context.Context, appID snowflake.ID, emojiIDs []snowflake.ID) ([]*model.Emoji, error) {
+	var emojis []*model.Emoji
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		emojis, err = emojiTable.getByIDs(tx, appID, emojiIDs)
+		return err
+	})
+	return emojis, err
+}
+
+func (c *Client) SearchGuildEmojis(ctx context.Context, params store.SearchGuildEmojisParams) ([]*model.Emoji, error) {
+	var emojis []*model.Emoji
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		emojis, err = emojiTable.search(tx, guildKey(params.AppID, params.GuildID), params.Data, params.Filter, params.ListOptions)
+		return err
+	})
+	return emojis, err
+}
+
+func (c *Client) SearchEmojis(ctx context.Context, params store.SearchEmojisParams) ([]*model.Emoji, error) {
+	var emojis []*model.Emoji
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		emojis, err = emojiTable.search(tx, appKey(params.AppID), params.Data, params.Filter, params.ListOptions)
+		return err
+	})
+	return emojis, err
+}
+
+func (c *Client) CountGuildEmojis(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (int, error) {
+	var count int
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		count = emojiTable.count(tx, guildKey(appID, guildID))
+		return nil
+	})
+	return count, err
+}
+
+func (c *Client) CountEmojis(ctx context.Context, appID snowflake.ID) (int, error) {
+	var count int
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		count = emojiTable.count(tx, appKey(appID))
+		return nil
+	})
+	return count, err
+}
+
+func (c *Client) UpsertEmojis(ctx context.Context, emojis ...store.UpsertEmojiParams) (int, error) {
+	written := 0
+	err := c.db.Update(func(tx *bbolt.Tx) error {
+		for _, emoji := range emojis {
+			ok, err := emojiTable.upsert(tx, emojiFromParams(emoji))
+			if err != nil {
+				return err
+			}
+			if ok {
+				written++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return written, nil
+}
+
+func (c *Client) ReplaceGuildEmojis(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, updatedAt time.Time, emojis ...store.UpsertEmojiParams) (bool, error) {
+	entities := make([]*model.Emoji, len(emojis))
+	for i, emoji := range emojis {
+		entities[i] = emojiFromParams(emoji)
+	}
+
+	replaced := false
+	err := c.db.Update(func(tx *bbolt.Tx) error {
+		var err error
+		replaced, err = emojiTable.replaceGuild(tx, appID, guildID, updatedAt, entities)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return replaced, nil
+}
+
+func (c *Client) DeleteEmoji(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, emojiID snowflake.ID) error {
+	return c.db.Update(func(tx *bbolt.Tx) error {
+		return emojiTable.delete(tx, appID, guildID, emojiID)
+	})
+}
+
+func emojiFromParams(emoji store.UpsertEmojiParams) *model.Emoji {
+	return &model.Emoji{
+		AppID:     emoji.AppID,
+		GuildID:   emoji.GuildID,
+		EmojiID:   emoji.EmojiID,
+		Data:      emoji.Data,
+		CreatedAt: timestampOrNow(emoji.CreatedAt),
+		UpdatedAt: timestampOrNow(emoji.UpdatedAt),
+	}
+}
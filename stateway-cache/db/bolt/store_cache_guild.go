@@ -0,0 +1,214 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"go.etcd.io/bbolt"
+)
+
+var guildTable = &entityTable[model.Guild]{
+	name:   "guild",
+	bucket: guildsBucket,
+	ids: func(guild *model.Guild) (snowflake.ID, snowflake.ID, snowflake.ID) {
+		return guild.AppID, guild.GuildID, guild.GuildID
+	},
+	tainted: func(guild *model.Guild) *bool {
+		return &guild.Tainted
+	},
+	updatedAt: func(guild *model.Guild) time.Time {
+		return guild.UpdatedAt
+	},
+	data: func(guild *model.Guild) any {
+		return guild.Data
+	},
+}
+
+func (c *Client) GetGuild(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (*model.Guild, error) {
+	var guild *model.Guild
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		guild, err = guildTable.get(tx, appID, guildID, guildID)
+		return err
+	})
+	return guild, err
+}
+
+func (c *Client) GetFullGuild(ctx context.Context, params store.GetFullGuildParams) (*model.FullGuild, error) {
+	var res *model.FullGuild
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		guild, err := guildTable.get(tx, params.AppID, params.GuildID, params.GuildID)
+		if err != nil {
+			return err
+		}
+
+		res = &model.FullGuild{Guild: *guild}
+		prefix := guildKey(params.AppID, params.GuildID)
+		opts := store.ListOptions{ExcludeTainted: params.ExcludeTainted}
+
+		if params.Include.Channels {
+			res.Channels, err = channelTable.list(tx, prefix, opts)
+			if err != nil {
+				return err
+			}
+		}
+		if params.Include.Roles {
+			res.Roles, err = roleTable.list(tx, prefix, opts)
+			if err != nil {
+				return err
+			}
+		}
+		if params.Include.Emojis {
+			res.Emojis, err = emojiTable.list(tx, prefix, opts)
+			if err != nil {
+				return err
+			}
+		}
+		if params.Include.Stickers {
+			res.Stickers, err = stickerTable.list(tx, prefix, opts)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return res, err
+}
+
+func (c *Client) GetGuildOwnerID(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (snowflake.ID, error) {
+	guild, err := c.GetGuild(ctx, appID, guildID)
+	if err != nil {
+		return 0, err
+	}
+	return guild.Data.OwnerID, nil
+}
+
+func (c *Client) GetGuilds(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Guild, error) {
+	var guilds []*model.Guild
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		guilds, err = guildTable.list(tx, appKey(appID), opts)
+		return err
+	})
+	return guilds, err
+}
+
+func (c *Client) GetGuildsByIDs(ctx context.Context, appID snowflake.ID, guildIDs []snowflake.ID) ([]*model.Guild, error) {
+	var guilds []*model.Guild
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		guilds, err = guildTable.getByIDs(tx, appID, guildIDs)
+		return err
+	})
+	return guilds, err
+}
+
+func (c *Client) CheckGuildExist(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (bool, error) {
+	var exists bool
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		exists = tx.Bucket(guildsBucket).Get(guildKey(appID, guildID)) != nil
+		return nil
+	})
+	return exists, err
+}
+
+// GetGuildApps seeks the guild in the guilds of every app.
+// The keys start with the app ID, so the cursor skips from app to app instead of reading all guilds.
+func (c *Client) GetGuildApps(ctx context.Context, guildID snowflake.ID) ([]*model.GuildApp, error) {
+	apps := make([]*model.GuildApp, 0)
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		cursor := tx.Bucket(guildsBucket).Cursor()
+		for k, _ := cursor.First(); k != nil; {
+			appID := decodeID(k, 0)
+
+			key := guildKey(appID, guildID)
+			found, v := cursor.Seek(key)
+			if bytes.Equal(found, key) {
+				guild, err := guildTable.decode(v)
+				if err != nil {
+					return err
+				}
+				apps = append(apps, &model.GuildApp{
+					AppID:       appID,
+					Unavailable: guild.Unavailable,
+					Tainted:     guild.Tainted,
+					UpdatedAt:   guild.UpdatedAt,
+				})
+			}
+
+			if appID == math.MaxUint64 {
+				break
+			}
+			k, _ = cursor.Seek(appKey(appID + 1))
+		}
+		return nil
+	})
+	return apps, err
+}
+
+func (c *Client) UpsertGuilds(ctx context.Context, guilds ...store.UpsertGuildParams) (int, error) {
+	written := 0
+	err := c.db.Update(func(tx *bbolt.Tx) error {
+		for _, guild := range guilds {
+			ok, err := guildTable.upsert(tx, guildFromParams(guild))
+			if err != nil {
+				return err
+			}
+			if ok {
+				written++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return written, nil
+}
+
+func (c *Client) MarkGuildUnavailable(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) error {
+	return c.db.Update(func(tx *bbolt.Tx) error {
+		guild, err := guildTable.get(tx, appID, guildID, guildID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		guild.Unavailable = true
+		return guildTable.put(tx, guild)
+	})
+}
+
+func (c *Client) DeleteGuild(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) error {
+	return c.db.Update(func(tx *bbolt.Tx) error {
+		return guildTable.delete(tx, appID, guildID, guildID)
+	})
+}
+
+func (c *Client) SearchGuilds(ctx context.Context, params store.SearchGuildsParams) ([]*model.Guild, error) {
+	var guilds []*model.Guild
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		guilds, err = guildTable.search(tx, appKey(params.AppID), params.Data, params.Filter, params.ListOptions)
+		return err
+	})
+	return guilds, err
+}
+
+func guildFromParams(guild store.UpsertGuildParams) *model.Guild {
+	return &model.Guild{
+		AppID:     guild.AppID,
+		GuildID:   guild.GuildID,
+		Data:      guild.Data,
+		CreatedAt: timestampOrNow(guild.CreatedAt),
+		UpdatedAt: timestampOrNow(guild.UpdatedAt),
+	}
+}
@@ -0,0 +1,173 @@
+package bolt
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"go.etcd.io/bbolt"
+)
+
+var roleTable = &entityTable[model.Role]{
+	name:        "role",
+	bucket:      rolesBucket,
+	indexBucket: rolesByIDBucket,
+	ids: func(role *model.Role) (snowflake.ID, snowflake.ID, snowflake.ID) {
+		return role.AppID, role.GuildID, role.RoleID
+	},
+	tainted: func(role *model.Role) *bool {
+		return &role.Tainted
+	},
+	updatedAt: func(role *model.Role) time.Time {
+		return role.UpdatedAt
+	},
+	data: func(role *model.Role) any {
+		return role.Data
+	},
+}
+
+func (c *Client) GetGuildRole(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, roleID snowflake.ID) (*model.Role, error) {
+	var role *model.Role
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		role, err = roleTable.get(tx, appID, guildID, roleID)
+		return err
+	})
+	return role, err
+}
+
+func (c *Client) GetRole(ctx context.Context, appID snowflake.ID, roleID snowflake.ID) (*model.Role, error) {
+	var role *model.Role
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		role, err = roleTable.getByID(tx, appID, roleID)
+		return err
+	})
+	return role, err
+}
+
+func (c *Client) GetGuildRoles(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Role, error) {
+	var roles []*model.Role
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		roles, err = roleTable.list(tx, guildKey(appID, guildID), opts)
+		return err
+	})
+	return roles, err
+}
+
+func (c *Client) GetGuildRolesByIDs(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, roleIDs []snowflake.ID) ([]*model.Role, error) {
+	roles := make([]*model.Role, 0, len(roleIDs))
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		for _, roleID := range roleIDs {
+			role, err := roleTable.get(tx, appID, guildID, roleID)
+			if err != nil {
+				if errors.Is(err, store.ErrNotFound) {
+					continue
+				}
+				return err
+			}
+			roles = append(roles, role)
+		}
+		return nil
+	})
+	return roles, err
+}
+
+func (c *Client) GetRoles(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Role, error) {
+	var roles []*model.Role
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		roles, err = roleTable.list(tx, appKey(appID), opts)
+		return err
+	})
+	return roles, err
+}
+
+func (c *Client) GetRolesByIDs(ctx context.Context, appID snowflake.ID, roleIDs []snowflake.ID) ([]*model.Role, error) {
+	var roles []*model.Role
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		roles, err = roleTable.getByIDs(tx, appID, roleIDs)
+		return err
+	})
+	return roles, err
+}
+
+func (c *Client) SearchGuildRoles(ctx context.Context, params store.SearchGuildRolesParams) ([]*model.Role, error) {
+	var roles []*model.Role
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		roles, err = roleTable.search(tx, guildKey(params.AppID, params.GuildID), params.Data, params.Filter, params.ListOptions)
+		return err
+	})
+	return roles, err
+}
+
+func (c *Client) SearchRoles(ctx context.Context, params store.SearchRolesParams) ([]*model.Role, error) {
+	var roles []*model.Role
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		roles, err = roleTable.search(tx, appKey(params.AppID), params.Data, params.Filter, params.ListOptions)
+		return err
+	})
+	return roles, err
+}
+
+func (c *Client) CountGuildRoles(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (int, error) {
+	var count int
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		count = roleTable.count(tx, guildKey(appID, guildID))
+		return nil
+	})
+	return count, err
+}
+
+func (c *Client) CountRoles(ctx context.Context, appID snowflake.ID) (int, error) {
+	var count int
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		count = roleTable.count(tx, appKey(appID))
+		return nil
+	})
+	return count, err
+}
+
+func (c *Client) UpsertRoles(ctx context.Context, roles ...store.UpsertRoleParams) (int, error) {
+	written := 0
+	err := c.db.Update(func(tx *bbolt.Tx) error {
+		for _, role := range roles {
+			ok, err := roleTable.upsert(tx, roleFromParams(role))
+			if err != nil {
+				return err
+			}
+			if ok {
+				written++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return written, nil
+}
+
+func (c *Client) DeleteRole(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, roleID snowflake.ID) error {
+	return c.db.Update(func(tx *bbolt.Tx) error {
+		return roleTable.delete(tx, appID, guildID, roleID)
+	})
+}
+
+func roleFromParams(role store.UpsertRoleParams) *model.Role {
+	return &model.Role{
+		AppID:     role.AppID,
+		GuildID:   role.GuildID,
+		RoleID:    role.RoleID,
+		Data:      role.Data,
+		CreatedAt: timestampOrNow(role.CreatedAt),
+		UpdatedAt: timestampOrNow(role.UpdatedAt),
+	}
+}
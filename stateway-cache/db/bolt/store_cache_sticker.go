@@ -0,0 +1,167 @@
+package bolt
+
+import (
+	"context"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"go.etcd.io/bbolt"
+)
+
+var stickerTable = &entityTable[model.Sticker]{
+	name:             "sticker",
+	bucket:           stickersBucket,
+	indexBucket:      stickersByIDBucket,
+	collectionBucket: stickerCollectionsBucket,
+	ids: func(sticker *model.Sticker) (snowflake.ID, snowflake.ID, snowflake.ID) {
+		return sticker.AppID, sticker.GuildID, sticker.StickerID
+	},
+	tainted: func(sticker *model.Sticker) *bool {
+		return &sticker.Tainted
+	},
+	updatedAt: func(sticker *model.Sticker) time.Time {
+		return sticker.UpdatedAt
+	},
+	data: func(sticker *model.Sticker) any {
+		return sticker.Data
+	},
+}
+
+func (c *Client) GetSticker(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, stickerID snowflake.ID) (*model.Sticker, error) {
+	var sticker *model.Sticker
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		sticker, err = stickerTable.get(tx, appID, guildID, stickerID)
+		return err
+	})
+	return sticker, err
+}
+
+func (c *Client) GetGuildSticker(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, stickerID snowflake.ID) (*model.Sticker, error) {
+	return c.GetSticker(ctx, appID, guildID, stickerID)
+}
+
+func (c *Client) GetGuildStickers(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Sticker, error) {
+	var stickers []*model.Sticker
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		stickers, err = stickerTable.list(tx, guildKey(appID, guildID), opts)
+		return err
+	})
+	return stickers, err
+}
+
+func (c *Client) GetStickers(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Sticker, error) {
+	var stickers []*model.Sticker
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		stickers, err = stickerTable.list(tx, appKey(appID), opts)
+		return err
+	})
+	return stickers, err
+}
+
+func (c *Client) GetStickersByIDs(ctx context.Context, appID snowflake.ID, stickerIDs []snowflake.ID) ([]*model.Sticker, error) {
+	var stickers []*model.Sticker
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		stickers, err = stickerTable.getByIDs(tx, appID, stickerIDs)
+		return err
+	})
+	return stickers, err
+}
+
+func (c *Client) SearchGuildStickers(ctx context.Context, params store.SearchGuildStickersParams) ([]*model.Sticker, error) {
+	var stickers []*model.Sticker
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		stickers, err = stickerTable.search(tx, guildKey(params.AppID, params.GuildID), params.Data, params.Filter, params.ListOptions)
+		return err
+	})
+	return stickers, err
+}
+
+func (c *Client) SearchStickers(ctx context.Context, params store.SearchStickersParams) ([]*model.Sticker, error) {
+	var stickers []*model.Sticker
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		stickers, err = stickerTable.search(tx, appKey(params.AppID), params.Data, params.Filter, params.ListOptions)
+		return err
+	})
+	return stickers, err
+}
+
+func (c *Client) CountGuildStickers(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (int, error) {
+	var count int
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		count = stickerTable.count(tx, guildKey(appID, guildID))
+		return nil
+	})
+	return count, err
+}
+
+func (c *Client) CountStickers(ctx context.Context, appID snowflake.ID) (int, error) {
+	var count int
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		count = stickerTable.count(tx, appKey(appID))
+		return nil
+	})
+	return count, err
+}
+
+func (c *Client) UpsertStickers(ctx context.Context, stickers ...store.UpsertStickerParams) (int, error) {
+	written := 0
+	err := c.db.Update(func(tx *bbolt.Tx) error {
+		for _, sticker := range stickers {
+			ok, err := stickerTable.upsert(tx, stickerFromParams(sticker))
+			if err != nil {
+				return err
+			}
+			if ok {
+				written++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return written, nil
+}
+
+func (c *Client) ReplaceGuildStickers(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, updatedAt time.Time, stickers ...store.UpsertStickerParams) (bool, error) {
+	entities := make([]*model.Sticker, len(stickers))
+	for i, sticker := range stickers {
+		entities[i] = stickerFromParams(sticker)
+	}
+
+	replaced := false
+	err := c.db.Update(func(tx *bbolt.Tx) error {
+		var err error
+		replaced, err = stickerTable.replaceGuild(tx, appID, guildID, updatedAt, entities)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return replaced, nil
+}
+
+func (c *Client) DeleteSticker(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, stickerID snowflake.ID) error {
+	return c.db.Update(func(tx *bbolt.Tx) error {
+		return stickerTable.delete(tx, appID, guildID, stickerID)
+	})
+}
+
+func stickerFromParams(sticker store.UpsertStickerParams) *model.Sticker {
+	return &model.Sticker{
+		AppID:     sticker.AppID,
+		GuildID:   sticker.GuildID,
+		StickerID: sticker.StickerID,
+		Data:      sticker.Data,
+		CreatedAt: timestampOrNow(sticker.CreatedAt),
+		UpdatedAt: timestampOrNow(sticker.UpdatedAt),
+	}
+}
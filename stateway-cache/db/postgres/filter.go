@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+)
+
+// filterSearch is a search query with a filter.
+// Filters can't be expressed with a static sqlc query, so the query is built at runtime.
+type filterSearch struct {
+	table    string
+	columns  string
+	idColumn string
+	appID    snowflake.ID
+	guildID  snowflake.ID
+	data     json.RawMessage
+	filter   model.Filter
+	opts     store.ListOptions
+}
+
+// searchFiltered runs the search and scans the rows into the sqlc model T.
+func searchFiltered[T any](ctx context.Context, c *Client, search filterSearch) ([]T, error) {
+	filter, err := search.filter.Normalize()
+	if err != nil {
+		return nil, fmt.Errorf("invalid search filter: %w", err)
+	}
+
+	q := &filterQuery{}
+	conditions := []string{"app_id = " + q.arg(int64(search.appID))}
+	if search.guildID != 0 {
+		conditions = append(conditions, "guild_id = "+q.arg(int64(search.guildID)))
+	}
+	if len(search.data) != 0 {
+		conditions = append(conditions, "data @> "+q.arg([]byte(search.data))+"::jsonb")
+	}
+	if search.opts.ExcludeTainted {
+		conditions = append(conditions, "NOT tainted")
+	}
+	if search.opts.After != 0 {
+		conditions = append(conditions, search.idColumn+" > "+q.arg(int64(search.opts.After)))
+	}
+
+	condition, err := q.compile(filter)
+	if err != nil {
+		return nil, err
+	}
+	conditions = append(conditions, condition)
+
+	sql := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s ORDER BY %s",
+		search.columns, search.table, strings.Join(conditions, " AND "), search.idColumn,
+	)
+	if search.opts.Limit > 0 {
+		sql += " LIMIT " + q.arg(search.opts.Limit)
+	}
+	if search.opts.Offset > 0 {
+		sql += " OFFSET " + q.arg(search.opts.Offset)
+	}
+
+	rows, err := c.DB.Query(ctx, sql, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[T])
+}
+
+// filterQuery compiles a normalized filter to a SQL condition on the data column.
+// All values, including the field paths, are passed as query arguments.
+type filterQuery struct {
+	args []any
+}
+
+func (q *filterQuery) arg(value any) string {
+	q.args = append(q.args, value)
+	return "$" + strconv.Itoa(len(q.args))
+}
+
+func (q *filterQuery) compile(f model.Filter) (string, error) {
+	switch f.Op {
+	case model.FilterOpAnd, model.FilterOpOr:
+		conditions := make([]string, len(f.Filters))
+		for i, filter := range f.Filters {
+			condition, err := q.compile(filter)
+			if err != nil {
+				return "", err
+			}
+			conditions[i] = condition
+		}
+		return "(" + strings.Join(conditions, " "+strings.ToUpper(string(f.Op))+" ") + ")", nil
+	}
+
+	path := q.arg(f.Path()) + "::text[]"
+	field := "(data #> " + path + ")"
+	text := "(data #>> " + path + ")"
+
+	switch f.Op {
+	case model.FilterOpEq:
+		value, err := json.Marshal(f.Value)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal filter value: %w", err)
+		}
+		return fmt.Sprintf("COALESCE(%s = %s::jsonb, false)", field, q.arg(value)), nil
+	case model.FilterOpIn:
+		value, err := json.Marshal(f.Value)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal filter value: %w", err)
+		}
+		return fmt.Sprintf("COALESCE(%s IN (SELECT jsonb_array_elements(%s::jsonb)), false)", field, q.arg(value)), nil
+	case model.FilterOpPrefix:
+		return fmt.Sprintf(
+			"CASE WHEN jsonb_typeof(%s) = 'string' THEN starts_with(%s, %s::text) ELSE false END",
+			field, text, q.arg(f.Value),
+		), nil
+	case model.FilterOpContains:
+		return fmt.Sprintf(
+			"CASE WHEN jsonb_typeof(%s) = 'string' THEN strpos(%s, %s::text) > 0 ELSE false END",
+			field, text, q.arg(f.Value),
+		), nil
+	case model.FilterOpGt, model.FilterOpGte, model.FilterOpLt, model.FilterOpLte:
+		operator := map[model.FilterOp]string{
+			model.FilterOpGt:  ">",
+			model.FilterOpGte: ">=",
+			model.FilterOpLt:  "<",
+			model.FilterOpLte: "<=",
+		}[f.Op]
+		return fmt.Sprintf(
+			"CASE WHEN jsonb_typeof(%s) = 'number' THEN %s::float8 %s %s::float8 ELSE false END",
+			field, text, operator, q.arg(f.Value),
+		), nil
+	case model.FilterOpHasFlags:
+		// Numbers and numeric strings are both accepted, values that don't fit a bigint never match
+		flags := q.arg(f.Value)
+		return fmt.Sprintf(
+			"CASE WHEN jsonb_typeof(%s) NOT IN ('number', 'string') OR %s !~ '^[0-9]{1,19}$' THEN false "+
+				"WHEN %s::numeric > 9223372036854775807 THEN false "+
+				"ELSE (%s::bigint & %s::bigint) = %s::bigint END",
+			field, text, text, text, flags, flags,
+		), nil
+	}
+
+	return "", fmt.Errorf("unknown filter op: %q", f.Op)
+}
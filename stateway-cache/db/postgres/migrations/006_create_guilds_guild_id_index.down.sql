@@ -0,0 +1 @@
+DROP INDEX IF EXISTS cache.idx_cache_guilds_guild_id;
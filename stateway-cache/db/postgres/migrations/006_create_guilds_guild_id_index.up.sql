@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_cache_guilds_guild_id ON cache.guilds (guild_id);
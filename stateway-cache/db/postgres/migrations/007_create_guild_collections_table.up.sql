@@ -0,0 +1,8 @@
+CREATE UNLOGGED TABLE IF NOT EXISTS cache.guild_collections (
+    app_id BIGINT NOT NULL,
+    guild_id BIGINT NOT NULL,
+    collection TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+
+    PRIMARY KEY (app_id, guild_id, collection)
+);
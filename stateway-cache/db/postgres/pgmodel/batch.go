@@ -17,7 +17,7 @@ var (
 	ErrBatchAlreadyClosed = errors.New("batch already closed")
 )
 
-const upsertChannels = `-- name: UpsertChannels :batchexec
+const upsertChannels = `-- name: UpsertChannels :batchone
 INSERT INTO cache.channels (
     app_id, 
     guild_id, 
@@ -30,6 +30,8 @@ ON CONFLICT (app_id, guild_id, channel_id) DO UPDATE SET
     data = EXCLUDED.data, 
     tainted = FALSE,
     updated_at = EXCLUDED.updated_at
+WHERE cache.channels.updated_at <= EXCLUDED.updated_at
+RETURNING channel_id
 `
 
 type UpsertChannelsBatchResults struct {
@@ -64,18 +66,20 @@ func (q *Queries) UpsertChannels(ctx context.Context, arg []UpsertChannelsParams
 	return &UpsertChannelsBatchResults{br, len(arg), false}
 }
 
-func (b *UpsertChannelsBatchResults) Exec(f func(int, error)) {
+func (b *UpsertChannelsBatchResults) QueryRow(f func(int, int64, error)) {
 	defer b.br.Close()
 	for t := 0; t < b.tot; t++ {
+		var channel_id int64
 		if b.closed {
 			if f != nil {
-				f(t, ErrBatchAlreadyClosed)
+				f(t, channel_id, ErrBatchAlreadyClosed)
 			}
 			continue
 		}
-		_, err := b.br.Exec()
+		row := b.br.QueryRow()
+		err := row.Scan(&channel_id)
 		if f != nil {
-			f(t, err)
+			f(t, channel_id, err)
 		}
 	}
 }
@@ -85,7 +89,7 @@ func (b *UpsertChannelsBatchResults) Close() error {
 	return b.br.Close()
 }
 
-const upsertEmojis = `-- name: UpsertEmojis :batchexec
+const upsertEmojis = `-- name: UpsertEmojis :batchone
 INSERT INTO cache.emojis (
     app_id, 
     guild_id, 
@@ -98,6 +102,8 @@ ON CONFLICT (app_id, guild_id, emoji_id) DO UPDATE SET
     data = EXCLUDED.data, 
     tainted = FALSE,
     updated_at = EXCLUDED.updated_at
+WHERE cache.emojis.updated_at <= EXCLUDED.updated_at
+RETURNING emoji_id
 `
 
 type UpsertEmojisBatchResults struct {
@@ -132,18 +138,20 @@ func (q *Queries) UpsertEmojis(ctx context.Context, arg []UpsertEmojisParams) *U
 	return &UpsertEmojisBatchResults{br, len(arg), false}
 }
 
-func (b *UpsertEmojisBatchResults) Exec(f func(int, error)) {
+func (b *UpsertEmojisBatchResults) QueryRow(f func(int, int64, error)) {
 	defer b.br.Close()
 	for t := 0; t < b.tot; t++ {
+		var emoji_id int64
 		if b.closed {
 			if f != nil {
-				f(t, ErrBatchAlreadyClosed)
+				f(t, emoji_id, ErrBatchAlreadyClosed)
 			}
 			continue
 		}
-		_, err := b.br.Exec()
+		row := b.br.QueryRow()
+		err := row.Scan(&emoji_id)
 		if f != nil {
-			f(t, err)
+			f(t, emoji_id, err)
 		}
 	}
 }
@@ -153,7 +161,7 @@ func (b *UpsertEmojisBatchResults) Close() error {
 	return b.br.Close()
 }
 
-const upsertGuilds = `-- name: UpsertGuilds :batchexec
+const upsertGuilds = `-- name: UpsertGuilds :batchone
 INSERT INTO cache.guilds (
     app_id, 
     guild_id, 
@@ -165,6 +173,8 @@ ON CONFLICT (app_id, guild_id) DO UPDATE SET
     data = EXCLUDED.data, 
     tainted = FALSE,
     updated_at = EXCLUDED.updated_at
+WHERE cache.guilds.updated_at <= EXCLUDED.updated_at
+RETURNING guild_id
 `
 
 type UpsertGuildsBatchResults struct {
@@ -197,18 +207,20 @@ func (q *Queries) UpsertGuilds(ctx context.Context, arg []UpsertGuildsParams) *U
 	return &UpsertGuildsBatchResults{br, len(arg), false}
 }
 
-func (b *UpsertGuildsBatchResults) Exec(f func(int, error)) {
+func (b *UpsertGuildsBatchResults) QueryRow(f func(int, int64, error)) {
 	defer b.br.Close()
 	for t := 0; t < b.tot; t++ {
+		var guild_id int64
 		if b.closed {
 			if f != nil {
-				f(t, ErrBatchAlreadyClosed)
+				f(t, guild_id, ErrBatchAlreadyClosed)
 			}
 			continue
 		}
-		_, err := b.br.Exec()
+		row := b.br.QueryRow()
+		err := row.Scan(&guild_id)
 		if f != nil {
-			f(t, err)
+			f(t, guild_id, err)
 		}
 	}
 }
@@ -218,7 +230,7 @@ func (b *UpsertGuildsBatchResults) Close() error {
 	return b.br.Close()
 }
 
-const upsertRoles = `-- name: UpsertRoles :batchexec
+const upsertRoles = `-- name: UpsertRoles :batchone
 INSERT INTO cache.roles (
     app_id, 
     guild_id, 
@@ -231,6 +243,8 @@ ON CONFLICT (app_id, guild_id, role_id) DO UPDATE SET
     data = EXCLUDED.data, 
     tainted = FALSE,
     updated_at = EXCLUDED.updated_at
+WHERE cache.roles.updated_at <= EXCLUDED.updated_at
+RETURNING role_id
 `
 
 type UpsertRolesBatchResults struct {
@@ -265,18 +279,20 @@ func (q *Queries) UpsertRoles(ctx context.Context, arg []UpsertRolesParams) *Ups
 	return &UpsertRolesBatchResults{br, len(arg), false}
 }
 
-func (b *UpsertRolesBatchResults) Exec(f func(int, error)) {
+func (b *UpsertRolesBatchResults) QueryRow(f func(int, int64, error)) {
 	defer b.br.Close()
 	for t := 0; t < b.tot; t++ {
+		var role_id int64
 		if b.closed {
 			if f != nil {
-				f(t, ErrBatchAlreadyClosed)
+				f(t, role_id, ErrBatchAlreadyClosed)
 			}
 			continue
 		}
-		_, err := b.br.Exec()
+		row := b.br.QueryRow()
+		err := row.Scan(&role_id)
 		if f != nil {
-			f(t, err)
+			f(t, role_id, err)
 		}
 	}
 }
@@ -286,7 +302,7 @@ func (b *UpsertRolesBatchResults) Close() error {
 	return b.br.Close()
 }
 
-const upsertStickers = `-- name: UpsertStickers :batchexec
+const upsertStickers = `-- name: UpsertStickers :batchone
 INSERT INTO cache.stickers (
     app_id, 
     guild_id, 
@@ -299,6 +315,8 @@ ON CONFLICT (app_id, guild_id, sticker_id) DO UPDATE SET
     data = EXCLUDED.data, 
     tainted = FALSE,
     updated_at = EXCLUDED.updated_at
+WHERE cache.stickers.updated_at <= EXCLUDED.updated_at
+RETURNING sticker_id
 `
 
 type UpsertStickersBatchResults struct {
@@ -333,18 +351,20 @@ func (q *Queries) UpsertStickers(ctx context.Context, arg []UpsertStickersParams
 	return &UpsertStickersBatchResults{br, len(arg), false}
 }
 
-func (b *UpsertStickersBatchResults) Exec(f func(int, error)) {
+func (b *UpsertStickersBatchResults) QueryRow(f func(int, int64, error)) {
 	defer b.br.Close()
 	for t := 0; t < b.tot; t++ {
+		var sticker_id int64
 		if b.closed {
 			if f != nil {
-				f(t, ErrBatchAlreadyClosed)
+				f(t, sticker_id, ErrBatchAlreadyClosed)
 			}
 			continue
 		}
-		_, err := b.br.Exec()
+		row := b.br.QueryRow()
+		err := row.Scan(&sticker_id)
 		if f != nil {
-			f(t, err)
+			f(t, sticker_id, err)
 		}
 	}
 }
@@ -38,6 +38,25 @@ func (q *Queries) CountGuildChannels(ctx context.Context, arg CountGuildChannels
 	return count, err
 }
 
+const deleteAppChannels = `-- name: DeleteAppChannels :execrows
+DELETE FROM cache.channels WHERE (app_id, guild_id, channel_id) IN (
+    SELECT app_id, guild_id, channel_id FROM cache.channels WHERE app_id = $1 LIMIT $2
+)
+`
+
+type DeleteAppChannelsParams struct {
+	AppID     int64
+	BatchSize int32
+}
+
+func (q *Queries) DeleteAppChannels(ctx context.Context, arg DeleteAppChannelsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteAppChannels, arg.AppID, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteChannel = `-- name: DeleteChannel :exec
 DELETE FROM cache.channels WHERE app_id = $1 AND guild_id = $2 AND channel_id = $3
 `
@@ -53,6 +72,33 @@ func (q *Queries) DeleteChannel(ctx context.Context, arg DeleteChannelParams) er
 	return err
 }
 
+const deleteShardTaintedChannels = `-- name: DeleteShardTaintedChannels :exec
+DELETE FROM cache.channels WHERE app_id = $1 AND (guild_id >> 22) % $2 = $3 AND NOT (guild_id = ANY($4::bigint[])) AND (guild_id >> 22) % $5 = $6 AND (
+    tainted OR guild_id IN (SELECT g.guild_id FROM cache.guilds g WHERE g.app_id = $1 AND g.tainted)
+)
+`
+
+type DeleteShardTaintedChannelsParams struct {
+	AppID          int64
+	ShardCount     int64
+	ShardID        int64
+	KeepGuildIds   []int64
+	PartitionCount int64
+	PartitionID    int64
+}
+
+func (q *Queries) DeleteShardTaintedChannels(ctx context.Context, arg DeleteShardTaintedChannelsParams) error {
+	_, err := q.db.Exec(ctx, deleteShardTaintedChannels,
+		arg.AppID,
+		arg.ShardCount,
+		arg.ShardID,
+		arg.KeepGuildIds,
+		arg.PartitionCount,
+		arg.PartitionID,
+	)
+	return err
+}
+
 const getChannel = `-- name: GetChannel :one
 SELECT app_id, guild_id, channel_id, data, tainted, created_at, updated_at FROM cache.channels WHERE app_id = $1 AND channel_id = $2 LIMIT 1
 `
@@ -77,18 +123,99 @@ func (q *Queries) GetChannel(ctx context.Context, arg GetChannelParams) (CacheCh
 	return i, err
 }
 
+const getChannelStats = `-- name: GetChannelStats :many
+SELECT app_id, COUNT(*) AS count, COUNT(*) FILTER (WHERE tainted) AS tainted, MIN(updated_at)::timestamp AS oldest_updated_at FROM cache.channels WHERE ($1::bigint IS NULL OR app_id = $1) GROUP BY app_id
+`
+
+type GetChannelStatsRow struct {
+	AppID           int64
+	Count           int64
+	Tainted         int64
+	OldestUpdatedAt pgtype.Timestamp
+}
+
+func (q *Queries) GetChannelStats(ctx context.Context, appID pgtype.Int8) ([]GetChannelStatsRow, error) {
+	rows, err := q.db.Query(ctx, getChannelStats, appID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChannelStatsRow
+	for rows.Next() {
+		var i GetChannelStatsRow
+		if err := rows.Scan(
+			&i.AppID,
+			&i.Count,
+			&i.Tainted,
+			&i.OldestUpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getChannels = `-- name: GetChannels :many
-SELECT app_id, guild_id, channel_id, data, tainted, created_at, updated_at FROM cache.channels WHERE app_id = $1 ORDER BY channel_id LIMIT $3 OFFSET $2
+SELECT app_id, guild_id, channel_id, data, tainted, created_at, updated_at FROM cache.channels WHERE app_id = $1 AND (NOT $2::boolean OR NOT tainted) AND ($3::bigint IS NULL OR channel_id > $3) ORDER BY channel_id LIMIT $5 OFFSET $4
 `
 
 type GetChannelsParams struct {
-	AppID  int64
-	Offset pgtype.Int4
-	Limit  pgtype.Int4
+	AppID          int64
+	ExcludeTainted bool
+	After          pgtype.Int8
+	Offset         pgtype.Int4
+	Limit          pgtype.Int4
 }
 
 func (q *Queries) GetChannels(ctx context.Context, arg GetChannelsParams) ([]CacheChannel, error) {
-	rows, err := q.db.Query(ctx, getChannels, arg.AppID, arg.Offset, arg.Limit)
+	rows, err := q.db.Query(ctx, getChannels,
+		arg.AppID,
+		arg.ExcludeTainted,
+		arg.After,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CacheChannel
+	for rows.Next() {
+		var i CacheChannel
+		if err := rows.Scan(
+			&i.AppID,
+			&i.GuildID,
+			&i.ChannelID,
+			&i.Data,
+			&i.Tainted,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChannelsByIDs = `-- name: GetChannelsByIDs :many
+SELECT app_id, guild_id, channel_id, data, tainted, created_at, updated_at FROM cache.channels WHERE app_id = $1 AND channel_id = ANY($2::bigint[])
+`
+
+type GetChannelsByIDsParams struct {
+	AppID      int64
+	ChannelIds []int64
+}
+
+func (q *Queries) GetChannelsByIDs(ctx context.Context, arg GetChannelsByIDsParams) ([]CacheChannel, error) {
+	rows, err := q.db.Query(ctx, getChannelsByIDs, arg.AppID, arg.ChannelIds)
 	if err != nil {
 		return nil, err
 	}
@@ -116,20 +243,24 @@ func (q *Queries) GetChannels(ctx context.Context, arg GetChannelsParams) ([]Cac
 }
 
 const getChannelsByType = `-- name: GetChannelsByType :many
-SELECT app_id, guild_id, channel_id, data, tainted, created_at, updated_at FROM cache.channels WHERE app_id = $1 AND (data->>'type')::INT = ANY($2::INT[]) ORDER BY channel_id LIMIT $4 OFFSET $3
+SELECT app_id, guild_id, channel_id, data, tainted, created_at, updated_at FROM cache.channels WHERE app_id = $1 AND (data->>'type')::INT = ANY($2::INT[]) AND (NOT $3::boolean OR NOT tainted) AND ($4::bigint IS NULL OR channel_id > $4) ORDER BY channel_id LIMIT $6 OFFSET $5
 `
 
 type GetChannelsByTypeParams struct {
-	AppID  int64
-	Types  []int32
-	Offset pgtype.Int4
-	Limit  pgtype.Int4
+	AppID          int64
+	Types          []int32
+	ExcludeTainted bool
+	After          pgtype.Int8
+	Offset         pgtype.Int4
+	Limit          pgtype.Int4
 }
 
 func (q *Queries) GetChannelsByType(ctx context.Context, arg GetChannelsByTypeParams) ([]CacheChannel, error) {
 	rows, err := q.db.Query(ctx, getChannelsByType,
 		arg.AppID,
 		arg.Types,
+		arg.ExcludeTainted,
+		arg.After,
 		arg.Offset,
 		arg.Limit,
 	)
@@ -185,20 +316,24 @@ func (q *Queries) GetGuildChannel(ctx context.Context, arg GetGuildChannelParams
 }
 
 const getGuildChannels = `-- name: GetGuildChannels :many
-SELECT app_id, guild_id, channel_id, data, tainted, created_at, updated_at FROM cache.channels WHERE app_id = $1 AND guild_id = $2 ORDER BY channel_id LIMIT $4 OFFSET $3
+SELECT app_id, guild_id, channel_id, data, tainted, created_at, updated_at FROM cache.channels WHERE app_id = $1 AND guild_id = $2 AND (NOT $3::boolean OR NOT tainted) AND ($4::bigint IS NULL OR channel_id > $4) ORDER BY channel_id LIMIT $6 OFFSET $5
 `
 
 type GetGuildChannelsParams struct {
-	AppID   int64
-	GuildID int64
-	Offset  pgtype.Int4
-	Limit   pgtype.Int4
+	AppID          int64
+	GuildID        int64
+	ExcludeTainted bool
+	After          pgtype.Int8
+	Offset         pgtype.Int4
+	Limit          pgtype.Int4
 }
 
 func (q *Queries) GetGuildChannels(ctx context.Context, arg GetGuildChannelsParams) ([]CacheChannel, error) {
 	rows, err := q.db.Query(ctx, getGuildChannels,
 		arg.AppID,
 		arg.GuildID,
+		arg.ExcludeTainted,
+		arg.After,
 		arg.Offset,
 		arg.Limit,
 	)
@@ -229,15 +364,17 @@ func (q *Queries) GetGuildChannels(ctx context.Context, arg GetGuildChannelsPara
 }
 
 const getGuildChannelsByType = `-- name: GetGuildChannelsByType :many
-SELECT app_id, guild_id, channel_id, data, tainted, created_at, updated_at FROM cache.channels WHERE app_id = $1 AND guild_id = $2 AND (data->>'type')::INT = ANY($3::INT[]) ORDER BY channel_id LIMIT $5 OFFSET $4
+SELECT app_id, guild_id, channel_id, data, tainted, created_at, updated_at FROM cache.channels WHERE app_id = $1 AND guild_id = $2 AND (data->>'type')::INT = ANY($3::INT[]) AND (NOT $4::boolean OR NOT tainted) AND ($5::bigint IS NULL OR channel_id > $5) ORDER BY channel_id LIMIT $7 OFFSET $6
 `
 
 type GetGuildChannelsByTypeParams struct {
-	AppID   int64
-	GuildID int64
-	Types   []int32
-	Offset  pgtype.Int4
-	Limit   pgtype.Int4
+	AppID          int64
+	GuildID        int64
+	Types          []int32
+	ExcludeTainted bool
+	After          pgtype.Int8
+	Offset         pgtype.Int4
+	Limit          pgtype.Int4
 }
 
 func (q *Queries) GetGuildChannelsByType(ctx context.Context, arg GetGuildChannelsByTypeParams) ([]CacheChannel, error) {
@@ -245,6 +382,8 @@ func (q *Queries) GetGuildChannelsByType(ctx context.Context, arg GetGuildChanne
 		arg.AppID,
 		arg.GuildID,
 		arg.Types,
+		arg.ExcludeTainted,
+		arg.After,
 		arg.Offset,
 		arg.Limit,
 	)
@@ -275,35 +414,47 @@ func (q *Queries) GetGuildChannelsByType(ctx context.Context, arg GetGuildChanne
 }
 
 const markShardChannelsTainted = `-- name: MarkShardChannelsTainted :exec
-UPDATE cache.channels SET tainted = TRUE WHERE app_id = $1 AND guild_id % $2 = $3
+UPDATE cache.channels SET tainted = TRUE WHERE app_id = $1 AND (guild_id >> 22) % $2 = $3 AND (guild_id >> 22) % $4 = $5
 `
 
 type MarkShardChannelsTaintedParams struct {
-	AppID      int64
-	ShardCount int64
-	ShardID    int64
+	AppID          int64
+	ShardCount     int64
+	ShardID        int64
+	PartitionCount int64
+	PartitionID    int64
 }
 
 func (q *Queries) MarkShardChannelsTainted(ctx context.Context, arg MarkShardChannelsTaintedParams) error {
-	_, err := q.db.Exec(ctx, markShardChannelsTainted, arg.AppID, arg.ShardCount, arg.ShardID)
+	_, err := q.db.Exec(ctx, markShardChannelsTainted,
+		arg.AppID,
+		arg.ShardCount,
+		arg.ShardID,
+		arg.PartitionCount,
+		arg.PartitionID,
+	)
 	return err
 }
 
 const searchChannels = `-- name: SearchChannels :many
-SELECT app_id, guild_id, channel_id, data, tainted, created_at, updated_at FROM cache.channels WHERE app_id = $1 AND data @> $2 ORDER BY channel_id LIMIT $4 OFFSET $3
+SELECT app_id, guild_id, channel_id, data, tainted, created_at, updated_at FROM cache.channels WHERE app_id = $1 AND data @> $2 AND (NOT $3::boolean OR NOT tainted) AND ($4::bigint IS NULL OR channel_id > $4) ORDER BY channel_id LIMIT $6 OFFSET $5
 `
 
 type SearchChannelsParams struct {
-	AppID  int64
-	Data   []byte
-	Offset pgtype.Int4
-	Limit  pgtype.Int4
+	AppID          int64
+	Data           []byte
+	ExcludeTainted bool
+	After          pgtype.Int8
+	Offset         pgtype.Int4
+	Limit          pgtype.Int4
 }
 
 func (q *Queries) SearchChannels(ctx context.Context, arg SearchChannelsParams) ([]CacheChannel, error) {
 	rows, err := q.db.Query(ctx, searchChannels,
 		arg.AppID,
 		arg.Data,
+		arg.ExcludeTainted,
+		arg.After,
 		arg.Offset,
 		arg.Limit,
 	)
@@ -334,15 +485,17 @@ func (q *Queries) SearchChannels(ctx context.Context, arg SearchChannelsParams)
 }
 
 const searchGuildChannels = `-- name: SearchGuildChannels :many
-SELECT app_id, guild_id, channel_id, data, tainted, created_at, updated_at FROM cache.channels WHERE app_id = $1 AND guild_id = $2 AND data @> $3 ORDER BY channel_id LIMIT $5 OFFSET $4
+SELECT app_id, guild_id, channel_id, data, tainted, created_at, updated_at FROM cache.channels WHERE app_id = $1 AND guild_id = $2 AND data @> $3 AND (NOT $4::boolean OR NOT tainted) AND ($5::bigint IS NULL OR channel_id > $5) ORDER BY channel_id LIMIT $7 OFFSET $6
 `
 
 type SearchGuildChannelsParams struct {
-	AppID   int64
-	GuildID int64
-	Data    []byte
-	Offset  pgtype.Int4
-	Limit   pgtype.Int4
+	AppID          int64
+	GuildID        int64
+	Data           []byte
+	ExcludeTainted bool
+	After          pgtype.Int8
+	Offset         pgtype.Int4
+	Limit          pgtype.Int4
 }
 
 func (q *Queries) SearchGuildChannels(ctx context.Context, arg SearchGuildChannelsParams) ([]CacheChannel, error) {
@@ -350,6 +503,8 @@ func (q *Queries) SearchGuildChannels(ctx context.Context, arg SearchGuildChanne
 		arg.AppID,
 		arg.GuildID,
 		arg.Data,
+		arg.ExcludeTainted,
+		arg.After,
 		arg.Offset,
 		arg.Limit,
 	)
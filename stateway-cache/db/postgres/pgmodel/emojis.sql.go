@@ -38,6 +38,25 @@ func (q *Queries) CountGuildEmojis(ctx context.Context, arg CountGuildEmojisPara
 	return count, err
 }
 
+const deleteAppEmojis = `-- name: DeleteAppEmojis :execrows
+DELETE FROM cache.emojis WHERE (app_id, guild_id, emoji_id) IN (
+    SELECT app_id, guild_id, emoji_id FROM cache.emojis WHERE app_id = $1 LIMIT $2
+)
+`
+
+type DeleteAppEmojisParams struct {
+	AppID     int64
+	BatchSize int32
+}
+
+func (q *Queries) DeleteAppEmojis(ctx context.Context, arg DeleteAppEmojisParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteAppEmojis, arg.AppID, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteEmoji = `-- name: DeleteEmoji :exec
 DELETE FROM cache.emojis WHERE app_id = $1 AND guild_id = $2 AND emoji_id = $3
 `
@@ -53,6 +72,54 @@ func (q *Queries) DeleteEmoji(ctx context.Context, arg DeleteEmojiParams) error
 	return err
 }
 
+const deleteGuildEmojisExcept = `-- name: DeleteGuildEmojisExcept :exec
+DELETE FROM cache.emojis WHERE app_id = $1 AND guild_id = $2 AND NOT (emoji_id = ANY($3::bigint[])) AND updated_at <= $4
+`
+
+type DeleteGuildEmojisExceptParams struct {
+	AppID     int64
+	GuildID   int64
+	EmojiIds  []int64
+	UpdatedAt pgtype.Timestamp
+}
+
+func (q *Queries) DeleteGuildEmojisExcept(ctx context.Context, arg DeleteGuildEmojisExceptParams) error {
+	_, err := q.db.Exec(ctx, deleteGuildEmojisExcept,
+		arg.AppID,
+		arg.GuildID,
+		arg.EmojiIds,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
+const deleteShardTaintedEmojis = `-- name: DeleteShardTaintedEmojis :exec
+DELETE FROM cache.emojis WHERE app_id = $1 AND (guild_id >> 22) % $2 = $3 AND NOT (guild_id = ANY($4::bigint[])) AND (guild_id >> 22) % $5 = $6 AND (
+    tainted OR guild_id IN (SELECT g.guild_id FROM cache.guilds g WHERE g.app_id = $1 AND g.tainted)
+)
+`
+
+type DeleteShardTaintedEmojisParams struct {
+	AppID          int64
+	ShardCount     int64
+	ShardID        int64
+	KeepGuildIds   []int64
+	PartitionCount int64
+	PartitionID    int64
+}
+
+func (q *Queries) DeleteShardTaintedEmojis(ctx context.Context, arg DeleteShardTaintedEmojisParams) error {
+	_, err := q.db.Exec(ctx, deleteShardTaintedEmojis,
+		arg.AppID,
+		arg.ShardCount,
+		arg.ShardID,
+		arg.KeepGuildIds,
+		arg.PartitionCount,
+		arg.PartitionID,
+	)
+	return err
+}
+
 const getEmoji = `-- name: GetEmoji :one
 SELECT app_id, guild_id, emoji_id, data, tainted, created_at, updated_at FROM cache.emojis WHERE app_id = $1 AND emoji_id = $2 LIMIT 1
 `
@@ -77,18 +144,99 @@ func (q *Queries) GetEmoji(ctx context.Context, arg GetEmojiParams) (CacheEmoji,
 	return i, err
 }
 
+const getEmojiStats = `-- name: GetEmojiStats :many
+SELECT app_id, COUNT(*) AS count, COUNT(*) FILTER (WHERE tainted) AS tainted, MIN(updated_at)::timestamp AS oldest_updated_at FROM cache.emojis WHERE ($1::bigint IS NULL OR app_id = $1) GROUP BY app_id
+`
+
+type GetEmojiStatsRow struct {
+	AppID           int64
+	Count           int64
+	Tainted         int64
+	OldestUpdatedAt pgtype.Timestamp
+}
+
+func (q *Queries) GetEmojiStats(ctx context.Context, appID pgtype.Int8) ([]GetEmojiStatsRow, error) {
+	rows, err := q.db.Query(ctx, getEmojiStats, appID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEmojiStatsRow
+	for rows.Next() {
+		var i GetEmojiStatsRow
+		if err := rows.Scan(
+			&i.AppID,
+			&i.Count,
+			&i.Tainted,
+			&i.OldestUpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getEmojis = `-- name: GetEmojis :many
-SELECT app_id, guild_id, emoji_id, data, tainted, created_at, updated_at FROM cache.emojis WHERE app_id = $1 ORDER BY emoji_id LIMIT $3 OFFSET $2
+SELECT app_id, guild_id, emoji_id, data, tainted, created_at, updated_at FROM cache.emojis WHERE app_id = $1 AND (NOT $2::boolean OR NOT tainted) AND ($3::bigint IS NULL OR emoji_id > $3) ORDER BY emoji_id LIMIT $5 OFFSET $4
 `
 
 type GetEmojisParams struct {
-	AppID  int64
-	Offset pgtype.Int4
-	Limit  pgtype.Int4
+	AppID          int64
+	ExcludeTainted bool
+	After          pgtype.Int8
+	Offset         pgtype.Int4
+	Limit          pgtype.Int4
 }
 
 func (q *Queries) GetEmojis(ctx context.Context, arg GetEmojisParams) ([]CacheEmoji, error) {
-	rows, err := q.db.Query(ctx, getEmojis, arg.AppID, arg.Offset, arg.Limit)
+	rows, err := q.db.Query(ctx, getEmojis,
+		arg.AppID,
+		arg.ExcludeTainted,
+		arg.After,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CacheEmoji
+	for rows.Next() {
+		var i CacheEmoji
+		if err := rows.Scan(
+			&i.AppID,
+			&i.GuildID,
+			&i.EmojiID,
+			&i.Data,
+			&i.Tainted,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEmojisByIDs = `-- name: GetEmojisByIDs :many
+SELECT app_id, guild_id, emoji_id, data, tainted, created_at, updated_at FROM cache.emojis WHERE app_id = $1 AND emoji_id = ANY($2::bigint[])
+`
+
+type GetEmojisByIDsParams struct {
+	AppID    int64
+	EmojiIds []int64
+}
+
+func (q *Queries) GetEmojisByIDs(ctx context.Context, arg GetEmojisByIDsParams) ([]CacheEmoji, error) {
+	rows, err := q.db.Query(ctx, getEmojisByIDs, arg.AppID, arg.EmojiIds)
 	if err != nil {
 		return nil, err
 	}
@@ -141,20 +289,24 @@ func (q *Queries) GetGuildEmoji(ctx context.Context, arg GetGuildEmojiParams) (C
 }
 
 const getGuildEmojis = `-- name: GetGuildEmojis :many
-SELECT app_id, guild_id, emoji_id, data, tainted, created_at, updated_at FROM cache.emojis WHERE app_id = $1 AND guild_id = $2 ORDER BY emoji_id LIMIT $4 OFFSET $3
+SELECT app_id, guild_id, emoji_id, data, tainted, created_at, updated_at FROM cache.emojis WHERE app_id = $1 AND guild_id = $2 AND (NOT $3::boolean OR NOT tainted) AND ($4::bigint IS NULL OR emoji_id > $4) ORDER BY emoji_id LIMIT $6 OFFSET $5
 `
 
 type GetGuildEmojisParams struct {
-	AppID   int64
-	GuildID int64
-	Offset  pgtype.Int4
-	Limit   pgtype.Int4
+	AppID          int64
+	GuildID        int64
+	ExcludeTainted bool
+	After          pgtype.Int8
+	Offset         pgtype.Int4
+	Limit          pgtype.Int4
 }
 
 func (q *Queries) GetGuildEmojis(ctx context.Context, arg GetGuildEmojisParams) ([]CacheEmoji, error) {
 	rows, err := q.db.Query(ctx, getGuildEmojis,
 		arg.AppID,
 		arg.GuildID,
+		arg.ExcludeTainted,
+		arg.After,
 		arg.Offset,
 		arg.Limit,
 	)
@@ -185,35 +337,47 @@ func (q *Queries) GetGuildEmojis(ctx context.Context, arg GetGuildEmojisParams)
 }
 
 const markShardEmojisTainted = `-- name: MarkShardEmojisTainted :exec
-UPDATE cache.emojis SET tainted = TRUE WHERE app_id = $1 AND guild_id % $2 = $3
+UPDATE cache.emojis SET tainted = TRUE WHERE app_id = $1 AND (guild_id >> 22) % $2 = $3 AND (guild_id >> 22) % $4 = $5
 `
 
 type MarkShardEmojisTaintedParams struct {
-	AppID      int64
-	ShardCount int64
-	ShardID    int64
+	AppID          int64
+	ShardCount     int64
+	ShardID        int64
+	PartitionCount int64
+	PartitionID    int64
 }
 
 func (q *Queries) MarkShardEmojisTainted(ctx context.Context, arg MarkShardEmojisTaintedParams) error {
-	_, err := q.db.Exec(ctx, markShardEmojisTainted, arg.AppID, arg.ShardCount, arg.ShardID)
+	_, err := q.db.Exec(ctx, markShardEmojisTainted,
+		arg.AppID,
+		arg.ShardCount,
+		arg.ShardID,
+		arg.PartitionCount,
+		arg.PartitionID,
+	)
 	return err
 }
 
 const searchEmojis = `-- name: SearchEmojis :many
-SELECT app_id, guild_id, emoji_id, data, tainted, created_at, updated_at FROM cache.emojis WHERE app_id = $1 AND data @> $2 ORDER BY emoji_id LIMIT $4 OFFSET $3
+SELECT app_id, guild_id, emoji_id, data, tainted, created_at, updated_at FROM cache.emojis WHERE app_id = $1 AND data @> $2 AND (NOT $3::boolean OR NOT tainted) AND ($4::bigint IS NULL OR emoji_id > $4) ORDER BY emoji_id LIMIT $6 OFFSET $5
 `
 
 type SearchEmojisParams struct {
-	AppID  int64
-	Data   []byte
-	Offset pgtype.Int4
-	Limit  pgtype.Int4
+	AppID          int64
+	Data           []byte
+	ExcludeTainted bool
+	After          pgtype.Int8
+	Offset         pgtype.Int4
+	Limit          pgtype.Int4
 }
 
 func (q *Queries) SearchEmojis(ctx context.Context, arg SearchEmojisParams) ([]CacheEmoji, error) {
 	rows, err := q.db.Query(ctx, searchEmojis,
 		arg.AppID,
 		arg.Data,
+		arg.ExcludeTainted,
+		arg.After,
 		arg.Offset,
 		arg.Limit,
 	)
@@ -244,15 +408,17 @@ func (q *Queries) SearchEmojis(ctx context.Context, arg SearchEmojisParams) ([]C
 }
 
 const searchGuildEmojis = `-- name: SearchGuildEmojis :many
-SELECT app_id, guild_id, emoji_id, data, tainted, created_at, updated_at FROM cache.emojis WHERE app_id = $1 AND guild_id = $2 AND data @> $3 ORDER BY emoji_id LIMIT $5 OFFSET $4
+SELECT app_id, guild_id, emoji_id, data, tainted, created_at, updated_at FROM cache.emojis WHERE app_id = $1 AND guild_id = $2 AND data @> $3 AND (NOT $4::boolean OR NOT tainted) AND ($5::bigint IS NULL OR emoji_id > $5) ORDER BY emoji_id LIMIT $7 OFFSET $6
 `
 
 type SearchGuildEmojisParams struct {
-	AppID   int64
-	GuildID int64
-	Data    []byte
-	Offset  pgtype.Int4
-	Limit   pgtype.Int4
+	AppID          int64
+	GuildID        int64
+	Data           []byte
+	ExcludeTainted bool
+	After          pgtype.Int8
+	Offset         pgtype.Int4
+	Limit          pgtype.Int4
 }
 
 func (q *Queries) SearchGuildEmojis(ctx context.Context, arg SearchGuildEmojisParams) ([]CacheEmoji, error) {
@@ -260,6 +426,8 @@ func (q *Queries) SearchGuildEmojis(ctx context.Context, arg SearchGuildEmojisPa
 		arg.AppID,
 		arg.GuildID,
 		arg.Data,
+		arg.ExcludeTainted,
+		arg.After,
 		arg.Offset,
 		arg.Limit,
 	)
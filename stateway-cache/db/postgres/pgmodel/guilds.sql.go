@@ -27,6 +27,25 @@ func (q *Queries) CheckGuildExist(ctx context.Context, arg CheckGuildExistParams
 	return exists, err
 }
 
+const deleteAppGuilds = `-- name: DeleteAppGuilds :execrows
+DELETE FROM cache.guilds WHERE (app_id, guild_id) IN (
+    SELECT app_id, guild_id FROM cache.guilds WHERE app_id = $1 LIMIT $2
+)
+`
+
+type DeleteAppGuildsParams struct {
+	AppID     int64
+	BatchSize int32
+}
+
+func (q *Queries) DeleteAppGuilds(ctx context.Context, arg DeleteAppGuildsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteAppGuilds, arg.AppID, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteGuild = `-- name: DeleteGuild :exec
 DELETE FROM cache.guilds WHERE app_id = $1 AND guild_id = $2
 `
@@ -41,6 +60,31 @@ func (q *Queries) DeleteGuild(ctx context.Context, arg DeleteGuildParams) error
 	return err
 }
 
+const deleteShardTaintedGuilds = `-- name: DeleteShardTaintedGuilds :exec
+DELETE FROM cache.guilds WHERE app_id = $1 AND tainted AND (guild_id >> 22) % $2 = $3 AND NOT (guild_id = ANY($4::bigint[])) AND (guild_id >> 22) % $5 = $6
+`
+
+type DeleteShardTaintedGuildsParams struct {
+	AppID          int64
+	ShardCount     int64
+	ShardID        int64
+	KeepGuildIds   []int64
+	PartitionCount int64
+	PartitionID    int64
+}
+
+func (q *Queries) DeleteShardTaintedGuilds(ctx context.Context, arg DeleteShardTaintedGuildsParams) error {
+	_, err := q.db.Exec(ctx, deleteShardTaintedGuilds,
+		arg.AppID,
+		arg.ShardCount,
+		arg.ShardID,
+		arg.KeepGuildIds,
+		arg.PartitionCount,
+		arg.PartitionID,
+	)
+	return err
+}
+
 const getGuild = `-- name: GetGuild :one
 SELECT app_id, guild_id, data, unavailable, tainted, created_at, updated_at FROM cache.guilds WHERE app_id = $1 AND guild_id = $2 LIMIT 1
 `
@@ -65,6 +109,42 @@ func (q *Queries) GetGuild(ctx context.Context, arg GetGuildParams) (CacheGuild,
 	return i, err
 }
 
+const getGuildApps = `-- name: GetGuildApps :many
+SELECT app_id, unavailable, tainted, updated_at FROM cache.guilds WHERE guild_id = $1 ORDER BY app_id
+`
+
+type GetGuildAppsRow struct {
+	AppID       int64
+	Unavailable bool
+	Tainted     bool
+	UpdatedAt   pgtype.Timestamp
+}
+
+func (q *Queries) GetGuildApps(ctx context.Context, guildID int64) ([]GetGuildAppsRow, error) {
+	rows, err := q.db.Query(ctx, getGuildApps, guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetGuildAppsRow
+	for rows.Next() {
+		var i GetGuildAppsRow
+		if err := rows.Scan(
+			&i.AppID,
+			&i.Unavailable,
+			&i.Tainted,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getGuildOwnerID = `-- name: GetGuildOwnerID :one
 SELECT (data->>'owner_id')::bigint FROM cache.guilds WHERE app_id = $1 AND guild_id = $2 LIMIT 1
 `
@@ -81,18 +161,101 @@ func (q *Queries) GetGuildOwnerID(ctx context.Context, arg GetGuildOwnerIDParams
 	return column_1, err
 }
 
+const getGuildStats = `-- name: GetGuildStats :many
+SELECT app_id, COUNT(*) AS count, COUNT(*) FILTER (WHERE tainted) AS tainted, COUNT(*) FILTER (WHERE unavailable) AS unavailable, MIN(updated_at)::timestamp AS oldest_updated_at FROM cache.guilds WHERE ($1::bigint IS NULL OR app_id = $1) GROUP BY app_id
+`
+
+type GetGuildStatsRow struct {
+	AppID           int64
+	Count           int64
+	Tainted         int64
+	Unavailable     int64
+	OldestUpdatedAt pgtype.Timestamp
+}
+
+func (q *Queries) GetGuildStats(ctx context.Context, appID pgtype.Int8) ([]GetGuildStatsRow, error) {
+	rows, err := q.db.Query(ctx, getGuildStats, appID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetGuildStatsRow
+	for rows.Next() {
+		var i GetGuildStatsRow
+		if err := rows.Scan(
+			&i.AppID,
+			&i.Count,
+			&i.Tainted,
+			&i.Unavailable,
+			&i.OldestUpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getGuilds = `-- name: GetGuilds :many
-SELECT app_id, guild_id, data, unavailable, tainted, created_at, updated_at FROM cache.guilds WHERE app_id = $1 ORDER BY guild_id LIMIT $3 OFFSET $2
+SELECT app_id, guild_id, data, unavailable, tainted, created_at, updated_at FROM cache.guilds WHERE app_id = $1 AND (NOT $2::boolean OR NOT tainted) AND ($3::bigint IS NULL OR guild_id > $3) ORDER BY guild_id LIMIT $5 OFFSET $4
 `
 
 type GetGuildsParams struct {
-	AppID  int64
-	Offset pgtype.Int4
-	Limit  pgtype.Int4
+	AppID          int64
+	ExcludeTainted bool
+	After          pgtype.Int8
+	Offset         pgtype.Int4
+	Limit          pgtype.Int4
 }
 
 func (q *Queries) GetGuilds(ctx context.Context, arg GetGuildsParams) ([]CacheGuild, error) {
-	rows, err := q.db.Query(ctx, getGuilds, arg.AppID, arg.Offset, arg.Limit)
+	rows, err := q.db.Query(ctx, getGuilds,
+		arg.AppID,
+		arg.ExcludeTainted,
+		arg.After,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CacheGuild
+	for rows.Next() {
+		var i CacheGuild
+		if err := rows.Scan(
+			&i.AppID,
+			&i.GuildID,
+			&i.Data,
+			&i.Unavailable,
+			&i.Tainted,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGuildsByIDs = `-- name: GetGuildsByIDs :many
+SELECT app_id, guild_id, data, unavailable, tainted, created_at, updated_at FROM cache.guilds WHERE app_id = $1 AND guild_id = ANY($2::bigint[])
+`
+
+type GetGuildsByIDsParams struct {
+	AppID    int64
+	GuildIds []int64
+}
+
+func (q *Queries) GetGuildsByIDs(ctx context.Context, arg GetGuildsByIDsParams) ([]CacheGuild, error) {
+	rows, err := q.db.Query(ctx, getGuildsByIDs, arg.AppID, arg.GuildIds)
 	if err != nil {
 		return nil, err
 	}
@@ -134,35 +297,47 @@ func (q *Queries) MarkGuildUnavailable(ctx context.Context, arg MarkGuildUnavail
 }
 
 const markShardGuildsTainted = `-- name: MarkShardGuildsTainted :exec
-UPDATE cache.guilds SET tainted = TRUE WHERE app_id = $1 AND guild_id % $2 = $3
+UPDATE cache.guilds SET tainted = TRUE WHERE app_id = $1 AND (guild_id >> 22) % $2 = $3 AND (guild_id >> 22) % $4 = $5
 `
 
 type MarkShardGuildsTaintedParams struct {
-	AppID      int64
-	ShardCount int64
-	ShardID    int64
+	AppID          int64
+	ShardCount     int64
+	ShardID        int64
+	PartitionCount int64
+	PartitionID    int64
 }
 
 func (q *Queries) MarkShardGuildsTainted(ctx context.Context, arg MarkShardGuildsTaintedParams) error {
-	_, err := q.db.Exec(ctx, markShardGuildsTainted, arg.AppID, arg.ShardCount, arg.ShardID)
+	_, err := q.db.Exec(ctx, markShardGuildsTainted,
+		arg.AppID,
+		arg.ShardCount,
+		arg.ShardID,
+		arg.PartitionCount,
+		arg.PartitionID,
+	)
 	return err
 }
 
 const searchGuilds = `-- name: SearchGuilds :many
-SELECT app_id, guild_id, data, unavailable, tainted, created_at, updated_at FROM cache.guilds WHERE app_id = $1 AND data @> $2 ORDER BY guild_id LIMIT $4 OFFSET $3
+SELECT app_id, guild_id, data, unavailable, tainted, created_at, updated_at FROM cache.guilds WHERE app_id = $1 AND data @> $2 AND (NOT $3::boolean OR NOT tainted) AND ($4::bigint IS NULL OR guild_id > $4) ORDER BY guild_id LIMIT $6 OFFSET $5
 `
 
 type SearchGuildsParams struct {
-	AppID  int64
-	Data   []byte
-	Offset pgtype.Int4
-	Limit  pgtype.Int4
+	AppID          int64
+	Data           []byte
+	ExcludeTainted bool
+	After          pgtype.Int8
+	Offset         pgtype.Int4
+	Limit          pgtype.Int4
 }
 
 func (q *Queries) SearchGuilds(ctx context.Context, arg SearchGuildsParams) ([]CacheGuild, error) {
 	rows, err := q.db.Query(ctx, searchGuilds,
 		arg.AppID,
 		arg.Data,
+		arg.ExcludeTainted,
+		arg.After,
 		arg.Offset,
 		arg.Limit,
 	)
@@ -38,6 +38,13 @@ type CacheGuild struct {
 	UpdatedAt   pgtype.Timestamp
 }
 
+type CacheGuildCollection struct {
+	AppID      int64
+	GuildID    int64
+	Collection string
+	UpdatedAt  pgtype.Timestamp
+}
+
 type CacheRole struct {
 	AppID     int64
 	GuildID   int64
@@ -38,6 +38,25 @@ func (q *Queries) CountRoles(ctx context.Context, appID int64) (int64, error) {
 	return count, err
 }
 
+const deleteAppRoles = `-- name: DeleteAppRoles :execrows
+DELETE FROM cache.roles WHERE (app_id, guild_id, role_id) IN (
+    SELECT app_id, guild_id, role_id FROM cache.roles WHERE app_id = $1 LIMIT $2
+)
+`
+
+type DeleteAppRolesParams struct {
+	AppID     int64
+	BatchSize int32
+}
+
+func (q *Queries) DeleteAppRoles(ctx context.Context, arg DeleteAppRolesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteAppRoles, arg.AppID, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteRole = `-- name: DeleteRole :exec
 DELETE FROM cache.roles WHERE app_id = $1 AND guild_id = $2 AND role_id = $3
 `
@@ -53,6 +72,33 @@ func (q *Queries) DeleteRole(ctx context.Context, arg DeleteRoleParams) error {
 	return err
 }
 
+const deleteShardTaintedRoles = `-- name: DeleteShardTaintedRoles :exec
+DELETE FROM cache.roles WHERE app_id = $1 AND (guild_id >> 22) % $2 = $3 AND NOT (guild_id = ANY($4::bigint[])) AND (guild_id >> 22) % $5 = $6 AND (
+    tainted OR guild_id IN (SELECT g.guild_id FROM cache.guilds g WHERE g.app_id = $1 AND g.tainted)
+)
+`
+
+type DeleteShardTaintedRolesParams struct {
+	AppID          int64
+	ShardCount     int64
+	ShardID        int64
+	KeepGuildIds   []int64
+	PartitionCount int64
+	PartitionID    int64
+}
+
+func (q *Queries) DeleteShardTaintedRoles(ctx context.Context, arg DeleteShardTaintedRolesParams) error {
+	_, err := q.db.Exec(ctx, deleteShardTaintedRoles,
+		arg.AppID,
+		arg.ShardCount,
+		arg.ShardID,
+		arg.KeepGuildIds,
+		arg.PartitionCount,
+		arg.PartitionID,
+	)
+	return err
+}
+
 const getGuildRole = `-- name: GetGuildRole :one
 SELECT app_id, guild_id, role_id, data, tainted, created_at, updated_at FROM cache.roles WHERE app_id = $1 AND guild_id = $2 AND role_id = $3 LIMIT 1
 `
@@ -79,20 +125,24 @@ func (q *Queries) GetGuildRole(ctx context.Context, arg GetGuildRoleParams) (Cac
 }
 
 const getGuildRoles = `-- name: GetGuildRoles :many
-SELECT app_id, guild_id, role_id, data, tainted, created_at, updated_at FROM cache.roles WHERE app_id = $1 AND guild_id = $2 ORDER BY role_id LIMIT $4 OFFSET $3
+SELECT app_id, guild_id, role_id, data, tainted, created_at, updated_at FROM cache.roles WHERE app_id = $1 AND guild_id = $2 AND (NOT $3::boolean OR NOT tainted) AND ($4::bigint IS NULL OR role_id > $4) ORDER BY role_id LIMIT $6 OFFSET $5
 `
 
 type GetGuildRolesParams struct {
-	AppID   int64
-	GuildID int64
-	Offset  pgtype.Int4
-	Limit   pgtype.Int4
+	AppID          int64
+	GuildID        int64
+	ExcludeTainted bool
+	After          pgtype.Int8
+	Offset         pgtype.Int4
+	Limit          pgtype.Int4
 }
 
 func (q *Queries) GetGuildRoles(ctx context.Context, arg GetGuildRolesParams) ([]CacheRole, error) {
 	rows, err := q.db.Query(ctx, getGuildRoles,
 		arg.AppID,
 		arg.GuildID,
+		arg.ExcludeTainted,
+		arg.After,
 		arg.Offset,
 		arg.Limit,
 	)
@@ -184,18 +234,99 @@ func (q *Queries) GetRole(ctx context.Context, arg GetRoleParams) (CacheRole, er
 	return i, err
 }
 
+const getRoleStats = `-- name: GetRoleStats :many
+SELECT app_id, COUNT(*) AS count, COUNT(*) FILTER (WHERE tainted) AS tainted, MIN(updated_at)::timestamp AS oldest_updated_at FROM cache.roles WHERE ($1::bigint IS NULL OR app_id = $1) GROUP BY app_id
+`
+
+type GetRoleStatsRow struct {
+	AppID           int64
+	Count           int64
+	Tainted         int64
+	OldestUpdatedAt pgtype.Timestamp
+}
+
+func (q *Queries) GetRoleStats(ctx context.Context, appID pgtype.Int8) ([]GetRoleStatsRow, error) {
+	rows, err := q.db.Query(ctx, getRoleStats, appID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRoleStatsRow
+	for rows.Next() {
+		var i GetRoleStatsRow
+		if err := rows.Scan(
+			&i.AppID,
+			&i.Count,
+			&i.Tainted,
+			&i.OldestUpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getRoles = `-- name: GetRoles :many
-SELECT app_id, guild_id, role_id, data, tainted, created_at, updated_at FROM cache.roles WHERE app_id = $1 ORDER BY role_id LIMIT $3 OFFSET $2
+SELECT app_id, guild_id, role_id, data, tainted, created_at, updated_at FROM cache.roles WHERE app_id = $1 AND (NOT $2::boolean OR NOT tainted) AND ($3::bigint IS NULL OR role_id > $3) ORDER BY role_id LIMIT $5 OFFSET $4
 `
 
 type GetRolesParams struct {
-	AppID  int64
-	Offset pgtype.Int4
-	Limit  pgtype.Int4
+	AppID          int64
+	ExcludeTainted bool
+	After          pgtype.Int8
+	Offset         pgtype.Int4
+	Limit          pgtype.Int4
 }
 
 func (q *Queries) GetRoles(ctx context.Context, arg GetRolesParams) ([]CacheRole, error) {
-	rows, err := q.db.Query(ctx, getRoles, arg.AppID, arg.Offset, arg.Limit)
+	rows, err := q.db.Query(ctx, getRoles,
+		arg.AppID,
+		arg.ExcludeTainted,
+		arg.After,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CacheRole
+	for rows.Next() {
+		var i CacheRole
+		if err := rows.Scan(
+			&i.AppID,
+			&i.GuildID,
+			&i.RoleID,
+			&i.Data,
+			&i.Tainted,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRolesByIDs = `-- name: GetRolesByIDs :many
+SELECT app_id, guild_id, role_id, data, tainted, created_at, updated_at FROM cache.roles WHERE app_id = $1 AND role_id = ANY($2::bigint[])
+`
+
+type GetRolesByIDsParams struct {
+	AppID   int64
+	RoleIds []int64
+}
+
+func (q *Queries) GetRolesByIDs(ctx context.Context, arg GetRolesByIDsParams) ([]CacheRole, error) {
+	rows, err := q.db.Query(ctx, getRolesByIDs, arg.AppID, arg.RoleIds)
 	if err != nil {
 		return nil, err
 	}
@@ -223,30 +354,40 @@ func (q *Queries) GetRoles(ctx context.Context, arg GetRolesParams) ([]CacheRole
 }
 
 const markShardRolesTainted = `-- name: MarkShardRolesTainted :exec
-UPDATE cache.roles SET tainted = TRUE WHERE app_id = $1 AND guild_id % $2 = $3
+UPDATE cache.roles SET tainted = TRUE WHERE app_id = $1 AND (guild_id >> 22) % $2 = $3 AND (guild_id >> 22) % $4 = $5
 `
 
 type MarkShardRolesTaintedParams struct {
-	AppID      int64
-	ShardCount int64
-	ShardID    int64
+	AppID          int64
+	ShardCount     int64
+	ShardID        int64
+	PartitionCount int64
+	PartitionID    int64
 }
 
 func (q *Queries) MarkShardRolesTainted(ctx context.Context, arg MarkShardRolesTaintedParams) error {
-	_, err := q.db.Exec(ctx, markShardRolesTainted, arg.AppID, arg.ShardCount, arg.ShardID)
+	_, err := q.db.Exec(ctx, markShardRolesTainted,
+		arg.AppID,
+		arg.ShardCount,
+		arg.ShardID,
+		arg.PartitionCount,
+		arg.PartitionID,
+	)
 	return err
 }
 
 const searchGuildRoles = `-- name: SearchGuildRoles :many
-SELECT app_id, guild_id, role_id, data, tainted, created_at, updated_at FROM cache.roles WHERE app_id = $1 AND guild_id = $2 AND data @> $3 ORDER BY role_id LIMIT $5 OFFSET $4
+SELECT app_id, guild_id, role_id, data, tainted, created_at, updated_at FROM cache.roles WHERE app_id = $1 AND guild_id = $2 AND data @> $3 AND (NOT $4::boolean OR NOT tainted) AND ($5::bigint IS NULL OR role_id > $5) ORDER BY role_id LIMIT $7 OFFSET $6
 `
 
 type SearchGuildRolesParams struct {
-	AppID   int64
-	GuildID int64
-	Data    []byte
-	Offset  pgtype.Int4
-	Limit   pgtype.Int4
+	AppID          int64
+	GuildID        int64
+	Data           []byte
+	ExcludeTainted bool
+	After          pgtype.Int8
+	Offset         pgtype.Int4
+	Limit          pgtype.Int4
 }
 
 func (q *Queries) SearchGuildRoles(ctx context.Context, arg SearchGuildRolesParams) ([]CacheRole, error) {
@@ -254,6 +395,8 @@ func (q *Queries) SearchGuildRoles(ctx context.Context, arg SearchGuildRolesPara
 		arg.AppID,
 		arg.GuildID,
 		arg.Data,
+		arg.ExcludeTainted,
+		arg.After,
 		arg.Offset,
 		arg.Limit,
 	)
@@ -284,20 +427,24 @@ func (q *Queries) SearchGuildRoles(ctx context.Context, arg SearchGuildRolesPara
 }
 
 const searchRoles = `-- name: SearchRoles :many
-SELECT app_id, guild_id, role_id, data, tainted, created_at, updated_at FROM cache.roles WHERE app_id = $1 AND data @> $2 ORDER BY role_id LIMIT $4 OFFSET $3
+SELECT app_id, guild_id, role_id, data, tainted, created_at, updated_at FROM cache.roles WHERE app_id = $1 AND data @> $2 AND (NOT $3::boolean OR NOT tainted) AND ($4::bigint IS NULL OR role_id > $4) ORDER BY role_id LIMIT $6 OFFSET $5
 `
 
 type SearchRolesParams struct {
-	AppID  int64
-	Data   []byte
-	Offset pgtype.Int4
-	Limit  pgtype.Int4
+	AppID          int64
+	Data           []byte
+	ExcludeTainted bool
+	After          pgtype.Int8
+	Offset         pgtype.Int4
+	Limit          pgtype.Int4
 }
 
 func (q *Queries) SearchRoles(ctx context.Context, arg SearchRolesParams) ([]CacheRole, error) {
 	rows, err := q.db.Query(ctx, searchRoles,
 		arg.AppID,
 		arg.Data,
+		arg.ExcludeTainted,
+		arg.After,
 		arg.Offset,
 		arg.Limit,
 	)
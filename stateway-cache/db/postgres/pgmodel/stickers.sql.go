@@ -38,6 +38,73 @@ func (q *Queries) CountStickers(ctx context.Context, appID int64) (int64, error)
 	return count, err
 }
 
+const deleteAppStickers = `-- name: DeleteAppStickers :execrows
+DELETE FROM cache.stickers WHERE (app_id, guild_id, sticker_id) IN (
+    SELECT app_id, guild_id, sticker_id FROM cache.stickers WHERE app_id = $1 LIMIT $2
+)
+`
+
+type DeleteAppStickersParams struct {
+	AppID     int64
+	BatchSize int32
+}
+
+func (q *Queries) DeleteAppStickers(ctx context.Context, arg DeleteAppStickersParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteAppStickers, arg.AppID, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteGuildStickersExcept = `-- name: DeleteGuildStickersExcept :exec
+DELETE FROM cache.stickers WHERE app_id = $1 AND guild_id = $2 AND NOT (sticker_id = ANY($3::bigint[])) AND updated_at <= $4
+`
+
+type DeleteGuildStickersExceptParams struct {
+	AppID      int64
+	GuildID    int64
+	StickerIds []int64
+	UpdatedAt  pgtype.Timestamp
+}
+
+func (q *Queries) DeleteGuildStickersExcept(ctx context.Context, arg DeleteGuildStickersExceptParams) error {
+	_, err := q.db.Exec(ctx, deleteGuildStickersExcept,
+		arg.AppID,
+		arg.GuildID,
+		arg.StickerIds,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
+const deleteShardTaintedStickers = `-- name: DeleteShardTaintedStickers :exec
+DELETE FROM cache.stickers WHERE app_id = $1 AND (guild_id >> 22) % $2 = $3 AND NOT (guild_id = ANY($4::bigint[])) AND (guild_id >> 22) % $5 = $6 AND (
+    tainted OR guild_id IN (SELECT g.guild_id FROM cache.guilds g WHERE g.app_id = $1 AND g.tainted)
+)
+`
+
+type DeleteShardTaintedStickersParams struct {
+	AppID          int64
+	ShardCount     int64
+	ShardID        int64
+	KeepGuildIds   []int64
+	PartitionCount int64
+	PartitionID    int64
+}
+
+func (q *Queries) DeleteShardTaintedStickers(ctx context.Context, arg DeleteShardTaintedStickersParams) error {
+	_, err := q.db.Exec(ctx, deleteShardTaintedStickers,
+		arg.AppID,
+		arg.ShardCount,
+		arg.ShardID,
+		arg.KeepGuildIds,
+		arg.PartitionCount,
+		arg.PartitionID,
+	)
+	return err
+}
+
 const deleteSticker = `-- name: DeleteSticker :exec
 DELETE FROM cache.stickers WHERE app_id = $1 AND guild_id = $2 AND sticker_id = $3
 `
@@ -79,20 +146,24 @@ func (q *Queries) GetGuildSticker(ctx context.Context, arg GetGuildStickerParams
 }
 
 const getGuildStickers = `-- name: GetGuildStickers :many
-SELECT app_id, guild_id, sticker_id, data, tainted, created_at, updated_at FROM cache.stickers WHERE app_id = $1 AND guild_id = $2 ORDER BY sticker_id LIMIT $4 OFFSET $3
+SELECT app_id, guild_id, sticker_id, data, tainted, created_at, updated_at FROM cache.stickers WHERE app_id = $1 AND guild_id = $2 AND (NOT $3::boolean OR NOT tainted) AND ($4::bigint IS NULL OR sticker_id > $4) ORDER BY sticker_id LIMIT $6 OFFSET $5
 `
 
 type GetGuildStickersParams struct {
-	AppID   int64
-	GuildID int64
-	Offset  pgtype.Int4
-	Limit   pgtype.Int4
+	AppID          int64
+	GuildID        int64
+	ExcludeTainted bool
+	After          pgtype.Int8
+	Offset         pgtype.Int4
+	Limit          pgtype.Int4
 }
 
 func (q *Queries) GetGuildStickers(ctx context.Context, arg GetGuildStickersParams) ([]CacheSticker, error) {
 	rows, err := q.db.Query(ctx, getGuildStickers,
 		arg.AppID,
 		arg.GuildID,
+		arg.ExcludeTainted,
+		arg.After,
 		arg.Offset,
 		arg.Limit,
 	)
@@ -146,18 +217,99 @@ func (q *Queries) GetSticker(ctx context.Context, arg GetStickerParams) (CacheSt
 	return i, err
 }
 
+const getStickerStats = `-- name: GetStickerStats :many
+SELECT app_id, COUNT(*) AS count, COUNT(*) FILTER (WHERE tainted) AS tainted, MIN(updated_at)::timestamp AS oldest_updated_at FROM cache.stickers WHERE ($1::bigint IS NULL OR app_id = $1) GROUP BY app_id
+`
+
+type GetStickerStatsRow struct {
+	AppID           int64
+	Count           int64
+	Tainted         int64
+	OldestUpdatedAt pgtype.Timestamp
+}
+
+func (q *Queries) GetStickerStats(ctx context.Context, appID pgtype.Int8) ([]GetStickerStatsRow, error) {
+	rows, err := q.db.Query(ctx, getStickerStats, appID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetStickerStatsRow
+	for rows.Next() {
+		var i GetStickerStatsRow
+		if err := rows.Scan(
+			&i.AppID,
+			&i.Count,
+			&i.Tainted,
+			&i.OldestUpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getStickers = `-- name: GetStickers :many
-SELECT app_id, guild_id, sticker_id, data, tainted, created_at, updated_at FROM cache.stickers WHERE app_id = $1 ORDER BY sticker_id LIMIT $3 OFFSET $2
+SELECT app_id, guild_id, sticker_id, data, tainted, created_at, updated_at FROM cache.stickers WHERE app_id = $1 AND (NOT $2::boolean OR NOT tainted) AND ($3::bigint IS NULL OR sticker_id > $3) ORDER BY sticker_id LIMIT $5 OFFSET $4
 `
 
 type GetStickersParams struct {
-	AppID  int64
-	Offset pgtype.Int4
-	Limit  pgtype.Int4
+	AppID          int64
+	ExcludeTainted bool
+	After          pgtype.Int8
+	Offset         pgtype.Int4
+	Limit          pgtype.Int4
 }
 
 func (q *Queries) GetStickers(ctx context.Context, arg GetStickersParams) ([]CacheSticker, error) {
-	rows, err := q.db.Query(ctx, getStickers, arg.AppID, arg.Offset, arg.Limit)
+	rows, err := q.db.Query(ctx, getStickers,
+		arg.AppID,
+		arg.ExcludeTainted,
+		arg.After,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CacheSticker
+	for rows.Next() {
+		var i CacheSticker
+		if err := rows.Scan(
+			&i.AppID,
+			&i.GuildID,
+			&i.StickerID,
+			&i.Data,
+			&i.Tainted,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getStickersByIDs = `-- name: GetStickersByIDs :many
+SELECT app_id, guild_id, sticker_id, data, tainted, created_at, updated_at FROM cache.stickers WHERE app_id = $1 AND sticker_id = ANY($2::bigint[])
+`
+
+type GetStickersByIDsParams struct {
+	AppID      int64
+	StickerIds []int64
+}
+
+func (q *Queries) GetStickersByIDs(ctx context.Context, arg GetStickersByIDsParams) ([]CacheSticker, error) {
+	rows, err := q.db.Query(ctx, getStickersByIDs, arg.AppID, arg.StickerIds)
 	if err != nil {
 		return nil, err
 	}
@@ -185,30 +337,40 @@ func (q *Queries) GetStickers(ctx context.Context, arg GetStickersParams) ([]Cac
 }
 
 const markShardStickersTainted = `-- name: MarkShardStickersTainted :exec
-UPDATE cache.stickers SET tainted = TRUE WHERE app_id = $1 AND guild_id % $2 = $3
+UPDATE cache.stickers SET tainted = TRUE WHERE app_id = $1 AND (guild_id >> 22) % $2 = $3 AND (guild_id >> 22) % $4 = $5
 `
 
 type MarkShardStickersTaintedParams struct {
-	AppID      int64
-	ShardCount int64
-	ShardID    int64
+	AppID          int64
+	ShardCount     int64
+	ShardID        int64
+	PartitionCount int64
+	PartitionID    int64
 }
 
 func (q *Queries) MarkShardStickersTainted(ctx context.Context, arg MarkShardStickersTaintedParams) error {
-	_, err := q.db.Exec(ctx, markShardStickersTainted, arg.AppID, arg.ShardCount, arg.ShardID)
+	_, err := q.db.Exec(ctx, markShardStickersTainted,
+		arg.AppID,
+		arg.ShardCount,
+		arg.ShardID,
+		arg.PartitionCount,
+		arg.PartitionID,
+	)
 	return err
 }
 
 const searchGuildStickers = `-- name: SearchGuildStickers :many
-SELECT app_id, guild_id, sticker_id, data, tainted, created_at, updated_at FROM cache.stickers WHERE app_id = $1 AND guild_id = $2 AND data @> $3 ORDER BY sticker_id LIMIT $5 OFFSET $4
+SELECT app_id, guild_id, sticker_id, data, tainted, created_at, updated_at FROM cache.stickers WHERE app_id = $1 AND guild_id = $2 AND data @> $3 AND (NOT $4::boolean OR NOT tainted) AND ($5::bigint IS NULL OR sticker_id > $5) ORDER BY sticker_id LIMIT $7 OFFSET $6
 `
 
 type SearchGuildStickersParams struct {
-	AppID   int64
-	GuildID int64
-	Data    []byte
-	Offset  pgtype.Int4
-	Limit   pgtype.Int4
+	AppID          int64
+	GuildID        int64
+	Data           []byte
+	ExcludeTainted bool
+	After          pgtype.Int8
+	Offset         pgtype.Int4
+	Limit          pgtype.Int4
 }
 
 func (q *Queries) SearchGuildStickers(ctx context.Context, arg SearchGuildStickersParams) ([]CacheSticker, error) {
@@ -216,6 +378,8 @@ func (q *Queries) SearchGuildStickers(ctx context.Context, arg SearchGuildSticke
 		arg.AppID,
 		arg.GuildID,
 		arg.Data,
+		arg.ExcludeTainted,
+		arg.After,
 		arg.Offset,
 		arg.Limit,
 	)
@@ -246,20 +410,24 @@ func (q *Queries) SearchGuildStickers(ctx context.Context, arg SearchGuildSticke
 }
 
 const searchStickers = `-- name: SearchStickers :many
-SELECT app_id, guild_id, sticker_id, data, tainted, created_at, updated_at FROM cache.stickers WHERE app_id = $1 AND data @> $2 ORDER BY sticker_id LIMIT $4 OFFSET $3
+SELECT app_id, guild_id, sticker_id, data, tainted, created_at, updated_at FROM cache.stickers WHERE app_id = $1 AND data @> $2 AND (NOT $3::boolean OR NOT tainted) AND ($4::bigint IS NULL OR sticker_id > $4) ORDER BY sticker_id LIMIT $6 OFFSET $5
 `
 
 type SearchStickersParams struct {
-	AppID  int64
-	Data   []byte
-	Offset pgtype.Int4
-	Limit  pgtype.Int4
+	AppID          int64
+	Data           []byte
+	ExcludeTainted bool
+	After          pgtype.Int8
+	Offset         pgtype.Int4
+	Limit          pgtype.Int4
 }
 
 func (q *Queries) SearchStickers(ctx context.Context, arg SearchStickersParams) ([]CacheSticker, error) {
 	rows, err := q.db.Query(ctx, searchStickers,
 		arg.AppID,
 		arg.Data,
+		arg.ExcludeTainted,
+		arg.After,
 		arg.Offset,
 		arg.Limit,
 	)
@@ -5,10 +5,13 @@ SELECT * FROM cache.channels WHERE app_id = $1 AND guild_id = $2 AND channel_id
 SELECT * FROM cache.channels WHERE app_id = $1 AND channel_id = $2 LIMIT 1;
 
 -- name: GetGuildChannels :many
-SELECT * FROM cache.channels WHERE app_id = $1 AND guild_id = $2 ORDER BY channel_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+SELECT * FROM cache.channels WHERE app_id = $1 AND guild_id = $2 AND (NOT @exclude_tainted::boolean OR NOT tainted) AND (sqlc.narg('after')::bigint IS NULL OR channel_id > sqlc.narg('after')) ORDER BY channel_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
 
 -- name: GetChannels :many
-SELECT * FROM cache.channels WHERE app_id = $1 ORDER BY channel_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+SELECT * FROM cache.channels WHERE app_id = $1 AND (NOT @exclude_tainted::boolean OR NOT tainted) AND (sqlc.narg('after')::bigint IS NULL OR channel_id > sqlc.narg('after')) ORDER BY channel_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+
+-- name: GetChannelsByIDs :many
+SELECT * FROM cache.channels WHERE app_id = $1 AND channel_id = ANY(@channel_ids::bigint[]);
 
 -- name: CountGuildChannels :one
 SELECT COUNT(*) FROM cache.channels WHERE app_id = $1 AND guild_id = $2;
@@ -17,18 +20,18 @@ SELECT COUNT(*) FROM cache.channels WHERE app_id = $1 AND guild_id = $2;
 SELECT COUNT(*) FROM cache.channels WHERE app_id = $1;
 
 -- name: GetGuildChannelsByType :many
-SELECT * FROM cache.channels WHERE app_id = $1 AND guild_id = $2 AND (data->>'type')::INT = ANY(@types::INT[]) ORDER BY channel_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+SELECT * FROM cache.channels WHERE app_id = $1 AND guild_id = $2 AND (data->>'type')::INT = ANY(@types::INT[]) AND (NOT @exclude_tainted::boolean OR NOT tainted) AND (sqlc.narg('after')::bigint IS NULL OR channel_id > sqlc.narg('after')) ORDER BY channel_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
 
 -- name: GetChannelsByType :many
-SELECT * FROM cache.channels WHERE app_id = $1 AND (data->>'type')::INT = ANY(@types::INT[]) ORDER BY channel_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+SELECT * FROM cache.channels WHERE app_id = $1 AND (data->>'type')::INT = ANY(@types::INT[]) AND (NOT @exclude_tainted::boolean OR NOT tainted) AND (sqlc.narg('after')::bigint IS NULL OR channel_id > sqlc.narg('after')) ORDER BY channel_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
 
 -- name: SearchGuildChannels :many
-SELECT * FROM cache.channels WHERE app_id = $1 AND guild_id = $2 AND data @> $3 ORDER BY channel_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+SELECT * FROM cache.channels WHERE app_id = $1 AND guild_id = $2 AND data @> $3 AND (NOT @exclude_tainted::boolean OR NOT tainted) AND (sqlc.narg('after')::bigint IS NULL OR channel_id > sqlc.narg('after')) ORDER BY channel_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
 
 -- name: SearchChannels :many
-SELECT * FROM cache.channels WHERE app_id = $1 AND data @> $2 ORDER BY channel_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+SELECT * FROM cache.channels WHERE app_id = $1 AND data @> $2 AND (NOT @exclude_tainted::boolean OR NOT tainted) AND (sqlc.narg('after')::bigint IS NULL OR channel_id > sqlc.narg('after')) ORDER BY channel_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
 
--- name: UpsertChannels :batchexec
+-- name: UpsertChannels :batchone
 INSERT INTO cache.channels (
     app_id, 
     guild_id, 
@@ -40,10 +43,25 @@ INSERT INTO cache.channels (
 ON CONFLICT (app_id, guild_id, channel_id) DO UPDATE SET 
     data = EXCLUDED.data, 
     tainted = FALSE,
-    updated_at = EXCLUDED.updated_at;
+    updated_at = EXCLUDED.updated_at
+WHERE cache.channels.updated_at <= EXCLUDED.updated_at
+RETURNING channel_id;
 
 -- name: DeleteChannel :exec
 DELETE FROM cache.channels WHERE app_id = $1 AND guild_id = $2 AND channel_id = $3;
 
 -- name: MarkShardChannelsTainted :exec
-UPDATE cache.channels SET tainted = TRUE WHERE app_id = $1 AND guild_id % @shard_count = @shard_id;
+UPDATE cache.channels SET tainted = TRUE WHERE app_id = $1 AND (guild_id >> 22) % @shard_count = @shard_id AND (guild_id >> 22) % @partition_count = @partition_id;
+
+-- name: DeleteShardTaintedChannels :exec
+DELETE FROM cache.channels WHERE app_id = $1 AND (guild_id >> 22) % @shard_count = @shard_id AND NOT (guild_id = ANY(@keep_guild_ids::bigint[])) AND (guild_id >> 22) % @partition_count = @partition_id AND (
+    tainted OR guild_id IN (SELECT g.guild_id FROM cache.guilds g WHERE g.app_id = $1 AND g.tainted)
+);
+
+-- name: DeleteAppChannels :execrows
+DELETE FROM cache.channels WHERE (app_id, guild_id, channel_id) IN (
+    SELECT app_id, guild_id, channel_id FROM cache.channels WHERE app_id = $1 LIMIT @batch_size
+);
+
+-- name: GetChannelStats :many
+SELECT app_id, COUNT(*) AS count, COUNT(*) FILTER (WHERE tainted) AS tainted, MIN(updated_at)::timestamp AS oldest_updated_at FROM cache.channels WHERE (sqlc.narg('app_id')::bigint IS NULL OR app_id = sqlc.narg('app_id')) GROUP BY app_id;
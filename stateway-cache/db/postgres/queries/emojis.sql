@@ -5,16 +5,19 @@ SELECT * FROM cache.emojis WHERE app_id = $1 AND guild_id = $2 AND emoji_id = $3
 SELECT * FROM cache.emojis WHERE app_id = $1 AND emoji_id = $2 LIMIT 1;
 
 -- name: GetGuildEmojis :many
-SELECT * FROM cache.emojis WHERE app_id = $1 AND guild_id = $2 ORDER BY emoji_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+SELECT * FROM cache.emojis WHERE app_id = $1 AND guild_id = $2 AND (NOT @exclude_tainted::boolean OR NOT tainted) AND (sqlc.narg('after')::bigint IS NULL OR emoji_id > sqlc.narg('after')) ORDER BY emoji_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
 
 -- name: GetEmojis :many
-SELECT * FROM cache.emojis WHERE app_id = $1 ORDER BY emoji_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+SELECT * FROM cache.emojis WHERE app_id = $1 AND (NOT @exclude_tainted::boolean OR NOT tainted) AND (sqlc.narg('after')::bigint IS NULL OR emoji_id > sqlc.narg('after')) ORDER BY emoji_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+
+-- name: GetEmojisByIDs :many
+SELECT * FROM cache.emojis WHERE app_id = $1 AND emoji_id = ANY(@emoji_ids::bigint[]);
 
 -- name: SearchGuildEmojis :many
-SELECT * FROM cache.emojis WHERE app_id = $1 AND guild_id = $2 AND data @> $3 ORDER BY emoji_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+SELECT * FROM cache.emojis WHERE app_id = $1 AND guild_id = $2 AND data @> $3 AND (NOT @exclude_tainted::boolean OR NOT tainted) AND (sqlc.narg('after')::bigint IS NULL OR emoji_id > sqlc.narg('after')) ORDER BY emoji_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
 
 -- name: SearchEmojis :many
-SELECT * FROM cache.emojis WHERE app_id = $1 AND data @> $2 ORDER BY emoji_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+SELECT * FROM cache.emojis WHERE app_id = $1 AND data @> $2 AND (NOT @exclude_tainted::boolean OR NOT tainted) AND (sqlc.narg('after')::bigint IS NULL OR emoji_id > sqlc.narg('after')) ORDER BY emoji_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
 
 -- name: CountGuildEmojis :one
 SELECT COUNT(*) FROM cache.emojis WHERE app_id = $1 AND guild_id = $2;
@@ -22,7 +25,7 @@ SELECT COUNT(*) FROM cache.emojis WHERE app_id = $1 AND guild_id = $2;
 -- name: CountEmojis :one
 SELECT COUNT(*) FROM cache.emojis WHERE app_id = $1;
 
--- name: UpsertEmojis :batchexec
+-- name: UpsertEmojis :batchone
 INSERT INTO cache.emojis (
     app_id, 
     guild_id, 
@@ -34,10 +37,28 @@ INSERT INTO cache.emojis (
 ON CONFLICT (app_id, guild_id, emoji_id) DO UPDATE SET 
     data = EXCLUDED.data, 
     tainted = FALSE,
-    updated_at = EXCLUDED.updated_at;
+    updated_at = EXCLUDED.updated_at
+WHERE cache.emojis.updated_at <= EXCLUDED.updated_at
+RETURNING emoji_id;
 
 -- name: DeleteEmoji :exec
 DELETE FROM cache.emojis WHERE app_id = $1 AND guild_id = $2 AND emoji_id = $3;
 
+-- name: DeleteGuildEmojisExcept :exec
+DELETE FROM cache.emojis WHERE app_id = $1 AND guild_id = $2 AND NOT (emoji_id = ANY(@emoji_ids::bigint[])) AND updated_at <= @updated_at;
+
 -- name: MarkShardEmojisTainted :exec
-UPDATE cache.emojis SET tainted = TRUE WHERE app_id = $1 AND guild_id % @shard_count = @shard_id;
+UPDATE cache.emojis SET tainted = TRUE WHERE app_id = $1 AND (guild_id >> 22) % @shard_count = @shard_id AND (guild_id >> 22) % @partition_count = @partition_id;
+
+-- name: DeleteShardTaintedEmojis :exec
+DELETE FROM cache.emojis WHERE app_id = $1 AND (guild_id >> 22) % @shard_count = @shard_id AND NOT (guild_id = ANY(@keep_guild_ids::bigint[])) AND (guild_id >> 22) % @partition_count = @partition_id AND (
+    tainted OR guild_id IN (SELECT g.guild_id FROM cache.guilds g WHERE g.app_id = $1 AND g.tainted)
+);
+
+-- name: DeleteAppEmojis :execrows
+DELETE FROM cache.emojis WHERE (app_id, guild_id, emoji_id) IN (
+    SELECT app_id, guild_id, emoji_id FROM cache.emojis WHERE app_id = $1 LIMIT @batch_size
+);
+
+-- name: GetEmojiStats :many
+SELECT app_id, COUNT(*) AS count, COUNT(*) FILTER (WHERE tainted) AS tainted, MIN(updated_at)::timestamp AS oldest_updated_at FROM cache.emojis WHERE (sqlc.narg('app_id')::bigint IS NULL OR app_id = sqlc.narg('app_id')) GROUP BY app_id;
@@ -0,0 +1,16 @@
+-- name: UpsertGuildCollection :one
+INSERT INTO cache.guild_collections (
+    app_id,
+    guild_id,
+    collection,
+    updated_at
+) VALUES ($1, $2, $3, $4)
+ON CONFLICT (app_id, guild_id, collection) DO UPDATE SET
+    updated_at = EXCLUDED.updated_at
+WHERE cache.guild_collections.updated_at <= EXCLUDED.updated_at
+RETURNING updated_at;
+
+-- name: DeleteAppGuildCollections :execrows
+DELETE FROM cache.guild_collections WHERE (app_id, guild_id, collection) IN (
+    SELECT app_id, guild_id, collection FROM cache.guild_collections WHERE app_id = $1 LIMIT @batch_size
+);
@@ -1,19 +1,25 @@
 -- name: GetGuild :one
 SELECT * FROM cache.guilds WHERE app_id = $1 AND guild_id = $2 LIMIT 1;
 
+-- name: GetGuildApps :many
+SELECT app_id, unavailable, tainted, updated_at FROM cache.guilds WHERE guild_id = $1 ORDER BY app_id;
+
 -- name: GetGuildOwnerID :one
 SELECT (data->>'owner_id')::bigint FROM cache.guilds WHERE app_id = $1 AND guild_id = $2 LIMIT 1;
 
 -- name: GetGuilds :many
-SELECT * FROM cache.guilds WHERE app_id = $1 ORDER BY guild_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+SELECT * FROM cache.guilds WHERE app_id = $1 AND (NOT @exclude_tainted::boolean OR NOT tainted) AND (sqlc.narg('after')::bigint IS NULL OR guild_id > sqlc.narg('after')) ORDER BY guild_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+
+-- name: GetGuildsByIDs :many
+SELECT * FROM cache.guilds WHERE app_id = $1 AND guild_id = ANY(@guild_ids::bigint[]);
 
 -- name: CheckGuildExist :one
 SELECT EXISTS(SELECT 1 FROM cache.guilds WHERE app_id = $1 AND guild_id = $2) AS exists;
 
 -- name: SearchGuilds :many
-SELECT * FROM cache.guilds WHERE app_id = $1 AND data @> $2 ORDER BY guild_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+SELECT * FROM cache.guilds WHERE app_id = $1 AND data @> $2 AND (NOT @exclude_tainted::boolean OR NOT tainted) AND (sqlc.narg('after')::bigint IS NULL OR guild_id > sqlc.narg('after')) ORDER BY guild_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
 
--- name: UpsertGuilds :batchexec
+-- name: UpsertGuilds :batchone
 INSERT INTO cache.guilds (
     app_id, 
     guild_id, 
@@ -24,7 +30,9 @@ INSERT INTO cache.guilds (
 ON CONFLICT (app_id, guild_id) DO UPDATE SET 
     data = EXCLUDED.data, 
     tainted = FALSE,
-    updated_at = EXCLUDED.updated_at;
+    updated_at = EXCLUDED.updated_at
+WHERE cache.guilds.updated_at <= EXCLUDED.updated_at
+RETURNING guild_id;
 
 -- name: DeleteGuild :exec
 DELETE FROM cache.guilds WHERE app_id = $1 AND guild_id = $2;
@@ -33,4 +41,15 @@ DELETE FROM cache.guilds WHERE app_id = $1 AND guild_id = $2;
 UPDATE cache.guilds SET unavailable = TRUE WHERE app_id = $1 AND guild_id = $2;
 
 -- name: MarkShardGuildsTainted :exec
-UPDATE cache.guilds SET tainted = TRUE WHERE app_id = $1 AND guild_id % @shard_count = @shard_id;
+UPDATE cache.guilds SET tainted = TRUE WHERE app_id = $1 AND (guild_id >> 22) % @shard_count = @shard_id AND (guild_id >> 22) % @partition_count = @partition_id;
+
+-- name: DeleteShardTaintedGuilds :exec
+DELETE FROM cache.guilds WHERE app_id = $1 AND tainted AND (guild_id >> 22) % @shard_count = @shard_id AND NOT (guild_id = ANY(@keep_guild_ids::bigint[])) AND (guild_id >> 22) % @partition_count = @partition_id;
+
+-- name: DeleteAppGuilds :execrows
+DELETE FROM cache.guilds WHERE (app_id, guild_id) IN (
+    SELECT app_id, guild_id FROM cache.guilds WHERE app_id = $1 LIMIT @batch_size
+);
+
+-- name: GetGuildStats :many
+SELECT app_id, COUNT(*) AS count, COUNT(*) FILTER (WHERE tainted) AS tainted, COUNT(*) FILTER (WHERE unavailable) AS unavailable, MIN(updated_at)::timestamp AS oldest_updated_at FROM cache.guilds WHERE (sqlc.narg('app_id')::bigint IS NULL OR app_id = sqlc.narg('app_id')) GROUP BY app_id;
@@ -5,19 +5,22 @@ SELECT * FROM cache.roles WHERE app_id = $1 AND guild_id = $2 AND role_id = $3 L
 SELECT * FROM cache.roles WHERE app_id = $1 AND role_id = $2 LIMIT 1;
 
 -- name: GetGuildRoles :many
-SELECT * FROM cache.roles WHERE app_id = $1 AND guild_id = $2 ORDER BY role_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+SELECT * FROM cache.roles WHERE app_id = $1 AND guild_id = $2 AND (NOT @exclude_tainted::boolean OR NOT tainted) AND (sqlc.narg('after')::bigint IS NULL OR role_id > sqlc.narg('after')) ORDER BY role_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
 
 -- name: GetGuildRolesByIDs :many
 SELECT * FROM cache.roles WHERE app_id = $1 AND guild_id = $2 AND role_id = ANY(@role_ids::bigint[]) ORDER BY role_id;
 
 -- name: GetRoles :many
-SELECT * FROM cache.roles WHERE app_id = $1 ORDER BY role_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+SELECT * FROM cache.roles WHERE app_id = $1 AND (NOT @exclude_tainted::boolean OR NOT tainted) AND (sqlc.narg('after')::bigint IS NULL OR role_id > sqlc.narg('after')) ORDER BY role_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+
+-- name: GetRolesByIDs :many
+SELECT * FROM cache.roles WHERE app_id = $1 AND role_id = ANY(@role_ids::bigint[]);
 
 -- name: SearchGuildRoles :many
-SELECT * FROM cache.roles WHERE app_id = $1 AND guild_id = $2 AND data @> $3 ORDER BY role_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+SELECT * FROM cache.roles WHERE app_id = $1 AND guild_id = $2 AND data @> $3 AND (NOT @exclude_tainted::boolean OR NOT tainted) AND (sqlc.narg('after')::bigint IS NULL OR role_id > sqlc.narg('after')) ORDER BY role_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
 
 -- name: SearchRoles :many
-SELECT * FROM cache.roles WHERE app_id = $1 AND data @> $2 ORDER BY role_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+SELECT * FROM cache.roles WHERE app_id = $1 AND data @> $2 AND (NOT @exclude_tainted::boolean OR NOT tainted) AND (sqlc.narg('after')::bigint IS NULL OR role_id > sqlc.narg('after')) ORDER BY role_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
 
 -- name: CountGuildRoles :one
 SELECT COUNT(*) FROM cache.roles WHERE app_id = $1 AND guild_id = $2;
@@ -25,7 +28,7 @@ SELECT COUNT(*) FROM cache.roles WHERE app_id = $1 AND guild_id = $2;
 -- name: CountRoles :one
 SELECT COUNT(*) FROM cache.roles WHERE app_id = $1;
 
--- name: UpsertRoles :batchexec
+-- name: UpsertRoles :batchone
 INSERT INTO cache.roles (
     app_id, 
     guild_id, 
@@ -37,10 +40,25 @@ INSERT INTO cache.roles (
 ON CONFLICT (app_id, guild_id, role_id) DO UPDATE SET 
     data = EXCLUDED.data, 
     tainted = FALSE,
-    updated_at = EXCLUDED.updated_at;
+    updated_at = EXCLUDED.updated_at
+WHERE cache.roles.updated_at <= EXCLUDED.updated_at
+RETURNING role_id;
 
 -- name: DeleteRole :exec
 DELETE FROM cache.roles WHERE app_id = $1 AND guild_id = $2 AND role_id = $3;
 
 -- name: MarkShardRolesTainted :exec
-UPDATE cache.roles SET tainted = TRUE WHERE app_id = $1 AND guild_id % @shard_count = @shard_id;
+UPDATE cache.roles SET tainted = TRUE WHERE app_id = $1 AND (guild_id >> 22) % @shard_count = @shard_id AND (guild_id >> 22) % @partition_count = @partition_id;
+
+-- name: DeleteShardTaintedRoles :exec
+DELETE FROM cache.roles WHERE app_id = $1 AND (guild_id >> 22) % @shard_count = @shard_id AND NOT (guild_id = ANY(@keep_guild_ids::bigint[])) AND (guild_id >> 22) % @partition_count = @partition_id AND (
+    tainted OR guild_id IN (SELECT g.guild_id FROM cache.guilds g WHERE g.app_id = $1 AND g.tainted)
+);
+
+-- name: DeleteAppRoles :execrows
+DELETE FROM cache.roles WHERE (app_id, guild_id, role_id) IN (
+    SELECT app_id, guild_id, role_id FROM cache.roles WHERE app_id = $1 LIMIT @batch_size
+);
+
+-- name: GetRoleStats :many
+SELECT app_id, COUNT(*) AS count, COUNT(*) FILTER (WHERE tainted) AS tainted, MIN(updated_at)::timestamp AS oldest_updated_at FROM cache.roles WHERE (sqlc.narg('app_id')::bigint IS NULL OR app_id = sqlc.narg('app_id')) GROUP BY app_id;
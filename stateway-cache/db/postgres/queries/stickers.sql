@@ -5,16 +5,19 @@ SELECT * FROM cache.stickers WHERE app_id = $1 AND guild_id = $2 AND sticker_id
 SELECT * FROM cache.stickers WHERE app_id = $1 AND sticker_id = $2 LIMIT 1;
 
 -- name: GetGuildStickers :many
-SELECT * FROM cache.stickers WHERE app_id = $1 AND guild_id = $2 ORDER BY sticker_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+SELECT * FROM cache.stickers WHERE app_id = $1 AND guild_id = $2 AND (NOT @exclude_tainted::boolean OR NOT tainted) AND (sqlc.narg('after')::bigint IS NULL OR sticker_id > sqlc.narg('after')) ORDER BY sticker_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
 
 -- name: GetStickers :many
-SELECT * FROM cache.stickers WHERE app_id = $1 ORDER BY sticker_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+SELECT * FROM cache.stickers WHERE app_id = $1 AND (NOT @exclude_tainted::boolean OR NOT tainted) AND (sqlc.narg('after')::bigint IS NULL OR sticker_id > sqlc.narg('after')) ORDER BY sticker_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+
+-- name: GetStickersByIDs :many
+SELECT * FROM cache.stickers WHERE app_id = $1 AND sticker_id = ANY(@sticker_ids::bigint[]);
 
 -- name: SearchGuildStickers :many
-SELECT * FROM cache.stickers WHERE app_id = $1 AND guild_id = $2 AND data @> $3 ORDER BY sticker_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+SELECT * FROM cache.stickers WHERE app_id = $1 AND guild_id = $2 AND data @> $3 AND (NOT @exclude_tainted::boolean OR NOT tainted) AND (sqlc.narg('after')::bigint IS NULL OR sticker_id > sqlc.narg('after')) ORDER BY sticker_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
 
 -- name: SearchStickers :many
-SELECT * FROM cache.stickers WHERE app_id = $1 AND data @> $2 ORDER BY sticker_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
+SELECT * FROM cache.stickers WHERE app_id = $1 AND data @> $2 AND (NOT @exclude_tainted::boolean OR NOT tainted) AND (sqlc.narg('after')::bigint IS NULL OR sticker_id > sqlc.narg('after')) ORDER BY sticker_id LIMIT sqlc.narg('limit') OFFSET sqlc.narg('offset');
 
 -- name: CountGuildStickers :one
 SELECT COUNT(*) FROM cache.stickers WHERE app_id = $1 AND guild_id = $2;
@@ -22,7 +25,7 @@ SELECT COUNT(*) FROM cache.stickers WHERE app_id = $1 AND guild_id = $2;
 -- name: CountStickers :one
 SELECT COUNT(*) FROM cache.stickers WHERE app_id = $1;
 
--- name: UpsertStickers :batchexec
+-- name: UpsertStickers :batchone
 INSERT INTO cache.stickers (
     app_id, 
     guild_id, 
@@ -34,10 +37,28 @@ INSERT INTO cache.stickers (
 ON CONFLICT (app_id, guild_id, sticker_id) DO UPDATE SET 
     data = EXCLUDED.data, 
     tainted = FALSE,
-    updated_at = EXCLUDED.updated_at;
+    updated_at = EXCLUDED.updated_at
+WHERE cache.stickers.updated_at <= EXCLUDED.updated_at
+RETURNING sticker_id;
 
 -- name: DeleteSticker :exec
 DELETE FROM cache.stickers WHERE app_id = $1 AND guild_id = $2 AND sticker_id = $3;
 
+-- name: DeleteGuildStickersExcept :exec
+DELETE FROM cache.stickers WHERE app_id = $1 AND guild_id = $2 AND NOT (sticker_id = ANY(@sticker_ids::bigint[])) AND updated_at <= @updated_at;
+
 -- name: MarkShardStickersTainted :exec
-UPDATE cache.stickers SET tainted = TRUE WHERE app_id = $1 AND guild_id % @shard_count = @shard_id;
+UPDATE cache.stickers SET tainted = TRUE WHERE app_id = $1 AND (guild_id >> 22) % @shard_count = @shard_id AND (guild_id >> 22) % @partition_count = @partition_id;
+
+-- name: DeleteShardTaintedStickers :exec
+DELETE FROM cache.stickers WHERE app_id = $1 AND (guild_id >> 22) % @shard_count = @shard_id AND NOT (guild_id = ANY(@keep_guild_ids::bigint[])) AND (guild_id >> 22) % @partition_count = @partition_id AND (
+    tainted OR guild_id IN (SELECT g.guild_id FROM cache.guilds g WHERE g.app_id = $1 AND g.tainted)
+);
+
+-- name: DeleteAppStickers :execrows
+DELETE FROM cache.stickers WHERE (app_id, guild_id, sticker_id) IN (
+    SELECT app_id, guild_id, sticker_id FROM cache.stickers WHERE app_id = $1 LIMIT @batch_size
+);
+
+-- name: GetStickerStats :many
+SELECT app_id, COUNT(*) AS count, COUNT(*) FILTER (WHERE tainted) AS tainted, MIN(updated_at)::timestamp AS oldest_updated_at FROM cache.stickers WHERE (sqlc.narg('app_id')::bigint IS NULL OR app_id = sqlc.narg('app_id')) GROUP BY app_id;
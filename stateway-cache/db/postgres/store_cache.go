@@ -3,14 +3,20 @@ package postgres
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/merlinfuchs/stateway/stateway-cache/db/postgres/pgmodel"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
 	"github.com/merlinfuchs/stateway/stateway-cache/store"
 )
 
 func (c *Client) MarkShardEntitiesTainted(ctx context.Context, params store.MarkShardEntitiesTaintedParams) error {
+	partitionCount, partitionID := partitionArgs(params.PartitionCount, params.PartitionID)
+
 	tx, err := c.DB.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -19,45 +25,55 @@ func (c *Client) MarkShardEntitiesTainted(ctx context.Context, params store.Mark
 
 	q := c.Q.WithTx(tx)
 	err = q.MarkShardGuildsTainted(ctx, pgmodel.MarkShardGuildsTaintedParams{
-		AppID:      int64(params.AppID),
-		ShardCount: int64(params.ShardCount),
-		ShardID:    int64(params.ShardID),
+		AppID:          int64(params.AppID),
+		ShardCount:     int64(params.ShardCount),
+		ShardID:        int64(params.ShardID),
+		PartitionCount: partitionCount,
+		PartitionID:    partitionID,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to mark shard guilds tainted: %w", err)
 	}
 
 	err = q.MarkShardRolesTainted(ctx, pgmodel.MarkShardRolesTaintedParams{
-		AppID:      int64(params.AppID),
-		ShardCount: int64(params.ShardCount),
-		ShardID:    int64(params.ShardID),
+		AppID:          int64(params.AppID),
+		ShardCount:     int64(params.ShardCount),
+		ShardID:        int64(params.ShardID),
+		PartitionCount: partitionCount,
+		PartitionID:    partitionID,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to mark shard roles tainted: %w", err)
 	}
 
 	err = q.MarkShardChannelsTainted(ctx, pgmodel.MarkShardChannelsTaintedParams{
-		AppID:      int64(params.AppID),
-		ShardCount: int64(params.ShardCount),
-		ShardID:    int64(params.ShardID),
+		AppID:          int64(params.AppID),
+		ShardCount:     int64(params.ShardCount),
+		ShardID:        int64(params.ShardID),
+		PartitionCount: partitionCount,
+		PartitionID:    partitionID,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to mark shard channels tainted: %w", err)
 	}
 
 	err = q.MarkShardEmojisTainted(ctx, pgmodel.MarkShardEmojisTaintedParams{
-		AppID:      int64(params.AppID),
-		ShardCount: int64(params.ShardCount),
-		ShardID:    int64(params.ShardID),
+		AppID:          int64(params.AppID),
+		ShardCount:     int64(params.ShardCount),
+		ShardID:        int64(params.ShardID),
+		PartitionCount: partitionCount,
+		PartitionID:    partitionID,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to mark shard emojis tainted: %w", err)
 	}
 
 	err = q.MarkShardStickersTainted(ctx, pgmodel.MarkShardStickersTaintedParams{
-		AppID:      int64(params.AppID),
-		ShardCount: int64(params.ShardCount),
-		ShardID:    int64(params.ShardID),
+		AppID:          int64(params.AppID),
+		ShardCount:     int64(params.ShardCount),
+		ShardID:        int64(params.ShardID),
+		PartitionCount: partitionCount,
+		PartitionID:    partitionID,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to mark shard stickers tainted: %w", err)
@@ -71,7 +87,13 @@ func (c *Client) MarkShardEntitiesTainted(ctx context.Context, params store.Mark
 	return nil
 }
 
-func (c *Client) MassUpsertEntities(ctx context.Context, params store.MassUpsertEntitiesParams) error {
+func (c *Client) DeleteShardTaintedEntities(ctx context.Context, params store.DeleteShardTaintedEntitiesParams) error {
+	keepGuildIDs := make([]int64, len(params.KeepGuildIDs))
+	for i, guildID := range params.KeepGuildIDs {
+		keepGuildIDs[i] = int64(guildID)
+	}
+	partitionCount, partitionID := partitionArgs(params.PartitionCount, params.PartitionID)
+
 	tx, err := c.DB.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -80,6 +102,211 @@ func (c *Client) MassUpsertEntities(ctx context.Context, params store.MassUpsert
 
 	q := c.Q.WithTx(tx)
 
+	// Child entities have to be deleted first because they are matched against the tainted guilds.
+	err = q.DeleteShardTaintedRoles(ctx, pgmodel.DeleteShardTaintedRolesParams{
+		AppID:          int64(params.AppID),
+		ShardCount:     int64(params.ShardCount),
+		ShardID:        int64(params.ShardID),
+		KeepGuildIds:   keepGuildIDs,
+		PartitionCount: partitionCount,
+		PartitionID:    partitionID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete tainted shard roles: %w", err)
+	}
+
+	err = q.DeleteShardTaintedChannels(ctx, pgmodel.DeleteShardTaintedChannelsParams{
+		AppID:          int64(params.AppID),
+		ShardCount:     int64(params.ShardCount),
+		ShardID:        int64(params.ShardID),
+		KeepGuildIds:   keepGuildIDs,
+		PartitionCount: partitionCount,
+		PartitionID:    partitionID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete tainted shard channels: %w", err)
+	}
+
+	err = q.DeleteShardTaintedEmojis(ctx, pgmodel.DeleteShardTaintedEmojisParams{
+		AppID:          int64(params.AppID),
+		ShardCount:     int64(params.ShardCount),
+		ShardID:        int64(params.ShardID),
+		KeepGuildIds:   keepGuildIDs,
+		PartitionCount: partitionCount,
+		PartitionID:    partitionID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete tainted shard emojis: %w", err)
+	}
+
+	err = q.DeleteShardTaintedStickers(ctx, pgmodel.DeleteShardTaintedStickersParams{
+		AppID:          int64(params.AppID),
+		ShardCount:     int64(params.ShardCount),
+		ShardID:        int64(params.ShardID),
+		KeepGuildIds:   keepGuildIDs,
+		PartitionCount: partitionCount,
+		PartitionID:    partitionID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete tainted shard stickers: %w", err)
+	}
+
+	err = q.DeleteShardTaintedGuilds(ctx, pgmodel.DeleteShardTaintedGuildsParams{
+		AppID:          int64(params.AppID),
+		ShardCount:     int64(params.ShardCount),
+		ShardID:        int64(params.ShardID),
+		KeepGuildIds:   keepGuildIDs,
+		PartitionCount: partitionCount,
+		PartitionID:    partitionID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete tainted shard guilds: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// partitionArgs returns a partition that covers all guilds when the cache isn't partitioned,
+// the queries can't skip the partition condition and a count of 0 would divide by zero.
+func partitionArgs(partitionCount int, partitionID int) (int64, int64) {
+	if partitionCount <= 1 {
+		return 1, 0
+	}
+	return int64(partitionCount), int64(partitionID)
+}
+
+// The collections of a guild that are replaced as a whole, their updated_at is stored in cache.guild_collections.
+const (
+	guildCollectionEmojis   = "emojis"
+	guildCollectionStickers = "stickers"
+)
+
+// deleteAppBatchSize is the number of rows deleted per statement when purging an app.
+// Deleting in batches keeps the locks short, so the cache stays usable for other apps while an app is purged.
+const deleteAppBatchSize = 10_000
+
+func (c *Client) DeleteAppEntities(ctx context.Context, appID snowflake.ID) (store.DeletedEntities, error) {
+	var deleted store.DeletedEntities
+	var err error
+
+	deleted.Roles, err = deleteInBatches(func() (int64, error) {
+		return c.Q.DeleteAppRoles(ctx, pgmodel.DeleteAppRolesParams{AppID: int64(appID), BatchSize: deleteAppBatchSize})
+	})
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete app roles: %w", err)
+	}
+
+	deleted.Channels, err = deleteInBatches(func() (int64, error) {
+		return c.Q.DeleteAppChannels(ctx, pgmodel.DeleteAppChannelsParams{AppID: int64(appID), BatchSize: deleteAppBatchSize})
+	})
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete app channels: %w", err)
+	}
+
+	deleted.Emojis, err = deleteInBatches(func() (int64, error) {
+		return c.Q.DeleteAppEmojis(ctx, pgmodel.DeleteAppEmojisParams{AppID: int64(appID), BatchSize: deleteAppBatchSize})
+	})
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete app emojis: %w", err)
+	}
+
+	deleted.Stickers, err = deleteInBatches(func() (int64, error) {
+		return c.Q.DeleteAppStickers(ctx, pgmodel.DeleteAppStickersParams{AppID: int64(appID), BatchSize: deleteAppBatchSize})
+	})
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete app stickers: %w", err)
+	}
+
+	_, err = deleteInBatches(func() (int64, error) {
+		return c.Q.DeleteAppGuildCollections(ctx, pgmodel.DeleteAppGuildCollectionsParams{AppID: int64(appID), BatchSize: deleteAppBatchSize})
+	})
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete app guild collections: %w", err)
+	}
+
+	// Guilds are deleted last, so a failed purge can be retried without leaving orphaned entities
+	deleted.Guilds, err = deleteInBatches(func() (int64, error) {
+		return c.Q.DeleteAppGuilds(ctx, pgmodel.DeleteAppGuildsParams{AppID: int64(appID), BatchSize: deleteAppBatchSize})
+	})
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete app guilds: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// deleteInBatches runs the batched delete until no rows are left and returns the total number of deleted rows.
+func deleteInBatches(deleteBatch func() (int64, error)) (int, error) {
+	total := 0
+	for {
+		n, err := deleteBatch()
+		if err != nil {
+			return total, err
+		}
+		total += int(n)
+		if n < deleteAppBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// countWritten reads the results of an upsert batch and returns the number of rows that have been written.
+// The upserts don't return a row when the stored row has a more recent updated_at.
+func countWritten(queryRow func(func(int, int64, error))) (int, error) {
+	written := 0
+	var batchErr error
+	queryRow(func(_ int, _ int64, err error) {
+		switch {
+		case err == nil:
+			written++
+		case errors.Is(err, pgx.ErrNoRows):
+		case batchErr == nil:
+			batchErr = err
+		}
+	})
+	return written, batchErr
+}
+
+// massUpsertCopyThreshold is the number of entities from which they are upserted with COPY.
+// Creating the staging tables costs more than it saves for a few entities, so those are upserted in a batch.
+const massUpsertCopyThreshold = 64
+
+func (c *Client) MassUpsertEntities(ctx context.Context, params store.MassUpsertEntitiesParams) error {
+	count := len(params.Guilds) + len(params.Roles) + len(params.Channels) + len(params.Emojis) + len(params.Stickers)
+	return c.massUpsertEntities(ctx, params, count >= massUpsertCopyThreshold)
+}
+
+func (c *Client) massUpsertEntities(ctx context.Context, params store.MassUpsertEntitiesParams, useCopy bool) error {
+	tx, err := c.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if useCopy {
+		err = copyUpsertEntities(ctx, tx, params)
+	} else {
+		err = batchUpsertEntities(ctx, c.Q.WithTx(tx), params)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// batchUpsertEntities upserts the entities with one INSERT ... ON CONFLICT statement per entity.
+func batchUpsertEntities(ctx context.Context, q *pgmodel.Queries, params store.MassUpsertEntitiesParams) error {
 	if len(params.Guilds) != 0 {
 		guilds := make([]pgmodel.UpsertGuildsParams, len(params.Guilds))
 		for i, guild := range params.Guilds {
@@ -219,10 +446,68 @@ func (c *Client) MassUpsertEntities(ctx context.Context, params store.MassUpsert
 		}
 	}
 
-	err = tx.Commit(ctx)
+	return nil
+}
+
+func (c *Client) GetCacheStats(ctx context.Context, params store.GetCacheStatsParams) ([]*model.CacheStats, error) {
+	appID := pgtype.Int8{Int64: int64(params.AppID), Valid: params.AppID != 0}
+
+	collector := store.NewStatsCollector()
+	if params.AppID != 0 {
+		collector.App(params.AppID)
+	}
+
+	guildRows, err := c.Q.GetGuildStats(ctx, appID)
 	if err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, fmt.Errorf("failed to get guild stats: %w", err)
+	}
+	for _, row := range guildRows {
+		collector.App(snowflake.ID(row.AppID)).Guilds = rowToEntityStats(row.Count, row.Tainted, row.Unavailable, row.OldestUpdatedAt)
 	}
 
-	return nil
+	roleRows, err := c.Q.GetRoleStats(ctx, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role stats: %w", err)
+	}
+	for _, row := range roleRows {
+		collector.App(snowflake.ID(row.AppID)).Roles = rowToEntityStats(row.Count, row.Tainted, 0, row.OldestUpdatedAt)
+	}
+
+	channelRows, err := c.Q.GetChannelStats(ctx, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel stats: %w", err)
+	}
+	for _, row := range channelRows {
+		collector.App(snowflake.ID(row.AppID)).Channels = rowToEntityStats(row.Count, row.Tainted, 0, row.OldestUpdatedAt)
+	}
+
+	emojiRows, err := c.Q.GetEmojiStats(ctx, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get emoji stats: %w", err)
+	}
+	for _, row := range emojiRows {
+		collector.App(snowflake.ID(row.AppID)).Emojis = rowToEntityStats(row.Count, row.Tainted, 0, row.OldestUpdatedAt)
+	}
+
+	stickerRows, err := c.Q.GetStickerStats(ctx, appID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sticker stats: %w", err)
+	}
+	for _, row := range stickerRows {
+		collector.App(snowflake.ID(row.AppID)).Stickers = rowToEntityStats(row.Count, row.Tainted, 0, row.OldestUpdatedAt)
+	}
+
+	return collector.Stats(), nil
+}
+
+func rowToEntityStats(count int64, tainted int64, unavailable int64, oldestUpdatedAt pgtype.Timestamp) model.EntityStats {
+	stats := model.EntityStats{
+		Count:       int(count),
+		Tainted:     int(tainted),
+		Unavailable: int(unavailable),
+	}
+	if oldestUpdatedAt.Valid {
+		stats.OldestUpdatedAt = &oldestUpdatedAt.Time
+	}
+	return stats
 }
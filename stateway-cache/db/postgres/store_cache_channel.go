@@ -31,16 +31,21 @@ func (c *Client) GetChannel(ctx context.Context, appID snowflake.ID, channelID s
 	return rowToChannel(row)
 }
 
-func (c *Client) GetChannels(ctx context.Context, appID snowflake.ID, limit int, offset int) ([]*model.Channel, error) {
+func (c *Client) GetChannels(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Channel, error) {
 	rows, err := c.Q.GetChannels(ctx, pgmodel.GetChannelsParams{
-		AppID: int64(appID),
+		AppID:          int64(appID),
+		ExcludeTainted: opts.ExcludeTainted,
+		After: pgtype.Int8{
+			Int64: int64(opts.After),
+			Valid: opts.After != 0,
+		},
 		Limit: pgtype.Int4{
-			Int32: int32(limit),
-			Valid: limit != 0,
+			Int32: int32(opts.Limit),
+			Valid: opts.Limit != 0,
 		},
 		Offset: pgtype.Int4{
-			Int32: int32(offset),
-			Valid: offset != 0,
+			Int32: int32(opts.Offset),
+			Valid: opts.Offset != 0,
 		},
 	})
 	if err != nil {
@@ -61,22 +66,52 @@ func (c *Client) GetChannels(ctx context.Context, appID snowflake.ID, limit int,
 	return channels, nil
 }
 
-func (c *Client) GetChannelsByType(ctx context.Context, appID snowflake.ID, types []int, limit int, offset int) ([]*model.Channel, error) {
+func (c *Client) GetChannelsByIDs(ctx context.Context, appID snowflake.ID, channelIDs []snowflake.ID) ([]*model.Channel, error) {
+	channelIDInts := make([]int64, len(channelIDs))
+	for i, channelID := range channelIDs {
+		channelIDInts[i] = int64(channelID)
+	}
+
+	rows, err := c.Q.GetChannelsByIDs(ctx, pgmodel.GetChannelsByIDsParams{
+		AppID:      int64(appID),
+		ChannelIds: channelIDInts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	channels := make([]*model.Channel, len(rows))
+	for i, row := range rows {
+		channel, err := rowToChannel(row)
+		if err != nil {
+			return nil, err
+		}
+		channels[i] = channel
+	}
+	return channels, nil
+}
+
+func (c *Client) GetChannelsByType(ctx context.Context, appID snowflake.ID, types []int, opts store.ListOptions) ([]*model.Channel, error) {
 	types32 := make([]int32, len(types))
 	for i, t := range types {
 		types32[i] = int32(t)
 	}
 
 	rows, err := c.Q.GetChannelsByType(ctx, pgmodel.GetChannelsByTypeParams{
-		AppID: int64(appID),
-		Types: types32,
+		AppID:          int64(appID),
+		Types:          types32,
+		ExcludeTainted: opts.ExcludeTainted,
+		After: pgtype.Int8{
+			Int64: int64(opts.After),
+			Valid: opts.After != 0,
+		},
 		Limit: pgtype.Int4{
-			Int32: int32(limit),
-			Valid: limit != 0,
+			Int32: int32(opts.Limit),
+			Valid: opts.Limit != 0,
 		},
 		Offset: pgtype.Int4{
-			Int32: int32(offset),
-			Valid: offset != 0,
+			Int32: int32(opts.Offset),
+			Valid: opts.Offset != 0,
 		},
 	})
 	if err != nil {
@@ -98,14 +133,33 @@ func (c *Client) GetChannelsByType(ctx context.Context, appID snowflake.ID, type
 }
 
 func (c *Client) SearchChannels(ctx context.Context, params store.SearchChannelsParams) ([]*model.Channel, error) {
-	rows, err := c.Q.SearchChannels(ctx, pgmodel.SearchChannelsParams{
-		AppID: int64(params.AppID),
-		Data:  params.Data,
-		Limit: pgtype.Int4{
-			Int32: int32(params.Limit),
-			Valid: params.Limit != 0,
-		},
-	})
+	var rows []pgmodel.CacheChannel
+	var err error
+	if params.Filter != nil {
+		rows, err = searchFiltered[pgmodel.CacheChannel](ctx, c, filterSearch{
+			table:    "cache.channels",
+			columns:  "app_id, guild_id, channel_id, data, tainted, created_at, updated_at",
+			idColumn: "channel_id",
+			appID:    params.AppID,
+			data:     params.Data,
+			filter:   *params.Filter,
+			opts:     params.ListOptions,
+		})
+	} else {
+		rows, err = c.Q.SearchChannels(ctx, pgmodel.SearchChannelsParams{
+			AppID:          int64(params.AppID),
+			Data:           params.Data,
+			ExcludeTainted: params.ExcludeTainted,
+			After: pgtype.Int8{
+				Int64: int64(params.After),
+				Valid: params.After != 0,
+			},
+			Limit: pgtype.Int4{
+				Int32: int32(params.Limit),
+				Valid: params.Limit != 0,
+			},
+		})
+	}
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, store.ErrNotFound
@@ -147,17 +201,22 @@ func (c *Client) GetGuildChannel(ctx context.Context, appID snowflake.ID, guildI
 	return rowToChannel(row)
 }
 
-func (c *Client) GetGuildChannels(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, limit int, offset int) ([]*model.Channel, error) {
+func (c *Client) GetGuildChannels(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Channel, error) {
 	rows, err := c.Q.GetGuildChannels(ctx, pgmodel.GetGuildChannelsParams{
-		AppID:   int64(appID),
-		GuildID: int64(guildID),
+		AppID:          int64(appID),
+		GuildID:        int64(guildID),
+		ExcludeTainted: opts.ExcludeTainted,
+		After: pgtype.Int8{
+			Int64: int64(opts.After),
+			Valid: opts.After != 0,
+		},
 		Limit: pgtype.Int4{
-			Int32: int32(limit),
-			Valid: limit != 0,
+			Int32: int32(opts.Limit),
+			Valid: opts.Limit != 0,
 		},
 		Offset: pgtype.Int4{
-			Int32: int32(offset),
-			Valid: offset != 0,
+			Int32: int32(opts.Offset),
+			Valid: opts.Offset != 0,
 		},
 	})
 	if err != nil {
@@ -178,23 +237,28 @@ func (c *Client) GetGuildChannels(ctx context.Context, appID snowflake.ID, guild
 	return channels, nil
 }
 
-func (c *Client) GetGuildChannelsByType(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, types []int, limit int, offset int) ([]*model.Channel, error) {
+func (c *Client) GetGuildChannelsByType(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, types []int, opts store.ListOptions) ([]*model.Channel, error) {
 	types32 := make([]int32, len(types))
 	for i, t := range types {
 		types32[i] = int32(t)
 	}
 
 	rows, err := c.Q.GetGuildChannelsByType(ctx, pgmodel.GetGuildChannelsByTypeParams{
-		AppID:   int64(appID),
-		GuildID: int64(guildID),
-		Types:   types32,
+		AppID:          int64(appID),
+		GuildID:        int64(guildID),
+		Types:          types32,
+		ExcludeTainted: opts.ExcludeTainted,
+		After: pgtype.Int8{
+			Int64: int64(opts.After),
+			Valid: opts.After != 0,
+		},
 		Limit: pgtype.Int4{
-			Int32: int32(limit),
-			Valid: limit != 0,
+			Int32: int32(opts.Limit),
+			Valid: opts.Limit != 0,
 		},
 		Offset: pgtype.Int4{
-			Int32: int32(offset),
-			Valid: offset != 0,
+			Int32: int32(opts.Offset),
+			Valid: opts.Offset != 0,
 		},
 	})
 	if err != nil {
@@ -216,19 +280,39 @@ func (c *Client) GetGuildChannelsByType(ctx context.Context, appID snowflake.ID,
 }
 
 func (c *Client) SearchGuildChannels(ctx context.Context, params store.SearchGuildChannelsParams) ([]*model.Channel, error) {
-	rows, err := c.Q.SearchGuildChannels(ctx, pgmodel.SearchGuildChannelsParams{
-		AppID:   int64(params.AppID),
-		GuildID: int64(params.GuildID),
-		Data:    params.Data,
-		Limit: pgtype.Int4{
-			Int32: int32(params.Limit),
-			Valid: params.Limit != 0,
-		},
-		Offset: pgtype.Int4{
-			Int32: int32(params.Offset),
-			Valid: params.Offset != 0,
-		},
-	})
+	var rows []pgmodel.CacheChannel
+	var err error
+	if params.Filter != nil {
+		rows, err = searchFiltered[pgmodel.CacheChannel](ctx, c, filterSearch{
+			table:    "cache.channels",
+			columns:  "app_id, guild_id, channel_id, data, tainted, created_at, updated_at",
+			idColumn: "channel_id",
+			appID:    params.AppID,
+			guildID:  params.GuildID,
+			data:     params.Data,
+			filter:   *params.Filter,
+			opts:     params.ListOptions,
+		})
+	} else {
+		rows, err = c.Q.SearchGuildChannels(ctx, pgmodel.SearchGuildChannelsParams{
+			AppID:          int64(params.AppID),
+			GuildID:        int64(params.GuildID),
+			Data:           params.Data,
+			ExcludeTainted: params.ExcludeTainted,
+			After: pgtype.Int8{
+				Int64: int64(params.After),
+				Valid: params.After != 0,
+			},
+			Limit: pgtype.Int4{
+				Int32: int32(params.Limit),
+				Valid: params.Limit != 0,
+			},
+			Offset: pgtype.Int4{
+				Int32: int32(params.Offset),
+				Valid: params.Offset != 0,
+			},
+		})
+	}
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, store.ErrNotFound
@@ -258,16 +342,16 @@ func (c *Client) CountGuildChannels(ctx context.Context, appID snowflake.ID, gui
 	return int(res), nil
 }
 
-func (c *Client) UpsertChannels(ctx context.Context, channels ...store.UpsertChannelParams) error {
+func (c *Client) UpsertChannels(ctx context.Context, channels ...store.UpsertChannelParams) (int, error) {
 	if len(channels) == 0 {
-		return nil
+		return 0, nil
 	}
 
 	params := make([]pgmodel.UpsertChannelsParams, len(channels))
 	for i, channel := range channels {
 		data, err := json.Marshal(channel.Data)
 		if err != nil {
-			return fmt.Errorf("failed to marshal channel data: %w", err)
+			return 0, fmt.Errorf("failed to marshal channel data: %w", err)
 		}
 
 		params[i] = pgmodel.UpsertChannelsParams{
@@ -285,8 +369,7 @@ func (c *Client) UpsertChannels(ctx context.Context, channels ...store.UpsertCha
 			},
 		}
 	}
-	res := c.Q.UpsertChannels(ctx, params)
-	return res.Close()
+	return countWritten(c.Q.UpsertChannels(ctx, params).QueryRow)
 }
 
 func (c *Client) DeleteChannel(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, channelID snowflake.ID) error {
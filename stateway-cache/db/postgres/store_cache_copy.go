@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+)
+
+// copyUpsertEntities copies the entities into temporary staging tables and merges each of them into its table
+// with a single statement, which is a lot faster than one statement per entity for large guilds.
+func copyUpsertEntities(ctx context.Context, tx pgx.Tx, params store.MassUpsertEntitiesParams) error {
+	guilds := make([][]any, len(params.Guilds))
+	for i, guild := range params.Guilds {
+		data, err := json.Marshal(guild.Data)
+		if err != nil {
+			return fmt.Errorf("failed to marshal guild data: %w", err)
+		}
+		guilds[i] = []any{int64(guild.AppID), int64(guild.GuildID), data, copyTimestamp(guild.CreatedAt), copyTimestamp(guild.UpdatedAt)}
+	}
+	err := copyUpsert(ctx, tx, "guilds", []string{"app_id", "guild_id"}, guilds)
+	if err != nil {
+		return fmt.Errorf("failed to upsert guilds: %w", err)
+	}
+
+	roles := make([][]any, len(params.Roles))
+	for i, role := range params.Roles {
+		data, err := json.Marshal(role.Data)
+		if err != nil {
+			return fmt.Errorf("failed to marshal role data: %w", err)
+		}
+		roles[i] = []any{int64(role.AppID), int64(role.GuildID), int64(role.RoleID), data, copyTimestamp(role.CreatedAt), copyTimestamp(role.UpdatedAt)}
+	}
+	err = copyUpsert(ctx, tx, "roles", []string{"app_id", "guild_id", "role_id"}, roles)
+	if err != nil {
+		return fmt.Errorf("failed to upsert roles: %w", err)
+	}
+
+	channels := make([][]any, len(params.Channels))
+	for i, channel := range params.Channels {
+		data, err := json.Marshal(channel.Data)
+		if err != nil {
+			return fmt.Errorf("failed to marshal channel data: %w", err)
+		}
+		channels[i] = []any{int64(channel.AppID), int64(channel.GuildID), int64(channel.ChannelID), data, copyTimestamp(channel.CreatedAt), copyTimestamp(channel.UpdatedAt)}
+	}
+	err = copyUpsert(ctx, tx, "channels", []string{"app_id", "guild_id", "channel_id"}, channels)
+	if err != nil {
+		return fmt.Errorf("failed to upsert channels: %w", err)
+	}
+
+	emojis := make([][]any, len(params.Emojis))
+	for i, emoji := range params.Emojis {
+		data, err := json.Marshal(emoji.Data)
+		if err != nil {
+			return fmt.Errorf("failed to marshal emoji data: %w", err)
+		}
+		emojis[i] = []any{int64(emoji.AppID), int64(emoji.GuildID), int64(emoji.EmojiID), data, copyTimestamp(emoji.CreatedAt), copyTimestamp(emoji.UpdatedAt)}
+	}
+	err = copyUpsert(ctx, tx, "emojis", []string{"app_id", "guild_id", "emoji_id"}, emojis)
+	if err != nil {
+		return fmt.Errorf("failed to upsert emojis: %w", err)
+	}
+
+	stickers := make([][]any, len(params.Stickers))
+	for i, sticker := range params.Stickers {
+		data, err := json.Marshal(sticker.Data)
+		if err != nil {
+			return fmt.Errorf("failed to marshal sticker data: %w", err)
+		}
+		stickers[i] = []any{int64(sticker.AppID), int64(sticker.GuildID), int64(sticker.StickerID), data, copyTimestamp(sticker.CreatedAt), copyTimestamp(sticker.UpdatedAt)}
+	}
+	err = copyUpsert(ctx, tx, "stickers", []string{"app_id", "guild_id", "sticker_id"}, stickers)
+	if err != nil {
+		return fmt.Errorf("failed to upsert stickers: %w", err)
+	}
+
+	return nil
+}
+
+// copyUpsert copies the rows into a staging table that is dropped on commit and merges it into the cache table.
+// The rows consist of the key columns followed by data, created_at and updated_at.
+func copyUpsert(ctx context.Context, tx pgx.Tx, table string, keyColumns []string, rows [][]any) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	staging := "cache_" + table + "_staging"
+	columns := append(append([]string{}, keyColumns...), "data", "created_at", "updated_at")
+
+	_, err := tx.Exec(ctx, fmt.Sprintf(
+		"CREATE TEMP TABLE %s (LIKE cache.%s INCLUDING DEFAULTS) ON COMMIT DROP",
+		staging, table,
+	))
+	if err != nil {
+		return fmt.Errorf("failed to create staging table: %w", err)
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{staging}, columns, pgx.CopyFromRows(rows))
+	if err != nil {
+		return fmt.Errorf("failed to copy rows: %w", err)
+	}
+
+	// A row can only be updated once per statement, so only the latest version of duplicated entities is merged
+	keys := strings.Join(keyColumns, ", ")
+	_, err = tx.Exec(ctx, fmt.Sprintf(`INSERT INTO cache.%[1]s (%[2]s)
+SELECT DISTINCT ON (%[3]s) %[2]s FROM %[4]s ORDER BY %[3]s, updated_at DESC
+ON CONFLICT (%[3]s) DO UPDATE SET
+    data = EXCLUDED.data,
+    tainted = FALSE,
+    updated_at = EXCLUDED.updated_at
+WHERE cache.%[1]s.updated_at <= EXCLUDED.updated_at`,
+		table, strings.Join(columns, ", "), keys, staging,
+	))
+	if err != nil {
+		return fmt.Errorf("failed to merge staging table: %w", err)
+	}
+
+	return nil
+}
+
+func copyTimestamp(t time.Time) pgtype.Timestamp {
+	return pgtype.Timestamp{Time: t, Valid: true}
+}
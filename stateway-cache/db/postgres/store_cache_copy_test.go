@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/merlinfuchs/stateway/stateway-cache/db/postgres/pgmodel"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClient connects to the database of STATEWAY_TEST_POSTGRES_DSN and migrates it.
+// The tests are skipped when it isn't set, as they need a running Postgres server.
+func newTestClient(tb testing.TB) *Client {
+	tb.Helper()
+
+	dsn := os.Getenv("STATEWAY_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		tb.Skip("STATEWAY_TEST_POSTGRES_DSN is not set")
+	}
+
+	db, err := pgxpool.New(context.Background(), dsn)
+	require.NoError(tb, err)
+	tb.Cleanup(db.Close)
+
+	client := &Client{
+		DB:            db,
+		Q:             pgmodel.New(db),
+		connectionDSN: dsn,
+	}
+
+	migrater, err := client.GetMigrater()
+	require.NoError(tb, err)
+	defer migrater.Close()
+
+	err = migrater.Up()
+	if !errors.Is(err, migrate.ErrNoChange) {
+		require.NoError(tb, err)
+	}
+
+	return client
+}
+
+// largeGuildParams returns the entities of a guild with the given number of channels and roles.
+func largeGuildParams(appID snowflake.ID, guildID snowflake.ID, channels int, roles int, updatedAt time.Time) store.MassUpsertEntitiesParams {
+	params := store.MassUpsertEntitiesParams{
+		AppID: appID,
+		Guilds: []store.UpsertGuildParams{
+			{AppID: appID, GuildID: guildID, Data: discord.Guild{ID: guildID, Name: "Guild"}, CreatedAt: updatedAt, UpdatedAt: updatedAt},
+		},
+	}
+	for i := range channels {
+		channelID := guildID + snowflake.ID(i+1)
+		params.Channels = append(params.Channels, store.UpsertChannelParams{
+			AppID:     appID,
+			GuildID:   guildID,
+			ChannelID: channelID,
+			Data:      discord.GuildTextChannel{ID: channelID, Name: fmt.Sprintf("channel-%d", i)},
+			CreatedAt: updatedAt,
+			UpdatedAt: updatedAt,
+		})
+	}
+	for i := range roles {
+		roleID := guildID + snowflake.ID(channels+i+1)
+		params.Roles = append(params.Roles, store.UpsertRoleParams{
+			AppID:     appID,
+			GuildID:   guildID,
+			RoleID:    roleID,
+			Data:      discord.Role{ID: roleID, Name: fmt.Sprintf("role-%d", i)},
+			CreatedAt: updatedAt,
+			UpdatedAt: updatedAt,
+		})
+	}
+	return params
+}
+
+func TestPostgresCopyUpsertEntities(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+
+	const appID = snowflake.ID(9_000_001)
+	t.Cleanup(func() {
+		_, _ = client.DeleteAppEntities(ctx, appID)
+	})
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	params := largeGuildParams(appID, 10_000, 100, 50, now)
+	// Entities of multiple guilds can be upserted at once
+	other := largeGuildParams(appID, 20_000, 10, 10, now)
+	params.Guilds = append(params.Guilds, other.Guilds...)
+	params.Channels = append(params.Channels, other.Channels...)
+	params.Roles = append(params.Roles, other.Roles...)
+	// Only the latest version of a duplicated entity is kept
+	params.Roles = append(params.Roles, store.UpsertRoleParams{
+		AppID:     appID,
+		GuildID:   10_000,
+		RoleID:    params.Roles[0].RoleID,
+		Data:      discord.Role{ID: params.Roles[0].RoleID, Name: "renamed"},
+		CreatedAt: now,
+		UpdatedAt: now.Add(time.Second),
+	})
+
+	err := client.massUpsertEntities(ctx, params, true)
+	require.NoError(t, err)
+
+	channels, err := client.GetGuildChannels(ctx, appID, 10_000, store.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, channels, 100)
+
+	role, err := client.GetRole(ctx, appID, params.Roles[0].RoleID)
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", role.Data.Name)
+
+	guild, err := client.GetGuild(ctx, appID, 20_000)
+	require.NoError(t, err)
+	assert.Equal(t, "Guild", guild.Data.Name)
+
+	// Stale entities don't overwrite newer ones
+	stale := largeGuildParams(appID, 10_000, 100, 50, now.Add(-time.Minute))
+	stale.Roles[0].Data.Name = "stale"
+	err = client.massUpsertEntities(ctx, stale, true)
+	require.NoError(t, err)
+
+	role, err = client.GetRole(ctx, appID, params.Roles[0].RoleID)
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", role.Data.Name)
+}
+
+func BenchmarkPostgresMassUpsertEntities(b *testing.B) {
+	ctx := context.Background()
+	client := newTestClient(b)
+
+	const appID = snowflake.ID(9_000_002)
+	b.Cleanup(func() {
+		_, _ = client.DeleteAppEntities(ctx, appID)
+	})
+
+	// Every upsert is newer than the last, so all entities are actually updated
+	start := time.Now().UTC()
+	version := 0
+
+	for _, useCopy := range []bool{false, true} {
+		name := "batch"
+		if useCopy {
+			name = "copy"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				version++
+				params := largeGuildParams(appID, 10_000, 500, 250, start.Add(time.Duration(version)*time.Second))
+				err := client.massUpsertEntities(ctx, params, useCopy)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/snowflake/v2"
@@ -44,17 +45,22 @@ func (c *Client) GetEmoji(ctx context.Context, appID snowflake.ID, emojiID snowf
 	return rowToEmoji(row)
 }
 
-func (c *Client) GetGuildEmojis(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, limit int, offset int) ([]*model.Emoji, error) {
+func (c *Client) GetGuildEmojis(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Emoji, error) {
 	rows, err := c.Q.GetGuildEmojis(ctx, pgmodel.GetGuildEmojisParams{
-		AppID:   int64(appID),
-		GuildID: int64(guildID),
+		AppID:          int64(appID),
+		GuildID:        int64(guildID),
+		ExcludeTainted: opts.ExcludeTainted,
+		After: pgtype.Int8{
+			Int64: int64(opts.After),
+			Valid: opts.After != 0,
+		},
 		Limit: pgtype.Int4{
-			Int32: int32(limit),
-			Valid: limit != 0,
+			Int32: int32(opts.Limit),
+			Valid: opts.Limit != 0,
 		},
 		Offset: pgtype.Int4{
-			Int32: int32(offset),
-			Valid: offset != 0,
+			Int32: int32(opts.Offset),
+			Valid: opts.Offset != 0,
 		},
 	})
 	if err != nil {
@@ -75,16 +81,21 @@ func (c *Client) GetGuildEmojis(ctx context.Context, appID snowflake.ID, guildID
 	return emojis, nil
 }
 
-func (c *Client) GetEmojis(ctx context.Context, appID snowflake.ID, limit int, offset int) ([]*model.Emoji, error) {
+func (c *Client) GetEmojis(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Emoji, error) {
 	rows, err := c.Q.GetEmojis(ctx, pgmodel.GetEmojisParams{
-		AppID: int64(appID),
+		AppID:          int64(appID),
+		ExcludeTainted: opts.ExcludeTainted,
+		After: pgtype.Int8{
+			Int64: int64(opts.After),
+			Valid: opts.After != 0,
+		},
 		Limit: pgtype.Int4{
-			Int32: int32(limit),
-			Valid: limit != 0,
+			Int32: int32(opts.Limit),
+			Valid: opts.Limit != 0,
 		},
 		Offset: pgtype.Int4{
-			Int32: int32(offset),
-			Valid: offset != 0,
+			Int32: int32(opts.Offset),
+			Valid: opts.Offset != 0,
 		},
 	})
 	if err != nil {
@@ -105,20 +116,65 @@ func (c *Client) GetEmojis(ctx context.Context, appID snowflake.ID, limit int, o
 	return emojis, nil
 }
 
-func (c *Client) SearchGuildEmojis(ctx context.Context, params store.SearchGuildEmojisParams) ([]*model.Emoji, error) {
-	rows, err := c.Q.SearchGuildEmojis(ctx, pgmodel.SearchGuildEmojisParams{
-		AppID:   int64(params.AppID),
-		GuildID: int64(params.GuildID),
-		Data:    params.Data,
-		Limit: pgtype.Int4{
-			Int32: int32(params.Limit),
-			Valid: params.Limit != 0,
-		},
-		Offset: pgtype.Int4{
-			Int32: int32(params.Offset),
-			Valid: params.Offset != 0,
-		},
+func (c *Client) GetEmojisByIDs(ctx context.Context, appID snowflake.ID, emojiIDs []snowflake.ID) ([]*model.Emoji, error) {
+	emojiIDInts := make([]int64, len(emojiIDs))
+	for i, emojiID := range emojiIDs {
+		emojiIDInts[i] = int64(emojiID)
+	}
+
+	rows, err := c.Q.GetEmojisByIDs(ctx, pgmodel.GetEmojisByIDsParams{
+		AppID:    int64(appID),
+		EmojiIds: emojiIDInts,
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	emojis := make([]*model.Emoji, len(rows))
+	for i, row := range rows {
+		emoji, err := rowToEmoji(row)
+		if err != nil {
+			return nil, err
+		}
+		emojis[i] = emoji
+	}
+	return emojis, nil
+}
+
+func (c *Client) SearchGuildEmojis(ctx context.Context, params store.SearchGuildEmojisParams) ([]*model.Emoji, error) {
+	var rows []pgmodel.CacheEmoji
+	var err error
+	if params.Filter != nil {
+		rows, err = searchFiltered[pgmodel.CacheEmoji](ctx, c, filterSearch{
+			table:    "cache.emojis",
+			columns:  "app_id, guild_id, emoji_id, data, tainted, created_at, updated_at",
+			idColumn: "emoji_id",
+			appID:    params.AppID,
+			guildID:  params.GuildID,
+			data:     params.Data,
+			filter:   *params.Filter,
+			opts:     params.ListOptions,
+		})
+	} else {
+		rows, err = c.Q.SearchGuildEmojis(ctx, pgmodel.SearchGuildEmojisParams{
+			AppID:          int64(params.AppID),
+			GuildID:        int64(params.GuildID),
+			Data:           params.Data,
+			ExcludeTainted: params.ExcludeTainted,
+			After: pgtype.Int8{
+				Int64: int64(params.After),
+				Valid: params.After != 0,
+			},
+			Limit: pgtype.Int4{
+				Int32: int32(params.Limit),
+				Valid: params.Limit != 0,
+			},
+			Offset: pgtype.Int4{
+				Int32: int32(params.Offset),
+				Valid: params.Offset != 0,
+			},
+		})
+	}
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, store.ErrNotFound
@@ -138,18 +194,37 @@ func (c *Client) SearchGuildEmojis(ctx context.Context, params store.SearchGuild
 }
 
 func (c *Client) SearchEmojis(ctx context.Context, params store.SearchEmojisParams) ([]*model.Emoji, error) {
-	rows, err := c.Q.SearchEmojis(ctx, pgmodel.SearchEmojisParams{
-		AppID: int64(params.AppID),
-		Data:  params.Data,
-		Limit: pgtype.Int4{
-			Int32: int32(params.Limit),
-			Valid: params.Limit != 0,
-		},
-		Offset: pgtype.Int4{
-			Int32: int32(params.Offset),
-			Valid: params.Offset != 0,
-		},
-	})
+	var rows []pgmodel.CacheEmoji
+	var err error
+	if params.Filter != nil {
+		rows, err = searchFiltered[pgmodel.CacheEmoji](ctx, c, filterSearch{
+			table:    "cache.emojis",
+			columns:  "app_id, guild_id, emoji_id, data, tainted, created_at, updated_at",
+			idColumn: "emoji_id",
+			appID:    params.AppID,
+			data:     params.Data,
+			filter:   *params.Filter,
+			opts:     params.ListOptions,
+		})
+	} else {
+		rows, err = c.Q.SearchEmojis(ctx, pgmodel.SearchEmojisParams{
+			AppID:          int64(params.AppID),
+			Data:           params.Data,
+			ExcludeTainted: params.ExcludeTainted,
+			After: pgtype.Int8{
+				Int64: int64(params.After),
+				Valid: params.After != 0,
+			},
+			Limit: pgtype.Int4{
+				Int32: int32(params.Limit),
+				Valid: params.Limit != 0,
+			},
+			Offset: pgtype.Int4{
+				Int32: int32(params.Offset),
+				Valid: params.Offset != 0,
+			},
+		})
+	}
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, store.ErrNotFound
@@ -187,16 +262,97 @@ func (c *Client) CountEmojis(ctx context.Context, appID snowflake.ID) (int, erro
 	return int(res), nil
 }
 
-func (c *Client) UpsertEmojis(ctx context.Context, emojis ...store.UpsertEmojiParams) error {
+func (c *Client) UpsertEmojis(ctx context.Context, emojis ...store.UpsertEmojiParams) (int, error) {
 	if len(emojis) == 0 {
-		return nil
+		return 0, nil
+	}
+
+	params, err := upsertEmojisParams(emojis)
+	if err != nil {
+		return 0, err
+	}
+
+	return countWritten(c.Q.UpsertEmojis(ctx, params).QueryRow)
+}
+
+func (c *Client) ReplaceGuildEmojis(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, updatedAt time.Time, emojis ...store.UpsertEmojiParams) (bool, error) {
+	params, err := upsertEmojisParams(emojis)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := c.DB.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	q := c.Q.WithTx(tx)
+
+	// The row of the collection is locked until the transaction ends, so concurrent replaces of the guild are serialized
+	_, err = q.UpsertGuildCollection(ctx, pgmodel.UpsertGuildCollectionParams{
+		AppID:      int64(appID),
+		GuildID:    int64(guildID),
+		Collection: guildCollectionEmojis,
+		UpdatedAt: pgtype.Timestamp{
+			Time:  updatedAt,
+			Valid: true,
+		},
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to update guild emojis timestamp: %w", err)
+	}
+
+	if len(params) != 0 {
+		res := q.UpsertEmojis(ctx, params)
+		if err := res.Close(); err != nil {
+			return false, fmt.Errorf("failed to upsert emojis: %w", err)
+		}
+	}
+
+	emojiIDs := make([]int64, len(emojis))
+	for i, emoji := range emojis {
+		emojiIDs[i] = int64(emoji.EmojiID)
+	}
+
+	err = q.DeleteGuildEmojisExcept(ctx, pgmodel.DeleteGuildEmojisExceptParams{
+		AppID:    int64(appID),
+		GuildID:  int64(guildID),
+		EmojiIds: emojiIDs,
+		UpdatedAt: pgtype.Timestamp{
+			Time:  updatedAt,
+			Valid: true,
+		},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete removed emojis: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	return true, nil
+}
+
+func (c *Client) DeleteEmoji(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, emojiID snowflake.ID) error {
+	return c.Q.DeleteEmoji(ctx, pgmodel.DeleteEmojiParams{
+		AppID:   int64(appID),
+		GuildID: int64(guildID),
+		EmojiID: int64(emojiID),
+	})
+}
+
+func upsertEmojisParams(emojis []store.UpsertEmojiParams) ([]pgmodel.UpsertEmojisParams, error) {
 	params := make([]pgmodel.UpsertEmojisParams, len(emojis))
 	for i, emoji := range emojis {
 		data, err := json.Marshal(emoji.Data)
 		if err != nil {
-			return fmt.Errorf("failed to marshal emoji data: %w", err)
+			return nil, fmt.Errorf("failed to marshal emoji data: %w", err)
 		}
 
 		params[i] = pgmodel.UpsertEmojisParams{
@@ -214,16 +370,7 @@ func (c *Client) UpsertEmojis(ctx context.Context, emojis ...store.UpsertEmojiPa
 			},
 		}
 	}
-	res := c.Q.UpsertEmojis(ctx, params)
-	return res.Close()
-}
-
-func (c *Client) DeleteEmoji(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, emojiID snowflake.ID) error {
-	return c.Q.DeleteEmoji(ctx, pgmodel.DeleteEmojiParams{
-		AppID:   int64(appID),
-		GuildID: int64(guildID),
-		EmojiID: int64(emojiID),
-	})
+	return params, nil
 }
 
 func rowToEmoji(row pgmodel.CacheEmoji) (*model.Emoji, error) {
@@ -29,6 +29,59 @@ func (c *Client) GetGuild(ctx context.Context, appID snowflake.ID, guildID snowf
 	return rowToGuild(row)
 }
 
+func (c *Client) GetFullGuild(ctx context.Context, params store.GetFullGuildParams) (*model.FullGuild, error) {
+	// REPEATABLE READ makes all queries of the transaction see the same snapshot.
+	tx, err := c.DB.BeginTx(ctx, pgx.TxOptions{
+		IsoLevel:   pgx.RepeatableRead,
+		AccessMode: pgx.ReadOnly,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	snapshot := &Client{Q: c.Q.WithTx(tx)}
+	opts := store.ListOptions{ExcludeTainted: params.ExcludeTainted}
+
+	guild, err := snapshot.GetGuild(ctx, params.AppID, params.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &model.FullGuild{Guild: *guild}
+	if params.Include.Channels {
+		res.Channels, err = snapshot.GetGuildChannels(ctx, params.AppID, params.GuildID, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get guild channels: %w", err)
+		}
+	}
+	if params.Include.Roles {
+		res.Roles, err = snapshot.GetGuildRoles(ctx, params.AppID, params.GuildID, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get guild roles: %w", err)
+		}
+	}
+	if params.Include.Emojis {
+		res.Emojis, err = snapshot.GetGuildEmojis(ctx, params.AppID, params.GuildID, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get guild emojis: %w", err)
+		}
+	}
+	if params.Include.Stickers {
+		res.Stickers, err = snapshot.GetGuildStickers(ctx, params.AppID, params.GuildID, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get guild stickers: %w", err)
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return res, nil
+}
+
 func (c *Client) GetGuildOwnerID(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (snowflake.ID, error) {
 	row, err := c.Q.GetGuildOwnerID(ctx, pgmodel.GetGuildOwnerIDParams{
 		AppID:   int64(appID),
@@ -43,16 +96,21 @@ func (c *Client) GetGuildOwnerID(ctx context.Context, appID snowflake.ID, guildI
 	return snowflake.ID(row), nil
 }
 
-func (c *Client) GetGuilds(ctx context.Context, appID snowflake.ID, limit int, offset int) ([]*model.Guild, error) {
+func (c *Client) GetGuilds(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Guild, error) {
 	rows, err := c.Q.GetGuilds(ctx, pgmodel.GetGuildsParams{
-		AppID: int64(appID),
+		AppID:          int64(appID),
+		ExcludeTainted: opts.ExcludeTainted,
+		After: pgtype.Int8{
+			Int64: int64(opts.After),
+			Valid: opts.After != 0,
+		},
 		Limit: pgtype.Int4{
-			Int32: int32(limit),
-			Valid: limit != 0,
+			Int32: int32(opts.Limit),
+			Valid: opts.Limit != 0,
 		},
 		Offset: pgtype.Int4{
-			Int32: int32(offset),
-			Valid: offset != 0,
+			Int32: int32(opts.Offset),
+			Valid: opts.Offset != 0,
 		},
 	})
 	if err != nil {
@@ -73,6 +131,31 @@ func (c *Client) GetGuilds(ctx context.Context, appID snowflake.ID, limit int, o
 	return guilds, nil
 }
 
+func (c *Client) GetGuildsByIDs(ctx context.Context, appID snowflake.ID, guildIDs []snowflake.ID) ([]*model.Guild, error) {
+	guildIDInts := make([]int64, len(guildIDs))
+	for i, guildID := range guildIDs {
+		guildIDInts[i] = int64(guildID)
+	}
+
+	rows, err := c.Q.GetGuildsByIDs(ctx, pgmodel.GetGuildsByIDsParams{
+		AppID:    int64(appID),
+		GuildIds: guildIDInts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	guilds := make([]*model.Guild, len(rows))
+	for i, row := range rows {
+		guild, err := rowToGuild(row)
+		if err != nil {
+			return nil, err
+		}
+		guilds[i] = guild
+	}
+	return guilds, nil
+}
+
 func (c *Client) CheckGuildExist(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (bool, error) {
 	row, err := c.Q.CheckGuildExist(ctx, pgmodel.CheckGuildExistParams{
 		AppID:   int64(appID),
@@ -87,19 +170,56 @@ func (c *Client) CheckGuildExist(ctx context.Context, appID snowflake.ID, guildI
 	return row, nil
 }
 
+func (c *Client) GetGuildApps(ctx context.Context, guildID snowflake.ID) ([]*model.GuildApp, error) {
+	rows, err := c.Q.GetGuildApps(ctx, int64(guildID))
+	if err != nil {
+		return nil, err
+	}
+
+	apps := make([]*model.GuildApp, len(rows))
+	for i, row := range rows {
+		apps[i] = &model.GuildApp{
+			AppID:       snowflake.ID(row.AppID),
+			Unavailable: row.Unavailable,
+			Tainted:     row.Tainted,
+			UpdatedAt:   row.UpdatedAt.Time,
+		}
+	}
+	return apps, nil
+}
+
 func (c *Client) SearchGuilds(ctx context.Context, params store.SearchGuildsParams) ([]*model.Guild, error) {
-	rows, err := c.Q.SearchGuilds(ctx, pgmodel.SearchGuildsParams{
-		AppID: int64(params.AppID),
-		Data:  params.Data,
-		Limit: pgtype.Int4{
-			Int32: int32(params.Limit),
-			Valid: params.Limit != 0,
-		},
-		Offset: pgtype.Int4{
-			Int32: int32(params.Offset),
-			Valid: params.Offset != 0,
-		},
-	})
+	var rows []pgmodel.CacheGuild
+	var err error
+	if params.Filter != nil {
+		rows, err = searchFiltered[pgmodel.CacheGuild](ctx, c, filterSearch{
+			table:    "cache.guilds",
+			columns:  "app_id, guild_id, data, unavailable, tainted, created_at, updated_at",
+			idColumn: "guild_id",
+			appID:    params.AppID,
+			data:     params.Data,
+			filter:   *params.Filter,
+			opts:     params.ListOptions,
+		})
+	} else {
+		rows, err = c.Q.SearchGuilds(ctx, pgmodel.SearchGuildsParams{
+			AppID:          int64(params.AppID),
+			Data:           params.Data,
+			ExcludeTainted: params.ExcludeTainted,
+			After: pgtype.Int8{
+				Int64: int64(params.After),
+				Valid: params.After != 0,
+			},
+			Limit: pgtype.Int4{
+				Int32: int32(params.Limit),
+				Valid: params.Limit != 0,
+			},
+			Offset: pgtype.Int4{
+				Int32: int32(params.Offset),
+				Valid: params.Offset != 0,
+			},
+		})
+	}
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, store.ErrNotFound
@@ -118,16 +238,16 @@ func (c *Client) SearchGuilds(ctx context.Context, params store.SearchGuildsPara
 	return guilds, nil
 }
 
-func (c *Client) UpsertGuilds(ctx context.Context, guilds ...store.UpsertGuildParams) error {
+func (c *Client) UpsertGuilds(ctx context.Context, guilds ...store.UpsertGuildParams) (int, error) {
 	if len(guilds) == 0 {
-		return nil
+		return 0, nil
 	}
 
 	params := make([]pgmodel.UpsertGuildsParams, len(guilds))
 	for i, guild := range guilds {
 		data, err := json.Marshal(guild.Data)
 		if err != nil {
-			return fmt.Errorf("failed to marshal guild data: %w", err)
+			return 0, fmt.Errorf("failed to marshal guild data: %w", err)
 		}
 
 		params[i] = pgmodel.UpsertGuildsParams{
@@ -144,8 +264,7 @@ func (c *Client) UpsertGuilds(ctx context.Context, guilds ...store.UpsertGuildPa
 			},
 		}
 	}
-	res := c.Q.UpsertGuilds(ctx, params)
-	return res.Close()
+	return countWritten(c.Q.UpsertGuilds(ctx, params).QueryRow)
 }
 
 func (c *Client) MarkGuildUnavailable(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) error {
@@ -44,17 +44,22 @@ func (c *Client) GetRole(ctx context.Context, appID snowflake.ID, roleID snowfla
 	return rowToRole(row)
 }
 
-func (c *Client) GetGuildRoles(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, limit int, offset int) ([]*model.Role, error) {
+func (c *Client) GetGuildRoles(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Role, error) {
 	rows, err := c.Q.GetGuildRoles(ctx, pgmodel.GetGuildRolesParams{
-		AppID:   int64(appID),
-		GuildID: int64(guildID),
+		AppID:          int64(appID),
+		GuildID:        int64(guildID),
+		ExcludeTainted: opts.ExcludeTainted,
+		After: pgtype.Int8{
+			Int64: int64(opts.After),
+			Valid: opts.After != 0,
+		},
 		Limit: pgtype.Int4{
-			Int32: int32(limit),
-			Valid: limit != 0,
+			Int32: int32(opts.Limit),
+			Valid: opts.Limit != 0,
 		},
 		Offset: pgtype.Int4{
-			Int32: int32(offset),
-			Valid: offset != 0,
+			Int32: int32(opts.Offset),
+			Valid: opts.Offset != 0,
 		},
 	})
 	if err != nil {
@@ -101,16 +106,21 @@ func (c *Client) GetGuildRolesByIDs(ctx context.Context, appID snowflake.ID, gui
 	return roles, nil
 }
 
-func (c *Client) GetRoles(ctx context.Context, appID snowflake.ID, limit int, offset int) ([]*model.Role, error) {
+func (c *Client) GetRoles(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Role, error) {
 	rows, err := c.Q.GetRoles(ctx, pgmodel.GetRolesParams{
-		AppID: int64(appID),
+		AppID:          int64(appID),
+		ExcludeTainted: opts.ExcludeTainted,
+		After: pgtype.Int8{
+			Int64: int64(opts.After),
+			Valid: opts.After != 0,
+		},
 		Limit: pgtype.Int4{
-			Int32: int32(limit),
-			Valid: limit != 0,
+			Int32: int32(opts.Limit),
+			Valid: opts.Limit != 0,
 		},
 		Offset: pgtype.Int4{
-			Int32: int32(offset),
-			Valid: offset != 0,
+			Int32: int32(opts.Offset),
+			Valid: opts.Offset != 0,
 		},
 	})
 	if err != nil {
@@ -131,20 +141,65 @@ func (c *Client) GetRoles(ctx context.Context, appID snowflake.ID, limit int, of
 	return roles, nil
 }
 
-func (c *Client) SearchGuildRoles(ctx context.Context, params store.SearchGuildRolesParams) ([]*model.Role, error) {
-	rows, err := c.Q.SearchGuildRoles(ctx, pgmodel.SearchGuildRolesParams{
-		AppID:   int64(params.AppID),
-		GuildID: int64(params.GuildID),
-		Data:    params.Data,
-		Limit: pgtype.Int4{
-			Int32: int32(params.Limit),
-			Valid: params.Limit != 0,
-		},
-		Offset: pgtype.Int4{
-			Int32: int32(params.Offset),
-			Valid: params.Offset != 0,
-		},
+func (c *Client) GetRolesByIDs(ctx context.Context, appID snowflake.ID, roleIDs []snowflake.ID) ([]*model.Role, error) {
+	roleIDInts := make([]int64, len(roleIDs))
+	for i, roleID := range roleIDs {
+		roleIDInts[i] = int64(roleID)
+	}
+
+	rows, err := c.Q.GetRolesByIDs(ctx, pgmodel.GetRolesByIDsParams{
+		AppID:   int64(appID),
+		RoleIds: roleIDInts,
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make([]*model.Role, len(rows))
+	for i, row := range rows {
+		role, err := rowToRole(row)
+		if err != nil {
+			return nil, err
+		}
+		roles[i] = role
+	}
+	return roles, nil
+}
+
+func (c *Client) SearchGuildRoles(ctx context.Context, params store.SearchGuildRolesParams) ([]*model.Role, error) {
+	var rows []pgmodel.CacheRole
+	var err error
+	if params.Filter != nil {
+		rows, err = searchFiltered[pgmodel.CacheRole](ctx, c, filterSearch{
+			table:    "cache.roles",
+			columns:  "app_id, guild_id, role_id, data, tainted, created_at, updated_at",
+			idColumn: "role_id",
+			appID:    params.AppID,
+			guildID:  params.GuildID,
+			data:     params.Data,
+			filter:   *params.Filter,
+			opts:     params.ListOptions,
+		})
+	} else {
+		rows, err = c.Q.SearchGuildRoles(ctx, pgmodel.SearchGuildRolesParams{
+			AppID:          int64(params.AppID),
+			GuildID:        int64(params.GuildID),
+			Data:           params.Data,
+			ExcludeTainted: params.ExcludeTainted,
+			After: pgtype.Int8{
+				Int64: int64(params.After),
+				Valid: params.After != 0,
+			},
+			Limit: pgtype.Int4{
+				Int32: int32(params.Limit),
+				Valid: params.Limit != 0,
+			},
+			Offset: pgtype.Int4{
+				Int32: int32(params.Offset),
+				Valid: params.Offset != 0,
+			},
+		})
+	}
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, store.ErrNotFound
@@ -164,18 +219,37 @@ func (c *Client) SearchGuildRoles(ctx context.Context, params store.SearchGuildR
 }
 
 func (c *Client) SearchRoles(ctx context.Context, params store.SearchRolesParams) ([]*model.Role, error) {
-	rows, err := c.Q.SearchRoles(ctx, pgmodel.SearchRolesParams{
-		AppID: int64(params.AppID),
-		Data:  params.Data,
-		Limit: pgtype.Int4{
-			Int32: int32(params.Limit),
-			Valid: params.Limit != 0,
-		},
-		Offset: pgtype.Int4{
-			Int32: int32(params.Offset),
-			Valid: params.Offset != 0,
-		},
-	})
+	var rows []pgmodel.CacheRole
+	var err error
+	if params.Filter != nil {
+		rows, err = searchFiltered[pgmodel.CacheRole](ctx, c, filterSearch{
+			table:    "cache.roles",
+			columns:  "app_id, guild_id, role_id, data, tainted, created_at, updated_at",
+			idColumn: "role_id",
+			appID:    params.AppID,
+			data:     params.Data,
+			filter:   *params.Filter,
+			opts:     params.ListOptions,
+		})
+	} else {
+		rows, err = c.Q.SearchRoles(ctx, pgmodel.SearchRolesParams{
+			AppID:          int64(params.AppID),
+			Data:           params.Data,
+			ExcludeTainted: params.ExcludeTainted,
+			After: pgtype.Int8{
+				Int64: int64(params.After),
+				Valid: params.After != 0,
+			},
+			Limit: pgtype.Int4{
+				Int32: int32(params.Limit),
+				Valid: params.Limit != 0,
+			},
+			Offset: pgtype.Int4{
+				Int32: int32(params.Offset),
+				Valid: params.Offset != 0,
+			},
+		})
+	}
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, store.ErrNotFound
@@ -213,16 +287,16 @@ func (c *Client) CountRoles(ctx context.Context, appID snowflake.ID) (int, error
 	return int(res), nil
 }
 
-func (c *Client) UpsertRoles(ctx context.Context, roles ...store.UpsertRoleParams) error {
+func (c *Client) UpsertRoles(ctx context.Context, roles ...store.UpsertRoleParams) (int, error) {
 	if len(roles) == 0 {
-		return nil
+		return 0, nil
 	}
 
 	params := make([]pgmodel.UpsertRolesParams, len(roles))
 	for i, role := range roles {
 		data, err := json.Marshal(role.Data)
 		if err != nil {
-			return fmt.Errorf("failed to marshal role data: %w", err)
+			return 0, fmt.Errorf("failed to marshal role data: %w", err)
 		}
 
 		params[i] = pgmodel.UpsertRolesParams{
@@ -240,8 +314,7 @@ func (c *Client) UpsertRoles(ctx context.Context, roles ...store.UpsertRoleParam
 			},
 		}
 	}
-	res := c.Q.UpsertRoles(ctx, params)
-	return res.Close()
+	return countWritten(c.Q.UpsertRoles(ctx, params).QueryRow)
 }
 
 func (c *Client) DeleteRole(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, roleID snowflake.ID) error {
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/snowflake/v2"
@@ -44,17 +45,22 @@ func (c *Client) GetSticker(ctx context.Context, appID snowflake.ID, guildID sno
 	return rowToSticker(row)
 }
 
-func (c *Client) GetGuildStickers(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, limit int, offset int) ([]*model.Sticker, error) {
+func (c *Client) GetGuildStickers(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Sticker, error) {
 	rows, err := c.Q.GetGuildStickers(ctx, pgmodel.GetGuildStickersParams{
-		AppID:   int64(appID),
-		GuildID: int64(guildID),
+		AppID:          int64(appID),
+		GuildID:        int64(guildID),
+		ExcludeTainted: opts.ExcludeTainted,
+		After: pgtype.Int8{
+			Int64: int64(opts.After),
+			Valid: opts.After != 0,
+		},
 		Limit: pgtype.Int4{
-			Int32: int32(limit),
-			Valid: limit != 0,
+			Int32: int32(opts.Limit),
+			Valid: opts.Limit != 0,
 		},
 		Offset: pgtype.Int4{
-			Int32: int32(offset),
-			Valid: offset != 0,
+			Int32: int32(opts.Offset),
+			Valid: opts.Offset != 0,
 		},
 	})
 	if err != nil {
@@ -75,16 +81,21 @@ func (c *Client) GetGuildStickers(ctx context.Context, appID snowflake.ID, guild
 	return stickers, nil
 }
 
-func (c *Client) GetStickers(ctx context.Context, appID snowflake.ID, limit int, offset int) ([]*model.Sticker, error) {
+func (c *Client) GetStickers(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Sticker, error) {
 	rows, err := c.Q.GetStickers(ctx, pgmodel.GetStickersParams{
-		AppID: int64(appID),
+		AppID:          int64(appID),
+		ExcludeTainted: opts.ExcludeTainted,
+		After: pgtype.Int8{
+			Int64: int64(opts.After),
+			Valid: opts.After != 0,
+		},
 		Limit: pgtype.Int4{
-			Int32: int32(limit),
-			Valid: limit != 0,
+			Int32: int32(opts.Limit),
+			Valid: opts.Limit != 0,
 		},
 		Offset: pgtype.Int4{
-			Int32: int32(offset),
-			Valid: offset != 0,
+			Int32: int32(opts.Offset),
+			Valid: opts.Offset != 0,
 		},
 	})
 	if err != nil {
@@ -105,20 +116,65 @@ func (c *Client) GetStickers(ctx context.Context, appID snowflake.ID, limit int,
 	return stickers, nil
 }
 
-func (c *Client) SearchGuildStickers(ctx context.Context, params store.SearchGuildStickersParams) ([]*model.Sticker, error) {
-	rows, err := c.Q.SearchGuildStickers(ctx, pgmodel.SearchGuildStickersParams{
-		AppID:   int64(params.AppID),
-		GuildID: int64(params.GuildID),
-		Data:    params.Data,
-		Limit: pgtype.Int4{
-			Int32: int32(params.Limit),
-			Valid: params.Limit != 0,
-		},
-		Offset: pgtype.Int4{
-			Int32: int32(params.Offset),
-			Valid: params.Offset != 0,
-		},
+func (c *Client) GetStickersByIDs(ctx context.Context, appID snowflake.ID, stickerIDs []snowflake.ID) ([]*model.Sticker, error) {
+	stickerIDInts := make([]int64, len(stickerIDs))
+	for i, stickerID := range stickerIDs {
+		stickerIDInts[i] = int64(stickerID)
+	}
+
+	rows, err := c.Q.GetStickersByIDs(ctx, pgmodel.GetStickersByIDsParams{
+		AppID:      int64(appID),
+		StickerIds: stickerIDInts,
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	stickers := make([]*model.Sticker, len(rows))
+	for i, row := range rows {
+		sticker, err := rowToSticker(row)
+		if err != nil {
+			return nil, err
+		}
+		stickers[i] = sticker
+	}
+	return stickers, nil
+}
+
+func (c *Client) SearchGuildStickers(ctx context.Context, params store.SearchGuildStickersParams) ([]*model.Sticker, error) {
+	var rows []pgmodel.CacheSticker
+	var err error
+	if params.Filter != nil {
+		rows, err = searchFiltered[pgmodel.CacheSticker](ctx, c, filterSearch{
+			table:    "cache.stickers",
+			columns:  "app_id, guild_id, sticker_id, data, tainted, created_at, updated_at",
+			idColumn: "sticker_id",
+			appID:    params.AppID,
+			guildID:  params.GuildID,
+			data:     params.Data,
+			filter:   *params.Filter,
+			opts:     params.ListOptions,
+		})
+	} else {
+		rows, err = c.Q.SearchGuildStickers(ctx, pgmodel.SearchGuildStickersParams{
+			AppID:          int64(params.AppID),
+			GuildID:        int64(params.GuildID),
+			Data:           params.Data,
+			ExcludeTainted: params.ExcludeTainted,
+			After: pgtype.Int8{
+				Int64: int64(params.After),
+				Valid: params.After != 0,
+			},
+			Limit: pgtype.Int4{
+				Int32: int32(params.Limit),
+				Valid: params.Limit != 0,
+			},
+			Offset: pgtype.Int4{
+				Int32: int32(params.Offset),
+				Valid: params.Offset != 0,
+			},
+		})
+	}
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, store.ErrNotFound
@@ -138,18 +194,37 @@ func (c *Client) SearchGuildStickers(ctx context.Context, params store.SearchGui
 }
 
 func (c *Client) SearchStickers(ctx context.Context, params store.SearchStickersParams) ([]*model.Sticker, error) {
-	rows, err := c.Q.SearchStickers(ctx, pgmodel.SearchStickersParams{
-		AppID: int64(params.AppID),
-		Data:  params.Data,
-		Limit: pgtype.Int4{
-			Int32: int32(params.Limit),
-			Valid: params.Limit != 0,
-		},
-		Offset: pgtype.Int4{
-			Int32: int32(params.Offset),
-			Valid: params.Offset != 0,
-		},
-	})
+	var rows []pgmodel.CacheSticker
+	var err error
+	if params.Filter != nil {
+		rows, err = searchFiltered[pgmodel.CacheSticker](ctx, c, filterSearch{
+			table:    "cache.stickers",
+			columns:  "app_id, guild_id, sticker_id, data, tainted, created_at, updated_at",
+			idColumn: "sticker_id",
+			appID:    params.AppID,
+			data:     params.Data,
+			filter:   *params.Filter,
+			opts:     params.ListOptions,
+		})
+	} else {
+		rows, err = c.Q.SearchStickers(ctx, pgmodel.SearchStickersParams{
+			AppID:          int64(params.AppID),
+			Data:           params.Data,
+			ExcludeTainted: params.ExcludeTainted,
+			After: pgtype.Int8{
+				Int64: int64(params.After),
+				Valid: params.After != 0,
+			},
+			Limit: pgtype.Int4{
+				Int32: int32(params.Limit),
+				Valid: params.Limit != 0,
+			},
+			Offset: pgtype.Int4{
+				Int32: int32(params.Offset),
+				Valid: params.Offset != 0,
+			},
+		})
+	}
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, store.ErrNotFound
@@ -187,16 +262,97 @@ func (c *Client) CountStickers(ctx context.Context, appID snowflake.ID) (int, er
 	return int(res), nil
 }
 
-func (c *Client) UpsertStickers(ctx context.Context, stickers ...store.UpsertStickerParams) error {
+func (c *Client) UpsertStickers(ctx context.Context, stickers ...store.UpsertStickerParams) (int, error) {
 	if len(stickers) == 0 {
-		return nil
+		return 0, nil
+	}
+
+	params, err := upsertStickersParams(stickers)
+	if err != nil {
+		return 0, err
+	}
+
+	return countWritten(c.Q.UpsertStickers(ctx, params).QueryRow)
+}
+
+func (c *Client) ReplaceGuildStickers(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, updatedAt time.Time, stickers ...store.UpsertStickerParams) (bool, error) {
+	params, err := upsertStickersParams(stickers)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := c.DB.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	q := c.Q.WithTx(tx)
+
+	// The row of the collection is locked until the transaction ends, so concurrent replaces of the guild are serialized
+	_, err = q.UpsertGuildCollection(ctx, pgmodel.UpsertGuildCollectionParams{
+		AppID:      int64(appID),
+		GuildID:    int64(guildID),
+		Collection: guildCollectionStickers,
+		UpdatedAt: pgtype.Timestamp{
+			Time:  updatedAt,
+			Valid: true,
+		},
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to update guild stickers timestamp: %w", err)
+	}
+
+	if len(params) != 0 {
+		res := q.UpsertStickers(ctx, params)
+		if err := res.Close(); err != nil {
+			return false, fmt.Errorf("failed to upsert stickers: %w", err)
+		}
+	}
+
+	stickerIDs := make([]int64, len(stickers))
+	for i, sticker := range stickers {
+		stickerIDs[i] = int64(sticker.StickerID)
+	}
+
+	err = q.DeleteGuildStickersExcept(ctx, pgmodel.DeleteGuildStickersExceptParams{
+		AppID:      int64(appID),
+		GuildID:    int64(guildID),
+		StickerIds: stickerIDs,
+		UpdatedAt: pgtype.Timestamp{
+			Time:  updatedAt,
+			Valid: true,
+		},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete removed stickers: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	return true, nil
+}
+
+func (c *Client) DeleteSticker(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, stickerID snowflake.ID) error {
+	return c.Q.DeleteSticker(ctx, pgmodel.DeleteStickerParams{
+		AppID:     int64(appID),
+		GuildID:   int64(guildID),
+		StickerID: int64(stickerID),
+	})
+}
+
+func upsertStickersParams(stickers []store.UpsertStickerParams) ([]pgmodel.UpsertStickersParams, error) {
 	params := make([]pgmodel.UpsertStickersParams, len(stickers))
 	for i, sticker := range stickers {
 		data, err := json.Marshal(sticker.Data)
 		if err != nil {
-			return fmt.Errorf("failed to marshal sticker data: %w", err)
+			return nil, fmt.Errorf("failed to marshal sticker data: %w", err)
 		}
 
 		params[i] = pgmodel.UpsertStickersParams{
@@ -214,16 +370,7 @@ func (c *Client) UpsertStickers(ctx context.Context, stickers ...store.UpsertSti
 			},
 		}
 	}
-	res := c.Q.UpsertStickers(ctx, params)
-	return res.Close()
-}
-
-func (c *Client) DeleteSticker(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, stickerID snowflake.ID) error {
-	return c.Q.DeleteSticker(ctx, pgmodel.DeleteStickerParams{
-		AppID:     int64(appID),
-		GuildID:   int64(guildID),
-		StickerID: int64(stickerID),
-	})
+	return params, nil
 }
 
 func rowToSticker(row pgmodel.CacheSticker) (*model.Sticker, error) {
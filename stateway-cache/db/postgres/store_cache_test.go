@@ -0,0 +1,28 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"github.com/merlinfuchs/stateway/stateway-cache/store/storetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresCacheStore(t *testing.T) {
+	storetest.TestCacheStore(t, func(t *testing.T) store.CacheStore {
+		client := newTestClient(t)
+
+		// The subtests expect an empty store, but all of them share the test database
+		_, err := client.DB.Exec(context.Background(), `TRUNCATE
+			cache.guilds,
+			cache.roles,
+			cache.channels,
+			cache.emojis,
+			cache.stickers,
+			cache.guild_collections`)
+		require.NoError(t, err)
+
+		return client
+	})
+}
@@ -0,0 +1,125 @@
+package redis
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+
+	"github.com/disgoorg/snowflake/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const DefaultKeyPrefix = "stateway:cache:"
+
+type entityKind string
+
+const (
+	entityKindGuild   entityKind = "guild"
+	entityKindRole    entityKind = "role"
+	entityKindChannel entityKind = "channel"
+	entityKindEmoji   entityKind = "emoji"
+	entityKindSticker entityKind = "sticker"
+)
+
+var (
+	//go:embed scripts/upsert_entities.lua
+	upsertEntitiesSource string
+	upsertEntitiesScript = goredis.NewScript(upsertEntitiesSource)
+
+	//go:embed scripts/replace_guild_entities.lua
+	replaceGuildEntitiesSource string
+	replaceGuildEntitiesScript = goredis.NewScript(replaceGuildEntitiesSource)
+
+	//go:embed scripts/mark_shard_tainted.lua
+	markShardTaintedSource string
+	markShardTaintedScript = goredis.NewScript(markShardTaintedSource)
+
+	//go:embed scripts/delete_shard_tainted.lua
+	deleteShardTaintedSource string
+	deleteShardTaintedScript = goredis.NewScript(deleteShardTaintedSource)
+
+	//go:embed scripts/get_full_guild.lua
+	getFullGuildSource string
+	getFullGuildScript = goredis.NewScript(getFullGuildSource)
+)
+
+// Client is a cache store backed by Redis.
+//
+// Entities are stored as JSON in one hash per entity kind and app (<prefix><kind>:<app_id>)
+// keyed by the entity ID. Guild-wide listings use one set per guild (<prefix><kind>:<app_id>:<guild_id>).
+// Tainted flags live in separate sets so whole shards can be flagged without rewriting the entities.
+//
+// Only a single Redis node is supported. The Lua scripts build the names of the keys they touch from their
+// arguments, which Redis Cluster rejects because the keys can't be routed to a slot up front.
+type Client struct {
+	rdb       *goredis.Client
+	keyPrefix string
+}
+
+type ClientConfig struct {
+	Address   string
+	Username  string
+	Password  string
+	DB        int
+	KeyPrefix string
+}
+
+func New(ctx context.Context, config ClientConfig) (*Client, error) {
+	rdb := goredis.NewClient(&goredis.Options{
+		Addr:     config.Address,
+		Username: config.Username,
+		Password: config.Password,
+		DB:       config.DB,
+	})
+
+	err := rdb.Ping(ctx).Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return NewWithClient(rdb, config.KeyPrefix), nil
+}
+
+// NewWithClient creates a cache store from an existing Redis client.
+// An empty key prefix falls back to DefaultKeyPrefix, the client has to connect to a single node.
+func NewWithClient(rdb *goredis.Client, keyPrefix string) *Client {
+	if keyPrefix == "" {
+		keyPrefix = DefaultKeyPrefix
+	}
+
+	return &Client{
+		rdb:       rdb,
+		keyPrefix: keyPrefix,
+	}
+}
+
+func (c *Client) Close() error {
+	return c.rdb.Close()
+}
+
+func (c *Client) entitiesKey(kind entityKind, appID snowflake.ID) string {
+	return fmt.Sprintf("%s%s:%s", c.keyPrefix, kind, appID)
+}
+
+func (c *Client) guildEntitiesKey(kind entityKind, appID snowflake.ID, guildID snowflake.ID) string {
+	return fmt.Sprintf("%s%s:%s:%s", c.keyPrefix, kind, appID, guildID)
+}
+
+func (c *Client) taintedKey(kind entityKind, appID snowflake.ID) string {
+	return fmt.Sprintf("%stainted:%s:%s", c.keyPrefix, kind, appID)
+}
+
+// collectionsKey holds when the entities of each guild have last been replaced as a whole.
+func (c *Client) collectionsKey(kind entityKind, appID snowflake.ID) string {
+	return fmt.Sprintf("%scollections:%s:%s", c.keyPrefix, kind, appID)
+}
+
+// guildIDsKey holds every guild of an app that owns at least one entity.
+func (c *Client) guildIDsKey(appID snowflake.ID) string {
+	return fmt.Sprintf("%sguild_ids:%s", c.keyPrefix, appID)
+}
+
+// guildAppsKey holds every app that has the guild cached.
+func (c *Client) guildAppsKey(guildID snowflake.ID) string {
+	return fmt.Sprintf("%sguild_apps:%s", c.keyPrefix, guildID)
+}
@@ -0,0 +1,42 @@
+-- Deletes tainted guilds with all of their entities and tainted entities of the remaining guilds.
+-- ARGV[1]: key prefix
+-- ARGV[2]: app_id
+-- ARGV[3..]: guild_ids of the shard that are not kept
+local prefix, app = ARGV[1], ARGV[2]
+local childKinds = { 'role', 'channel', 'emoji', 'sticker' }
+local guildsKey = prefix .. 'guild:' .. app
+local taintedGuildsKey = prefix .. 'tainted:guild:' .. app
+
+for i = 3, #ARGV do
+    local guild = ARGV[i]
+
+    local removed = redis.call('SISMEMBER', taintedGuildsKey, guild) == 1
+    if removed then
+        redis.call('HDEL', guildsKey, guild)
+        redis.call('SREM', taintedGuildsKey, guild)
+        redis.call('SREM', prefix .. 'guild_apps:' .. guild, app)
+    end
+
+    local remaining = redis.call('HEXISTS', guildsKey, guild)
+    for _, kind in ipairs(childKinds) do
+        local entitiesKey = prefix .. kind .. ':' .. app
+        local guildKey = entitiesKey .. ':' .. guild
+        local taintedKey = prefix .. 'tainted:' .. kind .. ':' .. app
+
+        for _, id in ipairs(redis.call('SMEMBERS', guildKey)) do
+            if removed or redis.call('SISMEMBER', taintedKey, id) == 1 then
+                redis.call('HDEL', entitiesKey, id)
+                redis.call('SREM', guildKey, id)
+                redis.call('SREM', taintedKey, id)
+            end
+        end
+
+        remaining = remaining + redis.call('SCARD', guildKey)
+    end
+
+    if remaining == 0 then
+        redis.call('SREM', prefix .. 'guild_ids:' .. app, guild)
+    end
+end
+
+return #ARGV - 2
@@ -0,0 +1,33 @@
+-- Reads a guild and the entities of the included kinds atomically.
+-- ARGV[1]: key prefix
+-- ARGV[2]: app_id
+-- ARGV[3]: guild_id
+-- ARGV[4..]: included kinds
+-- Returns nil if the guild doesn't exist, otherwise the guild data, its tainted flag
+-- and one list of entity_id, data, tainted triples per included kind.
+local prefix, app, guild = ARGV[1], ARGV[2], ARGV[3]
+
+local data = redis.call('HGET', prefix .. 'guild:' .. app, guild)
+if not data then
+    return false
+end
+
+local result = { data, redis.call('SISMEMBER', prefix .. 'tainted:guild:' .. app, guild) }
+for i = 4, #ARGV do
+    local kind = ARGV[i]
+    local entitiesKey = prefix .. kind .. ':' .. app
+    local taintedKey = prefix .. 'tainted:' .. kind .. ':' .. app
+
+    local entities = {}
+    for _, id in ipairs(redis.call('SMEMBERS', entitiesKey .. ':' .. guild)) do
+        local entity = redis.call('HGET', entitiesKey, id)
+        if entity then
+            table.insert(entities, id)
+            table.insert(entities, entity)
+            table.insert(entities, redis.call('SISMEMBER', taintedKey, id))
+        end
+    end
+    table.insert(result, entities)
+end
+
+return result
@@ -0,0 +1,23 @@
+-- Flags a guild and all of its entities as tainted.
+-- ARGV[1]: key prefix
+-- ARGV[2]: app_id
+-- ARGV[3..]: guild_ids of the shard
+local prefix, app = ARGV[1], ARGV[2]
+local childKinds = { 'role', 'channel', 'emoji', 'sticker' }
+
+for i = 3, #ARGV do
+    local guild = ARGV[i]
+
+    if redis.call('HEXISTS', prefix .. 'guild:' .. app, guild) == 1 then
+        redis.call('SADD', prefix .. 'tainted:guild:' .. app, guild)
+    end
+
+    for _, kind in ipairs(childKinds) do
+        local taintedKey = prefix .. 'tainted:' .. kind .. ':' .. app
+        for _, id in ipairs(redis.call('SMEMBERS', prefix .. kind .. ':' .. app .. ':' .. guild)) do
+            redis.call('SADD', taintedKey, id)
+        end
+    end
+end
+
+return #ARGV - 2
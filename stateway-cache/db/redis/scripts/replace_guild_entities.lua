@@ -0,0 +1,66 @@
+-- Replaces all entities of one kind in a guild.
+-- The replace is skipped when the entities of the guild have been replaced by a more recent event,
+-- and entities that are stored with a more recent updated_at are neither overwritten nor deleted.
+-- ARGV[1]: key prefix
+-- ARGV[2]: kind
+-- ARGV[3]: app_id
+-- ARGV[4]: guild_id
+-- ARGV[5]: updated_at
+-- ARGV[6..]: pairs of entity_id, data
+-- Returns 1 when the entities have been replaced and 0 when the replace is stale.
+local prefix, kind, app, guild, updatedAt = ARGV[1], ARGV[2], ARGV[3], ARGV[4], ARGV[5]
+local entitiesKey = prefix .. kind .. ':' .. app
+local guildKey = entitiesKey .. ':' .. guild
+local taintedKey = prefix .. 'tainted:' .. kind .. ':' .. app
+local collectionsKey = prefix .. 'collections:' .. kind .. ':' .. app
+
+local replacedAt = redis.call('HGET', collectionsKey, guild)
+if replacedAt and replacedAt > updatedAt then
+    return 0
+end
+redis.call('HSET', collectionsKey, guild, updatedAt)
+
+-- Normalizes an RFC 3339 UTC timestamp to the layout of the updated_at argument, e.g. 2006-01-02T15:04:05.000000000
+local function normalizeTimestamp(value)
+    local seconds, fraction = string.match(value, '^(%d+%-%d+%-%d+T%d+:%d+:%d+)%.?(%d*)')
+    if not seconds then
+        return ''
+    end
+    return seconds .. '.' .. string.sub(fraction .. '000000000', 1, 9)
+end
+
+local function isNewer(id)
+    local existing = redis.call('HGET', entitiesKey, id)
+    if not existing then
+        return false
+    end
+    local storedAt = cjson.decode(existing)['updated_at']
+    return type(storedAt) == 'string' and normalizeTimestamp(storedAt) > updatedAt
+end
+
+local replaced = {}
+for i = 6, #ARGV, 2 do
+    replaced[ARGV[i]] = true
+end
+
+for _, id in ipairs(redis.call('SMEMBERS', guildKey)) do
+    if not replaced[id] and not isNewer(id) then
+        redis.call('HDEL', entitiesKey, id)
+        redis.call('SREM', guildKey, id)
+        redis.call('SREM', taintedKey, id)
+    end
+end
+
+for i = 6, #ARGV, 2 do
+    if not isNewer(ARGV[i]) then
+        redis.call('HSET', entitiesKey, ARGV[i], ARGV[i + 1])
+        redis.call('SADD', guildKey, ARGV[i])
+        redis.call('SREM', taintedKey, ARGV[i])
+    end
+end
+
+if redis.call('SCARD', guildKey) > 0 then
+    redis.call('SADD', prefix .. 'guild_ids:' .. app, guild)
+end
+
+return 1
@@ -0,0 +1,42 @@
+-- Upserts entities of any kind and clears their tainted flag.
+-- Entities that are stored with a more recent updated_at are skipped, so older data never overwrites newer data.
+-- ARGV[1]: key prefix
+-- ARGV[2..]: groups of kind, app_id, guild_id, entity_id, updated_at, data
+local prefix = ARGV[1]
+local count = 0
+
+-- Normalizes an RFC 3339 UTC timestamp to the layout of the updated_at arguments, e.g. 2006-01-02T15:04:05.000000000
+local function normalizeTimestamp(value)
+    local seconds, fraction = string.match(value, '^(%d+%-%d+%-%d+T%d+:%d+:%d+)%.?(%d*)')
+    if not seconds then
+        return ''
+    end
+    return seconds .. '.' .. string.sub(fraction .. '000000000', 1, 9)
+end
+
+for i = 2, #ARGV, 6 do
+    local kind, app, guild, id, updatedAt, data = ARGV[i], ARGV[i + 1], ARGV[i + 2], ARGV[i + 3], ARGV[i + 4], ARGV[i + 5]
+    local entitiesKey = prefix .. kind .. ':' .. app
+
+    local stale = false
+    local existing = redis.call('HGET', entitiesKey, id)
+    if existing then
+        local storedAt = cjson.decode(existing)['updated_at']
+        stale = type(storedAt) == 'string' and normalizeTimestamp(storedAt) > updatedAt
+    end
+
+    if not stale then
+        redis.call('HSET', entitiesKey, id, data)
+        redis.call('SREM', prefix .. 'tainted:' .. kind .. ':' .. app, id)
+        redis.call('SADD', prefix .. 'guild_ids:' .. app, guild)
+        if kind == 'guild' then
+            redis.call('SADD', prefix .. 'guild_apps:' .. guild, app)
+        else
+            redis.call('SADD', entitiesKey .. ':' .. guild, id)
+        end
+
+        count = count + 1
+    end
+end
+
+return count
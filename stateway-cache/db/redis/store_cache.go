@@ -0,0 +1,594 @@
+package redis
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+var _ store.CacheStore = (*Client)(nil)
+
+func (c *Client) MarkShardEntitiesTainted(ctx context.Context, params store.MarkShardEntitiesTaintedParams) error {
+	guildIDs, err := c.shardGuildIDs(ctx, params.AppID, params.InShard, nil)
+	if err != nil {
+		return err
+	}
+	if len(guildIDs) == 0 {
+		return nil
+	}
+
+	err = markShardTaintedScript.Run(ctx, c.rdb, nil, shardScriptArgs(c.keyPrefix, params.AppID, guildIDs)...).Err()
+	if err != nil {
+		return fmt.Errorf("failed to mark shard entities tainted: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) DeleteShardTaintedEntities(ctx context.Context, params store.DeleteShardTaintedEntitiesParams) error {
+	guildIDs, err := c.shardGuildIDs(ctx, params.AppID, params.InShard, params.KeepGuildIDs)
+	if err != nil {
+		return err
+	}
+	if len(guildIDs) == 0 {
+		return nil
+	}
+
+	err = deleteShardTaintedScript.Run(ctx, c.rdb, nil, shardScriptArgs(c.keyPrefix, params.AppID, guildIDs)...).Err()
+	if err != nil {
+		return fmt.Errorf("failed to delete shard tainted entities: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) MassUpsertEntities(ctx context.Context, params store.MassUpsertEntitiesParams) error {
+	entries := make([]upsertEntry, 0, len(params.Guilds)+len(params.Roles)+len(params.Channels)+len(params.Emojis)+len(params.Stickers))
+	for _, guild := range params.Guilds {
+		entries = append(entries, guildUpsertEntry(guild))
+	}
+	for _, role := range params.Roles {
+		entries = append(entries, roleUpsertEntry(role))
+	}
+	for _, channel := range params.Channels {
+		entries = append(entries, channelUpsertEntry(channel))
+	}
+	for _, emoji := range params.Emojis {
+		entries = append(entries, emojiUpsertEntry(emoji))
+	}
+	for _, sticker := range params.Stickers {
+		entries = append(entries, stickerUpsertEntry(sticker))
+	}
+
+	_, err := c.upsertEntities(ctx, entries)
+	return err
+}
+
+// deleteAppKeysBatchSize is the number of keys unlinked per command when purging an app.
+const deleteAppKeysBatchSize = 1000
+
+func (c *Client) DeleteAppEntities(ctx context.Context, appID snowflake.ID) (store.DeletedEntities, error) {
+	var deleted store.DeletedEntities
+
+	guildIDs, err := listIDs(c.rdb.SMembers(ctx, c.guildIDsKey(appID)))
+	if err != nil {
+		return deleted, fmt.Errorf("failed to get guild ids: %w", err)
+	}
+
+	kinds := []struct {
+		kind  entityKind
+		count *int
+	}{
+		{entityKindGuild, &deleted.Guilds},
+		{entityKindRole, &deleted.Roles},
+		{entityKindChannel, &deleted.Channels},
+		{entityKindEmoji, &deleted.Emojis},
+		{entityKindSticker, &deleted.Stickers},
+	}
+
+	keys := []string{c.guildIDsKey(appID), c.collectionsKey(entityKindEmoji, appID), c.collectionsKey(entityKindSticker, appID)}
+	counts := make([]*goredis.IntCmd, len(kinds))
+	pipe := c.rdb.Pipeline()
+	for i, k := range kinds {
+		counts[i] = pipe.HLen(ctx, c.entitiesKey(k.kind, appID))
+		keys = append(keys, c.entitiesKey(k.kind, appID), c.taintedKey(k.kind, appID))
+		if k.kind == entityKindGuild {
+			continue
+		}
+		for _, guildID := range guildIDs {
+			keys = append(keys, c.guildEntitiesKey(k.kind, appID, guildID))
+		}
+	}
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to count app entities: %w", err)
+	}
+	for i, k := range kinds {
+		*k.count = int(counts[i].Val())
+	}
+
+	pipe = c.rdb.Pipeline()
+	for _, guildID := range guildIDs {
+		pipe.SRem(ctx, c.guildAppsKey(guildID), appID.String())
+	}
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to remove app from guild apps: %w", err)
+	}
+
+	// UNLINK frees the memory in the background, so large apps don't block Redis
+	for batch := range slices.Chunk(keys, deleteAppKeysBatchSize) {
+		err = c.rdb.Unlink(ctx, batch...).Err()
+		if err != nil {
+			return deleted, fmt.Errorf("failed to unlink app keys: %w", err)
+		}
+	}
+	return deleted, nil
+}
+
+func (c *Client) GetCacheStats(ctx context.Context, params store.GetCacheStatsParams) ([]*model.CacheStats, error) {
+	appIDs := []snowflake.ID{params.AppID}
+	if params.AppID == 0 {
+		var err error
+		appIDs, err = c.appIDs(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	collector := store.NewStatsCollector()
+	for _, appID := range appIDs {
+		stats := collector.App(appID)
+		kinds := []struct {
+			kind  entityKind
+			stats *model.EntityStats
+		}{
+			{entityKindGuild, &stats.Guilds},
+			{entityKindRole, &stats.Roles},
+			{entityKindChannel, &stats.Channels},
+			{entityKindEmoji, &stats.Emojis},
+			{entityKindSticker, &stats.Stickers},
+		}
+		for _, k := range kinds {
+			err := c.collectStats(ctx, k.kind, appID, k.stats)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return collector.Stats(), nil
+}
+
+// appIDs returns all apps that have entities in the cache.
+func (c *Client) appIDs(ctx context.Context) ([]snowflake.ID, error) {
+	prefix := c.keyPrefix + "guild_ids:"
+
+	appIDs := make([]snowflake.ID, 0)
+	iter := c.rdb.Scan(ctx, 0, prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		appID, err := snowflake.Parse(strings.TrimPrefix(iter.Val(), prefix))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse app id of key %q: %w", iter.Val(), err)
+		}
+		appIDs = append(appIDs, appID)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan app ids: %w", err)
+	}
+	return appIDs, nil
+}
+
+// collectStats adds all entities of the kind to the stats.
+// The hash is scanned in batches and only the fields the stats depend on are decoded.
+func (c *Client) collectStats(ctx context.Context, kind entityKind, appID snowflake.ID, stats *model.EntityStats) error {
+	tainted, err := c.rdb.SMembersMap(ctx, c.taintedKey(kind, appID)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get tainted %ss: %w", kind, err)
+	}
+
+	iter := c.rdb.HScan(ctx, c.entitiesKey(kind, appID), 0, "", 1000).Iterator()
+	for iter.Next(ctx) {
+		entityID := iter.Val()
+		if !iter.Next(ctx) {
+			break
+		}
+
+		var entity store.StatsEntity
+		err := json.Unmarshal([]byte(iter.Val()), &entity)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal %s: %w", kind, err)
+		}
+
+		_, entity.Tainted = tainted[entityID]
+		store.AddEntityStats(stats, entity)
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("failed to scan %ss: %w", kind, err)
+	}
+	return nil
+}
+
+// shardGuildIDs returns all known guilds of the app that belong to the shard.
+// The shard is computed in Go because Lua numbers can't represent snowflakes exactly.
+func (c *Client) shardGuildIDs(
+	ctx context.Context,
+	appID snowflake.ID,
+	inShard func(guildID snowflake.ID) bool,
+	keepGuildIDs []snowflake.ID,
+) ([]snowflake.ID, error) {
+	guildIDs, err := listIDs(c.rdb.SMembers(ctx, c.guildIDsKey(appID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild ids: %w", err)
+	}
+
+	return slices.DeleteFunc(guildIDs, func(guildID snowflake.ID) bool {
+		return !inShard(guildID) || slices.Contains(keepGuildIDs, guildID)
+	}), nil
+}
+
+func shardScriptArgs(keyPrefix string, appID snowflake.ID, guildIDs []snowflake.ID) []any {
+	args := make([]any, 0, len(guildIDs)+2)
+	args = append(args, keyPrefix, appID.String())
+	for _, guildID := range guildIDs {
+		args = append(args, guildID.String())
+	}
+	return args
+}
+
+// scriptTimestampLayout formats timestamps so the upsert script can compare them as strings.
+// It matches how the script normalizes the updated_at of stored entities.
+const scriptTimestampLayout = "2006-01-02T15:04:05.000000000"
+
+type upsertEntry struct {
+	kind     entityKind
+	appID    snowflake.ID
+	guildID  snowflake.ID
+	entityID snowflake.ID
+	// updatedAt decides whether the entry is newer than the stored entity.
+	updatedAt time.Time
+	entity    any
+}
+
+// upsertEntities returns the number of entries that have been written.
+func (c *Client) upsertEntities(ctx context.Context, entries []upsertEntry) (int, error) {
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	args := make([]any, 0, len(entries)*6+1)
+	args = append(args, c.keyPrefix)
+	for _, entry := range entries {
+		data, err := json.Marshal(entry.entity)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal %s: %w", entry.kind, err)
+		}
+
+		args = append(args,
+			string(entry.kind),
+			entry.appID.String(),
+			entry.guildID.String(),
+			entry.entityID.String(),
+			entry.updatedAt.UTC().Format(scriptTimestampLayout),
+			data,
+		)
+	}
+
+	written, err := upsertEntitiesScript.Run(ctx, c.rdb, nil, args...).Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to upsert entities: %w", err)
+	}
+	return written, nil
+}
+
+// replaceGuildEntities reports whether the entities have been replaced, see replace_guild_entities.lua.
+func (c *Client) replaceGuildEntities(
+	ctx context.Context,
+	kind entityKind,
+	appID snowflake.ID,
+	guildID snowflake.ID,
+	updatedAt time.Time,
+	entries []upsertEntry,
+) (bool, error) {
+	args := make([]any, 0, len(entries)*2+5)
+	args = append(args, c.keyPrefix, string(kind), appID.String(), guildID.String(), updatedAt.UTC().Format(scriptTimestampLayout))
+	for _, entry := range entries {
+		data, err := json.Marshal(entry.entity)
+		if err != nil {
+			return false, fmt.Errorf("failed to marshal %s: %w", kind, err)
+		}
+
+		args = append(args, entry.entityID.String(), data)
+	}
+
+	replaced, err := replaceGuildEntitiesScript.Run(ctx, c.rdb, nil, args...).Bool()
+	if err != nil {
+		return false, fmt.Errorf("failed to replace guild %ss: %w", kind, err)
+	}
+	return replaced, nil
+}
+
+func (c *Client) deleteGuildEntity(
+	ctx context.Context,
+	kind entityKind,
+	appID snowflake.ID,
+	guildID snowflake.ID,
+	entityID snowflake.ID,
+) error {
+	_, err := c.rdb.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.HDel(ctx, c.entitiesKey(kind, appID), entityID.String())
+		pipe.SRem(ctx, c.guildEntitiesKey(kind, appID, guildID), entityID.String())
+		pipe.SRem(ctx, c.taintedKey(kind, appID), entityID.String())
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", kind, err)
+	}
+	return nil
+}
+
+// listIDs parses the members of a set or the fields of a hash and sorts them.
+func listIDs(cmd *goredis.StringSliceCmd) ([]snowflake.ID, error) {
+	members, err := cmd.Result()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]snowflake.ID, 0, len(members))
+	for _, member := range members {
+		id, err := snowflake.Parse(member)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse id %q: %w", member, err)
+		}
+		ids = append(ids, id)
+	}
+
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// pageIDs applies the list options to a sorted list of entity IDs.
+func (c *Client) pageIDs(ctx context.Context, kind entityKind, appID snowflake.ID, ids []snowflake.ID, opts store.ListOptions) ([]snowflake.ID, error) {
+	if opts.ExcludeTainted {
+		tainted, err := c.rdb.SMembersMap(ctx, c.taintedKey(kind, appID)).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tainted %ss: %w", kind, err)
+		}
+
+		if len(tainted) > 0 {
+			ids = slices.DeleteFunc(ids, func(id snowflake.ID) bool {
+				_, ok := tainted[id.String()]
+				return ok
+			})
+		}
+	}
+
+	if opts.After != 0 {
+		start, _ := slices.BinarySearch(ids, opts.After+1)
+		ids = ids[start:]
+	}
+
+	if opts.Offset > 0 {
+		if opts.Offset >= len(ids) {
+			return nil, nil
+		}
+		ids = ids[opts.Offset:]
+	}
+	if opts.Limit > 0 && opts.Limit < len(ids) {
+		ids = ids[:opts.Limit]
+	}
+	return ids, nil
+}
+
+// getEntity loads a single entity and applies its tainted flag.
+func getEntity[T any](
+	ctx context.Context,
+	c *Client,
+	kind entityKind,
+	appID snowflake.ID,
+	entityID snowflake.ID,
+	setTainted func(*T),
+) (*T, error) {
+	var dataCmd *goredis.StringCmd
+	var taintedCmd *goredis.BoolCmd
+	_, err := c.rdb.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		dataCmd = pipe.HGet(ctx, c.entitiesKey(kind, appID), entityID.String())
+		taintedCmd = pipe.SIsMember(ctx, c.taintedKey(kind, appID), entityID.String())
+		return nil
+	})
+	if err != nil && !errors.Is(err, goredis.Nil) {
+		return nil, fmt.Errorf("failed to get %s: %w", kind, err)
+	}
+
+	data, err := dataCmd.Bytes()
+	if err != nil {
+		if errors.Is(err, goredis.Nil) {
+			return nil, store.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get %s: %w", kind, err)
+	}
+
+	var entity T
+	err = json.Unmarshal(data, &entity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", kind, err)
+	}
+
+	if taintedCmd.Val() {
+		setTainted(&entity)
+	}
+	return &entity, nil
+}
+
+// getEntities loads the entities with the given IDs in order and skips IDs that don't exist.
+func getEntities[T any](
+	ctx context.Context,
+	c *Client,
+	kind entityKind,
+	appID snowflake.ID,
+	ids []snowflake.ID,
+	setTainted func(*T),
+) ([]*T, error) {
+	if len(ids) == 0 {
+		return []*T{}, nil
+	}
+
+	fields := make([]string, len(ids))
+	members := make([]any, len(ids))
+	for i, id := range ids {
+		fields[i] = id.String()
+		members[i] = fields[i]
+	}
+
+	var dataCmd *goredis.SliceCmd
+	var taintedCmd *goredis.BoolSliceCmd
+	_, err := c.rdb.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		dataCmd = pipe.HMGet(ctx, c.entitiesKey(kind, appID), fields...)
+		taintedCmd = pipe.SMIsMember(ctx, c.taintedKey(kind, appID), members...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %ss: %w", kind, err)
+	}
+
+	tainted := taintedCmd.Val()
+	entities := make([]*T, 0, len(ids))
+	for i, value := range dataCmd.Val() {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		var entity T
+		err = json.Unmarshal([]byte(data), &entity)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s: %w", kind, err)
+		}
+
+		if i < len(tainted) && tainted[i] {
+			setTainted(&entity)
+		}
+		entities = append(entities, &entity)
+	}
+	return entities, nil
+}
+
+// decodeScriptEntity decodes the data and tainted flag of an entity returned by a script.
+func decodeScriptEntity[T any](kind entityKind, data any, tainted any, setTainted func(*T)) (*T, error) {
+	value, ok := data.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected %s data type %T", kind, data)
+	}
+
+	var entity T
+	err := json.Unmarshal([]byte(value), &entity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", kind, err)
+	}
+
+	if flag, _ := tainted.(int64); flag == 1 {
+		setTainted(&entity)
+	}
+	return &entity, nil
+}
+
+// decodeScriptEntities decodes a list of entity_id, data, tainted triples returned by a script
+// and orders the entities by ID.
+func decodeScriptEntities[T any](kind entityKind, reply any, excludeTainted bool, setTainted func(*T)) ([]*T, error) {
+	values, ok := reply.([]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected %s list type %T", kind, reply)
+	}
+
+	type entry struct {
+		id     snowflake.ID
+		entity *T
+	}
+
+	entries := make([]entry, 0, len(values)/3)
+	for i := 0; i+2 < len(values); i += 3 {
+		if flag, _ := values[i+2].(int64); excludeTainted && flag == 1 {
+			continue
+		}
+
+		rawID, _ := values[i].(string)
+		id, err := snowflake.Parse(rawID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse id %q: %w", rawID, err)
+		}
+
+		entity, err := decodeScriptEntity(kind, values[i+1], values[i+2], setTainted)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{id: id, entity: entity})
+	}
+
+	slices.SortFunc(entries, func(a, b entry) int {
+		return cmp.Compare(a.id, b.id)
+	})
+
+	entities := make([]*T, len(entries))
+	for i, entry := range entries {
+		entities[i] = entry.entity
+	}
+	return entities, nil
+}
+
+// listEntities loads one page of the entities with the given sorted IDs.
+func listEntities[T any](
+	ctx context.Context,
+	c *Client,
+	kind entityKind,
+	appID snowflake.ID,
+	ids []snowflake.ID,
+	opts store.ListOptions,
+	setTainted func(*T),
+) ([]*T, error) {
+	ids, err := c.pageIDs(ctx, kind, appID, ids, opts)
+	if err != nil {
+		return nil, err
+	}
+	return getEntities(ctx, c, kind, appID, ids, setTainted)
+}
+
+// searchEntities loads all entities with the given sorted IDs and returns the page of entities
+// that match the data and filter of the search.
+func searchEntities[T any](
+	ctx context.Context,
+	c *Client,
+	kind entityKind,
+	appID snowflake.ID,
+	ids []snowflake.ID,
+	data json.RawMessage,
+	filter *model.Filter,
+	opts store.ListOptions,
+	getData func(*T) any,
+	setTainted func(*T),
+) ([]*T, error) {
+	ids, err := c.pageIDs(ctx, kind, appID, ids, store.ListOptions{ExcludeTainted: opts.ExcludeTainted, After: opts.After})
+	if err != nil {
+		return nil, err
+	}
+
+	entities, err := getEntities(ctx, c, kind, appID, ids, setTainted)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.SearchEntities(entities, data, filter, opts, getData)
+}
+
+func timestampOrNow(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Now().UTC()
+	}
+	return t.UTC()
+}
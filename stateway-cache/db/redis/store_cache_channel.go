@@ -0,0 +1,141 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+)
+
+func (c *Client) GetGuildChannel(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, channelID snowflake.ID) (*model.Channel, error) {
+	channel, err := c.GetChannel(ctx, appID, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if channel.GuildID != guildID {
+		return nil, store.ErrNotFound
+	}
+	return channel, nil
+}
+
+func (c *Client) GetChannel(ctx context.Context, appID snowflake.ID, channelID snowflake.ID) (*model.Channel, error) {
+	return getEntity(ctx, c, entityKindChannel, appID, channelID, setChannelTainted)
+}
+
+func (c *Client) GetGuildChannels(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Channel, error) {
+	channelIDs, err := listIDs(c.rdb.SMembers(ctx, c.guildEntitiesKey(entityKindChannel, appID, guildID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel ids: %w", err)
+	}
+	return listEntities(ctx, c, entityKindChannel, appID, channelIDs, opts, setChannelTainted)
+}
+
+func (c *Client) GetChannels(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Channel, error) {
+	channelIDs, err := listIDs(c.rdb.HKeys(ctx, c.entitiesKey(entityKindChannel, appID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel ids: %w", err)
+	}
+	return listEntities(ctx, c, entityKindChannel, appID, channelIDs, opts, setChannelTainted)
+}
+
+func (c *Client) GetChannelsByIDs(ctx context.Context, appID snowflake.ID, channelIDs []snowflake.ID) ([]*model.Channel, error) {
+	return getEntities(ctx, c, entityKindChannel, appID, channelIDs, setChannelTainted)
+}
+
+func (c *Client) GetChannelsByType(ctx context.Context, appID snowflake.ID, types []int, opts store.ListOptions) ([]*model.Channel, error) {
+	channelIDs, err := listIDs(c.rdb.HKeys(ctx, c.entitiesKey(entityKindChannel, appID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel ids: %w", err)
+	}
+
+	channels, err := listEntities(ctx, c, entityKindChannel, appID, channelIDs, store.ListOptions{
+		ExcludeTainted: opts.ExcludeTainted,
+		After:          opts.After,
+	}, setChannelTainted)
+	if err != nil {
+		return nil, err
+	}
+
+	channels = slices.DeleteFunc(channels, func(channel *model.Channel) bool {
+		return !slices.Contains(types, int(channel.Data.Type()))
+	})
+
+	if opts.Offset > 0 {
+		if opts.Offset >= len(channels) {
+			return []*model.Channel{}, nil
+		}
+		channels = channels[opts.Offset:]
+	}
+	if opts.Limit > 0 && opts.Limit < len(channels) {
+		channels = channels[:opts.Limit]
+	}
+	return channels, nil
+}
+
+func (c *Client) SearchGuildChannels(ctx context.Context, params store.SearchGuildChannelsParams) ([]*model.Channel, error) {
+	channelIDs, err := listIDs(c.rdb.SMembers(ctx, c.guildEntitiesKey(entityKindChannel, params.AppID, params.GuildID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel ids: %w", err)
+	}
+	return searchEntities(ctx, c, entityKindChannel, params.AppID, channelIDs, params.Data, params.Filter, params.ListOptions, channelData, setChannelTainted)
+}
+
+func (c *Client) SearchChannels(ctx context.Context, params store.SearchChannelsParams) ([]*model.Channel, error) {
+	channelIDs, err := listIDs(c.rdb.HKeys(ctx, c.entitiesKey(entityKindChannel, params.AppID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel ids: %w", err)
+	}
+	return searchEntities(ctx, c, entityKindChannel, params.AppID, channelIDs, params.Data, params.Filter, params.ListOptions, channelData, setChannelTainted)
+}
+
+func (c *Client) CountGuildChannels(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (int, error) {
+	count, err := c.rdb.SCard(ctx, c.guildEntitiesKey(entityKindChannel, appID, guildID)).Result()
+	return int(count), err
+}
+
+func (c *Client) CountChannels(ctx context.Context, appID snowflake.ID) (int, error) {
+	count, err := c.rdb.HLen(ctx, c.entitiesKey(entityKindChannel, appID)).Result()
+	return int(count), err
+}
+
+func (c *Client) UpsertChannels(ctx context.Context, channels ...store.UpsertChannelParams) (int, error) {
+	entries := make([]upsertEntry, len(channels))
+	for i, channel := range channels {
+		entries[i] = channelUpsertEntry(channel)
+	}
+	return c.upsertEntities(ctx, entries)
+}
+
+func (c *Client) DeleteChannel(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, channelID snowflake.ID) error {
+	return c.deleteGuildEntity(ctx, entityKindChannel, appID, guildID, channelID)
+}
+
+func channelUpsertEntry(channel store.UpsertChannelParams) upsertEntry {
+	updatedAt := timestampOrNow(channel.UpdatedAt)
+	return upsertEntry{
+		kind:      entityKindChannel,
+		appID:     channel.AppID,
+		guildID:   channel.GuildID,
+		entityID:  channel.ChannelID,
+		updatedAt: updatedAt,
+		entity: &model.Channel{
+			AppID:     channel.AppID,
+			GuildID:   channel.GuildID,
+			ChannelID: channel.ChannelID,
+			Data:      channel.Data,
+			CreatedAt: timestampOrNow(channel.CreatedAt),
+			UpdatedAt: updatedAt,
+		},
+	}
+}
+
+func channelData(channel *model.Channel) any {
+	return channel.Data
+}
+
+func setChannelTainted(channel *model.Channel) {
+	channel.Tainted = true
+}
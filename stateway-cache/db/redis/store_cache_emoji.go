@@ -0,0 +1,119 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+)
+
+func (c *Client) GetGuildEmoji(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, emojiID snowflake.ID) (*model.Emoji, error) {
+	emoji, err := c.GetEmoji(ctx, appID, emojiID)
+	if err != nil {
+		return nil, err
+	}
+	if emoji.GuildID != guildID {
+		return nil, store.ErrNotFound
+	}
+	return emoji, nil
+}
+
+func (c *Client) GetEmoji(ctx context.Context, appID snowflake.ID, emojiID snowflake.ID) (*model.Emoji, error) {
+	return getEntity(ctx, c, entityKindEmoji, appID, emojiID, setEmojiTainted)
+}
+
+func (c *Client) GetGuildEmojis(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Emoji, error) {
+	emojiIDs, err := listIDs(c.rdb.SMembers(ctx, c.guildEntitiesKey(entityKindEmoji, appID, guildID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get emoji ids: %w", err)
+	}
+	return listEntities(ctx, c, entityKindEmoji, appID, emojiIDs, opts, setEmojiTainted)
+}
+
+func (c *Client) GetEmojis(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Emoji, error) {
+	emojiIDs, err := listIDs(c.rdb.HKeys(ctx, c.entitiesKey(entityKindEmoji, appID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get emoji ids: %w", err)
+	}
+	return listEntities(ctx, c, entityKindEmoji, appID, emojiIDs, opts, setEmojiTainted)
+}
+
+func (c *Client) GetEmojisByIDs(ctx context.Context, appID snowflake.ID, emojiIDs []snowflake.ID) ([]*model.Emoji, error) {
+	return getEntities(ctx, c, entityKindEmoji, appID, emojiIDs, setEmojiTainted)
+}
+
+func (c *Client) SearchGuildEmojis(ctx context.Context, params store.SearchGuildEmojisParams) ([]*model.Emoji, error) {
+	emojiIDs, err := listIDs(c.rdb.SMembers(ctx, c.guildEntitiesKey(entityKindEmoji, params.AppID, params.GuildID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get emoji ids: %w", err)
+	}
+	return searchEntities(ctx, c, entityKindEmoji, params.AppID, emojiIDs, params.Data, params.Filter, params.ListOptions, emojiData, setEmojiTainted)
+}
+
+func (c *Client) SearchEmojis(ctx context.Context, params store.SearchEmojisParams) ([]*model.Emoji, error) {
+	emojiIDs, err := listIDs(c.rdb.HKeys(ctx, c.entitiesKey(entityKindEmoji, params.AppID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get emoji ids: %w", err)
+	}
+	return searchEntities(ctx, c, entityKindEmoji, params.AppID, emojiIDs, params.Data, params.Filter, params.ListOptions, emojiData, setEmojiTainted)
+}
+
+func (c *Client) CountGuildEmojis(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (int, error) {
+	count, err := c.rdb.SCard(ctx, c.guildEntitiesKey(entityKindEmoji, appID, guildID)).Result()
+	return int(count), err
+}
+
+func (c *Client) CountEmojis(ctx context.Context, appID snowflake.ID) (int, error) {
+	count, err := c.rdb.HLen(ctx, c.entitiesKey(entityKindEmoji, appID)).Result()
+	return int(count), err
+}
+
+func (c *Client) UpsertEmojis(ctx context.Context, emojis ...store.UpsertEmojiParams) (int, error) {
+	entries := make([]upsertEntry, len(emojis))
+	for i, emoji := range emojis {
+		entries[i] = emojiUpsertEntry(emoji)
+	}
+	return c.upsertEntities(ctx, entries)
+}
+
+func (c *Client) ReplaceGuildEmojis(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, updatedAt time.Time, emojis ...store.UpsertEmojiParams) (bool, error) {
+	entries := make([]upsertEntry, len(emojis))
+	for i, emoji := range emojis {
+		entries[i] = emojiUpsertEntry(emoji)
+	}
+	return c.replaceGuildEntities(ctx, entityKindEmoji, appID, guildID, updatedAt, entries)
+}
+
+func (c *Client) DeleteEmoji(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, emojiID snowflake.ID) error {
+	return c.deleteGuildEntity(ctx, entityKindEmoji, appID, guildID, emojiID)
+}
+
+func emojiUpsertEntry(emoji store.UpsertEmojiParams) upsertEntry {
+	updatedAt := timestampOrNow(emoji.UpdatedAt)
+	return upsertEntry{
+		kind:      entityKindEmoji,
+		appID:     emoji.AppID,
+		guildID:   emoji.GuildID,
+		entityID:  emoji.EmojiID,
+		updatedAt: updatedAt,
+		entity: &model.Emoji{
+			AppID:     emoji.AppID,
+			GuildID:   emoji.GuildID,
+			EmojiID:   emoji.EmojiID,
+			Data:      emoji.Data,
+			CreatedAt: timestampOrNow(emoji.CreatedAt),
+			UpdatedAt: updatedAt,
+		},
+	}
+}
+
+func emojiData(emoji *model.Emoji) any {
+	return emoji.Data
+}
+
+func setEmojiTainted(emoji *model.Emoji) {
+	emoji.Tainted = true
+}
@@ -0,0 +1,241 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func (c *Client) GetGuild(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (*model.Guild, error) {
+	return getEntity(ctx, c, entityKindGuild, appID, guildID, setGuildTainted)
+}
+
+func (c *Client) GetFullGuild(ctx context.Context, params store.GetFullGuildParams) (*model.FullGuild, error) {
+	// The script reads all entities atomically, so no write can happen in between.
+	args := []any{c.keyPrefix, params.AppID.String(), params.GuildID.String()}
+	if params.Include.Channels {
+		args = append(args, string(entityKindChannel))
+	}
+	if params.Include.Roles {
+		args = append(args, string(entityKindRole))
+	}
+	if params.Include.Emojis {
+		args = append(args, string(entityKindEmoji))
+	}
+	if params.Include.Stickers {
+		args = append(args, string(entityKindSticker))
+	}
+
+	reply, err := getFullGuildScript.Run(ctx, c.rdb, nil, args...).Slice()
+	if err != nil {
+		if errors.Is(err, goredis.Nil) {
+			return nil, store.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get full guild: %w", err)
+	}
+
+	guild, err := decodeScriptEntity(entityKindGuild, reply[0], reply[1], setGuildTainted)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &model.FullGuild{Guild: *guild}
+	collections := reply[2:]
+	if params.Include.Channels {
+		res.Channels, err = decodeScriptEntities(entityKindChannel, collections[0], params.ExcludeTainted, setChannelTainted)
+		if err != nil {
+			return nil, err
+		}
+		collections = collections[1:]
+	}
+	if params.Include.Roles {
+		res.Roles, err = decodeScriptEntities(entityKindRole, collections[0], params.ExcludeTainted, setRoleTainted)
+		if err != nil {
+			return nil, err
+		}
+		collections = collections[1:]
+	}
+	if params.Include.Emojis {
+		res.Emojis, err = decodeScriptEntities(entityKindEmoji, collections[0], params.ExcludeTainted, setEmojiTainted)
+		if err != nil {
+			return nil, err
+		}
+		collections = collections[1:]
+	}
+	if params.Include.Stickers {
+		res.Stickers, err = decodeScriptEntities(entityKindSticker, collections[0], params.ExcludeTainted, setStickerTainted)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+func (c *Client) GetGuildOwnerID(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (snowflake.ID, error) {
+	guild, err := c.GetGuild(ctx, appID, guildID)
+	if err != nil {
+		return 0, err
+	}
+	return guild.Data.OwnerID, nil
+}
+
+func (c *Client) GetGuilds(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Guild, error) {
+	guildIDs, err := listIDs(c.rdb.HKeys(ctx, c.entitiesKey(entityKindGuild, appID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild ids: %w", err)
+	}
+	return listEntities(ctx, c, entityKindGuild, appID, guildIDs, opts, setGuildTainted)
+}
+
+func (c *Client) GetGuildsByIDs(ctx context.Context, appID snowflake.ID, guildIDs []snowflake.ID) ([]*model.Guild, error) {
+	return getEntities(ctx, c, entityKindGuild, appID, guildIDs, setGuildTainted)
+}
+
+func (c *Client) CheckGuildExist(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (bool, error) {
+	return c.rdb.HExists(ctx, c.entitiesKey(entityKindGuild, appID), guildID.String()).Result()
+}
+
+// GetGuildApps looks the guild up in the apps of the guild apps set, which is kept up to date with the guild hashes.
+func (c *Client) GetGuildApps(ctx context.Context, guildID snowflake.ID) ([]*model.GuildApp, error) {
+	appIDs, err := listIDs(c.rdb.SMembers(ctx, c.guildAppsKey(guildID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild app ids: %w", err)
+	}
+
+	dataCmds := make([]*goredis.StringCmd, len(appIDs))
+	taintedCmds := make([]*goredis.BoolCmd, len(appIDs))
+	_, err = c.rdb.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for i, appID := range appIDs {
+			dataCmds[i] = pipe.HGet(ctx, c.entitiesKey(entityKindGuild, appID), guildID.String())
+			taintedCmds[i] = pipe.SIsMember(ctx, c.taintedKey(entityKindGuild, appID), guildID.String())
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, goredis.Nil) {
+		return nil, fmt.Errorf("failed to get guild apps: %w", err)
+	}
+
+	apps := make([]*model.GuildApp, 0, len(appIDs))
+	for i, appID := range appIDs {
+		data, err := dataCmds[i].Bytes()
+		if err != nil {
+			if errors.Is(err, goredis.Nil) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get guild: %w", err)
+		}
+
+		var guild model.Guild
+		err = json.Unmarshal(data, &guild)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal guild: %w", err)
+		}
+
+		apps = append(apps, &model.GuildApp{
+			AppID:       appID,
+			Unavailable: guild.Unavailable,
+			Tainted:     taintedCmds[i].Val(),
+			UpdatedAt:   guild.UpdatedAt,
+		})
+	}
+	return apps, nil
+}
+
+func (c *Client) UpsertGuilds(ctx context.Context, guilds ...store.UpsertGuildParams) (int, error) {
+	entries := make([]upsertEntry, len(guilds))
+	for i, guild := range guilds {
+		entries[i] = guildUpsertEntry(guild)
+	}
+	return c.upsertEntities(ctx, entries)
+}
+
+func (c *Client) MarkGuildUnavailable(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) error {
+	key := c.entitiesKey(entityKindGuild, appID)
+
+	err := c.rdb.Watch(ctx, func(tx *goredis.Tx) error {
+		data, err := tx.HGet(ctx, key, guildID.String()).Bytes()
+		if err != nil {
+			if errors.Is(err, goredis.Nil) {
+				return nil
+			}
+			return err
+		}
+
+		var guild model.Guild
+		err = json.Unmarshal(data, &guild)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal guild: %w", err)
+		}
+
+		guild.Unavailable = true
+
+		data, err = json.Marshal(guild)
+		if err != nil {
+			return fmt.Errorf("failed to marshal guild: %w", err)
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+			pipe.HSet(ctx, key, guildID.String(), data)
+			return nil
+		})
+		return err
+	}, key)
+	if err != nil {
+		return fmt.Errorf("failed to mark guild unavailable: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) DeleteGuild(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) error {
+	_, err := c.rdb.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.HDel(ctx, c.entitiesKey(entityKindGuild, appID), guildID.String())
+		pipe.SRem(ctx, c.taintedKey(entityKindGuild, appID), guildID.String())
+		pipe.SRem(ctx, c.guildAppsKey(guildID), appID.String())
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete guild: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) SearchGuilds(ctx context.Context, params store.SearchGuildsParams) ([]*model.Guild, error) {
+	guildIDs, err := listIDs(c.rdb.HKeys(ctx, c.entitiesKey(entityKindGuild, params.AppID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild ids: %w", err)
+	}
+	return searchEntities(ctx, c, entityKindGuild, params.AppID, guildIDs, params.Data, params.Filter, params.ListOptions, guildData, setGuildTainted)
+}
+
+func guildUpsertEntry(guild store.UpsertGuildParams) upsertEntry {
+	updatedAt := timestampOrNow(guild.UpdatedAt)
+	return upsertEntry{
+		kind:      entityKindGuild,
+		appID:     guild.AppID,
+		guildID:   guild.GuildID,
+		entityID:  guild.GuildID,
+		updatedAt: updatedAt,
+		entity: &model.Guild{
+			AppID:     guild.AppID,
+			GuildID:   guild.GuildID,
+			Data:      guild.Data,
+			CreatedAt: timestampOrNow(guild.CreatedAt),
+			UpdatedAt: updatedAt,
+		},
+	}
+}
+
+func guildData(guild *model.Guild) any {
+	return guild.Data
+}
+
+func setGuildTainted(guild *model.Guild) {
+	guild.Tainted = true
+}
@@ -0,0 +1,125 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+)
+
+func (c *Client) GetGuildRole(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, roleID snowflake.ID) (*model.Role, error) {
+	role, err := c.GetRole(ctx, appID, roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role.GuildID != guildID {
+		return nil, store.ErrNotFound
+	}
+	return role, nil
+}
+
+func (c *Client) GetRole(ctx context.Context, appID snowflake.ID, roleID snowflake.ID) (*model.Role, error) {
+	return getEntity(ctx, c, entityKindRole, appID, roleID, setRoleTainted)
+}
+
+func (c *Client) GetGuildRoles(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Role, error) {
+	roleIDs, err := listIDs(c.rdb.SMembers(ctx, c.guildEntitiesKey(entityKindRole, appID, guildID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role ids: %w", err)
+	}
+	return listEntities(ctx, c, entityKindRole, appID, roleIDs, opts, setRoleTainted)
+}
+
+func (c *Client) GetGuildRolesByIDs(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, roleIDs []snowflake.ID) ([]*model.Role, error) {
+	roles, err := getEntities(ctx, c, entityKindRole, appID, roleIDs, setRoleTainted)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*model.Role, 0, len(roles))
+	for _, role := range roles {
+		if role.GuildID == guildID {
+			res = append(res, role)
+		}
+	}
+	return res, nil
+}
+
+func (c *Client) GetRoles(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Role, error) {
+	roleIDs, err := listIDs(c.rdb.HKeys(ctx, c.entitiesKey(entityKindRole, appID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role ids: %w", err)
+	}
+	return listEntities(ctx, c, entityKindRole, appID, roleIDs, opts, setRoleTainted)
+}
+
+func (c *Client) GetRolesByIDs(ctx context.Context, appID snowflake.ID, roleIDs []snowflake.ID) ([]*model.Role, error) {
+	return getEntities(ctx, c, entityKindRole, appID, roleIDs, setRoleTainted)
+}
+
+func (c *Client) SearchGuildRoles(ctx context.Context, params store.SearchGuildRolesParams) ([]*model.Role, error) {
+	roleIDs, err := listIDs(c.rdb.SMembers(ctx, c.guildEntitiesKey(entityKindRole, params.AppID, params.GuildID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role ids: %w", err)
+	}
+	return searchEntities(ctx, c, entityKindRole, params.AppID, roleIDs, params.Data, params.Filter, params.ListOptions, roleData, setRoleTainted)
+}
+
+func (c *Client) SearchRoles(ctx context.Context, params store.SearchRolesParams) ([]*model.Role, error) {
+	roleIDs, err := listIDs(c.rdb.HKeys(ctx, c.entitiesKey(entityKindRole, params.AppID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role ids: %w", err)
+	}
+	return searchEntities(ctx, c, entityKindRole, params.AppID, roleIDs, params.Data, params.Filter, params.ListOptions, roleData, setRoleTainted)
+}
+
+func (c *Client) CountGuildRoles(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (int, error) {
+	count, err := c.rdb.SCard(ctx, c.guildEntitiesKey(entityKindRole, appID, guildID)).Result()
+	return int(count), err
+}
+
+func (c *Client) CountRoles(ctx context.Context, appID snowflake.ID) (int, error) {
+	count, err := c.rdb.HLen(ctx, c.entitiesKey(entityKindRole, appID)).Result()
+	return int(count), err
+}
+
+func (c *Client) UpsertRoles(ctx context.Context, roles ...store.UpsertRoleParams) (int, error) {
+	entries := make([]upsertEntry, len(roles))
+	for i, role := range roles {
+		entries[i] = roleUpsertEntry(role)
+	}
+	return c.upsertEntities(ctx, entries)
+}
+
+func (c *Client) DeleteRole(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, roleID snowflake.ID) error {
+	return c.deleteGuildEntity(ctx, entityKindRole, appID, guildID, roleID)
+}
+
+func roleUpsertEntry(role store.UpsertRoleParams) upsertEntry {
+	updatedAt := timestampOrNow(role.UpdatedAt)
+	return upsertEntry{
+		kind:      entityKindRole,
+		appID:     role.AppID,
+		guildID:   role.GuildID,
+		entityID:  role.RoleID,
+		updatedAt: updatedAt,
+		entity: &model.Role{
+			AppID:     role.AppID,
+			GuildID:   role.GuildID,
+			RoleID:    role.RoleID,
+			Data:      role.Data,
+			CreatedAt: timestampOrNow(role.CreatedAt),
+			UpdatedAt: updatedAt,
+		},
+	}
+}
+
+func roleData(role *model.Role) any {
+	return role.Data
+}
+
+func setRoleTainted(role *model.Role) {
+	role.Tainted = true
+}
@@ -0,0 +1,119 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+)
+
+func (c *Client) GetSticker(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, stickerID snowflake.ID) (*model.Sticker, error) {
+	sticker, err := getEntity(ctx, c, entityKindSticker, appID, stickerID, setStickerTainted)
+	if err != nil {
+		return nil, err
+	}
+	if sticker.GuildID != guildID {
+		return nil, store.ErrNotFound
+	}
+	return sticker, nil
+}
+
+func (c *Client) GetGuildSticker(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, stickerID snowflake.ID) (*model.Sticker, error) {
+	return c.GetSticker(ctx, appID, guildID, stickerID)
+}
+
+func (c *Client) GetGuildStickers(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Sticker, error) {
+	stickerIDs, err := listIDs(c.rdb.SMembers(ctx, c.guildEntitiesKey(entityKindSticker, appID, guildID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sticker ids: %w", err)
+	}
+	return listEntities(ctx, c, entityKindSticker, appID, stickerIDs, opts, setStickerTainted)
+}
+
+func (c *Client) GetStickers(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Sticker, error) {
+	stickerIDs, err := listIDs(c.rdb.HKeys(ctx, c.entitiesKey(entityKindSticker, appID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sticker ids: %w", err)
+	}
+	return listEntities(ctx, c, entityKindSticker, appID, stickerIDs, opts, setStickerTainted)
+}
+
+func (c *Client) GetStickersByIDs(ctx context.Context, appID snowflake.ID, stickerIDs []snowflake.ID) ([]*model.Sticker, error) {
+	return getEntities(ctx, c, entityKindSticker, appID, stickerIDs, setStickerTainted)
+}
+
+func (c *Client) SearchGuildStickers(ctx context.Context, params store.SearchGuildStickersParams) ([]*model.Sticker, error) {
+	stickerIDs, err := listIDs(c.rdb.SMembers(ctx, c.guildEntitiesKey(entityKindSticker, params.AppID, params.GuildID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sticker ids: %w", err)
+	}
+	return searchEntities(ctx, c, entityKindSticker, params.AppID, stickerIDs, params.Data, params.Filter, params.ListOptions, stickerData, setStickerTainted)
+}
+
+func (c *Client) SearchStickers(ctx context.Context, params store.SearchStickersParams) ([]*model.Sticker, error) {
+	stickerIDs, err := listIDs(c.rdb.HKeys(ctx, c.entitiesKey(entityKindSticker, params.AppID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sticker ids: %w", err)
+	}
+	return searchEntities(ctx, c, entityKindSticker, params.AppID, stickerIDs, params.Data, params.Filter, params.ListOptions, stickerData, setStickerTainted)
+}
+
+func (c *Client) CountGuildStickers(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (int, error) {
+	count, err := c.rdb.SCard(ctx, c.guildEntitiesKey(entityKindSticker, appID, guildID)).Result()
+	return int(count), err
+}
+
+func (c *Client) CountStickers(ctx context.Context, appID snowflake.ID) (int, error) {
+	count, err := c.rdb.HLen(ctx, c.entitiesKey(entityKindSticker, appID)).Result()
+	return int(count), err
+}
+
+func (c *Client) UpsertStickers(ctx context.Context, stickers ...store.UpsertStickerParams) (int, error) {
+	entries := make([]upsertEntry, len(stickers))
+	for i, sticker := range stickers {
+		entries[i] = stickerUpsertEntry(sticker)
+	}
+	return c.upsertEntities(ctx, entries)
+}
+
+func (c *Client) ReplaceGuildStickers(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, updatedAt time.Time, stickers ...store.UpsertStickerParams) (bool, error) {
+	entries := make([]upsertEntry, len(stickers))
+	for i, sticker := range stickers {
+		entries[i] = stickerUpsertEntry(sticker)
+	}
+	return c.replaceGuildEntities(ctx, entityKindSticker, appID, guildID, updatedAt, entries)
+}
+
+func (c *Client) DeleteSticker(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, stickerID snowflake.ID) error {
+	return c.deleteGuildEntity(ctx, entityKindSticker, appID, guildID, stickerID)
+}
+
+func stickerUpsertEntry(sticker store.UpsertStickerParams) upsertEntry {
+	updatedAt := timestampOrNow(sticker.UpdatedAt)
+	return upsertEntry{
+		kind:      entityKindSticker,
+		appID:     sticker.AppID,
+		guildID:   sticker.GuildID,
+		entityID:  sticker.StickerID,
+		updatedAt: updatedAt,
+		entity: &model.Sticker{
+			AppID:     sticker.AppID,
+			GuildID:   sticker.GuildID,
+			StickerID: sticker.StickerID,
+			Data:      sticker.Data,
+			CreatedAt: timestampOrNow(sticker.CreatedAt),
+			UpdatedAt: updatedAt,
+		},
+	}
+}
+
+func stickerData(sticker *model.Sticker) any {
+	return sticker.Data
+}
+
+func setStickerTainted(sticker *model.Sticker) {
+	sticker.Tainted = true
+}
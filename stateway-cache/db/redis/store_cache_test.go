@@ -0,0 +1,167 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"github.com/merlinfuchs/stateway/stateway-cache/store/storetest"
+	cachelib "github.com/merlinfuchs/stateway/stateway-lib/cache"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	rdb := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		rdb.Close()
+	})
+
+	return NewWithClient(rdb, "")
+}
+
+func TestRedisCacheStore(t *testing.T) {
+	storetest.TestCacheStore(t, func(t *testing.T) store.CacheStore {
+		return newTestClient(t)
+	})
+}
+
+func TestRedisGuildCache(t *testing.T) {
+	ctx := context.Background()
+	cache := newTestClient(t)
+
+	_, err := cache.UpsertGuilds(ctx,
+		store.UpsertGuildParams{AppID: 1, GuildID: 3, Data: discord.Guild{Name: "c"}},
+		store.UpsertGuildParams{AppID: 1, GuildID: 1, Data: discord.Guild{Name: "a"}},
+		store.UpsertGuildParams{AppID: 1, GuildID: 2, Data: discord.Guild{Name: "b"}},
+		store.UpsertGuildParams{AppID: 2, GuildID: 1, Data: discord.Guild{Name: "a"}},
+	)
+	require.NoError(t, err)
+
+	guild, err := cache.GetGuild(ctx, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, snowflake.ID(1), guild.GuildID)
+	assert.Equal(t, "a", guild.Data.Name)
+
+	_, err = cache.GetGuild(ctx, 1, 4)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	guilds, err := cache.GetGuilds(ctx, 1, store.ListOptions{Limit: 2, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, guilds, 2)
+	assert.Equal(t, snowflake.ID(2), guilds[0].GuildID)
+	assert.Equal(t, snowflake.ID(3), guilds[1].GuildID)
+
+	guilds, err = cache.SearchGuilds(ctx, store.SearchGuildsParams{
+		AppID: 1,
+		Data:  json.RawMessage(`{"name": "b"}`),
+	})
+	require.NoError(t, err)
+	require.Len(t, guilds, 1)
+	assert.Equal(t, snowflake.ID(2), guilds[0].GuildID)
+
+	err = cache.MarkGuildUnavailable(ctx, 1, 2)
+	require.NoError(t, err)
+
+	guild, err = cache.GetGuild(ctx, 1, 2)
+	require.NoError(t, err)
+	assert.True(t, guild.Unavailable)
+
+	err = cache.DeleteGuild(ctx, 1, 2)
+	require.NoError(t, err)
+
+	exists, err := cache.CheckGuildExist(ctx, 1, 2)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	exists, err = cache.CheckGuildExist(ctx, 2, 1)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestRedisRoleCache(t *testing.T) {
+	ctx := context.Background()
+	cache := newTestClient(t)
+
+	_, err := cache.UpsertRoles(ctx,
+		store.UpsertRoleParams{AppID: 1, GuildID: 1, RoleID: 1, Data: discord.Role{Name: "a"}},
+		store.UpsertRoleParams{AppID: 1, GuildID: 1, RoleID: 2, Data: discord.Role{Name: "b"}},
+		store.UpsertRoleParams{AppID: 1, GuildID: 2, RoleID: 3, Data: discord.Role{Name: "c"}},
+	)
+	require.NoError(t, err)
+
+	role, err := cache.GetGuildRole(ctx, 1, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "b", role.Data.Name)
+
+	_, err = cache.GetGuildRole(ctx, 1, 2, 2)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	roles, err := cache.GetGuildRolesByIDs(ctx, 1, 1, []snowflake.ID{1, 3, 4})
+	require.NoError(t, err)
+	require.Len(t, roles, 1)
+	assert.Equal(t, snowflake.ID(1), roles[0].RoleID)
+
+	count, err := cache.CountGuildRoles(ctx, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	count, err = cache.CountRoles(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	filter := cachelib.FilterOr(cachelib.FilterEq("name", "a"), cachelib.FilterPrefix("name", "c"))
+	roles, err = cache.SearchRoles(ctx, store.SearchRolesParams{AppID: 1, Filter: &filter})
+	require.NoError(t, err)
+	require.Len(t, roles, 2)
+	assert.Equal(t, snowflake.ID(1), roles[0].RoleID)
+	assert.Equal(t, snowflake.ID(3), roles[1].RoleID)
+
+	err = cache.DeleteRole(ctx, 1, 1, 1)
+	require.NoError(t, err)
+
+	roles, err = cache.GetGuildRoles(ctx, 1, 1, store.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, roles, 1)
+	assert.Equal(t, snowflake.ID(2), roles[0].RoleID)
+}
+
+func TestRedisGuildAppsIndex(t *testing.T) {
+	ctx := context.Background()
+	cache := newTestClient(t)
+
+	for _, appID := range []snowflake.ID{1, 2} {
+		_, err := cache.UpsertGuilds(ctx, store.UpsertGuildParams{AppID: appID, GuildID: 100})
+		require.NoError(t, err)
+	}
+
+	members, err := cache.rdb.SMembers(ctx, cache.guildAppsKey(100)).Result()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1", "2"}, members)
+
+	// Guilds that are swept as tainted are removed from the index
+	err = cache.MarkShardEntitiesTainted(ctx, store.MarkShardEntitiesTaintedParams{AppID: 2, ShardCount: 1})
+	require.NoError(t, err)
+	err = cache.DeleteShardTaintedEntities(ctx, store.DeleteShardTaintedEntitiesParams{AppID: 2, ShardCount: 1})
+	require.NoError(t, err)
+
+	members, err = cache.rdb.SMembers(ctx, cache.guildAppsKey(100)).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, members)
+
+	// Purged apps are removed from the index of all their guilds
+	_, err = cache.DeleteAppEntities(ctx, 1)
+	require.NoError(t, err)
+
+	exists, err := cache.rdb.Exists(ctx, cache.guildAppsKey(100)).Result()
+	require.NoError(t, err)
+	assert.Zero(t, exists)
+}
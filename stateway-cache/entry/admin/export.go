@@ -0,0 +1,120 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/export"
+	"github.com/merlinfuchs/stateway/stateway-cache/inmemory"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"github.com/olekukonko/tablewriter"
+)
+
+// Export writes the cached guild, or all guilds of the app if guildID is 0, with their entities as JSONL to w.
+// In-memory stores only live in the cache server, so they are exported from their last snapshot.
+func Export(
+	ctx context.Context,
+	cacheStore store.CacheStore,
+	snapshotPath string,
+	w io.Writer,
+	appID snowflake.ID,
+	guildID snowflake.ID,
+) error {
+	if _, ok := cacheStore.(inmemory.SnapshotStore); ok {
+		if snapshotPath == "" {
+			return errors.New("in-memory stores can only be exported from a snapshot, snapshot_path isn't set")
+		}
+
+		snapshot, err := inmemory.ReadSnapshotFile(snapshotPath)
+		if err != nil {
+			return err
+		}
+		err = inmemory.RestoreSnapshot(ctx, cacheStore, snapshot)
+		if err != nil {
+			return err
+		}
+	}
+
+	counts, err := export.WriteEntities(ctx, cacheStore, w, appID, guildID)
+	if err != nil {
+		return fmt.Errorf("failed to export entities: %w", err)
+	}
+
+	// The records may be written to stdout, so the counts go to stderr
+	return renderExportTable(os.Stderr, "Exported", counts)
+}
+
+// Import loads the JSONL records from r into the cache store.
+// Imports into in-memory stores are added to their snapshot, which the cache server restores on startup.
+// The cache server overwrites the snapshot with its own state, so it has to be stopped during the import.
+func Import(ctx context.Context, cacheStore store.CacheStore, snapshotPath string, r io.Reader) error {
+	snapshotStore, isSnapshotStore := cacheStore.(inmemory.SnapshotStore)
+	if isSnapshotStore {
+		if snapshotPath == "" {
+			return errors.New("in-memory stores can only be imported into a snapshot, snapshot_path isn't set")
+		}
+
+		locked, lockPath, err := inmemory.SnapshotFileLocked(snapshotPath)
+		if err != nil {
+			return err
+		}
+		if locked {
+			return fmt.Errorf("the snapshot is owned by a running cache server, stop it first or remove %s if it isn't running", lockPath)
+		}
+
+		// The records are added to the existing snapshot instead of replacing it
+		snapshot, err := inmemory.ReadSnapshotFile(snapshotPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if snapshot != nil {
+			err = inmemory.RestoreSnapshot(ctx, cacheStore, snapshot)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	counts, err := export.ReadEntities(ctx, cacheStore, r)
+	if err != nil {
+		return fmt.Errorf("failed to import entities: %w", err)
+	}
+
+	if isSnapshotStore {
+		snapshot, err := snapshotStore.Snapshot(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to take snapshot: %w", err)
+		}
+		err = inmemory.WriteSnapshotFile(snapshotPath, snapshot)
+		if err != nil {
+			return err
+		}
+	}
+
+	return renderExportTable(os.Stdout, "Imported", counts)
+}
+
+func renderExportTable(w io.Writer, column string, counts export.Counts) error {
+	table := tablewriter.NewWriter(w)
+	table.Header([]string{"Entity", column})
+
+	rows := [][]string{
+		{"guilds", strconv.Itoa(counts.Guilds)},
+		{"channels", strconv.Itoa(counts.Channels)},
+		{"roles", strconv.Itoa(counts.Roles)},
+		{"emojis", strconv.Itoa(counts.Emojis)},
+		{"stickers", strconv.Itoa(counts.Stickers)},
+	}
+	for _, row := range rows {
+		err := table.Append(row)
+		if err != nil {
+			return fmt.Errorf("failed to append counts to table: %w", err)
+		}
+	}
+	return table.Render()
+}
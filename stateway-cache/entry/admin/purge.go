@@ -0,0 +1,38 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-lib/cache"
+	"github.com/olekukonko/tablewriter"
+)
+
+// PurgeApp deletes all cached entities of the app and prints how many have been deleted.
+func PurgeApp(ctx context.Context, caches cache.PurgeCache, appID snowflake.ID) error {
+	result, err := caches.PurgeApp(ctx, cache.WithAppID(appID))
+	if err != nil {
+		return fmt.Errorf("failed to purge app: %w", err)
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.Header([]string{"Entity", "Deleted"})
+
+	rows := [][]string{
+		{"guilds", strconv.Itoa(result.Deleted.Guilds)},
+		{"channels", strconv.Itoa(result.Deleted.Channels)},
+		{"roles", strconv.Itoa(result.Deleted.Roles)},
+		{"emojis", strconv.Itoa(result.Deleted.Emojis)},
+		{"stickers", strconv.Itoa(result.Deleted.Stickers)},
+	}
+	for _, row := range rows {
+		err := table.Append(row)
+		if err != nil {
+			return fmt.Errorf("failed to append purge counts to table: %w", err)
+		}
+	}
+	return table.Render()
+}
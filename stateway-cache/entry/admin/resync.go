@@ -0,0 +1,89 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-lib/cache"
+	"github.com/olekukonko/tablewriter"
+)
+
+// Resync refetches the guild, or all guilds of the app if guildID is 0, from Discord and prints what has changed.
+// Guilds are resynced one by one, so a failed guild doesn't abort the resync of the others.
+func Resync(ctx context.Context, caches cache.ResyncCache, appID snowflake.ID, guildID snowflake.ID) error {
+	if guildID != 0 {
+		result, err := caches.Resync(ctx, guildID, cache.WithAppID(appID))
+		if err != nil {
+			return fmt.Errorf("failed to resync guild: %w", err)
+		}
+		return renderResyncTable(result.Upserted, result.Deleted)
+	}
+
+	result, err := caches.Resync(ctx, 0, cache.WithAppID(appID))
+	if err != nil {
+		return fmt.Errorf("failed to resync guilds: %w", err)
+	}
+
+	upserted := result.Upserted
+	deleted := result.Deleted
+	failed := 0
+	for i, guildID := range result.GuildIDs {
+		guildResult, err := caches.Resync(ctx, guildID, cache.WithAppID(appID))
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			slog.Error(
+				"Failed to resync guild",
+				slog.String("guild_id", guildID.String()),
+				slog.Any("error", err),
+			)
+			failed++
+			continue
+		}
+
+		upserted.Add(guildResult.Upserted)
+		deleted.Add(guildResult.Deleted)
+		slog.Info(
+			"Resynced guild",
+			slog.String("guild_id", guildID.String()),
+			slog.Int("progress", i+1),
+			slog.Int("total", len(result.GuildIDs)),
+		)
+	}
+
+	err = renderResyncTable(upserted, deleted)
+	if err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to resync %d of %d guilds", failed, len(result.GuildIDs))
+	}
+	return nil
+}
+
+func renderResyncTable(upserted cache.ResyncCounts, deleted cache.ResyncCounts) error {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.Header([]string{"Entity", "Upserted", "Deleted"})
+
+	rows := [][]string{
+		{"guilds", strconv.Itoa(upserted.Guilds), strconv.Itoa(deleted.Guilds)},
+		{"channels", strconv.Itoa(upserted.Channels), strconv.Itoa(deleted.Channels)},
+		{"roles", strconv.Itoa(upserted.Roles), strconv.Itoa(deleted.Roles)},
+		{"emojis", strconv.Itoa(upserted.Emojis), strconv.Itoa(deleted.Emojis)},
+		{"stickers", strconv.Itoa(upserted.Stickers), strconv.Itoa(deleted.Stickers)},
+	}
+	for _, row := range rows {
+		err := table.Append(row)
+		if err != nil {
+			return fmt.Errorf("failed to append resync counts to table: %w", err)
+		}
+	}
+	return table.Render()
+}
@@ -0,0 +1,86 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-lib/cache"
+	"github.com/olekukonko/tablewriter"
+)
+
+// PrintStats prints the stats of the app, or of all apps if appID is 0, as reported by the running cache service.
+func PrintStats(ctx context.Context, caches cache.StatsCache, appID snowflake.ID) error {
+	stats, err := caches.GetStats(ctx, cache.WithAppID(appID))
+	if err != nil {
+		return fmt.Errorf("failed to get cache stats: %w", err)
+	}
+
+	err = renderStatsTable(stats)
+	if err != nil {
+		return fmt.Errorf("failed to render stats table: %w", err)
+	}
+
+	return nil
+}
+
+func renderStatsTable(stats []*cache.CacheStats) error {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.Header([]string{"App ID", "Entity", "Count", "Tainted", "Unavailable", "Oldest Updated At", "Memory"})
+	for _, app := range stats {
+		entities := []struct {
+			name  string
+			stats cache.EntityStats
+		}{
+			{"guilds", app.Guilds},
+			{"channels", app.Channels},
+			{"roles", app.Roles},
+			{"emojis", app.Emojis},
+			{"stickers", app.Stickers},
+		}
+
+		for i, entity := range entities {
+			oldestUpdatedAt := "-"
+			if entity.stats.OldestUpdatedAt != nil {
+				oldestUpdatedAt = entity.stats.OldestUpdatedAt.Format(time.RFC3339)
+			}
+
+			// The memory usage is only known for the whole app
+			memory := ""
+			if i == 0 && app.MemoryBytes > 0 {
+				memory = formatBytes(app.MemoryBytes)
+			}
+
+			err := table.Append([]string{
+				app.AppID.String(),
+				entity.name,
+				strconv.Itoa(entity.stats.Count),
+				strconv.Itoa(entity.stats.Tainted),
+				strconv.Itoa(entity.stats.Unavailable),
+				oldestUpdatedAt,
+				memory,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to append stats to table: %w", err)
+			}
+		}
+	}
+	return table.Render()
+}
+
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -5,11 +5,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/snowflake/v2"
 	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"github.com/merlinfuchs/stateway/stateway-lib/broker"
 	"github.com/merlinfuchs/stateway/stateway-lib/cache"
+	"github.com/merlinfuchs/stateway/stateway-lib/permissions"
 	"github.com/merlinfuchs/stateway/stateway-lib/service"
 )
 
@@ -17,25 +21,213 @@ var _ cache.Cache = (*Cache)(nil)
 
 type Cache struct {
 	cacheStore store.CacheStore
+	// fetcher loads missing entities from Discord for requests with FetchOnMiss, nil disables it.
+	fetcher *RESTFetcher
+	// waiter wakes up guild.wait requests, nil makes them return right away.
+	waiter *GuildWaiter
+	// broker broadcasts the invalidations of purges, nil disables them.
+	broker broker.Broker
 }
 
-func NewCaches(cacheStore store.CacheStore) *Cache {
+func NewCaches(cacheStore store.CacheStore, fetcher *RESTFetcher, waiter *GuildWaiter, br broker.Broker) *Cache {
 	return &Cache{
 		cacheStore: cacheStore,
+		fetcher:    fetcher,
+		waiter:     waiter,
+		broker:     br,
 	}
 }
 
+// fetchOnMiss reports whether missing entities should be fetched from Discord.
+func (c *Cache) fetchOnMiss(options cache.CacheOptions) bool {
+	return options.FetchOnMiss && c.fetcher != nil
+}
+
+func listOptions(options cache.CacheOptions) store.ListOptions {
+	return store.ListOptions{
+		Limit:          options.Limit,
+		Offset:         options.Offset,
+		ExcludeTainted: options.ExcludeTainted,
+		After:          options.After,
+	}
+}
+
+// pageOptions requests one entity more than the limit, so newPage can tell if there is a next page.
+func pageOptions(options cache.CacheOptions) store.ListOptions {
+	opts := listOptions(options)
+	if opts.Limit > 0 {
+		opts.Limit++
+	}
+	return opts
+}
+
+// newPage trims the entities fetched with pageOptions to the limit and sets the cursor of the next page.
+func newPage[T any](entities []*T, options cache.CacheOptions, id func(*T) snowflake.ID) *cache.Page[*T] {
+	page := &cache.Page[*T]{Items: entities}
+	if options.Limit > 0 && len(entities) > options.Limit {
+		page.Items = entities[:options.Limit]
+		cursor := id(page.Items[len(page.Items)-1])
+		page.NextCursor = &cursor
+	}
+	return page
+}
+
+func guildEntityID(guild *cache.Guild) snowflake.ID       { return guild.GuildID }
+func channelEntityID(channel *cache.Channel) snowflake.ID { return channel.ChannelID }
+func roleEntityID(role *cache.Role) snowflake.ID          { return role.RoleID }
+func emojiEntityID(emoji *cache.Emoji) snowflake.ID       { return emoji.EmojiID }
+func stickerEntityID(sticker *cache.Sticker) snowflake.ID { return sticker.StickerID }
+
+// maxBatchGetIDs is the maximum number of entities a single batch get can request.
+const maxBatchGetIDs = 1000
+
+func checkBatchGetIDs(ids []snowflake.ID) error {
+	if len(ids) > maxBatchGetIDs {
+		return service.ErrInvalidRequest(fmt.Sprintf("at most %d IDs can be requested at once", maxBatchGetIDs), nil)
+	}
+	return nil
+}
+
+// batchGetResult orders the entities like the requested IDs.
+// IDs of entities that are missing or rejected by include are nil in the result.
+func batchGetResult[T any](entities []*T, ids []snowflake.ID, id func(*T) snowflake.ID, include func(*T) bool) []*T {
+	byID := make(map[snowflake.ID]*T, len(entities))
+	for _, entity := range entities {
+		if include(entity) {
+			byID[id(entity)] = entity
+		}
+	}
+
+	res := make([]*T, len(ids))
+	for i, entityID := range ids {
+		res[i] = byID[entityID]
+	}
+	return res
+}
+
+// searchFilter validates the filter of a search before it's passed to the store.
+func searchFilter(options cache.CacheOptions) (*cache.Filter, error) {
+	if options.Filter == nil {
+		return nil, nil
+	}
+
+	filter, err := options.Filter.Normalize()
+	if err != nil {
+		return nil, service.ErrInvalidRequest("invalid search filter", err)
+	}
+	return &filter, nil
+}
+
 func (c *Cache) GetGuild(ctx context.Context, id snowflake.ID, opts ...cache.CacheOption) (*cache.Guild, error) {
 	options := cache.ResolveOptions(opts...)
 
 	guild, err := c.cacheStore.GetGuild(ctx, options.AppID, id)
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
+			if c.fetchOnMiss(options) {
+				return c.fetcher.FetchGuild(ctx, options.AppID, id)
+			}
 			return nil, service.ErrNotFound("guild not found")
 		}
 		return nil, err
 	}
 
+	if options.ExcludeTainted && guild.Tainted {
+		if c.fetchOnMiss(options) {
+			return c.fetcher.FetchGuild(ctx, options.AppID, id)
+		}
+		return nil, service.ErrNotFound("guild not found")
+	}
+
+	return guild, nil
+}
+
+// maxGuildWaitTimeout is the longest a guild.wait request can wait, so requests don't pile up for guilds that never arrive.
+const maxGuildWaitTimeout = time.Minute
+
+func (c *Cache) WaitForGuild(
+	ctx context.Context,
+	id snowflake.ID,
+	timeout time.Duration,
+	opts ...cache.CacheOption,
+) (*cache.Guild, error) {
+	options := cache.ResolveOptions(opts...)
+
+	if c.waiter == nil || timeout <= 0 {
+		return c.GetGuild(ctx, id, opts...)
+	}
+
+	cached, stop := c.waiter.Wait(options.AppID, id)
+	defer stop()
+
+	guild, err := c.cacheStore.GetGuild(ctx, options.AppID, id)
+	if err == nil {
+		return guild, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+
+	timer := time.NewTimer(min(timeout, maxGuildWaitTimeout))
+	defer timer.Stop()
+
+	select {
+	case <-cached:
+	case <-timer.C:
+		return nil, service.ErrNotFound("guild not found")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	guild, err = c.cacheStore.GetGuild(ctx, options.AppID, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, service.ErrNotFound("guild not found")
+		}
+		return nil, err
+	}
+	return guild, nil
+}
+
+func (c *Cache) GetGuildApps(ctx context.Context, guildID snowflake.ID, opts ...cache.CacheOption) ([]*cache.GuildApp, error) {
+	options := cache.ResolveOptions(opts...)
+
+	apps, err := c.cacheStore.GetGuildApps(ctx, guildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild apps: %w", err)
+	}
+
+	if options.ExcludeTainted {
+		apps = slices.DeleteFunc(apps, func(app *cache.GuildApp) bool { return app.Tainted })
+	}
+	return apps, nil
+}
+
+func (c *Cache) GetFullGuild(
+	ctx context.Context,
+	id snowflake.ID,
+	include cache.FullGuildInclude,
+	opts ...cache.CacheOption,
+) (*cache.FullGuild, error) {
+	options := cache.ResolveOptions(opts...)
+
+	guild, err := c.cacheStore.GetFullGuild(ctx, store.GetFullGuildParams{
+		AppID:          options.AppID,
+		GuildID:        id,
+		Include:        include,
+		ExcludeTainted: options.ExcludeTainted,
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, service.ErrNotFound("guild not found")
+		}
+		return nil, err
+	}
+
+	if options.ExcludeTainted && guild.Tainted {
+		return nil, service.ErrNotFound("guild not found")
+	}
+
 	return guild, nil
 }
 
@@ -57,11 +249,12 @@ func (c *Cache) GetGuildWithPermissions(
 		return nil, err
 	}
 
-	guildPermissions, err := c.ComputeGuildPermissions(ctx, guildID, userID, roleIDs, opts...)
+	calculator, err := c.permissionCalculator(ctx, options, guildID, userID, roleIDs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compute guild permissions: %w", err)
 	}
 
+	guildPermissions := calculator.Guild()
 	if guildPermissions.Has(discord.PermissionAdministrator) {
 		return &cache.GuildWithPermissions{
 			Guild:                 *guild,
@@ -79,7 +272,9 @@ func (c *Cache) GetGuildWithPermissions(
 		}, nil
 	}
 
-	channels, err := c.cacheStore.GetGuildChannels(ctx, options.AppID, guildID, 0, 0)
+	channels, err := c.cacheStore.GetGuildChannels(ctx, options.AppID, guildID, store.ListOptions{
+		ExcludeTainted: options.ExcludeTainted,
+	})
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			return nil, service.ErrNotFound("channels not found")
@@ -90,17 +285,23 @@ func (c *Cache) GetGuildWithPermissions(
 	maxChannelPermissions := discord.PermissionsNone
 	minChannelPermissions := discord.PermissionsAll
 
+	guildChannels := guildChannelsByID(channels)
 	for _, channel := range channels {
-		guildChannel, ok := channel.Data.(discord.GuildChannel)
+		guildChannel, ok := guildChannels[channel.ChannelID]
 		if !ok {
 			continue
 		}
 
-		permissions := computeChannelPermissions(guildChannel, userID, roleIDs, guildPermissions)
-		maxChannelPermissions |= permissions
-		minChannelPermissions &= permissions
+		permissionChannel, err := c.permissionChannel(ctx, options.AppID, guildChannel, guildChannels)
+		if err != nil {
+			return nil, err
+		}
 
-		if abortAtPermissions != 0 && permissions.Has(abortAtPermissions) {
+		channelPermissions := calculator.Channel(permissionChannel)
+		maxChannelPermissions |= channelPermissions
+		minChannelPermissions &= channelPermissions
+
+		if abortAtPermissions != 0 && channelPermissions.Has(abortAtPermissions) {
 			break
 		}
 	}
@@ -113,10 +314,10 @@ func (c *Cache) GetGuildWithPermissions(
 	}, nil
 }
 
-func (c *Cache) GetGuilds(ctx context.Context, opts ...cache.CacheOption) ([]*cache.Guild, error) {
+func (c *Cache) GetGuilds(ctx context.Context, opts ...cache.CacheOption) (*cache.Page[*cache.Guild], error) {
 	options := cache.ResolveOptions(opts...)
 
-	guilds, err := c.cacheStore.GetGuilds(ctx, options.AppID, options.Limit, options.Offset)
+	guilds, err := c.cacheStore.GetGuilds(ctx, options.AppID, pageOptions(options))
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			return nil, service.ErrNotFound("guilds not found")
@@ -124,7 +325,7 @@ func (c *Cache) GetGuilds(ctx context.Context, opts ...cache.CacheOption) ([]*ca
 		return nil, err
 	}
 
-	return guilds, nil
+	return newPage(guilds, options, guildEntityID), nil
 }
 
 func (c *Cache) CheckGuildsExist(ctx context.Context, guildIDs []snowflake.ID, opts ...cache.CacheOption) ([]bool, error) {
@@ -142,14 +343,37 @@ func (c *Cache) CheckGuildsExist(ctx context.Context, guildIDs []snowflake.ID, o
 	return res, nil
 }
 
-func (c *Cache) SearchGuilds(ctx context.Context, data json.RawMessage, opts ...cache.CacheOption) ([]*cache.Guild, error) {
+func (c *Cache) BatchGetGuilds(ctx context.Context, guildIDs []snowflake.ID, opts ...cache.CacheOption) ([]*cache.Guild, error) {
+	options := cache.ResolveOptions(opts...)
+
+	err := checkBatchGetIDs(guildIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	guilds, err := c.cacheStore.GetGuildsByIDs(ctx, options.AppID, guildIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return batchGetResult(guilds, guildIDs, guildEntityID, func(guild *cache.Guild) bool {
+		return !options.ExcludeTainted || !guild.Tainted
+	}), nil
+}
+
+func (c *Cache) SearchGuilds(ctx context.Context, data json.RawMessage, opts ...cache.CacheOption) (*cache.Page[*cache.Guild], error) {
 	options := cache.ResolveOptions(opts...)
 
+	filter, err := searchFilter(options)
+	if err != nil {
+		return nil, err
+	}
+
 	guilds, err := c.cacheStore.SearchGuilds(ctx, store.SearchGuildsParams{
-		AppID:  options.AppID,
-		Limit:  options.Limit,
-		Offset: options.Offset,
-		Data:   data,
+		AppID:       options.AppID,
+		ListOptions: pageOptions(options),
+		Data:        data,
+		Filter:      filter,
 	})
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
@@ -158,7 +382,7 @@ func (c *Cache) SearchGuilds(ctx context.Context, data json.RawMessage, opts ...
 		return nil, err
 	}
 
-	return guilds, nil
+	return newPage(guilds, options, guildEntityID), nil
 }
 
 func (c *Cache) ComputeGuildPermissions(
@@ -170,41 +394,48 @@ func (c *Cache) ComputeGuildPermissions(
 ) (discord.Permissions, error) {
 	options := cache.ResolveOptions(opts...)
 
-	ownerID, err := c.cacheStore.GetGuildOwnerID(ctx, options.AppID, guildID)
+	calculator, err := c.permissionCalculator(ctx, options, guildID, userID, roleIDs)
 	if err != nil {
-		if errors.Is(err, store.ErrNotFound) {
-			return 0, service.ErrNotFound("guild not found")
-		}
-		return 0, fmt.Errorf("failed to get guild owner ID: %w", err)
+		return 0, err
 	}
 
-	if ownerID == userID {
-		return discord.PermissionsAll, nil
-	}
+	return calculator.Guild(), nil
+}
 
-	defaultRole, err := c.cacheStore.GetGuildRole(ctx, options.AppID, guildID, guildID)
+func (c *Cache) CheckMemberHierarchy(
+	ctx context.Context,
+	guildID snowflake.ID,
+	actorUserID snowflake.ID,
+	actorRoleIDs []snowflake.ID,
+	targetUserID snowflake.ID,
+	targetRoleIDs []snowflake.ID,
+	requiredPermissions discord.Permissions,
+	opts ...cache.CacheOption,
+) (*cache.HierarchyVerdict, error) {
+	options := cache.ResolveOptions(opts...)
+
+	calculator, err := c.permissionCalculator(ctx, options, guildID, actorUserID, actorRoleIDs)
 	if err != nil {
-		if errors.Is(err, store.ErrNotFound) {
-			return 0, service.ErrNotFound("default role not found")
-		}
-		return 0, fmt.Errorf("failed to get default role: %w", err)
+		return nil, err
 	}
 
-	permissions := defaultRole.Data.Permissions
+	ownerID, err := c.cacheStore.GetGuildOwnerID(ctx, options.AppID, guildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild owner ID: %w", err)
+	}
 
-	roles, err := c.cacheStore.GetGuildRolesByIDs(ctx, options.AppID, guildID, roleIDs)
+	actor, err := c.hierarchyMember(ctx, options.AppID, guildID, actorUserID, actorRoleIDs)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get guild roles by IDs: %w", err)
+		return nil, err
 	}
 
-	for _, role := range roles {
-		permissions |= role.Data.Permissions
-		if permissions.Has(discord.PermissionAdministrator) {
-			return discord.PermissionsAll, nil
-		}
+	target, err := c.hierarchyMember(ctx, options.AppID, guildID, targetUserID, targetRoleIDs)
+	if err != nil {
+		return nil, err
 	}
 
-	return permissions, nil
+	verdict := permissions.CheckMemberHierarchy(ownerID, actor, calculator.Guild(), target, requiredPermissions)
+	return &verdict, nil
 }
 
 func (c *Cache) GetChannel(ctx context.Context, channelID snowflake.ID, opts ...cache.CacheOption) (*cache.Channel, error) {
@@ -213,18 +444,28 @@ func (c *Cache) GetChannel(ctx context.Context, channelID snowflake.ID, opts ...
 	channel, err := c.cacheStore.GetChannel(ctx, options.AppID, channelID)
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
+			if c.fetchOnMiss(options) {
+				return c.fetcher.FetchChannel(ctx, options.AppID, channelID)
+			}
 			return nil, service.ErrNotFound("channel not found")
 		}
 		return nil, err
 	}
 
+	if options.ExcludeTainted && channel.Tainted {
+		if c.fetchOnMiss(options) {
+			return c.fetcher.FetchChannel(ctx, options.AppID, channelID)
+		}
+		return nil, service.ErrNotFound("channel not found")
+	}
+
 	return channel, nil
 }
 
-func (c *Cache) GetChannels(ctx context.Context, opts ...cache.CacheOption) ([]*cache.Channel, error) {
+func (c *Cache) GetChannels(ctx context.Context, opts ...cache.CacheOption) (*cache.Page[*cache.Channel], error) {
 	options := cache.ResolveOptions(opts...)
 
-	channels, err := c.cacheStore.GetChannels(ctx, options.AppID, options.Limit, options.Offset)
+	channels, err := c.cacheStore.GetChannels(ctx, options.AppID, pageOptions(options))
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			return nil, service.ErrNotFound("channels not found")
@@ -232,7 +473,11 @@ func (c *Cache) GetChannels(ctx context.Context, opts ...cache.CacheOption) ([]*
 		return nil, err
 	}
 
-	return channels, nil
+	return newPage(channels, options, channelEntityID), nil
+}
+
+func (c *Cache) BatchGetChannels(ctx context.Context, channelIDs []snowflake.ID, opts ...cache.CacheOption) ([]*cache.Channel, error) {
+	return c.batchGetChannels(ctx, 0, channelIDs, cache.ResolveOptions(opts...))
 }
 
 func (c *Cache) CountChannels(ctx context.Context, opts ...cache.CacheOption) (int, error) {
@@ -252,18 +497,42 @@ func (c *Cache) GetGuildChannel(ctx context.Context, guildID snowflake.ID, chann
 	channel, err := c.cacheStore.GetGuildChannel(ctx, options.AppID, guildID, channelID)
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
+			if c.fetchOnMiss(options) {
+				return c.fetchGuildChannel(ctx, options.AppID, guildID, channelID)
+			}
 			return nil, service.ErrNotFound("channel not found")
 		}
 		return nil, err
 	}
 
+	if options.ExcludeTainted && channel.Tainted {
+		if c.fetchOnMiss(options) {
+			return c.fetchGuildChannel(ctx, options.AppID, guildID, channelID)
+		}
+		return nil, service.ErrNotFound("channel not found")
+	}
+
 	return channel, nil
 }
 
-func (c *Cache) GetGuildChannels(ctx context.Context, guildID snowflake.ID, opts ...cache.CacheOption) ([]*cache.Channel, error) {
+// fetchGuildChannel fetches the channel from Discord and makes sure that it belongs to the guild.
+func (c *Cache) fetchGuildChannel(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, channelID snowflake.ID) (*cache.Channel, error) {
+	channel, err := c.fetcher.FetchChannel(ctx, appID, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if channel.GuildID != guildID {
+		return nil, service.ErrNotFound("channel not found")
+	}
+
+	return channel, nil
+}
+
+func (c *Cache) GetGuildChannels(ctx context.Context, guildID snowflake.ID, opts ...cache.CacheOption) (*cache.Page[*cache.Channel], error) {
 	options := cache.ResolveOptions(opts...)
 
-	channels, err := c.cacheStore.GetGuildChannels(ctx, options.AppID, guildID, options.Limit, options.Offset)
+	channels, err := c.cacheStore.GetGuildChannels(ctx, options.AppID, guildID, pageOptions(options))
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			return nil, service.ErrNotFound("channels not found")
@@ -271,7 +540,28 @@ func (c *Cache) GetGuildChannels(ctx context.Context, guildID snowflake.ID, opts
 		return nil, err
 	}
 
-	return channels, nil
+	return newPage(channels, options, channelEntityID), nil
+}
+
+func (c *Cache) BatchGetGuildChannels(ctx context.Context, guildID snowflake.ID, channelIDs []snowflake.ID, opts ...cache.CacheOption) ([]*cache.Channel, error) {
+	return c.batchGetChannels(ctx, guildID, channelIDs, cache.ResolveOptions(opts...))
+}
+
+// batchGetChannels gets the channels of all guilds if guildID is 0.
+func (c *Cache) batchGetChannels(ctx context.Context, guildID snowflake.ID, channelIDs []snowflake.ID, options cache.CacheOptions) ([]*cache.Channel, error) {
+	err := checkBatchGetIDs(channelIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	channels, err := c.cacheStore.GetChannelsByIDs(ctx, options.AppID, channelIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return batchGetResult(channels, channelIDs, channelEntityID, func(channel *cache.Channel) bool {
+		return (guildID == 0 || channel.GuildID == guildID) && (!options.ExcludeTainted || !channel.Tainted)
+	}), nil
 }
 
 func (c *Cache) GetGuildChannelsWithPermissions(
@@ -283,7 +573,7 @@ func (c *Cache) GetGuildChannelsWithPermissions(
 ) ([]*cache.ChannelWithPermissions, error) {
 	options := cache.ResolveOptions(opts...)
 
-	channels, err := c.cacheStore.GetGuildChannels(ctx, options.AppID, guildID, options.Limit, options.Offset)
+	channels, err := c.cacheStore.GetGuildChannels(ctx, options.AppID, guildID, listOptions(options))
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			return nil, service.ErrNotFound("channels not found")
@@ -291,23 +581,27 @@ func (c *Cache) GetGuildChannelsWithPermissions(
 		return nil, err
 	}
 
-	guildPermissions, err := c.ComputeGuildPermissions(ctx, guildID, userID, roleIDs, opts...)
+	calculator, err := c.permissionCalculator(ctx, options, guildID, userID, roleIDs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compute guild permissions: %w", err)
 	}
 
+	guildChannels := guildChannelsByID(channels)
 	res := make([]*cache.ChannelWithPermissions, 0, len(channels))
 	for _, channel := range channels {
-		guildChannel, ok := channel.Data.(discord.GuildChannel)
+		guildChannel, ok := guildChannels[channel.ChannelID]
 		if !ok {
 			continue
 		}
 
-		permissions := computeChannelPermissions(guildChannel, userID, roleIDs, guildPermissions)
+		permissionChannel, err := c.permissionChannel(ctx, options.AppID, guildChannel, guildChannels)
+		if err != nil {
+			return nil, err
+		}
 
 		res = append(res, &cache.ChannelWithPermissions{
 			Channel:     *channel,
-			Permissions: permissions,
+			Permissions: calculator.Channel(permissionChannel),
 		})
 	}
 
@@ -325,14 +619,19 @@ func (c *Cache) CountGuildChannels(ctx context.Context, guildID snowflake.ID, op
 	return count, nil
 }
 
-func (c *Cache) SearchChannels(ctx context.Context, data json.RawMessage, opts ...cache.CacheOption) ([]*cache.Channel, error) {
+func (c *Cache) SearchChannels(ctx context.Context, data json.RawMessage, opts ...cache.CacheOption) (*cache.Page[*cache.Channel], error) {
 	options := cache.ResolveOptions(opts...)
 
+	filter, err := searchFilter(options)
+	if err != nil {
+		return nil, err
+	}
+
 	channels, err := c.cacheStore.SearchChannels(ctx, store.SearchChannelsParams{
-		AppID:  options.AppID,
-		Limit:  options.Limit,
-		Offset: options.Offset,
-		Data:   data,
+		AppID:       options.AppID,
+		ListOptions: pageOptions(options),
+		Data:        data,
+		Filter:      filter,
 	})
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
@@ -341,18 +640,23 @@ func (c *Cache) SearchChannels(ctx context.Context, data json.RawMessage, opts .
 		return nil, err
 	}
 
-	return channels, nil
+	return newPage(channels, options, channelEntityID), nil
 }
 
-func (c *Cache) SearchGuildChannels(ctx context.Context, guildID snowflake.ID, data json.RawMessage, opts ...cache.CacheOption) ([]*cache.Channel, error) {
+func (c *Cache) SearchGuildChannels(ctx context.Context, guildID snowflake.ID, data json.RawMessage, opts ...cache.CacheOption) (*cache.Page[*cache.Channel], error) {
 	options := cache.ResolveOptions(opts...)
 
+	filter, err := searchFilter(options)
+	if err != nil {
+		return nil, err
+	}
+
 	channels, err := c.cacheStore.SearchGuildChannels(ctx, store.SearchGuildChannelsParams{
-		AppID:   options.AppID,
-		GuildID: guildID,
-		Limit:   options.Limit,
-		Offset:  options.Offset,
-		Data:    data,
+		AppID:       options.AppID,
+		GuildID:     guildID,
+		ListOptions: pageOptions(options),
+		Data:        data,
+		Filter:      filter,
 	})
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
@@ -361,7 +665,7 @@ func (c *Cache) SearchGuildChannels(ctx context.Context, guildID snowflake.ID, d
 		return nil, err
 	}
 
-	return channels, nil
+	return newPage(channels, options, channelEntityID), nil
 }
 
 func (c *Cache) ComputeChannelPermissions(
@@ -381,21 +685,22 @@ func (c *Cache) ComputeChannelPermissions(
 		return 0, fmt.Errorf("failed to get channel: %w", err)
 	}
 
-	guildPermissions, err := c.ComputeGuildPermissions(ctx, channel.GuildID, userID, roleIDs, opts...)
+	calculator, err := c.permissionCalculator(ctx, options, channel.GuildID, userID, roleIDs)
 	if err != nil {
 		return 0, fmt.Errorf("failed to compute guild permissions: %w", err)
 	}
 
-	if guildPermissions.Has(discord.PermissionAdministrator) {
-		return discord.PermissionsAll, nil
-	}
-
 	guildChannel, ok := channel.Data.(discord.GuildChannel)
 	if !ok {
-		return 0, fmt.Errorf("channel is not a guild channel: %w", err)
+		return 0, errors.New("channel is not a guild channel")
+	}
+
+	permissionChannel, err := c.permissionChannel(ctx, options.AppID, guildChannel, nil)
+	if err != nil {
+		return 0, err
 	}
 
-	return computeChannelPermissions(guildChannel, userID, roleIDs, guildPermissions), nil
+	return calculator.Channel(permissionChannel), nil
 }
 
 func (c *Cache) MassComputeChannelPermissions(
@@ -408,11 +713,12 @@ func (c *Cache) MassComputeChannelPermissions(
 ) ([]discord.Permissions, error) {
 	options := cache.ResolveOptions(opts...)
 
-	guildPermissions, err := c.ComputeGuildPermissions(ctx, guildID, userID, roleIDs, opts...)
+	calculator, err := c.permissionCalculator(ctx, options, guildID, userID, roleIDs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compute guild permissions: %w", err)
 	}
 
+	guildPermissions := calculator.Guild()
 	res := make([]discord.Permissions, len(channelIDs))
 	for i, channelID := range channelIDs {
 		if guildPermissions.Has(discord.PermissionAdministrator) {
@@ -430,7 +736,12 @@ func (c *Cache) MassComputeChannelPermissions(
 			continue
 		}
 
-		res[i] = computeChannelPermissions(guildChannel, userID, roleIDs, guildPermissions)
+		permissionChannel, err := c.permissionChannel(ctx, options.AppID, guildChannel, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		res[i] = calculator.Channel(permissionChannel)
 	}
 
 	return res, nil
@@ -447,13 +758,17 @@ func (c *Cache) GetRole(ctx context.Context, roleID snowflake.ID, opts ...cache.
 		return nil, err
 	}
 
+	if options.ExcludeTainted && role.Tainted {
+		return nil, service.ErrNotFound("role not found")
+	}
+
 	return role, nil
 }
 
-func (c *Cache) GetRoles(ctx context.Context, opts ...cache.CacheOption) ([]*cache.Role, error) {
+func (c *Cache) GetRoles(ctx context.Context, opts ...cache.CacheOption) (*cache.Page[*cache.Role], error) {
 	options := cache.ResolveOptions(opts...)
 
-	roles, err := c.cacheStore.GetRoles(ctx, options.AppID, options.Limit, options.Offset)
+	roles, err := c.cacheStore.GetRoles(ctx, options.AppID, pageOptions(options))
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			return nil, service.ErrNotFound("roles not found")
@@ -461,7 +776,11 @@ func (c *Cache) GetRoles(ctx context.Context, opts ...cache.CacheOption) ([]*cac
 		return nil, err
 	}
 
-	return roles, nil
+	return newPage(roles, options, roleEntityID), nil
+}
+
+func (c *Cache) BatchGetRoles(ctx context.Context, roleIDs []snowflake.ID, opts ...cache.CacheOption) ([]*cache.Role, error) {
+	return c.batchGetRoles(ctx, 0, roleIDs, cache.ResolveOptions(opts...))
 }
 
 func (c *Cache) CountRoles(ctx context.Context, opts ...cache.CacheOption) (int, error) {
@@ -481,18 +800,28 @@ func (c *Cache) GetGuildRole(ctx context.Context, guildID snowflake.ID, roleID s
 	role, err := c.cacheStore.GetGuildRole(ctx, options.AppID, guildID, roleID)
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
+			if c.fetchOnMiss(options) {
+				return c.fetcher.FetchGuildRole(ctx, options.AppID, guildID, roleID)
+			}
 			return nil, service.ErrNotFound("role not found")
 		}
 		return nil, err
 	}
 
+	if options.ExcludeTainted && role.Tainted {
+		if c.fetchOnMiss(options) {
+			return c.fetcher.FetchGuildRole(ctx, options.AppID, guildID, roleID)
+		}
+		return nil, service.ErrNotFound("role not found")
+	}
+
 	return role, nil
 }
 
-func (c *Cache) GetGuildRoles(ctx context.Context, guildID snowflake.ID, opts ...cache.CacheOption) ([]*cache.Role, error) {
+func (c *Cache) GetGuildRoles(ctx context.Context, guildID snowflake.ID, opts ...cache.CacheOption) (*cache.Page[*cache.Role], error) {
 	options := cache.ResolveOptions(opts...)
 
-	roles, err := c.cacheStore.GetGuildRoles(ctx, options.AppID, guildID, options.Limit, options.Offset)
+	roles, err := c.cacheStore.GetGuildRoles(ctx, options.AppID, guildID, pageOptions(options))
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			return nil, service.ErrNotFound("roles not found")
@@ -500,7 +829,28 @@ func (c *Cache) GetGuildRoles(ctx context.Context, guildID snowflake.ID, opts ..
 		return nil, err
 	}
 
-	return roles, nil
+	return newPage(roles, options, roleEntityID), nil
+}
+
+func (c *Cache) BatchGetGuildRoles(ctx context.Context, guildID snowflake.ID, roleIDs []snowflake.ID, opts ...cache.CacheOption) ([]*cache.Role, error) {
+	return c.batchGetRoles(ctx, guildID, roleIDs, cache.ResolveOptions(opts...))
+}
+
+// batchGetRoles gets the roles of all guilds if guildID is 0.
+func (c *Cache) batchGetRoles(ctx context.Context, guildID snowflake.ID, roleIDs []snowflake.ID, options cache.CacheOptions) ([]*cache.Role, error) {
+	err := checkBatchGetIDs(roleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := c.cacheStore.GetRolesByIDs(ctx, options.AppID, roleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return batchGetResult(roles, roleIDs, roleEntityID, func(role *cache.Role) bool {
+		return (guildID == 0 || role.GuildID == guildID) && (!options.ExcludeTainted || !role.Tainted)
+	}), nil
 }
 
 func (c *Cache) CountGuildRoles(ctx context.Context, guildID snowflake.ID, opts ...cache.CacheOption) (int, error) {
@@ -514,14 +864,19 @@ func (c *Cache) CountGuildRoles(ctx context.Context, guildID snowflake.ID, opts
 	return count, nil
 }
 
-func (c *Cache) SearchRoles(ctx context.Context, data json.RawMessage, opts ...cache.CacheOption) ([]*cache.Role, error) {
+func (c *Cache) SearchRoles(ctx context.Context, data json.RawMessage, opts ...cache.CacheOption) (*cache.Page[*cache.Role], error) {
 	options := cache.ResolveOptions(opts...)
 
+	filter, err := searchFilter(options)
+	if err != nil {
+		return nil, err
+	}
+
 	roles, err := c.cacheStore.SearchRoles(ctx, store.SearchRolesParams{
-		AppID:  options.AppID,
-		Limit:  options.Limit,
-		Offset: options.Offset,
-		Data:   data,
+		AppID:       options.AppID,
+		ListOptions: pageOptions(options),
+		Data:        data,
+		Filter:      filter,
 	})
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
@@ -530,18 +885,23 @@ func (c *Cache) SearchRoles(ctx context.Context, data json.RawMessage, opts ...c
 		return nil, err
 	}
 
-	return roles, nil
+	return newPage(roles, options, roleEntityID), nil
 }
 
-func (c *Cache) SearchGuildRoles(ctx context.Context, guildID snowflake.ID, data json.RawMessage, opts ...cache.CacheOption) ([]*cache.Role, error) {
+func (c *Cache) SearchGuildRoles(ctx context.Context, guildID snowflake.ID, data json.RawMessage, opts ...cache.CacheOption) (*cache.Page[*cache.Role], error) {
 	options := cache.ResolveOptions(opts...)
 
+	filter, err := searchFilter(options)
+	if err != nil {
+		return nil, err
+	}
+
 	roles, err := c.cacheStore.SearchGuildRoles(ctx, store.SearchGuildRolesParams{
-		AppID:   options.AppID,
-		GuildID: guildID,
-		Limit:   options.Limit,
-		Offset:  options.Offset,
-		Data:    data,
+		AppID:       options.AppID,
+		GuildID:     guildID,
+		ListOptions: pageOptions(options),
+		Data:        data,
+		Filter:      filter,
 	})
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
@@ -550,27 +910,152 @@ func (c *Cache) SearchGuildRoles(ctx context.Context, guildID snowflake.ID, data
 		return nil, err
 	}
 
-	return roles, nil
+	return newPage(roles, options, roleEntityID), nil
+}
+
+func (c *Cache) CanManageRole(
+	ctx context.Context,
+	guildID snowflake.ID,
+	userID snowflake.ID,
+	roleIDs []snowflake.ID,
+	roleID snowflake.ID,
+	opts ...cache.CacheOption,
+) (*cache.HierarchyVerdict, error) {
+	options := cache.ResolveOptions(opts...)
+
+	calculator, err := c.permissionCalculator(ctx, options, guildID, userID, roleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	ownerID, err := c.cacheStore.GetGuildOwnerID(ctx, options.AppID, guildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild owner ID: %w", err)
+	}
+
+	role, err := c.cacheStore.GetGuildRole(ctx, options.AppID, guildID, roleID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, service.ErrNotFound("role not found")
+		}
+		return nil, fmt.Errorf("failed to get guild role: %w", err)
+	}
+
+	actor, err := c.hierarchyMember(ctx, options.AppID, guildID, userID, roleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	verdict := permissions.CheckRoleHierarchy(guildID, ownerID, actor, calculator.Guild(), hierarchyRole(role))
+	return &verdict, nil
+}
+
+func (c *Cache) GetGuildEmojis(ctx context.Context, guildID snowflake.ID, opts ...cache.CacheOption) (*cache.Page[*cache.Emoji], error) {
+	options := cache.ResolveOptions(opts...)
+
+	emojis, err := c.cacheStore.GetGuildEmojis(ctx, options.AppID, guildID, pageOptions(options))
+	if err != nil {
+		return nil, err
+	}
+
+	return newPage(emojis, options, emojiEntityID), nil
 }
 
-func (c *Cache) GetGuildEmojis(ctx context.Context, guildID snowflake.ID, opts ...cache.CacheOption) ([]*cache.Emoji, error) {
+func (c *Cache) BatchGetEmojis(ctx context.Context, emojiIDs []snowflake.ID, opts ...cache.CacheOption) ([]*cache.Emoji, error) {
 	options := cache.ResolveOptions(opts...)
 
-	emojis, err := c.cacheStore.GetGuildEmojis(ctx, options.AppID, guildID, options.Limit, options.Offset)
+	err := checkBatchGetIDs(emojiIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	emojis, err := c.cacheStore.GetEmojisByIDs(ctx, options.AppID, emojiIDs)
 	if err != nil {
 		return nil, err
 	}
 
-	return emojis, nil
+	return batchGetResult(emojis, emojiIDs, emojiEntityID, func(emoji *cache.Emoji) bool {
+		return !options.ExcludeTainted || !emoji.Tainted
+	}), nil
 }
 
-func (c *Cache) GetGuildStickers(ctx context.Context, guildID snowflake.ID, opts ...cache.CacheOption) ([]*cache.Sticker, error) {
+func (c *Cache) GetGuildStickers(ctx context.Context, guildID snowflake.ID, opts ...cache.CacheOption) (*cache.Page[*cache.Sticker], error) {
 	options := cache.ResolveOptions(opts...)
 
-	stickers, err := c.cacheStore.GetGuildStickers(ctx, options.AppID, guildID, options.Limit, options.Offset)
+	stickers, err := c.cacheStore.GetGuildStickers(ctx, options.AppID, guildID, pageOptions(options))
 	if err != nil {
 		return nil, err
 	}
 
-	return stickers, nil
+	return newPage(stickers, options, stickerEntityID), nil
+}
+
+func (c *Cache) BatchGetStickers(ctx context.Context, stickerIDs []snowflake.ID, opts ...cache.CacheOption) ([]*cache.Sticker, error) {
+	options := cache.ResolveOptions(opts...)
+
+	err := checkBatchGetIDs(stickerIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	stickers, err := c.cacheStore.GetStickersByIDs(ctx, options.AppID, stickerIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return batchGetResult(stickers, stickerIDs, stickerEntityID, func(sticker *cache.Sticker) bool {
+		return !options.ExcludeTainted || !sticker.Tainted
+	}), nil
+}
+
+func (c *Cache) GetStats(ctx context.Context, opts ...cache.CacheOption) ([]*cache.CacheStats, error) {
+	options := cache.ResolveOptions(opts...)
+
+	stats, err := c.cacheStore.GetCacheStats(ctx, store.GetCacheStatsParams{
+		AppID: options.AppID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cache stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+func (c *Cache) Resync(ctx context.Context, guildID snowflake.ID, opts ...cache.CacheOption) (*cache.ResyncResult, error) {
+	options := cache.ResolveOptions(opts...)
+
+	if options.AppID == 0 {
+		return nil, service.ErrInvalidRequest("resyncing requires an app ID", nil)
+	}
+	if c.fetcher == nil {
+		return nil, errors.New("resyncing requires a REST fetcher")
+	}
+
+	if guildID == 0 {
+		result, err := c.fetcher.ResyncGuilds(ctx, options.AppID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resync guilds: %w", err)
+		}
+		return result, nil
+	}
+
+	result, err := c.fetcher.ResyncGuild(ctx, options.AppID, guildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resync guild: %w", err)
+	}
+	return result, nil
+}
+
+func (c *Cache) PurgeApp(ctx context.Context, opts ...cache.CacheOption) (*cache.PurgeResult, error) {
+	options := cache.ResolveOptions(opts...)
+
+	if options.AppID == 0 {
+		return nil, service.ErrInvalidRequest("purging requires an app ID", nil)
+	}
+
+	deleted, err := purgeApp(ctx, c.cacheStore, c.broker, options.AppID)
+	if err != nil {
+		return nil, err
+	}
+	return &cache.PurgeResult{Deleted: deleted}, nil
 }
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"github.com/merlinfuchs/stateway/stateway-lib/broker"
+	"github.com/merlinfuchs/stateway/stateway-lib/cache"
+)
+
+// existingEntity turns a not found error into a nil entity so it can be used as the old version of a change.
+func existingEntity[T any](entity *T, err error) (*T, error) {
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return entity, nil
+}
+
+// sameData reports whether two Discord entities serialize to the same JSON.
+func sameData(a any, b any) bool {
+	rawA, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	rawB, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(rawA, rawB)
+}
+
+// publishChange publishes a change event for an entity that has been written to the store.
+// The store write has already happened at this point, so failures are only logged.
+func publishChange[T any](
+	ctx context.Context,
+	br broker.Broker,
+	appID snowflake.ID,
+	guildID snowflake.ID,
+	entityType cache.ChangeEntityType,
+	entityID snowflake.ID,
+	oldEntity *T,
+	newEntity *T,
+) {
+	if br == nil || (oldEntity == nil && newEntity == nil) {
+		return
+	}
+
+	change, err := cache.NewChangeEvent(appID, guildID, entityType, entityID, oldEntity, newEntity)
+	if err != nil {
+		slog.Error(
+			"Failed to create cache change event",
+			slog.String("entity_type", string(entityType)),
+			slog.String("entity_id", entityID.String()),
+			slog.Any("error", err),
+		)
+		return
+	}
+
+	err = br.Publish(ctx, change)
+	if err != nil {
+		slog.Error(
+			"Failed to publish cache change event",
+			slog.String("subject", change.EventSubject()),
+			slog.Any("error", err),
+		)
+	}
+
+	publishInvalidation(ctx, br, cache.Invalidation{
+		AppID:      appID,
+		GuildID:    guildID,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Action:     change.Action,
+	})
+}
+
+// publishInvalidation tells the near caches of clients to drop the entity.
+// Failures are only logged, the near caches will refetch the entity after their TTL.
+func publishInvalidation(ctx context.Context, br broker.Broker, invalidation cache.Invalidation) {
+	if br == nil {
+		return
+	}
+
+	err := cache.PublishInvalidation(ctx, br, invalidation)
+	if err != nil {
+		slog.Error(
+			"Failed to publish cache invalidation",
+			slog.String("subject", invalidation.Subject()),
+			slog.Any("error", err),
+		)
+	}
+}
+
+// syncGuildEntities publishes the changes between the cached entities of a guild collection and the entities of an event
+// that replaces the whole collection, like GUILD_CREATE.
+// Cached entities that are missing from the event are deleted, unless they have been updated after the event.
+// No changes are published for cached entities that have been updated after the event, the store doesn't overwrite them.
+func syncGuildEntities[T any](
+	ctx context.Context,
+	br broker.Broker,
+	appID snowflake.ID,
+	guildID snowflake.ID,
+	entityType cache.ChangeEntityType,
+	updatedAt time.Time,
+	oldEntities []*T,
+	newEntities []*T,
+	entityID func(*T) snowflake.ID,
+	entityUpdatedAt func(*T) time.Time,
+	entityData func(*T) any,
+	deleteEntity func(snowflake.ID) error,
+) error {
+	oldByID := make(map[snowflake.ID]*T, len(oldEntities))
+	for _, entity := range oldEntities {
+		oldByID[entityID(entity)] = entity
+	}
+
+	for _, newEntity := range newEntities {
+		id := entityID(newEntity)
+		oldEntity := oldByID[id]
+		delete(oldByID, id)
+		if oldEntity != nil && (sameData(entityData(oldEntity), entityData(newEntity)) || entityUpdatedAt(oldEntity).After(updatedAt)) {
+			continue
+		}
+
+		publishChange(ctx, br, appID, guildID, entityType, id, oldEntity, newEntity)
+	}
+
+	for id, oldEntity := range oldByID {
+		if entityUpdatedAt(oldEntity).After(updatedAt) {
+			continue
+		}
+
+		err := deleteEntity(id)
+		if err != nil {
+			return err
+		}
+		publishChange(ctx, br, appID, guildID, entityType, id, oldEntity, nil)
+	}
+	return nil
+}
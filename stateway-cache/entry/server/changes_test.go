@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/gateway"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/inmemory"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
+	"github.com/merlinfuchs/stateway/stateway-lib/broker"
+	"github.com/merlinfuchs/stateway/stateway-lib/cache"
+	"github.com/merlinfuchs/stateway/stateway-lib/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingBroker records the change events that are published and ignores invalidations.
+type recordingBroker struct {
+	broker.Broker
+	changes []*cache.ChangeEvent
+}
+
+func (b *recordingBroker) Publish(ctx context.Context, evt event.Event) error {
+	if change, ok := evt.(*cache.ChangeEvent); ok {
+		b.changes = append(b.changes, change)
+	}
+	return nil
+}
+
+func (b *recordingBroker) Broadcast(ctx context.Context, subject string, data []byte) error {
+	return nil
+}
+
+func TestSyncGuildEntities(t *testing.T) {
+	ctx := context.Background()
+	br := &recordingBroker{}
+
+	eventTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	role := func(id snowflake.ID, name string, updatedAt time.Time) *model.Role {
+		return &model.Role{AppID: 1, GuildID: 100, RoleID: id, Data: discord.Role{ID: id, Name: name}, UpdatedAt: updatedAt}
+	}
+
+	var deleted []snowflake.ID
+	err := syncGuildEntities(ctx, br, 1, 100, cache.ChangeEntityTypeRole, eventTime,
+		[]*model.Role{
+			role(1, "unchanged", eventTime.Add(-time.Hour)),
+			role(2, "old name", eventTime.Add(-time.Hour)),
+			role(3, "deleted", eventTime.Add(-time.Hour)),
+			role(4, "created after the event", eventTime.Add(time.Hour)),
+			role(6, "renamed after the event", eventTime.Add(time.Hour)),
+		},
+		[]*model.Role{
+			role(1, "unchanged", eventTime),
+			role(2, "new name", eventTime),
+			role(5, "created", eventTime),
+			role(6, "old name", eventTime),
+		},
+		func(r *model.Role) snowflake.ID { return r.RoleID },
+		func(r *model.Role) time.Time { return r.UpdatedAt },
+		func(r *model.Role) any { return r.Data },
+		func(id snowflake.ID) error {
+			deleted = append(deleted, id)
+			return nil
+		},
+	)
+	require.NoError(t, err)
+
+	// Roles that are newer than the event are kept and aren't published, they have been changed after it
+	assert.Equal(t, []snowflake.ID{3}, deleted)
+
+	actions := make(map[snowflake.ID]cache.ChangeAction, len(br.changes))
+	for _, change := range br.changes {
+		actions[change.EntityID] = change.Action
+	}
+	assert.Equal(t, map[snowflake.ID]cache.ChangeAction{
+		2: cache.ChangeActionUpdated,
+		3: cache.ChangeActionDeleted,
+		5: cache.ChangeActionCreated,
+	}, actions)
+}
+
+func TestCacheWorkerSkipsStaleRoleUpdate(t *testing.T) {
+	ctx := context.Background()
+	br := &recordingBroker{}
+	cacheStore := inmemory.NewMapCacheStore()
+	worker := &CacheWorker{
+		cacheStore: cacheStore,
+		policies:   newCachePolicies(&fakeGateway{}, cacheStore),
+		broker:     br,
+	}
+
+	eventTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	roleUpdate := func(name string, at time.Time) *event.GatewayEvent {
+		data, err := json.Marshal(gateway.EventGuildRoleUpdate{GuildID: 100, Role: discord.Role{ID: 10, Name: name}})
+		require.NoError(t, err)
+		return &event.GatewayEvent{ID: snowflake.New(at), AppID: 1, Type: string(gateway.EventTypeGuildRoleUpdate), Data: data}
+	}
+
+	_, err := worker.HandleEvent(ctx, roleUpdate("new", eventTime.Add(time.Minute)))
+	require.NoError(t, err)
+	require.Len(t, br.changes, 1)
+
+	// A redelivered older event is neither written nor published
+	_, err = worker.HandleEvent(ctx, roleUpdate("old", eventTime))
+	require.NoError(t, err)
+	assert.Len(t, br.changes, 1)
+
+	role, err := cacheStore.GetRole(ctx, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "new", role.Data.Name)
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+)
+
+// upsertCoalescerMaxBatch is the maximum number of mass upserts that are merged into one.
+const upsertCoalescerMaxBatch = 64
+
+// upsertCoalescer merges the mass upserts of guilds that are handled concurrently, e.g. the GUILD_CREATE flood
+// after a gateway restart, so they are written in a single transaction per app.
+// Upserts that arrive while a batch is being written are merged into the next one, a single upsert isn't delayed.
+type upsertCoalescer struct {
+	cacheStore store.CacheStore
+	requests   chan coalescedUpsert
+}
+
+type coalescedUpsert struct {
+	params store.MassUpsertEntitiesParams
+	done   chan error
+}
+
+func newUpsertCoalescer(ctx context.Context, cacheStore store.CacheStore) *upsertCoalescer {
+	c := &upsertCoalescer{
+		cacheStore: cacheStore,
+		requests:   make(chan coalescedUpsert),
+	}
+	go c.run(ctx)
+	return c
+}
+
+// MassUpsertEntities upserts the entities together with the entities of other guilds and waits until they are written.
+func (c *upsertCoalescer) MassUpsertEntities(ctx context.Context, params store.MassUpsertEntitiesParams) error {
+	req := coalescedUpsert{
+		params: params,
+		done:   make(chan error, 1),
+	}
+
+	select {
+	case c.requests <- req:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-req.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *upsertCoalescer) run(ctx context.Context) {
+	for {
+		var batch []coalescedUpsert
+		select {
+		case <-ctx.Done():
+			return
+		case req := <-c.requests:
+			batch = append(batch, req)
+		}
+
+	collect:
+		for len(batch) < upsertCoalescerMaxBatch {
+			select {
+			case req := <-c.requests:
+				batch = append(batch, req)
+			default:
+				break collect
+			}
+		}
+
+		c.flush(ctx, batch)
+	}
+}
+
+// flush writes the batch with one mass upsert per app and reports the result to every upsert of the app.
+// When the merged upsert of an app fails its upserts are retried one by one, so only the guild that caused the error fails.
+func (c *upsertCoalescer) flush(ctx context.Context, batch []coalescedUpsert) {
+	appIDs := make([]snowflake.ID, 0, 1)
+	apps := make(map[snowflake.ID]*store.MassUpsertEntitiesParams)
+	merged := make(map[snowflake.ID]int)
+	for _, req := range batch {
+		merged[req.params.AppID]++
+		params, ok := apps[req.params.AppID]
+		if !ok {
+			params = &store.MassUpsertEntitiesParams{AppID: req.params.AppID}
+			apps[req.params.AppID] = params
+			appIDs = append(appIDs, req.params.AppID)
+		}
+
+		params.Guilds = append(params.Guilds, req.params.Guilds...)
+		params.Roles = append(params.Roles, req.params.Roles...)
+		params.Channels = append(params.Channels, req.params.Channels...)
+		params.Emojis = append(params.Emojis, req.params.Emojis...)
+		params.Stickers = append(params.Stickers, req.params.Stickers...)
+	}
+
+	errs := make(map[snowflake.ID]error, len(appIDs))
+	for _, appID := range appIDs {
+		errs[appID] = c.cacheStore.MassUpsertEntities(ctx, *apps[appID])
+	}
+
+	for _, req := range batch {
+		err := errs[req.params.AppID]
+		if err != nil && merged[req.params.AppID] > 1 {
+			err = c.cacheStore.MassUpsertEntities(ctx, req.params)
+		}
+		req.done <- err
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/inmemory"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStore counts the mass upserts that reach the store.
+type countingStore struct {
+	store.CacheStore
+	mu    sync.Mutex
+	calls []store.MassUpsertEntitiesParams
+}
+
+func (s *countingStore) MassUpsertEntities(ctx context.Context, params store.MassUpsertEntitiesParams) error {
+	s.mu.Lock()
+	s.calls = append(s.calls, params)
+	s.mu.Unlock()
+	return s.CacheStore.MassUpsertEntities(ctx, params)
+}
+
+func guildCreateParams(appID snowflake.ID, guildID snowflake.ID) store.MassUpsertEntitiesParams {
+	return store.MassUpsertEntitiesParams{
+		AppID:  appID,
+		Guilds: []store.UpsertGuildParams{{AppID: appID, GuildID: guildID, Data: discord.Guild{ID: guildID}}},
+		Roles:  []store.UpsertRoleParams{{AppID: appID, GuildID: guildID, RoleID: guildID, Data: discord.Role{ID: guildID}}},
+	}
+}
+
+func TestUpsertCoalescerMergesApps(t *testing.T) {
+	ctx := context.Background()
+	cacheStore := &countingStore{CacheStore: inmemory.NewMapCacheStore()}
+	coalescer := &upsertCoalescer{cacheStore: cacheStore}
+
+	batch := []coalescedUpsert{
+		{params: guildCreateParams(1, 10), done: make(chan error, 1)},
+		{params: guildCreateParams(2, 20), done: make(chan error, 1)},
+		{params: guildCreateParams(1, 11), done: make(chan error, 1)},
+	}
+	coalescer.flush(ctx, batch)
+
+	for _, req := range batch {
+		assert.NoError(t, <-req.done)
+	}
+
+	require.Len(t, cacheStore.calls, 2)
+	assert.Equal(t, snowflake.ID(1), cacheStore.calls[0].AppID)
+	assert.Len(t, cacheStore.calls[0].Guilds, 2)
+	assert.Len(t, cacheStore.calls[0].Roles, 2)
+	assert.Equal(t, snowflake.ID(2), cacheStore.calls[1].AppID)
+	assert.Len(t, cacheStore.calls[1].Guilds, 1)
+}
+
+// failingStore fails every mass upsert that contains the bad guild.
+type failingStore struct {
+	countingStore
+	badGuildID snowflake.ID
+}
+
+func (s *failingStore) MassUpsertEntities(ctx context.Context, params store.MassUpsertEntitiesParams) error {
+	for _, guild := range params.Guilds {
+		if guild.GuildID == s.badGuildID {
+			return errors.New("bad guild")
+		}
+	}
+	return s.countingStore.MassUpsertEntities(ctx, params)
+}
+
+func TestUpsertCoalescerIsolatesFailures(t *testing.T) {
+	ctx := context.Background()
+	cacheStore := &failingStore{countingStore: countingStore{CacheStore: inmemory.NewMapCacheStore()}, badGuildID: 11}
+	coalescer := &upsertCoalescer{cacheStore: cacheStore}
+
+	batch := []coalescedUpsert{
+		{params: guildCreateParams(1, 10), done: make(chan error, 1)},
+		{params: guildCreateParams(1, 11), done: make(chan error, 1)},
+		{params: guildCreateParams(1, 12), done: make(chan error, 1)},
+	}
+	coalescer.flush(ctx, batch)
+
+	// Only the upsert of the bad guild fails, the others are retried on their own
+	assert.NoError(t, <-batch[0].done)
+	assert.Error(t, <-batch[1].done)
+	assert.NoError(t, <-batch[2].done)
+
+	guilds, err := cacheStore.GetGuilds(ctx, 1, store.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, guilds, 2)
+}
+
+func TestUpsertCoalescerConcurrentUpserts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cacheStore := &countingStore{CacheStore: inmemory.NewMapCacheStore()}
+	coalescer := newUpsertCoalescer(ctx, cacheStore)
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := coalescer.MassUpsertEntities(ctx, guildCreateParams(1, snowflake.ID(100+i)))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	guilds, err := cacheStore.GetGuilds(ctx, 1, store.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, guilds, 20)
+	assert.LessOrEqual(t, len(cacheStore.calls), 20)
+}
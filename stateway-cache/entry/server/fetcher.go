@@ -0,0 +1,310 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/rest"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"github.com/merlinfuchs/stateway/stateway-lib/broker"
+	"github.com/merlinfuchs/stateway/stateway-lib/cache"
+	"github.com/merlinfuchs/stateway/stateway-lib/gateway"
+	"github.com/merlinfuchs/stateway/stateway-lib/service"
+	"golang.org/x/sync/singleflight"
+)
+
+// restFetchTimeout limits how long a coalesced fetch can take, independent of the callers waiting for it.
+const restFetchTimeout = 10 * time.Second
+
+// RESTFetcher loads entities that aren't cached from the Discord REST API and writes them to the store.
+// Concurrent fetches of the same entity are coalesced into a single REST call.
+type RESTFetcher struct {
+	cacheStore store.CacheStore
+	gateway    gateway.Gateway
+	broker     broker.Broker
+	partition  cache.Partition
+	policies   *cachePolicies
+	group      singleflight.Group
+
+	mu      sync.Mutex
+	clients map[snowflake.ID]rest.Rest
+}
+
+// NewRESTFetcher creates a fetcher that gets the bot tokens of the apps from the gateway service.
+// Invalidations for resynced guilds are published to the broker, nil disables them.
+// Only the guilds of the partition are stored, fetched entities are filtered and stripped by the cache policy of the app.
+func NewRESTFetcher(
+	cacheStore store.CacheStore,
+	gw gateway.Gateway,
+	br broker.Broker,
+	partition cache.Partition,
+	policies *cachePolicies,
+) *RESTFetcher {
+	return &RESTFetcher{
+		cacheStore: cacheStore,
+		gateway:    gw,
+		broker:     br,
+		partition:  partition,
+		policies:   policies,
+		clients:    make(map[snowflake.ID]rest.Rest),
+	}
+}
+
+// FetchGuild fetches the guild together with its roles, emojis and stickers.
+func (f *RESTFetcher) FetchGuild(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (*cache.Guild, error) {
+	key := fmt.Sprintf("guild:%d:%d", appID, guildID)
+	return fetchCoalesced(ctx, f, key, func(ctx context.Context) (*cache.Guild, error) {
+		client, err := f.client(ctx, appID)
+		if err != nil {
+			return nil, err
+		}
+
+		guild, err := client.GetGuild(guildID, false, rest.WithCtx(ctx))
+		if err != nil {
+			return nil, f.restError(appID, err, "guild not found")
+		}
+
+		params, err := f.policies.Get(ctx, appID).apply(guildUpsertParams(appID, guildID, guild, time.Now().UTC()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply cache policy to fetched guild: %w", err)
+		}
+
+		err = f.cacheStore.MassUpsertEntities(ctx, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store fetched guild: %w", err)
+		}
+
+		return f.cacheStore.GetGuild(ctx, appID, guildID)
+	})
+}
+
+// guildUpsertParams returns the params to store the guild together with its roles, emojis and stickers.
+func guildUpsertParams(appID snowflake.ID, guildID snowflake.ID, guild *discord.RestGuild, now time.Time) store.MassUpsertEntitiesParams {
+	params := store.MassUpsertEntitiesParams{
+		AppID: appID,
+		Guilds: []store.UpsertGuildParams{{
+			AppID:     appID,
+			GuildID:   guildID,
+			Data:      guild.Guild,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}},
+		Roles:    make([]store.UpsertRoleParams, len(guild.Roles)),
+		Emojis:   make([]store.UpsertEmojiParams, len(guild.Emojis)),
+		Stickers: make([]store.UpsertStickerParams, len(guild.Stickers)),
+	}
+	for i, role := range guild.Roles {
+		params.Roles[i] = store.UpsertRoleParams{
+			AppID:     appID,
+			GuildID:   guildID,
+			RoleID:    role.ID,
+			Data:      role,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+	}
+	for i, emoji := range guild.Emojis {
+		params.Emojis[i] = store.UpsertEmojiParams{
+			AppID:     appID,
+			GuildID:   guildID,
+			EmojiID:   emoji.ID,
+			Data:      emoji,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+	}
+	for i, sticker := range guild.Stickers {
+		params.Stickers[i] = store.UpsertStickerParams{
+			AppID:     appID,
+			GuildID:   guildID,
+			StickerID: sticker.ID,
+			Data:      sticker,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+	}
+	return params
+}
+
+// FetchChannel fetches the channel, only guild channels are cached.
+func (f *RESTFetcher) FetchChannel(ctx context.Context, appID snowflake.ID, channelID snowflake.ID) (*cache.Channel, error) {
+	key := fmt.Sprintf("channel:%d:%d", appID, channelID)
+	return fetchCoalesced(ctx, f, key, func(ctx context.Context) (*cache.Channel, error) {
+		client, err := f.client(ctx, appID)
+		if err != nil {
+			return nil, err
+		}
+
+		channel, err := client.GetChannel(channelID, rest.WithCtx(ctx))
+		if err != nil {
+			return nil, f.restError(appID, err, "channel not found")
+		}
+
+		guildChannel, ok := channel.(discord.GuildChannel)
+		if !ok {
+			return nil, service.ErrNotFound("channel not found")
+		}
+
+		now := time.Now().UTC()
+		policy := f.policies.Get(ctx, appID)
+		// Channels that the app doesn't cache are returned without storing them,
+		// like channels of guilds that belong to another partition, which only the owning partition may store
+		if !policy.cachesChannel(channel) || !f.partition.OwnsGuild(guildChannel.GuildID()) {
+			return &cache.Channel{
+				AppID:     appID,
+				GuildID:   guildChannel.GuildID(),
+				ChannelID: channelID,
+				Data:      channel,
+				CreatedAt: now,
+				UpdatedAt: now,
+			}, nil
+		}
+
+		data, err := stripChannelFields(policy, channel)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = f.cacheStore.UpsertChannels(ctx, store.UpsertChannelParams{
+			AppID:     appID,
+			GuildID:   guildChannel.GuildID(),
+			ChannelID: channelID,
+			Data:      data,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to store fetched channel: %w", err)
+		}
+
+		return f.cacheStore.GetChannel(ctx, appID, channelID)
+	})
+}
+
+// FetchGuildRole fetches a role of the guild.
+func (f *RESTFetcher) FetchGuildRole(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, roleID snowflake.ID) (*cache.Role, error) {
+	key := fmt.Sprintf("role:%d:%d:%d", appID, guildID, roleID)
+	return fetchCoalesced(ctx, f, key, func(ctx context.Context) (*cache.Role, error) {
+		client, err := f.client(ctx, appID)
+		if err != nil {
+			return nil, err
+		}
+
+		role, err := client.GetRole(guildID, roleID, rest.WithCtx(ctx))
+		if err != nil {
+			return nil, f.restError(appID, err, "role not found")
+		}
+
+		now := time.Now().UTC()
+		policy := f.policies.Get(ctx, appID)
+		if !policy.caches(gateway.AppCacheEntityRole) {
+			// Roles of apps that don't cache them are returned without storing them
+			return &cache.Role{
+				AppID:     appID,
+				GuildID:   guildID,
+				RoleID:    roleID,
+				Data:      *role,
+				CreatedAt: now,
+				UpdatedAt: now,
+			}, nil
+		}
+
+		data, err := stripFields(policy, gateway.AppCacheEntityRole, *role)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = f.cacheStore.UpsertRoles(ctx, store.UpsertRoleParams{
+			AppID:     appID,
+			GuildID:   guildID,
+			RoleID:    roleID,
+			Data:      data,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to store fetched role: %w", err)
+		}
+
+		return f.cacheStore.GetGuildRole(ctx, appID, guildID, roleID)
+	})
+}
+
+// client returns the REST client of the app, creating it with the bot token from the gateway service.
+func (f *RESTFetcher) client(ctx context.Context, appID snowflake.ID) (rest.Rest, error) {
+	f.mu.Lock()
+	client, ok := f.clients[appID]
+	f.mu.Unlock()
+	if ok {
+		return client, nil
+	}
+
+	app, err := f.gateway.GetApp(ctx, appID, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get app: %w", err)
+	}
+	if app.Disabled {
+		return nil, fmt.Errorf("app %s is disabled", appID)
+	}
+
+	client = rest.New(rest.NewClient(app.DiscordBotToken))
+
+	f.mu.Lock()
+	f.clients[appID] = client
+	f.mu.Unlock()
+
+	return client, nil
+}
+
+// restError turns Discord errors into service errors.
+// Entities the bot can't access are treated as not found, invalid tokens drop the client so the token is reloaded.
+func (f *RESTFetcher) restError(appID snowflake.ID, err error, notFoundMessage string) error {
+	if isRESTNotFound(err) {
+		return service.ErrNotFound(notFoundMessage)
+	}
+
+	var restErr *rest.Error
+	if errors.As(err, &restErr) && restErr.Response != nil && restErr.Response.StatusCode == http.StatusUnauthorized {
+		f.mu.Lock()
+		delete(f.clients, appID)
+		f.mu.Unlock()
+	}
+	return fmt.Errorf("failed to fetch from Discord: %w", err)
+}
+
+// isRESTNotFound reports whether Discord responded that the entity doesn't exist or that the bot can't access it.
+func isRESTNotFound(err error) bool {
+	var restErr *rest.Error
+	if !errors.As(err, &restErr) || restErr.Response == nil {
+		return false
+	}
+	return restErr.Response.StatusCode == http.StatusNotFound || restErr.Response.StatusCode == http.StatusForbidden
+}
+
+// fetchCoalesced runs fetch once for all concurrent callers with the same key.
+// The fetch isn't canceled when one of the callers gives up, so it can still complete for the others.
+func fetchCoalesced[T any](ctx context.Context, f *RESTFetcher, key string, fetch func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+
+	resCh := f.group.DoChan(key, func() (any, error) {
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), restFetchTimeout)
+		defer cancel()
+		return fetch(fetchCtx)
+	})
+
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case res := <-resCh:
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		return res.Val.(T), nil
+	}
+}
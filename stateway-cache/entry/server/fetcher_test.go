@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/disgo/rest"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/inmemory"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"github.com/merlinfuchs/stateway/stateway-lib/cache"
+	"github.com/merlinfuchs/stateway/stateway-lib/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchChannelCoalesced(t *testing.T) {
+	ctx := context.Background()
+
+	var requests atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /channels/200", func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": "200", "type": 0, "guild_id": "100", "name": "general", "position": 0}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	cacheStore := inmemory.NewMapCacheStore()
+	fetcher := NewRESTFetcher(cacheStore, nil, nil, cache.Partition{}, newCachePolicies(&fakeGateway{}, cacheStore))
+	fetcher.clients[1] = rest.New(rest.NewClient("token", rest.WithURL(srv.URL)))
+
+	// A burst of misses for the same channel is served by a single REST call
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			channel, err := fetcher.FetchChannel(ctx, 1, 200)
+			if assert.NoError(t, err) {
+				assert.Equal(t, snowflake.ID(100), channel.GuildID)
+			}
+		}()
+	}
+
+	<-started
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), requests.Load())
+
+	channel, err := cacheStore.GetChannel(ctx, 1, 200)
+	require.NoError(t, err)
+	assert.Equal(t, "general", channel.Data.Name())
+}
+
+func TestFetchGuild(t *testing.T) {
+	ctx := context.Background()
+	srv := newFakeDiscord(t)
+
+	cacheStore := inmemory.NewMapCacheStore()
+	fetcher := NewRESTFetcher(cacheStore, nil, nil, cache.Partition{}, newCachePolicies(&fakeGateway{}, cacheStore))
+	fetcher.clients[1] = rest.New(rest.NewClient("token", rest.WithURL(srv.URL)))
+
+	guild, err := fetcher.FetchGuild(ctx, 1, 100)
+	require.NoError(t, err)
+	assert.Equal(t, "Guild", guild.Data.Name)
+
+	count, err := cacheStore.CountGuildRoles(ctx, 1, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// Guilds the app isn't in are reported as not found and not stored
+	_, err = fetcher.FetchGuild(ctx, 1, 999)
+	assert.True(t, service.IsErrorCode(err, service.ErrorCodeNotFound))
+
+	_, err = cacheStore.GetGuild(ctx, 1, 999)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
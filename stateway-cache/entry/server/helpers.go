@@ -1,39 +1,142 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"github.com/merlinfuchs/stateway/stateway-lib/cache"
+	"github.com/merlinfuchs/stateway/stateway-lib/permissions"
+	"github.com/merlinfuchs/stateway/stateway-lib/service"
 )
 
-func computeChannelPermissions(channel discord.GuildChannel, userID snowflake.ID, roleIDs []snowflake.ID, permissions discord.Permissions) discord.Permissions {
-	if overwrite, ok := channel.PermissionOverwrites().Role(channel.GuildID()); ok {
-		permissions |= overwrite.Allow
-		permissions &= ^overwrite.Deny
+// permissionCalculator loads the cached guild state that the permissions of the member depend on.
+func (c *Cache) permissionCalculator(
+	ctx context.Context,
+	options cache.CacheOptions,
+	guildID snowflake.ID,
+	userID snowflake.ID,
+	roleIDs []snowflake.ID,
+) (*permissions.Calculator, error) {
+	member := permissions.Member{
+		UserID:                     userID,
+		RoleIDs:                    roleIDs,
+		CommunicationDisabledUntil: options.CommunicationDisabledUntil,
+	}
+
+	ownerID, err := c.cacheStore.GetGuildOwnerID(ctx, options.AppID, guildID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, service.ErrNotFound("guild not found")
+		}
+		return nil, fmt.Errorf("failed to get guild owner ID: %w", err)
+	}
+
+	guild := permissions.Guild{
+		ID:      guildID,
+		OwnerID: ownerID,
+	}
+
+	// The owner has all permissions, so the roles don't have to be loaded
+	if ownerID == userID {
+		return permissions.NewCalculator(guild, member, time.Now()), nil
+	}
+
+	defaultRole, err := c.cacheStore.GetGuildRole(ctx, options.AppID, guildID, guildID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, service.ErrNotFound("default role not found")
+		}
+		return nil, fmt.Errorf("failed to get default role: %w", err)
+	}
+	guild.EveryonePermissions = defaultRole.Data.Permissions
+
+	roles, err := c.cacheStore.GetGuildRolesByIDs(ctx, options.AppID, guildID, roleIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guild roles by IDs: %w", err)
+	}
+
+	guild.RolePermissions = make(map[snowflake.ID]discord.Permissions, len(roles))
+	for _, role := range roles {
+		guild.RolePermissions[role.RoleID] = role.Data.Permissions
 	}
 
-	var (
-		allow discord.Permissions
-		deny  discord.Permissions
-	)
+	return permissions.NewCalculator(guild, member, time.Now()), nil
+}
+
+// permissionChannel returns the permission state of the channel.
+// Threads use the overwrites of their parent channel, which is looked up in channels before it's loaded from the store.
+// Threads whose parent isn't cached have no overwrites.
+func (c *Cache) permissionChannel(
+	ctx context.Context,
+	appID snowflake.ID,
+	channel discord.GuildChannel,
+	channels map[snowflake.ID]discord.GuildChannel,
+) (permissions.Channel, error) {
+	parentID := channel.ParentID()
+	if !permissions.IsThread(channel) || parentID == nil {
+		return permissions.NewChannel(channel, nil), nil
+	}
 
-	for _, roleID := range roleIDs {
-		if roleID == channel.GuildID() {
-			continue
+	parent, ok := channels[*parentID]
+	if !ok {
+		parentChannel, err := c.cacheStore.GetChannel(ctx, appID, *parentID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return permissions.Channel{}, fmt.Errorf("failed to get parent channel: %w", err)
 		}
+		if parentChannel != nil {
+			parent, _ = parentChannel.Data.(discord.GuildChannel)
+		}
+	}
+
+	return permissions.NewChannel(channel, parent), nil
+}
 
-		if overwrite, ok := channel.PermissionOverwrites().Role(roleID); ok {
-			allow |= overwrite.Allow
-			deny |= overwrite.Deny
+// guildChannelsByID returns the guild channels by their ID to look up thread parents.
+func guildChannelsByID(channels []*cache.Channel) map[snowflake.ID]discord.GuildChannel {
+	res := make(map[snowflake.ID]discord.GuildChannel, len(channels))
+	for _, channel := range channels {
+		if guildChannel, ok := channel.Data.(discord.GuildChannel); ok {
+			res[channel.ChannelID] = guildChannel
 		}
 	}
+	return res
+}
 
-	if overwrite, ok := channel.PermissionOverwrites().Member(userID); ok {
-		allow |= overwrite.Allow
-		deny |= overwrite.Deny
+// hierarchyMember loads the cached roles of the member that the role hierarchy depends on.
+// Roles that aren't cached, e.g. because they were deleted, are ignored.
+func (c *Cache) hierarchyMember(
+	ctx context.Context,
+	appID snowflake.ID,
+	guildID snowflake.ID,
+	userID snowflake.ID,
+	roleIDs []snowflake.ID,
+) (permissions.HierarchyMember, error) {
+	member := permissions.HierarchyMember{UserID: userID}
+	if len(roleIDs) == 0 {
+		return member, nil
 	}
 
-	permissions &= ^deny
-	permissions |= allow
+	roles, err := c.cacheStore.GetGuildRolesByIDs(ctx, appID, guildID, roleIDs)
+	if err != nil {
+		return member, fmt.Errorf("failed to get guild roles by IDs: %w", err)
+	}
 
-	return permissions
+	member.Roles = make([]permissions.Role, 0, len(roles))
+	for _, role := range roles {
+		member.Roles = append(member.Roles, hierarchyRole(role))
+	}
+	return member, nil
+}
+
+func hierarchyRole(role *cache.Role) permissions.Role {
+	return permissions.Role{
+		ID:       role.RoleID,
+		Position: role.Data.Position,
+		Managed:  role.Data.Managed,
+	}
 }
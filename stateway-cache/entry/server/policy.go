@@ -0,0 +1,608 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"github.com/merlinfuchs/stateway/stateway-lib/gateway"
+	"golang.org/x/sync/singleflight"
+)
+
+// cachePolicyTTL is how long the cache config of an app is used before it's reloaded from the gateway service.
+const cachePolicyTTL = time.Minute
+
+// cachePolicyRetryInterval is how long the previous or default policy is used when the cache config of an app can't be loaded.
+const cachePolicyRetryInterval = 5 * time.Second
+
+// cachePolicyLoadTimeout limits how long loading the cache config of an app can take.
+const cachePolicyLoadTimeout = 10 * time.Second
+
+// cachePolicyPurgeBatchSize is how many entities are listed at once when purging the entities that an app no longer caches.
+const cachePolicyPurgeBatchSize = 1000
+
+// cachePolicies resolves which entities of an app are cached from the cache config of the app and its group.
+// Configs are loaded from the gateway service and reloaded after cachePolicyTTL, so changes take effect without a restart.
+// When a reloaded config excludes entity types that were cached before, the cached entities of these types are purged,
+// when it strips more fields, they are stripped from the cached entities.
+type cachePolicies struct {
+	gateway    gateway.Gateway
+	cacheStore store.CacheStore
+	group      singleflight.Group
+	purges     sync.WaitGroup
+
+	mu       sync.Mutex
+	policies map[snowflake.ID]*cachePolicy
+}
+
+func newCachePolicies(gw gateway.Gateway, cacheStore store.CacheStore) *cachePolicies {
+	return &cachePolicies{
+		gateway:    gw,
+		cacheStore: cacheStore,
+		policies:   make(map[snowflake.ID]*cachePolicy),
+	}
+}
+
+// Get returns the policy of the app.
+// When the config can't be loaded the previous policy is kept, apps without a policy cache everything.
+// Loading is retried after cachePolicyRetryInterval in that case.
+func (p *cachePolicies) Get(ctx context.Context, appID snowflake.ID) *cachePolicy {
+	p.mu.Lock()
+	policy, ok := p.policies[appID]
+	p.mu.Unlock()
+	if ok && !policy.expired() {
+		return policy
+	}
+
+	res, _, _ := p.group.Do(appID.String(), func() (any, error) {
+		// The load is shared with all callers that wait for it, so it must not be canceled together with the first caller
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cachePolicyLoadTimeout)
+		defer cancel()
+
+		config, err := p.load(loadCtx, appID)
+		if err != nil {
+			slog.Error(
+				"Failed to load cache config of app",
+				slog.String("app_id", appID.String()),
+				slog.Any("error", err),
+			)
+			if ok {
+				config = policy.config
+			}
+		}
+
+		newPolicy := newCachePolicy(config, time.Now())
+		newPolicy.fallback = err != nil
+		if err == nil && ok {
+			// Purging can take a while for large apps, events are handled with the new policy in the meantime
+			p.purges.Add(1)
+			go func() {
+				defer p.purges.Done()
+				p.purgeExcluded(context.WithoutCancel(ctx), appID, policy, newPolicy)
+				p.restrip(context.WithoutCancel(ctx), appID, policy, newPolicy)
+			}()
+		}
+
+		p.mu.Lock()
+		p.policies[appID] = newPolicy
+		p.mu.Unlock()
+
+		return newPolicy, nil
+	})
+	return res.(*cachePolicy)
+}
+
+// load merges the cache config of the app into the default config of its group.
+func (p *cachePolicies) load(ctx context.Context, appID snowflake.ID) (gateway.AppCacheConfig, error) {
+	app, err := p.gateway.GetApp(ctx, appID, false)
+	if err != nil {
+		return gateway.AppCacheConfig{}, fmt.Errorf("failed to get app: %w", err)
+	}
+
+	group, err := p.gateway.GetGroup(ctx, app.GroupID)
+	if err != nil {
+		return gateway.AppCacheConfig{}, fmt.Errorf("failed to get group: %w", err)
+	}
+
+	config := group.DefaultConfig.Merge(app.Config)
+	if config.Cache == nil {
+		return gateway.AppCacheConfig{}, nil
+	}
+	return *config.Cache, nil
+}
+
+// purgeExcluded deletes the cached entities of the app that were cached by the old policy but aren't by the new one.
+func (p *cachePolicies) purgeExcluded(ctx context.Context, appID snowflake.ID, oldPolicy *cachePolicy, newPolicy *cachePolicy) {
+	var deleted store.DeletedEntities
+	var err error
+
+	purgeChannels := oldPolicy.caches(gateway.AppCacheEntityChannel) && !newPolicy.caches(gateway.AppCacheEntityChannel)
+	purgeThreads := oldPolicy.caches(gateway.AppCacheEntityChannel) && oldPolicy.threads && !newPolicy.threads
+	if purgeChannels || purgeThreads {
+		deleted.Channels, err = forEachEntity(
+			func(after snowflake.ID) ([]*model.Channel, error) {
+				return p.cacheStore.GetChannels(ctx, appID, store.ListOptions{Limit: cachePolicyPurgeBatchSize, After: after})
+			},
+			func(c *model.Channel) snowflake.ID { return c.ChannelID },
+			func(c *model.Channel) bool { return !newPolicy.cachesChannel(c.Data) },
+			func(c *model.Channel) error { return p.cacheStore.DeleteChannel(ctx, appID, c.GuildID, c.ChannelID) },
+		)
+		if err != nil {
+			err = fmt.Errorf("failed to purge channels: %w", err)
+		}
+	}
+
+	if err == nil && oldPolicy.caches(gateway.AppCacheEntityRole) && !newPolicy.caches(gateway.AppCacheEntityRole) {
+		deleted.Roles, err = forEachEntity(
+			func(after snowflake.ID) ([]*model.Role, error) {
+				return p.cacheStore.GetRoles(ctx, appID, store.ListOptions{Limit: cachePolicyPurgeBatchSize, After: after})
+			},
+			func(r *model.Role) snowflake.ID { return r.RoleID },
+			nil,
+			func(r *model.Role) error { return p.cacheStore.DeleteRole(ctx, appID, r.GuildID, r.RoleID) },
+		)
+		if err != nil {
+			err = fmt.Errorf("failed to purge roles: %w", err)
+		}
+	}
+
+	if err == nil && oldPolicy.caches(gateway.AppCacheEntityEmoji) && !newPolicy.caches(gateway.AppCacheEntityEmoji) {
+		deleted.Emojis, err = forEachEntity(
+			func(after snowflake.ID) ([]*model.Emoji, error) {
+				return p.cacheStore.GetEmojis(ctx, appID, store.ListOptions{Limit: cachePolicyPurgeBatchSize, After: after})
+			},
+			func(e *model.Emoji) snowflake.ID { return e.EmojiID },
+			nil,
+			func(e *model.Emoji) error { return p.cacheStore.DeleteEmoji(ctx, appID, e.GuildID, e.EmojiID) },
+		)
+		if err != nil {
+			err = fmt.Errorf("failed to purge emojis: %w", err)
+		}
+	}
+
+	if err == nil && oldPolicy.caches(gateway.AppCacheEntitySticker) && !newPolicy.caches(gateway.AppCacheEntitySticker) {
+		deleted.Stickers, err = forEachEntity(
+			func(after snowflake.ID) ([]*model.Sticker, error) {
+				return p.cacheStore.GetStickers(ctx, appID, store.ListOptions{Limit: cachePolicyPurgeBatchSize, After: after})
+			},
+			func(s *model.Sticker) snowflake.ID { return s.StickerID },
+			nil,
+			func(s *model.Sticker) error { return p.cacheStore.DeleteSticker(ctx, appID, s.GuildID, s.StickerID) },
+		)
+		if err != nil {
+			err = fmt.Errorf("failed to purge stickers: %w", err)
+		}
+	}
+
+	if err != nil {
+		slog.Error(
+			"Failed to purge entities excluded by cache policy",
+			slog.String("app_id", appID.String()),
+			slog.Any("error", err),
+		)
+		return
+	}
+
+	if deleted != (store.DeletedEntities{}) {
+		slog.Info(
+			"Purged entities excluded by cache policy",
+			slog.String("app_id", appID.String()),
+			slog.Int("channels", deleted.Channels),
+			slog.Int("roles", deleted.Roles),
+			slog.Int("emojis", deleted.Emojis),
+			slog.Int("stickers", deleted.Stickers),
+		)
+	}
+}
+
+// restrip strips the fields that the new policy strips but the old one didn't from the cached entities of the app.
+// The entities are stored again with their UpdatedAt, so entities that are updated in the meantime are left alone.
+// Tainted entities are skipped, they are either refetched or deleted after the READY of their shard.
+func (p *cachePolicies) restrip(ctx context.Context, appID snowflake.ID, oldPolicy *cachePolicy, newPolicy *cachePolicy) {
+	var restripped store.DeletedEntities
+	var err error
+
+	if stripsMoreFields(oldPolicy, newPolicy, gateway.AppCacheEntityGuild) {
+		restripped.Guilds, err = forEachEntity(
+			func(after snowflake.ID) ([]*model.Guild, error) {
+				return p.cacheStore.GetGuilds(ctx, appID, restripListOptions(after))
+			},
+			func(g *model.Guild) snowflake.ID { return g.GuildID },
+			func(g *model.Guild) bool { return !g.Unavailable },
+			func(g *model.Guild) error {
+				data, err := stripFields(newPolicy, gateway.AppCacheEntityGuild, g.Data)
+				if err != nil {
+					return err
+				}
+				_, err = p.cacheStore.UpsertGuilds(ctx, store.UpsertGuildParams{
+					AppID:     appID,
+					GuildID:   g.GuildID,
+					Data:      data,
+					CreatedAt: g.CreatedAt,
+					UpdatedAt: g.UpdatedAt,
+				})
+				return err
+			},
+		)
+		if err != nil {
+			err = fmt.Errorf("failed to restrip guilds: %w", err)
+		}
+	}
+
+	if err == nil && newPolicy.caches(gateway.AppCacheEntityChannel) && stripsMoreFields(oldPolicy, newPolicy, gateway.AppCacheEntityChannel) {
+		restripped.Channels, err = forEachEntity(
+			func(after snowflake.ID) ([]*model.Channel, error) {
+				return p.cacheStore.GetChannels(ctx, appID, restripListOptions(after))
+			},
+			func(c *model.Channel) snowflake.ID { return c.ChannelID },
+			nil,
+			func(c *model.Channel) error {
+				data, err := stripChannelFields(newPolicy, c.Data)
+				if err != nil {
+					return err
+				}
+				_, err = p.cacheStore.UpsertChannels(ctx, store.UpsertChannelParams{
+					AppID:     appID,
+					GuildID:   c.GuildID,
+					ChannelID: c.ChannelID,
+					Data:      data,
+					CreatedAt: c.CreatedAt,
+					UpdatedAt: c.UpdatedAt,
+				})
+				return err
+			},
+		)
+		if err != nil {
+			err = fmt.Errorf("failed to restrip channels: %w", err)
+		}
+	}
+
+	if err == nil && newPolicy.caches(gateway.AppCacheEntityRole) && stripsMoreFields(oldPolicy, newPolicy, gateway.AppCacheEntityRole) {
+		restripped.Roles, err = forEachEntity(
+			func(after snowflake.ID) ([]*model.Role, error) {
+				return p.cacheStore.GetRoles(ctx, appID, restripListOptions(after))
+			},
+			func(r *model.Role) snowflake.ID { return r.RoleID },
+			nil,
+			func(r *model.Role) error {
+				data, err := stripFields(newPolicy, gateway.AppCacheEntityRole, r.Data)
+				if err != nil {
+					return err
+				}
+				_, err = p.cacheStore.UpsertRoles(ctx, store.UpsertRoleParams{
+					AppID:     appID,
+					GuildID:   r.GuildID,
+					RoleID:    r.RoleID,
+					Data:      data,
+					CreatedAt: r.CreatedAt,
+					UpdatedAt: r.UpdatedAt,
+				})
+				return err
+			},
+		)
+		if err != nil {
+			err = fmt.Errorf("failed to restrip roles: %w", err)
+		}
+	}
+
+	if err == nil && newPolicy.caches(gateway.AppCacheEntityEmoji) && stripsMoreFields(oldPolicy, newPolicy, gateway.AppCacheEntityEmoji) {
+		restripped.Emojis, err = forEachEntity(
+			func(after snowflake.ID) ([]*model.Emoji, error) {
+				return p.cacheStore.GetEmojis(ctx, appID, restripListOptions(after))
+			},
+			func(e *model.Emoji) snowflake.ID { return e.EmojiID },
+			nil,
+			func(e *model.Emoji) error {
+				data, err := stripFields(newPolicy, gateway.AppCacheEntityEmoji, e.Data)
+				if err != nil {
+					return err
+				}
+				_, err = p.cacheStore.UpsertEmojis(ctx, store.UpsertEmojiParams{
+					AppID:     appID,
+					GuildID:   e.GuildID,
+					EmojiID:   e.EmojiID,
+					Data:      data,
+					CreatedAt: e.CreatedAt,
+					UpdatedAt: e.UpdatedAt,
+				})
+				return err
+			},
+		)
+		if err != nil {
+			err = fmt.Errorf("failed to restrip emojis: %w", err)
+		}
+	}
+
+	if err == nil && newPolicy.caches(gateway.AppCacheEntitySticker) && stripsMoreFields(oldPolicy, newPolicy, gateway.AppCacheEntitySticker) {
+		restripped.Stickers, err = forEachEntity(
+			func(after snowflake.ID) ([]*model.Sticker, error) {
+				return p.cacheStore.GetStickers(ctx, appID, restripListOptions(after))
+			},
+			func(s *model.Sticker) snowflake.ID { return s.StickerID },
+			nil,
+			func(s *model.Sticker) error {
+				data, err := stripFields(newPolicy, gateway.AppCacheEntitySticker, s.Data)
+				if err != nil {
+					return err
+				}
+				_, err = p.cacheStore.UpsertStickers(ctx, store.UpsertStickerParams{
+					AppID:     appID,
+					GuildID:   s.GuildID,
+					StickerID: s.StickerID,
+					Data:      data,
+					CreatedAt: s.CreatedAt,
+					UpdatedAt: s.UpdatedAt,
+				})
+				return err
+			},
+		)
+		if err != nil {
+			err = fmt.Errorf("failed to restrip stickers: %w", err)
+		}
+	}
+
+	if err != nil {
+		slog.Error(
+			"Failed to strip fields from cached entities",
+			slog.String("app_id", appID.String()),
+			slog.Any("error", err),
+		)
+		return
+	}
+
+	if restripped != (store.DeletedEntities{}) {
+		slog.Info(
+			"Stripped fields from cached entities",
+			slog.String("app_id", appID.String()),
+			slog.Int("guilds", restripped.Guilds),
+			slog.Int("channels", restripped.Channels),
+			slog.Int("roles", restripped.Roles),
+			slog.Int("emojis", restripped.Emojis),
+			slog.Int("stickers", restripped.Stickers),
+		)
+	}
+}
+
+// restripListOptions lists the next batch of entities to strip, tainted entities are skipped.
+func restripListOptions(after snowflake.ID) store.ListOptions {
+	return store.ListOptions{Limit: cachePolicyPurgeBatchSize, After: after, ExcludeTainted: true}
+}
+
+// stripsMoreFields reports whether the new policy strips fields of the entity type that the old one didn't.
+func stripsMoreFields(oldPolicy *cachePolicy, newPolicy *cachePolicy, entity gateway.AppCacheEntity) bool {
+	for _, field := range newPolicy.stripFields[entity] {
+		if !slices.Contains(oldPolicy.stripFields[entity], field) {
+			return true
+		}
+	}
+	return false
+}
+
+// forEachEntity pages through the entities and calls fn for those that match, a nil match matches all of them.
+// It returns for how many entities fn has been called.
+func forEachEntity[T any](
+	list func(after snowflake.ID) ([]T, error),
+	id func(T) snowflake.ID,
+	match func(T) bool,
+	fn func(T) error,
+) (int, error) {
+	var count int
+	var after snowflake.ID
+	for {
+		entities, err := list(after)
+		if err != nil {
+			return count, err
+		}
+
+		for _, entity := range entities {
+			if match != nil && !match(entity) {
+				continue
+			}
+			err = fn(entity)
+			if err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		if len(entities) < cachePolicyPurgeBatchSize {
+			return count, nil
+		}
+		after = id(entities[len(entities)-1])
+	}
+}
+
+// cachePolicy decides which entities of an app are stored and what is stripped from their data.
+type cachePolicy struct {
+	config   gateway.AppCacheConfig
+	loadedAt time.Time
+	// fallback is set when the config couldn't be loaded, the policy is then reloaded sooner
+	fallback bool
+
+	// entities is nil when all entity types are cached
+	entities    map[gateway.AppCacheEntity]bool
+	threads     bool
+	stripFields map[gateway.AppCacheEntity][]string
+}
+
+func newCachePolicy(config gateway.AppCacheConfig, loadedAt time.Time) *cachePolicy {
+	policy := &cachePolicy{
+		config:      config,
+		loadedAt:    loadedAt,
+		threads:     !config.Threads.Valid || config.Threads.Bool,
+		stripFields: config.StripFields,
+	}
+	if config.Entities != nil {
+		policy.entities = make(map[gateway.AppCacheEntity]bool, len(config.Entities))
+		for _, entity := range config.Entities {
+			policy.entities[entity] = true
+		}
+	}
+	return policy
+}
+
+// expired reports whether the policy has to be reloaded.
+func (p *cachePolicy) expired() bool {
+	ttl := cachePolicyTTL
+	if p.fallback {
+		ttl = cachePolicyRetryInterval
+	}
+	return time.Since(p.loadedAt) >= ttl
+}
+
+// caches reports whether entities of the type are stored, guilds are always stored.
+func (p *cachePolicy) caches(entity gateway.AppCacheEntity) bool {
+	if entity == gateway.AppCacheEntityGuild || p.entities == nil {
+		return true
+	}
+	return p.entities[entity]
+}
+
+// cachesChannel reports whether the channel is stored, threads can be excluded on their own.
+func (p *cachePolicy) cachesChannel(channel discord.Channel) bool {
+	if !p.caches(gateway.AppCacheEntityChannel) {
+		return false
+	}
+	if _, ok := channel.(discord.GuildThread); ok {
+		return p.threads
+	}
+	return true
+}
+
+// apply drops the entities that aren't cached and strips the configured fields from the others.
+func (p *cachePolicy) apply(params store.MassUpsertEntitiesParams) (store.MassUpsertEntitiesParams, error) {
+	guilds := make([]store.UpsertGuildParams, len(params.Guilds))
+	for i, guild := range params.Guilds {
+		data, err := stripFields(p, gateway.AppCacheEntityGuild, guild.Data)
+		if err != nil {
+			return params, err
+		}
+		guild.Data = data
+		guilds[i] = guild
+	}
+	params.Guilds = guilds
+
+	var roles []store.UpsertRoleParams
+	if p.caches(gateway.AppCacheEntityRole) {
+		roles = make([]store.UpsertRoleParams, len(params.Roles))
+		for i, role := range params.Roles {
+			data, err := stripFields(p, gateway.AppCacheEntityRole, role.Data)
+			if err != nil {
+				return params, err
+			}
+			role.Data = data
+			roles[i] = role
+		}
+	}
+	params.Roles = roles
+
+	channels := make([]store.UpsertChannelParams, 0, len(params.Channels))
+	for _, channel := range params.Channels {
+		if !p.cachesChannel(channel.Data) {
+			continue
+		}
+		data, err := stripChannelFields(p, channel.Data)
+		if err != nil {
+			return params, err
+		}
+		channel.Data = data
+		channels = append(channels, channel)
+	}
+	params.Channels = channels
+
+	var emojis []store.UpsertEmojiParams
+	if p.caches(gateway.AppCacheEntityEmoji) {
+		emojis = make([]store.UpsertEmojiParams, len(params.Emojis))
+		for i, emoji := range params.Emojis {
+			data, err := stripFields(p, gateway.AppCacheEntityEmoji, emoji.Data)
+			if err != nil {
+				return params, err
+			}
+			emoji.Data = data
+			emojis[i] = emoji
+		}
+	}
+	params.Emojis = emojis
+
+	var stickers []store.UpsertStickerParams
+	if p.caches(gateway.AppCacheEntitySticker) {
+		stickers = make([]store.UpsertStickerParams, len(params.Stickers))
+		for i, sticker := range params.Stickers {
+			data, err := stripFields(p, gateway.AppCacheEntitySticker, sticker.Data)
+			if err != nil {
+				return params, err
+			}
+			sticker.Data = data
+			stickers[i] = sticker
+		}
+	}
+	params.Stickers = stickers
+
+	return params, nil
+}
+
+// stripFields removes the configured fields of the entity type from the data.
+// The data is decoded again without them, only nullable fields can be configured so they end up as null.
+func stripFields[T any](p *cachePolicy, entity gateway.AppCacheEntity, data T) (T, error) {
+	raw, ok, err := stripJSONFields(data, p.stripFields[entity])
+	if err != nil || !ok {
+		return data, err
+	}
+
+	var stripped T
+	err = json.Unmarshal(raw, &stripped)
+	if err != nil {
+		return data, fmt.Errorf("failed to unmarshal stripped %s: %w", entity, err)
+	}
+	return stripped, nil
+}
+
+// stripChannelFields is stripFields for channels, which have to be decoded by their type.
+func stripChannelFields(p *cachePolicy, channel discord.Channel) (discord.Channel, error) {
+	raw, ok, err := stripJSONFields(channel, p.stripFields[gateway.AppCacheEntityChannel])
+	if err != nil || !ok {
+		return channel, err
+	}
+
+	var stripped discord.UnmarshalChannel
+	err = json.Unmarshal(raw, &stripped)
+	if err != nil {
+		return channel, fmt.Errorf("failed to unmarshal stripped channel: %w", err)
+	}
+	return stripped.Channel, nil
+}
+
+// stripJSONFields returns the JSON of the data without the fields, ok is false when there is nothing to strip.
+func stripJSONFields(data any, fields []string) (raw []byte, ok bool, err error) {
+	if len(fields) == 0 {
+		return nil, false, nil
+	}
+
+	raw, err = json.Marshal(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to marshal data: %w", err)
+	}
+
+	var object map[string]json.RawMessage
+	err = json.Unmarshal(raw, &object)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal data: %w", err)
+	}
+	for _, field := range fields {
+		delete(object, field)
+	}
+
+	raw, err = json.Marshal(object)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to marshal stripped data: %w", err)
+	}
+	return raw, true, nil
+}
@@ -0,0 +1,265 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/inmemory"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"github.com/merlinfuchs/stateway/stateway-lib/gateway"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+)
+
+// fakeGateway serves the apps and groups that the cache policies are loaded from.
+type fakeGateway struct {
+	gateway.Gateway
+	apps   map[snowflake.ID]*gateway.App
+	groups map[string]*gateway.Group
+}
+
+func (g *fakeGateway) GetApp(ctx context.Context, appID snowflake.ID, withSecrets bool) (*gateway.App, error) {
+	err := ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+	app, ok := g.apps[appID]
+	if !ok {
+		return nil, errors.New("app not found")
+	}
+	return app, nil
+}
+
+func (g *fakeGateway) GetGroup(ctx context.Context, groupID string) (*gateway.Group, error) {
+	group, ok := g.groups[groupID]
+	if !ok {
+		return nil, errors.New("group not found")
+	}
+	return group, nil
+}
+
+func TestCachePolicyApply(t *testing.T) {
+	policy := newCachePolicy(gateway.AppCacheConfig{
+		Entities: []gateway.AppCacheEntity{gateway.AppCacheEntityChannel, gateway.AppCacheEntityRole},
+		Threads:  null.BoolFrom(false),
+		StripFields: map[gateway.AppCacheEntity][]string{
+			gateway.AppCacheEntityGuild:   {"description"},
+			gateway.AppCacheEntityChannel: {"topic"},
+		},
+	}, time.Now())
+
+	var text discord.GuildTextChannel
+	err := json.Unmarshal([]byte(`{"id": "200", "type": 0, "guild_id": "100", "name": "general", "topic": "A long topic"}`), &text)
+	require.NoError(t, err)
+	var thread discord.GuildThread
+	err = json.Unmarshal([]byte(`{"id": "201", "type": 11, "guild_id": "100", "name": "thread"}`), &thread)
+	require.NoError(t, err)
+
+	description := "A long description"
+	params, err := policy.apply(store.MassUpsertEntitiesParams{
+		AppID: 1,
+		Guilds: []store.UpsertGuildParams{
+			{AppID: 1, GuildID: 100, Data: discord.Guild{ID: 100, Name: "Guild", Description: &description}},
+		},
+		Roles: []store.UpsertRoleParams{
+			{AppID: 1, GuildID: 100, RoleID: 100, Data: discord.Role{ID: 100, Name: "@everyone"}},
+		},
+		Channels: []store.UpsertChannelParams{
+			{AppID: 1, GuildID: 100, ChannelID: 200, Data: text},
+			{AppID: 1, GuildID: 100, ChannelID: 201, Data: thread},
+		},
+		Emojis:   []store.UpsertEmojiParams{{AppID: 1, GuildID: 100, EmojiID: 300}},
+		Stickers: []store.UpsertStickerParams{{AppID: 1, GuildID: 100, StickerID: 400}},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, params.Guilds, 1)
+	assert.Equal(t, "Guild", params.Guilds[0].Data.Name)
+	assert.Nil(t, params.Guilds[0].Data.Description)
+
+	require.Len(t, params.Roles, 1)
+	assert.Equal(t, "@everyone", params.Roles[0].Data.Name)
+
+	// Threads are dropped, the remaining channels keep their type
+	require.Len(t, params.Channels, 1)
+	channel, ok := params.Channels[0].Data.(discord.GuildTextChannel)
+	require.True(t, ok)
+	assert.Equal(t, "general", channel.Name())
+	assert.Equal(t, snowflake.ID(100), channel.GuildID())
+	assert.Nil(t, channel.Topic())
+
+	assert.Empty(t, params.Emojis)
+	assert.Empty(t, params.Stickers)
+}
+
+func TestCachePoliciesMergeGroupConfig(t *testing.T) {
+	ctx := context.Background()
+	policies := newCachePolicies(&fakeGateway{
+		apps: map[snowflake.ID]*gateway.App{
+			1: {ID: 1, GroupID: "default", Config: gateway.AppConfig{
+				Cache: &gateway.AppCacheConfig{Threads: null.BoolFrom(true)},
+			}},
+		},
+		groups: map[string]*gateway.Group{
+			"default": {ID: "default", DefaultConfig: gateway.AppConfig{
+				Cache: &gateway.AppCacheConfig{
+					Entities: []gateway.AppCacheEntity{gateway.AppCacheEntityChannel},
+					Threads:  null.BoolFrom(false),
+				},
+			}},
+		},
+	}, nil)
+
+	// The app inherits the entities of its group and overrides the threads
+	policy := policies.Get(ctx, 1)
+	assert.True(t, policy.caches(gateway.AppCacheEntityGuild))
+	assert.True(t, policy.caches(gateway.AppCacheEntityChannel))
+	assert.False(t, policy.caches(gateway.AppCacheEntityEmoji))
+	assert.True(t, policy.cachesChannel(discord.GuildThread{}))
+
+	// The policy is reused until it expires
+	assert.Same(t, policy, policies.Get(ctx, 1))
+
+	// Apps whose config can't be loaded cache everything
+	policy = policies.Get(ctx, 2)
+	assert.True(t, policy.caches(gateway.AppCacheEntityEmoji))
+	assert.True(t, policy.cachesChannel(discord.GuildThread{}))
+}
+
+func TestCachePoliciesPurgeExcluded(t *testing.T) {
+	ctx := context.Background()
+	cacheStore := inmemory.NewMapCacheStore()
+
+	var text discord.GuildTextChannel
+	err := json.Unmarshal([]byte(`{"id": "200", "type": 0, "guild_id": "100", "name": "general"}`), &text)
+	require.NoError(t, err)
+	var thread discord.GuildThread
+	err = json.Unmarshal([]byte(`{"id": "201", "type": 11, "guild_id": "100", "name": "thread"}`), &thread)
+	require.NoError(t, err)
+
+	err = cacheStore.MassUpsertEntities(ctx, store.MassUpsertEntitiesParams{
+		AppID:  1,
+		Guilds: []store.UpsertGuildParams{{AppID: 1, GuildID: 100}},
+		Roles:  []store.UpsertRoleParams{{AppID: 1, GuildID: 100, RoleID: 100}},
+		Channels: []store.UpsertChannelParams{
+			{AppID: 1, GuildID: 100, ChannelID: 200, Data: text},
+			{AppID: 1, GuildID: 100, ChannelID: 201, Data: thread},
+		},
+		Emojis: []store.UpsertEmojiParams{{AppID: 1, GuildID: 100, EmojiID: 300}},
+	})
+	require.NoError(t, err)
+
+	app := &gateway.App{ID: 1, GroupID: "default"}
+	policies := newCachePolicies(&fakeGateway{
+		apps:   map[snowflake.ID]*gateway.App{1: app},
+		groups: map[string]*gateway.Group{"default": {ID: "default"}},
+	}, cacheStore)
+	policies.Get(ctx, 1)
+
+	// The app stops caching roles and threads, the policy is reloaded once it expires
+	app.Config.Cache = &gateway.AppCacheConfig{
+		Entities: []gateway.AppCacheEntity{gateway.AppCacheEntityChannel, gateway.AppCacheEntityEmoji},
+		Threads:  null.BoolFrom(false),
+	}
+	policies.policies[1].loadedAt = time.Now().Add(-cachePolicyTTL)
+	policies.Get(ctx, 1)
+	policies.purges.Wait()
+
+	count, err := cacheStore.CountGuildRoles(ctx, 1, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	_, err = cacheStore.GetChannel(ctx, 1, 201)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	_, err = cacheStore.GetChannel(ctx, 1, 200)
+	assert.NoError(t, err)
+
+	count, err = cacheStore.CountGuildEmojis(ctx, 1, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestCachePoliciesRetryFailedLoad(t *testing.T) {
+	ctx := context.Background()
+	gw := &fakeGateway{
+		apps:   map[snowflake.ID]*gateway.App{},
+		groups: map[string]*gateway.Group{"default": {ID: "default"}},
+	}
+	policies := newCachePolicies(gw, inmemory.NewMapCacheStore())
+
+	// The default policy is only used until the load is retried
+	policy := policies.Get(ctx, 1)
+	assert.True(t, policy.caches(gateway.AppCacheEntityEmoji))
+	assert.False(t, policy.expired())
+
+	gw.apps[1] = &gateway.App{ID: 1, GroupID: "default", Config: gateway.AppConfig{
+		Cache: &gateway.AppCacheConfig{Entities: []gateway.AppCacheEntity{}},
+	}}
+	policies.policies[1].loadedAt = time.Now().Add(-cachePolicyRetryInterval)
+
+	policy = policies.Get(ctx, 1)
+	policies.purges.Wait()
+	assert.False(t, policy.caches(gateway.AppCacheEntityEmoji))
+	assert.False(t, policy.fallback)
+
+	// Callers that are canceled don't cancel the load
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	policies.policies[1].loadedAt = time.Now().Add(-cachePolicyTTL)
+	policy = policies.Get(canceledCtx, 1)
+	policies.purges.Wait()
+	assert.False(t, policy.fallback)
+}
+
+func TestCachePoliciesRestrip(t *testing.T) {
+	ctx := context.Background()
+	cacheStore := inmemory.NewMapCacheStore()
+	updatedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	description := "A long description"
+	err := cacheStore.MassUpsertEntities(ctx, store.MassUpsertEntitiesParams{
+		AppID: 1,
+		Guilds: []store.UpsertGuildParams{
+			{AppID: 1, GuildID: 100, Data: discord.Guild{ID: 100, Name: "Guild", Description: &description}, UpdatedAt: updatedAt},
+		},
+		Roles: []store.UpsertRoleParams{
+			{AppID: 1, GuildID: 100, RoleID: 100, Data: discord.Role{ID: 100, Name: "@everyone", Description: &description}, UpdatedAt: updatedAt},
+		},
+	})
+	require.NoError(t, err)
+
+	app := &gateway.App{ID: 1, GroupID: "default"}
+	policies := newCachePolicies(&fakeGateway{
+		apps:   map[snowflake.ID]*gateway.App{1: app},
+		groups: map[string]*gateway.Group{"default": {ID: "default"}},
+	}, cacheStore)
+	policies.Get(ctx, 1)
+
+	// The app starts stripping the description of guilds, roles keep theirs
+	app.Config.Cache = &gateway.AppCacheConfig{
+		StripFields: map[gateway.AppCacheEntity][]string{
+			gateway.AppCacheEntityGuild: {"description"},
+		},
+	}
+	policies.policies[1].loadedAt = time.Now().Add(-cachePolicyTTL)
+	policies.Get(ctx, 1)
+	policies.purges.Wait()
+
+	guild, err := cacheStore.GetGuild(ctx, 1, 100)
+	require.NoError(t, err)
+	assert.Equal(t, "Guild", guild.Data.Name)
+	assert.Nil(t, guild.Data.Description)
+	assert.True(t, updatedAt.Equal(guild.UpdatedAt))
+
+	role, err := cacheStore.GetRole(ctx, 1, 100)
+	require.NoError(t, err)
+	require.NotNil(t, role.Data.Description)
+	assert.Equal(t, description, *role.Data.Description)
+}
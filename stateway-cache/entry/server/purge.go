@@ -0,0 +1,44 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"github.com/merlinfuchs/stateway/stateway-lib/broker"
+	"github.com/merlinfuchs/stateway/stateway-lib/cache"
+)
+
+// purgeApp deletes all cached entities of the app and tells the near caches of clients to drop them.
+func purgeApp(ctx context.Context, cacheStore store.CacheStore, br broker.Broker, appID snowflake.ID) (cache.ResyncCounts, error) {
+	deleted, err := cacheStore.DeleteAppEntities(ctx, appID)
+	if err != nil {
+		return cache.ResyncCounts{}, fmt.Errorf("failed to delete app entities: %w", err)
+	}
+
+	publishInvalidation(ctx, br, cache.Invalidation{
+		AppID:      appID,
+		EntityType: cache.InvalidationEntityTypeApp,
+		Action:     cache.ChangeActionDeleted,
+	})
+
+	slog.Info(
+		"Purged cached entities of app",
+		slog.String("app_id", appID.String()),
+		slog.Int("guilds", deleted.Guilds),
+		slog.Int("channels", deleted.Channels),
+		slog.Int("roles", deleted.Roles),
+		slog.Int("emojis", deleted.Emojis),
+		slog.Int("stickers", deleted.Stickers),
+	)
+
+	return cache.ResyncCounts{
+		Guilds:   deleted.Guilds,
+		Channels: deleted.Channels,
+		Roles:    deleted.Roles,
+		Emojis:   deleted.Emojis,
+		Stickers: deleted.Stickers,
+	}, nil
+}
@@ -0,0 +1,286 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/disgoorg/disgo/rest"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"github.com/merlinfuchs/stateway/stateway-lib/cache"
+)
+
+// resyncGuildsPageSize is the maximum number of guilds Discord returns per page.
+const resyncGuildsPageSize = 200
+
+// ResyncGuilds reconciles the cached guilds of the app with the guilds the app is currently in.
+// Cached guilds that the app has left are deleted together with their entities.
+// The other guilds of the partition are only listed in the result, they have to be resynced with ResyncGuild.
+func (f *RESTFetcher) ResyncGuilds(ctx context.Context, appID snowflake.ID) (*cache.ResyncResult, error) {
+	client, err := f.client(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &cache.ResyncResult{
+		GuildIDs: make([]snowflake.ID, 0),
+	}
+
+	var after snowflake.ID
+	for {
+		guilds, err := client.GetCurrentUserGuilds("", 0, after, resyncGuildsPageSize, false, rest.WithCtx(ctx))
+		if err != nil {
+			return nil, f.restError(appID, err, "app not found")
+		}
+
+		for _, guild := range guilds {
+			if f.partition.OwnsGuild(guild.ID) {
+				result.GuildIDs = append(result.GuildIDs, guild.ID)
+			}
+		}
+
+		if len(guilds) < resyncGuildsPageSize {
+			break
+		}
+		after = guilds[len(guilds)-1].ID
+	}
+
+	cachedGuilds, err := f.cacheStore.GetGuilds(ctx, appID, store.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guilds: %w", err)
+	}
+
+	guildIDs := idSet(result.GuildIDs, func(id snowflake.ID) snowflake.ID { return id })
+	for _, guild := range cachedGuilds {
+		// The guilds of other partitions are left to them
+		if _, ok := guildIDs[guild.GuildID]; ok || !f.partition.OwnsGuild(guild.GuildID) {
+			continue
+		}
+
+		deleted, err := f.deleteGuild(ctx, appID, guild.GuildID)
+		if err != nil {
+			return nil, err
+		}
+		result.Deleted.Add(deleted)
+	}
+
+	return result, nil
+}
+
+// ResyncGuild refetches the guild with its channels, roles, emojis and stickers and reconciles the cache with it.
+// Cached entities that no longer exist are deleted, the whole guild is deleted if the app isn't in it anymore.
+func (f *RESTFetcher) ResyncGuild(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (*cache.ResyncResult, error) {
+	client, err := f.client(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	guild, err := client.GetGuild(guildID, false, rest.WithCtx(ctx))
+	if err != nil {
+		if isRESTNotFound(err) {
+			deleted, err := f.deleteGuild(ctx, appID, guildID)
+			if err != nil {
+				return nil, err
+			}
+			return &cache.ResyncResult{Deleted: deleted}, nil
+		}
+		return nil, f.restError(appID, err, "guild not found")
+	}
+
+	channels, err := client.GetGuildChannels(guildID, rest.WithCtx(ctx))
+	if err != nil {
+		return nil, f.restError(appID, err, "guild not found")
+	}
+
+	// Active threads aren't part of the guild channels, but they are cached like the other channels
+	activeThreads, err := client.GetActiveGuildThreads(guildID, rest.WithCtx(ctx))
+	if err != nil {
+		return nil, f.restError(appID, err, "guild not found")
+	}
+
+	now := time.Now().UTC()
+	params := guildUpsertParams(appID, guildID, guild, now)
+	params.Channels = make([]store.UpsertChannelParams, 0, len(channels)+len(activeThreads.Threads))
+	for _, channel := range channels {
+		params.Channels = append(params.Channels, store.UpsertChannelParams{
+			AppID:     appID,
+			GuildID:   guildID,
+			ChannelID: channel.ID(),
+			Data:      channel,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+	for _, thread := range activeThreads.Threads {
+		params.Channels = append(params.Channels, store.UpsertChannelParams{
+			AppID:     appID,
+			GuildID:   guildID,
+			ChannelID: thread.ID(),
+			Data:      thread,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+
+	// Entities that the app no longer caches are left out, so they are deleted as vanished entities below
+	params, err = f.policies.Get(ctx, appID).apply(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply cache policy to resynced guild: %w", err)
+	}
+
+	err = f.cacheStore.MassUpsertEntities(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store resynced guild: %w", err)
+	}
+
+	deleted, err := f.deleteVanishedEntities(ctx, appID, guildID, params)
+	if err != nil {
+		return nil, err
+	}
+
+	f.invalidateGuild(ctx, appID, guildID)
+
+	return &cache.ResyncResult{
+		Upserted: cache.ResyncCounts{
+			Guilds:   1,
+			Channels: len(params.Channels),
+			Roles:    len(params.Roles),
+			Emojis:   len(params.Emojis),
+			Stickers: len(params.Stickers),
+		},
+		Deleted: deleted,
+	}, nil
+}
+
+// deleteGuild deletes the guild and all of its entities from the cache.
+func (f *RESTFetcher) deleteGuild(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (cache.ResyncCounts, error) {
+	deleted, err := f.deleteVanishedEntities(ctx, appID, guildID, store.MassUpsertEntitiesParams{})
+	if err != nil {
+		return deleted, err
+	}
+
+	exists, err := f.cacheStore.CheckGuildExist(ctx, appID, guildID)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to check guild exist: %w", err)
+	}
+	if exists {
+		err = f.cacheStore.DeleteGuild(ctx, appID, guildID)
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete guild: %w", err)
+		}
+		deleted.Guilds = 1
+	}
+
+	f.invalidateGuild(ctx, appID, guildID)
+	return deleted, nil
+}
+
+// deleteVanishedEntities deletes the cached channels, roles, emojis and stickers of the guild that aren't in params.
+func (f *RESTFetcher) deleteVanishedEntities(
+	ctx context.Context,
+	appID snowflake.ID,
+	guildID snowflake.ID,
+	params store.MassUpsertEntitiesParams,
+) (cache.ResyncCounts, error) {
+	var deleted cache.ResyncCounts
+
+	channels, err := f.cacheStore.GetGuildChannels(ctx, appID, guildID, store.ListOptions{})
+	if err != nil {
+		return deleted, fmt.Errorf("failed to get guild channels: %w", err)
+	}
+	deleted.Channels, err = deleteVanished(
+		channels,
+		idSet(params.Channels, func(c store.UpsertChannelParams) snowflake.ID { return c.ChannelID }),
+		func(c *cache.Channel) snowflake.ID { return c.ChannelID },
+		func(id snowflake.ID) error { return f.cacheStore.DeleteChannel(ctx, appID, guildID, id) },
+	)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete channel: %w", err)
+	}
+
+	roles, err := f.cacheStore.GetGuildRoles(ctx, appID, guildID, store.ListOptions{})
+	if err != nil {
+		return deleted, fmt.Errorf("failed to get guild roles: %w", err)
+	}
+	deleted.Roles, err = deleteVanished(
+		roles,
+		idSet(params.Roles, func(r store.UpsertRoleParams) snowflake.ID { return r.RoleID }),
+		func(r *cache.Role) snowflake.ID { return r.RoleID },
+		func(id snowflake.ID) error { return f.cacheStore.DeleteRole(ctx, appID, guildID, id) },
+	)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete role: %w", err)
+	}
+
+	emojis, err := f.cacheStore.GetGuildEmojis(ctx, appID, guildID, store.ListOptions{})
+	if err != nil {
+		return deleted, fmt.Errorf("failed to get guild emojis: %w", err)
+	}
+	deleted.Emojis, err = deleteVanished(
+		emojis,
+		idSet(params.Emojis, func(e store.UpsertEmojiParams) snowflake.ID { return e.EmojiID }),
+		func(e *cache.Emoji) snowflake.ID { return e.EmojiID },
+		func(id snowflake.ID) error { return f.cacheStore.DeleteEmoji(ctx, appID, guildID, id) },
+	)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete emoji: %w", err)
+	}
+
+	stickers, err := f.cacheStore.GetGuildStickers(ctx, appID, guildID, store.ListOptions{})
+	if err != nil {
+		return deleted, fmt.Errorf("failed to get guild stickers: %w", err)
+	}
+	deleted.Stickers, err = deleteVanished(
+		stickers,
+		idSet(params.Stickers, func(s store.UpsertStickerParams) snowflake.ID { return s.StickerID }),
+		func(s *cache.Sticker) snowflake.ID { return s.StickerID },
+		func(id snowflake.ID) error { return f.cacheStore.DeleteSticker(ctx, appID, guildID, id) },
+	)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete sticker: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// invalidateGuild tells the near caches of clients to drop the guild and everything that belongs to it.
+func (f *RESTFetcher) invalidateGuild(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) {
+	publishInvalidation(ctx, f.broker, cache.Invalidation{
+		AppID:      appID,
+		GuildID:    guildID,
+		EntityType: cache.ChangeEntityTypeGuild,
+		EntityID:   guildID,
+		Action:     cache.ChangeActionUpdated,
+	})
+}
+
+// deleteVanished deletes the entities whose ID isn't in keep and returns how many were deleted.
+func deleteVanished[T any](
+	entities []T,
+	keep map[snowflake.ID]struct{},
+	id func(T) snowflake.ID,
+	del func(id snowflake.ID) error,
+) (int, error) {
+	deleted := 0
+	for _, entity := range entities {
+		if _, ok := keep[id(entity)]; ok {
+			continue
+		}
+
+		err := del(id(entity))
+		if err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+func idSet[T any](items []T, id func(T) snowflake.ID) map[snowflake.ID]struct{} {
+	res := make(map[snowflake.ID]struct{}, len(items))
+	for _, item := range items {
+		res[id(item)] = struct{}{}
+	}
+	return res
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/rest"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/inmemory"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"github.com/merlinfuchs/stateway/stateway-lib/cache"
+	"github.com/merlinfuchs/stateway/stateway-lib/gateway"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeDiscord serves the REST endpoints used by resyncs, the app is only in guild 100.
+func newFakeDiscord(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	respond := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(body))
+		}
+	}
+
+	mux.HandleFunc("GET /users/@me/guilds", respond(`[{"id": "100", "name": "Guild"}]`))
+	mux.HandleFunc("GET /guilds/100", respond(`{
+		"id": "100",
+		"name": "Guild",
+		"owner_id": "1",
+		"roles": [
+			{"id": "100", "name": "@everyone", "position": 0, "permissions": "0"},
+			{"id": "101", "name": "Moderator", "position": 1, "permissions": "8"}
+		],
+		"emojis": [{"id": "300", "name": "emoji"}],
+		"stickers": []
+	}`))
+	mux.HandleFunc("GET /guilds/100/channels", respond(`[
+		{"id": "200", "type": 0, "guild_id": "100", "name": "general", "position": 0}
+	]`))
+	mux.HandleFunc("GET /guilds/100/threads/active", respond(`{"threads": [], "members": []}`))
+	mux.HandleFunc("GET /guilds/{guildID}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message": "Unknown Guild", "code": 10004}`))
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestFetcher(t *testing.T) (*RESTFetcher, store.CacheStore) {
+	srv := newFakeDiscord(t)
+
+	cacheStore := inmemory.NewMapCacheStore()
+	fetcher := NewRESTFetcher(cacheStore, nil, nil, cache.Partition{}, newCachePolicies(&fakeGateway{}, cacheStore))
+	fetcher.clients[1] = rest.New(rest.NewClient("token", rest.WithURL(srv.URL)))
+
+	err := cacheStore.MassUpsertEntities(context.Background(), store.MassUpsertEntitiesParams{
+		AppID: 1,
+		Guilds: []store.UpsertGuildParams{
+			{AppID: 1, GuildID: 100},
+			{AppID: 1, GuildID: 999},
+		},
+		Roles: []store.UpsertRoleParams{
+			{AppID: 1, GuildID: 100, RoleID: 100},
+			{AppID: 1, GuildID: 100, RoleID: 102},
+			{AppID: 1, GuildID: 999, RoleID: 900},
+		},
+		Channels: []store.UpsertChannelParams{
+			{AppID: 1, GuildID: 100, ChannelID: 201, Data: discord.GuildTextChannel{}},
+		},
+		Stickers: []store.UpsertStickerParams{
+			{AppID: 1, GuildID: 100, StickerID: 400},
+		},
+	})
+	require.NoError(t, err)
+
+	return fetcher, cacheStore
+}
+
+func TestResyncGuild(t *testing.T) {
+	ctx := context.Background()
+	fetcher, cacheStore := newTestFetcher(t)
+
+	result, err := fetcher.ResyncGuild(ctx, 1, 100)
+	require.NoError(t, err)
+	assert.Equal(t, cache.ResyncCounts{Guilds: 1, Channels: 1, Roles: 2, Emojis: 1}, result.Upserted)
+	assert.Equal(t, cache.ResyncCounts{Channels: 1, Roles: 1, Stickers: 1}, result.Deleted)
+
+	guild, err := cacheStore.GetGuild(ctx, 1, 100)
+	require.NoError(t, err)
+	assert.Equal(t, "Guild", guild.Data.Name)
+
+	roles, err := cacheStore.GetGuildRoles(ctx, 1, 100, store.ListOptions{})
+	require.NoError(t, err)
+	roleIDs := make([]snowflake.ID, len(roles))
+	for i, role := range roles {
+		roleIDs[i] = role.RoleID
+	}
+	assert.ElementsMatch(t, []snowflake.ID{100, 101}, roleIDs)
+
+	_, err = cacheStore.GetChannel(ctx, 1, 201)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	channel, err := cacheStore.GetChannel(ctx, 1, 200)
+	require.NoError(t, err)
+	assert.Equal(t, snowflake.ID(100), channel.GuildID)
+
+	count, err := cacheStore.CountGuildStickers(ctx, 1, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestResyncGuildLeft(t *testing.T) {
+	ctx := context.Background()
+	fetcher, cacheStore := newTestFetcher(t)
+
+	result, err := fetcher.ResyncGuild(ctx, 1, 999)
+	require.NoError(t, err)
+	assert.Equal(t, cache.ResyncCounts{}, result.Upserted)
+	assert.Equal(t, cache.ResyncCounts{Guilds: 1, Roles: 1}, result.Deleted)
+
+	exists, err := cacheStore.CheckGuildExist(ctx, 1, 999)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	_, err = cacheStore.GetRole(ctx, 1, 900)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func TestResyncGuilds(t *testing.T) {
+	ctx := context.Background()
+	fetcher, cacheStore := newTestFetcher(t)
+
+	result, err := fetcher.ResyncGuilds(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []snowflake.ID{100}, result.GuildIDs)
+	assert.Equal(t, cache.ResyncCounts{Guilds: 1, Roles: 1}, result.Deleted)
+
+	exists, err := cacheStore.CheckGuildExist(ctx, 1, 999)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// The guilds the app is still in are left for ResyncGuild
+	count, err := cacheStore.CountGuildRoles(ctx, 1, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestResyncGuildAppliesCachePolicy(t *testing.T) {
+	ctx := context.Background()
+	fetcher, cacheStore := newTestFetcher(t)
+	fetcher.policies = newCachePolicies(&fakeGateway{
+		apps: map[snowflake.ID]*gateway.App{
+			1: {ID: 1, GroupID: "default", Config: gateway.AppConfig{
+				Cache: &gateway.AppCacheConfig{Entities: []gateway.AppCacheEntity{gateway.AppCacheEntityChannel}},
+			}},
+		},
+		groups: map[string]*gateway.Group{
+			"default": {ID: "default"},
+		},
+	}, cacheStore)
+
+	// Entity types that the app doesn't cache are not stored and the cached ones are deleted
+	result, err := fetcher.ResyncGuild(ctx, 1, 100)
+	require.NoError(t, err)
+	assert.Equal(t, cache.ResyncCounts{Guilds: 1, Channels: 1}, result.Upserted)
+	assert.Equal(t, cache.ResyncCounts{Channels: 1, Roles: 2, Stickers: 1}, result.Deleted)
+
+	count, err := cacheStore.CountGuildRoles(ctx, 1, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	count, err = cacheStore.CountGuildEmojis(ctx, 1, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
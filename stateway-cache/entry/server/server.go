@@ -4,26 +4,69 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/disgoorg/snowflake/v2"
 	"github.com/merlinfuchs/stateway/stateway-cache/db/postgres"
 	"github.com/merlinfuchs/stateway/stateway-cache/inmemory"
-	"github.com/merlinfuchs/stateway/stateway-cache/store"
 	"github.com/merlinfuchs/stateway/stateway-lib/broker"
 	"github.com/merlinfuchs/stateway/stateway-lib/cache"
 	"github.com/merlinfuchs/stateway/stateway-lib/config"
+	"github.com/merlinfuchs/stateway/stateway-lib/gateway"
 )
 
 func Run(ctx context.Context, pg *postgres.Client, cfg *config.RootCacheConfig) error {
+	partition := cache.Partition{
+		ID:    cfg.Cache.PartitionID,
+		Count: cfg.Cache.PartitionCount,
+	}
+	if partition.Partitioned() && partition.ID >= partition.Count {
+		return fmt.Errorf("partition ID %d must be lower than the partition count %d", partition.ID, partition.Count)
+	}
+
 	slog.Info(
 		"Starting cache server and listening to gateway events",
 		slog.Any("gateway_ids", cfg.Cache.GatewayIDs),
+		slog.Int("partition_id", partition.ID),
+		slog.Int("partition_count", partition.Count),
+		slog.Int("worker_concurrency", cfg.Cache.WorkerConcurrency),
 	)
 
-	var cacheStore store.CacheStore = pg
-	if cfg.Cache.InMemory {
-		slog.Info("Using in-memory cache store")
-		cacheStore = inmemory.NewMapCacheStore()
+	cacheStore, closeStore, err := OpenCacheStore(ctx, pg, &cfg.Cache, &cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	if snapshotStore, ok := cacheStore.(inmemory.SnapshotStore); ok && cfg.Cache.SnapshotPath != "" {
+		err = restoreSnapshot(ctx, snapshotStore, cfg.Cache.SnapshotPath)
+		if err != nil {
+			return fmt.Errorf("failed to restore cache snapshot: %w", err)
+		}
+
+		unlockSnapshot, err := inmemory.LockSnapshotFile(cfg.Cache.SnapshotPath)
+		if err != nil {
+			return err
+		}
+		defer unlockSnapshot()
+
+		interval := time.Duration(cfg.Cache.SnapshotInterval) * time.Second
+		if interval <= 0 {
+			interval = time.Minute
+		}
+
+		// The final snapshot has to be written before the store is closed
+		snapshotCtx, cancelSnapshots := context.WithCancel(ctx)
+		snapshotsDone := make(chan struct{})
+		defer func() {
+			cancelSnapshots()
+			<-snapshotsDone
+		}()
+
+		go func() {
+			defer close(snapshotsDone)
+			runSnapshots(snapshotCtx, snapshotStore, cfg.Cache.SnapshotPath, interval)
+		}()
 	}
 
 	// Discord some times sends unquoted snowflake IDs, so we need to allow them
@@ -34,10 +77,26 @@ func Run(ctx context.Context, pg *postgres.Client, cfg *config.RootCacheConfig)
 		return fmt.Errorf("failed to create NATS broker: %w", err)
 	}
 
+	err = br.CreateCacheStream(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create cache stream: %w", err)
+	}
+
+	gw := gateway.NewGatewayClient(br)
+	upserts := newUpsertCoalescer(ctx, cacheStore)
+	sweeper := NewTaintedSweeper(ctx, cacheStore, br, partition, time.Duration(cfg.Cache.TaintedGracePeriod)*time.Second)
+	policies := newCachePolicies(gw, cacheStore)
+
 	if len(cfg.Cache.GatewayIDs) == 0 {
 		slog.Info("Listening to events from all gateways")
 		err = broker.Listen(ctx, br, &CacheWorker{
-			cacheStore: cacheStore,
+			cacheStore:  cacheStore,
+			upserts:     upserts,
+			sweeper:     sweeper,
+			policies:    policies,
+			broker:      br,
+			partition:   partition,
+			concurrency: cfg.Cache.WorkerConcurrency,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to listen to gateway events: %w", err)
@@ -46,8 +105,14 @@ func Run(ctx context.Context, pg *postgres.Client, cfg *config.RootCacheConfig)
 		for _, gatewayID := range cfg.Cache.GatewayIDs {
 			slog.Info("Listening to events from gateway", slog.Int("gateway_id", gatewayID))
 			err = broker.Listen(ctx, br, &CacheWorker{
-				cacheStore: cacheStore,
-				gatewayIDs: []int{gatewayID},
+				cacheStore:  cacheStore,
+				upserts:     upserts,
+				sweeper:     sweeper,
+				policies:    policies,
+				broker:      br,
+				gatewayIDs:  []int{gatewayID},
+				partition:   partition,
+				concurrency: cfg.Cache.WorkerConcurrency,
 			})
 			if err != nil {
 				return fmt.Errorf("failed to listen to gateway events: %w", err)
@@ -55,8 +120,15 @@ func Run(ctx context.Context, pg *postgres.Client, cfg *config.RootCacheConfig)
 		}
 	}
 
-	cacheService := cache.NewCacheService(NewCaches(cacheStore))
-	err = broker.Provide(ctx, br, cacheService)
+	waiter := NewGuildWaiter()
+	err = waiter.Listen(ctx, br)
+	if err != nil {
+		return fmt.Errorf("failed to listen to guild invalidations: %w", err)
+	}
+
+	fetcher := NewRESTFetcher(cacheStore, gw, br, partition, policies)
+	cacheService := cache.NewCacheService(NewCaches(cacheStore, fetcher, waiter, br))
+	err = broker.Provide(ctx, br, cacheService, broker.WithProvidePartition(partition.Name()))
 	if err != nil {
 		return fmt.Errorf("failed to provide cache service: %w", err)
 	}
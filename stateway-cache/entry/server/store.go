@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/merlinfuchs/stateway/stateway-cache/db/bolt"
+	"github.com/merlinfuchs/stateway/stateway-cache/db/postgres"
+	"github.com/merlinfuchs/stateway/stateway-cache/db/redis"
+	"github.com/merlinfuchs/stateway/stateway-cache/inmemory"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"github.com/merlinfuchs/stateway/stateway-lib/config"
+)
+
+// OpenCacheStore creates the cache store selected in the config.
+// The returned close function must be called once the store is no longer used.
+func OpenCacheStore(ctx context.Context, pg *postgres.Client, cfg *config.CacheConfig, dbCfg *config.DatabaseConfig) (store.CacheStore, func(), error) {
+	storeType := cfg.Store
+	if cfg.InMemory {
+		storeType = "map"
+	}
+
+	switch storeType {
+	case "map":
+		slog.Info("Using in-memory map cache store")
+		return inmemory.NewMapCacheStore(), func() {}, nil
+	case "memdb":
+		slog.Info("Using in-memory memdb cache store")
+		memdbStore, err := inmemory.NewMemDBCacheStore()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create memdb cache store: %w", err)
+		}
+		return memdbStore, func() {}, nil
+	case "redis":
+		slog.Info("Using Redis cache store")
+		rdb, err := redis.New(ctx, redis.ClientConfig(dbCfg.Redis))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create redis client: %w", err)
+		}
+		return rdb, func() { rdb.Close() }, nil
+	case "bolt":
+		slog.Info("Using embedded bolt cache store", slog.String("path", cfg.BoltPath))
+		db, err := bolt.New(bolt.ClientConfig{
+			Path: cfg.BoltPath,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open bolt cache store: %w", err)
+		}
+		return db, func() { db.Close() }, nil
+	case "postgres", "":
+		slog.Info("Using Postgres cache store")
+		return pg, func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown cache store %q", storeType)
+	}
+}
+
+// restoreSnapshot loads the last snapshot into the store if there is one.
+func restoreSnapshot(ctx context.Context, cacheStore inmemory.SnapshotStore, path string) error {
+	snapshot, err := inmemory.ReadSnapshotFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			slog.Info("No cache snapshot to restore", slog.String("path", path))
+			return nil
+		}
+		return err
+	}
+
+	err = inmemory.RestoreSnapshot(ctx, cacheStore, snapshot)
+	if err != nil {
+		return err
+	}
+
+	slog.Info(
+		"Restored cache snapshot",
+		slog.String("path", path),
+		slog.Time("created_at", snapshot.CreatedAt),
+		slog.Int("guilds", len(snapshot.Guilds)),
+	)
+	return nil
+}
+
+// runSnapshots writes a snapshot of the store every interval and once more when the context is done.
+func runSnapshots(ctx context.Context, cacheStore inmemory.SnapshotStore, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			writeSnapshot(context.Background(), cacheStore, path)
+			return
+		case <-ticker.C:
+			writeSnapshot(ctx, cacheStore, path)
+		}
+	}
+}
+
+func writeSnapshot(ctx context.Context, cacheStore inmemory.SnapshotStore, path string) {
+	start := time.Now()
+
+	snapshot, err := cacheStore.Snapshot(ctx)
+	if err != nil {
+		slog.Error("Failed to create cache snapshot", slog.Any("error", err))
+		return
+	}
+
+	err = inmemory.WriteSnapshotFile(path, snapshot)
+	if err != nil {
+		slog.Error("Failed to write cache snapshot", slog.String("path", path), slog.Any("error", err))
+		return
+	}
+
+	slog.Debug(
+		"Wrote cache snapshot",
+		slog.String("path", path),
+		slog.Duration("duration", time.Since(start)),
+	)
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"github.com/merlinfuchs/stateway/stateway-lib/broker"
+	"github.com/merlinfuchs/stateway/stateway-lib/cache"
+)
+
+// TaintedSweeper removes entities that are still tainted after a shard READY.
+// A shard is swept once all guilds from its READY payload have been received,
+// or once the grace period has passed, whichever comes first.
+type TaintedSweeper struct {
+	ctx         context.Context
+	cacheStore  store.CacheStore
+	broker      broker.Broker
+	partition   cache.Partition
+	gracePeriod time.Duration
+
+	mu     sync.Mutex
+	shards map[sweeperShardKey]*sweeperShard
+}
+
+type sweeperShardKey struct {
+	appID   snowflake.ID
+	shardID int
+}
+
+type sweeperShard struct {
+	shardCount    int
+	pendingGuilds map[snowflake.ID]struct{}
+	timer         *time.Timer
+}
+
+func NewTaintedSweeper(
+	ctx context.Context,
+	cacheStore store.CacheStore,
+	br broker.Broker,
+	partition cache.Partition,
+	gracePeriod time.Duration,
+) *TaintedSweeper {
+	return &TaintedSweeper{
+		ctx:         ctx,
+		cacheStore:  cacheStore,
+		broker:      br,
+		partition:   partition,
+		gracePeriod: gracePeriod,
+		shards:      make(map[sweeperShardKey]*sweeperShard),
+	}
+}
+
+// ShardReady starts tracking the guilds of a shard that has just been marked as tainted.
+// It replaces any pending sweep for the same shard.
+func (s *TaintedSweeper) ShardReady(appID snowflake.ID, shardID int, shardCount int, guildIDs []snowflake.ID) {
+	key := sweeperShardKey{appID: appID, shardID: shardID}
+
+	shard := &sweeperShard{
+		shardCount:    shardCount,
+		pendingGuilds: make(map[snowflake.ID]struct{}, len(guildIDs)),
+	}
+	for _, guildID := range guildIDs {
+		shard.pendingGuilds[guildID] = struct{}{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if previous, ok := s.shards[key]; ok && previous.timer != nil {
+		previous.timer.Stop()
+	}
+
+	if len(shard.pendingGuilds) == 0 {
+		delete(s.shards, key)
+		go s.sweep(key, shardCount, nil)
+		return
+	}
+
+	if s.gracePeriod > 0 {
+		shard.timer = time.AfterFunc(s.gracePeriod, func() {
+			s.expire(key, shard)
+		})
+	}
+	s.shards[key] = shard
+}
+
+// GuildAvailable marks a guild of the shard as received and sweeps the shard
+// when it was the last pending guild.
+func (s *TaintedSweeper) GuildAvailable(appID snowflake.ID, shardID int, guildID snowflake.ID) {
+	key := sweeperShardKey{appID: appID, shardID: shardID}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	shard, ok := s.shards[key]
+	if !ok {
+		return
+	}
+
+	delete(shard.pendingGuilds, guildID)
+	if len(shard.pendingGuilds) != 0 {
+		return
+	}
+
+	if shard.timer != nil {
+		shard.timer.Stop()
+	}
+	delete(s.shards, key)
+
+	go s.sweep(key, shard.shardCount, nil)
+}
+
+func (s *TaintedSweeper) expire(key sweeperShardKey, shard *sweeperShard) {
+	s.mu.Lock()
+	if s.shards[key] != shard {
+		// The shard has been swept or received a new READY in the meantime
+		s.mu.Unlock()
+		return
+	}
+	delete(s.shards, key)
+
+	keepGuildIDs := make([]snowflake.ID, 0, len(shard.pendingGuilds))
+	for guildID := range shard.pendingGuilds {
+		keepGuildIDs = append(keepGuildIDs, guildID)
+	}
+	s.mu.Unlock()
+
+	slog.Warn(
+		"Grace period for tainted entities expired before all guilds were received",
+		slog.String("app_id", key.appID.String()),
+		slog.Int("shard_id", key.shardID),
+		slog.Int("pending_guilds", len(keepGuildIDs)),
+	)
+
+	s.sweep(key, shard.shardCount, keepGuildIDs)
+}
+
+func (s *TaintedSweeper) sweep(key sweeperShardKey, shardCount int, keepGuildIDs []snowflake.ID) {
+	err := s.cacheStore.DeleteShardTaintedEntities(s.ctx, store.DeleteShardTaintedEntitiesParams{
+		AppID:          key.appID,
+		ShardCount:     shardCount,
+		ShardID:        key.shardID,
+		PartitionCount: s.partition.Count,
+		PartitionID:    s.partition.ID,
+		KeepGuildIDs:   keepGuildIDs,
+	})
+	if err != nil {
+		slog.Error(
+			"Failed to delete tainted shard entities",
+			slog.String("app_id", key.appID.String()),
+			slog.Int("shard_id", key.shardID),
+			slog.Any("error", err),
+		)
+		return
+	}
+
+	// The swept entities aren't known, so the near caches drop all entities of the app
+	publishInvalidation(s.ctx, s.broker, cache.Invalidation{
+		AppID:      key.appID,
+		EntityType: cache.InvalidationEntityTypeApp,
+		Action:     cache.ChangeActionDeleted,
+	})
+
+	slog.Debug(
+		"Deleted tainted shard entities",
+		slog.String("app_id", key.appID.String()),
+		slog.Int("shard_id", key.shardID),
+	)
+}
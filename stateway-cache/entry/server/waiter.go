@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-lib/broker"
+	"github.com/merlinfuchs/stateway/stateway-lib/cache"
+)
+
+type guildWaitKey struct {
+	appID   snowflake.ID
+	guildID snowflake.ID
+}
+
+// GuildWaiter wakes up guild.wait requests once their guild has been cached.
+// The worker broadcasts a guild invalidation after every guild upsert, so waiters are also woken up
+// if another instance of the cache service handled the GUILD_CREATE.
+type GuildWaiter struct {
+	mu      sync.Mutex
+	waiting map[guildWaitKey][]chan struct{}
+}
+
+func NewGuildWaiter() *GuildWaiter {
+	return &GuildWaiter{
+		waiting: make(map[guildWaitKey][]chan struct{}),
+	}
+}
+
+// Listen wakes up the waiters of guilds that have been created or updated until the context is cancelled.
+func (w *GuildWaiter) Listen(ctx context.Context, br broker.Broker) error {
+	subject := fmt.Sprintf("%s.*.*.%s", cache.InvalidationSubjectPrefix, cache.ChangeEntityTypeGuild)
+
+	return br.Subscribe(ctx, subject, func(subject string, data []byte) {
+		var invalidation cache.Invalidation
+		err := json.Unmarshal(data, &invalidation)
+		if err != nil {
+			slog.Error(
+				"Failed to unmarshal invalidation",
+				slog.String("subject", subject),
+				slog.Any("error", err),
+			)
+			return
+		}
+
+		if invalidation.Action != cache.ChangeActionDeleted {
+			w.GuildCached(invalidation.AppID, invalidation.GuildID)
+		}
+	})
+}
+
+// Wait returns a channel that is closed once the guild has been cached.
+// The guild has to be looked up after calling Wait, so an upsert in between isn't missed. Stop has to be called when done.
+func (w *GuildWaiter) Wait(appID snowflake.ID, guildID snowflake.ID) (cached <-chan struct{}, stop func()) {
+	key := guildWaitKey{appID: appID, guildID: guildID}
+	ch := make(chan struct{})
+
+	w.mu.Lock()
+	w.waiting[key] = append(w.waiting[key], ch)
+	w.mu.Unlock()
+
+	return ch, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		waiting := w.waiting[key]
+		for i, c := range waiting {
+			if c == ch {
+				waiting = append(waiting[:i], waiting[i+1:]...)
+				break
+			}
+		}
+		if len(waiting) == 0 {
+			delete(w.waiting, key)
+		} else {
+			w.waiting[key] = waiting
+		}
+	}
+}
+
+// GuildCached wakes up all waiters of the guild.
+func (w *GuildWaiter) GuildCached(appID snowflake.ID, guildID snowflake.ID) {
+	key := guildWaitKey{appID: appID, guildID: guildID}
+
+	w.mu.Lock()
+	waiting := w.waiting[key]
+	delete(w.waiting, key)
+	w.mu.Unlock()
+
+	for _, ch := range waiting {
+		close(ch)
+	}
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/merlinfuchs/stateway/stateway-cache/inmemory"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"github.com/merlinfuchs/stateway/stateway-lib/cache"
+	"github.com/merlinfuchs/stateway/stateway-lib/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForGuild(t *testing.T) {
+	ctx := context.Background()
+	cacheStore := inmemory.NewMapCacheStore()
+	waiter := NewGuildWaiter()
+	caches := NewCaches(cacheStore, nil, waiter, nil)
+
+	// The guild is cached while the request is waiting
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_, err := cacheStore.UpsertGuilds(ctx, store.UpsertGuildParams{AppID: 1, GuildID: 100, Data: discord.Guild{ID: 100, Name: "Guild"}})
+		assert.NoError(t, err)
+		waiter.GuildCached(1, 100)
+	}()
+
+	guild, err := caches.WaitForGuild(ctx, 100, 5*time.Second, cache.WithAppID(1))
+	require.NoError(t, err)
+	assert.Equal(t, "Guild", guild.Data.Name)
+
+	// Guilds that are already cached are returned right away
+	guild, err = caches.WaitForGuild(ctx, 100, time.Hour, cache.WithAppID(1))
+	require.NoError(t, err)
+	assert.Equal(t, "Guild", guild.Data.Name)
+
+	assert.Empty(t, waiter.waiting)
+}
+
+func TestWaitForGuildTimeout(t *testing.T) {
+	ctx := context.Background()
+	waiter := NewGuildWaiter()
+	caches := NewCaches(inmemory.NewMapCacheStore(), nil, waiter, nil)
+
+	// Guilds of other apps don't wake up the request
+	waiter.GuildCached(2, 100)
+
+	_, err := caches.WaitForGuild(ctx, 100, 20*time.Millisecond, cache.WithAppID(1))
+	assert.True(t, service.IsErrorCode(err, service.ErrorCodeNotFound))
+	assert.Empty(t, waiter.waiting)
+}
@@ -2,21 +2,38 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"time"
 
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/gateway"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
 	"github.com/merlinfuchs/stateway/stateway-cache/store"
 	"github.com/merlinfuchs/stateway/stateway-lib/broker"
+	"github.com/merlinfuchs/stateway/stateway-lib/cache"
 	"github.com/merlinfuchs/stateway/stateway-lib/event"
+	gatewaylib "github.com/merlinfuchs/stateway/stateway-lib/gateway"
 	"github.com/nats-io/nats.go/jetstream"
 )
 
+const (
+	// cacheWorkerMaxDeliver is how often an event is delivered before it's dropped, so a broken event can't block the worker.
+	cacheWorkerMaxDeliver = 5
+	cacheWorkerNackDelay  = 2 * time.Second
+)
+
 type CacheWorker struct {
-	cacheStore store.CacheStore
-	gatewayIDs []int
+	cacheStore  store.CacheStore
+	upserts     *upsertCoalescer
+	sweeper     *TaintedSweeper
+	policies    *cachePolicies
+	broker      broker.Broker
+	gatewayIDs  []int
+	partition   cache.Partition
+	concurrency int
 }
 
 func (l *CacheWorker) BalanceKey() string {
@@ -27,6 +44,10 @@ func (l *CacheWorker) BalanceKey() string {
 	if len(l.gatewayIDs) == 0 {
 		key += "_all"
 	}
+	// Every partition has to receive all events to pick out the events of its guilds
+	if l.partition.Partitioned() {
+		key += fmt.Sprintf("_p%d", l.partition.ID)
+	}
 	return key
 }
 
@@ -38,35 +59,84 @@ func (l *CacheWorker) EventFilter() broker.EventFilter {
 			"guild.>",
 			"channel.>",
 			"thread.>",
+			"app.>",
 		},
 	}
 }
 
 func (l *CacheWorker) ConsumerConfig() broker.ConsumerConfig {
+	// Events are acked once they have been written, failed writes are retried.
+	// A failed event is retried before later events of its guild are handled, as deletes leave no timestamp that a stale upsert could be checked against.
+	// Events of different guilds are handled concurrently, the events of a guild stay in order.
 	return broker.ConsumerConfig{
-		AckPolicy: jetstream.AckNonePolicy,
+		AckPolicy:    jetstream.AckExplicitPolicy,
+		MaxDeliver:   cacheWorkerMaxDeliver,
+		NackDelay:    cacheWorkerNackDelay,
+		Concurrency:  l.concurrency,
+		PartitionKey: eventPartitionKey,
 	}
 }
 
 func (l *CacheWorker) HandleEvent(ctx context.Context, event *event.GatewayEvent) (bool, error) {
 	slog.Debug("Received event:", slog.String("type", event.Type))
 
+	if isAppPurgeEvent(event.Type) {
+		_, err := purgeApp(ctx, l.cacheStore, l.broker, event.AppID)
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	if l.partition.Partitioned() {
+		guildID, ok := eventGuildID(event)
+		if ok && !l.partition.OwnsGuild(guildID) {
+			return true, nil
+		}
+	}
+
 	e, err := gateway.UnmarshalEventData(event.Data, gateway.EventType(event.Type))
 	if err != nil {
 		return false, fmt.Errorf("failed to unmarshal event data: %w", err)
 	}
 
+	// The cache config of the app decides which entities are stored and what is stripped from them
+	policy := l.policies.Get(ctx, event.AppID)
+
+	// Entities are stamped with the time of the event, so the stores can tell stale redeliveries apart from newer data
+	updatedAt := eventTime(event)
+
 	switch e := e.(type) {
 	case gateway.EventReady:
 		err = l.cacheStore.MarkShardEntitiesTainted(ctx, store.MarkShardEntitiesTaintedParams{
-			AppID:      event.AppID,
-			ShardCount: e.Shard[1],
-			ShardID:    e.Shard[0],
+			AppID:          event.AppID,
+			ShardCount:     e.Shard[1],
+			ShardID:        e.Shard[0],
+			PartitionCount: l.partition.Count,
+			PartitionID:    l.partition.ID,
 		})
 		if err != nil {
 			return false, fmt.Errorf("failed to mark shard guilds as tainted: %w", err)
 		}
+
+		guildIDs := make([]snowflake.ID, 0, len(e.Guilds))
+		for _, guild := range e.Guilds {
+			if l.partition.OwnsGuild(guild.ID) {
+				guildIDs = append(guildIDs, guild.ID)
+			}
+		}
+		l.sweeper.ShardReady(event.AppID, e.Shard[0], e.Shard[1], guildIDs)
 	case gateway.EventGuildCreate:
+		// The collections of the guild are loaded too, so the changes of its entities can be published
+		oldGuild, err := existingEntity(l.cacheStore.GetFullGuild(ctx, store.GetFullGuildParams{
+			AppID:   event.AppID,
+			GuildID: e.ID,
+			Include: cache.FullGuildIncludeAll,
+		}))
+		if err != nil {
+			return false, fmt.Errorf("failed to get guild: %w", err)
+		}
+
 		roles := make([]store.UpsertRoleParams, len(e.Roles))
 		for i, role := range e.Roles {
 			roles[i] = store.UpsertRoleParams{
@@ -75,7 +145,7 @@ func (l *CacheWorker) HandleEvent(ctx context.Context, event *event.GatewayEvent
 				RoleID:    role.ID,
 				Data:      role,
 				CreatedAt: time.Now().UTC(),
-				UpdatedAt: time.Now().UTC(),
+				UpdatedAt: updatedAt,
 			}
 		}
 
@@ -88,7 +158,7 @@ func (l *CacheWorker) HandleEvent(ctx context.Context, event *event.GatewayEvent
 				ChannelID: channel.ID(),
 				Data:      channel,
 				CreatedAt: time.Now().UTC(),
-				UpdatedAt: time.Now().UTC(),
+				UpdatedAt: updatedAt,
 			})
 		}
 		for _, thread := range e.Threads {
@@ -99,11 +169,11 @@ func (l *CacheWorker) HandleEvent(ctx context.Context, event *event.GatewayEvent
 				ChannelID: thread.ID(),
 				Data:      thread,
 				CreatedAt: time.Now().UTC(),
-				UpdatedAt: time.Now().UTC(),
+				UpdatedAt: updatedAt,
 			})
 		}
 
-		emojis := make([]store.UpsertEmojiParams, len(e.Emojis))
+		emojis := make([]store.UpsertEmojiParams, 0, len(e.Emojis))
 		for _, emoji := range e.Emojis {
 			emojis = append(emojis, store.UpsertEmojiParams{
 				AppID:     event.AppID,
@@ -111,7 +181,7 @@ func (l *CacheWorker) HandleEvent(ctx context.Context, event *event.GatewayEvent
 				EmojiID:   emoji.ID,
 				Data:      emoji,
 				CreatedAt: time.Now().UTC(),
-				UpdatedAt: time.Now().UTC(),
+				UpdatedAt: updatedAt,
 			})
 		}
 
@@ -123,11 +193,11 @@ func (l *CacheWorker) HandleEvent(ctx context.Context, event *event.GatewayEvent
 				StickerID: sticker.ID,
 				Data:      sticker,
 				CreatedAt: time.Now().UTC(),
-				UpdatedAt: time.Now().UTC(),
+				UpdatedAt: updatedAt,
 			}
 		}
 
-		err = l.cacheStore.MassUpsertEntities(ctx, store.MassUpsertEntitiesParams{
+		params, err := policy.apply(store.MassUpsertEntitiesParams{
 			AppID: event.AppID,
 			Guilds: []store.UpsertGuildParams{
 				{
@@ -135,7 +205,7 @@ func (l *CacheWorker) HandleEvent(ctx context.Context, event *event.GatewayEvent
 					GuildID:   e.Guild.ID,
 					Data:      e.Guild,
 					CreatedAt: time.Now().UTC(),
-					UpdatedAt: time.Now().UTC(),
+					UpdatedAt: updatedAt,
 				},
 			},
 			Roles:    roles,
@@ -143,157 +213,579 @@ func (l *CacheWorker) HandleEvent(ctx context.Context, event *event.GatewayEvent
 			Emojis:   emojis,
 			Stickers: stickers,
 		})
+		if err != nil {
+			return false, fmt.Errorf("failed to apply cache policy to guild %s: %w", e.ID, err)
+		}
+		guild := params.Guilds[0]
+
+		// Guilds that are created at the same time are written together, see upsertCoalescer
+		err = l.upserts.MassUpsertEntities(ctx, params)
 		if err != nil {
 			return false, fmt.Errorf("failed to mass upsert entities for guild %s: %w", e.ID, err)
 		}
 
+		l.sweeper.GuildAvailable(event.AppID, event.ShardID, e.ID)
+
+		var oldFullGuild model.FullGuild
+		var oldGuildEntity *model.Guild
+		if oldGuild != nil {
+			oldFullGuild = *oldGuild
+			oldGuildEntity = &oldGuild.Guild
+		}
+
+		// The stored guild isn't overwritten when it has been updated more recently than the event
+		if oldGuildEntity == nil || (!sameData(oldGuildEntity.Data, guild.Data) && !oldGuildEntity.UpdatedAt.After(updatedAt)) {
+			publishChange(ctx, l.broker, event.AppID, e.ID, cache.ChangeEntityTypeGuild, e.ID, oldGuildEntity, &model.Guild{
+				AppID:     guild.AppID,
+				GuildID:   guild.GuildID,
+				Data:      guild.Data,
+				CreatedAt: guild.CreatedAt,
+				UpdatedAt: guild.UpdatedAt,
+			})
+		}
+
+		err = l.syncGuildCollections(ctx, event, e.ID, updatedAt, &oldFullGuild, params)
+		if err != nil {
+			return false, err
+		}
+
 		return true, nil
 	case gateway.EventGuildUpdate:
-		err = l.cacheStore.UpsertGuilds(ctx, store.UpsertGuildParams{
+		oldGuild, err := existingEntity(l.cacheStore.GetGuild(ctx, event.AppID, e.Guild.ID))
+		if err != nil {
+			return false, fmt.Errorf("failed to get guild: %w", err)
+		}
+
+		data, err := stripFields(policy, gatewaylib.AppCacheEntityGuild, e.Guild)
+		if err != nil {
+			return false, err
+		}
+
+		newGuild := store.UpsertGuildParams{
 			AppID:     event.AppID,
 			GuildID:   e.Guild.ID,
-			Data:      e.Guild,
+			Data:      data,
 			CreatedAt: time.Now().UTC(),
-			UpdatedAt: time.Now().UTC(),
-		})
+			UpdatedAt: updatedAt,
+		}
+		written, err := l.cacheStore.UpsertGuilds(ctx, newGuild)
 		if err != nil {
 			return false, fmt.Errorf("failed to upsert guild: %w", err)
 		}
+		if written == 0 {
+			break
+		}
+
+		publishChange(ctx, l.broker, event.AppID, e.Guild.ID, cache.ChangeEntityTypeGuild, e.Guild.ID, oldGuild, &model.Guild{
+			AppID:     newGuild.AppID,
+			GuildID:   newGuild.GuildID,
+			Data:      newGuild.Data,
+			CreatedAt: newGuild.CreatedAt,
+			UpdatedAt: newGuild.UpdatedAt,
+		})
 	case gateway.EventGuildDelete:
 		if !e.Unavailable {
 			err = l.cacheStore.MarkGuildUnavailable(ctx, event.AppID, e.ID)
 			if err != nil {
 				return false, fmt.Errorf("failed to mark guild as unavailable: %w", err)
 			}
+
+			publishInvalidation(ctx, l.broker, cache.Invalidation{
+				AppID:      event.AppID,
+				GuildID:    e.ID,
+				EntityType: cache.ChangeEntityTypeGuild,
+				EntityID:   e.ID,
+				Action:     cache.ChangeActionUpdated,
+			})
 		} else {
+			oldGuild, err := existingEntity(l.cacheStore.GetGuild(ctx, event.AppID, e.ID))
+			if err != nil {
+				return false, fmt.Errorf("failed to get guild: %w", err)
+			}
+
 			err = l.cacheStore.DeleteGuild(ctx, event.AppID, e.ID)
 			if err != nil {
 				return false, fmt.Errorf("failed to delete guild: %w", err)
 			}
+
+			publishChange(ctx, l.broker, event.AppID, e.ID, cache.ChangeEntityTypeGuild, e.ID, oldGuild, nil)
 		}
 	case gateway.EventGuildRoleCreate:
-		err = l.cacheStore.UpsertRoles(ctx, store.UpsertRoleParams{
+		err = l.upsertRole(ctx, event, policy, store.UpsertRoleParams{
 			AppID:     event.AppID,
 			GuildID:   e.GuildID,
 			RoleID:    e.Role.ID,
 			Data:      e.Role,
 			CreatedAt: time.Now().UTC(),
-			UpdatedAt: time.Now().UTC(),
+			UpdatedAt: updatedAt,
 		})
 		if err != nil {
-			return false, fmt.Errorf("failed to upsert role: %w", err)
+			return false, err
 		}
 	case gateway.EventGuildRoleUpdate:
-		err = l.cacheStore.UpsertRoles(ctx, store.UpsertRoleParams{
+		err = l.upsertRole(ctx, event, policy, store.UpsertRoleParams{
 			AppID:     event.AppID,
 			GuildID:   e.GuildID,
 			RoleID:    e.Role.ID,
 			Data:      e.Role,
 			CreatedAt: time.Now().UTC(),
-			UpdatedAt: time.Now().UTC(),
+			UpdatedAt: updatedAt,
 		})
 		if err != nil {
-			return false, fmt.Errorf("failed to upsert role: %w", err)
+			return false, err
 		}
 	case gateway.EventGuildRoleDelete:
+		oldRole, err := existingEntity(l.cacheStore.GetGuildRole(ctx, event.AppID, e.GuildID, e.RoleID))
+		if err != nil {
+			return false, fmt.Errorf("failed to get role: %w", err)
+		}
+
 		err = l.cacheStore.DeleteRole(ctx, event.AppID, e.GuildID, e.RoleID)
 		if err != nil {
 			return false, fmt.Errorf("failed to delete role: %w", err)
 		}
+
+		publishChange(ctx, l.broker, event.AppID, e.GuildID, cache.ChangeEntityTypeRole, e.RoleID, oldRole, nil)
 	case gateway.EventChannelCreate:
-		err = l.cacheStore.UpsertChannels(ctx, store.UpsertChannelParams{
+		err = l.upsertChannel(ctx, event, policy, store.UpsertChannelParams{
 			AppID:     event.AppID,
 			GuildID:   e.GuildID(),
 			ChannelID: e.ID(),
 			Data:      e.GuildChannel,
 			CreatedAt: time.Now().UTC(),
-			UpdatedAt: time.Now().UTC(),
+			UpdatedAt: updatedAt,
 		})
 		if err != nil {
-			return false, fmt.Errorf("failed to upsert channel: %w", err)
+			return false, err
 		}
 	case gateway.EventChannelUpdate:
-
-		err = l.cacheStore.UpsertChannels(ctx, store.UpsertChannelParams{
+		err = l.upsertChannel(ctx, event, policy, store.UpsertChannelParams{
 			AppID:     event.AppID,
 			GuildID:   e.GuildID(),
 			ChannelID: e.ID(),
 			Data:      e.GuildChannel,
 			CreatedAt: time.Now().UTC(),
-			UpdatedAt: time.Now().UTC(),
+			UpdatedAt: updatedAt,
 		})
 		if err != nil {
-			return false, fmt.Errorf("failed to upsert channel: %w", err)
+			return false, err
 		}
 	case gateway.EventChannelDelete:
+		oldChannel, err := existingEntity(l.cacheStore.GetGuildChannel(ctx, event.AppID, e.GuildID(), e.ID()))
+		if err != nil {
+			return false, fmt.Errorf("failed to get channel: %w", err)
+		}
+
 		err = l.cacheStore.DeleteChannel(ctx, event.AppID, e.GuildID(), e.ID())
 		if err != nil {
 			return false, fmt.Errorf("failed to delete channel: %w", err)
 		}
+
+		publishChange(ctx, l.broker, event.AppID, e.GuildID(), cache.ChangeEntityTypeChannel, e.ID(), oldChannel, nil)
 	case gateway.EventThreadCreate:
-		err = l.cacheStore.UpsertChannels(ctx, store.UpsertChannelParams{
+		err = l.upsertChannel(ctx, event, policy, store.UpsertChannelParams{
 			AppID:     event.AppID,
 			GuildID:   e.GuildID(),
 			ChannelID: e.ID(),
 			Data:      e.GuildThread,
 			CreatedAt: time.Now().UTC(),
-			UpdatedAt: time.Now().UTC(),
+			UpdatedAt: updatedAt,
 		})
 		if err != nil {
-			return false, fmt.Errorf("failed to upsert thread: %w", err)
+			return false, err
 		}
 	case gateway.EventThreadUpdate:
-		err = l.cacheStore.UpsertChannels(ctx, store.UpsertChannelParams{
+		err = l.upsertChannel(ctx, event, policy, store.UpsertChannelParams{
 			AppID:     event.AppID,
 			GuildID:   e.GuildID(),
 			ChannelID: e.ID(),
 			Data:      e.GuildThread,
 			CreatedAt: time.Now().UTC(),
-			UpdatedAt: time.Now().UTC(),
+			UpdatedAt: updatedAt,
 		})
 		if err != nil {
-			return false, fmt.Errorf("failed to upsert thread: %w", err)
+			return false, err
 		}
 	case gateway.EventThreadDelete:
+		oldChannel, err := existingEntity(l.cacheStore.GetGuildChannel(ctx, event.AppID, e.GuildID, e.ID))
+		if err != nil {
+			return false, fmt.Errorf("failed to get thread: %w", err)
+		}
+
 		err = l.cacheStore.DeleteChannel(ctx, event.AppID, e.GuildID, e.ID)
 		if err != nil {
 			return false, fmt.Errorf("failed to delete thread: %w", err)
 		}
+
+		publishChange(ctx, l.broker, event.AppID, e.GuildID, cache.ChangeEntityTypeChannel, e.ID, oldChannel, nil)
 	case gateway.EventGuildEmojisUpdate:
+		if !policy.caches(gatewaylib.AppCacheEntityEmoji) {
+			break
+		}
+
 		emojis := make([]store.UpsertEmojiParams, len(e.Emojis))
 		for i, emoji := range e.Emojis {
+			data, err := stripFields(policy, gatewaylib.AppCacheEntityEmoji, emoji)
+			if err != nil {
+				return false, err
+			}
+
 			emojis[i] = store.UpsertEmojiParams{
 				AppID:     event.AppID,
 				GuildID:   e.GuildID,
 				EmojiID:   emoji.ID,
-				Data:      emoji,
+				Data:      data,
 				CreatedAt: time.Now().UTC(),
-				UpdatedAt: time.Now().UTC(),
+				UpdatedAt: updatedAt,
 			}
 		}
 
-		err = l.cacheStore.UpsertEmojis(ctx, emojis...)
+		err = l.replaceGuildEmojis(ctx, event, e.GuildID, updatedAt, emojis)
 		if err != nil {
-			return false, fmt.Errorf("failed to upsert emojis: %w", err)
+			return false, err
 		}
 	case gateway.EventGuildStickersUpdate:
+		if !policy.caches(gatewaylib.AppCacheEntitySticker) {
+			break
+		}
+
 		stickers := make([]store.UpsertStickerParams, len(e.Stickers))
 		for i, sticker := range e.Stickers {
+			data, err := stripFields(policy, gatewaylib.AppCacheEntitySticker, sticker)
+			if err != nil {
+				return false, err
+			}
+
 			stickers[i] = store.UpsertStickerParams{
 				AppID:     event.AppID,
 				GuildID:   e.GuildID,
 				StickerID: sticker.ID,
-				Data:      sticker,
+				Data:      data,
 				CreatedAt: time.Now().UTC(),
-				UpdatedAt: time.Now().UTC(),
+				UpdatedAt: updatedAt,
 			}
 		}
 
-		err = l.cacheStore.UpsertStickers(ctx, stickers...)
+		err = l.replaceGuildStickers(ctx, event, e.GuildID, updatedAt, stickers)
 		if err != nil {
-			return false, fmt.Errorf("failed to upsert stickers: %w", err)
+			return false, err
 		}
 	}
 
 	return true, nil
 }
+
+// syncGuildCollections publishes the changes of the roles, channels, emojis and stickers of a GUILD_CREATE
+// and deletes the cached entities that are no longer part of the guild.
+func (l *CacheWorker) syncGuildCollections(
+	ctx context.Context,
+	event *event.GatewayEvent,
+	guildID snowflake.ID,
+	updatedAt time.Time,
+	oldGuild *model.FullGuild,
+	params store.MassUpsertEntitiesParams,
+) error {
+	roles := make([]*model.Role, len(params.Roles))
+	for i, role := range params.Roles {
+		roles[i] = &model.Role{
+			AppID:     role.AppID,
+			GuildID:   role.GuildID,
+			RoleID:    role.RoleID,
+			Data:      role.Data,
+			CreatedAt: role.CreatedAt,
+			UpdatedAt: role.UpdatedAt,
+		}
+	}
+	err := syncGuildEntities(ctx, l.broker, event.AppID, guildID, cache.ChangeEntityTypeRole, updatedAt, oldGuild.Roles, roles,
+		func(r *model.Role) snowflake.ID { return r.RoleID },
+		func(r *model.Role) time.Time { return r.UpdatedAt },
+		func(r *model.Role) any { return r.Data },
+		func(id snowflake.ID) error { return l.cacheStore.DeleteRole(ctx, event.AppID, guildID, id) },
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+
+	channels := make([]*model.Channel, len(params.Channels))
+	for i, channel := range params.Channels {
+		channels[i] = &model.Channel{
+			AppID:     channel.AppID,
+			GuildID:   channel.GuildID,
+			ChannelID: channel.ChannelID,
+			Data:      channel.Data,
+			CreatedAt: channel.CreatedAt,
+			UpdatedAt: channel.UpdatedAt,
+		}
+	}
+	err = syncGuildEntities(ctx, l.broker, event.AppID, guildID, cache.ChangeEntityTypeChannel, updatedAt, oldGuild.Channels, channels,
+		func(c *model.Channel) snowflake.ID { return c.ChannelID },
+		func(c *model.Channel) time.Time { return c.UpdatedAt },
+		func(c *model.Channel) any { return c.Data },
+		func(id snowflake.ID) error { return l.cacheStore.DeleteChannel(ctx, event.AppID, guildID, id) },
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete channel: %w", err)
+	}
+
+	emojis := make([]*model.Emoji, len(params.Emojis))
+	for i, emoji := range params.Emojis {
+		emojis[i] = &model.Emoji{
+			AppID:     emoji.AppID,
+			GuildID:   emoji.GuildID,
+			EmojiID:   emoji.EmojiID,
+			Data:      emoji.Data,
+			CreatedAt: emoji.CreatedAt,
+			UpdatedAt: emoji.UpdatedAt,
+		}
+	}
+	err = syncGuildEntities(ctx, l.broker, event.AppID, guildID, cache.ChangeEntityTypeEmoji, updatedAt, oldGuild.Emojis, emojis,
+		func(e *model.Emoji) snowflake.ID { return e.EmojiID },
+		func(e *model.Emoji) time.Time { return e.UpdatedAt },
+		func(e *model.Emoji) any { return e.Data },
+		func(id snowflake.ID) error { return l.cacheStore.DeleteEmoji(ctx, event.AppID, guildID, id) },
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete emoji: %w", err)
+	}
+
+	stickers := make([]*model.Sticker, len(params.Stickers))
+	for i, sticker := range params.Stickers {
+		stickers[i] = &model.Sticker{
+			AppID:     sticker.AppID,
+			GuildID:   sticker.GuildID,
+			StickerID: sticker.StickerID,
+			Data:      sticker.Data,
+			CreatedAt: sticker.CreatedAt,
+			UpdatedAt: sticker.UpdatedAt,
+		}
+	}
+	err = syncGuildEntities(ctx, l.broker, event.AppID, guildID, cache.ChangeEntityTypeSticker, updatedAt, oldGuild.Stickers, stickers,
+		func(s *model.Sticker) snowflake.ID { return s.StickerID },
+		func(s *model.Sticker) time.Time { return s.UpdatedAt },
+		func(s *model.Sticker) any { return s.Data },
+		func(id snowflake.ID) error { return l.cacheStore.DeleteSticker(ctx, event.AppID, guildID, id) },
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete sticker: %w", err)
+	}
+
+	return nil
+}
+
+func (l *CacheWorker) upsertRole(ctx context.Context, event *event.GatewayEvent, policy *cachePolicy, role store.UpsertRoleParams) error {
+	if !policy.caches(gatewaylib.AppCacheEntityRole) {
+		return nil
+	}
+
+	data, err := stripFields(policy, gatewaylib.AppCacheEntityRole, role.Data)
+	if err != nil {
+		return err
+	}
+	role.Data = data
+
+	oldRole, err := existingEntity(l.cacheStore.GetGuildRole(ctx, role.AppID, role.GuildID, role.RoleID))
+	if err != nil {
+		return fmt.Errorf("failed to get role: %w", err)
+	}
+
+	written, err := l.cacheStore.UpsertRoles(ctx, role)
+	if err != nil {
+		return fmt.Errorf("failed to upsert role: %w", err)
+	}
+	if written == 0 {
+		return nil
+	}
+
+	publishChange(ctx, l.broker, event.AppID, role.GuildID, cache.ChangeEntityTypeRole, role.RoleID, oldRole, &model.Role{
+		AppID:     role.AppID,
+		GuildID:   role.GuildID,
+		RoleID:    role.RoleID,
+		Data:      role.Data,
+		CreatedAt: role.CreatedAt,
+		UpdatedAt: role.UpdatedAt,
+	})
+	return nil
+}
+
+func (l *CacheWorker) upsertChannel(ctx context.Context, event *event.GatewayEvent, policy *cachePolicy, channel store.UpsertChannelParams) error {
+	if !policy.cachesChannel(channel.Data) {
+		return nil
+	}
+
+	data, err := stripChannelFields(policy, channel.Data)
+	if err != nil {
+		return err
+	}
+	channel.Data = data
+
+	oldChannel, err := existingEntity(l.cacheStore.GetGuildChannel(ctx, channel.AppID, channel.GuildID, channel.ChannelID))
+	if err != nil {
+		return fmt.Errorf("failed to get channel: %w", err)
+	}
+
+	written, err := l.cacheStore.UpsertChannels(ctx, channel)
+	if err != nil {
+		return fmt.Errorf("failed to upsert channel: %w", err)
+	}
+	if written == 0 {
+		return nil
+	}
+
+	publishChange(ctx, l.broker, event.AppID, channel.GuildID, cache.ChangeEntityTypeChannel, channel.ChannelID, oldChannel, &model.Channel{
+		AppID:     channel.AppID,
+		GuildID:   channel.GuildID,
+		ChannelID: channel.ChannelID,
+		Data:      channel.Data,
+		CreatedAt: channel.CreatedAt,
+		UpdatedAt: channel.UpdatedAt,
+	})
+	return nil
+}
+
+func (l *CacheWorker) replaceGuildEmojis(ctx context.Context, event *event.GatewayEvent, guildID snowflake.ID, updatedAt time.Time, emojis []store.UpsertEmojiParams) error {
+	oldEmojis, err := l.cacheStore.GetGuildEmojis(ctx, event.AppID, guildID, store.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get emojis: %w", err)
+	}
+
+	replaced, err := l.cacheStore.ReplaceGuildEmojis(ctx, event.AppID, guildID, updatedAt, emojis...)
+	if err != nil {
+		return fmt.Errorf("failed to replace emojis: %w", err)
+	}
+	if !replaced {
+		return nil
+	}
+
+	oldByID := make(map[snowflake.ID]*model.Emoji, len(oldEmojis))
+	for _, emoji := range oldEmojis {
+		oldByID[emoji.EmojiID] = emoji
+	}
+
+	for _, emoji := range emojis {
+		oldEmoji := oldByID[emoji.EmojiID]
+		delete(oldByID, emoji.EmojiID)
+		if oldEmoji != nil && (sameData(oldEmoji.Data, emoji.Data) || oldEmoji.UpdatedAt.After(updatedAt)) {
+			continue
+		}
+
+		publishChange(ctx, l.broker, event.AppID, guildID, cache.ChangeEntityTypeEmoji, emoji.EmojiID, oldEmoji, &model.Emoji{
+			AppID:     emoji.AppID,
+			GuildID:   emoji.GuildID,
+			EmojiID:   emoji.EmojiID,
+			Data:      emoji.Data,
+			CreatedAt: emoji.CreatedAt,
+			UpdatedAt: emoji.UpdatedAt,
+		})
+	}
+
+	for emojiID, oldEmoji := range oldByID {
+		// The store keeps the emojis that have been updated after the event
+		if oldEmoji.UpdatedAt.After(updatedAt) {
+			continue
+		}
+		publishChange(ctx, l.broker, event.AppID, guildID, cache.ChangeEntityTypeEmoji, emojiID, oldEmoji, nil)
+	}
+	return nil
+}
+
+func (l *CacheWorker) replaceGuildStickers(ctx context.Context, event *event.GatewayEvent, guildID snowflake.ID, updatedAt time.Time, stickers []store.UpsertStickerParams) error {
+	oldStickers, err := l.cacheStore.GetGuildStickers(ctx, event.AppID, guildID, store.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get stickers: %w", err)
+	}
+
+	replaced, err := l.cacheStore.ReplaceGuildStickers(ctx, event.AppID, guildID, updatedAt, stickers...)
+	if err != nil {
+		return fmt.Errorf("failed to replace stickers: %w", err)
+	}
+	if !replaced {
+		return nil
+	}
+
+	oldByID := make(map[snowflake.ID]*model.Sticker, len(oldStickers))
+	for _, sticker := range oldStickers {
+		oldByID[sticker.StickerID] = sticker
+	}
+
+	for _, sticker := range stickers {
+		oldSticker := oldByID[sticker.StickerID]
+		delete(oldByID, sticker.StickerID)
+		if oldSticker != nil && (sameData(oldSticker.Data, sticker.Data) || oldSticker.UpdatedAt.After(updatedAt)) {
+			continue
+		}
+
+		publishChange(ctx, l.broker, event.AppID, guildID, cache.ChangeEntityTypeSticker, sticker.StickerID, oldSticker, &model.Sticker{
+			AppID:     sticker.AppID,
+			GuildID:   sticker.GuildID,
+			StickerID: sticker.StickerID,
+			Data:      sticker.Data,
+			CreatedAt: sticker.CreatedAt,
+			UpdatedAt: sticker.UpdatedAt,
+		})
+	}
+
+	for stickerID, oldSticker := range oldByID {
+		// The store keeps the stickers that have been updated after the event
+		if oldSticker.UpdatedAt.After(updatedAt) {
+			continue
+		}
+		publishChange(ctx, l.broker, event.AppID, guildID, cache.ChangeEntityTypeSticker, stickerID, oldSticker, nil)
+	}
+	return nil
+}
+
+// eventGuildID returns the ID of the guild that the event belongs to.
+// The guild events carry the guild ID as their ID, the other events in their guild_id field.
+func eventGuildID(event *event.GatewayEvent) (snowflake.ID, bool) {
+	if event.GuildID != nil {
+		return *event.GuildID, true
+	}
+
+	var data struct {
+		ID      snowflake.ID  `json:"id"`
+		GuildID *snowflake.ID `json:"guild_id"`
+	}
+	err := json.Unmarshal(event.Data, &data)
+	if err != nil {
+		return 0, false
+	}
+
+	if data.GuildID != nil {
+		return *data.GuildID, true
+	}
+
+	switch gateway.EventType(event.Type) {
+	case gateway.EventTypeGuildCreate, gateway.EventTypeGuildUpdate, gateway.EventTypeGuildDelete:
+		return data.ID, true
+	}
+	return 0, false
+}
+
+// eventPartitionKey orders the events of a guild across the workers of the cache worker.
+// Events that aren't scoped to a guild, like READY or app purges, affect many guilds and have no key.
+func eventPartitionKey(evt event.Event) (uint64, bool) {
+	gatewayEvent, ok := evt.(*event.GatewayEvent)
+	if !ok || isAppPurgeEvent(gatewayEvent.Type) {
+		return 0, false
+	}
+
+	guildID, ok := eventGuildID(gatewayEvent)
+	if !ok {
+		return 0, false
+	}
+	return uint64(guildID), true
+}
+
+// eventTime returns when the gateway received the event, which is encoded in the snowflake ID of the event.
+func eventTime(event *event.GatewayEvent) time.Time {
+	if event.ID == 0 {
+		return time.Now().UTC()
+	}
+	return event.ID.Time().UTC()
+}
+
+// isAppPurgeEvent reports whether the event announces that the app has been deleted or disabled.
+// Its entities won't be updated anymore, so they are purged.
+func isAppPurgeEvent(eventType string) bool {
+	return eventType == event.GatewayEventTypeAppDeleted || eventType == event.GatewayEventTypeAppDisabled
+}
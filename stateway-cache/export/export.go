@@ -0,0 +1,324 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"github.com/merlinfuchs/stateway/stateway-lib/cache"
+)
+
+// importBatchSize is the number of records that are upserted at once when importing.
+const importBatchSize = 1000
+
+// Record is a single cached entity, exports contain one record per line.
+// Guilds are written before their entities, the EntityID of a guild is the guild ID.
+type Record struct {
+	Type        cache.ChangeEntityType `json:"type"`
+	AppID       snowflake.ID           `json:"app_id"`
+	GuildID     snowflake.ID           `json:"guild_id"`
+	EntityID    snowflake.ID           `json:"entity_id"`
+	Unavailable bool                   `json:"unavailable,omitempty"`
+	Data        json.RawMessage        `json:"data"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+// Counts is the number of records per entity type that have been exported or imported.
+type Counts struct {
+	Guilds   int
+	Roles    int
+	Channels int
+	Emojis   int
+	Stickers int
+}
+
+// WriteEntities writes the guild, or all guilds of the app if guildID is 0, with their entities as JSONL.
+func WriteEntities(ctx context.Context, cacheStore store.CacheStore, w io.Writer, appID snowflake.ID, guildID snowflake.ID) (Counts, error) {
+	var counts Counts
+
+	var guilds []*cache.Guild
+	if guildID != 0 {
+		guild, err := cacheStore.GetGuild(ctx, appID, guildID)
+		if err != nil {
+			return counts, fmt.Errorf("failed to get guild: %w", err)
+		}
+		guilds = []*cache.Guild{guild}
+	} else {
+		var err error
+		guilds, err = cacheStore.GetGuilds(ctx, appID, store.ListOptions{})
+		if err != nil {
+			return counts, fmt.Errorf("failed to get guilds: %w", err)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	write := func(entityType cache.ChangeEntityType, guildID snowflake.ID, entityID snowflake.ID, data any, createdAt time.Time, updatedAt time.Time) error {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s data: %w", entityType, err)
+		}
+
+		err = enc.Encode(Record{
+			Type:      entityType,
+			AppID:     appID,
+			GuildID:   guildID,
+			EntityID:  entityID,
+			Data:      raw,
+			CreatedAt: createdAt,
+			UpdatedAt: updatedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write %s record: %w", entityType, err)
+		}
+		return nil
+	}
+
+	for _, guild := range guilds {
+		raw, err := json.Marshal(guild.Data)
+		if err != nil {
+			return counts, fmt.Errorf("failed to marshal guild data: %w", err)
+		}
+		err = enc.Encode(Record{
+			Type:        cache.ChangeEntityTypeGuild,
+			AppID:       appID,
+			GuildID:     guild.GuildID,
+			EntityID:    guild.GuildID,
+			Unavailable: guild.Unavailable,
+			Data:        raw,
+			CreatedAt:   guild.CreatedAt,
+			UpdatedAt:   guild.UpdatedAt,
+		})
+		if err != nil {
+			return counts, fmt.Errorf("failed to write guild record: %w", err)
+		}
+		counts.Guilds++
+
+		roles, err := cacheStore.GetGuildRoles(ctx, appID, guild.GuildID, store.ListOptions{})
+		if err != nil {
+			return counts, fmt.Errorf("failed to get guild roles: %w", err)
+		}
+		for _, role := range roles {
+			err = write(cache.ChangeEntityTypeRole, role.GuildID, role.RoleID, role.Data, role.CreatedAt, role.UpdatedAt)
+			if err != nil {
+				return counts, err
+			}
+			counts.Roles++
+		}
+
+		channels, err := cacheStore.GetGuildChannels(ctx, appID, guild.GuildID, store.ListOptions{})
+		if err != nil {
+			return counts, fmt.Errorf("failed to get guild channels: %w", err)
+		}
+		for _, channel := range channels {
+			err = write(cache.ChangeEntityTypeChannel, channel.GuildID, channel.ChannelID, channel.Data, channel.CreatedAt, channel.UpdatedAt)
+			if err != nil {
+				return counts, err
+			}
+			counts.Channels++
+		}
+
+		emojis, err := cacheStore.GetGuildEmojis(ctx, appID, guild.GuildID, store.ListOptions{})
+		if err != nil {
+			return counts, fmt.Errorf("failed to get guild emojis: %w", err)
+		}
+		for _, emoji := range emojis {
+			err = write(cache.ChangeEntityTypeEmoji, emoji.GuildID, emoji.EmojiID, emoji.Data, emoji.CreatedAt, emoji.UpdatedAt)
+			if err != nil {
+				return counts, err
+			}
+			counts.Emojis++
+		}
+
+		stickers, err := cacheStore.GetGuildStickers(ctx, appID, guild.GuildID, store.ListOptions{})
+		if err != nil {
+			return counts, fmt.Errorf("failed to get guild stickers: %w", err)
+		}
+		for _, sticker := range stickers {
+			err = write(cache.ChangeEntityTypeSticker, sticker.GuildID, sticker.StickerID, sticker.Data, sticker.CreatedAt, sticker.UpdatedAt)
+			if err != nil {
+				return counts, err
+			}
+			counts.Stickers++
+		}
+	}
+
+	err := bw.Flush()
+	if err != nil {
+		return counts, fmt.Errorf("failed to flush records: %w", err)
+	}
+	return counts, nil
+}
+
+// ReadEntities loads the records written by WriteEntities into the cache store.
+// Records are upserted in batches, so the entities of large exports don't have to fit in memory at once.
+func ReadEntities(ctx context.Context, cacheStore store.CacheStore, r io.Reader) (Counts, error) {
+	var counts Counts
+
+	dec := json.NewDecoder(r)
+	batch := newImportBatch()
+	for {
+		var record Record
+		err := dec.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return counts, fmt.Errorf("failed to decode record: %w", err)
+		}
+
+		err = batch.add(record)
+		if err != nil {
+			return counts, err
+		}
+
+		if batch.size >= importBatchSize {
+			err = batch.flush(ctx, cacheStore, &counts)
+			if err != nil {
+				return counts, err
+			}
+		}
+	}
+
+	err := batch.flush(ctx, cacheStore, &counts)
+	if err != nil {
+		return counts, err
+	}
+	return counts, nil
+}
+
+// importBatch collects the records of multiple apps until they are upserted.
+type importBatch struct {
+	apps              map[snowflake.ID]*store.MassUpsertEntitiesParams
+	unavailableGuilds []Record
+	size              int
+}
+
+func newImportBatch() *importBatch {
+	return &importBatch{
+		apps: make(map[snowflake.ID]*store.MassUpsertEntitiesParams),
+	}
+}
+
+func (b *importBatch) add(record Record) error {
+	params, ok := b.apps[record.AppID]
+	if !ok {
+		params = &store.MassUpsertEntitiesParams{AppID: record.AppID}
+		b.apps[record.AppID] = params
+	}
+
+	switch record.Type {
+	case cache.ChangeEntityTypeGuild:
+		var data discord.Guild
+		err := json.Unmarshal(record.Data, &data)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal guild %s: %w", record.EntityID, err)
+		}
+		params.Guilds = append(params.Guilds, store.UpsertGuildParams{
+			AppID:     record.AppID,
+			GuildID:   record.GuildID,
+			Data:      data,
+			CreatedAt: record.CreatedAt,
+			UpdatedAt: record.UpdatedAt,
+		})
+		if record.Unavailable {
+			b.unavailableGuilds = append(b.unavailableGuilds, record)
+		}
+	case cache.ChangeEntityTypeRole:
+		var data discord.Role
+		err := json.Unmarshal(record.Data, &data)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal role %s: %w", record.EntityID, err)
+		}
+		params.Roles = append(params.Roles, store.UpsertRoleParams{
+			AppID:     record.AppID,
+			GuildID:   record.GuildID,
+			RoleID:    record.EntityID,
+			Data:      data,
+			CreatedAt: record.CreatedAt,
+			UpdatedAt: record.UpdatedAt,
+		})
+	case cache.ChangeEntityTypeChannel:
+		var data discord.UnmarshalChannel
+		err := json.Unmarshal(record.Data, &data)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal channel %s: %w", record.EntityID, err)
+		}
+		params.Channels = append(params.Channels, store.UpsertChannelParams{
+			AppID:     record.AppID,
+			GuildID:   record.GuildID,
+			ChannelID: record.EntityID,
+			Data:      data.Channel,
+			CreatedAt: record.CreatedAt,
+			UpdatedAt: record.UpdatedAt,
+		})
+	case cache.ChangeEntityTypeEmoji:
+		var data discord.Emoji
+		err := json.Unmarshal(record.Data, &data)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal emoji %s: %w", record.EntityID, err)
+		}
+		params.Emojis = append(params.Emojis, store.UpsertEmojiParams{
+			AppID:     record.AppID,
+			GuildID:   record.GuildID,
+			EmojiID:   record.EntityID,
+			Data:      data,
+			CreatedAt: record.CreatedAt,
+			UpdatedAt: record.UpdatedAt,
+		})
+	case cache.ChangeEntityTypeSticker:
+		var data discord.Sticker
+		err := json.Unmarshal(record.Data, &data)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal sticker %s: %w", record.EntityID, err)
+		}
+		params.Stickers = append(params.Stickers, store.UpsertStickerParams{
+			AppID:     record.AppID,
+			GuildID:   record.GuildID,
+			StickerID: record.EntityID,
+			Data:      data,
+			CreatedAt: record.CreatedAt,
+			UpdatedAt: record.UpdatedAt,
+		})
+	default:
+		return fmt.Errorf("unknown record type: %s", record.Type)
+	}
+
+	b.size++
+	return nil
+}
+
+// flush upserts the collected records and resets the batch.
+func (b *importBatch) flush(ctx context.Context, cacheStore store.CacheStore, counts *Counts) error {
+	for appID, params := range b.apps {
+		err := cacheStore.MassUpsertEntities(ctx, *params)
+		if err != nil {
+			return fmt.Errorf("failed to import entities of app %s: %w", appID, err)
+		}
+
+		counts.Guilds += len(params.Guilds)
+		counts.Roles += len(params.Roles)
+		counts.Channels += len(params.Channels)
+		counts.Emojis += len(params.Emojis)
+		counts.Stickers += len(params.Stickers)
+	}
+
+	// The upsert params can't carry the unavailable flag, so it's restored once the guilds exist
+	for _, record := range b.unavailableGuilds {
+		err := cacheStore.MarkGuildUnavailable(ctx, record.AppID, record.GuildID)
+		if err != nil {
+			return fmt.Errorf("failed to import unavailable guild %s: %w", record.GuildID, err)
+		}
+	}
+
+	*b = *newImportBatch()
+	return nil
+}
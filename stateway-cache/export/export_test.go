@@ -0,0 +1,116 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/inmemory"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"github.com/merlinfuchs/stateway/stateway-lib/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newExportStore(t *testing.T) store.CacheStore {
+	cacheStore := inmemory.NewMapCacheStore()
+
+	err := cacheStore.MassUpsertEntities(context.Background(), store.MassUpsertEntitiesParams{
+		AppID: 1,
+		Guilds: []store.UpsertGuildParams{
+			{AppID: 1, GuildID: 10, Data: discord.Guild{ID: 10, Name: "Guild"}},
+			{AppID: 1, GuildID: 20, Data: discord.Guild{ID: 20, Name: "Other"}},
+		},
+		Roles: []store.UpsertRoleParams{
+			{AppID: 1, GuildID: 10, RoleID: 11, Data: discord.Role{ID: 11, Name: "Moderator"}},
+			{AppID: 1, GuildID: 20, RoleID: 21, Data: discord.Role{ID: 21}},
+		},
+		Channels: []store.UpsertChannelParams{
+			{AppID: 1, GuildID: 10, ChannelID: 12, Data: discord.GuildTextChannel{}},
+		},
+		Emojis: []store.UpsertEmojiParams{
+			{AppID: 1, GuildID: 10, EmojiID: 13, Data: discord.Emoji{ID: 13, Name: "emoji"}},
+		},
+	})
+	require.NoError(t, err)
+
+	err = cacheStore.MarkGuildUnavailable(context.Background(), 1, 20)
+	require.NoError(t, err)
+
+	return cacheStore
+}
+
+func TestExportGuild(t *testing.T) {
+	ctx := context.Background()
+	cacheStore := newExportStore(t)
+
+	var buf bytes.Buffer
+	counts, err := WriteEntities(ctx, cacheStore, &buf, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, Counts{Guilds: 1, Roles: 1, Channels: 1, Emojis: 1}, counts)
+
+	types := make([]cache.ChangeEntityType, 0)
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var record Record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		assert.Equal(t, snowflake.ID(1), record.AppID)
+		assert.Equal(t, snowflake.ID(10), record.GuildID)
+		types = append(types, record.Type)
+	}
+	assert.Equal(t, []cache.ChangeEntityType{
+		cache.ChangeEntityTypeGuild,
+		cache.ChangeEntityTypeRole,
+		cache.ChangeEntityTypeChannel,
+		cache.ChangeEntityTypeEmoji,
+	}, types)
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	cacheStore := newExportStore(t)
+
+	var buf bytes.Buffer
+	_, err := WriteEntities(ctx, cacheStore, &buf, 1, 0)
+	require.NoError(t, err)
+
+	target, err := inmemory.NewMemDBCacheStore()
+	require.NoError(t, err)
+
+	counts, err := ReadEntities(ctx, target, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, Counts{Guilds: 2, Roles: 2, Channels: 1, Emojis: 1}, counts)
+
+	guild, err := target.GetGuild(ctx, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "Guild", guild.Data.Name)
+	assert.False(t, guild.Unavailable)
+
+	guild, err = target.GetGuild(ctx, 1, 20)
+	require.NoError(t, err)
+	assert.True(t, guild.Unavailable)
+
+	role, err := target.GetRole(ctx, 1, 11)
+	require.NoError(t, err)
+	assert.Equal(t, "Moderator", role.Data.Name)
+
+	channel, err := target.GetChannel(ctx, 1, 12)
+	require.NoError(t, err)
+	assert.Equal(t, snowflake.ID(10), channel.GuildID)
+	assert.IsType(t, discord.GuildTextChannel{}, channel.Data)
+
+	emoji, err := target.GetEmoji(ctx, 1, 13)
+	require.NoError(t, err)
+	assert.Equal(t, "emoji", emoji.Data.Name)
+}
+
+func TestImportUnknownType(t *testing.T) {
+	target := inmemory.NewMapCacheStore()
+
+	_, err := ReadEntities(context.Background(), target, bytes.NewBufferString(`{"type": "member", "app_id": "1"}`))
+	assert.Error(t, err)
+}
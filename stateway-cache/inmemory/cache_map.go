@@ -1,8 +1,8 @@
 package inmemory
 
 import (
+	"cmp"
 	"context"
-	"fmt"
 	"slices"
 	"sync"
 	"time"
@@ -17,9 +17,10 @@ var _ store.CacheStore = (*MapCacheStore)(nil)
 // MapCacheStore is a thread-safe in-memory cache store implementation using Go maps.
 // It uses RWMutex for fine-grained locking and maintains multiple indexes for efficient queries.
 type MapCacheStore struct {
-	// Guilds: primary index by appID -> guildID
-	guildsMu sync.RWMutex
-	guilds   map[snowflake.ID]map[snowflake.ID]*model.Guild
+	// Guilds: primary index by appID -> guildID, app index by guildID -> appID
+	guildsMu  sync.RWMutex
+	guilds    map[snowflake.ID]map[snowflake.ID]*model.Guild
+	guildApps map[snowflake.ID]map[snowflake.ID]struct{}
 
 	// Channels: primary index by appID -> channelID, guild index by appID -> guildID -> channelID
 	channelsMu      sync.RWMutex
@@ -35,24 +36,31 @@ type MapCacheStore struct {
 	emojisMu      sync.RWMutex
 	emojis        map[snowflake.ID]map[snowflake.ID]*model.Emoji
 	emojisByGuild map[snowflake.ID]map[snowflake.ID]map[snowflake.ID]*model.Emoji
+	// When the emojis of each guild have last been replaced: appID -> guildID
+	emojiReplaces map[snowflake.ID]map[snowflake.ID]time.Time
 
 	// Stickers: primary index by appID -> stickerID, guild index by appID -> guildID -> stickerID
 	stickersMu      sync.RWMutex
 	stickers        map[snowflake.ID]map[snowflake.ID]*model.Sticker
 	stickersByGuild map[snowflake.ID]map[snowflake.ID]map[snowflake.ID]*model.Sticker
+	// When the stickers of each guild have last been replaced: appID -> guildID
+	stickerReplaces map[snowflake.ID]map[snowflake.ID]time.Time
 }
 
 func NewMapCacheStore() *MapCacheStore {
 	return &MapCacheStore{
 		guilds:          make(map[snowflake.ID]map[snowflake.ID]*model.Guild),
+		guildApps:       make(map[snowflake.ID]map[snowflake.ID]struct{}),
 		channels:        make(map[snowflake.ID]map[snowflake.ID]*model.Channel),
 		channelsByGuild: make(map[snowflake.ID]map[snowflake.ID]map[snowflake.ID]*model.Channel),
 		roles:           make(map[snowflake.ID]map[snowflake.ID]*model.Role),
 		rolesByGuild:    make(map[snowflake.ID]map[snowflake.ID]map[snowflake.ID]*model.Role),
 		emojis:          make(map[snowflake.ID]map[snowflake.ID]*model.Emoji),
 		emojisByGuild:   make(map[snowflake.ID]map[snowflake.ID]map[snowflake.ID]*model.Emoji),
+		emojiReplaces:   make(map[snowflake.ID]map[snowflake.ID]time.Time),
 		stickers:        make(map[snowflake.ID]map[snowflake.ID]*model.Sticker),
 		stickersByGuild: make(map[snowflake.ID]map[snowflake.ID]map[snowflake.ID]*model.Sticker),
+		stickerReplaces: make(map[snowflake.ID]map[snowflake.ID]time.Time),
 	}
 }
 
@@ -75,6 +83,72 @@ func (s *MapCacheStore) GetGuild(ctx context.Context, appID snowflake.ID, guildI
 	return guild, nil
 }
 
+// GetFullGuild holds the read locks of all entity types at once, so no write can happen in between reading them.
+// The locks are always acquired in the same order and writers only ever hold one of them.
+func (s *MapCacheStore) GetFullGuild(ctx context.Context, params store.GetFullGuildParams) (*model.FullGuild, error) {
+	s.guildsMu.RLock()
+	defer s.guildsMu.RUnlock()
+	s.channelsMu.RLock()
+	defer s.channelsMu.RUnlock()
+	s.rolesMu.RLock()
+	defer s.rolesMu.RUnlock()
+	s.emojisMu.RLock()
+	defer s.emojisMu.RUnlock()
+	s.stickersMu.RLock()
+	defer s.stickersMu.RUnlock()
+
+	guild, ok := s.guilds[params.AppID][params.GuildID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	res := &model.FullGuild{Guild: *guild}
+	if params.Include.Channels {
+		res.Channels = guildEntitiesLocked(s.channelsByGuild, params, channelEntityID, func(channel *model.Channel) bool {
+			return channel.Tainted
+		})
+	}
+	if params.Include.Roles {
+		res.Roles = guildEntitiesLocked(s.rolesByGuild, params, roleEntityID, func(role *model.Role) bool {
+			return role.Tainted
+		})
+	}
+	if params.Include.Emojis {
+		res.Emojis = guildEntitiesLocked(s.emojisByGuild, params, emojiEntityID, func(emoji *model.Emoji) bool {
+			return emoji.Tainted
+		})
+	}
+	if params.Include.Stickers {
+		res.Stickers = guildEntitiesLocked(s.stickersByGuild, params, stickerEntityID, func(sticker *model.Sticker) bool {
+			return sticker.Tainted
+		})
+	}
+
+	return res, nil
+}
+
+// guildEntitiesLocked returns all entities of the guild ordered by ID.
+// The caller must hold the lock guarding the index.
+func guildEntitiesLocked[T any](
+	entitiesByGuild map[snowflake.ID]map[snowflake.ID]map[snowflake.ID]*T,
+	params store.GetFullGuildParams,
+	id func(*T) snowflake.ID,
+	isTainted func(*T) bool,
+) []*T {
+	entities := entitiesByGuild[params.AppID][params.GuildID]
+
+	result := make([]*T, 0, len(entities))
+	for _, entity := range entities {
+		if params.ExcludeTainted && isTainted(entity) {
+			continue
+		}
+
+		result = append(result, entity)
+	}
+
+	return pageEntities(result, store.ListOptions{}, id)
+}
+
 func (s *MapCacheStore) GetGuildOwnerID(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (snowflake.ID, error) {
 	guild, err := s.GetGuild(ctx, appID, guildID)
 	if err != nil {
@@ -83,7 +157,7 @@ func (s *MapCacheStore) GetGuildOwnerID(ctx context.Context, appID snowflake.ID,
 	return guild.Data.OwnerID, nil
 }
 
-func (s *MapCacheStore) GetGuilds(ctx context.Context, appID snowflake.ID, limit int, offset int) ([]*model.Guild, error) {
+func (s *MapCacheStore) GetGuilds(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Guild, error) {
 	s.guildsMu.RLock()
 	defer s.guildsMu.RUnlock()
 
@@ -92,26 +166,32 @@ func (s *MapCacheStore) GetGuilds(ctx context.Context, appID snowflake.ID, limit
 		return []*model.Guild{}, nil
 	}
 
-	guilds := make([]*model.Guild, 0)
-	if limit > 0 {
-		guilds = make([]*model.Guild, 0, limit)
-	}
-
-	currentOffset := 0
+	guilds := make([]*model.Guild, 0, len(appGuilds))
 	for _, guild := range appGuilds {
-		if currentOffset < offset {
-			currentOffset++
+		if opts.ExcludeTainted && guild.Tainted {
 			continue
 		}
 
 		guilds = append(guilds, guild)
+	}
+
+	return pageEntities(guilds, opts, guildEntityID), nil
+}
+
+func (s *MapCacheStore) GetGuildsByIDs(ctx context.Context, appID snowflake.ID, guildIDs []snowflake.ID) ([]*model.Guild, error) {
+	s.guildsMu.RLock()
+	defer s.guildsMu.RUnlock()
 
-		if limit > 0 && len(guilds) >= limit {
-			break
+	appGuilds := s.guilds[appID]
+
+	result := make([]*model.Guild, 0, len(guildIDs))
+	for _, guildID := range guildIDs {
+		if guild, ok := appGuilds[guildID]; ok {
+			result = append(result, guild)
 		}
 	}
 
-	return guilds, nil
+	return result, nil
 }
 
 func (s *MapCacheStore) CheckGuildExist(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (bool, error) {
@@ -127,10 +207,47 @@ func (s *MapCacheStore) CheckGuildExist(ctx context.Context, appID snowflake.ID,
 	return ok, nil
 }
 
-func (s *MapCacheStore) UpsertGuilds(ctx context.Context, guilds ...store.UpsertGuildParams) error {
+func (s *MapCacheStore) GetGuildApps(ctx context.Context, guildID snowflake.ID) ([]*model.GuildApp, error) {
+	s.guildsMu.RLock()
+	defer s.guildsMu.RUnlock()
+
+	apps := make([]*model.GuildApp, 0, len(s.guildApps[guildID]))
+	for appID := range s.guildApps[guildID] {
+		guild := s.guilds[appID][guildID]
+		apps = append(apps, &model.GuildApp{
+			AppID:       appID,
+			Unavailable: guild.Unavailable,
+			Tainted:     guild.Tainted,
+			UpdatedAt:   guild.UpdatedAt,
+		})
+	}
+	slices.SortFunc(apps, func(a, b *model.GuildApp) int {
+		return cmp.Compare(a.AppID, b.AppID)
+	})
+	return apps, nil
+}
+
+// indexGuildApp adds the app to the apps of the guild, the caller must hold the guilds lock.
+func (s *MapCacheStore) indexGuildApp(appID snowflake.ID, guildID snowflake.ID) {
+	if s.guildApps[guildID] == nil {
+		s.guildApps[guildID] = make(map[snowflake.ID]struct{})
+	}
+	s.guildApps[guildID][appID] = struct{}{}
+}
+
+// unindexGuildApp removes the app from the apps of the guild, the caller must hold the guilds lock.
+func (s *MapCacheStore) unindexGuildApp(appID snowflake.ID, guildID snowflake.ID) {
+	delete(s.guildApps[guildID], appID)
+	if len(s.guildApps[guildID]) == 0 {
+		delete(s.guildApps, guildID)
+	}
+}
+
+func (s *MapCacheStore) UpsertGuilds(ctx context.Context, guilds ...store.UpsertGuildParams) (int, error) {
 	s.guildsMu.Lock()
 	defer s.guildsMu.Unlock()
 
+	written := 0
 	for _, guild := range guilds {
 		if s.guilds[guild.AppID] == nil {
 			s.guilds[guild.AppID] = make(map[snowflake.ID]*model.Guild)
@@ -144,6 +261,9 @@ func (s *MapCacheStore) UpsertGuilds(ctx context.Context, guilds ...store.Upsert
 		if updatedAt.IsZero() {
 			updatedAt = time.Now().UTC()
 		}
+		if existing, ok := s.guilds[guild.AppID][guild.GuildID]; ok && existing.UpdatedAt.After(updatedAt) {
+			continue
+		}
 
 		s.guilds[guild.AppID][guild.GuildID] = &model.Guild{
 			AppID:     guild.AppID,
@@ -152,9 +272,11 @@ func (s *MapCacheStore) UpsertGuilds(ctx context.Context, guilds ...store.Upsert
 			CreatedAt: createdAt,
 			UpdatedAt: updatedAt,
 		}
+		s.indexGuildApp(guild.AppID, guild.GuildID)
+		written++
 	}
 
-	return nil
+	return written, nil
 }
 
 func (s *MapCacheStore) MarkGuildUnavailable(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) error {
@@ -172,7 +294,6 @@ func (s *MapCacheStore) MarkGuildUnavailable(ctx context.Context, appID snowflak
 	}
 
 	guild.Unavailable = true
-	guild.UpdatedAt = time.Now().UTC()
 
 	return nil
 }
@@ -195,12 +316,17 @@ func (s *MapCacheStore) DeleteGuild(ctx context.Context, appID snowflake.ID, gui
 	if len(appGuilds) == 0 {
 		delete(s.guilds, appID)
 	}
+	s.unindexGuildApp(appID, guildID)
 
 	return nil
 }
 
 func (s *MapCacheStore) SearchGuilds(ctx context.Context, params store.SearchGuildsParams) ([]*model.Guild, error) {
-	return nil, fmt.Errorf("not implemented")
+	guilds, err := s.GetGuilds(ctx, params.AppID, store.ListOptions{ExcludeTainted: params.ExcludeTainted, After: params.After})
+	if err != nil {
+		return nil, err
+	}
+	return store.SearchEntities(guilds, params.Data, params.Filter, params.ListOptions, guildData)
 }
 
 // CacheRoleStore methods
@@ -244,7 +370,7 @@ func (s *MapCacheStore) GetRole(ctx context.Context, appID snowflake.ID, roleID
 	return role, nil
 }
 
-func (s *MapCacheStore) GetGuildRoles(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, limit int, offset int) ([]*model.Role, error) {
+func (s *MapCacheStore) GetGuildRoles(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Role, error) {
 	s.rolesMu.RLock()
 	defer s.rolesMu.RUnlock()
 
@@ -258,26 +384,16 @@ func (s *MapCacheStore) GetGuildRoles(ctx context.Context, appID snowflake.ID, g
 		return []*model.Role{}, nil
 	}
 
-	result := make([]*model.Role, 0)
-	if limit > 0 {
-		result = make([]*model.Role, 0, limit)
-	}
-
-	currentOffset := 0
+	result := make([]*model.Role, 0, len(roles))
 	for _, role := range roles {
-		if currentOffset < offset {
-			currentOffset++
+		if opts.ExcludeTainted && role.Tainted {
 			continue
 		}
 
 		result = append(result, role)
-
-		if limit > 0 && len(result) >= limit {
-			break
-		}
 	}
 
-	return result, nil
+	return pageEntities(result, opts, roleEntityID), nil
 }
 
 func (s *MapCacheStore) GetGuildRolesByIDs(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, roleIDs []snowflake.ID) ([]*model.Role, error) {
@@ -304,7 +420,7 @@ func (s *MapCacheStore) GetGuildRolesByIDs(ctx context.Context, appID snowflake.
 	return result, nil
 }
 
-func (s *MapCacheStore) GetRoles(ctx context.Context, appID snowflake.ID, limit int, offset int) ([]*model.Role, error) {
+func (s *MapCacheStore) GetRoles(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Role, error) {
 	s.rolesMu.RLock()
 	defer s.rolesMu.RUnlock()
 
@@ -313,34 +429,48 @@ func (s *MapCacheStore) GetRoles(ctx context.Context, appID snowflake.ID, limit
 		return []*model.Role{}, nil
 	}
 
-	roles := make([]*model.Role, 0)
-	if limit > 0 {
-		roles = make([]*model.Role, 0, limit)
-	}
-
-	currentOffset := 0
+	roles := make([]*model.Role, 0, len(appRoles))
 	for _, role := range appRoles {
-		if currentOffset < offset {
-			currentOffset++
+		if opts.ExcludeTainted && role.Tainted {
 			continue
 		}
 
 		roles = append(roles, role)
+	}
+
+	return pageEntities(roles, opts, roleEntityID), nil
+}
+
+func (s *MapCacheStore) GetRolesByIDs(ctx context.Context, appID snowflake.ID, roleIDs []snowflake.ID) ([]*model.Role, error) {
+	s.rolesMu.RLock()
+	defer s.rolesMu.RUnlock()
 
-		if limit > 0 && len(roles) >= limit {
-			break
+	appRoles := s.roles[appID]
+
+	result := make([]*model.Role, 0, len(roleIDs))
+	for _, roleID := range roleIDs {
+		if role, ok := appRoles[roleID]; ok {
+			result = append(result, role)
 		}
 	}
 
-	return roles, nil
+	return result, nil
 }
 
 func (s *MapCacheStore) SearchGuildRoles(ctx context.Context, params store.SearchGuildRolesParams) ([]*model.Role, error) {
-	return nil, fmt.Errorf("not implemented")
+	roles, err := s.GetGuildRoles(ctx, params.AppID, params.GuildID, store.ListOptions{ExcludeTainted: params.ExcludeTainted, After: params.After})
+	if err != nil {
+		return nil, err
+	}
+	return store.SearchEntities(roles, params.Data, params.Filter, params.ListOptions, roleData)
 }
 
 func (s *MapCacheStore) SearchRoles(ctx context.Context, params store.SearchRolesParams) ([]*model.Role, error) {
-	return nil, fmt.Errorf("not implemented")
+	roles, err := s.GetRoles(ctx, params.AppID, store.ListOptions{ExcludeTainted: params.ExcludeTainted, After: params.After})
+	if err != nil {
+		return nil, err
+	}
+	return store.SearchEntities(roles, params.Data, params.Filter, params.ListOptions, roleData)
 }
 
 func (s *MapCacheStore) CountGuildRoles(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (int, error) {
@@ -372,10 +502,11 @@ func (s *MapCacheStore) CountRoles(ctx context.Context, appID snowflake.ID) (int
 	return len(appRoles), nil
 }
 
-func (s *MapCacheStore) UpsertRoles(ctx context.Context, roles ...store.UpsertRoleParams) error {
+func (s *MapCacheStore) UpsertRoles(ctx context.Context, roles ...store.UpsertRoleParams) (int, error) {
 	s.rolesMu.Lock()
 	defer s.rolesMu.Unlock()
 
+	written := 0
 	for _, role := range roles {
 		if s.roles[role.AppID] == nil {
 			s.roles[role.AppID] = make(map[snowflake.ID]*model.Role)
@@ -395,6 +526,9 @@ func (s *MapCacheStore) UpsertRoles(ctx context.Context, roles ...store.UpsertRo
 		if updatedAt.IsZero() {
 			updatedAt = time.Now().UTC()
 		}
+		if existing, ok := s.roles[role.AppID][role.RoleID]; ok && existing.UpdatedAt.After(updatedAt) {
+			continue
+		}
 
 		r := &model.Role{
 			AppID:     role.AppID,
@@ -407,9 +541,10 @@ func (s *MapCacheStore) UpsertRoles(ctx context.Context, roles ...store.UpsertRo
 
 		s.roles[role.AppID][role.RoleID] = r
 		s.rolesByGuild[role.AppID][role.GuildID][role.RoleID] = r
+		written++
 	}
 
-	return nil
+	return written, nil
 }
 
 func (s *MapCacheStore) DeleteRole(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, roleID snowflake.ID) error {
@@ -489,7 +624,7 @@ func (s *MapCacheStore) GetChannel(ctx context.Context, appID snowflake.ID, chan
 	return channel, nil
 }
 
-func (s *MapCacheStore) GetGuildChannels(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, limit int, offset int) ([]*model.Channel, error) {
+func (s *MapCacheStore) GetGuildChannels(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Channel, error) {
 	s.channelsMu.RLock()
 	defer s.channelsMu.RUnlock()
 
@@ -503,29 +638,19 @@ func (s *MapCacheStore) GetGuildChannels(ctx context.Context, appID snowflake.ID
 		return []*model.Channel{}, nil
 	}
 
-	result := make([]*model.Channel, 0)
-	if limit > 0 {
-		result = make([]*model.Channel, 0, limit)
-	}
-
-	currentOffset := 0
+	result := make([]*model.Channel, 0, len(channels))
 	for _, channel := range channels {
-		if currentOffset < offset {
-			currentOffset++
+		if opts.ExcludeTainted && channel.Tainted {
 			continue
 		}
 
 		result = append(result, channel)
-
-		if limit > 0 && len(result) >= limit {
-			break
-		}
 	}
 
-	return result, nil
+	return pageEntities(result, opts, channelEntityID), nil
 }
 
-func (s *MapCacheStore) GetChannels(ctx context.Context, appID snowflake.ID, limit int, offset int) ([]*model.Channel, error) {
+func (s *MapCacheStore) GetChannels(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Channel, error) {
 	s.channelsMu.RLock()
 	defer s.channelsMu.RUnlock()
 
@@ -534,29 +659,35 @@ func (s *MapCacheStore) GetChannels(ctx context.Context, appID snowflake.ID, lim
 		return []*model.Channel{}, nil
 	}
 
-	channels := make([]*model.Channel, 0)
-	if limit > 0 {
-		channels = make([]*model.Channel, 0, limit)
-	}
-
-	currentOffset := 0
+	channels := make([]*model.Channel, 0, len(appChannels))
 	for _, channel := range appChannels {
-		if currentOffset < offset {
-			currentOffset++
+		if opts.ExcludeTainted && channel.Tainted {
 			continue
 		}
 
 		channels = append(channels, channel)
+	}
+
+	return pageEntities(channels, opts, channelEntityID), nil
+}
+
+func (s *MapCacheStore) GetChannelsByIDs(ctx context.Context, appID snowflake.ID, channelIDs []snowflake.ID) ([]*model.Channel, error) {
+	s.channelsMu.RLock()
+	defer s.channelsMu.RUnlock()
 
-		if limit > 0 && len(channels) >= limit {
-			break
+	appChannels := s.channels[appID]
+
+	result := make([]*model.Channel, 0, len(channelIDs))
+	for _, channelID := range channelIDs {
+		if channel, ok := appChannels[channelID]; ok {
+			result = append(result, channel)
 		}
 	}
 
-	return channels, nil
+	return result, nil
 }
 
-func (s *MapCacheStore) GetChannelsByType(ctx context.Context, appID snowflake.ID, types []int, limit int, offset int) ([]*model.Channel, error) {
+func (s *MapCacheStore) GetChannelsByType(ctx context.Context, appID snowflake.ID, types []int, opts store.ListOptions) ([]*model.Channel, error) {
 	s.channelsMu.RLock()
 	defer s.channelsMu.RUnlock()
 
@@ -565,38 +696,36 @@ func (s *MapCacheStore) GetChannelsByType(ctx context.Context, appID snowflake.I
 		return []*model.Channel{}, nil
 	}
 
-	channels := make([]*model.Channel, 0)
-	if limit > 0 {
-		channels = make([]*model.Channel, 0, limit)
-	}
-
-	currentOffset := 0
+	channels := make([]*model.Channel, 0, len(appChannels))
 	for _, channel := range appChannels {
 		if !slices.Contains(types, int(channel.Data.Type())) {
 			continue
 		}
 
-		if currentOffset < offset {
-			currentOffset++
+		if opts.ExcludeTainted && channel.Tainted {
 			continue
 		}
 
 		channels = append(channels, channel)
-
-		if limit > 0 && len(channels) >= limit {
-			break
-		}
 	}
 
-	return channels, nil
+	return pageEntities(channels, opts, channelEntityID), nil
 }
 
 func (s *MapCacheStore) SearchGuildChannels(ctx context.Context, params store.SearchGuildChannelsParams) ([]*model.Channel, error) {
-	return nil, fmt.Errorf("not implemented")
+	channels, err := s.GetGuildChannels(ctx, params.AppID, params.GuildID, store.ListOptions{ExcludeTainted: params.ExcludeTainted, After: params.After})
+	if err != nil {
+		return nil, err
+	}
+	return store.SearchEntities(channels, params.Data, params.Filter, params.ListOptions, channelData)
 }
 
 func (s *MapCacheStore) SearchChannels(ctx context.Context, params store.SearchChannelsParams) ([]*model.Channel, error) {
-	return nil, fmt.Errorf("not implemented")
+	channels, err := s.GetChannels(ctx, params.AppID, store.ListOptions{ExcludeTainted: params.ExcludeTainted, After: params.After})
+	if err != nil {
+		return nil, err
+	}
+	return store.SearchEntities(channels, params.Data, params.Filter, params.ListOptions, channelData)
 }
 
 func (s *MapCacheStore) CountGuildChannels(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (int, error) {
@@ -628,10 +757,11 @@ func (s *MapCacheStore) CountChannels(ctx context.Context, appID snowflake.ID) (
 	return len(appChannels), nil
 }
 
-func (s *MapCacheStore) UpsertChannels(ctx context.Context, channels ...store.UpsertChannelParams) error {
+func (s *MapCacheStore) UpsertChannels(ctx context.Context, channels ...store.UpsertChannelParams) (int, error) {
 	s.channelsMu.Lock()
 	defer s.channelsMu.Unlock()
 
+	written := 0
 	for _, channel := range channels {
 		if s.channels[channel.AppID] == nil {
 			s.channels[channel.AppID] = make(map[snowflake.ID]*model.Channel)
@@ -651,6 +781,9 @@ func (s *MapCacheStore) UpsertChannels(ctx context.Context, channels ...store.Up
 		if updatedAt.IsZero() {
 			updatedAt = time.Now().UTC()
 		}
+		if existing, ok := s.channels[channel.AppID][channel.ChannelID]; ok && existing.UpdatedAt.After(updatedAt) {
+			continue
+		}
 
 		c := &model.Channel{
 			AppID:     channel.AppID,
@@ -663,9 +796,10 @@ func (s *MapCacheStore) UpsertChannels(ctx context.Context, channels ...store.Up
 
 		s.channels[channel.AppID][channel.ChannelID] = c
 		s.channelsByGuild[channel.AppID][channel.GuildID][channel.ChannelID] = c
+		written++
 	}
 
-	return nil
+	return written, nil
 }
 
 func (s *MapCacheStore) DeleteChannel(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, channelID snowflake.ID) error {
@@ -745,7 +879,7 @@ func (s *MapCacheStore) GetEmoji(ctx context.Context, appID snowflake.ID, emojiI
 	return emoji, nil
 }
 
-func (s *MapCacheStore) GetGuildEmojis(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, limit int, offset int) ([]*model.Emoji, error) {
+func (s *MapCacheStore) GetGuildEmojis(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Emoji, error) {
 	s.emojisMu.RLock()
 	defer s.emojisMu.RUnlock()
 
@@ -759,29 +893,19 @@ func (s *MapCacheStore) GetGuildEmojis(ctx context.Context, appID snowflake.ID,
 		return []*model.Emoji{}, nil
 	}
 
-	result := make([]*model.Emoji, 0)
-	if limit > 0 {
-		result = make([]*model.Emoji, 0, limit)
-	}
-
-	currentOffset := 0
+	result := make([]*model.Emoji, 0, len(emojis))
 	for _, emoji := range emojis {
-		if currentOffset < offset {
-			currentOffset++
+		if opts.ExcludeTainted && emoji.Tainted {
 			continue
 		}
 
 		result = append(result, emoji)
-
-		if limit > 0 && len(result) >= limit {
-			break
-		}
 	}
 
-	return result, nil
+	return pageEntities(result, opts, emojiEntityID), nil
 }
 
-func (s *MapCacheStore) GetEmojis(ctx context.Context, appID snowflake.ID, limit int, offset int) ([]*model.Emoji, error) {
+func (s *MapCacheStore) GetEmojis(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Emoji, error) {
 	s.emojisMu.RLock()
 	defer s.emojisMu.RUnlock()
 
@@ -790,34 +914,48 @@ func (s *MapCacheStore) GetEmojis(ctx context.Context, appID snowflake.ID, limit
 		return []*model.Emoji{}, nil
 	}
 
-	emojis := make([]*model.Emoji, 0)
-	if limit > 0 {
-		emojis = make([]*model.Emoji, 0, limit)
-	}
-
-	currentOffset := 0
+	emojis := make([]*model.Emoji, 0, len(appEmojis))
 	for _, emoji := range appEmojis {
-		if currentOffset < offset {
-			currentOffset++
+		if opts.ExcludeTainted && emoji.Tainted {
 			continue
 		}
 
 		emojis = append(emojis, emoji)
+	}
+
+	return pageEntities(emojis, opts, emojiEntityID), nil
+}
 
-		if limit > 0 && len(emojis) >= limit {
-			break
+func (s *MapCacheStore) GetEmojisByIDs(ctx context.Context, appID snowflake.ID, emojiIDs []snowflake.ID) ([]*model.Emoji, error) {
+	s.emojisMu.RLock()
+	defer s.emojisMu.RUnlock()
+
+	appEmojis := s.emojis[appID]
+
+	result := make([]*model.Emoji, 0, len(emojiIDs))
+	for _, emojiID := range emojiIDs {
+		if emoji, ok := appEmojis[emojiID]; ok {
+			result = append(result, emoji)
 		}
 	}
 
-	return emojis, nil
+	return result, nil
 }
 
 func (s *MapCacheStore) SearchGuildEmojis(ctx context.Context, params store.SearchGuildEmojisParams) ([]*model.Emoji, error) {
-	return nil, fmt.Errorf("not implemented")
+	emojis, err := s.GetGuildEmojis(ctx, params.AppID, params.GuildID, store.ListOptions{ExcludeTainted: params.ExcludeTainted, After: params.After})
+	if err != nil {
+		return nil, err
+	}
+	return store.SearchEntities(emojis, params.Data, params.Filter, params.ListOptions, emojiData)
 }
 
 func (s *MapCacheStore) SearchEmojis(ctx context.Context, params store.SearchEmojisParams) ([]*model.Emoji, error) {
-	return nil, fmt.Errorf("not implemented")
+	emojis, err := s.GetEmojis(ctx, params.AppID, store.ListOptions{ExcludeTainted: params.ExcludeTainted, After: params.After})
+	if err != nil {
+		return nil, err
+	}
+	return store.SearchEntities(emojis, params.Data, params.Filter, params.ListOptions, emojiData)
 }
 
 func (s *MapCacheStore) CountGuildEmojis(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (int, error) {
@@ -849,10 +987,56 @@ func (s *MapCacheStore) CountEmojis(ctx context.Context, appID snowflake.ID) (in
 	return len(appEmojis), nil
 }
 
-func (s *MapCacheStore) UpsertEmojis(ctx context.Context, emojis ...store.UpsertEmojiParams) error {
+func (s *MapCacheStore) UpsertEmojis(ctx context.Context, emojis ...store.UpsertEmojiParams) (int, error) {
+	s.emojisMu.Lock()
+	defer s.emojisMu.Unlock()
+
+	return s.upsertEmojisLocked(emojis), nil
+}
+
+func (s *MapCacheStore) ReplaceGuildEmojis(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, updatedAt time.Time, emojis ...store.UpsertEmojiParams) (bool, error) {
 	s.emojisMu.Lock()
 	defer s.emojisMu.Unlock()
 
+	if !markGuildReplaced(s.emojiReplaces, appID, guildID, updatedAt) {
+		return false, nil
+	}
+
+	keep := make(map[snowflake.ID]struct{}, len(emojis))
+	for _, emoji := range emojis {
+		keep[emoji.EmojiID] = struct{}{}
+	}
+
+	if guildEmojis, ok := s.emojisByGuild[appID]; ok {
+		for emojiID := range guildEmojis[guildID] {
+			if _, ok := keep[emojiID]; ok || guildEmojis[guildID][emojiID].UpdatedAt.After(updatedAt) {
+				continue
+			}
+
+			delete(guildEmojis[guildID], emojiID)
+			if appEmojis, ok := s.emojis[appID]; ok {
+				delete(appEmojis, emojiID)
+				if len(appEmojis) == 0 {
+					delete(s.emojis, appID)
+				}
+			}
+		}
+
+		if len(guildEmojis[guildID]) == 0 {
+			delete(guildEmojis, guildID)
+			if len(guildEmojis) == 0 {
+				delete(s.emojisByGuild, appID)
+			}
+		}
+	}
+
+	s.upsertEmojisLocked(emojis)
+	return true, nil
+}
+
+// upsertEmojisLocked expects the caller to hold s.emojisMu and returns how many emojis have been written.
+func (s *MapCacheStore) upsertEmojisLocked(emojis []store.UpsertEmojiParams) int {
+	written := 0
 	for _, emoji := range emojis {
 		if s.emojis[emoji.AppID] == nil {
 			s.emojis[emoji.AppID] = make(map[snowflake.ID]*model.Emoji)
@@ -872,6 +1056,9 @@ func (s *MapCacheStore) UpsertEmojis(ctx context.Context, emojis ...store.Upsert
 		if updatedAt.IsZero() {
 			updatedAt = time.Now().UTC()
 		}
+		if existing, ok := s.emojis[emoji.AppID][emoji.EmojiID]; ok && existing.UpdatedAt.After(updatedAt) {
+			continue
+		}
 
 		e := &model.Emoji{
 			AppID:     emoji.AppID,
@@ -884,9 +1071,9 @@ func (s *MapCacheStore) UpsertEmojis(ctx context.Context, emojis ...store.Upsert
 
 		s.emojis[emoji.AppID][emoji.EmojiID] = e
 		s.emojisByGuild[emoji.AppID][emoji.GuildID][emoji.EmojiID] = e
+		written++
 	}
-
-	return nil
+	return written
 }
 
 func (s *MapCacheStore) DeleteEmoji(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, emojiID snowflake.ID) error {
@@ -953,7 +1140,7 @@ func (s *MapCacheStore) GetGuildSticker(ctx context.Context, appID snowflake.ID,
 	return s.GetSticker(ctx, appID, guildID, stickerID)
 }
 
-func (s *MapCacheStore) GetStickers(ctx context.Context, appID snowflake.ID, limit int, offset int) ([]*model.Sticker, error) {
+func (s *MapCacheStore) GetStickers(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Sticker, error) {
 	s.stickersMu.RLock()
 	defer s.stickersMu.RUnlock()
 
@@ -962,29 +1149,35 @@ func (s *MapCacheStore) GetStickers(ctx context.Context, appID snowflake.ID, lim
 		return []*model.Sticker{}, nil
 	}
 
-	stickers := make([]*model.Sticker, 0)
-	if limit > 0 {
-		stickers = make([]*model.Sticker, 0, limit)
-	}
-
-	currentOffset := 0
+	stickers := make([]*model.Sticker, 0, len(appStickers))
 	for _, sticker := range appStickers {
-		if currentOffset < offset {
-			currentOffset++
+		if opts.ExcludeTainted && sticker.Tainted {
 			continue
 		}
 
 		stickers = append(stickers, sticker)
+	}
+
+	return pageEntities(stickers, opts, stickerEntityID), nil
+}
+
+func (s *MapCacheStore) GetStickersByIDs(ctx context.Context, appID snowflake.ID, stickerIDs []snowflake.ID) ([]*model.Sticker, error) {
+	s.stickersMu.RLock()
+	defer s.stickersMu.RUnlock()
 
-		if limit > 0 && len(stickers) >= limit {
-			break
+	appStickers := s.stickers[appID]
+
+	result := make([]*model.Sticker, 0, len(stickerIDs))
+	for _, stickerID := range stickerIDs {
+		if sticker, ok := appStickers[stickerID]; ok {
+			result = append(result, sticker)
 		}
 	}
 
-	return stickers, nil
+	return result, nil
 }
 
-func (s *MapCacheStore) GetGuildStickers(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, limit int, offset int) ([]*model.Sticker, error) {
+func (s *MapCacheStore) GetGuildStickers(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Sticker, error) {
 	s.stickersMu.RLock()
 	defer s.stickersMu.RUnlock()
 
@@ -998,34 +1191,32 @@ func (s *MapCacheStore) GetGuildStickers(ctx context.Context, appID snowflake.ID
 		return []*model.Sticker{}, nil
 	}
 
-	result := make([]*model.Sticker, 0)
-	if limit > 0 {
-		result = make([]*model.Sticker, 0, limit)
-	}
-
-	currentOffset := 0
+	result := make([]*model.Sticker, 0, len(stickers))
 	for _, sticker := range stickers {
-		if currentOffset < offset {
-			currentOffset++
+		if opts.ExcludeTainted && sticker.Tainted {
 			continue
 		}
 
 		result = append(result, sticker)
-
-		if limit > 0 && len(result) >= limit {
-			break
-		}
 	}
 
-	return result, nil
+	return pageEntities(result, opts, stickerEntityID), nil
 }
 
 func (s *MapCacheStore) SearchStickers(ctx context.Context, params store.SearchStickersParams) ([]*model.Sticker, error) {
-	return nil, fmt.Errorf("not implemented")
+	stickers, err := s.GetStickers(ctx, params.AppID, store.ListOptions{ExcludeTainted: params.ExcludeTainted, After: params.After})
+	if err != nil {
+		return nil, err
+	}
+	return store.SearchEntities(stickers, params.Data, params.Filter, params.ListOptions, stickerData)
 }
 
 func (s *MapCacheStore) SearchGuildStickers(ctx context.Context, params store.SearchGuildStickersParams) ([]*model.Sticker, error) {
-	return nil, fmt.Errorf("not implemented")
+	stickers, err := s.GetGuildStickers(ctx, params.AppID, params.GuildID, store.ListOptions{ExcludeTainted: params.ExcludeTainted, After: params.After})
+	if err != nil {
+		return nil, err
+	}
+	return store.SearchEntities(stickers, params.Data, params.Filter, params.ListOptions, stickerData)
 }
 
 func (s *MapCacheStore) CountGuildStickers(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (int, error) {
@@ -1057,10 +1248,56 @@ func (s *MapCacheStore) CountStickers(ctx context.Context, appID snowflake.ID) (
 	return len(appStickers), nil
 }
 
-func (s *MapCacheStore) UpsertStickers(ctx context.Context, stickers ...store.UpsertStickerParams) error {
+func (s *MapCacheStore) UpsertStickers(ctx context.Context, stickers ...store.UpsertStickerParams) (int, error) {
+	s.stickersMu.Lock()
+	defer s.stickersMu.Unlock()
+
+	return s.upsertStickersLocked(stickers), nil
+}
+
+func (s *MapCacheStore) ReplaceGuildStickers(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, updatedAt time.Time, stickers ...store.UpsertStickerParams) (bool, error) {
 	s.stickersMu.Lock()
 	defer s.stickersMu.Unlock()
 
+	if !markGuildReplaced(s.stickerReplaces, appID, guildID, updatedAt) {
+		return false, nil
+	}
+
+	keep := make(map[snowflake.ID]struct{}, len(stickers))
+	for _, sticker := range stickers {
+		keep[sticker.StickerID] = struct{}{}
+	}
+
+	if guildStickers, ok := s.stickersByGuild[appID]; ok {
+		for stickerID := range guildStickers[guildID] {
+			if _, ok := keep[stickerID]; ok || guildStickers[guildID][stickerID].UpdatedAt.After(updatedAt) {
+				continue
+			}
+
+			delete(guildStickers[guildID], stickerID)
+			if appStickers, ok := s.stickers[appID]; ok {
+				delete(appStickers, stickerID)
+				if len(appStickers) == 0 {
+					delete(s.stickers, appID)
+				}
+			}
+		}
+
+		if len(guildStickers[guildID]) == 0 {
+			delete(guildStickers, guildID)
+			if len(guildStickers) == 0 {
+				delete(s.stickersByGuild, appID)
+			}
+		}
+	}
+
+	s.upsertStickersLocked(stickers)
+	return true, nil
+}
+
+// upsertStickersLocked expects the caller to hold s.stickersMu and returns how many stickers have been written.
+func (s *MapCacheStore) upsertStickersLocked(stickers []store.UpsertStickerParams) int {
+	written := 0
 	for _, sticker := range stickers {
 		if s.stickers[sticker.AppID] == nil {
 			s.stickers[sticker.AppID] = make(map[snowflake.ID]*model.Sticker)
@@ -1080,6 +1317,9 @@ func (s *MapCacheStore) UpsertStickers(ctx context.Context, stickers ...store.Up
 		if updatedAt.IsZero() {
 			updatedAt = time.Now().UTC()
 		}
+		if existing, ok := s.stickers[sticker.AppID][sticker.StickerID]; ok && existing.UpdatedAt.After(updatedAt) {
+			continue
+		}
 
 		st := &model.Sticker{
 			AppID:     sticker.AppID,
@@ -1092,9 +1332,9 @@ func (s *MapCacheStore) UpsertStickers(ctx context.Context, stickers ...store.Up
 
 		s.stickers[sticker.AppID][sticker.StickerID] = st
 		s.stickersByGuild[sticker.AppID][sticker.GuildID][sticker.StickerID] = st
+		written++
 	}
-
-	return nil
+	return written
 }
 
 func (s *MapCacheStore) DeleteSticker(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, stickerID snowflake.ID) error {
@@ -1136,40 +1376,169 @@ func (s *MapCacheStore) DeleteSticker(ctx context.Context, appID snowflake.ID, g
 // CacheStore methods
 
 func (s *MapCacheStore) MarkShardEntitiesTainted(ctx context.Context, params store.MarkShardEntitiesTaintedParams) error {
-	// Not implemented for in-memory store
+	s.guildsMu.Lock()
+	for guildID, guild := range s.guilds[params.AppID] {
+		if params.InShard(guildID) {
+			guild.Tainted = true
+		}
+	}
+	s.guildsMu.Unlock()
+
+	s.rolesMu.Lock()
+	markShardTaintedLocked(s.rolesByGuild, params.AppID, params.InShard, func(role *model.Role) {
+		role.Tainted = true
+	})
+	s.rolesMu.Unlock()
+
+	s.channelsMu.Lock()
+	markShardTaintedLocked(s.channelsByGuild, params.AppID, params.InShard, func(channel *model.Channel) {
+		channel.Tainted = true
+	})
+	s.channelsMu.Unlock()
+
+	s.emojisMu.Lock()
+	markShardTaintedLocked(s.emojisByGuild, params.AppID, params.InShard, func(emoji *model.Emoji) {
+		emoji.Tainted = true
+	})
+	s.emojisMu.Unlock()
+
+	s.stickersMu.Lock()
+	markShardTaintedLocked(s.stickersByGuild, params.AppID, params.InShard, func(sticker *model.Sticker) {
+		sticker.Tainted = true
+	})
+	s.stickersMu.Unlock()
+
 	return nil
 }
 
+func (s *MapCacheStore) DeleteShardTaintedEntities(ctx context.Context, params store.DeleteShardTaintedEntitiesParams) error {
+	sweep := newShardSweep(params)
+
+	s.guildsMu.Lock()
+	appGuilds := s.guilds[params.AppID]
+	for guildID, guild := range appGuilds {
+		if guild.Tainted && sweep.covers(guildID) {
+			sweep.removedGuildIDs[guildID] = struct{}{}
+			delete(appGuilds, guildID)
+			s.unindexGuildApp(params.AppID, guildID)
+		}
+	}
+	if len(appGuilds) == 0 {
+		delete(s.guilds, params.AppID)
+	}
+	s.guildsMu.Unlock()
+
+	s.rolesMu.Lock()
+	deleteShardTaintedLocked(s.roles, s.rolesByGuild, params.AppID, sweep, func(role *model.Role) bool {
+		return role.Tainted
+	})
+	s.rolesMu.Unlock()
+
+	s.channelsMu.Lock()
+	deleteShardTaintedLocked(s.channels, s.channelsByGuild, params.AppID, sweep, func(channel *model.Channel) bool {
+		return channel.Tainted
+	})
+	s.channelsMu.Unlock()
+
+	s.emojisMu.Lock()
+	deleteShardTaintedLocked(s.emojis, s.emojisByGuild, params.AppID, sweep, func(emoji *model.Emoji) bool {
+		return emoji.Tainted
+	})
+	s.emojisMu.Unlock()
+
+	s.stickersMu.Lock()
+	deleteShardTaintedLocked(s.stickers, s.stickersByGuild, params.AppID, sweep, func(sticker *model.Sticker) bool {
+		return sticker.Tainted
+	})
+	s.stickersMu.Unlock()
+
+	return nil
+}
+
+// markGuildReplaced records when the entities of the guild have been replaced.
+// It reports false without recording anything when they have already been replaced by a more recent event.
+func markGuildReplaced(replacedAt map[snowflake.ID]map[snowflake.ID]time.Time, appID snowflake.ID, guildID snowflake.ID, updatedAt time.Time) bool {
+	if replacedAt[appID] == nil {
+		replacedAt[appID] = make(map[snowflake.ID]time.Time)
+	}
+	if last, ok := replacedAt[appID][guildID]; ok && last.After(updatedAt) {
+		return false
+	}
+	replacedAt[appID][guildID] = updatedAt
+	return true
+}
+
 func (s *MapCacheStore) MassUpsertEntities(ctx context.Context, params store.MassUpsertEntitiesParams) error {
 	if len(params.Guilds) > 0 {
-		if err := s.UpsertGuilds(ctx, params.Guilds...); err != nil {
+		if _, err := s.UpsertGuilds(ctx, params.Guilds...); err != nil {
 			return err
 		}
 	}
 
 	if len(params.Roles) > 0 {
-		if err := s.UpsertRoles(ctx, params.Roles...); err != nil {
+		if _, err := s.UpsertRoles(ctx, params.Roles...); err != nil {
 			return err
 		}
 	}
 
 	if len(params.Channels) > 0 {
-		if err := s.UpsertChannels(ctx, params.Channels...); err != nil {
+		if _, err := s.UpsertChannels(ctx, params.Channels...); err != nil {
 			return err
 		}
 	}
 
 	if len(params.Emojis) > 0 {
-		if err := s.UpsertEmojis(ctx, params.Emojis...); err != nil {
+		if _, err := s.UpsertEmojis(ctx, params.Emojis...); err != nil {
 			return err
 		}
 	}
 
 	if len(params.Stickers) > 0 {
-		if err := s.UpsertStickers(ctx, params.Stickers...); err != nil {
+		if _, err := s.UpsertStickers(ctx, params.Stickers...); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// DeleteAppEntities drops the indexes of the app, so the entities don't have to be removed one by one.
+func (s *MapCacheStore) DeleteAppEntities(ctx context.Context, appID snowflake.ID) (store.DeletedEntities, error) {
+	var deleted store.DeletedEntities
+
+	s.guildsMu.Lock()
+	deleted.Guilds = len(s.guilds[appID])
+	for guildID := range s.guilds[appID] {
+		s.unindexGuildApp(appID, guildID)
+	}
+	delete(s.guilds, appID)
+	s.guildsMu.Unlock()
+
+	s.rolesMu.Lock()
+	deleted.Roles = len(s.roles[appID])
+	delete(s.roles, appID)
+	delete(s.rolesByGuild, appID)
+	s.rolesMu.Unlock()
+
+	s.channelsMu.Lock()
+	deleted.Channels = len(s.channels[appID])
+	delete(s.channels, appID)
+	delete(s.channelsByGuild, appID)
+	s.channelsMu.Unlock()
+
+	s.emojisMu.Lock()
+	deleted.Emojis = len(s.emojis[appID])
+	delete(s.emojis, appID)
+	delete(s.emojisByGuild, appID)
+	delete(s.emojiReplaces, appID)
+	s.emojisMu.Unlock()
+
+	s.stickersMu.Lock()
+	deleted.Stickers = len(s.stickers[appID])
+	delete(s.stickers, appID)
+	delete(s.stickersByGuild, appID)
+	delete(s.stickerReplaces, appID)
+	s.stickersMu.Unlock()
+
+	return deleted, nil
+}
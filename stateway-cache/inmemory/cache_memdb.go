@@ -1,8 +1,9 @@
 package inmemory
 
 import (
+	"cmp"
 	"context"
-	"fmt"
+	"slices"
 	"time"
 
 	"github.com/disgoorg/snowflake/v2"
@@ -31,6 +32,13 @@ var memDBCacheSchema = &memdb.DBSchema{
 						&memdb.UintFieldIndex{Field: "AppID"},
 					}},
 				},
+				"guild_id": {
+					Name:   "guild_id",
+					Unique: false,
+					Indexer: &memdb.CompoundIndex{Indexes: []memdb.Indexer{
+						&memdb.UintFieldIndex{Field: "GuildID"},
+					}},
+				},
 			},
 		},
 		"channels": {
@@ -177,9 +185,61 @@ var memDBCacheSchema = &memdb.DBSchema{
 				},
 			},
 		},
+		"guild_collections": {
+			Name: "guild_collections",
+			Indexes: map[string]*memdb.IndexSchema{
+				"id": {
+					Name:   "id",
+					Unique: true,
+					Indexer: &memdb.CompoundIndex{Indexes: []memdb.Indexer{
+						&memdb.UintFieldIndex{Field: "AppID"},
+						&memdb.UintFieldIndex{Field: "GuildID"},
+						&memdb.StringFieldIndex{Field: "Collection"},
+					}},
+				},
+				"app_id": {
+					Name:   "app_id",
+					Unique: false,
+					Indexer: &memdb.CompoundIndex{Indexes: []memdb.Indexer{
+						&memdb.UintFieldIndex{Field: "AppID"},
+					}},
+				},
+			},
+		},
 	},
 }
 
+// memDBGuildCollection records when the entities of a guild collection have last been replaced as a whole.
+type memDBGuildCollection struct {
+	AppID      snowflake.ID
+	GuildID    snowflake.ID
+	Collection string
+	UpdatedAt  time.Time
+}
+
+// markGuildCollectionReplaced records when the entities of the guild collection have been replaced.
+// It reports false without recording anything when they have already been replaced by a more recent event.
+func markGuildCollectionReplaced(txn *memdb.Txn, appID snowflake.ID, guildID snowflake.ID, collection string, updatedAt time.Time) (bool, error) {
+	existing, err := txn.First("guild_collections", "id", appID, guildID, collection)
+	if err != nil {
+		return false, err
+	}
+	if existing != nil && existing.(*memDBGuildCollection).UpdatedAt.After(updatedAt) {
+		return false, nil
+	}
+
+	err = txn.Insert("guild_collections", &memDBGuildCollection{
+		AppID:      appID,
+		GuildID:    guildID,
+		Collection: collection,
+		UpdatedAt:  updatedAt,
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 var _ store.CacheStore = (*MemDBCacheStore)(nil)
 
 type MemDBCacheStore struct {
@@ -210,6 +270,50 @@ func (s *MemDBCacheStore) GetGuild(ctx context.Context, appID snowflake.ID, guil
 	return guild.(*model.Guild), nil
 }
 
+func (s *MemDBCacheStore) GetFullGuild(ctx context.Context, params store.GetFullGuildParams) (*model.FullGuild, error) {
+	// A read transaction sees a consistent snapshot of all tables.
+	txn := s.db.Txn(false)
+	defer txn.Abort()
+
+	guild, err := txn.First("guilds", "id", params.AppID, params.GuildID)
+	if err != nil {
+		return nil, err
+	}
+	if guild == nil {
+		return nil, store.ErrNotFound
+	}
+
+	res := &model.FullGuild{Guild: *guild.(*model.Guild)}
+	opts := store.ListOptions{ExcludeTainted: params.ExcludeTainted}
+
+	if params.Include.Channels {
+		res.Channels, err = memDBGuildChannels(txn, params.AppID, params.GuildID, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if params.Include.Roles {
+		res.Roles, err = memDBGuildRoles(txn, params.AppID, params.GuildID, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if params.Include.Emojis {
+		res.Emojis, err = memDBGuildEmojis(txn, params.AppID, params.GuildID, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if params.Include.Stickers {
+		res.Stickers, err = memDBGuildStickers(txn, params.AppID, params.GuildID, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
 func (s *MemDBCacheStore) GetGuildOwnerID(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (snowflake.ID, error) {
 	txn := s.db.Txn(false)
 	defer txn.Abort()
@@ -226,7 +330,7 @@ func (s *MemDBCacheStore) GetGuildOwnerID(ctx context.Context, appID snowflake.I
 	return guild.(*model.Guild).Data.OwnerID, nil
 }
 
-func (s *MemDBCacheStore) GetGuilds(ctx context.Context, appID snowflake.ID, limit int, offset int) ([]*model.Guild, error) {
+func (s *MemDBCacheStore) GetGuilds(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Guild, error) {
 	txn := s.db.Txn(false)
 	defer txn.Abort()
 
@@ -235,16 +339,31 @@ func (s *MemDBCacheStore) GetGuilds(ctx context.Context, appID snowflake.ID, lim
 		return nil, err
 	}
 
-	guilds := make([]*model.Guild, 0, limit)
+	guilds := make([]*model.Guild, 0)
 	for guild := iter.Next(); guild != nil; guild = iter.Next() {
-		if offset > 0 {
-			offset--
+		g := guild.(*model.Guild)
+		if opts.ExcludeTainted && g.Tainted {
 			continue
 		}
-		if limit > 0 && len(guilds) >= limit {
-			break
+		guilds = append(guilds, g)
+	}
+
+	return pageEntities(guilds, opts, guildEntityID), nil
+}
+
+func (s *MemDBCacheStore) GetGuildsByIDs(ctx context.Context, appID snowflake.ID, guildIDs []snowflake.ID) ([]*model.Guild, error) {
+	txn := s.db.Txn(false)
+	defer txn.Abort()
+
+	guilds := make([]*model.Guild, 0, len(guildIDs))
+	for _, guildID := range guildIDs {
+		guild, err := txn.First("guilds", "id", appID, guildID)
+		if err != nil {
+			return nil, err
+		}
+		if guild != nil {
+			guilds = append(guilds, guild.(*model.Guild))
 		}
-		guilds = append(guilds, guild.(*model.Guild))
 	}
 
 	return guilds, nil
@@ -262,10 +381,37 @@ func (s *MemDBCacheStore) CheckGuildExist(ctx context.Context, appID snowflake.I
 	return guild != nil, nil
 }
 
-func (s *MemDBCacheStore) UpsertGuilds(ctx context.Context, guilds ...store.UpsertGuildParams) error {
+func (s *MemDBCacheStore) GetGuildApps(ctx context.Context, guildID snowflake.ID) ([]*model.GuildApp, error) {
+	txn := s.db.Txn(false)
+	defer txn.Abort()
+
+	iter, err := txn.Get("guilds", "guild_id", guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	apps := make([]*model.GuildApp, 0)
+	for guild := iter.Next(); guild != nil; guild = iter.Next() {
+		g := guild.(*model.Guild)
+		apps = append(apps, &model.GuildApp{
+			AppID:       g.AppID,
+			Unavailable: g.Unavailable,
+			Tainted:     g.Tainted,
+			UpdatedAt:   g.UpdatedAt,
+		})
+	}
+	slices.SortFunc(apps, func(a, b *model.GuildApp) int {
+		return cmp.Compare(a.AppID, b.AppID)
+	})
+
+	return apps, nil
+}
+
+func (s *MemDBCacheStore) UpsertGuilds(ctx context.Context, guilds ...store.UpsertGuildParams) (int, error) {
 	txn := s.db.Txn(true)
 	defer txn.Abort()
 
+	written := 0
 	for _, guild := range guilds {
 		createdAt := guild.CreatedAt
 		if createdAt.IsZero() {
@@ -276,7 +422,15 @@ func (s *MemDBCacheStore) UpsertGuilds(ctx context.Context, guilds ...store.Upse
 			updatedAt = time.Now().UTC()
 		}
 
-		err := txn.Insert("guilds", &model.Guild{
+		stale, err := isStale(txn, "guilds", updatedAt, guild.AppID, guild.GuildID)
+		if err != nil {
+			return 0, err
+		}
+		if stale {
+			continue
+		}
+
+		err = txn.Insert("guilds", &model.Guild{
 			AppID:     guild.AppID,
 			GuildID:   guild.GuildID,
 			Data:      guild.Data,
@@ -284,12 +438,13 @@ func (s *MemDBCacheStore) UpsertGuilds(ctx context.Context, guilds ...store.Upse
 			UpdatedAt: updatedAt,
 		})
 		if err != nil {
-			return err
+			return 0, err
 		}
+		written++
 	}
 
 	txn.Commit()
-	return nil
+	return written, nil
 }
 
 func (s *MemDBCacheStore) MarkGuildUnavailable(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) error {
@@ -307,7 +462,6 @@ func (s *MemDBCacheStore) MarkGuildUnavailable(ctx context.Context, appID snowfl
 
 	g := *guild.(*model.Guild)
 	g.Unavailable = true
-	g.UpdatedAt = time.Now().UTC()
 
 	err = txn.Insert("guilds", &g)
 	if err != nil {
@@ -332,7 +486,11 @@ func (s *MemDBCacheStore) DeleteGuild(ctx context.Context, appID snowflake.ID, g
 }
 
 func (s *MemDBCacheStore) SearchGuilds(ctx context.Context, params store.SearchGuildsParams) ([]*model.Guild, error) {
-	return nil, fmt.Errorf("not implemented")
+	guilds, err := s.GetGuilds(ctx, params.AppID, store.ListOptions{ExcludeTainted: params.ExcludeTainted, After: params.After})
+	if err != nil {
+		return nil, err
+	}
+	return store.SearchEntities(guilds, params.Data, params.Filter, params.ListOptions, guildData)
 }
 
 // CacheRoleStore methods
@@ -369,32 +527,32 @@ func (s *MemDBCacheStore) GetRole(ctx context.Context, appID snowflake.ID, roleI
 	return role.(*model.Role), nil
 }
 
-func (s *MemDBCacheStore) GetGuildRoles(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, limit int, offset int) ([]*model.Role, error) {
+func (s *MemDBCacheStore) GetGuildRoles(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Role, error) {
 	txn := s.db.Txn(false)
 	defer txn.Abort()
 
+	return memDBGuildRoles(txn, appID, guildID, opts)
+}
+
+func memDBGuildRoles(txn *memdb.Txn, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Role, error) {
 	iter, err := txn.Get("roles", "guild_id", guildID)
 	if err != nil {
 		return nil, err
 	}
 
-	roles := make([]*model.Role, 0, limit)
+	roles := make([]*model.Role, 0)
 	for role := iter.Next(); role != nil; role = iter.Next() {
 		r := role.(*model.Role)
 		if r.AppID != appID {
 			continue
 		}
-		if offset > 0 {
-			offset--
+		if opts.ExcludeTainted && r.Tainted {
 			continue
 		}
-		if limit > 0 && len(roles) >= limit {
-			break
-		}
 		roles = append(roles, r)
 	}
 
-	return roles, nil
+	return pageEntities(roles, opts, roleEntityID), nil
 }
 
 func (s *MemDBCacheStore) GetGuildRolesByIDs(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, roleIDs []snowflake.ID) ([]*model.Role, error) {
@@ -415,7 +573,7 @@ func (s *MemDBCacheStore) GetGuildRolesByIDs(ctx context.Context, appID snowflak
 	return roles, nil
 }
 
-func (s *MemDBCacheStore) GetRoles(ctx context.Context, appID snowflake.ID, limit int, offset int) ([]*model.Role, error) {
+func (s *MemDBCacheStore) GetRoles(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Role, error) {
 	txn := s.db.Txn(false)
 	defer txn.Abort()
 
@@ -424,27 +582,50 @@ func (s *MemDBCacheStore) GetRoles(ctx context.Context, appID snowflake.ID, limi
 		return nil, err
 	}
 
-	roles := make([]*model.Role, 0, limit)
+	roles := make([]*model.Role, 0)
 	for role := iter.Next(); role != nil; role = iter.Next() {
-		if offset > 0 {
-			offset--
+		r := role.(*model.Role)
+		if opts.ExcludeTainted && r.Tainted {
 			continue
 		}
-		if limit > 0 && len(roles) >= limit {
-			break
+		roles = append(roles, r)
+	}
+
+	return pageEntities(roles, opts, roleEntityID), nil
+}
+
+func (s *MemDBCacheStore) GetRolesByIDs(ctx context.Context, appID snowflake.ID, roleIDs []snowflake.ID) ([]*model.Role, error) {
+	txn := s.db.Txn(false)
+	defer txn.Abort()
+
+	roles := make([]*model.Role, 0, len(roleIDs))
+	for _, roleID := range roleIDs {
+		role, err := txn.First("roles", "role_id", appID, roleID)
+		if err != nil {
+			return nil, err
+		}
+		if role != nil {
+			roles = append(roles, role.(*model.Role))
 		}
-		roles = append(roles, role.(*model.Role))
 	}
 
 	return roles, nil
 }
 
 func (s *MemDBCacheStore) SearchGuildRoles(ctx context.Context, params store.SearchGuildRolesParams) ([]*model.Role, error) {
-	return nil, fmt.Errorf("not implemented")
+	roles, err := s.GetGuildRoles(ctx, params.AppID, params.GuildID, store.ListOptions{ExcludeTainted: params.ExcludeTainted, After: params.After})
+	if err != nil {
+		return nil, err
+	}
+	return store.SearchEntities(roles, params.Data, params.Filter, params.ListOptions, roleData)
 }
 
 func (s *MemDBCacheStore) SearchRoles(ctx context.Context, params store.SearchRolesParams) ([]*model.Role, error) {
-	return nil, fmt.Errorf("not implemented")
+	roles, err := s.GetRoles(ctx, params.AppID, store.ListOptions{ExcludeTainted: params.ExcludeTainted, After: params.After})
+	if err != nil {
+		return nil, err
+	}
+	return store.SearchEntities(roles, params.Data, params.Filter, params.ListOptions, roleData)
 }
 
 func (s *MemDBCacheStore) CountGuildRoles(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (int, error) {
@@ -483,10 +664,11 @@ func (s *MemDBCacheStore) CountRoles(ctx context.Context, appID snowflake.ID) (i
 	return count, nil
 }
 
-func (s *MemDBCacheStore) UpsertRoles(ctx context.Context, roles ...store.UpsertRoleParams) error {
+func (s *MemDBCacheStore) UpsertRoles(ctx context.Context, roles ...store.UpsertRoleParams) (int, error) {
 	txn := s.db.Txn(true)
 	defer txn.Abort()
 
+	written := 0
 	for _, role := range roles {
 		createdAt := role.CreatedAt
 		if createdAt.IsZero() {
@@ -497,7 +679,15 @@ func (s *MemDBCacheStore) UpsertRoles(ctx context.Context, roles ...store.Upsert
 			updatedAt = time.Now().UTC()
 		}
 
-		err := txn.Insert("roles", &model.Role{
+		stale, err := isStale(txn, "roles", updatedAt, role.AppID, role.GuildID, role.RoleID)
+		if err != nil {
+			return 0, err
+		}
+		if stale {
+			continue
+		}
+
+		err = txn.Insert("roles", &model.Role{
 			AppID:     role.AppID,
 			GuildID:   role.GuildID,
 			RoleID:    role.RoleID,
@@ -506,12 +696,13 @@ func (s *MemDBCacheStore) UpsertRoles(ctx context.Context, roles ...store.Upsert
 			UpdatedAt: updatedAt,
 		})
 		if err != nil {
-			return err
+			return 0, err
 		}
+		written++
 	}
 
 	txn.Commit()
-	return nil
+	return written, nil
 }
 
 func (s *MemDBCacheStore) DeleteRole(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, roleID snowflake.ID) error {
@@ -561,35 +752,35 @@ func (s *MemDBCacheStore) GetChannel(ctx context.Context, appID snowflake.ID, ch
 	return channel.(*model.Channel), nil
 }
 
-func (s *MemDBCacheStore) GetGuildChannels(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, limit int, offset int) ([]*model.Channel, error) {
+func (s *MemDBCacheStore) GetGuildChannels(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Channel, error) {
 	txn := s.db.Txn(false)
 	defer txn.Abort()
 
+	return memDBGuildChannels(txn, appID, guildID, opts)
+}
+
+func memDBGuildChannels(txn *memdb.Txn, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Channel, error) {
 	iter, err := txn.Get("channels", "guild_id", guildID)
 	if err != nil {
 		return nil, err
 	}
 
-	channels := make([]*model.Channel, 0, limit)
+	channels := make([]*model.Channel, 0)
 	for channel := iter.Next(); channel != nil; channel = iter.Next() {
 		c := channel.(*model.Channel)
 		if c.AppID != appID {
 			continue
 		}
-		if offset > 0 {
-			offset--
+		if opts.ExcludeTainted && c.Tainted {
 			continue
 		}
-		if limit > 0 && len(channels) >= limit {
-			break
-		}
 		channels = append(channels, c)
 	}
 
-	return channels, nil
+	return pageEntities(channels, opts, channelEntityID), nil
 }
 
-func (s *MemDBCacheStore) GetChannels(ctx context.Context, appID snowflake.ID, limit int, offset int) ([]*model.Channel, error) {
+func (s *MemDBCacheStore) GetChannels(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Channel, error) {
 	txn := s.db.Txn(false)
 	defer txn.Abort()
 
@@ -598,22 +789,37 @@ func (s *MemDBCacheStore) GetChannels(ctx context.Context, appID snowflake.ID, l
 		return nil, err
 	}
 
-	channels := make([]*model.Channel, 0, limit)
+	channels := make([]*model.Channel, 0)
 	for channel := iter.Next(); channel != nil; channel = iter.Next() {
-		if offset > 0 {
-			offset--
+		c := channel.(*model.Channel)
+		if opts.ExcludeTainted && c.Tainted {
 			continue
 		}
-		if limit > 0 && len(channels) >= limit {
-			break
+		channels = append(channels, c)
+	}
+
+	return pageEntities(channels, opts, channelEntityID), nil
+}
+
+func (s *MemDBCacheStore) GetChannelsByIDs(ctx context.Context, appID snowflake.ID, channelIDs []snowflake.ID) ([]*model.Channel, error) {
+	txn := s.db.Txn(false)
+	defer txn.Abort()
+
+	channels := make([]*model.Channel, 0, len(channelIDs))
+	for _, channelID := range channelIDs {
+		channel, err := txn.First("channels", "channel_id", appID, channelID)
+		if err != nil {
+			return nil, err
+		}
+		if channel != nil {
+			channels = append(channels, channel.(*model.Channel))
 		}
-		channels = append(channels, channel.(*model.Channel))
 	}
 
 	return channels, nil
 }
 
-func (s *MemDBCacheStore) GetChannelsByType(ctx context.Context, appID snowflake.ID, types []int, limit int, offset int) ([]*model.Channel, error) {
+func (s *MemDBCacheStore) GetChannelsByType(ctx context.Context, appID snowflake.ID, types []int, opts store.ListOptions) ([]*model.Channel, error) {
 	txn := s.db.Txn(false)
 	defer txn.Abort()
 
@@ -622,7 +828,7 @@ func (s *MemDBCacheStore) GetChannelsByType(ctx context.Context, appID snowflake
 		return nil, err
 	}
 
-	channels := make([]*model.Channel, 0, limit)
+	channels := make([]*model.Channel, 0)
 	for channel := iter.Next(); channel != nil; channel = iter.Next() {
 		c := channel.(*model.Channel)
 		channelType := int(c.Data.Type())
@@ -636,25 +842,29 @@ func (s *MemDBCacheStore) GetChannelsByType(ctx context.Context, appID snowflake
 		if !found {
 			continue
 		}
-		if offset > 0 {
-			offset--
+		if opts.ExcludeTainted && c.Tainted {
 			continue
 		}
-		if limit > 0 && len(channels) >= limit {
-			break
-		}
 		channels = append(channels, c)
 	}
 
-	return channels, nil
+	return pageEntities(channels, opts, channelEntityID), nil
 }
 
 func (s *MemDBCacheStore) SearchGuildChannels(ctx context.Context, params store.SearchGuildChannelsParams) ([]*model.Channel, error) {
-	return nil, fmt.Errorf("not implemented")
+	channels, err := s.GetGuildChannels(ctx, params.AppID, params.GuildID, store.ListOptions{ExcludeTainted: params.ExcludeTainted, After: params.After})
+	if err != nil {
+		return nil, err
+	}
+	return store.SearchEntities(channels, params.Data, params.Filter, params.ListOptions, channelData)
 }
 
 func (s *MemDBCacheStore) SearchChannels(ctx context.Context, params store.SearchChannelsParams) ([]*model.Channel, error) {
-	return nil, fmt.Errorf("not implemented")
+	channels, err := s.GetChannels(ctx, params.AppID, store.ListOptions{ExcludeTainted: params.ExcludeTainted, After: params.After})
+	if err != nil {
+		return nil, err
+	}
+	return store.SearchEntities(channels, params.Data, params.Filter, params.ListOptions, channelData)
 }
 
 func (s *MemDBCacheStore) CountGuildChannels(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (int, error) {
@@ -693,10 +903,11 @@ func (s *MemDBCacheStore) CountChannels(ctx context.Context, appID snowflake.ID)
 	return count, nil
 }
 
-func (s *MemDBCacheStore) UpsertChannels(ctx context.Context, channels ...store.UpsertChannelParams) error {
+func (s *MemDBCacheStore) UpsertChannels(ctx context.Context, channels ...store.UpsertChannelParams) (int, error) {
 	txn := s.db.Txn(true)
 	defer txn.Abort()
 
+	written := 0
 	for _, channel := range channels {
 		createdAt := channel.CreatedAt
 		if createdAt.IsZero() {
@@ -707,7 +918,15 @@ func (s *MemDBCacheStore) UpsertChannels(ctx context.Context, channels ...store.
 			updatedAt = time.Now().UTC()
 		}
 
-		err := txn.Insert("channels", &model.Channel{
+		stale, err := isStale(txn, "channels", updatedAt, channel.AppID, channel.GuildID, channel.ChannelID)
+		if err != nil {
+			return 0, err
+		}
+		if stale {
+			continue
+		}
+
+		err = txn.Insert("channels", &model.Channel{
 			AppID:     channel.AppID,
 			GuildID:   channel.GuildID,
 			ChannelID: channel.ChannelID,
@@ -716,12 +935,13 @@ func (s *MemDBCacheStore) UpsertChannels(ctx context.Context, channels ...store.
 			UpdatedAt: updatedAt,
 		})
 		if err != nil {
-			return err
+			return 0, err
 		}
+		written++
 	}
 
 	txn.Commit()
-	return nil
+	return written, nil
 }
 
 func (s *MemDBCacheStore) DeleteChannel(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, channelID snowflake.ID) error {
@@ -771,35 +991,35 @@ func (s *MemDBCacheStore) GetEmoji(ctx context.Context, appID snowflake.ID, emoj
 	return emoji.(*model.Emoji), nil
 }
 
-func (s *MemDBCacheStore) GetGuildEmojis(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, limit int, offset int) ([]*model.Emoji, error) {
+func (s *MemDBCacheStore) GetGuildEmojis(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Emoji, error) {
 	txn := s.db.Txn(false)
 	defer txn.Abort()
 
+	return memDBGuildEmojis(txn, appID, guildID, opts)
+}
+
+func memDBGuildEmojis(txn *memdb.Txn, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Emoji, error) {
 	iter, err := txn.Get("emojis", "guild_id", guildID)
 	if err != nil {
 		return nil, err
 	}
 
-	emojis := make([]*model.Emoji, 0, limit)
+	emojis := make([]*model.Emoji, 0)
 	for emoji := iter.Next(); emoji != nil; emoji = iter.Next() {
 		e := emoji.(*model.Emoji)
 		if e.AppID != appID {
 			continue
 		}
-		if offset > 0 {
-			offset--
+		if opts.ExcludeTainted && e.Tainted {
 			continue
 		}
-		if limit > 0 && len(emojis) >= limit {
-			break
-		}
 		emojis = append(emojis, e)
 	}
 
-	return emojis, nil
+	return pageEntities(emojis, opts, emojiEntityID), nil
 }
 
-func (s *MemDBCacheStore) GetEmojis(ctx context.Context, appID snowflake.ID, limit int, offset int) ([]*model.Emoji, error) {
+func (s *MemDBCacheStore) GetEmojis(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Emoji, error) {
 	txn := s.db.Txn(false)
 	defer txn.Abort()
 
@@ -808,27 +1028,50 @@ func (s *MemDBCacheStore) GetEmojis(ctx context.Context, appID snowflake.ID, lim
 		return nil, err
 	}
 
-	emojis := make([]*model.Emoji, 0, limit)
+	emojis := make([]*model.Emoji, 0)
 	for emoji := iter.Next(); emoji != nil; emoji = iter.Next() {
-		if offset > 0 {
-			offset--
+		e := emoji.(*model.Emoji)
+		if opts.ExcludeTainted && e.Tainted {
 			continue
 		}
-		if limit > 0 && len(emojis) >= limit {
-			break
+		emojis = append(emojis, e)
+	}
+
+	return pageEntities(emojis, opts, emojiEntityID), nil
+}
+
+func (s *MemDBCacheStore) GetEmojisByIDs(ctx context.Context, appID snowflake.ID, emojiIDs []snowflake.ID) ([]*model.Emoji, error) {
+	txn := s.db.Txn(false)
+	defer txn.Abort()
+
+	emojis := make([]*model.Emoji, 0, len(emojiIDs))
+	for _, emojiID := range emojiIDs {
+		emoji, err := txn.First("emojis", "emoji_id", appID, emojiID)
+		if err != nil {
+			return nil, err
+		}
+		if emoji != nil {
+			emojis = append(emojis, emoji.(*model.Emoji))
 		}
-		emojis = append(emojis, emoji.(*model.Emoji))
 	}
 
 	return emojis, nil
 }
 
 func (s *MemDBCacheStore) SearchGuildEmojis(ctx context.Context, params store.SearchGuildEmojisParams) ([]*model.Emoji, error) {
-	return nil, fmt.Errorf("not implemented")
+	emojis, err := s.GetGuildEmojis(ctx, params.AppID, params.GuildID, store.ListOptions{ExcludeTainted: params.ExcludeTainted, After: params.After})
+	if err != nil {
+		return nil, err
+	}
+	return store.SearchEntities(emojis, params.Data, params.Filter, params.ListOptions, emojiData)
 }
 
 func (s *MemDBCacheStore) SearchEmojis(ctx context.Context, params store.SearchEmojisParams) ([]*model.Emoji, error) {
-	return nil, fmt.Errorf("not implemented")
+	emojis, err := s.GetEmojis(ctx, params.AppID, store.ListOptions{ExcludeTainted: params.ExcludeTainted, After: params.After})
+	if err != nil {
+		return nil, err
+	}
+	return store.SearchEntities(emojis, params.Data, params.Filter, params.ListOptions, emojiData)
 }
 
 func (s *MemDBCacheStore) CountGuildEmojis(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (int, error) {
@@ -867,10 +1110,11 @@ func (s *MemDBCacheStore) CountEmojis(ctx context.Context, appID snowflake.ID) (
 	return count, nil
 }
 
-func (s *MemDBCacheStore) UpsertEmojis(ctx context.Context, emojis ...store.UpsertEmojiParams) error {
+func (s *MemDBCacheStore) UpsertEmojis(ctx context.Context, emojis ...store.UpsertEmojiParams) (int, error) {
 	txn := s.db.Txn(true)
 	defer txn.Abort()
 
+	written := 0
 	for _, emoji := range emojis {
 		createdAt := emoji.CreatedAt
 		if createdAt.IsZero() {
@@ -881,7 +1125,15 @@ func (s *MemDBCacheStore) UpsertEmojis(ctx context.Context, emojis ...store.Upse
 			updatedAt = time.Now().UTC()
 		}
 
-		err := txn.Insert("emojis", &model.Emoji{
+		stale, err := isStale(txn, "emojis", updatedAt, emoji.AppID, emoji.GuildID, emoji.EmojiID)
+		if err != nil {
+			return 0, err
+		}
+		if stale {
+			continue
+		}
+
+		err = txn.Insert("emojis", &model.Emoji{
 			AppID:     emoji.AppID,
 			GuildID:   emoji.GuildID,
 			EmojiID:   emoji.EmojiID,
@@ -890,12 +1142,85 @@ func (s *MemDBCacheStore) UpsertEmojis(ctx context.Context, emojis ...store.Upse
 			UpdatedAt: updatedAt,
 		})
 		if err != nil {
-			return err
+			return 0, err
 		}
+		written++
 	}
 
 	txn.Commit()
-	return nil
+	return written, nil
+}
+
+func (s *MemDBCacheStore) ReplaceGuildEmojis(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, updatedAt time.Time, emojis ...store.UpsertEmojiParams) (bool, error) {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	replaced, err := markGuildCollectionReplaced(txn, appID, guildID, "emojis", updatedAt)
+	if err != nil || !replaced {
+		return false, err
+	}
+
+	keep := make(map[snowflake.ID]struct{}, len(emojis))
+	for _, emoji := range emojis {
+		keep[emoji.EmojiID] = struct{}{}
+	}
+
+	iter, err := txn.Get("emojis", "guild_id", guildID)
+	if err != nil {
+		return false, err
+	}
+
+	var removed []*model.Emoji
+	for emoji := iter.Next(); emoji != nil; emoji = iter.Next() {
+		e := emoji.(*model.Emoji)
+		if e.AppID != appID {
+			continue
+		}
+		if _, ok := keep[e.EmojiID]; !ok && !e.UpdatedAt.After(updatedAt) {
+			removed = append(removed, e)
+		}
+	}
+
+	for _, e := range removed {
+		err := txn.Delete("emojis", e)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	for _, emoji := range emojis {
+		createdAt := emoji.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = time.Now().UTC()
+		}
+		emojiUpdatedAt := emoji.UpdatedAt
+		if emojiUpdatedAt.IsZero() {
+			emojiUpdatedAt = time.Now().UTC()
+		}
+
+		stale, err := isStale(txn, "emojis", emojiUpdatedAt, emoji.AppID, emoji.GuildID, emoji.EmojiID)
+		if err != nil {
+			return false, err
+		}
+		if stale {
+			continue
+		}
+
+		err = txn.Insert("emojis", &model.Emoji{
+			AppID:     emoji.AppID,
+			GuildID:   emoji.GuildID,
+			EmojiID:   emoji.EmojiID,
+			Data:      emoji.Data,
+			CreatedAt: createdAt,
+			UpdatedAt: emojiUpdatedAt,
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	txn.Commit()
+	return true, nil
 }
 
 func (s *MemDBCacheStore) DeleteEmoji(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, emojiID snowflake.ID) error {
@@ -933,7 +1258,7 @@ func (s *MemDBCacheStore) GetGuildSticker(ctx context.Context, appID snowflake.I
 	return s.GetSticker(ctx, appID, guildID, stickerID)
 }
 
-func (s *MemDBCacheStore) GetStickers(ctx context.Context, appID snowflake.ID, limit int, offset int) ([]*model.Sticker, error) {
+func (s *MemDBCacheStore) GetStickers(ctx context.Context, appID snowflake.ID, opts store.ListOptions) ([]*model.Sticker, error) {
 	txn := s.db.Txn(false)
 	defer txn.Abort()
 
@@ -942,55 +1267,78 @@ func (s *MemDBCacheStore) GetStickers(ctx context.Context, appID snowflake.ID, l
 		return nil, err
 	}
 
-	stickers := make([]*model.Sticker, 0, limit)
+	stickers := make([]*model.Sticker, 0)
 	for sticker := iter.Next(); sticker != nil; sticker = iter.Next() {
-		if offset > 0 {
-			offset--
+		st := sticker.(*model.Sticker)
+		if opts.ExcludeTainted && st.Tainted {
 			continue
 		}
-		if limit > 0 && len(stickers) >= limit {
-			break
+		stickers = append(stickers, st)
+	}
+
+	return pageEntities(stickers, opts, stickerEntityID), nil
+}
+
+func (s *MemDBCacheStore) GetStickersByIDs(ctx context.Context, appID snowflake.ID, stickerIDs []snowflake.ID) ([]*model.Sticker, error) {
+	txn := s.db.Txn(false)
+	defer txn.Abort()
+
+	stickers := make([]*model.Sticker, 0, len(stickerIDs))
+	for _, stickerID := range stickerIDs {
+		sticker, err := txn.First("stickers", "sticker_id", appID, stickerID)
+		if err != nil {
+			return nil, err
+		}
+		if sticker != nil {
+			stickers = append(stickers, sticker.(*model.Sticker))
 		}
-		stickers = append(stickers, sticker.(*model.Sticker))
 	}
 
 	return stickers, nil
 }
 
-func (s *MemDBCacheStore) GetGuildStickers(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, limit int, offset int) ([]*model.Sticker, error) {
+func (s *MemDBCacheStore) GetGuildStickers(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Sticker, error) {
 	txn := s.db.Txn(false)
 	defer txn.Abort()
 
+	return memDBGuildStickers(txn, appID, guildID, opts)
+}
+
+func memDBGuildStickers(txn *memdb.Txn, appID snowflake.ID, guildID snowflake.ID, opts store.ListOptions) ([]*model.Sticker, error) {
 	iter, err := txn.Get("stickers", "guild_id", guildID)
 	if err != nil {
 		return nil, err
 	}
 
-	stickers := make([]*model.Sticker, 0, limit)
+	stickers := make([]*model.Sticker, 0)
 	for sticker := iter.Next(); sticker != nil; sticker = iter.Next() {
 		s := sticker.(*model.Sticker)
 		if s.AppID != appID {
 			continue
 		}
-		if offset > 0 {
-			offset--
+		if opts.ExcludeTainted && s.Tainted {
 			continue
 		}
-		if limit > 0 && len(stickers) >= limit {
-			break
-		}
 		stickers = append(stickers, s)
 	}
 
-	return stickers, nil
+	return pageEntities(stickers, opts, stickerEntityID), nil
 }
 
 func (s *MemDBCacheStore) SearchStickers(ctx context.Context, params store.SearchStickersParams) ([]*model.Sticker, error) {
-	return nil, fmt.Errorf("not implemented")
+	stickers, err := s.GetStickers(ctx, params.AppID, store.ListOptions{ExcludeTainted: params.ExcludeTainted, After: params.After})
+	if err != nil {
+		return nil, err
+	}
+	return store.SearchEntities(stickers, params.Data, params.Filter, params.ListOptions, stickerData)
 }
 
 func (s *MemDBCacheStore) SearchGuildStickers(ctx context.Context, params store.SearchGuildStickersParams) ([]*model.Sticker, error) {
-	return nil, fmt.Errorf("not implemented")
+	stickers, err := s.GetGuildStickers(ctx, params.AppID, params.GuildID, store.ListOptions{ExcludeTainted: params.ExcludeTainted, After: params.After})
+	if err != nil {
+		return nil, err
+	}
+	return store.SearchEntities(stickers, params.Data, params.Filter, params.ListOptions, stickerData)
 }
 
 func (s *MemDBCacheStore) CountGuildStickers(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (int, error) {
@@ -1029,10 +1377,11 @@ func (s *MemDBCacheStore) CountStickers(ctx context.Context, appID snowflake.ID)
 	return count, nil
 }
 
-func (s *MemDBCacheStore) UpsertStickers(ctx context.Context, stickers ...store.UpsertStickerParams) error {
+func (s *MemDBCacheStore) UpsertStickers(ctx context.Context, stickers ...store.UpsertStickerParams) (int, error) {
 	txn := s.db.Txn(true)
 	defer txn.Abort()
 
+	written := 0
 	for _, sticker := range stickers {
 		createdAt := sticker.CreatedAt
 		if createdAt.IsZero() {
@@ -1043,7 +1392,15 @@ func (s *MemDBCacheStore) UpsertStickers(ctx context.Context, stickers ...store.
 			updatedAt = time.Now().UTC()
 		}
 
-		err := txn.Insert("stickers", &model.Sticker{
+		stale, err := isStale(txn, "stickers", updatedAt, sticker.AppID, sticker.GuildID, sticker.StickerID)
+		if err != nil {
+			return 0, err
+		}
+		if stale {
+			continue
+		}
+
+		err = txn.Insert("stickers", &model.Sticker{
 			AppID:     sticker.AppID,
 			GuildID:   sticker.GuildID,
 			StickerID: sticker.StickerID,
@@ -1052,12 +1409,85 @@ func (s *MemDBCacheStore) UpsertStickers(ctx context.Context, stickers ...store.
 			UpdatedAt: updatedAt,
 		})
 		if err != nil {
-			return err
+			return 0, err
 		}
+		written++
 	}
 
 	txn.Commit()
-	return nil
+	return written, nil
+}
+
+func (s *MemDBCacheStore) ReplaceGuildStickers(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, updatedAt time.Time, stickers ...store.UpsertStickerParams) (bool, error) {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	replaced, err := markGuildCollectionReplaced(txn, appID, guildID, "stickers", updatedAt)
+	if err != nil || !replaced {
+		return false, err
+	}
+
+	keep := make(map[snowflake.ID]struct{}, len(stickers))
+	for _, sticker := range stickers {
+		keep[sticker.StickerID] = struct{}{}
+	}
+
+	iter, err := txn.Get("stickers", "guild_id", guildID)
+	if err != nil {
+		return false, err
+	}
+
+	var removed []*model.Sticker
+	for sticker := iter.Next(); sticker != nil; sticker = iter.Next() {
+		st := sticker.(*model.Sticker)
+		if st.AppID != appID {
+			continue
+		}
+		if _, ok := keep[st.StickerID]; !ok && !st.UpdatedAt.After(updatedAt) {
+			removed = append(removed, st)
+		}
+	}
+
+	for _, st := range removed {
+		err := txn.Delete("stickers", st)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	for _, sticker := range stickers {
+		createdAt := sticker.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = time.Now().UTC()
+		}
+		stickerUpdatedAt := sticker.UpdatedAt
+		if stickerUpdatedAt.IsZero() {
+			stickerUpdatedAt = time.Now().UTC()
+		}
+
+		stale, err := isStale(txn, "stickers", stickerUpdatedAt, sticker.AppID, sticker.GuildID, sticker.StickerID)
+		if err != nil {
+			return false, err
+		}
+		if stale {
+			continue
+		}
+
+		err = txn.Insert("stickers", &model.Sticker{
+			AppID:     sticker.AppID,
+			GuildID:   sticker.GuildID,
+			StickerID: sticker.StickerID,
+			Data:      sticker.Data,
+			CreatedAt: createdAt,
+			UpdatedAt: stickerUpdatedAt,
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	txn.Commit()
+	return true, nil
 }
 
 func (s *MemDBCacheStore) DeleteSticker(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, stickerID snowflake.ID) error {
@@ -1075,11 +1505,154 @@ func (s *MemDBCacheStore) DeleteSticker(ctx context.Context, appID snowflake.ID,
 
 // CacheStore methods
 
+var memDBEntityTables = []string{"guilds", "roles", "channels", "emojis", "stickers"}
+
 func (s *MemDBCacheStore) MarkShardEntitiesTainted(ctx context.Context, params store.MarkShardEntitiesTaintedParams) error {
-	// Not implemented for in-memory store
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	for _, table := range memDBEntityTables {
+		iter, err := txn.Get(table, "app_id", params.AppID)
+		if err != nil {
+			return err
+		}
+
+		// Collect first, the iterator must not be used after writing to the table.
+		var tainted []interface{}
+		for obj := iter.Next(); obj != nil; obj = iter.Next() {
+			guildID, _ := memDBEntityInfo(obj)
+			if params.InShard(guildID) {
+				tainted = append(tainted, memDBTaintedCopy(obj))
+			}
+		}
+
+		for _, obj := range tainted {
+			if err := txn.Insert(table, obj); err != nil {
+				return err
+			}
+		}
+	}
+
+	txn.Commit()
+	return nil
+}
+
+func (s *MemDBCacheStore) DeleteShardTaintedEntities(ctx context.Context, params store.DeleteShardTaintedEntitiesParams) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	sweep := newShardSweep(params)
+
+	iter, err := txn.Get("guilds", "app_id", params.AppID)
+	if err != nil {
+		return err
+	}
+	for obj := iter.Next(); obj != nil; obj = iter.Next() {
+		guild := obj.(*model.Guild)
+		if guild.Tainted && sweep.covers(guild.GuildID) {
+			sweep.removedGuildIDs[guild.GuildID] = struct{}{}
+		}
+	}
+
+	for _, table := range memDBEntityTables {
+		iter, err := txn.Get(table, "app_id", params.AppID)
+		if err != nil {
+			return err
+		}
+
+		var removed []interface{}
+		for obj := iter.Next(); obj != nil; obj = iter.Next() {
+			guildID, tainted := memDBEntityInfo(obj)
+			if sweep.shouldDelete(guildID, tainted) {
+				removed = append(removed, obj)
+			}
+		}
+
+		for _, obj := range removed {
+			if err := txn.Delete(table, obj); err != nil {
+				return err
+			}
+		}
+	}
+
+	txn.Commit()
 	return nil
 }
 
+func (s *MemDBCacheStore) DeleteAppEntities(ctx context.Context, appID snowflake.ID) (store.DeletedEntities, error) {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	var deleted store.DeletedEntities
+	counts := map[string]*int{
+		"guilds":   &deleted.Guilds,
+		"roles":    &deleted.Roles,
+		"channels": &deleted.Channels,
+		"emojis":   &deleted.Emojis,
+		"stickers": &deleted.Stickers,
+	}
+	for _, table := range memDBEntityTables {
+		n, err := txn.DeleteAll(table, "app_id", appID)
+		if err != nil {
+			return store.DeletedEntities{}, err
+		}
+		*counts[table] = n
+	}
+
+	_, err := txn.DeleteAll("guild_collections", "app_id", appID)
+	if err != nil {
+		return store.DeletedEntities{}, err
+	}
+
+	txn.Commit()
+	return deleted, nil
+}
+
+// memDBEntityInfo returns the guild ID and tainted flag of an object from one of the entity tables.
+func memDBEntityInfo(obj interface{}) (snowflake.ID, bool) {
+	switch e := obj.(type) {
+	case *model.Guild:
+		return e.GuildID, e.Tainted
+	case *model.Role:
+		return e.GuildID, e.Tainted
+	case *model.Channel:
+		return e.GuildID, e.Tainted
+	case *model.Emoji:
+		return e.GuildID, e.Tainted
+	case *model.Sticker:
+		return e.GuildID, e.Tainted
+	}
+	return 0, false
+}
+
+// memDBTaintedCopy returns a copy of the object with the tainted flag set.
+// Objects stored in memdb must not be modified in place.
+func memDBTaintedCopy(obj interface{}) interface{} {
+	switch e := obj.(type) {
+	case *model.Guild:
+		c := *e
+		c.Tainted = true
+		return &c
+	case *model.Role:
+		c := *e
+		c.Tainted = true
+		return &c
+	case *model.Channel:
+		c := *e
+		c.Tainted = true
+		return &c
+	case *model.Emoji:
+		c := *e
+		c.Tainted = true
+		return &c
+	case *model.Sticker:
+		c := *e
+		c.Tainted = true
+		return &c
+	}
+	return obj
+}
+
 func (s *MemDBCacheStore) MassUpsertEntities(ctx context.Context, params store.MassUpsertEntitiesParams) error {
 	txn := s.db.Txn(true)
 	defer txn.Abort()
@@ -1094,7 +1667,15 @@ func (s *MemDBCacheStore) MassUpsertEntities(ctx context.Context, params store.M
 			updatedAt = time.Now().UTC()
 		}
 
-		err := txn.Insert("guilds", &model.Guild{
+		stale, err := isStale(txn, "guilds", updatedAt, guild.AppID, guild.GuildID)
+		if err != nil {
+			return err
+		}
+		if stale {
+			continue
+		}
+
+		err = txn.Insert("guilds", &model.Guild{
 			AppID:     guild.AppID,
 			GuildID:   guild.GuildID,
 			Data:      guild.Data,
@@ -1116,7 +1697,15 @@ func (s *MemDBCacheStore) MassUpsertEntities(ctx context.Context, params store.M
 			updatedAt = time.Now().UTC()
 		}
 
-		err := txn.Insert("roles", &model.Role{
+		stale, err := isStale(txn, "roles", updatedAt, role.AppID, role.GuildID, role.RoleID)
+		if err != nil {
+			return err
+		}
+		if stale {
+			continue
+		}
+
+		err = txn.Insert("roles", &model.Role{
 			AppID:     role.AppID,
 			GuildID:   role.GuildID,
 			RoleID:    role.RoleID,
@@ -1139,7 +1728,15 @@ func (s *MemDBCacheStore) MassUpsertEntities(ctx context.Context, params store.M
 			updatedAt = time.Now().UTC()
 		}
 
-		err := txn.Insert("channels", &model.Channel{
+		stale, err := isStale(txn, "channels", updatedAt, channel.AppID, channel.GuildID, channel.ChannelID)
+		if err != nil {
+			return err
+		}
+		if stale {
+			continue
+		}
+
+		err = txn.Insert("channels", &model.Channel{
 			AppID:     channel.AppID,
 			GuildID:   channel.GuildID,
 			ChannelID: channel.ChannelID,
@@ -1162,7 +1759,15 @@ func (s *MemDBCacheStore) MassUpsertEntities(ctx context.Context, params store.M
 			updatedAt = time.Now().UTC()
 		}
 
-		err := txn.Insert("emojis", &model.Emoji{
+		stale, err := isStale(txn, "emojis", updatedAt, emoji.AppID, emoji.GuildID, emoji.EmojiID)
+		if err != nil {
+			return err
+		}
+		if stale {
+			continue
+		}
+
+		err = txn.Insert("emojis", &model.Emoji{
 			AppID:     emoji.AppID,
 			GuildID:   emoji.GuildID,
 			EmojiID:   emoji.EmojiID,
@@ -1185,7 +1790,15 @@ func (s *MemDBCacheStore) MassUpsertEntities(ctx context.Context, params store.M
 			updatedAt = time.Now().UTC()
 		}
 
-		err := txn.Insert("stickers", &model.Sticker{
+		stale, err := isStale(txn, "stickers", updatedAt, sticker.AppID, sticker.GuildID, sticker.StickerID)
+		if err != nil {
+			return err
+		}
+		if stale {
+			continue
+		}
+
+		err = txn.Insert("stickers", &model.Sticker{
 			AppID:     sticker.AppID,
 			GuildID:   sticker.GuildID,
 			StickerID: sticker.StickerID,
@@ -1201,3 +1814,27 @@ func (s *MemDBCacheStore) MassUpsertEntities(ctx context.Context, params store.M
 	txn.Commit()
 	return nil
 }
+
+// isStale reports whether the stored entity has been updated more recently than updatedAt.
+// Events can be redelivered or arrive out of order, so older data must never overwrite newer data.
+func isStale(txn *memdb.Txn, table string, updatedAt time.Time, args ...any) (bool, error) {
+	existing, err := txn.First(table, "id", args...)
+	if err != nil || existing == nil {
+		return false, err
+	}
+
+	var storedAt time.Time
+	switch entity := existing.(type) {
+	case *model.Guild:
+		storedAt = entity.UpdatedAt
+	case *model.Role:
+		storedAt = entity.UpdatedAt
+	case *model.Channel:
+		storedAt = entity.UpdatedAt
+	case *model.Emoji:
+		storedAt = entity.UpdatedAt
+	case *model.Sticker:
+		storedAt = entity.UpdatedAt
+	}
+	return storedAt.After(updatedAt), nil
+}
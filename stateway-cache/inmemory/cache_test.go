@@ -2,13 +2,16 @@ package inmemory
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/snowflake/v2"
 	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	"github.com/merlinfuchs/stateway/stateway-cache/store/storetest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -72,7 +75,7 @@ func TestInMemoryGuildCache(t *testing.T) {
 		t.Fatalf("failed to create in-memory cache store: %v", err)
 	}
 
-	err = cache.UpsertGuilds(context.Background(), testGuilds...)
+	_, err = cache.UpsertGuilds(context.Background(), testGuilds...)
 	assert.NoError(t, err)
 
 	guild, err := cache.GetGuild(context.Background(), 1, 1)
@@ -81,14 +84,14 @@ func TestInMemoryGuildCache(t *testing.T) {
 	assert.Equal(t, guild.AppID, snowflake.ID(1))
 	assert.Equal(t, guild.Data, discord.Guild{})
 
-	guilds, err := cache.GetGuilds(context.Background(), 1, 10, 0)
+	guilds, err := cache.GetGuilds(context.Background(), 1, store.ListOptions{Limit: 10})
 	assert.NoError(t, err)
 	assert.Equal(t, len(guilds), 4)
 	assert.Equal(t, guilds[0].GuildID, snowflake.ID(1))
 	assert.Equal(t, guilds[0].AppID, snowflake.ID(1))
 	assert.Equal(t, guilds[0].Data, discord.Guild{})
 
-	guilds, err = cache.GetGuilds(context.Background(), 2, 10, 3)
+	guilds, err = cache.GetGuilds(context.Background(), 2, store.ListOptions{Limit: 10, Offset: 3})
 	assert.NoError(t, err)
 	assert.Equal(t, len(guilds), 3)
 	assert.Equal(t, guilds[0].GuildID, snowflake.ID(5))
@@ -118,10 +121,188 @@ func TestInMemoryCacheInsertPerformance(t *testing.T) {
 					Data:    discord.Guild{},
 				})
 			}
-			err = cache.UpsertGuilds(context.Background(), data...)
+			_, err = cache.UpsertGuilds(context.Background(), data...)
 			require.NoError(t, err)
 		}
 		duration := time.Since(start)
 		fmt.Printf("Upserted %d guilds in %v\n", totalCount, duration)
 	}
 }
+
+func TestInMemoryCacheStore(t *testing.T) {
+	newStores := map[string]func(t *testing.T) store.CacheStore{
+		"map": func(t *testing.T) store.CacheStore {
+			return NewMapCacheStore()
+		},
+		"memdb": func(t *testing.T) store.CacheStore {
+			cache, err := NewMemDBCacheStore()
+			require.NoError(t, err)
+			return cache
+		},
+	}
+
+	for name, newStore := range newStores {
+		t.Run(name, func(t *testing.T) {
+			storetest.TestCacheStore(t, newStore)
+		})
+	}
+}
+
+func TestInMemoryGetCacheStatsMemoryBytes(t *testing.T) {
+	memdbCache, err := NewMemDBCacheStore()
+	require.NoError(t, err)
+
+	stores := map[string]store.CacheStore{
+		"map":   NewMapCacheStore(),
+		"memdb": memdbCache,
+	}
+
+	for name, cache := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			_, err := cache.UpsertRoles(ctx, store.UpsertRoleParams{AppID: 1, GuildID: 1, RoleID: 10, Data: discord.Role{Name: "a"}})
+			require.NoError(t, err)
+
+			stats, err := cache.GetCacheStats(ctx, store.GetCacheStatsParams{})
+			require.NoError(t, err)
+			require.Len(t, stats, 1)
+			assert.Positive(t, stats[0].MemoryBytes)
+		})
+	}
+}
+
+func TestInMemoryListGuildsAfter(t *testing.T) {
+	memdbCache, err := NewMemDBCacheStore()
+	require.NoError(t, err)
+
+	stores := map[string]store.CacheStore{
+		"map":   NewMapCacheStore(),
+		"memdb": memdbCache,
+	}
+
+	for name, cache := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			// IDs with varint encodings of different lengths to make sure they are ordered numerically
+			guildIDs := []snowflake.ID{1000, 5, 300, 70000, 128, 42}
+			for _, guildID := range guildIDs {
+				_, err := cache.UpsertGuilds(ctx, store.UpsertGuildParams{AppID: 1, GuildID: guildID})
+				require.NoError(t, err)
+			}
+
+			var pages [][]snowflake.ID
+			var after snowflake.ID
+			for {
+				guilds, err := cache.GetGuilds(ctx, 1, store.ListOptions{Limit: 2, After: after})
+				require.NoError(t, err)
+				if len(guilds) == 0 {
+					break
+				}
+
+				page := make([]snowflake.ID, len(guilds))
+				for i, guild := range guilds {
+					page[i] = guild.GuildID
+				}
+				pages = append(pages, page)
+				after = page[len(page)-1]
+			}
+
+			assert.Equal(t, [][]snowflake.ID{{5, 42}, {128, 300}, {1000, 70000}}, pages)
+		})
+	}
+}
+
+func TestInMemorySnapshotRestore(t *testing.T) {
+	newStores := map[string]func() SnapshotStore{
+		"map": func() SnapshotStore {
+			return NewMapCacheStore()
+		},
+		"memdb": func() SnapshotStore {
+			cache, err := NewMemDBCacheStore()
+			require.NoError(t, err)
+			return cache
+		},
+	}
+
+	for name, newStore := range newStores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			cache := newStore()
+
+			err := cache.MassUpsertEntities(ctx, store.MassUpsertEntitiesParams{
+				AppID:  1,
+				Guilds: []store.UpsertGuildParams{{AppID: 1, GuildID: 1}, {AppID: 1, GuildID: 2}},
+				Roles:  []store.UpsertRoleParams{{AppID: 1, GuildID: 1, RoleID: 10}},
+				Emojis: []store.UpsertEmojiParams{{AppID: 1, GuildID: 2, EmojiID: 20}},
+			})
+			require.NoError(t, err)
+
+			_, err = cache.UpsertGuilds(ctx, store.UpsertGuildParams{AppID: 2, GuildID: 3})
+			require.NoError(t, err)
+
+			err = cache.MarkGuildUnavailable(ctx, 1, 2)
+			require.NoError(t, err)
+
+			snapshot, err := cache.Snapshot(ctx)
+			require.NoError(t, err)
+
+			path := filepath.Join(t.TempDir(), "snapshot.json.gz")
+			err = WriteSnapshotFile(path, snapshot)
+			require.NoError(t, err)
+
+			snapshot, err = ReadSnapshotFile(path)
+			require.NoError(t, err)
+
+			restored := newStore()
+			err = RestoreSnapshot(ctx, restored, snapshot)
+			require.NoError(t, err)
+
+			guilds, err := restored.GetGuilds(ctx, 1, store.ListOptions{})
+			require.NoError(t, err)
+			assert.Len(t, guilds, 2)
+			for _, guild := range guilds {
+				assert.True(t, guild.Tainted)
+				assert.Equal(t, guild.GuildID == 2, guild.Unavailable)
+			}
+
+			role, err := restored.GetRole(ctx, 1, 10)
+			require.NoError(t, err)
+			assert.True(t, role.Tainted)
+
+			emoji, err := restored.GetEmoji(ctx, 1, 20)
+			require.NoError(t, err)
+			assert.True(t, emoji.Tainted)
+
+			exists, err := restored.CheckGuildExist(ctx, 2, 3)
+			require.NoError(t, err)
+			assert.True(t, exists)
+
+			guilds, err = restored.GetGuilds(ctx, 1, store.ListOptions{ExcludeTainted: true})
+			require.NoError(t, err)
+			assert.Empty(t, guilds)
+		})
+	}
+}
+
+func TestInMemorySnapshotFileLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json.gz")
+
+	locked, _, err := SnapshotFileLocked(path)
+	require.NoError(t, err)
+	assert.False(t, locked)
+
+	unlock, err := LockSnapshotFile(path)
+	require.NoError(t, err)
+
+	locked, _, err = SnapshotFileLocked(path)
+	require.NoError(t, err)
+	assert.True(t, locked)
+
+	unlock()
+
+	locked, _, err = SnapshotFileLocked(path)
+	require.NoError(t, err)
+	assert.False(t, locked)
+}
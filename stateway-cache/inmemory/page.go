@@ -0,0 +1,60 @@
+package inmemory
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+)
+
+// pageEntities sorts the entities by ID and applies the cursor, offset and limit of the list options.
+// Neither Go maps nor the memdb indexes are ordered by snowflake ID,
+// so lists have to be sorted for cursors to return consistent pages.
+func pageEntities[T any](entities []*T, opts store.ListOptions, id func(*T) snowflake.ID) []*T {
+	slices.SortFunc(entities, func(a, b *T) int {
+		return cmp.Compare(id(a), id(b))
+	})
+
+	if opts.After != 0 {
+		start, _ := slices.BinarySearchFunc(entities, opts.After, func(entity *T, after snowflake.ID) int {
+			if id(entity) <= after {
+				return -1
+			}
+			return 1
+		})
+		entities = entities[start:]
+	}
+
+	if opts.Offset > 0 {
+		if opts.Offset >= len(entities) {
+			return []*T{}
+		}
+		entities = entities[opts.Offset:]
+	}
+	if opts.Limit > 0 && opts.Limit < len(entities) {
+		entities = entities[:opts.Limit]
+	}
+	return entities
+}
+
+func guildEntityID(guild *model.Guild) snowflake.ID {
+	return guild.GuildID
+}
+
+func roleEntityID(role *model.Role) snowflake.ID {
+	return role.RoleID
+}
+
+func channelEntityID(channel *model.Channel) snowflake.ID {
+	return channel.ChannelID
+}
+
+func emojiEntityID(emoji *model.Emoji) snowflake.ID {
+	return emoji.EmojiID
+}
+
+func stickerEntityID(sticker *model.Sticker) snowflake.ID {
+	return sticker.StickerID
+}
@@ -0,0 +1,25 @@
+package inmemory
+
+import "github.com/merlinfuchs/stateway/stateway-cache/model"
+
+// The search methods of the in-memory stores evaluate the search against the entity data in Go.
+
+func guildData(guild *model.Guild) any {
+	return guild.Data
+}
+
+func roleData(role *model.Role) any {
+	return role.Data
+}
+
+func channelData(channel *model.Channel) any {
+	return channel.Data
+}
+
+func emojiData(emoji *model.Emoji) any {
+	return emoji.Data
+}
+
+func stickerData(sticker *model.Sticker) any {
+	return sticker.Data
+}
@@ -0,0 +1,98 @@
+package inmemory
+
+import (
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+)
+
+// shardSweep describes which guilds are affected by a DeleteShardTaintedEntities call.
+type shardSweep struct {
+	inShard         func(guildID snowflake.ID) bool
+	keepGuildIDs    map[snowflake.ID]struct{}
+	removedGuildIDs map[snowflake.ID]struct{}
+}
+
+func newShardSweep(params store.DeleteShardTaintedEntitiesParams) *shardSweep {
+	keep := make(map[snowflake.ID]struct{}, len(params.KeepGuildIDs))
+	for _, guildID := range params.KeepGuildIDs {
+		keep[guildID] = struct{}{}
+	}
+
+	return &shardSweep{
+		inShard:         params.InShard,
+		keepGuildIDs:    keep,
+		removedGuildIDs: make(map[snowflake.ID]struct{}),
+	}
+}
+
+// covers reports whether the guild is on the swept shard and not explicitly kept.
+func (s *shardSweep) covers(guildID snowflake.ID) bool {
+	if !s.inShard(guildID) {
+		return false
+	}
+	_, keep := s.keepGuildIDs[guildID]
+	return !keep
+}
+
+// shouldDelete reports whether an entity of the given guild has to be removed.
+func (s *shardSweep) shouldDelete(guildID snowflake.ID, tainted bool) bool {
+	if !s.covers(guildID) {
+		return false
+	}
+	if tainted {
+		return true
+	}
+	_, removed := s.removedGuildIDs[guildID]
+	return removed
+}
+
+// markShardTaintedLocked flags all guild entities of the shard as tainted.
+// The caller must hold the lock guarding the index.
+func markShardTaintedLocked[T any](
+	entitiesByGuild map[snowflake.ID]map[snowflake.ID]map[snowflake.ID]*T,
+	appID snowflake.ID,
+	inShard func(guildID snowflake.ID) bool,
+	markTainted func(*T),
+) {
+	for guildID, guildEntities := range entitiesByGuild[appID] {
+		if !inShard(guildID) {
+			continue
+		}
+		for _, entity := range guildEntities {
+			markTainted(entity)
+		}
+	}
+}
+
+// deleteShardTaintedLocked removes guild entities that are tainted or belong to a removed guild.
+// The caller must hold the lock guarding both indexes.
+func deleteShardTaintedLocked[T any](
+	entities map[snowflake.ID]map[snowflake.ID]*T,
+	entitiesByGuild map[snowflake.ID]map[snowflake.ID]map[snowflake.ID]*T,
+	appID snowflake.ID,
+	sweep *shardSweep,
+	isTainted func(*T) bool,
+) {
+	guildEntities := entitiesByGuild[appID]
+	for guildID, byID := range guildEntities {
+		for entityID, entity := range byID {
+			if !sweep.shouldDelete(guildID, isTainted(entity)) {
+				continue
+			}
+
+			delete(byID, entityID)
+			delete(entities[appID], entityID)
+		}
+
+		if len(byID) == 0 {
+			delete(guildEntities, guildID)
+		}
+	}
+
+	if len(guildEntities) == 0 {
+		delete(entitiesByGuild, appID)
+	}
+	if len(entities[appID]) == 0 {
+		delete(entities, appID)
+	}
+}
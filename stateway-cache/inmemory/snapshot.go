@@ -0,0 +1,310 @@
+package inmemory
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+)
+
+// Snapshot contains all entities of an in-memory cache store at a point in time.
+type Snapshot struct {
+	CreatedAt time.Time        `json:"created_at"`
+	Guilds    []*model.Guild   `json:"guilds"`
+	Roles     []*model.Role    `json:"roles"`
+	Channels  []*model.Channel `json:"channels"`
+	Emojis    []*model.Emoji   `json:"emojis"`
+	Stickers  []*model.Sticker `json:"stickers"`
+}
+
+// SnapshotStore is a cache store that can dump its entities into a snapshot.
+type SnapshotStore interface {
+	store.CacheStore
+	Snapshot(ctx context.Context) (*Snapshot, error)
+}
+
+// WriteSnapshotFile writes the snapshot as gzipped JSON.
+// The file is replaced atomically so a crash never leaves a partial snapshot behind.
+func WriteSnapshotFile(path string, snapshot *Snapshot) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	err = json.NewEncoder(gz).Encode(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	err = gz.Close()
+	if err != nil {
+		return fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close snapshot file: %w", err)
+	}
+
+	err = os.Rename(f.Name(), path)
+	if err != nil {
+		return fmt.Errorf("failed to replace snapshot file: %w", err)
+	}
+	return nil
+}
+
+// ReadSnapshotFile reads a snapshot written by WriteSnapshotFile.
+// The returned error wraps os.ErrNotExist if there is no snapshot yet.
+func ReadSnapshotFile(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot file: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress snapshot: %w", err)
+	}
+	defer gz.Close()
+
+	var snapshot Snapshot
+	err = json.NewDecoder(gz).Decode(&snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// LockSnapshotFile marks the snapshot as owned by a running cache server until the returned unlock function is called.
+// The server overwrites the snapshot with its own state, so other writers check SnapshotFileLocked first.
+// A lock left behind by a crashed server is taken over on the next start.
+func LockSnapshotFile(path string) (func(), error) {
+	lockPath := snapshotLockPath(path)
+	err := os.WriteFile(lockPath, []byte(strconv.Itoa(os.Getpid())), 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write snapshot lock file: %w", err)
+	}
+
+	return func() {
+		os.Remove(lockPath)
+	}, nil
+}
+
+// SnapshotFileLocked reports whether a cache server owns the snapshot and returns the path of its lock file.
+func SnapshotFileLocked(path string) (bool, string, error) {
+	lockPath := snapshotLockPath(path)
+	_, err := os.Stat(lockPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, lockPath, nil
+		}
+		return false, lockPath, fmt.Errorf("failed to check snapshot lock file: %w", err)
+	}
+	return true, lockPath, nil
+}
+
+func snapshotLockPath(path string) string {
+	return path + ".lock"
+}
+
+// RestoreSnapshot loads the entities of a snapshot into the cache store.
+// All restored entities are marked as tainted because they may be outdated,
+// they are cleared or swept once their shard receives READY again.
+func RestoreSnapshot(ctx context.Context, cacheStore store.CacheStore, snapshot *Snapshot) error {
+	apps := make(map[snowflake.ID]*store.MassUpsertEntitiesParams)
+	app := func(appID snowflake.ID) *store.MassUpsertEntitiesParams {
+		params, ok := apps[appID]
+		if !ok {
+			params = &store.MassUpsertEntitiesParams{AppID: appID}
+			apps[appID] = params
+		}
+		return params
+	}
+
+	unavailableGuilds := make([]*model.Guild, 0)
+	for _, guild := range snapshot.Guilds {
+		params := app(guild.AppID)
+		params.Guilds = append(params.Guilds, store.UpsertGuildParams{
+			AppID:     guild.AppID,
+			GuildID:   guild.GuildID,
+			Data:      guild.Data,
+			CreatedAt: guild.CreatedAt,
+			UpdatedAt: guild.UpdatedAt,
+		})
+		if guild.Unavailable {
+			unavailableGuilds = append(unavailableGuilds, guild)
+		}
+	}
+	for _, role := range snapshot.Roles {
+		params := app(role.AppID)
+		params.Roles = append(params.Roles, store.UpsertRoleParams{
+			AppID:     role.AppID,
+			GuildID:   role.GuildID,
+			RoleID:    role.RoleID,
+			Data:      role.Data,
+			CreatedAt: role.CreatedAt,
+			UpdatedAt: role.UpdatedAt,
+		})
+	}
+	for _, channel := range snapshot.Channels {
+		params := app(channel.AppID)
+		params.Channels = append(params.Channels, store.UpsertChannelParams{
+			AppID:     channel.AppID,
+			GuildID:   channel.GuildID,
+			ChannelID: channel.ChannelID,
+			Data:      channel.Data,
+			CreatedAt: channel.CreatedAt,
+			UpdatedAt: channel.UpdatedAt,
+		})
+	}
+	for _, emoji := range snapshot.Emojis {
+		params := app(emoji.AppID)
+		params.Emojis = append(params.Emojis, store.UpsertEmojiParams{
+			AppID:     emoji.AppID,
+			GuildID:   emoji.GuildID,
+			EmojiID:   emoji.EmojiID,
+			Data:      emoji.Data,
+			CreatedAt: emoji.CreatedAt,
+			UpdatedAt: emoji.UpdatedAt,
+		})
+	}
+	for _, sticker := range snapshot.Stickers {
+		params := app(sticker.AppID)
+		params.Stickers = append(params.Stickers, store.UpsertStickerParams{
+			AppID:     sticker.AppID,
+			GuildID:   sticker.GuildID,
+			StickerID: sticker.StickerID,
+			Data:      sticker.Data,
+			CreatedAt: sticker.CreatedAt,
+			UpdatedAt: sticker.UpdatedAt,
+		})
+	}
+
+	for appID, params := range apps {
+		err := cacheStore.MassUpsertEntities(ctx, *params)
+		if err != nil {
+			return fmt.Errorf("failed to restore entities of app %s: %w", appID, err)
+		}
+
+		// A single shard covers all guilds of the app
+		err = cacheStore.MarkShardEntitiesTainted(ctx, store.MarkShardEntitiesTaintedParams{
+			AppID:      appID,
+			ShardCount: 1,
+			ShardID:    0,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to mark restored entities of app %s as tainted: %w", appID, err)
+		}
+	}
+
+	for _, guild := range unavailableGuilds {
+		err := cacheStore.MarkGuildUnavailable(ctx, guild.AppID, guild.GuildID)
+		if err != nil {
+			return fmt.Errorf("failed to restore unavailable guild %s: %w", guild.GuildID, err)
+		}
+	}
+
+	return nil
+}
+
+var (
+	_ SnapshotStore = (*MapCacheStore)(nil)
+	_ SnapshotStore = (*MemDBCacheStore)(nil)
+)
+
+func (s *MapCacheStore) Snapshot(ctx context.Context) (*Snapshot, error) {
+	snapshot := &Snapshot{CreatedAt: time.Now().UTC()}
+
+	// Entities are copied while holding the lock because some flags are updated in place.
+	s.guildsMu.RLock()
+	for _, appGuilds := range s.guilds {
+		for _, guild := range appGuilds {
+			g := *guild
+			snapshot.Guilds = append(snapshot.Guilds, &g)
+		}
+	}
+	s.guildsMu.RUnlock()
+
+	s.rolesMu.RLock()
+	for _, appRoles := range s.roles {
+		for _, role := range appRoles {
+			r := *role
+			snapshot.Roles = append(snapshot.Roles, &r)
+		}
+	}
+	s.rolesMu.RUnlock()
+
+	s.channelsMu.RLock()
+	for _, appChannels := range s.channels {
+		for _, channel := range appChannels {
+			c := *channel
+			snapshot.Channels = append(snapshot.Channels, &c)
+		}
+	}
+	s.channelsMu.RUnlock()
+
+	s.emojisMu.RLock()
+	for _, appEmojis := range s.emojis {
+		for _, emoji := range appEmojis {
+			e := *emoji
+			snapshot.Emojis = append(snapshot.Emojis, &e)
+		}
+	}
+	s.emojisMu.RUnlock()
+
+	s.stickersMu.RLock()
+	for _, appStickers := range s.stickers {
+		for _, sticker := range appStickers {
+			st := *sticker
+			snapshot.Stickers = append(snapshot.Stickers, &st)
+		}
+	}
+	s.stickersMu.RUnlock()
+
+	return snapshot, nil
+}
+
+func (s *MemDBCacheStore) Snapshot(ctx context.Context) (*Snapshot, error) {
+	snapshot := &Snapshot{CreatedAt: time.Now().UTC()}
+
+	// Objects in memdb are never modified in place, so a read transaction is a consistent view.
+	txn := s.db.Txn(false)
+	defer txn.Abort()
+
+	for _, table := range memDBEntityTables {
+		iter, err := txn.Get(table, "id_prefix")
+		if err != nil {
+			return nil, err
+		}
+
+		for obj := iter.Next(); obj != nil; obj = iter.Next() {
+			switch e := obj.(type) {
+			case *model.Guild:
+				snapshot.Guilds = append(snapshot.Guilds, e)
+			case *model.Role:
+				snapshot.Roles = append(snapshot.Roles, e)
+			case *model.Channel:
+				snapshot.Channels = append(snapshot.Channels, e)
+			case *model.Emoji:
+				snapshot.Emojis = append(snapshot.Emojis, e)
+			case *model.Sticker:
+				snapshot.Stickers = append(snapshot.Stickers, e)
+			}
+		}
+	}
+
+	return snapshot, nil
+}
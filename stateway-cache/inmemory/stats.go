@@ -0,0 +1,157 @@
+package inmemory
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"unsafe"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+)
+
+// GetCacheStats walks the entities of the apps under the read lock of each entity type.
+// Only the entities of the requested app are visited when an app ID is set.
+func (s *MapCacheStore) GetCacheStats(ctx context.Context, params store.GetCacheStatsParams) ([]*model.CacheStats, error) {
+	collector := store.NewStatsCollector()
+	if params.AppID != 0 {
+		collector.App(params.AppID)
+	}
+
+	collectMapStats(collector, params.AppID, &s.guildsMu, s.guilds, addGuildStats)
+	collectMapStats(collector, params.AppID, &s.channelsMu, s.channels, addChannelStats)
+	collectMapStats(collector, params.AppID, &s.rolesMu, s.roles, addRoleStats)
+	collectMapStats(collector, params.AppID, &s.emojisMu, s.emojis, addEmojiStats)
+	collectMapStats(collector, params.AppID, &s.stickersMu, s.stickers, addStickerStats)
+
+	return collector.Stats(), nil
+}
+
+// collectMapStats adds the entities of the app, or of all apps for 0, to the collector.
+func collectMapStats[T any](
+	collector *store.StatsCollector,
+	appID snowflake.ID,
+	mu *sync.RWMutex,
+	entities map[snowflake.ID]map[snowflake.ID]*T,
+	add func(*model.CacheStats, *T),
+) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for entityAppID, appEntities := range entities {
+		if (appID != 0 && entityAppID != appID) || len(appEntities) == 0 {
+			continue
+		}
+
+		stats := collector.App(entityAppID)
+		for _, entity := range appEntities {
+			add(stats, entity)
+		}
+	}
+}
+
+// GetCacheStats iterates the entities of a read transaction, through the app index when an app ID is set.
+func (s *MemDBCacheStore) GetCacheStats(ctx context.Context, params store.GetCacheStatsParams) ([]*model.CacheStats, error) {
+	collector := store.NewStatsCollector()
+	if params.AppID != 0 {
+		collector.App(params.AppID)
+	}
+
+	txn := s.db.Txn(false)
+	defer txn.Abort()
+
+	for _, table := range memDBEntityTables {
+		index, args := "id_prefix", []any{}
+		if params.AppID != 0 {
+			index, args = "app_id", []any{params.AppID}
+		}
+
+		iter, err := txn.Get(table, index, args...)
+		if err != nil {
+			return nil, err
+		}
+
+		for obj := iter.Next(); obj != nil; obj = iter.Next() {
+			switch e := obj.(type) {
+			case *model.Guild:
+				addGuildStats(collector.App(e.AppID), e)
+			case *model.Channel:
+				addChannelStats(collector.App(e.AppID), e)
+			case *model.Role:
+				addRoleStats(collector.App(e.AppID), e)
+			case *model.Emoji:
+				addEmojiStats(collector.App(e.AppID), e)
+			case *model.Sticker:
+				addStickerStats(collector.App(e.AppID), e)
+			}
+		}
+	}
+
+	return collector.Stats(), nil
+}
+
+// addGuildStats adds the guild to the stats of its app.
+// The memory usage of all entity types is estimated from the size of the entity structs and their strings and slices,
+// which is good enough to compare apps and much cheaper than encoding every entity.
+func addGuildStats(stats *model.CacheStats, guild *model.Guild) {
+	store.AddEntityStats(&stats.Guilds, store.StatsEntity{
+		AppID:       guild.AppID,
+		Unavailable: guild.Unavailable,
+		Tainted:     guild.Tainted,
+		UpdatedAt:   guild.UpdatedAt,
+	})
+
+	size := int64(unsafe.Sizeof(*guild)) + int64(len(guild.Data.Name)+len(guild.Data.PreferredLocale))
+	for _, feature := range guild.Data.Features {
+		size += int64(unsafe.Sizeof(feature)) + int64(len(feature))
+	}
+	stats.MemoryBytes += size
+}
+
+func addChannelStats(stats *model.CacheStats, channel *model.Channel) {
+	store.AddEntityStats(&stats.Channels, store.StatsEntity{
+		AppID:     channel.AppID,
+		Tainted:   channel.Tainted,
+		UpdatedAt: channel.UpdatedAt,
+	})
+
+	size := int64(unsafe.Sizeof(*channel))
+	if channel.Data != nil {
+		// The channel data is boxed in an interface, so the size of its concrete type is added on top
+		size += int64(reflect.TypeOf(channel.Data).Size()) + int64(len(channel.Data.Name()))
+	}
+	stats.MemoryBytes += size
+}
+
+func addRoleStats(stats *model.CacheStats, role *model.Role) {
+	store.AddEntityStats(&stats.Roles, store.StatsEntity{
+		AppID:     role.AppID,
+		Tainted:   role.Tainted,
+		UpdatedAt: role.UpdatedAt,
+	})
+
+	stats.MemoryBytes += int64(unsafe.Sizeof(*role)) + int64(len(role.Data.Name))
+}
+
+func addEmojiStats(stats *model.CacheStats, emoji *model.Emoji) {
+	store.AddEntityStats(&stats.Emojis, store.StatsEntity{
+		AppID:     emoji.AppID,
+		Tainted:   emoji.Tainted,
+		UpdatedAt: emoji.UpdatedAt,
+	})
+
+	stats.MemoryBytes += int64(unsafe.Sizeof(*emoji)) + int64(len(emoji.Data.Name)) +
+		int64(len(emoji.Data.Roles))*int64(unsafe.Sizeof(snowflake.ID(0)))
+}
+
+func addStickerStats(stats *model.CacheStats, sticker *model.Sticker) {
+	store.AddEntityStats(&stats.Stickers, store.StatsEntity{
+		AppID:     sticker.AppID,
+		Tainted:   sticker.Tainted,
+		UpdatedAt: sticker.UpdatedAt,
+	})
+
+	stats.MemoryBytes += int64(unsafe.Sizeof(*sticker)) +
+		int64(len(sticker.Data.Name)+len(sticker.Data.Description)+len(sticker.Data.Tags))
+}
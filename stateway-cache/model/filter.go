@@ -0,0 +1,5 @@
+package model
+
+import "github.com/merlinfuchs/stateway/stateway-lib/cache"
+
+type Filter = cache.Filter
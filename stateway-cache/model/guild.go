@@ -3,3 +3,9 @@ package model
 import "github.com/merlinfuchs/stateway/stateway-lib/cache"
 
 type Guild = cache.Guild
+
+type GuildApp = cache.GuildApp
+
+type FullGuild = cache.FullGuild
+
+type FullGuildInclude = cache.FullGuildInclude
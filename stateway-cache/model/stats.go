@@ -0,0 +1,7 @@
+package model
+
+import "github.com/merlinfuchs/stateway/stateway-lib/cache"
+
+type CacheStats = cache.CacheStats
+
+type EntityStats = cache.EntityStats
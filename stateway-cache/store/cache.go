@@ -4,14 +4,66 @@ import (
 	"context"
 
 	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
 )
 
+// ListOptions controls pagination and filtering of list and search methods.
+type ListOptions struct {
+	Limit  int
+	Offset int
+	// ExcludeTainted skips entities that are still flagged as tainted.
+	ExcludeTainted bool
+	// After only returns entities with a higher ID, entities are always ordered by ID.
+	After snowflake.ID
+}
+
+// GuildShardID computes the shard a guild belongs to using the same formula as Discord.
+func GuildShardID(guildID snowflake.ID, shardCount int) int {
+	if shardCount <= 1 {
+		return 0
+	}
+	return int((uint64(guildID) >> 22) % uint64(shardCount))
+}
+
+// GuildInShard reports whether the guild is on the shard and belongs to the cache partition.
+// A partitionCount of 0 or 1 means the cache isn't partitioned.
+func GuildInShard(guildID snowflake.ID, shardCount int, shardID int, partitionCount int, partitionID int) bool {
+	return GuildShardID(guildID, shardCount) == shardID && GuildShardID(guildID, partitionCount) == partitionID
+}
+
 type MarkShardEntitiesTaintedParams struct {
 	AppID      snowflake.ID
 	ShardCount int
 	ShardID    int
+	// PartitionCount and PartitionID limit the shard to the guilds of a cache partition,
+	// partitions that share a store must not taint the guilds of other partitions.
+	PartitionCount int
+	PartitionID    int
+}
+
+// InShard reports whether the entities of the guild are marked as tainted.
+func (p MarkShardEntitiesTaintedParams) InShard(guildID snowflake.ID) bool {
+	return GuildInShard(guildID, p.ShardCount, p.ShardID, p.PartitionCount, p.PartitionID)
 }
 
+type DeleteShardTaintedEntitiesParams struct {
+	AppID      snowflake.ID
+	ShardCount int
+	ShardID    int
+	// PartitionCount and PartitionID limit the shard to the guilds of a cache partition,
+	// partitions that share a store must not delete the guilds of other partitions.
+	PartitionCount int
+	PartitionID    int
+	// KeepGuildIDs are guilds that haven't been received since READY yet and must not be deleted.
+	KeepGuildIDs []snowflake.ID
+}
+
+// InShard reports whether the tainted entities of the guild are deleted, unless the guild is kept.
+func (p DeleteShardTaintedEntitiesParams) InShard(guildID snowflake.ID) bool {
+	return GuildInShard(guildID, p.ShardCount, p.ShardID, p.PartitionCount, p.PartitionID)
+}
+
+// MassUpsertEntitiesParams holds the entities of one or more guilds of the app.
 type MassUpsertEntitiesParams struct {
 	AppID    snowflake.ID
 	Guilds   []UpsertGuildParams
@@ -21,6 +73,15 @@ type MassUpsertEntitiesParams struct {
 	Stickers []UpsertStickerParams
 }
 
+// DeletedEntities is the number of entities per type that have been deleted.
+type DeletedEntities struct {
+	Guilds   int
+	Roles    int
+	Channels int
+	Emojis   int
+	Stickers int
+}
+
 type CacheStore interface {
 	CacheGuildStore
 	CacheRoleStore
@@ -29,5 +90,12 @@ type CacheStore interface {
 	CacheStickerStore
 
 	MarkShardEntitiesTainted(ctx context.Context, params MarkShardEntitiesTaintedParams) error
+	DeleteShardTaintedEntities(ctx context.Context, params DeleteShardTaintedEntitiesParams) error
+	// MassUpsertEntities upserts all entities at once, entities that are stored with a more recent UpdatedAt are skipped.
+	// Events can be redelivered or arrive out of order, this makes sure that older data never overwrites newer data.
 	MassUpsertEntities(ctx context.Context, params MassUpsertEntitiesParams) error
+	// DeleteAppEntities deletes all guilds, roles, channels, emojis and stickers of the app.
+	DeleteAppEntities(ctx context.Context, appID snowflake.ID) (DeletedEntities, error)
+	// GetCacheStats returns the stats of the cached entities per app, ordered by app ID.
+	GetCacheStats(ctx context.Context, params GetCacheStatsParams) ([]*model.CacheStats, error)
 }
@@ -21,29 +21,32 @@ type UpsertChannelParams struct {
 
 type SearchChannelsParams struct {
 	AppID  snowflake.ID
-	Limit  int
-	Offset int
 	Data   json.RawMessage
+	Filter *model.Filter
+	ListOptions
 }
 
 type SearchGuildChannelsParams struct {
 	AppID   snowflake.ID
 	GuildID snowflake.ID
-	Limit   int
-	Offset  int
 	Data    json.RawMessage
+	Filter  *model.Filter
+	ListOptions
 }
 
 type CacheChannelStore interface {
 	GetGuildChannel(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, channelID snowflake.ID) (*model.Channel, error)
 	GetChannel(ctx context.Context, appID snowflake.ID, channelID snowflake.ID) (*model.Channel, error)
-	GetGuildChannels(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, limit int, offset int) ([]*model.Channel, error)
-	GetChannels(ctx context.Context, appID snowflake.ID, limit int, offset int) ([]*model.Channel, error)
-	GetChannelsByType(ctx context.Context, appID snowflake.ID, types []int, limit int, offset int) ([]*model.Channel, error)
+	GetGuildChannels(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts ListOptions) ([]*model.Channel, error)
+	GetChannels(ctx context.Context, appID snowflake.ID, opts ListOptions) ([]*model.Channel, error)
+	// GetChannelsByIDs returns the channels that exist in no particular order.
+	GetChannelsByIDs(ctx context.Context, appID snowflake.ID, channelIDs []snowflake.ID) ([]*model.Channel, error)
+	GetChannelsByType(ctx context.Context, appID snowflake.ID, types []int, opts ListOptions) ([]*model.Channel, error)
 	SearchGuildChannels(ctx context.Context, params SearchGuildChannelsParams) ([]*model.Channel, error)
 	SearchChannels(ctx context.Context, params SearchChannelsParams) ([]*model.Channel, error)
 	CountGuildChannels(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (int, error)
 	CountChannels(ctx context.Context, appID snowflake.ID) (int, error)
-	UpsertChannels(ctx context.Context, channels ...UpsertChannelParams) error
+	// UpsertChannels skips the channels that are stored with a more recent UpdatedAt and returns how many have been written.
+	UpsertChannels(ctx context.Context, channels ...UpsertChannelParams) (int, error)
 	DeleteChannel(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, channelID snowflake.ID) error
 }
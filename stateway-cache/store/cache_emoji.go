@@ -21,28 +21,34 @@ type UpsertEmojiParams struct {
 
 type SearchEmojisParams struct {
 	AppID  snowflake.ID
-	Limit  int
-	Offset int
 	Data   json.RawMessage
+	Filter *model.Filter
+	ListOptions
 }
 
 type SearchGuildEmojisParams struct {
 	AppID   snowflake.ID
 	GuildID snowflake.ID
-	Limit   int
-	Offset  int
 	Data    json.RawMessage
+	Filter  *model.Filter
+	ListOptions
 }
 
 type CacheEmojiStore interface {
 	GetGuildEmoji(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, emojiID snowflake.ID) (*model.Emoji, error)
 	GetEmoji(ctx context.Context, appID snowflake.ID, emojiID snowflake.ID) (*model.Emoji, error)
-	GetGuildEmojis(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, limit int, offset int) ([]*model.Emoji, error)
-	GetEmojis(ctx context.Context, appID snowflake.ID, limit int, offset int) ([]*model.Emoji, error)
+	GetGuildEmojis(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts ListOptions) ([]*model.Emoji, error)
+	GetEmojis(ctx context.Context, appID snowflake.ID, opts ListOptions) ([]*model.Emoji, error)
+	// GetEmojisByIDs returns the emojis that exist in no particular order.
+	GetEmojisByIDs(ctx context.Context, appID snowflake.ID, emojiIDs []snowflake.ID) ([]*model.Emoji, error)
 	SearchGuildEmojis(ctx context.Context, params SearchGuildEmojisParams) ([]*model.Emoji, error)
 	SearchEmojis(ctx context.Context, params SearchEmojisParams) ([]*model.Emoji, error)
 	CountGuildEmojis(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (int, error)
 	CountEmojis(ctx context.Context, appID snowflake.ID) (int, error)
-	UpsertEmojis(ctx context.Context, emojis ...UpsertEmojiParams) error
+	// UpsertEmojis skips the emojis that are stored with a more recent UpdatedAt and returns how many have been written.
+	UpsertEmojis(ctx context.Context, emojis ...UpsertEmojiParams) (int, error)
+	// ReplaceGuildEmojis reports whether the emojis of the guild have been replaced.
+	// They are kept when they have been replaced by a more recent event, and emojis updated after updatedAt aren't deleted.
+	ReplaceGuildEmojis(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, updatedAt time.Time, emojis ...UpsertEmojiParams) (bool, error)
 	DeleteEmoji(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, emojiID snowflake.ID) error
 }
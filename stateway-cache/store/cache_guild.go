@@ -20,17 +20,34 @@ type UpsertGuildParams struct {
 
 type SearchGuildsParams struct {
 	AppID  snowflake.ID
-	Limit  int
-	Offset int
 	Data   json.RawMessage
+	Filter *model.Filter
+	ListOptions
+}
+
+type GetFullGuildParams struct {
+	AppID   snowflake.ID
+	GuildID snowflake.ID
+	Include model.FullGuildInclude
+	// ExcludeTainted skips tainted entities in the included collections.
+	ExcludeTainted bool
 }
 
 type CacheGuildStore interface {
 	GetGuild(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (*model.Guild, error)
+	// GetFullGuild reads the guild and the included collections from a single snapshot.
+	// The collections are ordered by ID.
+	GetFullGuild(ctx context.Context, params GetFullGuildParams) (*model.FullGuild, error)
 	GetGuildOwnerID(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (snowflake.ID, error)
-	GetGuilds(ctx context.Context, appID snowflake.ID, limit int, offset int) ([]*model.Guild, error)
+	GetGuilds(ctx context.Context, appID snowflake.ID, opts ListOptions) ([]*model.Guild, error)
+	// GetGuildsByIDs returns the guilds that exist in no particular order.
+	GetGuildsByIDs(ctx context.Context, appID snowflake.ID, guildIDs []snowflake.ID) ([]*model.Guild, error)
 	CheckGuildExist(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (bool, error)
-	UpsertGuilds(ctx context.Context, guilds ...UpsertGuildParams) error
+	// GetGuildApps returns the apps that have the guild cached, ordered by app ID.
+	GetGuildApps(ctx context.Context, guildID snowflake.ID) ([]*model.GuildApp, error)
+	// UpsertGuilds skips the guilds that are stored with a more recent UpdatedAt and returns how many have been written.
+	UpsertGuilds(ctx context.Context, guilds ...UpsertGuildParams) (int, error)
+	// MarkGuildUnavailable keeps the UpdatedAt of the guild, so the guild is still updated by the next event.
 	MarkGuildUnavailable(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) error
 	DeleteGuild(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) error
 	SearchGuilds(ctx context.Context, params SearchGuildsParams) ([]*model.Guild, error)
@@ -21,29 +21,32 @@ type UpsertRoleParams struct {
 
 type SearchRolesParams struct {
 	AppID  snowflake.ID
-	Limit  int
-	Offset int
 	Data   json.RawMessage
+	Filter *model.Filter
+	ListOptions
 }
 
 type SearchGuildRolesParams struct {
 	AppID   snowflake.ID
 	GuildID snowflake.ID
-	Limit   int
-	Offset  int
 	Data    json.RawMessage
+	Filter  *model.Filter
+	ListOptions
 }
 
 type CacheRoleStore interface {
 	GetGuildRole(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, roleID snowflake.ID) (*model.Role, error)
 	GetRole(ctx context.Context, appID snowflake.ID, roleID snowflake.ID) (*model.Role, error)
-	GetGuildRoles(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, limit int, offset int) ([]*model.Role, error)
+	GetGuildRoles(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts ListOptions) ([]*model.Role, error)
 	GetGuildRolesByIDs(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, roleIDs []snowflake.ID) ([]*model.Role, error)
-	GetRoles(ctx context.Context, appID snowflake.ID, limit int, offset int) ([]*model.Role, error)
+	GetRoles(ctx context.Context, appID snowflake.ID, opts ListOptions) ([]*model.Role, error)
+	// GetRolesByIDs returns the roles that exist in no particular order.
+	GetRolesByIDs(ctx context.Context, appID snowflake.ID, roleIDs []snowflake.ID) ([]*model.Role, error)
 	SearchGuildRoles(ctx context.Context, params SearchGuildRolesParams) ([]*model.Role, error)
 	SearchRoles(ctx context.Context, params SearchRolesParams) ([]*model.Role, error)
 	CountGuildRoles(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (int, error)
 	CountRoles(ctx context.Context, appID snowflake.ID) (int, error)
-	UpsertRoles(ctx context.Context, roles ...UpsertRoleParams) error
+	// UpsertRoles skips the roles that are stored with a more recent UpdatedAt and returns how many have been written.
+	UpsertRoles(ctx context.Context, roles ...UpsertRoleParams) (int, error)
 	DeleteRole(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, roleID snowflake.ID) error
 }
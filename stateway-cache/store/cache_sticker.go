@@ -21,28 +21,34 @@ type UpsertStickerParams struct {
 
 type SearchStickersParams struct {
 	AppID  snowflake.ID
-	Limit  int
-	Offset int
 	Data   json.RawMessage
+	Filter *model.Filter
+	ListOptions
 }
 
 type SearchGuildStickersParams struct {
 	AppID   snowflake.ID
 	GuildID snowflake.ID
-	Limit   int
-	Offset  int
 	Data    json.RawMessage
+	Filter  *model.Filter
+	ListOptions
 }
 
 type CacheStickerStore interface {
 	GetSticker(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, stickerID snowflake.ID) (*model.Sticker, error)
 	GetGuildSticker(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, stickerID snowflake.ID) (*model.Sticker, error)
-	GetStickers(ctx context.Context, appID snowflake.ID, limit int, offset int) ([]*model.Sticker, error)
-	GetGuildStickers(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, limit int, offset int) ([]*model.Sticker, error)
+	GetStickers(ctx context.Context, appID snowflake.ID, opts ListOptions) ([]*model.Sticker, error)
+	// GetStickersByIDs returns the stickers that exist in no particular order.
+	GetStickersByIDs(ctx context.Context, appID snowflake.ID, stickerIDs []snowflake.ID) ([]*model.Sticker, error)
+	GetGuildStickers(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, opts ListOptions) ([]*model.Sticker, error)
 	SearchStickers(ctx context.Context, params SearchStickersParams) ([]*model.Sticker, error)
 	SearchGuildStickers(ctx context.Context, params SearchGuildStickersParams) ([]*model.Sticker, error)
 	CountGuildStickers(ctx context.Context, appID snowflake.ID, guildID snowflake.ID) (int, error)
 	CountStickers(ctx context.Context, appID snowflake.ID) (int, error)
-	UpsertStickers(ctx context.Context, stickers ...UpsertStickerParams) error
+	// UpsertStickers skips the stickers that are stored with a more recent UpdatedAt and returns how many have been written.
+	UpsertStickers(ctx context.Context, stickers ...UpsertStickerParams) (int, error)
+	// ReplaceGuildStickers reports whether the stickers of the guild have been replaced.
+	// They are kept when they have been replaced by a more recent event, and stickers updated after updatedAt aren't deleted.
+	ReplaceGuildStickers(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, updatedAt time.Time, stickers ...UpsertStickerParams) (bool, error)
 	DeleteSticker(ctx context.Context, appID snowflake.ID, guildID snowflake.ID, stickerID snowflake.ID) error
 }
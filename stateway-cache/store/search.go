@@ -0,0 +1,135 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
+)
+
+// SearchMatcher evaluates the data and filter of a search in Go.
+// It is used by stores that can't query the JSON data of entities natively.
+type SearchMatcher struct {
+	query  any
+	filter *model.Filter
+}
+
+// NewSearchMatcher creates a matcher for entities whose data contains the data
+// of the search, like the jsonb @> operator, and that match the filter.
+// Both data and filter are optional.
+func NewSearchMatcher(data json.RawMessage, filter *model.Filter) (*SearchMatcher, error) {
+	m := &SearchMatcher{}
+
+	if len(data) != 0 {
+		err := json.Unmarshal(data, &m.query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal search data: %w", err)
+		}
+	}
+
+	if filter != nil {
+		normalized, err := filter.Normalize()
+		if err != nil {
+			return nil, fmt.Errorf("invalid search filter: %w", err)
+		}
+		m.filter = &normalized
+	}
+
+	return m, nil
+}
+
+// Match reports whether the Discord data of an entity matches the search.
+func (m *SearchMatcher) Match(data any) (bool, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal entity data: %w", err)
+	}
+
+	var value any
+	err = json.Unmarshal(raw, &value)
+	if err != nil {
+		return false, fmt.Errorf("failed to unmarshal entity data: %w", err)
+	}
+
+	if m.query != nil && !jsonContains(value, m.query) {
+		return false, nil
+	}
+	if m.filter != nil && !m.filter.Match(value) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// SearchEntities returns the page of entities whose data matches the search.
+// The entities must not be paginated yet, opts.ExcludeTainted is expected to be applied already.
+func SearchEntities[T any](
+	entities []*T,
+	data json.RawMessage,
+	filter *model.Filter,
+	opts ListOptions,
+	getData func(*T) any,
+) ([]*T, error) {
+	matcher, err := NewSearchMatcher(data, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*T, 0)
+	skipped := 0
+	for _, entity := range entities {
+		ok, err := matcher.Match(getData(entity))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		if skipped < opts.Offset {
+			skipped++
+			continue
+		}
+
+		res = append(res, entity)
+		if opts.Limit > 0 && len(res) >= opts.Limit {
+			break
+		}
+	}
+
+	return res, nil
+}
+
+// jsonContains mirrors the semantics of the jsonb @> operator for decoded JSON values.
+func jsonContains(value any, query any) bool {
+	switch q := query.(type) {
+	case map[string]any:
+		v, ok := value.(map[string]any)
+		if !ok {
+			return false
+		}
+		for key, queryValue := range q {
+			fieldValue, ok := v[key]
+			if !ok || !jsonContains(fieldValue, queryValue) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		v, ok := value.([]any)
+		if !ok {
+			return false
+		}
+		for _, queryElement := range q {
+			if !slices.ContainsFunc(v, func(element any) bool {
+				return jsonContains(element, queryElement)
+			}) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(value, query)
+	}
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
+)
+
+type GetCacheStatsParams struct {
+	// AppID limits the stats to a single app, 0 returns the stats of all apps.
+	AppID snowflake.ID
+}
+
+// StatsEntity holds the fields of an entity that the stats depend on.
+// Stores that keep entities as JSON can decode it without decoding the entity data.
+type StatsEntity struct {
+	AppID       snowflake.ID `json:"app_id"`
+	Unavailable bool         `json:"unavailable"`
+	Tainted     bool         `json:"tainted"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// StatsCollector collects the stats of stores that compute them from the individual entities.
+type StatsCollector struct {
+	apps map[snowflake.ID]*model.CacheStats
+}
+
+func NewStatsCollector() *StatsCollector {
+	return &StatsCollector{
+		apps: make(map[snowflake.ID]*model.CacheStats),
+	}
+}
+
+// App returns the stats of the app, they are created if the app has no stats yet.
+func (c *StatsCollector) App(appID snowflake.ID) *model.CacheStats {
+	stats, ok := c.apps[appID]
+	if !ok {
+		stats = &model.CacheStats{AppID: appID}
+		c.apps[appID] = stats
+	}
+	return stats
+}
+
+// Stats returns the stats of all apps ordered by app ID.
+func (c *StatsCollector) Stats() []*model.CacheStats {
+	res := make([]*model.CacheStats, 0, len(c.apps))
+	for _, stats := range c.apps {
+		res = append(res, stats)
+	}
+
+	slices.SortFunc(res, func(a, b *model.CacheStats) int {
+		return cmp.Compare(a.AppID, b.AppID)
+	})
+	return res
+}
+
+// AddEntityStats counts the entity towards the stats of its type.
+func AddEntityStats(stats *model.EntityStats, entity StatsEntity) {
+	stats.Count++
+	if entity.Tainted {
+		stats.Tainted++
+	}
+	if entity.Unavailable {
+		stats.Unavailable++
+	}
+
+	if stats.OldestUpdatedAt == nil || entity.UpdatedAt.Before(*stats.OldestUpdatedAt) {
+		updatedAt := entity.UpdatedAt
+		stats.OldestUpdatedAt = &updatedAt
+	}
+}
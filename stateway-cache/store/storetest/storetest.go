@@ -0,0 +1,615 @@
+// Package storetest implements a conformance suite for cache stores.
+package storetest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-cache/model"
+	"github.com/merlinfuchs/stateway/stateway-cache/store"
+	cachelib "github.com/merlinfuchs/stateway/stateway-lib/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCacheStore runs the conformance suite against the cache store implementation.
+// Every subtest gets an empty store from newStore.
+func TestCacheStore(t *testing.T, newStore func(t *testing.T) store.CacheStore) {
+	tests := map[string]func(t *testing.T, cache store.CacheStore){
+		"GetByIDs":           testGetByIDs,
+		"GetFullGuild":       testGetFullGuild,
+		"GetCacheStats":      testGetCacheStats,
+		"DeleteAppEntities":  testDeleteAppEntities,
+		"GetGuildApps":       testGetGuildApps,
+		"ReplaceGuildEmojis": testReplaceGuildEmojis,
+		"DeleteShardTainted": testDeleteShardTainted,
+		"SkipStaleUpserts":   testSkipStaleUpserts,
+		"SearchWithFilter":   testSearchWithFilter,
+		"PartitionedSweep":   testPartitionedSweep,
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test(t, newStore(t))
+		})
+	}
+}
+
+func testGetByIDs(t *testing.T, cache store.CacheStore) {
+	ctx := context.Background()
+
+	err := cache.MassUpsertEntities(ctx, store.MassUpsertEntitiesParams{
+		AppID: 1,
+		Guilds: []store.UpsertGuildParams{
+			{AppID: 1, GuildID: 1},
+			{AppID: 1, GuildID: 2},
+			{AppID: 1, GuildID: 3},
+		},
+		Roles: []store.UpsertRoleParams{
+			{AppID: 1, GuildID: 1, RoleID: 1},
+			{AppID: 1, GuildID: 2, RoleID: 2},
+			{AppID: 1, GuildID: 2, RoleID: 3},
+		},
+		Channels: []store.UpsertChannelParams{
+			{AppID: 1, GuildID: 1, ChannelID: 1, Data: discord.GuildTextChannel{}},
+			{AppID: 1, GuildID: 2, ChannelID: 2, Data: discord.GuildTextChannel{}},
+		},
+		Emojis: []store.UpsertEmojiParams{
+			{AppID: 1, GuildID: 1, EmojiID: 1},
+			{AppID: 1, GuildID: 2, EmojiID: 2},
+		},
+		Stickers: []store.UpsertStickerParams{
+			{AppID: 1, GuildID: 1, StickerID: 1},
+			{AppID: 1, GuildID: 2, StickerID: 2},
+		},
+	})
+	require.NoError(t, err)
+
+	err = cache.MassUpsertEntities(ctx, store.MassUpsertEntitiesParams{
+		AppID:  2,
+		Guilds: []store.UpsertGuildParams{{AppID: 2, GuildID: 4}},
+		Roles:  []store.UpsertRoleParams{{AppID: 2, GuildID: 4, RoleID: 4}},
+	})
+	require.NoError(t, err)
+
+	// Missing IDs and entities of other apps are skipped
+	guilds, err := cache.GetGuildsByIDs(ctx, 1, []snowflake.ID{3, 4, 1})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []snowflake.ID{1, 3}, entityIDs(guilds, func(g *model.Guild) snowflake.ID { return g.GuildID }))
+
+	roles, err := cache.GetRolesByIDs(ctx, 1, []snowflake.ID{3, 4, 1, 5})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []snowflake.ID{1, 3}, entityIDs(roles, func(r *model.Role) snowflake.ID { return r.RoleID }))
+
+	channels, err := cache.GetChannelsByIDs(ctx, 1, []snowflake.ID{2, 3})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []snowflake.ID{2}, entityIDs(channels, func(c *model.Channel) snowflake.ID { return c.ChannelID }))
+
+	emojis, err := cache.GetEmojisByIDs(ctx, 1, []snowflake.ID{1, 2})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []snowflake.ID{1, 2}, entityIDs(emojis, func(e *model.Emoji) snowflake.ID { return e.EmojiID }))
+
+	stickers, err := cache.GetStickersByIDs(ctx, 1, []snowflake.ID{2})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []snowflake.ID{2}, entityIDs(stickers, func(s *model.Sticker) snowflake.ID { return s.StickerID }))
+
+	guilds, err = cache.GetGuildsByIDs(ctx, 1, nil)
+	require.NoError(t, err)
+	assert.Empty(t, guilds)
+}
+
+func testGetFullGuild(t *testing.T, cache store.CacheStore) {
+	ctx := context.Background()
+
+	err := cache.MassUpsertEntities(ctx, store.MassUpsertEntitiesParams{
+		AppID:  1,
+		Guilds: []store.UpsertGuildParams{{AppID: 1, GuildID: 1}},
+		Channels: []store.UpsertChannelParams{
+			{AppID: 1, GuildID: 1, ChannelID: 3, Data: discord.GuildTextChannel{}},
+			{AppID: 1, GuildID: 1, ChannelID: 2, Data: discord.GuildTextChannel{}},
+			{AppID: 1, GuildID: 2, ChannelID: 4, Data: discord.GuildTextChannel{}},
+		},
+		Roles: []store.UpsertRoleParams{
+			{AppID: 1, GuildID: 1, RoleID: 1},
+			{AppID: 2, GuildID: 1, RoleID: 5},
+		},
+		Emojis: []store.UpsertEmojiParams{
+			{AppID: 1, GuildID: 1, EmojiID: 1},
+		},
+	})
+	require.NoError(t, err)
+
+	guild, err := cache.GetFullGuild(ctx, store.GetFullGuildParams{
+		AppID:   1,
+		GuildID: 1,
+		Include: cachelib.FullGuildInclude{Channels: true, Roles: true, Emojis: true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, snowflake.ID(1), guild.GuildID)
+
+	assert.Equal(t, []snowflake.ID{2, 3}, entityIDs(guild.Channels, func(c *model.Channel) snowflake.ID { return c.ChannelID }))
+	require.Len(t, guild.Roles, 1)
+	assert.Equal(t, snowflake.ID(1), guild.Roles[0].RoleID)
+	require.Len(t, guild.Emojis, 1)
+	assert.Equal(t, snowflake.ID(1), guild.Emojis[0].EmojiID)
+	assert.Nil(t, guild.Stickers)
+
+	_, err = cache.GetFullGuild(ctx, store.GetFullGuildParams{AppID: 1, GuildID: 2})
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	_, err = cache.GetFullGuild(ctx, store.GetFullGuildParams{AppID: 2, GuildID: 1})
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func testGetCacheStats(t *testing.T, cache store.CacheStore) {
+	ctx := context.Background()
+	oldest := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	err := cache.MassUpsertEntities(ctx, store.MassUpsertEntitiesParams{
+		AppID: 1,
+		Guilds: []store.UpsertGuildParams{
+			{AppID: 1, GuildID: 1, UpdatedAt: oldest},
+			{AppID: 1, GuildID: 2, UpdatedAt: oldest.Add(time.Hour)},
+		},
+		Roles: []store.UpsertRoleParams{
+			{AppID: 1, GuildID: 1, RoleID: 10, UpdatedAt: oldest},
+			{AppID: 1, GuildID: 1, RoleID: 11, UpdatedAt: oldest},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = cache.UpsertGuilds(ctx, store.UpsertGuildParams{AppID: 2, GuildID: 1, UpdatedAt: oldest})
+	require.NoError(t, err)
+
+	err = cache.MarkGuildUnavailable(ctx, 1, 2)
+	require.NoError(t, err)
+
+	err = cache.MarkShardEntitiesTainted(ctx, store.MarkShardEntitiesTaintedParams{
+		AppID:      2,
+		ShardCount: 1,
+		ShardID:    0,
+	})
+	require.NoError(t, err)
+
+	stats, err := cache.GetCacheStats(ctx, store.GetCacheStatsParams{})
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+
+	assert.Equal(t, snowflake.ID(1), stats[0].AppID)
+	assert.Equal(t, 2, stats[0].Guilds.Count)
+	assert.Equal(t, 1, stats[0].Guilds.Unavailable)
+	assert.Equal(t, 0, stats[0].Guilds.Tainted)
+	assert.Equal(t, 2, stats[0].Roles.Count)
+	assert.Equal(t, 0, stats[0].Channels.Count)
+	require.NotNil(t, stats[0].Guilds.OldestUpdatedAt)
+	assert.True(t, oldest.Equal(*stats[0].Guilds.OldestUpdatedAt))
+
+	assert.Equal(t, snowflake.ID(2), stats[1].AppID)
+	assert.Equal(t, 1, stats[1].Guilds.Count)
+	assert.Equal(t, 1, stats[1].Guilds.Tainted)
+
+	stats, err = cache.GetCacheStats(ctx, store.GetCacheStatsParams{AppID: 2})
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 1, stats[0].Guilds.Count)
+	assert.Equal(t, 0, stats[0].Roles.Count)
+
+	// Apps without entities are reported with empty stats
+	stats, err = cache.GetCacheStats(ctx, store.GetCacheStatsParams{AppID: 3})
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, snowflake.ID(3), stats[0].AppID)
+	assert.Equal(t, 0, stats[0].Guilds.Count)
+	assert.Nil(t, stats[0].Guilds.OldestUpdatedAt)
+}
+
+func testDeleteAppEntities(t *testing.T, cache store.CacheStore) {
+	ctx := context.Background()
+
+	err := cache.MassUpsertEntities(ctx, store.MassUpsertEntitiesParams{
+		AppID: 1,
+		Guilds: []store.UpsertGuildParams{
+			{AppID: 1, GuildID: 1},
+			{AppID: 1, GuildID: 2},
+		},
+		Roles: []store.UpsertRoleParams{
+			{AppID: 1, GuildID: 1, RoleID: 10},
+			{AppID: 1, GuildID: 2, RoleID: 20},
+		},
+		Channels: []store.UpsertChannelParams{
+			{AppID: 1, GuildID: 1, ChannelID: 100, Data: discord.GuildTextChannel{}},
+		},
+		Emojis: []store.UpsertEmojiParams{
+			{AppID: 1, GuildID: 2, EmojiID: 200},
+		},
+	})
+	require.NoError(t, err)
+
+	err = cache.MassUpsertEntities(ctx, store.MassUpsertEntitiesParams{
+		AppID:  2,
+		Guilds: []store.UpsertGuildParams{{AppID: 2, GuildID: 1}},
+		Roles:  []store.UpsertRoleParams{{AppID: 2, GuildID: 1, RoleID: 10}},
+	})
+	require.NoError(t, err)
+
+	deleted, err := cache.DeleteAppEntities(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, store.DeletedEntities{Guilds: 2, Roles: 2, Channels: 1, Emojis: 1}, deleted)
+
+	exists, err := cache.CheckGuildExist(ctx, 1, 1)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	_, err = cache.GetRole(ctx, 1, 10)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	_, err = cache.GetChannel(ctx, 1, 100)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	roles, err := cache.GetGuildRoles(ctx, 1, 2, store.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+
+	// Other apps are left untouched
+	role, err := cache.GetRole(ctx, 2, 10)
+	require.NoError(t, err)
+	assert.Equal(t, snowflake.ID(1), role.GuildID)
+
+	stats, err := cache.GetCacheStats(ctx, store.GetCacheStatsParams{})
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, snowflake.ID(2), stats[0].AppID)
+
+	// Purging an app without entities is a no-op
+	deleted, err = cache.DeleteAppEntities(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, store.DeletedEntities{}, deleted)
+}
+
+func testGetGuildApps(t *testing.T, cache store.CacheStore) {
+	ctx := context.Background()
+
+	for _, appID := range []snowflake.ID{3, 1, 2} {
+		_, err := cache.UpsertGuilds(ctx, store.UpsertGuildParams{AppID: appID, GuildID: 100})
+		require.NoError(t, err)
+	}
+	_, err := cache.UpsertGuilds(ctx, store.UpsertGuildParams{AppID: 1, GuildID: 200})
+	require.NoError(t, err)
+
+	err = cache.MarkGuildUnavailable(ctx, 2, 100)
+	require.NoError(t, err)
+
+	apps, err := cache.GetGuildApps(ctx, 100)
+	require.NoError(t, err)
+	require.Len(t, apps, 3)
+	assert.Equal(t, snowflake.ID(1), apps[0].AppID)
+	assert.False(t, apps[0].Unavailable)
+	assert.Equal(t, snowflake.ID(2), apps[1].AppID)
+	assert.True(t, apps[1].Unavailable)
+	assert.Equal(t, snowflake.ID(3), apps[2].AppID)
+
+	// Deleted guilds and purged apps are no longer returned
+	err = cache.DeleteGuild(ctx, 3, 100)
+	require.NoError(t, err)
+	_, err = cache.DeleteAppEntities(ctx, 1)
+	require.NoError(t, err)
+
+	apps, err = cache.GetGuildApps(ctx, 100)
+	require.NoError(t, err)
+	require.Len(t, apps, 1)
+	assert.Equal(t, snowflake.ID(2), apps[0].AppID)
+
+	apps, err = cache.GetGuildApps(ctx, 200)
+	require.NoError(t, err)
+	assert.Empty(t, apps)
+}
+
+func testReplaceGuildEmojis(t *testing.T, cache store.CacheStore) {
+	ctx := context.Background()
+
+	_, err := cache.UpsertEmojis(ctx,
+		store.UpsertEmojiParams{AppID: 1, GuildID: 1, EmojiID: 1},
+		store.UpsertEmojiParams{AppID: 1, GuildID: 1, EmojiID: 2},
+		store.UpsertEmojiParams{AppID: 1, GuildID: 2, EmojiID: 3},
+		store.UpsertEmojiParams{AppID: 2, GuildID: 1, EmojiID: 4},
+	)
+	require.NoError(t, err)
+
+	replacedAt := time.Now()
+	replaced, err := cache.ReplaceGuildEmojis(ctx, 1, 1, replacedAt,
+		store.UpsertEmojiParams{AppID: 1, GuildID: 1, EmojiID: 2},
+		store.UpsertEmojiParams{AppID: 1, GuildID: 1, EmojiID: 5},
+	)
+	require.NoError(t, err)
+	assert.True(t, replaced)
+
+	// A redelivered older event doesn't replace the emojis again
+	replaced, err = cache.ReplaceGuildEmojis(ctx, 1, 1, replacedAt.Add(-time.Minute),
+		store.UpsertEmojiParams{AppID: 1, GuildID: 1, EmojiID: 1},
+	)
+	require.NoError(t, err)
+	assert.False(t, replaced)
+
+	emojis, err := cache.GetGuildEmojis(ctx, 1, 1, store.ListOptions{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []snowflake.ID{2, 5}, entityIDs(emojis, func(e *model.Emoji) snowflake.ID { return e.EmojiID }))
+
+	_, err = cache.GetEmoji(ctx, 1, 1)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	// Other guilds and apps are left untouched
+	count, err := cache.CountGuildEmojis(ctx, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	count, err = cache.CountGuildEmojis(ctx, 2, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	replaced, err = cache.ReplaceGuildEmojis(ctx, 1, 1, time.Now())
+	require.NoError(t, err)
+	assert.True(t, replaced)
+
+	count, err = cache.CountGuildEmojis(ctx, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func testDeleteShardTainted(t *testing.T, cache store.CacheStore) {
+	ctx := context.Background()
+
+	// Guilds A and B are on shard 0, guild C is on shard 1 of 2
+	guildA := snowflake.ID(2 << 22)
+	guildB := snowflake.ID(4 << 22)
+	guildC := snowflake.ID(1 << 22)
+
+	err := cache.MassUpsertEntities(ctx, store.MassUpsertEntitiesParams{
+		AppID: 1,
+		Guilds: []store.UpsertGuildParams{
+			{AppID: 1, GuildID: guildA},
+			{AppID: 1, GuildID: guildB},
+			{AppID: 1, GuildID: guildC},
+		},
+		Roles: []store.UpsertRoleParams{
+			{AppID: 1, GuildID: guildA, RoleID: 10},
+			{AppID: 1, GuildID: guildA, RoleID: 13},
+			{AppID: 1, GuildID: guildB, RoleID: 11},
+			{AppID: 1, GuildID: guildC, RoleID: 12},
+		},
+	})
+	require.NoError(t, err)
+
+	err = cache.MarkShardEntitiesTainted(ctx, store.MarkShardEntitiesTaintedParams{
+		AppID:      1,
+		ShardCount: 2,
+		ShardID:    0,
+	})
+	require.NoError(t, err)
+
+	role, err := cache.GetRole(ctx, 1, 11)
+	require.NoError(t, err)
+	assert.True(t, role.Tainted)
+
+	// Only guild A and one of its roles are received again after READY
+	err = cache.MassUpsertEntities(ctx, store.MassUpsertEntitiesParams{
+		AppID:  1,
+		Guilds: []store.UpsertGuildParams{{AppID: 1, GuildID: guildA}},
+		Roles:  []store.UpsertRoleParams{{AppID: 1, GuildID: guildA, RoleID: 10}},
+	})
+	require.NoError(t, err)
+
+	guilds, err := cache.GetGuilds(ctx, 1, store.ListOptions{ExcludeTainted: true})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []snowflake.ID{guildA, guildC}, entityIDs(guilds, func(g *model.Guild) snowflake.ID { return g.GuildID }))
+
+	err = cache.DeleteShardTaintedEntities(ctx, store.DeleteShardTaintedEntitiesParams{
+		AppID:      1,
+		ShardCount: 2,
+		ShardID:    0,
+	})
+	require.NoError(t, err)
+
+	exists, err := cache.CheckGuildExist(ctx, 1, guildB)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	roles, err := cache.GetRoles(ctx, 1, store.ListOptions{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []snowflake.ID{10, 12}, entityIDs(roles, func(r *model.Role) snowflake.ID { return r.RoleID }))
+
+	// Guilds that haven't been received yet are kept
+	err = cache.MarkShardEntitiesTainted(ctx, store.MarkShardEntitiesTaintedParams{
+		AppID:      1,
+		ShardCount: 2,
+		ShardID:    0,
+	})
+	require.NoError(t, err)
+
+	err = cache.DeleteShardTaintedEntities(ctx, store.DeleteShardTaintedEntitiesParams{
+		AppID:        1,
+		ShardCount:   2,
+		ShardID:      0,
+		KeepGuildIDs: []snowflake.ID{guildA},
+	})
+	require.NoError(t, err)
+
+	exists, err = cache.CheckGuildExist(ctx, 1, guildA)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	count, err := cache.CountGuildRoles(ctx, 1, guildA)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func testSkipStaleUpserts(t *testing.T, cache store.CacheStore) {
+	ctx := context.Background()
+
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Millisecond)
+
+	written, err := cache.UpsertRoles(ctx, store.UpsertRoleParams{
+		AppID: 1, GuildID: 1, RoleID: 10, Data: discord.Role{Name: "new"}, UpdatedAt: newer,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, written)
+
+	// A redelivered older event doesn't overwrite the newer role
+	written, err = cache.UpsertRoles(ctx, store.UpsertRoleParams{
+		AppID: 1, GuildID: 1, RoleID: 10, Data: discord.Role{Name: "old"}, UpdatedAt: older,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0, written)
+
+	err = cache.MassUpsertEntities(ctx, store.MassUpsertEntitiesParams{
+		AppID: 1,
+		Roles: []store.UpsertRoleParams{
+			{AppID: 1, GuildID: 1, RoleID: 10, Data: discord.Role{Name: "old"}, UpdatedAt: older},
+			{AppID: 1, GuildID: 1, RoleID: 11, Data: discord.Role{Name: "other"}, UpdatedAt: older},
+		},
+	})
+	require.NoError(t, err)
+
+	role, err := cache.GetRole(ctx, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "new", role.Data.Name)
+
+	role, err = cache.GetRole(ctx, 1, 11)
+	require.NoError(t, err)
+	assert.Equal(t, "other", role.Data.Name)
+
+	// Events with the same timestamp are applied in the order they are processed
+	written, err = cache.UpsertRoles(ctx, store.UpsertRoleParams{
+		AppID: 1, GuildID: 1, RoleID: 10, Data: discord.Role{Name: "newest"}, UpdatedAt: newer,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, written)
+
+	role, err = cache.GetRole(ctx, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "newest", role.Data.Name)
+}
+
+func testSearchWithFilter(t *testing.T, cache store.CacheStore) {
+	ctx := context.Background()
+
+	_, err := cache.UpsertRoles(ctx,
+		store.UpsertRoleParams{AppID: 1, GuildID: 1, RoleID: 1, Data: discord.Role{Name: "ticket-admin", Position: 3, Permissions: discord.PermissionAdministrator}},
+		store.UpsertRoleParams{AppID: 1, GuildID: 1, RoleID: 2, Data: discord.Role{Name: "ticket-support", Position: 2, Permissions: discord.PermissionManageMessages}},
+		store.UpsertRoleParams{AppID: 1, GuildID: 1, RoleID: 3, Data: discord.Role{Name: "member", Position: 1}},
+		store.UpsertRoleParams{AppID: 1, GuildID: 2, RoleID: 4, Data: discord.Role{Name: "ticket-admin", Position: 1, Permissions: discord.PermissionAdministrator}},
+	)
+	require.NoError(t, err)
+
+	search := func(filter cachelib.Filter) []snowflake.ID {
+		roles, err := cache.SearchGuildRoles(ctx, store.SearchGuildRolesParams{
+			AppID:   1,
+			GuildID: 1,
+			Filter:  &filter,
+		})
+		require.NoError(t, err)
+		return entityIDs(roles, func(r *model.Role) snowflake.ID { return r.RoleID })
+	}
+
+	assert.ElementsMatch(t, []snowflake.ID{1, 2}, search(cachelib.FilterPrefix("name", "ticket-")))
+	assert.ElementsMatch(t, []snowflake.ID{2}, search(cachelib.FilterContains("name", "support")))
+	assert.ElementsMatch(t, []snowflake.ID{1, 3}, search(cachelib.FilterIn("name", "ticket-admin", "member")))
+	assert.ElementsMatch(t, []snowflake.ID{1, 2}, search(cachelib.FilterGte("position", 2)))
+	assert.ElementsMatch(t, []snowflake.ID{3}, search(cachelib.FilterLt("position", 2)))
+	assert.ElementsMatch(t, []snowflake.ID{1}, search(cachelib.FilterHasFlags("permissions", discord.PermissionAdministrator)))
+	assert.ElementsMatch(t, []snowflake.ID{1, 3}, search(cachelib.FilterOr(
+		cachelib.FilterEq("name", "member"),
+		cachelib.FilterAnd(
+			cachelib.FilterPrefix("name", "ticket-"),
+			cachelib.FilterGt("position", 2),
+		),
+	)))
+	assert.Empty(t, search(cachelib.FilterEq("unknown", "ticket-admin")))
+
+	roles, err := cache.SearchRoles(ctx, store.SearchRolesParams{
+		AppID: 1,
+		Data:  json.RawMessage(`{"name":"ticket-admin"}`),
+	})
+	require.NoError(t, err)
+	assert.Len(t, roles, 2)
+}
+
+func testPartitionedSweep(t *testing.T, cache store.CacheStore) {
+	ctx := context.Background()
+
+	// With two partitions, guild 1 << 22 belongs to partition 1 and guild 2 << 22 to partition 0
+	partitionGuildID := snowflake.ID(1 << 22)
+	otherGuildID := snowflake.ID(2 << 22)
+
+	err := cache.MassUpsertEntities(ctx, store.MassUpsertEntitiesParams{
+		AppID: 1,
+		Guilds: []store.UpsertGuildParams{
+			{AppID: 1, GuildID: partitionGuildID},
+			{AppID: 1, GuildID: otherGuildID},
+		},
+		Roles: []store.UpsertRoleParams{
+			{AppID: 1, GuildID: partitionGuildID, RoleID: 10},
+			{AppID: 1, GuildID: otherGuildID, RoleID: 20},
+		},
+	})
+	require.NoError(t, err)
+
+	err = cache.MarkShardEntitiesTainted(ctx, store.MarkShardEntitiesTaintedParams{
+		AppID:          1,
+		ShardCount:     1,
+		ShardID:        0,
+		PartitionCount: 2,
+		PartitionID:    1,
+	})
+	require.NoError(t, err)
+
+	stats, err := cache.GetCacheStats(ctx, store.GetCacheStatsParams{AppID: 1})
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 1, stats[0].Guilds.Tainted)
+
+	// The guilds of the other partition are neither tainted nor deleted, even if they are tainted by their own partition
+	err = cache.MarkShardEntitiesTainted(ctx, store.MarkShardEntitiesTaintedParams{
+		AppID:          1,
+		ShardCount:     1,
+		ShardID:        0,
+		PartitionCount: 2,
+		PartitionID:    0,
+	})
+	require.NoError(t, err)
+
+	err = cache.DeleteShardTaintedEntities(ctx, store.DeleteShardTaintedEntitiesParams{
+		AppID:          1,
+		ShardCount:     1,
+		ShardID:        0,
+		PartitionCount: 2,
+		PartitionID:    1,
+	})
+	require.NoError(t, err)
+
+	exists, err := cache.CheckGuildExist(ctx, 1, partitionGuildID)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	_, err = cache.GetRole(ctx, 1, 10)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	exists, err = cache.CheckGuildExist(ctx, 1, otherGuildID)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	_, err = cache.GetRole(ctx, 1, 20)
+	require.NoError(t, err)
+}
+
+func entityIDs[T any](entities []*T, id func(*T) snowflake.ID) []snowflake.ID {
+	ids := make([]snowflake.ID, len(entities))
+	for i, entity := range entities {
+		ids[i] = id(entity)
+	}
+	return ids
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-gateway/model"
+	"github.com/merlinfuchs/stateway/stateway-lib/broker"
+	"github.com/merlinfuchs/stateway/stateway-lib/event"
+)
+
+// NewAppEvent creates an event about the app itself, like event.GatewayEventTypeAppDeleted.
+func NewAppEvent(app *model.App, eventType string, gatewayID int) *event.GatewayEvent {
+	return &event.GatewayEvent{
+		ID:        snowflake.New(time.Now().UTC()),
+		GatewayID: gatewayID,
+		GroupID:   app.GroupID,
+		AppID:     app.ID,
+		Type:      eventType,
+	}
+}
+
+// AppGatewayIDs returns the gateways that run the app, sharded apps are run by all gateways.
+func AppGatewayIDs(app *model.App, gatewayCount int) []int {
+	if app.ShardCount <= 1 {
+		return []int{int(uint64(app.ID) % uint64(max(gatewayCount, 1)))}
+	}
+
+	gatewayIDs := make([]int, gatewayCount)
+	for i := range gatewayIDs {
+		gatewayIDs[i] = i
+	}
+	return gatewayIDs
+}
+
+// PublishAppEvents publishes an event about the app for every gateway that runs it.
+// Listeners that only consume the events of some gateways receive it that way too.
+func PublishAppEvents(ctx context.Context, br broker.Broker, app *model.App, eventType string, gatewayCount int) error {
+	for _, gatewayID := range AppGatewayIDs(app, gatewayCount) {
+		err := br.Publish(ctx, NewAppEvent(app, eventType, gatewayID))
+		if err != nil {
+			return fmt.Errorf("failed to publish app event: %w", err)
+		}
+	}
+
+	err := br.PublishComplete(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to complete publishing app events: %w", err)
+	}
+	return nil
+}
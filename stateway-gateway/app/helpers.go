@@ -12,6 +12,7 @@ import (
 	"github.com/disgoorg/disgo/sharding"
 	"github.com/gorilla/websocket"
 	"github.com/merlinfuchs/stateway/stateway-gateway/store"
+	"github.com/merlinfuchs/stateway/stateway-lib/event"
 	"github.com/merlinfuchs/stateway/stateway-lib/gateway"
 	"gopkg.in/guregu/null.v4"
 )
@@ -149,6 +150,9 @@ func (a *App) disable(ctx context.Context, code gateway.AppDisabledCode, message
 		)
 		return
 	}
+
+	// Every gateway that runs the app disables it on its own, so the event is only published for this gateway
+	a.eventHandler.HandleEvent(NewAppEvent(a.model, event.GatewayEventTypeAppDisabled, a.cfg.GatewayID))
 }
 
 func (a *App) storeSession(ctx context.Context, g disgateway.Gateway) {
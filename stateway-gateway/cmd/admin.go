@@ -191,7 +191,7 @@ var adminCMD = cli.Command{
 							return fmt.Errorf("failed to parse app ID: %w", err)
 						}
 
-						err = admin.DeleteApp(ctx, env.pg, appID)
+						err = admin.DeleteApp(ctx, env.pg, env.cfg, appID)
 						if err != nil {
 							return fmt.Errorf("failed to delete app: %w", err)
 						}
@@ -237,7 +237,7 @@ var adminCMD = cli.Command{
 							return fmt.Errorf("failed to parse app ID: %w", err)
 						}
 
-						err = admin.DisableApp(ctx, env.pg, appID, gateway.AppDisabledCode(c.String("code")), c.String("message"))
+						err = admin.DisableApp(ctx, env.pg, env.cfg, appID, gateway.AppDisabledCode(c.String("code")), c.String("message"))
 						if err != nil {
 							return fmt.Errorf("failed to disable app: %w", err)
 						}
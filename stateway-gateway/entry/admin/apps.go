@@ -10,10 +10,13 @@ import (
 
 	"github.com/disgoorg/disgo/rest"
 	"github.com/disgoorg/snowflake/v2"
+	appruntime "github.com/merlinfuchs/stateway/stateway-gateway/app"
 	"github.com/merlinfuchs/stateway/stateway-gateway/db/postgres"
 	"github.com/merlinfuchs/stateway/stateway-gateway/model"
 	"github.com/merlinfuchs/stateway/stateway-gateway/store"
+	"github.com/merlinfuchs/stateway/stateway-lib/broker"
 	"github.com/merlinfuchs/stateway/stateway-lib/config"
+	"github.com/merlinfuchs/stateway/stateway-lib/event"
 	"github.com/merlinfuchs/stateway/stateway-lib/gateway"
 	"github.com/olekukonko/tablewriter"
 	"gopkg.in/guregu/null.v4"
@@ -107,16 +110,34 @@ func CreateApp(
 	return nil
 }
 
-func DeleteApp(ctx context.Context, appStore store.AppStore, id snowflake.ID) error {
-	err := appStore.DeleteApp(ctx, id)
+func DeleteApp(ctx context.Context, appStore store.AppStore, cfg *config.RootGatewayConfig, id snowflake.ID) error {
+	app, err := appStore.GetApp(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get app: %w", err)
+	}
+
+	err = appStore.DeleteApp(ctx, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete app: %w", err)
 	}
-	return nil
+
+	return publishAppEvents(ctx, cfg, app, event.GatewayEventTypeAppDeleted)
 }
 
-func DisableApp(ctx context.Context, appStore store.AppStore, id snowflake.ID, code gateway.AppDisabledCode, message string) error {
-	err := appStore.DisableApp(ctx, store.DisableAppParams{
+func DisableApp(
+	ctx context.Context,
+	appStore store.AppStore,
+	cfg *config.RootGatewayConfig,
+	id snowflake.ID,
+	code gateway.AppDisabledCode,
+	message string,
+) error {
+	app, err := appStore.GetApp(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get app: %w", err)
+	}
+
+	err = appStore.DisableApp(ctx, store.DisableAppParams{
 		ID:              id,
 		DisabledCode:    code,
 		DisabledMessage: null.NewString(message, message != ""),
@@ -124,20 +145,40 @@ func DisableApp(ctx context.Context, appStore store.AppStore, id snowflake.ID, c
 	if err != nil {
 		return fmt.Errorf("failed to disable app: %w", err)
 	}
-	return nil
+
+	return publishAppEvents(ctx, cfg, app, event.GatewayEventTypeAppDisabled)
+}
+
+// publishAppEvents tells the cache and audit services to purge the data of the app.
+func publishAppEvents(ctx context.Context, cfg *config.RootGatewayConfig, app *model.App, eventType string) error {
+	br, err := broker.NewNATSBroker(cfg.Broker.NATS.URL)
+	if err != nil {
+		return fmt.Errorf("failed to create NATS broker: %w", err)
+	}
+	defer br.Close(ctx)
+
+	return appruntime.PublishAppEvents(ctx, br, app, eventType, cfg.Gateway.GatewayCount)
 }
 
 func InitializeApps(ctx context.Context, pg *postgres.Client, cfg *config.RootGatewayConfig) error {
 	slog.Info("Initializing apps from config", slog.Int("app_count", len(cfg.Gateway.Apps)))
 
 	for _, groupCfg := range cfg.Gateway.Groups {
-		_, err := pg.UpsertGroup(ctx, store.UpsertGroupParams{
+		cacheConfig, err := appCacheConfig(groupCfg.Cache)
+		if err != nil {
+			return fmt.Errorf("invalid cache config of group %s: %w", groupCfg.ID, err)
+		}
+
+		_, err = pg.UpsertGroup(ctx, store.UpsertGroupParams{
 			ID:          groupCfg.ID,
 			DisplayName: groupCfg.DisplayName,
 			DefaultConstraints: gateway.AppConstraints{
 				MaxShards: null.NewInt(int64(groupCfg.MaxShards), groupCfg.MaxShards != 0),
 				MaxGuilds: null.NewInt(int64(groupCfg.MaxGuilds), groupCfg.MaxGuilds != 0),
 			},
+			DefaultConfig: gateway.AppConfig{
+				Cache: cacheConfig,
+			},
 			CreatedAt: time.Now().UTC(),
 			UpdatedAt: time.Now().UTC(),
 		})
@@ -154,9 +195,15 @@ func InitializeApps(ctx context.Context, pg *postgres.Client, cfg *config.RootGa
 			return fmt.Errorf("failed to get current app: %w", err)
 		}
 
+		cacheConfig, err := appCacheConfig(appCfg.Cache)
+		if err != nil {
+			return fmt.Errorf("invalid cache config of app %s: %w", discordApp.ID, err)
+		}
+
 		config := gateway.AppConfig{
 			Intents:          null.NewInt(appCfg.Intents, appCfg.Intents != 0),
 			ShardConcurrency: null.NewInt(int64(appCfg.ShardConcurrency), appCfg.ShardConcurrency != 0),
+			Cache:            cacheConfig,
 		}
 		if appCfg.Presence != nil {
 			config.Presence = &gateway.AppPresenceConfig{
@@ -202,6 +249,36 @@ func InitializeApps(ctx context.Context, pg *postgres.Client, cfg *config.RootGa
 	return nil
 }
 
+// appCacheConfig converts the cache config of a group or app in the config file, nil stays nil.
+func appCacheConfig(cfg *config.GatewayCacheConfig) (*gateway.AppCacheConfig, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	cacheConfig := &gateway.AppCacheConfig{}
+	if cfg.Entities != nil {
+		cacheConfig.Entities = make([]gateway.AppCacheEntity, len(cfg.Entities))
+		for i, entity := range cfg.Entities {
+			cacheConfig.Entities[i] = gateway.AppCacheEntity(entity)
+		}
+	}
+	if cfg.Threads != nil {
+		cacheConfig.Threads = null.BoolFrom(*cfg.Threads)
+	}
+	if cfg.StripFields != nil {
+		cacheConfig.StripFields = make(map[gateway.AppCacheEntity][]string, len(cfg.StripFields))
+		for entity, fields := range cfg.StripFields {
+			cacheConfig.StripFields[gateway.AppCacheEntity(entity)] = fields
+		}
+	}
+
+	err := cacheConfig.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return cacheConfig, nil
+}
+
 func renderAppsTable(apps []*model.App) error {
 	table := tablewriter.NewWriter(os.Stdout)
 	table.Header([]string{"ID", "Group", "Name", "Shard Count", "Disabled", "Created At", "Updated At"})
@@ -6,21 +6,30 @@ import (
 	"time"
 
 	"github.com/disgoorg/snowflake/v2"
+	appruntime "github.com/merlinfuchs/stateway/stateway-gateway/app"
 	"github.com/merlinfuchs/stateway/stateway-gateway/store"
+	"github.com/merlinfuchs/stateway/stateway-lib/broker"
+	"github.com/merlinfuchs/stateway/stateway-lib/event"
 	"github.com/merlinfuchs/stateway/stateway-lib/gateway"
 	"github.com/merlinfuchs/stateway/stateway-lib/service"
 	"gopkg.in/guregu/null.v4"
 )
 
 type Gateway struct {
-	groupStore store.GroupStore
-	appStore   store.AppStore
+	groupStore   store.GroupStore
+	appStore     store.AppStore
+	broker       broker.Broker
+	gatewayCount int
 }
 
-func NewGateway(groupStore store.GroupStore, appStore store.AppStore) *Gateway {
+// NewGateway creates the gateway service.
+// Deleted and disabled apps are announced on the broker for all gatewayCount gateways.
+func NewGateway(groupStore store.GroupStore, appStore store.AppStore, br broker.Broker, gatewayCount int) *Gateway {
 	return &Gateway{
-		groupStore: groupStore,
-		appStore:   appStore,
+		groupStore:   groupStore,
+		appStore:     appStore,
+		broker:       br,
+		gatewayCount: gatewayCount,
 	}
 }
 
@@ -82,7 +91,12 @@ func (g *Gateway) UpsertApp(ctx context.Context, params gateway.UpsertAppRequest
 }
 
 func (g *Gateway) DisableApp(ctx context.Context, appID snowflake.ID) error {
-	err := g.appStore.DisableApp(ctx, store.DisableAppParams{
+	app, err := g.GetApp(ctx, appID, false)
+	if err != nil {
+		return err
+	}
+
+	err = g.appStore.DisableApp(ctx, store.DisableAppParams{
 		ID:              appID,
 		DisabledCode:    gateway.AppDisabledCodeUnknown,
 		DisabledMessage: null.String{},
@@ -91,15 +105,22 @@ func (g *Gateway) DisableApp(ctx context.Context, appID snowflake.ID) error {
 	if err != nil {
 		return err
 	}
-	return nil
+
+	return appruntime.PublishAppEvents(ctx, g.broker, app, event.GatewayEventTypeAppDisabled, g.gatewayCount)
 }
 
 func (g *Gateway) DeleteApp(ctx context.Context, appID snowflake.ID) error {
-	err := g.appStore.DeleteApp(ctx, appID)
+	app, err := g.GetApp(ctx, appID, false)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	err = g.appStore.DeleteApp(ctx, appID)
+	if err != nil {
+		return err
+	}
+
+	return appruntime.PublishAppEvents(ctx, g.broker, app, event.GatewayEventTypeAppDeleted, g.gatewayCount)
 }
 
 func (g *Gateway) GetGroup(ctx context.Context, groupID string) (*gateway.Group, error) {
@@ -24,7 +24,7 @@ func Run(ctx context.Context, pg *postgres.Client, cfg *config.RootGatewayConfig
 		return fmt.Errorf("failed to create NATS broker: %w", err)
 	}
 
-	gatewayService := gateway.NewGatewayService(NewGateway(pg, pg))
+	gatewayService := gateway.NewGatewayService(NewGateway(pg, pg, br, cfg.Gateway.GatewayCount))
 	err = broker.Provide(ctx, br, gatewayService)
 	if err != nil {
 		return fmt.Errorf("failed to provide gateway service: %w", err)
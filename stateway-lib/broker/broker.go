@@ -12,6 +12,16 @@ type Broker interface {
 	PublishComplete(ctx context.Context) error
 	Listen(ctx context.Context, listener GenericListener) error
 	Request(ctx context.Context, serviceType service.ServiceType, method string, request any, opts ...RequestOption) (service.Response, error)
-	Provide(ctx context.Context, svc GenericBrokerService) error
+	Provide(ctx context.Context, svc GenericBrokerService, opts ...ProvideOption) error
+	// Broadcast sends a message to all current subscribers of the subject without persisting it.
+	Broadcast(ctx context.Context, subject string, data []byte) error
+	// Subscribe receives broadcast messages matching the subject until the context is cancelled.
+	Subscribe(ctx context.Context, subject string, handle func(subject string, data []byte)) error
 	Close(ctx context.Context) error
 }
+
+// SubjectEvent is implemented by events that are not gateway events and define their own NATS subject.
+type SubjectEvent interface {
+	event.Event
+	EventSubject() string
+}
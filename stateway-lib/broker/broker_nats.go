@@ -23,17 +23,30 @@ type NATSBroker struct {
 const (
 	GatewayStreamName    = "GATEWAY"
 	GatewayStreamSubject = "gateway.>"
+	CacheStreamName      = "CACHE"
+	CacheStreamSubject   = "cache.>"
 )
 
 func streamFromService(st service.ServiceType) (string, error) {
 	switch st {
 	case service.ServiceTypeGateway:
 		return GatewayStreamName, nil
+	case service.ServiceTypeCache:
+		return CacheStreamName, nil
 	default:
 		return "", fmt.Errorf("unknown service type: %s", st)
 	}
 }
 
+// serviceName returns the subject token of the service.
+// Every partition of a service gets its own token, so unpartitioned instances don't receive the requests of partitions.
+func serviceName(serviceType service.ServiceType, partition string) string {
+	if partition == "" {
+		return string(serviceType)
+	}
+	return fmt.Sprintf("%s-%s", serviceType, partition)
+}
+
 func gatewayEventSubject(e *event.GatewayEvent) string {
 	eventType := strings.ToLower(strings.ReplaceAll(e.EventType(), "_", "."))
 
@@ -93,6 +106,25 @@ func (b *NATSBroker) CreateGatewayStream(ctx context.Context) error {
 	return nil
 }
 
+func (b *NATSBroker) CreateCacheStream(ctx context.Context) error {
+	_, err := b.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:      CacheStreamName,
+		Subjects:  []string{CacheStreamSubject},
+		Retention: jetstream.InterestPolicy,
+		MaxAge:    1 * time.Hour,
+		MaxBytes:  8 * 1024 * 1024 * 1024, // 8GB
+		MaxMsgs:   -1,
+		Discard:   jetstream.DiscardOld,
+		Storage:   jetstream.FileStorage,
+		Replicas:  1,
+	})
+	if err != nil && !errors.Is(err, jetstream.ErrStreamNotFound) {
+		return fmt.Errorf("failed to create or update stream: %w", err)
+	}
+
+	return nil
+}
+
 func (b *NATSBroker) Publish(ctx context.Context, evt event.Event) error {
 	switch e := evt.(type) {
 	case *event.GatewayEvent:
@@ -112,6 +144,19 @@ func (b *NATSBroker) Publish(ctx context.Context, evt event.Event) error {
 			return fmt.Errorf("failed to publish event to %s: %w", subject, err)
 		}
 		return nil
+	case SubjectEvent:
+		rawEvent, err := event.MarshalEvent(e)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event: %w", err)
+		}
+
+		subject := e.EventSubject()
+
+		_, err = b.js.PublishAsync(subject, rawEvent)
+		if err != nil {
+			return fmt.Errorf("failed to publish event to %s: %w", subject, err)
+		}
+		return nil
 	default:
 		return fmt.Errorf("unsupported event type: %T", e)
 	}
@@ -142,12 +187,21 @@ func (b *NATSBroker) Listen(ctx context.Context, listener GenericListener) error
 		consumerConfig.NackDelay = time.Second
 	}
 
+	var pool *workerPool
+	if consumerConfig.Concurrency > 1 {
+		if consumerConfig.PartitionKey == nil {
+			return errors.New("consumer concurrency requires a partition key")
+		}
+		pool = newWorkerPool(ctx, consumerConfig.Concurrency)
+	}
+
 	consumer, err := b.js.CreateOrUpdateConsumer(ctx, stream, jetstream.ConsumerConfig{
 		Name:              listener.BalanceKey(),
 		Durable:           listener.BalanceKey(),
 		FilterSubjects:    filterSubjects,
 		AckPolicy:         consumerConfig.AckPolicy,
 		MaxAckPending:     consumerConfig.MaxAckPending,
+		MaxDeliver:        consumerConfig.MaxDeliver,
 		InactiveThreshold: time.Minute * 15,
 	})
 	if err != nil {
@@ -156,6 +210,11 @@ func (b *NATSBroker) Listen(ctx context.Context, listener GenericListener) error
 
 	subject := fmt.Sprintf("%s.>", listener.ServiceType())
 
+	// Failed events of ordered consumers are retried in place instead of being nacked.
+	// Nacking would let later events of the same key overtake the failed event,
+	// e.g. a redelivered create would bring back an entity that was deleted in the meantime.
+	ordered := consumerConfig.PartitionKey != nil && !consumerConfig.Async
+
 	cc, err := consumer.Consume(func(msg jetstream.Msg) {
 		event, err := event.UnmarshalEvent(msg.Data())
 		if err != nil {
@@ -164,20 +223,42 @@ func (b *NATSBroker) Listen(ctx context.Context, listener GenericListener) error
 				slog.String("subject", msg.Subject()),
 				slog.String("error", err.Error()),
 			)
+
+			// Redelivering a message that can't be decoded will never succeed
+			if consumerConfig.AckPolicy != jetstream.AckNonePolicy {
+				err := msg.Term()
+				if err != nil {
+					slog.Error(
+						"Failed to terminate message",
+						slog.String("subject", msg.Subject()),
+						slog.String("error", err.Error()),
+					)
+				}
+			}
 			return
 		}
 
 		handle := func() {
-			ok, err := listener.HandleEvent(ctx, event)
-			if err != nil {
-				slog.Error(
-					"Failed to handle event",
-					slog.String("subject", msg.Subject()),
-					slog.String("error", err.Error()),
-				)
+			deliveries := uint64(1)
+			metadata, err := msg.Metadata()
+			if err == nil {
+				deliveries = metadata.NumDelivered
 			}
 
-			if consumerConfig.AckPolicy != jetstream.AckNonePolicy {
+			for ; ; deliveries++ {
+				ok, err := listener.HandleEvent(ctx, event)
+				if err != nil {
+					slog.Error(
+						"Failed to handle event",
+						slog.String("subject", msg.Subject()),
+						slog.String("error", err.Error()),
+					)
+				}
+
+				if consumerConfig.AckPolicy == jetstream.AckNonePolicy {
+					return
+				}
+
 				if ok {
 					err := msg.Ack()
 					if err != nil {
@@ -187,7 +268,28 @@ func (b *NATSBroker) Listen(ctx context.Context, listener GenericListener) error
 							slog.String("error", err.Error()),
 						)
 					}
-				} else {
+					return
+				}
+
+				if consumerConfig.MaxDeliver > 0 && deliveries >= uint64(consumerConfig.MaxDeliver) {
+					slog.Error(
+						"Dropping message after too many failed deliveries",
+						slog.String("subject", msg.Subject()),
+						slog.Uint64("deliveries", deliveries),
+					)
+
+					err := msg.Term()
+					if err != nil {
+						slog.Error(
+							"Failed to terminate message",
+							slog.String("subject", msg.Subject()),
+							slog.String("error", err.Error()),
+						)
+					}
+					return
+				}
+
+				if !ordered {
 					err := msg.NakWithDelay(consumerConfig.NackDelay)
 					if err != nil {
 						slog.Error(
@@ -196,13 +298,38 @@ func (b *NATSBroker) Listen(ctx context.Context, listener GenericListener) error
 							slog.String("error", err.Error()),
 						)
 					}
+					return
+				}
+
+				// The message stays with this worker while it's retried, so the server must not redeliver it elsewhere
+				err = msg.InProgress()
+				if err != nil {
+					slog.Error(
+						"Failed to mark message in progress",
+						slog.String("subject", msg.Subject()),
+						slog.String("error", err.Error()),
+					)
+				}
+
+				select {
+				case <-time.After(consumerConfig.NackDelay):
+				case <-ctx.Done():
+					return
 				}
 			}
 		}
 
-		if consumerConfig.Async {
+		switch {
+		case pool != nil:
+			key, ok := consumerConfig.PartitionKey(event)
+			if ok {
+				pool.submit(ctx, key, handle)
+			} else {
+				pool.submitExclusive(ctx, handle)
+			}
+		case consumerConfig.Async:
 			go handle()
-		} else {
+		default:
 			handle()
 		}
 	}, jetstream.ConsumeErrHandler(func(consumeCtx jetstream.ConsumeContext, err error) {
@@ -230,8 +357,6 @@ func (b *NATSBroker) Request(
 	request any,
 	opts ...RequestOption,
 ) (service.Response, error) {
-	subject := fmt.Sprintf("service.%s.%s", serviceType, method)
-
 	options := &RequestOptions{
 		Timeout: 5 * time.Second,
 	}
@@ -239,6 +364,8 @@ func (b *NATSBroker) Request(
 		opt(options)
 	}
 
+	subject := fmt.Sprintf("service.%s.%s", serviceName(serviceType, options.Partition), method)
+
 	rawRequest, err := json.Marshal(request)
 	if err != nil {
 		return service.Response{
@@ -270,9 +397,14 @@ func (b *NATSBroker) Request(
 	return resp, nil
 }
 
-func (b *NATSBroker) Provide(ctx context.Context, svc GenericBrokerService) error {
-	subject := fmt.Sprintf("service.%s.>", svc.ServiceType())
-	queue := string(svc.ServiceType())
+func (b *NATSBroker) Provide(ctx context.Context, svc GenericBrokerService, opts ...ProvideOption) error {
+	options := &ProvideOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	queue := serviceName(svc.ServiceType(), options.Partition)
+	subject := fmt.Sprintf("service.%s.>", queue)
 
 	sub, err := b.nc.QueueSubscribe(subject, queue, func(msg *nats.Msg) {
 		method := strings.SplitN(msg.Subject, ".", 3)[2]
@@ -356,6 +488,37 @@ func (b *NATSBroker) Provide(ctx context.Context, svc GenericBrokerService) erro
 	return nil
 }
 
+func (b *NATSBroker) Broadcast(ctx context.Context, subject string, data []byte) error {
+	err := b.nc.Publish(subject, data)
+	if err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", subject, err)
+	}
+	return nil
+}
+
+func (b *NATSBroker) Subscribe(ctx context.Context, subject string, handle func(subject string, data []byte)) error {
+	sub, err := b.nc.Subscribe(subject, func(msg *nats.Msg) {
+		handle(msg.Subject, msg.Data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", subject, err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		err := sub.Unsubscribe()
+		if err != nil {
+			slog.Error(
+				"Failed to unsubscribe",
+				slog.String("subject", subject),
+				slog.Any("error", err),
+			)
+		}
+	}()
+
+	return nil
+}
+
 func (b *NATSBroker) Close(ctx context.Context) error {
 	return b.nc.Drain()
 }
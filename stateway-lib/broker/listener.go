@@ -104,8 +104,15 @@ func (l *FuncListener[E]) HandleEvent(ctx context.Context, event E) error {
 type ConsumerConfig struct {
 	AckPolicy     jetstream.AckPolicy
 	MaxAckPending int
-	NackDelay     time.Duration
-	Async         bool
+	// MaxDeliver limits how often a message is delivered before it's dropped, 0 means unlimited.
+	MaxDeliver int
+	NackDelay  time.Duration
+	Async      bool
+	// Concurrency is the number of workers that handle events at once, zero or one handles them one after another.
+	// Events with the same PartitionKey are handled in order, events without a key wait until all workers are idle.
+	// When a PartitionKey is set, failed events are retried in place, so later events of the same key can't overtake them.
+	Concurrency  int
+	PartitionKey func(event event.Event) (uint64, bool)
 }
 
 type EventFilter struct {
@@ -113,9 +120,16 @@ type EventFilter struct {
 	GroupIDs   []string
 	AppIDs     []snowflake.ID
 	EventTypes []string
+	// RawSubjects are used as-is instead of the gateway subject layout when set.
+	// They are relative to the service prefix of the listener.
+	RawSubjects []string
 }
 
 func (f EventFilter) Subjects() []string {
+	if len(f.RawSubjects) != 0 {
+		return f.RawSubjects
+	}
+
 	subjects := []string{}
 
 	gatewayIDs := make([]string, len(f.GatewayIDs))
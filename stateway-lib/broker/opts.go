@@ -4,6 +4,9 @@ import "time"
 
 type RequestOptions struct {
 	Timeout time.Duration
+	// Partition sends the request to the instances of the service that provide the partition.
+	// It's empty for services that aren't partitioned.
+	Partition string
 }
 
 type RequestOption func(*RequestOptions)
@@ -13,3 +16,25 @@ func WithTimeout(timeout time.Duration) RequestOption {
 		o.Timeout = timeout
 	}
 }
+
+// WithPartition sends the request to the instances of the service that provide the partition.
+func WithPartition(partition string) RequestOption {
+	return func(o *RequestOptions) {
+		o.Partition = partition
+	}
+}
+
+type ProvideOptions struct {
+	// Partition only receives the requests for the partition, instead of the requests for the unpartitioned service.
+	Partition string
+}
+
+type ProvideOption func(*ProvideOptions)
+
+// WithProvidePartition provides the service for a single partition.
+// Instances that provide the same partition share its requests.
+func WithProvidePartition(partition string) ProvideOption {
+	return func(o *ProvideOptions) {
+		o.Partition = partition
+	}
+}
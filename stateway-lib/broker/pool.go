@@ -0,0 +1,73 @@
+package broker
+
+import (
+	"context"
+	"sync"
+)
+
+// workerQueueSize is the number of tasks that can be queued per worker before submitting blocks.
+const workerQueueSize = 100
+
+// workerPool runs tasks on a fixed number of workers.
+// Tasks with the same key always run on the same worker, so they run in the order they were submitted.
+type workerPool struct {
+	queues  []chan func()
+	pending sync.WaitGroup
+}
+
+func newWorkerPool(ctx context.Context, workers int) *workerPool {
+	p := &workerPool{
+		queues: make([]chan func(), workers),
+	}
+
+	for i := range p.queues {
+		queue := make(chan func(), workerQueueSize)
+		p.queues[i] = queue
+
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case task := <-queue:
+					task()
+					p.pending.Done()
+				}
+			}
+		}()
+	}
+
+	return p
+}
+
+// submit queues the task on the worker of the key, it blocks while the queue of the worker is full.
+func (p *workerPool) submit(ctx context.Context, key uint64, task func()) {
+	p.pending.Add(1)
+	select {
+	case p.queues[p.worker(key)] <- task:
+	case <-ctx.Done():
+		p.pending.Done()
+	}
+}
+
+// submitExclusive waits until all queued tasks are done and runs the task on its own.
+// No tasks are submitted in the meantime, as tasks are submitted by the same goroutine.
+func (p *workerPool) submitExclusive(ctx context.Context, task func()) {
+	idle := make(chan struct{})
+	go func() {
+		p.pending.Wait()
+		close(idle)
+	}()
+
+	select {
+	case <-idle:
+		task()
+	case <-ctx.Done():
+	}
+}
+
+// worker returns the worker of the key.
+// Keys are mixed first, so keys that share a factor with the number of workers are still spread across all of them.
+func (p *workerPool) worker(key uint64) int {
+	return int(((key * 0x9E3779B97F4A7C15) >> 32) % uint64(len(p.queues)))
+}
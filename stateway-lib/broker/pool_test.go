@@ -0,0 +1,94 @@
+package broker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolOrdersTasksByKey(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool := newWorkerPool(ctx, 4)
+
+	var mu sync.Mutex
+	handled := make(map[uint64][]int)
+
+	var wg sync.WaitGroup
+	for i := range 100 {
+		key := uint64(i % 10)
+		wg.Add(1)
+		pool.submit(ctx, key, func() {
+			defer wg.Done()
+			mu.Lock()
+			handled[key] = append(handled[key], i)
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+
+	for key, order := range handled {
+		for j := 1; j < len(order); j++ {
+			if order[j] < order[j-1] {
+				t.Fatalf("tasks of key %d ran out of order: %v", key, order)
+			}
+		}
+	}
+}
+
+func TestWorkerPoolSpreadsKeys(t *testing.T) {
+	pool := &workerPool{queues: make([]chan func(), 4)}
+
+	// Keys that are all multiples of the worker count must not end up on the same worker
+	workers := make(map[int]bool)
+	for i := range uint64(100) {
+		workers[pool.worker(i*4)] = true
+	}
+	if len(workers) != 4 {
+		t.Errorf("expected keys on 4 workers, got %d", len(workers))
+	}
+}
+
+func TestWorkerPoolExclusiveWaitsForTasks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool := newWorkerPool(ctx, 4)
+
+	var running atomic.Int32
+	for i := range 8 {
+		pool.submit(ctx, uint64(i), func() {
+			running.Add(1)
+			time.Sleep(10 * time.Millisecond)
+			running.Add(-1)
+		})
+	}
+
+	ran := false
+	pool.submitExclusive(ctx, func() {
+		ran = true
+		if n := running.Load(); n != 0 {
+			t.Errorf("expected no running tasks, got %d", n)
+		}
+	})
+	if !ran {
+		t.Error("expected exclusive task to run")
+	}
+}
+
+func TestWorkerPoolExclusiveStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	pool := newWorkerPool(ctx, 1)
+	block := make(chan struct{})
+	defer close(block)
+	pool.submit(ctx, 0, func() { <-block })
+
+	cancel()
+	pool.submitExclusive(ctx, func() {
+		t.Error("expected exclusive task not to run after the context is done")
+	})
+}
@@ -48,8 +48,13 @@ type BrokerService[REQUEST any, RESPONSE any, METHOD ServiceMethod[REQUEST]] int
 	HandleRequest(ctx context.Context, method METHOD, request REQUEST) (RESPONSE, error)
 }
 
-func Provide[REQUEST any, RESPONSE any, METHOD ServiceMethod[REQUEST]](ctx context.Context, b Broker, server BrokerService[REQUEST, RESPONSE, METHOD]) error {
-	return b.Provide(ctx, &genericBrokerService[REQUEST, RESPONSE, METHOD]{inner: server})
+func Provide[REQUEST any, RESPONSE any, METHOD ServiceMethod[REQUEST]](
+	ctx context.Context,
+	b Broker,
+	server BrokerService[REQUEST, RESPONSE, METHOD],
+	opts ...ProvideOption,
+) error {
+	return b.Provide(ctx, &genericBrokerService[REQUEST, RESPONSE, METHOD]{inner: server}, opts...)
 }
 
 type ServiceMethod[REQUEST any] interface {
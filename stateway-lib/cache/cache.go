@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/snowflake/v2"
@@ -14,6 +15,25 @@ type Cache interface {
 	RoleCache
 	EmojiCache
 	StickerCache
+	StatsCache
+	ResyncCache
+	PurgeCache
+}
+
+type StatsCache interface {
+	// GetStats returns the stats of the app, or of all apps if no app ID is set.
+	GetStats(ctx context.Context, opts ...CacheOption) ([]*CacheStats, error)
+}
+
+type ResyncCache interface {
+	// Resync refetches the guild with its channels, roles, emojis and stickers from Discord and reconciles the cache with it.
+	// If guildID is 0 the guild list of the app is reconciled instead, the returned guild IDs then have to be resynced one by one.
+	Resync(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) (*ResyncResult, error)
+}
+
+type PurgeCache interface {
+	// PurgeApp deletes all cached entities of the app, e.g. after the app has been deleted.
+	PurgeApp(ctx context.Context, opts ...CacheOption) (*PurgeResult, error)
 }
 
 type GuildCache interface {
@@ -26,9 +46,18 @@ type GuildCache interface {
 		abortAtPermissions discord.Permissions,
 		opts ...CacheOption,
 	) (*GuildWithPermissions, error)
-	GetGuilds(ctx context.Context, opts ...CacheOption) ([]*Guild, error)
+	// GetFullGuild returns the guild with the included collections read from a single snapshot.
+	GetFullGuild(ctx context.Context, id snowflake.ID, include FullGuildInclude, opts ...CacheOption) (*FullGuild, error)
+	// WaitForGuild returns the guild once it has been cached, e.g. right after the app has joined it.
+	// It fails with a not found error if the guild hasn't been cached before the timeout.
+	WaitForGuild(ctx context.Context, id snowflake.ID, timeout time.Duration, opts ...CacheOption) (*Guild, error)
+	// GetGuildApps returns all apps that have the guild cached ordered by app ID, regardless of the app of the options.
+	GetGuildApps(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) ([]*GuildApp, error)
+	GetGuilds(ctx context.Context, opts ...CacheOption) (*Page[*Guild], error)
 	CheckGuildsExist(ctx context.Context, guildIDs []snowflake.ID, opts ...CacheOption) ([]bool, error)
-	SearchGuilds(ctx context.Context, data json.RawMessage, opts ...CacheOption) ([]*Guild, error)
+	// BatchGetGuilds returns the guilds in the order of the IDs, missing guilds are nil.
+	BatchGetGuilds(ctx context.Context, guildIDs []snowflake.ID, opts ...CacheOption) ([]*Guild, error)
+	SearchGuilds(ctx context.Context, data json.RawMessage, opts ...CacheOption) (*Page[*Guild], error)
 	ComputeGuildPermissions(
 		ctx context.Context,
 		guildID snowflake.ID,
@@ -36,14 +65,29 @@ type GuildCache interface {
 		roleIDs []snowflake.ID,
 		opts ...CacheOption,
 	) (discord.Permissions, error)
+	// CheckMemberHierarchy reports whether the actor can act on the target member, e.g. to kick or time them out.
+	// The actor also needs the required permissions, 0 only checks the role hierarchy.
+	CheckMemberHierarchy(
+		ctx context.Context,
+		guildID snowflake.ID,
+		actorUserID snowflake.ID,
+		actorRoleIDs []snowflake.ID,
+		targetUserID snowflake.ID,
+		targetRoleIDs []snowflake.ID,
+		requiredPermissions discord.Permissions,
+		opts ...CacheOption,
+	) (*HierarchyVerdict, error)
 }
 
 type ChannelCache interface {
 	GetChannel(ctx context.Context, channelID snowflake.ID, opts ...CacheOption) (*Channel, error)
-	GetChannels(ctx context.Context, opts ...CacheOption) ([]*Channel, error)
+	GetChannels(ctx context.Context, opts ...CacheOption) (*Page[*Channel], error)
+	// BatchGetChannels returns the channels in the order of the IDs, missing channels are nil.
+	BatchGetChannels(ctx context.Context, channelIDs []snowflake.ID, opts ...CacheOption) ([]*Channel, error)
 	CountChannels(ctx context.Context, opts ...CacheOption) (int, error)
 	GetGuildChannel(ctx context.Context, guildID snowflake.ID, channelID snowflake.ID, opts ...CacheOption) (*Channel, error)
-	GetGuildChannels(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) ([]*Channel, error)
+	GetGuildChannels(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) (*Page[*Channel], error)
+	BatchGetGuildChannels(ctx context.Context, guildID snowflake.ID, channelIDs []snowflake.ID, opts ...CacheOption) ([]*Channel, error)
 	GetGuildChannelsWithPermissions(
 		ctx context.Context,
 		guildID snowflake.ID,
@@ -52,8 +96,8 @@ type ChannelCache interface {
 		opts ...CacheOption,
 	) ([]*ChannelWithPermissions, error)
 	CountGuildChannels(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) (int, error)
-	SearchChannels(ctx context.Context, data json.RawMessage, opts ...CacheOption) ([]*Channel, error)
-	SearchGuildChannels(ctx context.Context, guildID snowflake.ID, data json.RawMessage, opts ...CacheOption) ([]*Channel, error)
+	SearchChannels(ctx context.Context, data json.RawMessage, opts ...CacheOption) (*Page[*Channel], error)
+	SearchGuildChannels(ctx context.Context, guildID snowflake.ID, data json.RawMessage, opts ...CacheOption) (*Page[*Channel], error)
 	ComputeChannelPermissions(
 		ctx context.Context,
 		channelID snowflake.ID,
@@ -73,19 +117,35 @@ type ChannelCache interface {
 
 type RoleCache interface {
 	GetRole(ctx context.Context, roleID snowflake.ID, opts ...CacheOption) (*Role, error)
-	GetRoles(ctx context.Context, opts ...CacheOption) ([]*Role, error)
+	GetRoles(ctx context.Context, opts ...CacheOption) (*Page[*Role], error)
+	// BatchGetRoles returns the roles in the order of the IDs, missing roles are nil.
+	BatchGetRoles(ctx context.Context, roleIDs []snowflake.ID, opts ...CacheOption) ([]*Role, error)
 	CountRoles(ctx context.Context, opts ...CacheOption) (int, error)
 	GetGuildRole(ctx context.Context, guildID snowflake.ID, roleID snowflake.ID, opts ...CacheOption) (*Role, error)
-	GetGuildRoles(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) ([]*Role, error)
+	GetGuildRoles(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) (*Page[*Role], error)
+	BatchGetGuildRoles(ctx context.Context, guildID snowflake.ID, roleIDs []snowflake.ID, opts ...CacheOption) ([]*Role, error)
 	CountGuildRoles(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) (int, error)
-	SearchRoles(ctx context.Context, data json.RawMessage, opts ...CacheOption) ([]*Role, error)
-	SearchGuildRoles(ctx context.Context, guildID snowflake.ID, data json.RawMessage, opts ...CacheOption) ([]*Role, error)
+	SearchRoles(ctx context.Context, data json.RawMessage, opts ...CacheOption) (*Page[*Role], error)
+	SearchGuildRoles(ctx context.Context, guildID snowflake.ID, data json.RawMessage, opts ...CacheOption) (*Page[*Role], error)
+	// CanManageRole reports whether the member can assign the role to members and remove it from them.
+	CanManageRole(
+		ctx context.Context,
+		guildID snowflake.ID,
+		userID snowflake.ID,
+		roleIDs []snowflake.ID,
+		roleID snowflake.ID,
+		opts ...CacheOption,
+	) (*HierarchyVerdict, error)
 }
 
 type EmojiCache interface {
-	GetGuildEmojis(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) ([]*Emoji, error)
+	GetGuildEmojis(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) (*Page[*Emoji], error)
+	// BatchGetEmojis returns the emojis in the order of the IDs, missing emojis are nil.
+	BatchGetEmojis(ctx context.Context, emojiIDs []snowflake.ID, opts ...CacheOption) ([]*Emoji, error)
 }
 
 type StickerCache interface {
-	GetGuildStickers(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) ([]*Sticker, error)
+	GetGuildStickers(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) (*Page[*Sticker], error)
+	// BatchGetStickers returns the stickers in the order of the IDs, missing stickers are nil.
+	BatchGetStickers(ctx context.Context, stickerIDs []snowflake.ID, opts ...CacheOption) ([]*Sticker, error)
 }
@@ -0,0 +1,238 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-lib/broker"
+	"github.com/merlinfuchs/stateway/stateway-lib/event"
+	"github.com/merlinfuchs/stateway/stateway-lib/service"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func init() {
+	event.RegisterEventType(service.ServiceTypeCache, func() event.Event {
+		return &ChangeEvent{}
+	})
+}
+
+type ChangeEntityType string
+
+const (
+	ChangeEntityTypeGuild   ChangeEntityType = "guild"
+	ChangeEntityTypeChannel ChangeEntityType = "channel"
+	ChangeEntityTypeRole    ChangeEntityType = "role"
+	ChangeEntityTypeEmoji   ChangeEntityType = "emoji"
+	ChangeEntityTypeSticker ChangeEntityType = "sticker"
+)
+
+type ChangeAction string
+
+const (
+	ChangeActionCreated ChangeAction = "created"
+	ChangeActionUpdated ChangeAction = "updated"
+	ChangeActionDeleted ChangeAction = "deleted"
+)
+
+var _ broker.SubjectEvent = (*ChangeEvent)(nil)
+
+// ChangeEvent is published by the cache service after an entity has been written to the cache.
+// Old is empty for created entities and New is empty for deleted entities.
+type ChangeEvent struct {
+	ID         snowflake.ID     `json:"id"`
+	AppID      snowflake.ID     `json:"app_id"`
+	GuildID    snowflake.ID     `json:"guild_id"`
+	EntityType ChangeEntityType `json:"entity_type"`
+	EntityID   snowflake.ID     `json:"entity_id"`
+	Action     ChangeAction     `json:"action"`
+	Old        json.RawMessage  `json:"old,omitempty"`
+	New        json.RawMessage  `json:"new,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+}
+
+// NewChangeEvent creates a change event from the old and new version of a cache entity.
+// Pass nil for old when the entity was created and nil for new when it was deleted.
+func NewChangeEvent[T any](
+	appID snowflake.ID,
+	guildID snowflake.ID,
+	entityType ChangeEntityType,
+	entityID snowflake.ID,
+	oldEntity *T,
+	newEntity *T,
+) (*ChangeEvent, error) {
+	e := &ChangeEvent{
+		ID:         snowflake.New(time.Now().UTC()),
+		AppID:      appID,
+		GuildID:    guildID,
+		EntityType: entityType,
+		EntityID:   entityID,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	switch {
+	case oldEntity == nil:
+		e.Action = ChangeActionCreated
+	case newEntity == nil:
+		e.Action = ChangeActionDeleted
+	default:
+		e.Action = ChangeActionUpdated
+	}
+
+	var err error
+	if oldEntity != nil {
+		e.Old, err = json.Marshal(oldEntity)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal old entity: %w", err)
+		}
+	}
+	if newEntity != nil {
+		e.New, err = json.Marshal(newEntity)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal new entity: %w", err)
+		}
+	}
+
+	return e, nil
+}
+
+func (e *ChangeEvent) EventID() snowflake.ID {
+	return e.ID
+}
+
+func (e *ChangeEvent) ServiceType() service.ServiceType {
+	return service.ServiceTypeCache
+}
+
+func (e *ChangeEvent) EventType() string {
+	return fmt.Sprintf("%s.%s", e.EntityType, e.Action)
+}
+
+// EventSubject returns the subject in the format cache.<app_id>.<guild_id>.<entity_type>.<action>.
+func (e *ChangeEvent) EventSubject() string {
+	return fmt.Sprintf("%s.%s.%s.%s", service.ServiceTypeCache, e.AppID, e.GuildID, e.EventType())
+}
+
+// DecodeChange unmarshals the old and new entity of a change event.
+// The returned pointers are nil if the respective version is not present.
+func DecodeChange[T any](e *ChangeEvent) (*T, *T, error) {
+	var oldEntity, newEntity *T
+	if len(e.Old) != 0 {
+		oldEntity = new(T)
+		if err := json.Unmarshal(e.Old, oldEntity); err != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal old entity: %w", err)
+		}
+	}
+	if len(e.New) != 0 {
+		newEntity = new(T)
+		if err := json.Unmarshal(e.New, newEntity); err != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal new entity: %w", err)
+		}
+	}
+	return oldEntity, newEntity, nil
+}
+
+// ChangeFilter selects which change events a listener receives. Empty fields match everything.
+type ChangeFilter struct {
+	AppIDs      []snowflake.ID
+	GuildIDs    []snowflake.ID
+	EntityTypes []ChangeEntityType
+	Actions     []ChangeAction
+}
+
+func (f ChangeFilter) Subjects() []string {
+	appIDs := make([]string, len(f.AppIDs))
+	for i, appID := range f.AppIDs {
+		appIDs[i] = appID.String()
+	}
+	if len(appIDs) == 0 {
+		appIDs = []string{"*"}
+	}
+
+	guildIDs := make([]string, len(f.GuildIDs))
+	for i, guildID := range f.GuildIDs {
+		guildIDs[i] = guildID.String()
+	}
+	if len(guildIDs) == 0 {
+		guildIDs = []string{"*"}
+	}
+
+	entityTypes := make([]string, len(f.EntityTypes))
+	for i, entityType := range f.EntityTypes {
+		entityTypes[i] = string(entityType)
+	}
+	if len(entityTypes) == 0 {
+		entityTypes = []string{"*"}
+	}
+
+	actions := make([]string, len(f.Actions))
+	for i, action := range f.Actions {
+		actions[i] = string(action)
+	}
+	if len(actions) == 0 {
+		actions = []string{"*"}
+	}
+
+	subjects := []string{}
+	for _, appID := range appIDs {
+		for _, guildID := range guildIDs {
+			for _, entityType := range entityTypes {
+				for _, action := range actions {
+					subjects = append(subjects, strings.Join([]string{appID, guildID, entityType, action}, "."))
+				}
+			}
+		}
+	}
+	return subjects
+}
+
+type changeListener struct {
+	balanceKey     string
+	filter         ChangeFilter
+	consumerConfig broker.ConsumerConfig
+	handleChange   func(ctx context.Context, e *ChangeEvent) error
+}
+
+func (l *changeListener) BalanceKey() string {
+	return l.balanceKey
+}
+
+func (l *changeListener) EventFilter() broker.EventFilter {
+	return broker.EventFilter{
+		RawSubjects: l.filter.Subjects(),
+	}
+}
+
+func (l *changeListener) ConsumerConfig() broker.ConsumerConfig {
+	return l.consumerConfig
+}
+
+func (l *changeListener) HandleEvent(ctx context.Context, e *ChangeEvent) (bool, error) {
+	err := l.handleChange(ctx, e)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListenChanges consumes the cache change feed. Listeners with the same balance key share the events between them.
+// Failed changes are redelivered.
+func ListenChanges(
+	ctx context.Context,
+	b broker.Broker,
+	balanceKey string,
+	filter ChangeFilter,
+	handleChange func(ctx context.Context, e *ChangeEvent) error,
+) error {
+	return broker.Listen(ctx, b, &changeListener{
+		balanceKey: balanceKey,
+		filter:     filter,
+		consumerConfig: broker.ConsumerConfig{
+			AckPolicy: jetstream.AckExplicitPolicy,
+		},
+		handleChange: handleChange,
+	})
+}
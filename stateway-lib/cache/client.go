@@ -3,6 +3,9 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"iter"
+	"time"
 
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/snowflake/v2"
@@ -12,31 +15,67 @@ import (
 
 var _ Cache = &CacheClient{}
 
+// guildWaitRequestMargin is added to the timeout of guild.wait requests, so the request doesn't time out before the wait.
+const guildWaitRequestMargin = 5 * time.Second
+
+// resyncRequestTimeout is the timeout of resync requests, which wait for the Discord API, and of purges of large apps.
+const resyncRequestTimeout = time.Minute
+
 type CacheClient struct {
 	b       broker.Broker
 	options CacheOptions
+	near    *nearCache
 }
 
 func NewCacheClient(b broker.Broker, opts ...CacheOption) *CacheClient {
-	return &CacheClient{b: b, options: ResolveOptions(opts...)}
+	return &CacheClient{b: b, options: ResolveOptions(opts...), near: &nearCache{}}
 }
 
+// NewCacheClientWithNearCache creates a client that keeps recently read guilds, channels and roles in a local LRU cache.
+// Entries are removed when the cache service broadcasts an invalidation for them, until the context is cancelled.
+// The invalidations of all apps are received, because clients created with WithOptions share the near cache for any app.
+func NewCacheClientWithNearCache(
+	ctx context.Context,
+	b broker.Broker,
+	config NearCacheConfig,
+	opts ...CacheOption,
+) (*CacheClient, error) {
+	c := NewCacheClient(b, opts...)
+	c.near = newNearCache(config)
+
+	err := ListenInvalidations(ctx, b, 0, c.near.invalidate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for cache invalidations: %w", err)
+	}
+
+	return c, nil
+}
+
+// WithOptions returns a client with different default options that shares the near cache of c.
 func (c *CacheClient) WithOptions(opts ...CacheOption) *CacheClient {
 	return &CacheClient{
 		b:       c.b,
 		options: ResolveOptions(opts...),
+		near:    c.near,
 	}
 }
 
+// NearCacheStats returns the hit and miss counts of the near cache.
+func (c *CacheClient) NearCacheStats() NearCacheStats {
+	return c.near.stats()
+}
+
 func (c *CacheClient) GetGuild(ctx context.Context, id snowflake.ID, opts ...CacheOption) (*Guild, error) {
 	options := c.options
 	for _, opt := range opts {
 		opt(&options)
 	}
 
-	return cacheRequest[*Guild](ctx, c.b, CacheMethodGetGuild, GuildGetRequest{
-		GuildID: id,
-		Options: options,
+	return nearCacheGet(c.near.guilds, newNearCacheKey(options, id), options.BypassNearCache, nil, func() (*Guild, error) {
+		return guildRequest[*Guild](ctx, c.b, options, id, CacheMethodGetGuild, GuildGetRequest{
+			GuildID: id,
+			Options: options,
+		})
 	})
 }
 
@@ -53,7 +92,7 @@ func (c *CacheClient) GetGuildWithPermissions(
 		opt(&options)
 	}
 
-	return cacheRequest[*GuildWithPermissions](ctx, c.b, CacheMethodGetGuildWithPermissions, GuildGetWithPermissionsRequest{
+	return guildRequest[*GuildWithPermissions](ctx, c.b, options, guildID, CacheMethodGetGuildWithPermissions, GuildGetWithPermissionsRequest{
 		GuildID:            guildID,
 		UserID:             userID,
 		RoleIDs:            roleIDs,
@@ -62,52 +101,121 @@ func (c *CacheClient) GetGuildWithPermissions(
 	})
 }
 
-func (c *CacheClient) GetGuilds(ctx context.Context, opts ...CacheOption) ([]*Guild, error) {
+func (c *CacheClient) GetFullGuild(
+	ctx context.Context,
+	id snowflake.ID,
+	include FullGuildInclude,
+	opts ...CacheOption,
+) (*FullGuild, error) {
+	options := c.options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return guildRequest[*FullGuild](ctx, c.b, options, id, CacheMethodGetFullGuild, GuildGetFullRequest{
+		GuildID: id,
+		Include: include,
+		Options: options,
+	})
+}
+
+func (c *CacheClient) WaitForGuild(
+	ctx context.Context,
+	id snowflake.ID,
+	timeout time.Duration,
+	opts ...CacheOption,
+) (*Guild, error) {
+	options := c.options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return guildRequest[*Guild](ctx, c.b, options, id, CacheMethodWaitForGuild, GuildWaitRequest{
+		GuildID:   id,
+		TimeoutMS: timeout.Milliseconds(),
+		Options:   options,
+	}, broker.WithTimeout(timeout+guildWaitRequestMargin))
+}
+
+func (c *CacheClient) GetGuildApps(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) ([]*GuildApp, error) {
 	options := c.options
 	for _, opt := range opts {
 		opt(&options)
 	}
 
-	return cacheRequest[[]*Guild](ctx, c.b, CacheMethodListGuilds, GuildListRequest{
+	return guildRequest[[]*GuildApp](ctx, c.b, options, guildID, CacheMethodGetGuildApps, GuildAppsRequest{
+		GuildID: guildID,
 		Options: options,
 	})
 }
 
+func (c *CacheClient) GetGuilds(ctx context.Context, opts ...CacheOption) (*Page[*Guild], error) {
+	options := c.options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return fanOutPage(ctx, c.b, options, CacheMethodListGuilds, func(options CacheOptions) CacheRequest {
+		return GuildListRequest{
+			Options: options,
+		}
+	}, func(guild *Guild) snowflake.ID { return guild.GuildID })
+}
+
 func (c *CacheClient) CheckGuildsExist(ctx context.Context, guildIDs []snowflake.ID, opts ...CacheOption) ([]bool, error) {
 	options := c.options
 	for _, opt := range opts {
 		opt(&options)
 	}
 
-	return cacheRequest[[]bool](ctx, c.b, CacheMethodCheckGuildsExist, GuildCheckExistRequest{
+	batches, err := fanOutRequest[[]bool](ctx, c.b, options, CacheMethodCheckGuildsExist, GuildCheckExistRequest{
 		GuildIDs: guildIDs,
 		Options:  options,
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeByIndex(batches, func(exists bool) bool { return exists }), nil
 }
 
-func (c *CacheClient) CountGuilds(ctx context.Context, opts ...CacheOption) (int, error) {
+func (c *CacheClient) BatchGetGuilds(ctx context.Context, guildIDs []snowflake.ID, opts ...CacheOption) ([]*Guild, error) {
 	options := c.options
 	for _, opt := range opts {
 		opt(&options)
 	}
 
-	return cacheRequest[int](ctx, c.b, CacheMethodCountGuilds, GuildCountRequest{
-		Options: options,
+	return fanOutBatch[Guild](ctx, c.b, options, CacheMethodBatchGetGuilds, GuildBatchGetRequest{
+		GuildIDs: guildIDs,
+		Options:  options,
 	})
 }
 
-func (c *CacheClient) SearchGuilds(ctx context.Context, data json.RawMessage, opts ...CacheOption) ([]*Guild, error) {
+func (c *CacheClient) CountGuilds(ctx context.Context, opts ...CacheOption) (int, error) {
 	options := c.options
 	for _, opt := range opts {
 		opt(&options)
 	}
 
-	return cacheRequest[[]*Guild](ctx, c.b, CacheMethodSearchGuilds, GuildSearchRequest{
-		Data:    data,
+	return fanOutCount(ctx, c.b, options, CacheMethodCountGuilds, GuildCountRequest{
 		Options: options,
 	})
 }
 
+func (c *CacheClient) SearchGuilds(ctx context.Context, data json.RawMessage, opts ...CacheOption) (*Page[*Guild], error) {
+	options := c.options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return fanOutPage(ctx, c.b, options, CacheMethodSearchGuilds, func(options CacheOptions) CacheRequest {
+		return GuildSearchRequest{
+			Data:    data,
+			Options: options,
+		}
+	}, func(guild *Guild) snowflake.ID { return guild.GuildID })
+}
+
 func (c *CacheClient) ComputeGuildPermissions(
 	ctx context.Context,
 	guildID snowflake.ID,
@@ -120,7 +228,7 @@ func (c *CacheClient) ComputeGuildPermissions(
 		opt(&options)
 	}
 
-	return cacheRequest[discord.Permissions](ctx, c.b, CacheMethodComputePermissions, PermissionsComputeRequest{
+	return guildRequest[discord.Permissions](ctx, c.b, options, guildID, CacheMethodComputePermissions, PermissionsComputeRequest{
 		GuildID: &guildID,
 		UserID:  userID,
 		RoleIDs: roleIDs,
@@ -128,26 +236,70 @@ func (c *CacheClient) ComputeGuildPermissions(
 	})
 }
 
+func (c *CacheClient) CheckMemberHierarchy(
+	ctx context.Context,
+	guildID snowflake.ID,
+	actorUserID snowflake.ID,
+	actorRoleIDs []snowflake.ID,
+	targetUserID snowflake.ID,
+	targetRoleIDs []snowflake.ID,
+	requiredPermissions discord.Permissions,
+	opts ...CacheOption,
+) (*HierarchyVerdict, error) {
+	options := c.options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return guildRequest[*HierarchyVerdict](ctx, c.b, options, guildID, CacheMethodCheckHierarchy, PermissionsHierarchyCheckRequest{
+		GuildID:             guildID,
+		ActorUserID:         actorUserID,
+		ActorRoleIDs:        actorRoleIDs,
+		TargetUserID:        targetUserID,
+		TargetRoleIDs:       targetRoleIDs,
+		RequiredPermissions: requiredPermissions,
+		Options:             options,
+	})
+}
+
 func (c *CacheClient) GetChannel(ctx context.Context, channelID snowflake.ID, opts ...CacheOption) (*Channel, error) {
 	options := c.options
 	for _, opt := range opts {
 		opt(&options)
 	}
 
-	return cacheRequest[*Channel](ctx, c.b, CacheMethodGetChannel, ChannelGetRequest{
-		ChannelID: channelID,
-		Options:   options,
+	return nearCacheGet(c.near.channels, newNearCacheKey(options, channelID), options.BypassNearCache, nil, func() (*Channel, error) {
+		return fanOutFind[*Channel](ctx, c.b, options, CacheMethodGetChannel, func(options CacheOptions) CacheRequest {
+			return ChannelGetRequest{
+				ChannelID: channelID,
+				Options:   options,
+			}
+		})
 	})
 }
 
-func (c *CacheClient) GetChannels(ctx context.Context, opts ...CacheOption) ([]*Channel, error) {
+func (c *CacheClient) GetChannels(ctx context.Context, opts ...CacheOption) (*Page[*Channel], error) {
 	options := c.options
 	for _, opt := range opts {
 		opt(&options)
 	}
 
-	return cacheRequest[[]*Channel](ctx, c.b, CacheMethodListChannels, ChannelListRequest{
-		Options: options,
+	return fanOutPage(ctx, c.b, options, CacheMethodListChannels, func(options CacheOptions) CacheRequest {
+		return ChannelListRequest{
+			Options: options,
+		}
+	}, func(channel *Channel) snowflake.ID { return channel.ChannelID })
+}
+
+func (c *CacheClient) BatchGetChannels(ctx context.Context, channelIDs []snowflake.ID, opts ...CacheOption) ([]*Channel, error) {
+	options := c.options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return fanOutBatch[Channel](ctx, c.b, options, CacheMethodBatchGetChannels, ChannelBatchGetRequest{
+		ChannelIDs: channelIDs,
+		Options:    options,
 	})
 }
 
@@ -157,21 +309,23 @@ func (c *CacheClient) CountChannels(ctx context.Context, opts ...CacheOption) (i
 		opt(&options)
 	}
 
-	return cacheRequest[int](ctx, c.b, CacheMethodCountChannels, ChannelCountRequest{
+	return fanOutCount(ctx, c.b, options, CacheMethodCountChannels, ChannelCountRequest{
 		Options: options,
 	})
 }
 
-func (c *CacheClient) SearchChannels(ctx context.Context, data json.RawMessage, opts ...CacheOption) ([]*Channel, error) {
+func (c *CacheClient) SearchChannels(ctx context.Context, data json.RawMessage, opts ...CacheOption) (*Page[*Channel], error) {
 	options := c.options
 	for _, opt := range opts {
 		opt(&options)
 	}
 
-	return cacheRequest[[]*Channel](ctx, c.b, CacheMethodSearchChannels, ChannelSearchRequest{
-		Data:    data,
-		Options: options,
-	})
+	return fanOutPage(ctx, c.b, options, CacheMethodSearchChannels, func(options CacheOptions) CacheRequest {
+		return ChannelSearchRequest{
+			Data:    data,
+			Options: options,
+		}
+	}, func(channel *Channel) snowflake.ID { return channel.ChannelID })
 }
 
 func (c *CacheClient) GetGuildChannel(ctx context.Context, guildID snowflake.ID, channelID snowflake.ID, opts ...CacheOption) (*Channel, error) {
@@ -180,22 +334,48 @@ func (c *CacheClient) GetGuildChannel(ctx context.Context, guildID snowflake.ID,
 		opt(&options)
 	}
 
-	return cacheRequest[*Channel](ctx, c.b, CacheMethodGetChannel, ChannelGetRequest{
-		GuildID:   &guildID,
-		ChannelID: channelID,
-		Options:   options,
+	inGuild := func(channel *Channel) bool {
+		return channel.GuildID == guildID
+	}
+
+	return nearCacheGet(c.near.channels, newNearCacheKey(options, channelID), options.BypassNearCache, inGuild, func() (*Channel, error) {
+		return guildRequest[*Channel](ctx, c.b, options, guildID, CacheMethodGetChannel, ChannelGetRequest{
+			GuildID:   &guildID,
+			ChannelID: channelID,
+			Options:   options,
+		})
 	})
 }
 
-func (c *CacheClient) GetGuildChannels(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) ([]*Channel, error) {
+func (c *CacheClient) GetGuildChannels(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) (*Page[*Channel], error) {
 	options := c.options
 	for _, opt := range opts {
 		opt(&options)
 	}
 
-	return cacheRequest[[]*Channel](ctx, c.b, CacheMethodListChannels, ChannelListRequest{
-		GuildID: &guildID,
-		Options: options,
+	fetch := func() (*Page[*Channel], error) {
+		return guildRequest[*Page[*Channel]](ctx, c.b, options, guildID, CacheMethodListChannels, ChannelListRequest{
+			GuildID: &guildID,
+			Options: options,
+		})
+	}
+	if options.paginated() {
+		return fetch()
+	}
+
+	return nearCacheGet(c.near.guildChannels, newNearCacheKey(options, guildID), options.BypassNearCache, nil, fetch)
+}
+
+func (c *CacheClient) BatchGetGuildChannels(ctx context.Context, guildID snowflake.ID, channelIDs []snowflake.ID, opts ...CacheOption) ([]*Channel, error) {
+	options := c.options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return guildRequest[[]*Channel](ctx, c.b, options, guildID, CacheMethodBatchGetChannels, ChannelBatchGetRequest{
+		GuildID:    &guildID,
+		ChannelIDs: channelIDs,
+		Options:    options,
 	})
 }
 
@@ -211,7 +391,7 @@ func (c *CacheClient) GetGuildChannelsWithPermissions(
 		opt(&options)
 	}
 
-	return cacheRequest[[]*ChannelWithPermissions](ctx, c.b, CacheMethodListChannels, ChannelListWithPermissionsRequest{
+	return guildRequest[[]*ChannelWithPermissions](ctx, c.b, options, guildID, CacheMethodListChannels, ChannelListWithPermissionsRequest{
 		GuildID: guildID,
 		UserID:  userID,
 		RoleIDs: roleIDs,
@@ -219,13 +399,13 @@ func (c *CacheClient) GetGuildChannelsWithPermissions(
 	})
 }
 
-func (c *CacheClient) SearchGuildChannels(ctx context.Context, guildID snowflake.ID, data json.RawMessage, opts ...CacheOption) ([]*Channel, error) {
+func (c *CacheClient) SearchGuildChannels(ctx context.Context, guildID snowflake.ID, data json.RawMessage, opts ...CacheOption) (*Page[*Channel], error) {
 	options := c.options
 	for _, opt := range opts {
 		opt(&options)
 	}
 
-	return cacheRequest[[]*Channel](ctx, c.b, CacheMethodSearchChannels, ChannelSearchRequest{
+	return guildRequest[*Page[*Channel]](ctx, c.b, options, guildID, CacheMethodSearchChannels, ChannelSearchRequest{
 		GuildID: &guildID,
 		Data:    data,
 		Options: options,
@@ -238,7 +418,7 @@ func (c *CacheClient) CountGuildChannels(ctx context.Context, guildID snowflake.
 		opt(&options)
 	}
 
-	return cacheRequest[int](ctx, c.b, CacheMethodCountChannels, ChannelCountRequest{
+	return guildRequest[int](ctx, c.b, options, guildID, CacheMethodCountChannels, ChannelCountRequest{
 		GuildID: &guildID,
 		Options: options,
 	})
@@ -256,11 +436,13 @@ func (c *CacheClient) ComputeChannelPermissions(
 		opt(&options)
 	}
 
-	return cacheRequest[discord.Permissions](ctx, c.b, CacheMethodComputePermissions, PermissionsComputeRequest{
-		ChannelID: &channelID,
-		UserID:    userID,
-		RoleIDs:   roleIDs,
-		Options:   options,
+	return fanOutFind[discord.Permissions](ctx, c.b, options, CacheMethodComputePermissions, func(options CacheOptions) CacheRequest {
+		return PermissionsComputeRequest{
+			ChannelID: &channelID,
+			UserID:    userID,
+			RoleIDs:   roleIDs,
+			Options:   options,
+		}
 	})
 }
 
@@ -277,7 +459,7 @@ func (c *CacheClient) MassComputeChannelPermissions(
 		opt(&options)
 	}
 
-	return cacheRequest[[]discord.Permissions](ctx, c.b, CacheMethodMassComputePermissions, MassComputePermissionsRequest{
+	return guildRequest[[]discord.Permissions](ctx, c.b, options, guildID, CacheMethodMassComputePermissions, MassComputePermissionsRequest{
 		GuildID:    guildID,
 		ChannelIDs: channelIDs,
 		UserID:     userID,
@@ -292,19 +474,37 @@ func (c *CacheClient) GetRole(ctx context.Context, roleID snowflake.ID, opts ...
 		opt(&options)
 	}
 
-	return cacheRequest[*Role](ctx, c.b, CacheMethodGetRole, RoleGetRequest{
-		RoleID:  roleID,
-		Options: options,
+	return nearCacheGet(c.near.roles, newNearCacheKey(options, roleID), options.BypassNearCache, nil, func() (*Role, error) {
+		return fanOutFind[*Role](ctx, c.b, options, CacheMethodGetRole, func(options CacheOptions) CacheRequest {
+			return RoleGetRequest{
+				RoleID:  roleID,
+				Options: options,
+			}
+		})
 	})
 }
 
-func (c *CacheClient) GetRoles(ctx context.Context, opts ...CacheOption) ([]*Role, error) {
+func (c *CacheClient) GetRoles(ctx context.Context, opts ...CacheOption) (*Page[*Role], error) {
 	options := c.options
 	for _, opt := range opts {
 		opt(&options)
 	}
 
-	return cacheRequest[[]*Role](ctx, c.b, CacheMethodListRoles, RoleListRequest{
+	return fanOutPage(ctx, c.b, options, CacheMethodListRoles, func(options CacheOptions) CacheRequest {
+		return RoleListRequest{
+			Options: options,
+		}
+	}, func(role *Role) snowflake.ID { return role.RoleID })
+}
+
+func (c *CacheClient) BatchGetRoles(ctx context.Context, roleIDs []snowflake.ID, opts ...CacheOption) ([]*Role, error) {
+	options := c.options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return fanOutBatch[Role](ctx, c.b, options, CacheMethodBatchGetRoles, RoleBatchGetRequest{
+		RoleIDs: roleIDs,
 		Options: options,
 	})
 }
@@ -315,21 +515,23 @@ func (c *CacheClient) CountRoles(ctx context.Context, opts ...CacheOption) (int,
 		opt(&options)
 	}
 
-	return cacheRequest[int](ctx, c.b, CacheMethodCountRoles, RoleCountRequest{
+	return fanOutCount(ctx, c.b, options, CacheMethodCountRoles, RoleCountRequest{
 		Options: options,
 	})
 }
 
-func (c *CacheClient) SearchRoles(ctx context.Context, data json.RawMessage, opts ...CacheOption) ([]*Role, error) {
+func (c *CacheClient) SearchRoles(ctx context.Context, data json.RawMessage, opts ...CacheOption) (*Page[*Role], error) {
 	options := c.options
 	for _, opt := range opts {
 		opt(&options)
 	}
 
-	return cacheRequest[[]*Role](ctx, c.b, CacheMethodSearchRoles, RoleSearchRequest{
-		Data:    data,
-		Options: options,
-	})
+	return fanOutPage(ctx, c.b, options, CacheMethodSearchRoles, func(options CacheOptions) CacheRequest {
+		return RoleSearchRequest{
+			Data:    data,
+			Options: options,
+		}
+	}, func(role *Role) snowflake.ID { return role.RoleID })
 }
 
 func (c *CacheClient) GetGuildRole(ctx context.Context, guildID snowflake.ID, roleID snowflake.ID, opts ...CacheOption) (*Role, error) {
@@ -338,21 +540,47 @@ func (c *CacheClient) GetGuildRole(ctx context.Context, guildID snowflake.ID, ro
 		opt(&options)
 	}
 
-	return cacheRequest[*Role](ctx, c.b, CacheMethodGetRole, RoleGetRequest{
-		GuildID: &guildID,
-		RoleID:  roleID,
-		Options: options,
+	inGuild := func(role *Role) bool {
+		return role.GuildID == guildID
+	}
+
+	return nearCacheGet(c.near.roles, newNearCacheKey(options, roleID), options.BypassNearCache, inGuild, func() (*Role, error) {
+		return guildRequest[*Role](ctx, c.b, options, guildID, CacheMethodGetRole, RoleGetRequest{
+			GuildID: &guildID,
+			RoleID:  roleID,
+			Options: options,
+		})
 	})
 }
 
-func (c *CacheClient) GetGuildRoles(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) ([]*Role, error) {
+func (c *CacheClient) GetGuildRoles(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) (*Page[*Role], error) {
+	options := c.options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	fetch := func() (*Page[*Role], error) {
+		return guildRequest[*Page[*Role]](ctx, c.b, options, guildID, CacheMethodListRoles, RoleListRequest{
+			GuildID: &guildID,
+			Options: options,
+		})
+	}
+	if options.paginated() {
+		return fetch()
+	}
+
+	return nearCacheGet(c.near.guildRoles, newNearCacheKey(options, guildID), options.BypassNearCache, nil, fetch)
+}
+
+func (c *CacheClient) BatchGetGuildRoles(ctx context.Context, guildID snowflake.ID, roleIDs []snowflake.ID, opts ...CacheOption) ([]*Role, error) {
 	options := c.options
 	for _, opt := range opts {
 		opt(&options)
 	}
 
-	return cacheRequest[[]*Role](ctx, c.b, CacheMethodListRoles, RoleListRequest{
+	return guildRequest[[]*Role](ctx, c.b, options, guildID, CacheMethodBatchGetRoles, RoleBatchGetRequest{
 		GuildID: &guildID,
+		RoleIDs: roleIDs,
 		Options: options,
 	})
 }
@@ -363,53 +591,220 @@ func (c *CacheClient) CountGuildRoles(ctx context.Context, guildID snowflake.ID,
 		opt(&options)
 	}
 
-	return cacheRequest[int](ctx, c.b, CacheMethodCountRoles, RoleCountRequest{
+	return guildRequest[int](ctx, c.b, options, guildID, CacheMethodCountRoles, RoleCountRequest{
 		GuildID: &guildID,
 		Options: options,
 	})
 }
 
-func (c *CacheClient) SearchGuildRoles(ctx context.Context, guildID snowflake.ID, data json.RawMessage, opts ...CacheOption) ([]*Role, error) {
+func (c *CacheClient) SearchGuildRoles(ctx context.Context, guildID snowflake.ID, data json.RawMessage, opts ...CacheOption) (*Page[*Role], error) {
 	options := c.options
 	for _, opt := range opts {
 		opt(&options)
 	}
 
-	return cacheRequest[[]*Role](ctx, c.b, CacheMethodSearchRoles, RoleSearchRequest{
+	return guildRequest[*Page[*Role]](ctx, c.b, options, guildID, CacheMethodSearchRoles, RoleSearchRequest{
 		GuildID: &guildID,
 		Data:    data,
 		Options: options,
 	})
 }
 
-func (c *CacheClient) GetGuildEmojis(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) ([]*Emoji, error) {
+func (c *CacheClient) CanManageRole(
+	ctx context.Context,
+	guildID snowflake.ID,
+	userID snowflake.ID,
+	roleIDs []snowflake.ID,
+	roleID snowflake.ID,
+	opts ...CacheOption,
+) (*HierarchyVerdict, error) {
+	options := c.options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return guildRequest[*HierarchyVerdict](ctx, c.b, options, guildID, CacheMethodCanManageRole, PermissionsCanManageRoleRequest{
+		GuildID: guildID,
+		UserID:  userID,
+		RoleIDs: roleIDs,
+		RoleID:  roleID,
+		Options: options,
+	})
+}
+
+func (c *CacheClient) GetGuildEmojis(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) (*Page[*Emoji], error) {
 	options := c.options
 	for _, opt := range opts {
 		opt(&options)
 	}
 
-	return cacheRequest[[]*Emoji](ctx, c.b, CacheMethodListEmojis, EmojiListRequest{
+	return guildRequest[*Page[*Emoji]](ctx, c.b, options, guildID, CacheMethodListEmojis, EmojiListRequest{
 		GuildID: guildID,
 		Options: options,
 	})
 }
 
-func (c *CacheClient) GetGuildStickers(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) ([]*Sticker, error) {
+func (c *CacheClient) BatchGetEmojis(ctx context.Context, emojiIDs []snowflake.ID, opts ...CacheOption) ([]*Emoji, error) {
+	options := c.options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return fanOutBatch[Emoji](ctx, c.b, options, CacheMethodBatchGetEmojis, EmojiBatchGetRequest{
+		EmojiIDs: emojiIDs,
+		Options:  options,
+	})
+}
+
+func (c *CacheClient) GetGuildStickers(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) (*Page[*Sticker], error) {
 	options := c.options
 	for _, opt := range opts {
 		opt(&options)
 	}
 
-	return cacheRequest[[]*Sticker](ctx, c.b, CacheMethodListStickers, StickerListRequest{
+	return guildRequest[*Page[*Sticker]](ctx, c.b, options, guildID, CacheMethodListStickers, StickerListRequest{
 		GuildID: guildID,
 		Options: options,
 	})
 }
 
-func cacheRequest[R any](ctx context.Context, b broker.Broker, method CacheMethod, request CacheRequest) (R, error) {
+func (c *CacheClient) BatchGetStickers(ctx context.Context, stickerIDs []snowflake.ID, opts ...CacheOption) ([]*Sticker, error) {
+	options := c.options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return fanOutBatch[Sticker](ctx, c.b, options, CacheMethodBatchGetStickers, StickerBatchGetRequest{
+		StickerIDs: stickerIDs,
+		Options:    options,
+	})
+}
+
+func (c *CacheClient) GetStats(ctx context.Context, opts ...CacheOption) ([]*CacheStats, error) {
+	options := c.options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	stats, err := fanOutRequest[[]*CacheStats](ctx, c.b, options, CacheMethodGetStats, StatsGetRequest{
+		Options: options,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeStats(stats), nil
+}
+
+func (c *CacheClient) Resync(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) (*ResyncResult, error) {
+	options := c.options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	request := CacheResyncRequest{
+		GuildID: guildID,
+		Options: options,
+	}
+	if guildID != 0 {
+		return guildRequest[*ResyncResult](ctx, c.b, options, guildID, CacheMethodResync, request, broker.WithTimeout(resyncRequestTimeout))
+	}
+
+	// Every partition reconciles its own guilds with the guild list of the app
+	results, err := fanOutRequest[*ResyncResult](ctx, c.b, options, CacheMethodResync, request, broker.WithTimeout(resyncRequestTimeout))
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeResyncResults(results), nil
+}
+
+func (c *CacheClient) PurgeApp(ctx context.Context, opts ...CacheOption) (*PurgeResult, error) {
+	options := c.options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	results, err := fanOutRequest[*PurgeResult](ctx, c.b, options, CacheMethodPurgeApp, CachePurgeAppRequest{
+		Options: options,
+	}, broker.WithTimeout(resyncRequestTimeout))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &PurgeResult{}
+	for _, result := range results {
+		res.Deleted.Add(result.Deleted)
+	}
+	return res, nil
+}
+
+// IterGuilds walks all guilds page by page, starting after the WithAfter cursor if set.
+func (c *CacheClient) IterGuilds(ctx context.Context, opts ...CacheOption) iter.Seq2[*Guild, error] {
+	return IterPages(ResolveOptions(opts...).After, func(after snowflake.ID) (*Page[*Guild], error) {
+		return c.GetGuilds(ctx, pageOptions(opts, after)...)
+	})
+}
+
+// IterSearchGuilds walks all guilds matching the search page by page.
+func (c *CacheClient) IterSearchGuilds(ctx context.Context, data json.RawMessage, opts ...CacheOption) iter.Seq2[*Guild, error] {
+	return IterPages(ResolveOptions(opts...).After, func(after snowflake.ID) (*Page[*Guild], error) {
+		return c.SearchGuilds(ctx, data, pageOptions(opts, after)...)
+	})
+}
+
+// IterChannels walks all channels page by page.
+func (c *CacheClient) IterChannels(ctx context.Context, opts ...CacheOption) iter.Seq2[*Channel, error] {
+	return IterPages(ResolveOptions(opts...).After, func(after snowflake.ID) (*Page[*Channel], error) {
+		return c.GetChannels(ctx, pageOptions(opts, after)...)
+	})
+}
+
+// IterGuildChannels walks all channels of a guild page by page.
+func (c *CacheClient) IterGuildChannels(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) iter.Seq2[*Channel, error] {
+	return IterPages(ResolveOptions(opts...).After, func(after snowflake.ID) (*Page[*Channel], error) {
+		return c.GetGuildChannels(ctx, guildID, pageOptions(opts, after)...)
+	})
+}
+
+// IterSearchChannels walks all channels matching the search page by page.
+func (c *CacheClient) IterSearchChannels(ctx context.Context, data json.RawMessage, opts ...CacheOption) iter.Seq2[*Channel, error] {
+	return IterPages(ResolveOptions(opts...).After, func(after snowflake.ID) (*Page[*Channel], error) {
+		return c.SearchChannels(ctx, data, pageOptions(opts, after)...)
+	})
+}
+
+// IterRoles walks all roles page by page.
+func (c *CacheClient) IterRoles(ctx context.Context, opts ...CacheOption) iter.Seq2[*Role, error] {
+	return IterPages(ResolveOptions(opts...).After, func(after snowflake.ID) (*Page[*Role], error) {
+		return c.GetRoles(ctx, pageOptions(opts, after)...)
+	})
+}
+
+// IterGuildRoles walks all roles of a guild page by page.
+func (c *CacheClient) IterGuildRoles(ctx context.Context, guildID snowflake.ID, opts ...CacheOption) iter.Seq2[*Role, error] {
+	return IterPages(ResolveOptions(opts...).After, func(after snowflake.ID) (*Page[*Role], error) {
+		return c.GetGuildRoles(ctx, guildID, pageOptions(opts, after)...)
+	})
+}
+
+// IterSearchRoles walks all roles matching the search page by page.
+func (c *CacheClient) IterSearchRoles(ctx context.Context, data json.RawMessage, opts ...CacheOption) iter.Seq2[*Role, error] {
+	return IterPages(ResolveOptions(opts...).After, func(after snowflake.ID) (*Page[*Role], error) {
+		return c.SearchRoles(ctx, data, pageOptions(opts, after)...)
+	})
+}
+
+func cacheRequest[R any](
+	ctx context.Context,
+	b broker.Broker,
+	method CacheMethod,
+	request CacheRequest,
+	opts ...broker.RequestOption,
+) (R, error) {
 	var r R
 
-	response, err := b.Request(ctx, service.ServiceTypeCache, string(method), request)
+	response, err := b.Request(ctx, service.ServiceTypeCache, string(method), request, opts...)
 	if err != nil {
 		return r, err
 	}
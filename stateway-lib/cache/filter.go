@@ -0,0 +1,300 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type FilterOp string
+
+const (
+	FilterOpEq       FilterOp = "eq"
+	FilterOpIn       FilterOp = "in"
+	FilterOpPrefix   FilterOp = "prefix"
+	FilterOpContains FilterOp = "contains"
+	FilterOpGt       FilterOp = "gt"
+	FilterOpGte      FilterOp = "gte"
+	FilterOpLt       FilterOp = "lt"
+	FilterOpLte      FilterOp = "lte"
+	FilterOpHasFlags FilterOp = "has_flags"
+	FilterOpAnd      FilterOp = "and"
+	FilterOpOr       FilterOp = "or"
+)
+
+// Filter is a typed condition on the Discord data of cached entities.
+// It is compiled to SQL by the Postgres store and evaluated in Go by all other stores,
+// so the same filter returns the same entities on every backend.
+//
+// Field is a dot separated path into the entity data, e.g. "name" or "parent_id".
+// Snowflake IDs are strings in the data, so they have to be compared as strings.
+type Filter struct {
+	Op      FilterOp `json:"op"`
+	Field   string   `json:"field,omitempty"`
+	Value   any      `json:"value,omitempty"`
+	Filters []Filter `json:"filters,omitempty"`
+}
+
+// FilterEq matches entities where the field is equal to the value.
+func FilterEq(field string, value any) Filter {
+	return Filter{Op: FilterOpEq, Field: field, Value: value}
+}
+
+// FilterIn matches entities where the field is equal to one of the values.
+func FilterIn[T any](field string, values ...T) Filter {
+	value := make([]any, len(values))
+	for i, v := range values {
+		value[i] = v
+	}
+	return Filter{Op: FilterOpIn, Field: field, Value: value}
+}
+
+// FilterPrefix matches entities where the string field starts with the prefix.
+func FilterPrefix(field string, prefix string) Filter {
+	return Filter{Op: FilterOpPrefix, Field: field, Value: prefix}
+}
+
+// FilterContains matches entities where the string field contains the substring.
+func FilterContains(field string, substring string) Filter {
+	return Filter{Op: FilterOpContains, Field: field, Value: substring}
+}
+
+// FilterGt matches entities where the numeric field is greater than the value.
+func FilterGt(field string, value float64) Filter {
+	return Filter{Op: FilterOpGt, Field: field, Value: value}
+}
+
+// FilterGte matches entities where the numeric field is greater than or equal to the value.
+func FilterGte(field string, value float64) Filter {
+	return Filter{Op: FilterOpGte, Field: field, Value: value}
+}
+
+// FilterLt matches entities where the numeric field is less than the value.
+func FilterLt(field string, value float64) Filter {
+	return Filter{Op: FilterOpLt, Field: field, Value: value}
+}
+
+// FilterLte matches entities where the numeric field is less than or equal to the value.
+func FilterLte(field string, value float64) Filter {
+	return Filter{Op: FilterOpLte, Field: field, Value: value}
+}
+
+// FilterHasFlags matches entities where all bits of flags are set in the field.
+// The field can either be a number or a numeric string like role permissions.
+func FilterHasFlags[T ~int | ~int64 | ~uint64](field string, flags T) Filter {
+	return Filter{Op: FilterOpHasFlags, Field: field, Value: strconv.FormatUint(uint64(flags), 10)}
+}
+
+// FilterAnd matches entities that match all of the filters.
+func FilterAnd(filters ...Filter) Filter {
+	return Filter{Op: FilterOpAnd, Filters: filters}
+}
+
+// FilterOr matches entities that match any of the filters.
+func FilterOr(filters ...Filter) Filter {
+	return Filter{Op: FilterOpOr, Filters: filters}
+}
+
+// Path returns the segments of the field path.
+func (f Filter) Path() []string {
+	return strings.Split(f.Field, ".")
+}
+
+// Normalize validates the filter and returns a copy where all values are converted to
+// their decoded JSON representation. Filters have to be normalized before calling Match.
+func (f Filter) Normalize() (Filter, error) {
+	res := Filter{Op: f.Op, Field: f.Field}
+
+	switch f.Op {
+	case FilterOpAnd, FilterOpOr:
+		if len(f.Filters) == 0 {
+			return res, fmt.Errorf("%s filter requires at least one filter", f.Op)
+		}
+		res.Filters = make([]Filter, len(f.Filters))
+		for i, filter := range f.Filters {
+			normalized, err := filter.Normalize()
+			if err != nil {
+				return res, err
+			}
+			res.Filters[i] = normalized
+		}
+		return res, nil
+	case FilterOpEq, FilterOpIn, FilterOpPrefix, FilterOpContains,
+		FilterOpGt, FilterOpGte, FilterOpLt, FilterOpLte, FilterOpHasFlags:
+	default:
+		return res, fmt.Errorf("unknown filter op: %q", f.Op)
+	}
+
+	if f.Field == "" || slices.Contains(f.Path(), "") {
+		return res, fmt.Errorf("%s filter has an invalid field: %q", f.Op, f.Field)
+	}
+
+	value, err := normalizeFilterValue(f.Value)
+	if err != nil {
+		return res, fmt.Errorf("failed to normalize %s filter value: %w", f.Op, err)
+	}
+	res.Value = value
+
+	switch f.Op {
+	case FilterOpIn:
+		if _, ok := value.([]any); !ok {
+			return res, fmt.Errorf("in filter requires a list value")
+		}
+	case FilterOpPrefix, FilterOpContains:
+		if _, ok := value.(string); !ok {
+			return res, fmt.Errorf("%s filter requires a string value", f.Op)
+		}
+	case FilterOpGt, FilterOpGte, FilterOpLt, FilterOpLte:
+		if _, ok := value.(float64); !ok {
+			return res, fmt.Errorf("%s filter requires a numeric value", f.Op)
+		}
+	case FilterOpHasFlags:
+		flags, ok := filterFlags(value)
+		if !ok {
+			return res, fmt.Errorf("has_flags filter requires a non-negative integer value")
+		}
+		res.Value = flags
+	}
+
+	return res, nil
+}
+
+// Match reports whether the decoded JSON data matches the normalized filter.
+func (f Filter) Match(data any) bool {
+	switch f.Op {
+	case FilterOpAnd:
+		for _, filter := range f.Filters {
+			if !filter.Match(data) {
+				return false
+			}
+		}
+		return true
+	case FilterOpOr:
+		for _, filter := range f.Filters {
+			if filter.Match(data) {
+				return true
+			}
+		}
+		return false
+	}
+
+	value, ok := lookupFilterField(data, f.Path())
+	if !ok {
+		return false
+	}
+
+	switch f.Op {
+	case FilterOpEq:
+		return reflect.DeepEqual(value, f.Value)
+	case FilterOpIn:
+		values, _ := f.Value.([]any)
+		return slices.ContainsFunc(values, func(v any) bool {
+			return reflect.DeepEqual(value, v)
+		})
+	case FilterOpPrefix:
+		s, ok := value.(string)
+		prefix, _ := f.Value.(string)
+		return ok && strings.HasPrefix(s, prefix)
+	case FilterOpContains:
+		s, ok := value.(string)
+		substring, _ := f.Value.(string)
+		return ok && strings.Contains(s, substring)
+	case FilterOpGt, FilterOpGte, FilterOpLt, FilterOpLte:
+		n, ok := value.(float64)
+		bound, _ := f.Value.(float64)
+		if !ok {
+			return false
+		}
+		switch f.Op {
+		case FilterOpGt:
+			return n > bound
+		case FilterOpGte:
+			return n >= bound
+		case FilterOpLt:
+			return n < bound
+		default:
+			return n <= bound
+		}
+	case FilterOpHasFlags:
+		flags, ok := filterFlags(value)
+		want, _ := f.Value.(int64)
+		return ok && flags&want == want
+	}
+
+	return false
+}
+
+// MatchValue encodes the value as JSON and reports whether it matches the normalized filter.
+func (f Filter) MatchValue(v any) (bool, error) {
+	data, err := normalizeFilterValue(v)
+	if err != nil {
+		return false, fmt.Errorf("failed to decode filter data: %w", err)
+	}
+	return f.Match(data), nil
+}
+
+func lookupFilterField(data any, path []string) (any, bool) {
+	for _, segment := range path {
+		switch v := data.(type) {
+		case map[string]any:
+			value, ok := v[segment]
+			if !ok {
+				return nil, false
+			}
+			data = value
+		case []any:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil, false
+			}
+			data = v[index]
+		default:
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+// filterFlags converts numbers and numeric strings to a bit set.
+// Bit sets are limited to 63 bits so they can be compared as a bigint in Postgres.
+func filterFlags(value any) (int64, bool) {
+	switch v := value.(type) {
+	case float64:
+		if v < 0 || v >= math.MaxInt64 || v != math.Trunc(v) {
+			return 0, false
+		}
+		return int64(v), true
+	case string:
+		if v == "" || strings.TrimLeft(v, "0123456789") != "" {
+			return 0, false
+		}
+		flags, err := strconv.ParseInt(v, 10, 64)
+		return flags, err == nil
+	case int64:
+		return v, v >= 0
+	}
+	return 0, false
+}
+
+func normalizeFilterValue(value any) (any, error) {
+	switch value.(type) {
+	case nil, bool, float64, string:
+		return value, nil
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var res any
+	err = json.Unmarshal(raw, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
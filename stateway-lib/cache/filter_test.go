@@ -0,0 +1,178 @@
+package cache
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const testFilterData = `{
+	"name": "ticket-admin",
+	"position": 3,
+	"permissions": "8",
+	"flags": 6,
+	"managed": false,
+	"tags": {"bot_id": "123"},
+	"role_ids": ["10", "11"]
+}`
+
+func TestFilterNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  Filter
+		want    Filter
+		wantErr bool
+	}{
+		{
+			name:   "values are decoded like JSON",
+			filter: FilterIn("position", 1, 2),
+			want:   Filter{Op: FilterOpIn, Field: "position", Value: []any{float64(1), float64(2)}},
+		},
+		{
+			name:   "flags are converted to a bit set",
+			filter: FilterHasFlags("permissions", 8),
+			want:   Filter{Op: FilterOpHasFlags, Field: "permissions", Value: int64(8)},
+		},
+		{
+			name:   "nested filters are normalized",
+			filter: FilterAnd(FilterEq("position", 3), FilterOr(FilterGt("flags", 1))),
+			want: Filter{Op: FilterOpAnd, Filters: []Filter{
+				{Op: FilterOpEq, Field: "position", Value: float64(3)},
+				{Op: FilterOpOr, Filters: []Filter{{Op: FilterOpGt, Field: "flags", Value: float64(1)}}},
+			}},
+		},
+		{
+			name:    "unknown op",
+			filter:  Filter{Op: "like", Field: "name", Value: "a"},
+			wantErr: true,
+		},
+		{
+			name:    "missing field",
+			filter:  FilterEq("", "a"),
+			wantErr: true,
+		},
+		{
+			name:    "empty path segment",
+			filter:  FilterEq("tags..bot_id", "a"),
+			wantErr: true,
+		},
+		{
+			name:    "in without a list",
+			filter:  Filter{Op: FilterOpIn, Field: "name", Value: "a"},
+			wantErr: true,
+		},
+		{
+			name:    "prefix without a string",
+			filter:  Filter{Op: FilterOpPrefix, Field: "name", Value: 1},
+			wantErr: true,
+		},
+		{
+			name:    "comparison without a number",
+			filter:  Filter{Op: FilterOpGt, Field: "position", Value: "1"},
+			wantErr: true,
+		},
+		{
+			name:    "negative flags",
+			filter:  Filter{Op: FilterOpHasFlags, Field: "flags", Value: -1},
+			wantErr: true,
+		},
+		{
+			name:    "and without filters",
+			filter:  FilterAnd(),
+			wantErr: true,
+		},
+		{
+			name:    "invalid nested filter",
+			filter:  FilterOr(FilterEq("name", "a"), FilterEq("", "b")),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.filter.Normalize()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Normalize() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Normalize() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	var data any
+	err := json.Unmarshal([]byte(testFilterData), &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"eq string", FilterEq("name", "ticket-admin"), true},
+		{"eq other string", FilterEq("name", "member"), false},
+		{"eq number", FilterEq("position", 3), true},
+		{"eq bool", FilterEq("managed", false), true},
+		{"eq number against string", FilterEq("permissions", 8), false},
+		{"eq nested field", FilterEq("tags.bot_id", "123"), true},
+		{"eq list index", FilterEq("role_ids.1", "11"), true},
+		{"list index out of range", FilterEq("role_ids.2", "11"), false},
+		{"missing field", FilterEq("unknown", "ticket-admin"), false},
+		{"in", FilterIn("name", "member", "ticket-admin"), true},
+		{"not in", FilterIn("position", 1, 2), false},
+		{"prefix", FilterPrefix("name", "ticket-"), true},
+		{"prefix of a number", FilterPrefix("position", "3"), false},
+		{"contains", FilterContains("name", "adm"), true},
+		{"not contains", FilterContains("name", "support"), false},
+		{"gt", FilterGt("position", 2), true},
+		{"gt equal", FilterGt("position", 3), false},
+		{"gte equal", FilterGte("position", 3), true},
+		{"lt", FilterLt("position", 3), false},
+		{"lte equal", FilterLte("position", 3), true},
+		{"comparison of a string", FilterGt("name", 0), false},
+		{"has flags of a numeric string", FilterHasFlags("permissions", 8), true},
+		{"has flags of a number", FilterHasFlags("flags", 4), true},
+		{"has some of the flags", FilterHasFlags("flags", 5), false},
+		{"and", FilterAnd(FilterPrefix("name", "ticket-"), FilterGt("position", 2)), true},
+		{"and with a mismatch", FilterAnd(FilterPrefix("name", "ticket-"), FilterLt("position", 2)), false},
+		{"or", FilterOr(FilterEq("name", "member"), FilterGte("position", 3)), true},
+		{"or without a match", FilterOr(FilterEq("name", "member"), FilterLt("position", 2)), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := tt.filter.Normalize()
+			if err != nil {
+				t.Fatalf("Normalize() error = %v", err)
+			}
+			if got := filter.Match(data); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterMatchValue(t *testing.T) {
+	filter, err := FilterHasFlags("permissions", 8).Normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := filter.MatchValue(map[string]any{"permissions": "12"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Error("MatchValue() = false, want true")
+	}
+}
@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-lib/broker"
+)
+
+// InvalidationSubjectPrefix is the prefix of the subjects invalidations are broadcast on.
+// It's outside of the CACHE stream subjects, so invalidations are never persisted.
+const InvalidationSubjectPrefix = "invalidation.cache"
+
+// InvalidationEntityTypeApp invalidates all entities of the app.
+// It's broadcast when entities are deleted in bulk, e.g. by a tainted sweep or a purge, because the deleted entities aren't known.
+const InvalidationEntityTypeApp ChangeEntityType = "app"
+
+// Invalidation is broadcast by the cache service when an entity has changed.
+// Unlike ChangeEvent it doesn't carry the entity data and is only delivered to clients that are connected at the time.
+type Invalidation struct {
+	AppID      snowflake.ID     `json:"app_id"`
+	GuildID    snowflake.ID     `json:"guild_id"`
+	EntityType ChangeEntityType `json:"entity_type"`
+	EntityID   snowflake.ID     `json:"entity_id"`
+	Action     ChangeAction     `json:"action"`
+}
+
+// Subject returns the subject in the format invalidation.cache.<app_id>.<guild_id>.<entity_type>.
+func (i Invalidation) Subject() string {
+	return fmt.Sprintf("%s.%s.%s.%s", InvalidationSubjectPrefix, i.AppID, i.GuildID, i.EntityType)
+}
+
+// PublishInvalidation broadcasts the invalidation to all connected clients.
+func PublishInvalidation(ctx context.Context, b broker.Broker, invalidation Invalidation) error {
+	data, err := json.Marshal(invalidation)
+	if err != nil {
+		return fmt.Errorf("failed to marshal invalidation: %w", err)
+	}
+
+	return b.Broadcast(ctx, invalidation.Subject(), data)
+}
+
+// ListenInvalidations calls handle for every invalidation until the context is cancelled.
+// If appID is not 0 only invalidations of that app are received.
+func ListenInvalidations(
+	ctx context.Context,
+	b broker.Broker,
+	appID snowflake.ID,
+	handle func(invalidation Invalidation),
+) error {
+	subject := InvalidationSubjectPrefix + ".>"
+	if appID != 0 {
+		subject = fmt.Sprintf("%s.%s.>", InvalidationSubjectPrefix, appID)
+	}
+
+	return b.Subscribe(ctx, subject, func(subject string, data []byte) {
+		var invalidation Invalidation
+		err := json.Unmarshal(data, &invalidation)
+		if err != nil {
+			slog.Error(
+				"Failed to unmarshal invalidation",
+				slog.String("subject", subject),
+				slog.Any("error", err),
+			)
+			return
+		}
+
+		handle(invalidation)
+	})
+}
@@ -13,23 +13,36 @@ type CacheMethod string
 const (
 	CacheMethodGetGuild                    CacheMethod = "guild.get"
 	CacheMethodGetGuildWithPermissions     CacheMethod = "guild.get_with_permissions"
+	CacheMethodGetFullGuild                CacheMethod = "guild.get_full"
+	CacheMethodWaitForGuild                CacheMethod = "guild.wait"
+	CacheMethodGetGuildApps                CacheMethod = "guild.apps"
 	CacheMethodListGuilds                  CacheMethod = "guild.list"
+	CacheMethodBatchGetGuilds              CacheMethod = "guild.batch_get"
 	CacheMethodCheckGuildsExist            CacheMethod = "guild.exists"
 	CacheMethodSearchGuilds                CacheMethod = "guild.search"
 	CacheMethodCountGuilds                 CacheMethod = "guild.count"
 	CacheMethodGetChannel                  CacheMethod = "channel.get"
 	CacheMethodListChannels                CacheMethod = "channel.list"
+	CacheMethodBatchGetChannels            CacheMethod = "channel.batch_get"
 	CacheMethodListChannelsWithPermissions CacheMethod = "channel.list_with_permissions"
 	CacheMethodSearchChannels              CacheMethod = "channel.search"
 	CacheMethodCountChannels               CacheMethod = "channel.count"
 	CacheMethodGetRole                     CacheMethod = "role.get"
 	CacheMethodListRoles                   CacheMethod = "role.list"
+	CacheMethodBatchGetRoles               CacheMethod = "role.batch_get"
 	CacheMethodSearchRoles                 CacheMethod = "role.search"
 	CacheMethodCountRoles                  CacheMethod = "role.count"
 	CacheMethodComputePermissions          CacheMethod = "permissions.compute"
 	CacheMethodMassComputePermissions      CacheMethod = "permissions.mass_compute"
+	CacheMethodCheckHierarchy              CacheMethod = "permissions.hierarchy_check"
+	CacheMethodCanManageRole               CacheMethod = "permissions.can_manage_role"
 	CacheMethodListEmojis                  CacheMethod = "emoji.list"
+	CacheMethodBatchGetEmojis              CacheMethod = "emoji.batch_get"
 	CacheMethodListStickers                CacheMethod = "sticker.list"
+	CacheMethodBatchGetStickers            CacheMethod = "sticker.batch_get"
+	CacheMethodGetStats                    CacheMethod = "stats.get"
+	CacheMethodResync                      CacheMethod = "cache.resync"
+	CacheMethodPurgeApp                    CacheMethod = "cache.purge_app"
 )
 
 func (m CacheMethod) UnmarshalRequest(data json.RawMessage) (CacheRequest, error) {
@@ -42,6 +55,18 @@ func (m CacheMethod) UnmarshalRequest(data json.RawMessage) (CacheRequest, error
 		var req GuildGetWithPermissionsRequest
 		err := json.Unmarshal(data, &req)
 		return req, err
+	case CacheMethodGetFullGuild:
+		var req GuildGetFullRequest
+		err := json.Unmarshal(data, &req)
+		return req, err
+	case CacheMethodWaitForGuild:
+		var req GuildWaitRequest
+		err := json.Unmarshal(data, &req)
+		return req, err
+	case CacheMethodGetGuildApps:
+		var req GuildAppsRequest
+		err := json.Unmarshal(data, &req)
+		return req, err
 	case CacheMethodCheckGuildsExist:
 		var req GuildCheckExistRequest
 		err := json.Unmarshal(data, &req)
@@ -50,6 +75,10 @@ func (m CacheMethod) UnmarshalRequest(data json.RawMessage) (CacheRequest, error
 		var req GuildListRequest
 		err := json.Unmarshal(data, &req)
 		return req, err
+	case CacheMethodBatchGetGuilds:
+		var req GuildBatchGetRequest
+		err := json.Unmarshal(data, &req)
+		return req, err
 	case CacheMethodSearchGuilds:
 		var req GuildSearchRequest
 		err := json.Unmarshal(data, &req)
@@ -62,6 +91,10 @@ func (m CacheMethod) UnmarshalRequest(data json.RawMessage) (CacheRequest, error
 		var req ChannelListRequest
 		err := json.Unmarshal(data, &req)
 		return req, err
+	case CacheMethodBatchGetChannels:
+		var req ChannelBatchGetRequest
+		err := json.Unmarshal(data, &req)
+		return req, err
 	case CacheMethodListChannelsWithPermissions:
 		var req ChannelListWithPermissionsRequest
 		err := json.Unmarshal(data, &req)
@@ -78,6 +111,10 @@ func (m CacheMethod) UnmarshalRequest(data json.RawMessage) (CacheRequest, error
 		var req RoleListRequest
 		err := json.Unmarshal(data, &req)
 		return req, err
+	case CacheMethodBatchGetRoles:
+		var req RoleBatchGetRequest
+		err := json.Unmarshal(data, &req)
+		return req, err
 	case CacheMethodSearchRoles:
 		var req RoleSearchRequest
 		err := json.Unmarshal(data, &req)
@@ -90,14 +127,42 @@ func (m CacheMethod) UnmarshalRequest(data json.RawMessage) (CacheRequest, error
 		var req MassComputePermissionsRequest
 		err := json.Unmarshal(data, &req)
 		return req, err
+	case CacheMethodCheckHierarchy:
+		var req PermissionsHierarchyCheckRequest
+		err := json.Unmarshal(data, &req)
+		return req, err
+	case CacheMethodCanManageRole:
+		var req PermissionsCanManageRoleRequest
+		err := json.Unmarshal(data, &req)
+		return req, err
 	case CacheMethodListEmojis:
 		var req EmojiListRequest
 		err := json.Unmarshal(data, &req)
 		return req, err
+	case CacheMethodBatchGetEmojis:
+		var req EmojiBatchGetRequest
+		err := json.Unmarshal(data, &req)
+		return req, err
 	case CacheMethodListStickers:
 		var req StickerListRequest
 		err := json.Unmarshal(data, &req)
 		return req, err
+	case CacheMethodBatchGetStickers:
+		var req StickerBatchGetRequest
+		err := json.Unmarshal(data, &req)
+		return req, err
+	case CacheMethodGetStats:
+		var req StatsGetRequest
+		err := json.Unmarshal(data, &req)
+		return req, err
+	case CacheMethodResync:
+		var req CacheResyncRequest
+		err := json.Unmarshal(data, &req)
+		return req, err
+	case CacheMethodPurgeApp:
+		var req CachePurgeAppRequest
+		err := json.Unmarshal(data, &req)
+		return req, err
 	default:
 		return nil, fmt.Errorf("unknown cache method: %v", m)
 	}
@@ -125,12 +190,44 @@ type GuildGetWithPermissionsRequest struct {
 
 func (r GuildGetWithPermissionsRequest) cacheRequest() {}
 
+type GuildGetFullRequest struct {
+	GuildID snowflake.ID     `json:"guild_id"`
+	Include FullGuildInclude `json:"include"`
+	Options CacheOptions     `json:"options,omitempty"`
+}
+
+func (r GuildGetFullRequest) cacheRequest() {}
+
+type GuildWaitRequest struct {
+	GuildID snowflake.ID `json:"guild_id"`
+	// TimeoutMS is how long to wait for the guild to be cached in milliseconds.
+	TimeoutMS int64        `json:"timeout_ms"`
+	Options   CacheOptions `json:"options,omitempty"`
+}
+
+func (r GuildWaitRequest) cacheRequest() {}
+
+// GuildAppsRequest looks up the apps that have the guild cached, the app ID of the options is ignored.
+type GuildAppsRequest struct {
+	GuildID snowflake.ID `json:"guild_id"`
+	Options CacheOptions `json:"options,omitempty"`
+}
+
+func (r GuildAppsRequest) cacheRequest() {}
+
 type GuildListRequest struct {
 	Options CacheOptions `json:"options,omitempty"`
 }
 
 func (r GuildListRequest) cacheRequest() {}
 
+type GuildBatchGetRequest struct {
+	GuildIDs []snowflake.ID `json:"guild_ids"`
+	Options  CacheOptions   `json:"options,omitempty"`
+}
+
+func (r GuildBatchGetRequest) cacheRequest() {}
+
 type GuildCheckExistRequest struct {
 	GuildIDs []snowflake.ID `json:"guild_ids"`
 	Options  CacheOptions   `json:"options,omitempty"`
@@ -166,6 +263,14 @@ type ChannelListRequest struct {
 
 func (r ChannelListRequest) cacheRequest() {}
 
+type ChannelBatchGetRequest struct {
+	GuildID    *snowflake.ID  `json:"guild_id,omitempty"`
+	ChannelIDs []snowflake.ID `json:"channel_ids"`
+	Options    CacheOptions   `json:"options,omitempty"`
+}
+
+func (r ChannelBatchGetRequest) cacheRequest() {}
+
 type ChannelListWithPermissionsRequest struct {
 	GuildID snowflake.ID   `json:"guild_id"`
 	UserID  snowflake.ID   `json:"user_id"`
@@ -205,6 +310,14 @@ type RoleListRequest struct {
 
 func (r RoleListRequest) cacheRequest() {}
 
+type RoleBatchGetRequest struct {
+	GuildID *snowflake.ID  `json:"guild_id,omitempty"`
+	RoleIDs []snowflake.ID `json:"role_ids"`
+	Options CacheOptions   `json:"options,omitempty"`
+}
+
+func (r RoleBatchGetRequest) cacheRequest() {}
+
 type RoleSearchRequest struct {
 	GuildID *snowflake.ID   `json:"guild_id,omitempty"`
 	Data    json.RawMessage `json:"data,omitempty"`
@@ -240,6 +353,29 @@ type MassComputePermissionsRequest struct {
 
 func (r MassComputePermissionsRequest) cacheRequest() {}
 
+type PermissionsHierarchyCheckRequest struct {
+	GuildID       snowflake.ID   `json:"guild_id"`
+	ActorUserID   snowflake.ID   `json:"actor_user_id"`
+	ActorRoleIDs  []snowflake.ID `json:"actor_role_ids"`
+	TargetUserID  snowflake.ID   `json:"target_user_id"`
+	TargetRoleIDs []snowflake.ID `json:"target_role_ids"`
+	// RequiredPermissions are the permissions the actor needs for the action, e.g. KICK_MEMBERS.
+	RequiredPermissions discord.Permissions `json:"required_permissions,omitempty"`
+	Options             CacheOptions        `json:"options,omitempty"`
+}
+
+func (r PermissionsHierarchyCheckRequest) cacheRequest() {}
+
+type PermissionsCanManageRoleRequest struct {
+	GuildID snowflake.ID   `json:"guild_id"`
+	UserID  snowflake.ID   `json:"user_id"`
+	RoleIDs []snowflake.ID `json:"role_ids"`
+	RoleID  snowflake.ID   `json:"role_id"`
+	Options CacheOptions   `json:"options,omitempty"`
+}
+
+func (r PermissionsCanManageRoleRequest) cacheRequest() {}
+
 type EmojiListRequest struct {
 	GuildID snowflake.ID `json:"guild_id"`
 	Options CacheOptions `json:"options,omitempty"`
@@ -247,9 +383,42 @@ type EmojiListRequest struct {
 
 func (r EmojiListRequest) cacheRequest() {}
 
+type EmojiBatchGetRequest struct {
+	EmojiIDs []snowflake.ID `json:"emoji_ids"`
+	Options  CacheOptions   `json:"options,omitempty"`
+}
+
+func (r EmojiBatchGetRequest) cacheRequest() {}
+
 type StickerListRequest struct {
 	GuildID snowflake.ID `json:"guild_id"`
 	Options CacheOptions `json:"options,omitempty"`
 }
 
 func (r StickerListRequest) cacheRequest() {}
+
+type StickerBatchGetRequest struct {
+	StickerIDs []snowflake.ID `json:"sticker_ids"`
+	Options    CacheOptions   `json:"options,omitempty"`
+}
+
+func (r StickerBatchGetRequest) cacheRequest() {}
+
+type StatsGetRequest struct {
+	Options CacheOptions `json:"options,omitempty"`
+}
+
+func (r StatsGetRequest) cacheRequest() {}
+
+type CacheResyncRequest struct {
+	GuildID snowflake.ID `json:"guild_id,omitempty"`
+	Options CacheOptions `json:"options,omitempty"`
+}
+
+func (r CacheResyncRequest) cacheRequest() {}
+
+type CachePurgeAppRequest struct {
+	Options CacheOptions `json:"options,omitempty"`
+}
+
+func (r CachePurgeAppRequest) cacheRequest() {}
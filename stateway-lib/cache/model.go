@@ -6,6 +6,7 @@ import (
 
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-lib/permissions"
 )
 
 type Channel struct {
@@ -43,6 +44,14 @@ type Guild struct {
 	UpdatedAt   time.Time     `json:"updated_at"`
 }
 
+// GuildApp is an app that has the guild cached.
+type GuildApp struct {
+	AppID       snowflake.ID `json:"app_id"`
+	Unavailable bool         `json:"unavailable"`
+	Tainted     bool         `json:"tainted"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
 type GuildWithPermissions struct {
 	Guild
 	GuildPermissions discord.Permissions `json:"guild_permissions"`
@@ -52,6 +61,35 @@ type GuildWithPermissions struct {
 	MinChannelPermissions discord.Permissions `json:"min_channel_permissions"`
 }
 
+// FullGuild is a guild with its collections read from a single snapshot of the cache.
+// Collections that weren't included in the request are nil.
+type FullGuild struct {
+	Guild
+	Channels []*Channel `json:"channels"`
+	Roles    []*Role    `json:"roles"`
+	Emojis   []*Emoji   `json:"emojis"`
+	Stickers []*Sticker `json:"stickers"`
+}
+
+// FullGuildInclude selects the collections that are returned with a FullGuild.
+type FullGuildInclude struct {
+	Channels bool `json:"channels,omitempty"`
+	Roles    bool `json:"roles,omitempty"`
+	Emojis   bool `json:"emojis,omitempty"`
+	Stickers bool `json:"stickers,omitempty"`
+}
+
+// FullGuildIncludeAll includes all collections of the guild.
+var FullGuildIncludeAll = FullGuildInclude{
+	Channels: true,
+	Roles:    true,
+	Emojis:   true,
+	Stickers: true,
+}
+
+// HierarchyVerdict is the result of the role hierarchy checks, see the permissions package for the reasons.
+type HierarchyVerdict = permissions.HierarchyVerdict
+
 type Role struct {
 	AppID     snowflake.ID `json:"app_id"`
 	GuildID   snowflake.ID `json:"guild_id"`
@@ -72,6 +110,59 @@ type Sticker struct {
 	UpdatedAt time.Time       `json:"updated_at"`
 }
 
+// CacheStats are the statistics of the cached entities of one app.
+type CacheStats struct {
+	AppID    snowflake.ID `json:"app_id"`
+	Guilds   EntityStats  `json:"guilds"`
+	Channels EntityStats  `json:"channels"`
+	Roles    EntityStats  `json:"roles"`
+	Emojis   EntityStats  `json:"emojis"`
+	Stickers EntityStats  `json:"stickers"`
+	// MemoryBytes is the approximate memory used by the entities, it's only reported by in-memory stores.
+	MemoryBytes int64 `json:"memory_bytes,omitempty"`
+}
+
+// EntityStats are the statistics of the cached entities of one type.
+type EntityStats struct {
+	Count   int `json:"count"`
+	Tainted int `json:"tainted"`
+	// Unavailable is only reported for guilds.
+	Unavailable int `json:"unavailable,omitempty"`
+	// OldestUpdatedAt is the update time of the least recently updated entity, nil if there are none.
+	OldestUpdatedAt *time.Time `json:"oldest_updated_at,omitempty"`
+}
+
+// ResyncResult reports how a resync changed the cache.
+type ResyncResult struct {
+	// GuildIDs are the guilds the app is in, they are only returned when the guild list of the app is resynced.
+	GuildIDs []snowflake.ID `json:"guild_ids,omitempty"`
+	Upserted ResyncCounts   `json:"upserted"`
+	Deleted  ResyncCounts   `json:"deleted"`
+}
+
+// ResyncCounts are the number of entities of each type that a resync has written or deleted.
+type ResyncCounts struct {
+	Guilds   int `json:"guilds"`
+	Channels int `json:"channels"`
+	Roles    int `json:"roles"`
+	Emojis   int `json:"emojis"`
+	Stickers int `json:"stickers"`
+}
+
+// Add adds the counts of other, e.g. to sum up the resyncs of multiple guilds.
+func (c *ResyncCounts) Add(other ResyncCounts) {
+	c.Guilds += other.Guilds
+	c.Channels += other.Channels
+	c.Roles += other.Roles
+	c.Emojis += other.Emojis
+	c.Stickers += other.Stickers
+}
+
+// PurgeResult reports how many entities have been deleted when purging an app.
+type PurgeResult struct {
+	Deleted ResyncCounts `json:"deleted"`
+}
+
 func (c *Channel) UnmarshalJSON(data []byte) error {
 	var aux struct {
 		AppID     snowflake.ID             `json:"app_id"`
@@ -96,9 +187,25 @@ func (c *Channel) UnmarshalJSON(data []byte) error {
 }
 
 type CacheOptions struct {
-	AppID  snowflake.ID `json:"app_id"`
-	Limit  int          `json:"limit"`
-	Offset int          `json:"offset"`
+	AppID          snowflake.ID `json:"app_id"`
+	Limit          int          `json:"limit"`
+	Offset         int          `json:"offset"`
+	After          snowflake.ID `json:"after,omitempty"`
+	ExcludeTainted bool         `json:"exclude_tainted,omitempty"`
+	Filter         *Filter      `json:"filter,omitempty"`
+	// CommunicationDisabledUntil is the end of the member's timeout when computing permissions.
+	CommunicationDisabledUntil *time.Time `json:"communication_disabled_until,omitempty"`
+	// FetchOnMiss fetches guilds, channels and roles from Discord if they aren't cached.
+	FetchOnMiss bool `json:"fetch_on_miss,omitempty"`
+	// BypassNearCache is only used by the client and never sent to the cache service.
+	BypassNearCache bool `json:"-"`
+	// PartitionCount is only used by the client to route requests to the partitions of the cache service.
+	PartitionCount int `json:"-"`
+}
+
+// paginated reports whether the options only select a part of a list.
+func (o CacheOptions) paginated() bool {
+	return o.Limit > 0 || o.Offset > 0 || o.After != 0 || o.Filter != nil
 }
 
 func ResolveOptions(opts ...CacheOption) CacheOptions {
@@ -120,6 +227,21 @@ func (o CacheOptions) Destructure() []CacheOption {
 	if o.Offset > 0 {
 		res = append(res, WithOffset(o.Offset))
 	}
+	if o.After != 0 {
+		res = append(res, WithAfter(o.After))
+	}
+	if o.ExcludeTainted {
+		res = append(res, WithExcludeTainted())
+	}
+	if o.Filter != nil {
+		res = append(res, WithFilter(*o.Filter))
+	}
+	if o.CommunicationDisabledUntil != nil {
+		res = append(res, WithCommunicationDisabledUntil(*o.CommunicationDisabledUntil))
+	}
+	if o.FetchOnMiss {
+		res = append(res, WithFetchOnMiss())
+	}
 	return res
 }
 
@@ -142,3 +264,61 @@ func WithOffset(offset int) CacheOption {
 		o.Offset = offset
 	}
 }
+
+// WithAfter only returns entities with a higher ID than the cursor.
+// Pass the NextCursor of a page to get the next page.
+func WithAfter(after snowflake.ID) CacheOption {
+	return func(o *CacheOptions) {
+		o.After = after
+	}
+}
+
+// WithExcludeTainted skips entities that were flagged as tainted by a shard READY
+// and have not been confirmed by the gateway since, so they may be stale.
+func WithExcludeTainted() CacheOption {
+	return func(o *CacheOptions) {
+		o.ExcludeTainted = true
+	}
+}
+
+// WithFilter only returns entities whose data matches the filter.
+// It applies to the search methods and is combined with the data of the search.
+func WithFilter(filter Filter) CacheOption {
+	return func(o *CacheOptions) {
+		o.Filter = &filter
+	}
+}
+
+// WithCommunicationDisabledUntil computes permissions for a member that is timed out until the given time.
+// Timed out members only keep VIEW_CHANNEL and READ_MESSAGE_HISTORY, unless they are the owner or an administrator.
+func WithCommunicationDisabledUntil(until time.Time) CacheOption {
+	return func(o *CacheOptions) {
+		o.CommunicationDisabledUntil = &until
+	}
+}
+
+// WithFetchOnMiss makes the cache service fetch guilds, channels and roles from the Discord API if they aren't cached
+// or tainted while ExcludeTainted is set. The fetched entity is stored before it's returned.
+// Concurrent misses for the same entity share a single API request.
+func WithFetchOnMiss() CacheOption {
+	return func(o *CacheOptions) {
+		o.FetchOnMiss = true
+	}
+}
+
+// WithPartitionCount routes requests to the partitions of a partitioned cache service.
+// It must match the partition count of the cache service, requests for a guild are sent to the partition that owns it
+// and app wide requests are sent to all partitions and merged.
+func WithPartitionCount(partitionCount int) CacheOption {
+	return func(o *CacheOptions) {
+		o.PartitionCount = partitionCount
+	}
+}
+
+// WithBypassNearCache reads the entity from the cache service even if it's in the near cache of the client.
+// The fetched entity still replaces the cached one.
+func WithBypassNearCache() CacheOption {
+	return func(o *CacheOptions) {
+		o.BypassNearCache = true
+	}
+}
@@ -0,0 +1,332 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// NearCacheEntityConfig configures the near cache of one entity type.
+type NearCacheEntityConfig struct {
+	// Size is the maximum number of entries, 0 disables the near cache for the entity type.
+	Size int
+	// TTL is how long an entry is served before it's fetched again, 0 keeps entries until they are evicted.
+	// Invalidations are not persisted, so the TTL bounds how long changes made while the client
+	// wasn't connected can go unnoticed.
+	TTL time.Duration
+}
+
+// NearCacheConfig configures the local cache of a CacheClient.
+// Single entities and the unpaginated channel and role lists of guilds are cached.
+type NearCacheConfig struct {
+	Guilds   NearCacheEntityConfig
+	Channels NearCacheEntityConfig
+	Roles    NearCacheEntityConfig
+}
+
+func DefaultNearCacheConfig() NearCacheConfig {
+	return NearCacheConfig{
+		Guilds:   NearCacheEntityConfig{Size: 1000, TTL: 5 * time.Minute},
+		Channels: NearCacheEntityConfig{Size: 10000, TTL: 5 * time.Minute},
+		Roles:    NearCacheEntityConfig{Size: 10000, TTL: 5 * time.Minute},
+	}
+}
+
+type NearCacheEntityStats struct {
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Invalidations uint64 `json:"invalidations"`
+	Size          int    `json:"size"`
+}
+
+type NearCacheStats struct {
+	Guilds   NearCacheEntityStats `json:"guilds"`
+	Channels NearCacheEntityStats `json:"channels"`
+	Roles    NearCacheEntityStats `json:"roles"`
+}
+
+// nearCacheKey identifies an entity, or the entity list of a guild, in the near cache.
+type nearCacheKey struct {
+	appID          snowflake.ID
+	id             snowflake.ID
+	excludeTainted bool
+}
+
+func newNearCacheKey(options CacheOptions, id snowflake.ID) nearCacheKey {
+	return nearCacheKey{appID: options.AppID, id: id, excludeTainted: options.ExcludeTainted}
+}
+
+// nearCacheKeys returns the keys of an entity for all option combinations.
+func nearCacheKeys(appID snowflake.ID, id snowflake.ID) []nearCacheKey {
+	return []nearCacheKey{
+		{appID: appID, id: id},
+		{appID: appID, id: id, excludeTainted: true},
+	}
+}
+
+// nearCache is the local cache of a CacheClient. The caches of disabled entity types are nil.
+// Cached entities are shared between callers and must not be modified.
+type nearCache struct {
+	guilds        *lruCache[nearCacheKey, *Guild]
+	channels      *lruCache[nearCacheKey, *Channel]
+	guildChannels *lruCache[nearCacheKey, *Page[*Channel]]
+	roles         *lruCache[nearCacheKey, *Role]
+	guildRoles    *lruCache[nearCacheKey, *Page[*Role]]
+}
+
+func newNearCache(config NearCacheConfig) *nearCache {
+	return &nearCache{
+		guilds:        newLRUCache[nearCacheKey, *Guild](config.Guilds),
+		channels:      newLRUCache[nearCacheKey, *Channel](config.Channels),
+		guildChannels: newLRUCache[nearCacheKey, *Page[*Channel]](config.Channels),
+		roles:         newLRUCache[nearCacheKey, *Role](config.Roles),
+		guildRoles:    newLRUCache[nearCacheKey, *Page[*Role]](config.Roles),
+	}
+}
+
+// invalidate removes the entries affected by the invalidation.
+// Guild invalidations remove all entities of the guild, because the cache service replaces them on GUILD_CREATE.
+func (n *nearCache) invalidate(i Invalidation) {
+	switch i.EntityType {
+	case InvalidationEntityTypeApp:
+		inApp := func(key nearCacheKey) bool {
+			return key.appID == i.AppID
+		}
+		removeKeysFunc(n.guilds, inApp)
+		removeKeysFunc(n.channels, inApp)
+		removeKeysFunc(n.guildChannels, inApp)
+		removeKeysFunc(n.roles, inApp)
+		removeKeysFunc(n.guildRoles, inApp)
+	case ChangeEntityTypeGuild:
+		n.guilds.remove(nearCacheKeys(i.AppID, i.EntityID)...)
+		n.channels.removeFunc(func(_ nearCacheKey, channel *Channel) bool {
+			return channel.AppID == i.AppID && channel.GuildID == i.GuildID
+		})
+		n.guildChannels.remove(nearCacheKeys(i.AppID, i.GuildID)...)
+		n.roles.removeFunc(func(_ nearCacheKey, role *Role) bool {
+			return role.AppID == i.AppID && role.GuildID == i.GuildID
+		})
+		n.guildRoles.remove(nearCacheKeys(i.AppID, i.GuildID)...)
+	case ChangeEntityTypeChannel:
+		n.channels.remove(nearCacheKeys(i.AppID, i.EntityID)...)
+		n.guildChannels.remove(nearCacheKeys(i.AppID, i.GuildID)...)
+	case ChangeEntityTypeRole:
+		n.roles.remove(nearCacheKeys(i.AppID, i.EntityID)...)
+		n.guildRoles.remove(nearCacheKeys(i.AppID, i.GuildID)...)
+	}
+}
+
+// removeKeysFunc removes the entries whose key matches fn.
+func removeKeysFunc[V any](c *lruCache[nearCacheKey, V], fn func(key nearCacheKey) bool) {
+	c.removeFunc(func(key nearCacheKey, _ V) bool {
+		return fn(key)
+	})
+}
+
+func (n *nearCache) stats() NearCacheStats {
+	return NearCacheStats{
+		Guilds:   n.guilds.stats(),
+		Channels: mergeNearCacheStats(n.channels.stats(), n.guildChannels.stats()),
+		Roles:    mergeNearCacheStats(n.roles.stats(), n.guildRoles.stats()),
+	}
+}
+
+func mergeNearCacheStats(a NearCacheEntityStats, b NearCacheEntityStats) NearCacheEntityStats {
+	return NearCacheEntityStats{
+		Hits:          a.Hits + b.Hits,
+		Misses:        a.Misses + b.Misses,
+		Invalidations: a.Invalidations + b.Invalidations,
+		Size:          a.Size + b.Size,
+	}
+}
+
+// nearCacheGet returns the cached value of the key or fetches and caches it.
+// Values that are rejected by valid are fetched again, bypass skips the lookup but still caches the fetched value.
+func nearCacheGet[V any](
+	c *lruCache[nearCacheKey, V],
+	key nearCacheKey,
+	bypass bool,
+	valid func(V) bool,
+	fetch func() (V, error),
+) (V, error) {
+	if c == nil {
+		return fetch()
+	}
+
+	if !bypass {
+		value, ok := c.get(key)
+		if ok && (valid == nil || valid(value)) {
+			c.hits.Add(1)
+			return value, nil
+		}
+		c.misses.Add(1)
+	}
+
+	// Invalidations that arrive while fetching would be lost if the result was cached
+	generation := c.generation()
+
+	value, err := fetch()
+	if err != nil {
+		return value, err
+	}
+
+	c.add(key, value, generation)
+	return value, nil
+}
+
+type lruEntry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+// lruCache is a size bounded cache that evicts the least recently used entry.
+// All methods can be called on a nil cache, which never contains any entries.
+type lruCache[K comparable, V any] struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[K]*list.Element
+	order   *list.List
+	// gen is incremented on every removal, so values fetched before the removal are not cached.
+	gen uint64
+
+	hits          atomic.Uint64
+	misses        atomic.Uint64
+	invalidations atomic.Uint64
+}
+
+func newLRUCache[K comparable, V any](config NearCacheEntityConfig) *lruCache[K, V] {
+	if config.Size <= 0 {
+		return nil
+	}
+
+	return &lruCache[K, V]{
+		size:    config.Size,
+		ttl:     config.TTL,
+		entries: make(map[K]*list.Element, config.Size),
+		order:   list.New(),
+	}
+}
+
+func (c *lruCache[K, V]) get(key K) (V, bool) {
+	var zero V
+	if c == nil {
+		return zero, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+
+	entry := el.Value.(*lruEntry[K, V])
+	if c.ttl > 0 && time.Now().After(entry.expiresAt) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return zero, false
+	}
+
+	c.order.MoveToFront(el)
+	return entry.value, true
+}
+
+func (c *lruCache[K, V]) generation() uint64 {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.gen
+}
+
+// add caches the value unless entries have been removed since the generation was read.
+func (c *lruCache[K, V]) add(key K, value V, generation uint64) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.gen {
+		return
+	}
+
+	expiresAt := time.Now().Add(c.ttl)
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*lruEntry[K, V])
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value, expiresAt: expiresAt})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[K, V]).key)
+	}
+}
+
+func (c *lruCache[K, V]) remove(keys ...K) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	for _, key := range keys {
+		if el, ok := c.entries[key]; ok {
+			c.order.Remove(el)
+			delete(c.entries, key)
+			c.invalidations.Add(1)
+		}
+	}
+}
+
+func (c *lruCache[K, V]) removeFunc(fn func(key K, value V) bool) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	for key, el := range c.entries {
+		if fn(key, el.Value.(*lruEntry[K, V]).value) {
+			c.order.Remove(el)
+			delete(c.entries, key)
+			c.invalidations.Add(1)
+		}
+	}
+}
+
+func (c *lruCache[K, V]) stats() NearCacheEntityStats {
+	if c == nil {
+		return NearCacheEntityStats{}
+	}
+
+	c.mu.Lock()
+	size := len(c.entries)
+	c.mu.Unlock()
+
+	return NearCacheEntityStats{
+		Hits:          c.hits.Load(),
+		Misses:        c.misses.Load(),
+		Invalidations: c.invalidations.Load(),
+		Size:          size,
+	}
+}
@@ -0,0 +1,256 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// fetchCounter returns a fetch function that returns the value and counts how often it's called.
+func fetchCounter[V any](value V, calls *int) func() (V, error) {
+	return func() (V, error) {
+		*calls++
+		return value, nil
+	}
+}
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newLRUCache[int, string](NearCacheEntityConfig{Size: 2})
+	c.add(1, "a", c.generation())
+	c.add(2, "b", c.generation())
+
+	// Reading 1 makes 2 the least recently used entry
+	if _, ok := c.get(1); !ok {
+		t.Fatal("expected 1 to be cached")
+	}
+	c.add(3, "c", c.generation())
+
+	if _, ok := c.get(2); ok {
+		t.Error("expected 2 to be evicted")
+	}
+	for _, key := range []int{1, 3} {
+		if _, ok := c.get(key); !ok {
+			t.Errorf("expected %d to be cached", key)
+		}
+	}
+	if size := c.stats().Size; size != 2 {
+		t.Errorf("got size %d, want 2", size)
+	}
+}
+
+func TestLRUCacheTTL(t *testing.T) {
+	c := newLRUCache[int, string](NearCacheEntityConfig{Size: 10, TTL: time.Minute})
+	c.add(1, "a", c.generation())
+	c.add(2, "b", c.generation())
+
+	if _, ok := c.get(1); !ok {
+		t.Fatal("expected 1 to be cached")
+	}
+
+	c.entries[1].Value.(*lruEntry[int, string]).expiresAt = time.Now().Add(-time.Second)
+
+	if _, ok := c.get(1); ok {
+		t.Error("expected 1 to be expired")
+	}
+	if _, ok := c.get(2); !ok {
+		t.Error("expected 2 to be cached")
+	}
+	if size := c.stats().Size; size != 1 {
+		t.Errorf("got size %d, want 1", size)
+	}
+}
+
+func TestLRUCacheDisabled(t *testing.T) {
+	c := newLRUCache[nearCacheKey, string](NearCacheEntityConfig{Size: 0})
+	if c != nil {
+		t.Fatal("expected a size of 0 to disable the cache")
+	}
+
+	calls := 0
+	for range 2 {
+		value, err := nearCacheGet(c, nearCacheKey{id: 1}, false, nil, fetchCounter("a", &calls))
+		if err != nil || value != "a" {
+			t.Fatalf("got %q, %v", value, err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("got %d fetches, want 2", calls)
+	}
+	if stats := c.stats(); stats != (NearCacheEntityStats{}) {
+		t.Errorf("got stats %+v, want none", stats)
+	}
+}
+
+func TestNearCacheGetStats(t *testing.T) {
+	c := newLRUCache[nearCacheKey, string](NearCacheEntityConfig{Size: 10})
+	key := nearCacheKey{appID: 1, id: 1}
+
+	calls := 0
+	for range 3 {
+		value, err := nearCacheGet(c, key, false, nil, fetchCounter("a", &calls))
+		if err != nil || value != "a" {
+			t.Fatalf("got %q, %v", value, err)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("got %d fetches, want 1", calls)
+	}
+	stats := c.stats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Size != 1 {
+		t.Errorf("got stats %+v, want 2 hits, 1 miss and 1 entry", stats)
+	}
+}
+
+func TestNearCacheGetErrorsAreNotCached(t *testing.T) {
+	c := newLRUCache[nearCacheKey, string](NearCacheEntityConfig{Size: 10})
+	key := nearCacheKey{appID: 1, id: 1}
+
+	_, err := nearCacheGet(c, key, false, nil, func() (string, error) {
+		return "", errors.New("not found")
+	})
+	if err == nil {
+		t.Fatal("expected the fetch error")
+	}
+
+	calls := 0
+	_, err = nearCacheGet(c, key, false, nil, fetchCounter("a", &calls))
+	if err != nil || calls != 1 {
+		t.Errorf("got %d fetches and %v, want the value to be fetched again", calls, err)
+	}
+}
+
+func TestNearCacheGetBypass(t *testing.T) {
+	c := newLRUCache[nearCacheKey, string](NearCacheEntityConfig{Size: 10})
+	key := nearCacheKey{appID: 1, id: 1}
+
+	calls := 0
+	_, err := nearCacheGet(c, key, false, nil, fetchCounter("a", &calls))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Bypassing fetches the value again and replaces the cached one
+	value, err := nearCacheGet(c, key, true, nil, fetchCounter("b", &calls))
+	if err != nil || value != "b" {
+		t.Fatalf("got %q, %v", value, err)
+	}
+
+	value, err = nearCacheGet(c, key, false, nil, fetchCounter("c", &calls))
+	if err != nil || value != "b" {
+		t.Fatalf("got %q, %v, want the value fetched while bypassing", value, err)
+	}
+
+	if calls != 2 {
+		t.Errorf("got %d fetches, want 2", calls)
+	}
+	stats := c.stats()
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("got stats %+v, want bypassed reads to be neither hits nor misses", stats)
+	}
+}
+
+func TestNearCacheGetValid(t *testing.T) {
+	c := newLRUCache[nearCacheKey, string](NearCacheEntityConfig{Size: 10})
+	key := nearCacheKey{appID: 1, id: 1}
+
+	calls := 0
+	_, err := nearCacheGet(c, key, false, nil, fetchCounter("short", &calls))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Cached values that don't satisfy the caller are fetched again
+	valid := func(value string) bool { return len(value) > 5 }
+	value, err := nearCacheGet(c, key, false, valid, fetchCounter("longer", &calls))
+	if err != nil || value != "longer" {
+		t.Fatalf("got %q, %v", value, err)
+	}
+	if calls != 2 {
+		t.Errorf("got %d fetches, want 2", calls)
+	}
+}
+
+func TestNearCacheGetInvalidatedWhileFetching(t *testing.T) {
+	c := newLRUCache[nearCacheKey, string](NearCacheEntityConfig{Size: 10})
+	key := nearCacheKey{appID: 1, id: 1}
+
+	// The value was read before the invalidation, so it could be stale and must not be cached
+	value, err := nearCacheGet(c, key, false, nil, func() (string, error) {
+		c.remove(key)
+		return "stale", nil
+	})
+	if err != nil || value != "stale" {
+		t.Fatalf("got %q, %v", value, err)
+	}
+	if _, ok := c.get(key); ok {
+		t.Error("expected the value fetched during the invalidation not to be cached")
+	}
+
+	calls := 0
+	_, err = nearCacheGet(c, key, false, nil, fetchCounter("fresh", &calls))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.get(key); !ok {
+		t.Error("expected the value fetched after the invalidation to be cached")
+	}
+}
+
+func TestNearCacheInvalidate(t *testing.T) {
+	n := newNearCache(DefaultNearCacheConfig())
+
+	addChannel := func(appID snowflake.ID, guildID snowflake.ID, channelID snowflake.ID) {
+		key := newNearCacheKey(CacheOptions{AppID: appID}, channelID)
+		n.channels.add(key, &Channel{AppID: appID, GuildID: guildID, ChannelID: channelID}, n.channels.generation())
+	}
+	addRole := func(appID snowflake.ID, guildID snowflake.ID, roleID snowflake.ID) {
+		key := newNearCacheKey(CacheOptions{AppID: appID, ExcludeTainted: true}, roleID)
+		n.roles.add(key, &Role{AppID: appID, GuildID: guildID, RoleID: roleID}, n.roles.generation())
+	}
+	cached := func(appID snowflake.ID, excludeTainted bool, id snowflake.ID) bool {
+		key := newNearCacheKey(CacheOptions{AppID: appID, ExcludeTainted: excludeTainted}, id)
+		_, channel := n.channels.get(key)
+		_, role := n.roles.get(key)
+		return channel || role
+	}
+
+	addChannel(1, 10, 100)
+	addChannel(1, 10, 101)
+	addChannel(1, 20, 200)
+	addRole(1, 10, 110)
+	addRole(1, 20, 210)
+	addChannel(2, 10, 100)
+
+	n.invalidate(Invalidation{AppID: 1, GuildID: 10, EntityType: ChangeEntityTypeChannel, EntityID: 100})
+	if cached(1, false, 100) {
+		t.Error("expected the invalidated channel to be removed")
+	}
+	if !cached(1, false, 101) || !cached(2, false, 100) {
+		t.Error("expected other channels and apps to be kept")
+	}
+
+	// Guild invalidations remove all entities of the guild, whatever options they were cached with
+	n.invalidate(Invalidation{AppID: 1, GuildID: 10, EntityType: ChangeEntityTypeGuild, EntityID: 10})
+	if cached(1, false, 101) || cached(1, true, 110) {
+		t.Error("expected the entities of the guild to be removed")
+	}
+	if !cached(1, false, 200) || !cached(1, true, 210) {
+		t.Error("expected the entities of other guilds to be kept")
+	}
+
+	n.invalidate(Invalidation{AppID: 1, EntityType: InvalidationEntityTypeApp})
+	if cached(1, false, 200) || cached(1, true, 210) {
+		t.Error("expected the entities of the app to be removed")
+	}
+	if !cached(2, false, 100) {
+		t.Error("expected the entities of other apps to be kept")
+	}
+
+	stats := n.stats()
+	if stats.Channels.Invalidations != 3 || stats.Roles.Invalidations != 2 {
+		t.Errorf("got stats %+v, want 3 channel and 2 role invalidations", stats)
+	}
+}
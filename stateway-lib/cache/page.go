@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"iter"
+	"slices"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// DefaultPageSize is the page size used by the iterators when no limit is set.
+const DefaultPageSize = 100
+
+// Page is a page of entities ordered by ID.
+type Page[T any] struct {
+	Items []T `json:"items"`
+	// NextCursor is the ID to pass to WithAfter to get the next page, it's nil on the last page.
+	NextCursor *snowflake.ID `json:"next_cursor,omitempty"`
+}
+
+// IterPages walks all pages returned by fetch, starting after the given cursor.
+// The iteration stops at the first error, which is yielded with the zero value of T.
+func IterPages[T any](after snowflake.ID, fetch func(after snowflake.ID) (*Page[T], error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			page, err := fetch(after)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			for _, item := range page.Items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			if page.NextCursor == nil {
+				return
+			}
+			after = *page.NextCursor
+		}
+	}
+}
+
+// pageOptions returns the options to fetch the page after the cursor.
+// The page size defaults to DefaultPageSize and can be overridden with WithLimit.
+// An offset would be applied to every page, so it shouldn't be combined with the iterators.
+func pageOptions(opts []CacheOption, after snowflake.ID) []CacheOption {
+	return slices.Concat([]CacheOption{WithLimit(DefaultPageSize)}, opts, []CacheOption{WithAfter(after)})
+}
@@ -0,0 +1,305 @@
+package cache
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-lib/broker"
+	"github.com/merlinfuchs/stateway/stateway-lib/service"
+)
+
+// GuildPartition returns the partition of the cache service that owns the guild.
+// Guilds are split by their creation time like Discord splits them across shards.
+func GuildPartition(guildID snowflake.ID, partitionCount int) int {
+	if partitionCount <= 1 {
+		return 0
+	}
+	return int((uint64(guildID) >> 22) % uint64(partitionCount))
+}
+
+// Partition is the part of the guilds that an instance of the cache service owns.
+// A Count of 0 or 1 means the cache isn't partitioned and the instance owns all guilds.
+type Partition struct {
+	ID    int
+	Count int
+}
+
+// Partitioned reports whether the guilds are split across multiple partitions.
+func (p Partition) Partitioned() bool {
+	return p.Count > 1
+}
+
+// OwnsGuild reports whether the guild belongs to the partition.
+func (p Partition) OwnsGuild(guildID snowflake.ID) bool {
+	return !p.Partitioned() || GuildPartition(guildID, p.Count) == p.ID
+}
+
+// Name returns the name of the partition that requests are routed by, it's empty if the cache isn't partitioned.
+func (p Partition) Name() string {
+	if !p.Partitioned() {
+		return ""
+	}
+	return strconv.Itoa(p.ID)
+}
+
+// guildRequest sends the request to the partition that owns the guild.
+func guildRequest[R any](
+	ctx context.Context,
+	b broker.Broker,
+	options CacheOptions,
+	guildID snowflake.ID,
+	method CacheMethod,
+	request CacheRequest,
+	opts ...broker.RequestOption,
+) (R, error) {
+	partition := Partition{ID: GuildPartition(guildID, options.PartitionCount), Count: options.PartitionCount}
+	if partition.Partitioned() {
+		opts = append(opts, broker.WithPartition(partition.Name()))
+	}
+	return cacheRequest[R](ctx, b, method, request, opts...)
+}
+
+// fanOutRequest sends the request to all partitions and returns their responses ordered by partition.
+// It fails if any of the partitions fails.
+func fanOutRequest[R any](
+	ctx context.Context,
+	b broker.Broker,
+	options CacheOptions,
+	method CacheMethod,
+	request CacheRequest,
+	opts ...broker.RequestOption,
+) ([]R, error) {
+	count := max(options.PartitionCount, 1)
+	responses := make([]R, count)
+	errs := make([]error, count)
+
+	var wg sync.WaitGroup
+	for i := range count {
+		partition := Partition{ID: i, Count: count}
+		wg.Go(func() {
+			partitionOpts := opts
+			if partition.Partitioned() {
+				partitionOpts = append(slices.Clip(opts), broker.WithPartition(partition.Name()))
+			}
+			responses[i], errs[i] = cacheRequest[R](ctx, b, method, request, partitionOpts...)
+		})
+	}
+	wg.Wait()
+
+	err := errors.Join(errs...)
+	if err != nil {
+		return nil, err
+	}
+	return responses, nil
+}
+
+// fanOutFind sends a request for an entity whose guild isn't known to all partitions.
+// Only the partition that owns the guild of the entity finds it, the others respond with not found errors.
+// With FetchOnMiss, the partitions are first asked without it and only one partition fetches the entity
+// once none of them has it cached, otherwise every miss would cost a Discord API request per partition.
+func fanOutFind[R any](
+	ctx context.Context,
+	b broker.Broker,
+	options CacheOptions,
+	method CacheMethod,
+	request func(options CacheOptions) CacheRequest,
+) (R, error) {
+	if options.PartitionCount <= 1 {
+		return cacheRequest[R](ctx, b, method, request(options))
+	}
+
+	cachedOptions := options
+	cachedOptions.FetchOnMiss = false
+
+	response, err := fanOutFindCached[R](ctx, b, options.PartitionCount, method, request(cachedOptions))
+	if err == nil || !options.FetchOnMiss || !service.IsErrorCode(err, service.ErrorCodeNotFound) {
+		return response, err
+	}
+
+	partition := Partition{ID: 0, Count: options.PartitionCount}
+	return cacheRequest[R](ctx, b, method, request(options), broker.WithPartition(partition.Name()))
+}
+
+// fanOutFindCached sends the request to all partitions and returns the first response that isn't a not found error.
+func fanOutFindCached[R any](
+	ctx context.Context,
+	b broker.Broker,
+	partitionCount int,
+	method CacheMethod,
+	request CacheRequest,
+) (R, error) {
+	// Each partition is asked individually, so the not found errors of the other partitions can be ignored
+	var zero R
+	var notFoundErr error
+	responses := make([]R, partitionCount)
+	errs := make([]error, partitionCount)
+
+	var wg sync.WaitGroup
+	for i := range partitionCount {
+		partition := Partition{ID: i, Count: partitionCount}
+		wg.Go(func() {
+			responses[i], errs[i] = cacheRequest[R](ctx, b, method, request, broker.WithPartition(partition.Name()))
+		})
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err == nil {
+			return responses[i], nil
+		}
+		if !service.IsErrorCode(err, service.ErrorCodeNotFound) {
+			return zero, err
+		}
+		notFoundErr = err
+	}
+	return zero, notFoundErr
+}
+
+// fanOutPage requests a page from all partitions and merges the pages by ID.
+// The offset can only be applied to the merged entities, so the partitions also return the entities before it.
+func fanOutPage[T any](
+	ctx context.Context,
+	b broker.Broker,
+	options CacheOptions,
+	method CacheMethod,
+	request func(options CacheOptions) CacheRequest,
+	id func(T) snowflake.ID,
+) (*Page[T], error) {
+	if options.PartitionCount <= 1 {
+		return cacheRequest[*Page[T]](ctx, b, method, request(options))
+	}
+
+	partitionOptions := options
+	partitionOptions.Offset = 0
+	if options.Limit > 0 {
+		partitionOptions.Limit = options.Limit + options.Offset
+	}
+
+	pages, err := fanOutRequest[*Page[T]](ctx, b, options, method, request(partitionOptions))
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := false
+	items := make([]T, 0)
+	for _, page := range pages {
+		items = append(items, page.Items...)
+		hasMore = hasMore || page.NextCursor != nil
+	}
+	slices.SortFunc(items, func(a, b T) int {
+		return cmp.Compare(id(a), id(b))
+	})
+
+	items = items[min(options.Offset, len(items)):]
+	if options.Limit > 0 && len(items) > options.Limit {
+		items = items[:options.Limit]
+		hasMore = true
+	}
+
+	page := &Page[T]{Items: items}
+	if hasMore && len(items) > 0 {
+		cursor := id(items[len(items)-1])
+		page.NextCursor = &cursor
+	}
+	return page, nil
+}
+
+// fanOutCount sums up the counts of all partitions.
+func fanOutCount(ctx context.Context, b broker.Broker, options CacheOptions, method CacheMethod, request CacheRequest) (int, error) {
+	counts, err := fanOutRequest[int](ctx, b, options, method, request)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	return total, nil
+}
+
+// fanOutBatch requests entities by ID from all partitions.
+// The responses are ordered like the requested IDs, every entity is taken from the partition that found it.
+func fanOutBatch[T any](ctx context.Context, b broker.Broker, options CacheOptions, method CacheMethod, request CacheRequest) ([]*T, error) {
+	batches, err := fanOutRequest[[]*T](ctx, b, options, method, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeByIndex(batches, func(entity *T) bool {
+		return entity != nil
+	}), nil
+}
+
+// mergeByIndex merges responses that are ordered like the requested IDs, taking the first value that is found.
+func mergeByIndex[T any](batches [][]T, found func(T) bool) []T {
+	if len(batches) == 0 {
+		return nil
+	}
+
+	res := slices.Clone(batches[0])
+	for _, batch := range batches[1:] {
+		for i, value := range batch {
+			if i < len(res) && !found(res[i]) && found(value) {
+				res[i] = value
+			}
+		}
+	}
+	return res
+}
+
+// mergeStats merges the stats of the partitions into the stats of each app.
+func mergeStats(partitions [][]*CacheStats) []*CacheStats {
+	byApp := make(map[snowflake.ID]*CacheStats)
+	for _, partition := range partitions {
+		for _, stats := range partition {
+			merged, ok := byApp[stats.AppID]
+			if !ok {
+				merged = &CacheStats{AppID: stats.AppID}
+				byApp[stats.AppID] = merged
+			}
+
+			merged.Guilds.add(stats.Guilds)
+			merged.Channels.add(stats.Channels)
+			merged.Roles.add(stats.Roles)
+			merged.Emojis.add(stats.Emojis)
+			merged.Stickers.add(stats.Stickers)
+			merged.MemoryBytes += stats.MemoryBytes
+		}
+	}
+
+	res := make([]*CacheStats, 0, len(byApp))
+	for _, stats := range byApp {
+		res = append(res, stats)
+	}
+	slices.SortFunc(res, func(a, b *CacheStats) int {
+		return cmp.Compare(a.AppID, b.AppID)
+	})
+	return res
+}
+
+func (s *EntityStats) add(other EntityStats) {
+	s.Count += other.Count
+	s.Tainted += other.Tainted
+	s.Unavailable += other.Unavailable
+	if other.OldestUpdatedAt != nil && (s.OldestUpdatedAt == nil || other.OldestUpdatedAt.Before(*s.OldestUpdatedAt)) {
+		s.OldestUpdatedAt = other.OldestUpdatedAt
+	}
+}
+
+// mergeResyncResults merges the guild list resyncs of the partitions, which only return the guilds they own.
+func mergeResyncResults(partitions []*ResyncResult) *ResyncResult {
+	res := &ResyncResult{GuildIDs: make([]snowflake.ID, 0)}
+	for _, partition := range partitions {
+		res.GuildIDs = append(res.GuildIDs, partition.GuildIDs...)
+		res.Upserted.Add(partition.Upserted)
+		res.Deleted.Add(partition.Deleted)
+	}
+	slices.Sort(res.GuildIDs)
+	return res
+}
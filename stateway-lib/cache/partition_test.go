@@ -0,0 +1,204 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/merlinfuchs/stateway/stateway-lib/broker"
+	"github.com/merlinfuchs/stateway/stateway-lib/service"
+)
+
+// fakePartitionBroker serves role list requests from the roles of each partition, like the cache service does.
+type fakePartitionBroker struct {
+	broker.Broker
+	roleIDs map[string][]snowflake.ID
+
+	mu       sync.Mutex
+	requests []CacheOptions
+}
+
+func (b *fakePartitionBroker) Request(
+	ctx context.Context,
+	serviceType service.ServiceType,
+	method string,
+	request any,
+	opts ...broker.RequestOption,
+) (service.Response, error) {
+	var options broker.RequestOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	listOptions := request.(RoleListRequest).Options
+	b.mu.Lock()
+	b.requests = append(b.requests, listOptions)
+	b.mu.Unlock()
+
+	var roles []*Role
+	for _, roleID := range b.roleIDs[options.Partition] {
+		if roleID > listOptions.After {
+			roles = append(roles, &Role{RoleID: roleID})
+		}
+	}
+	roles = roles[min(listOptions.Offset, len(roles)):]
+
+	page := &Page[*Role]{Items: roles}
+	if listOptions.Limit > 0 && len(roles) > listOptions.Limit {
+		page.Items = roles[:listOptions.Limit]
+		cursor := page.Items[len(page.Items)-1].RoleID
+		page.NextCursor = &cursor
+	}
+
+	data, err := json.Marshal(page)
+	if err != nil {
+		return service.Response{}, err
+	}
+	return service.Response{Success: true, Data: data}, nil
+}
+
+func newFakePartitionBroker() *fakePartitionBroker {
+	return &fakePartitionBroker{
+		roleIDs: map[string][]snowflake.ID{
+			"0": {2, 4, 6, 8},
+			"1": {1, 3, 5, 7, 9},
+		},
+	}
+}
+
+func listRoles(b broker.Broker, options CacheOptions) (*Page[*Role], error) {
+	options.PartitionCount = 2
+	return fanOutPage(context.Background(), b, options, CacheMethodListRoles, func(options CacheOptions) CacheRequest {
+		return RoleListRequest{Options: options}
+	}, func(role *Role) snowflake.ID { return role.RoleID })
+}
+
+func pageRoleIDs(page *Page[*Role]) []snowflake.ID {
+	ids := make([]snowflake.ID, len(page.Items))
+	for i, role := range page.Items {
+		ids[i] = role.RoleID
+	}
+	return ids
+}
+
+func TestFanOutPageOffsetAndLimit(t *testing.T) {
+	b := newFakePartitionBroker()
+
+	page, err := listRoles(b, CacheOptions{Limit: 3, Offset: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := pageRoleIDs(page), []snowflake.ID{3, 4, 5}; !slices.Equal(got, want) {
+		t.Errorf("got roles %v, want %v", got, want)
+	}
+	if page.NextCursor == nil || *page.NextCursor != 5 {
+		t.Errorf("got cursor %v, want 5", page.NextCursor)
+	}
+
+	// The offset is applied to the merged roles, so every partition has to return the roles before it
+	for _, options := range b.requests {
+		if options.Offset != 0 || options.Limit != 5 {
+			t.Errorf("got partition request with offset %d and limit %d, want 0 and 5", options.Offset, options.Limit)
+		}
+	}
+}
+
+func TestFanOutPageCursor(t *testing.T) {
+	b := newFakePartitionBroker()
+
+	var pages [][]snowflake.ID
+	var after snowflake.ID
+	for {
+		page, err := listRoles(b, CacheOptions{Limit: 4, After: after})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, pageRoleIDs(page))
+		if page.NextCursor == nil {
+			break
+		}
+		after = *page.NextCursor
+	}
+
+	want := [][]snowflake.ID{{1, 2, 3, 4}, {5, 6, 7, 8}, {9}}
+	if !reflect.DeepEqual(pages, want) {
+		t.Errorf("got pages %v, want %v", pages, want)
+	}
+}
+
+func TestFanOutPageWithoutLimit(t *testing.T) {
+	page, err := listRoles(newFakePartitionBroker(), CacheOptions{Offset: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := pageRoleIDs(page), []snowflake.ID{8, 9}; !slices.Equal(got, want) {
+		t.Errorf("got roles %v, want %v", got, want)
+	}
+	if page.NextCursor != nil {
+		t.Errorf("got cursor %v, want none", *page.NextCursor)
+	}
+}
+
+func TestMergeByIndex(t *testing.T) {
+	found := func(s string) bool { return s != "" }
+
+	tests := []struct {
+		name    string
+		batches [][]string
+		want    []string
+	}{
+		{
+			name:    "no partitions",
+			batches: nil,
+			want:    nil,
+		},
+		{
+			name:    "entities are taken from the partition that found them",
+			batches: [][]string{{"", "b", ""}, {"a", "", ""}},
+			want:    []string{"a", "b", ""},
+		},
+		{
+			name:    "the first partition wins",
+			batches: [][]string{{"a"}, {"b"}},
+			want:    []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeByIndex(tt.batches, found); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeStats(t *testing.T) {
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	merged := mergeStats([][]*CacheStats{
+		{
+			{AppID: 2, Guilds: EntityStats{Count: 2, Tainted: 1, OldestUpdatedAt: &newer}, MemoryBytes: 100},
+		},
+		{
+			{AppID: 1, Roles: EntityStats{Count: 3}},
+			{AppID: 2, Guilds: EntityStats{Count: 1, Unavailable: 1, OldestUpdatedAt: &older}, MemoryBytes: 50},
+		},
+	})
+
+	want := []*CacheStats{
+		{AppID: 1, Roles: EntityStats{Count: 3}},
+		{AppID: 2, Guilds: EntityStats{Count: 3, Tainted: 1, Unavailable: 1, OldestUpdatedAt: &older}, MemoryBytes: 150},
+	}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("got %+v, want %+v", merged, want)
+	}
+}
@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/merlinfuchs/stateway/stateway-lib/service"
 )
@@ -32,8 +33,16 @@ func (s *CacheService) HandleRequest(ctx context.Context, method CacheMethod, re
 			req.AbortAtPermissions,
 			req.Options.Destructure()...,
 		)
+	case GuildGetFullRequest:
+		return s.caches.GetFullGuild(ctx, req.GuildID, req.Include, req.Options.Destructure()...)
+	case GuildWaitRequest:
+		return s.caches.WaitForGuild(ctx, req.GuildID, time.Duration(req.TimeoutMS)*time.Millisecond, req.Options.Destructure()...)
+	case GuildAppsRequest:
+		return s.caches.GetGuildApps(ctx, req.GuildID, req.Options.Destructure()...)
 	case GuildListRequest:
 		return s.caches.GetGuilds(ctx, req.Options.Destructure()...)
+	case GuildBatchGetRequest:
+		return s.caches.BatchGetGuilds(ctx, req.GuildIDs, req.Options.Destructure()...)
 	case GuildCheckExistRequest:
 		return s.caches.CheckGuildsExist(ctx, req.GuildIDs, req.Options.Destructure()...)
 	case GuildSearchRequest:
@@ -50,6 +59,12 @@ func (s *CacheService) HandleRequest(ctx context.Context, method CacheMethod, re
 		} else {
 			return s.caches.GetGuildChannels(ctx, *req.GuildID, req.Options.Destructure()...)
 		}
+	case ChannelBatchGetRequest:
+		if req.GuildID == nil {
+			return s.caches.BatchGetChannels(ctx, req.ChannelIDs, req.Options.Destructure()...)
+		} else {
+			return s.caches.BatchGetGuildChannels(ctx, *req.GuildID, req.ChannelIDs, req.Options.Destructure()...)
+		}
 	case ChannelListWithPermissionsRequest:
 		return s.caches.GetGuildChannelsWithPermissions(ctx, req.GuildID, req.UserID, req.RoleIDs, req.Options.Destructure()...)
 	case ChannelSearchRequest:
@@ -70,6 +85,12 @@ func (s *CacheService) HandleRequest(ctx context.Context, method CacheMethod, re
 		} else {
 			return s.caches.GetGuildRoles(ctx, *req.GuildID, req.Options.Destructure()...)
 		}
+	case RoleBatchGetRequest:
+		if req.GuildID == nil {
+			return s.caches.BatchGetRoles(ctx, req.RoleIDs, req.Options.Destructure()...)
+		} else {
+			return s.caches.BatchGetGuildRoles(ctx, *req.GuildID, req.RoleIDs, req.Options.Destructure()...)
+		}
 	case RoleSearchRequest:
 		if req.GuildID == nil {
 			return s.caches.SearchRoles(ctx, req.Data, req.Options.Destructure()...)
@@ -82,10 +103,33 @@ func (s *CacheService) HandleRequest(ctx context.Context, method CacheMethod, re
 		} else if req.GuildID != nil {
 			return s.caches.ComputeGuildPermissions(ctx, *req.GuildID, req.UserID, req.RoleIDs, req.Options.Destructure()...)
 		}
+	case PermissionsHierarchyCheckRequest:
+		return s.caches.CheckMemberHierarchy(
+			ctx,
+			req.GuildID,
+			req.ActorUserID,
+			req.ActorRoleIDs,
+			req.TargetUserID,
+			req.TargetRoleIDs,
+			req.RequiredPermissions,
+			req.Options.Destructure()...,
+		)
+	case PermissionsCanManageRoleRequest:
+		return s.caches.CanManageRole(ctx, req.GuildID, req.UserID, req.RoleIDs, req.RoleID, req.Options.Destructure()...)
 	case EmojiListRequest:
 		return s.caches.GetGuildEmojis(ctx, req.GuildID, req.Options.Destructure()...)
+	case EmojiBatchGetRequest:
+		return s.caches.BatchGetEmojis(ctx, req.EmojiIDs, req.Options.Destructure()...)
 	case StickerListRequest:
 		return s.caches.GetGuildStickers(ctx, req.GuildID, req.Options.Destructure()...)
+	case StickerBatchGetRequest:
+		return s.caches.BatchGetStickers(ctx, req.StickerIDs, req.Options.Destructure()...)
+	case StatsGetRequest:
+		return s.caches.GetStats(ctx, req.Options.Destructure()...)
+	case CacheResyncRequest:
+		return s.caches.Resync(ctx, req.GuildID, req.Options.Destructure()...)
+	case CachePurgeAppRequest:
+		return s.caches.PurgeApp(ctx, req.Options.Destructure()...)
 	}
 	return nil, fmt.Errorf("unknown request type: %T", request)
 }
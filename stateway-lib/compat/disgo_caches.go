@@ -35,7 +35,7 @@ func (c *DisgoCaches) MemberPermissions(member discord.Member) discord.Permissio
 	ctx, cancel := cacheCtx(c.ctx)
 	defer cancel()
 
-	permissions, err := c.cache.ComputeGuildPermissions(ctx, member.GuildID, member.User.ID, member.RoleIDs)
+	permissions, err := c.cache.ComputeGuildPermissions(ctx, member.GuildID, member.User.ID, member.RoleIDs, memberOptions(member)...)
 	if err != nil {
 		slog.Error(
 			"Failed to compute guild permissions",
@@ -52,7 +52,7 @@ func (c *DisgoCaches) MemberPermissionsInChannel(channel discord.GuildChannel, m
 	ctx, cancel := cacheCtx(c.ctx)
 	defer cancel()
 
-	permissions, err := c.cache.ComputeChannelPermissions(ctx, channel.ID(), member.User.ID, member.RoleIDs)
+	permissions, err := c.cache.ComputeChannelPermissions(ctx, channel.ID(), member.User.ID, member.RoleIDs, memberOptions(member)...)
 	if err != nil {
 		slog.Error(
 			"Failed to compute channel permissions",
@@ -66,6 +66,14 @@ func (c *DisgoCaches) MemberPermissionsInChannel(channel discord.GuildChannel, m
 	return permissions
 }
 
+// memberOptions passes the timeout of the member to permission computations.
+func memberOptions(member discord.Member) []cache.CacheOption {
+	if member.CommunicationDisabledUntil == nil {
+		return nil
+	}
+	return []cache.CacheOption{cache.WithCommunicationDisabledUntil(*member.CommunicationDisabledUntil)}
+}
+
 type GuildCache struct {
 	ctx   context.Context
 	cache cache.GuildCache
@@ -121,33 +129,26 @@ func (c *GuildCache) Guild(guildID snowflake.ID) (discord.Guild, bool) {
 }
 
 func (c *GuildCache) Guilds() iter.Seq[discord.Guild] {
-	var offset int
-
 	return func(fn func(discord.Guild) bool) {
-		for {
+		guilds := cache.IterPages(0, func(after snowflake.ID) (*cache.Page[*cache.Guild], error) {
 			ctx, cancel := cacheCtx(c.ctx)
+			defer cancel()
 
-			guilds, err := c.cache.GetGuilds(ctx, cache.WithLimit(100), cache.WithOffset(offset))
+			return c.cache.GetGuilds(ctx, cache.WithLimit(cache.DefaultPageSize), cache.WithAfter(after))
+		})
+
+		for guild, err := range guilds {
 			if err != nil {
 				slog.Error(
 					"Failed to get guilds from cache",
 					slog.Any("error", err),
 				)
+				return
 			}
 
-			cancel()
-
-			for _, guild := range guilds {
-				if !fn(guild.Data) {
-					return
-				}
-			}
-
-			if len(guilds) < 100 {
-				break
+			if !fn(guild.Data) {
+				return
 			}
-
-			offset += 100
 		}
 	}
 }
@@ -202,38 +203,31 @@ func (c *ChannelCache) Channel(channelID snowflake.ID) (discord.GuildChannel, bo
 }
 
 func (c *ChannelCache) Channels() iter.Seq[discord.GuildChannel] {
-	var offset int
-
 	return func(fn func(discord.GuildChannel) bool) {
-		for {
+		channels := cache.IterPages(0, func(after snowflake.ID) (*cache.Page[*cache.Channel], error) {
 			ctx, cancel := cacheCtx(c.ctx)
+			defer cancel()
+
+			return c.cache.GetChannels(ctx, cache.WithLimit(cache.DefaultPageSize), cache.WithAfter(after))
+		})
 
-			channels, err := c.cache.GetChannels(ctx, cache.WithLimit(100), cache.WithOffset(offset))
+		for channel, err := range channels {
 			if err != nil {
 				slog.Error(
 					"Failed to get channels from cache",
 					slog.Any("error", err),
 				)
+				return
 			}
 
-			cancel()
-
-			for _, channel := range channels {
-				guildChannel, ok := channel.Data.(discord.GuildChannel)
-				if !ok {
-					continue
-				}
-
-				if !fn(guildChannel) {
-					return
-				}
+			guildChannel, ok := channel.Data.(discord.GuildChannel)
+			if !ok {
+				continue
 			}
 
-			if len(channels) < 100 {
-				break
+			if !fn(guildChannel) {
+				return
 			}
-
-			offset += 100
 		}
 	}
 }
@@ -242,15 +236,18 @@ func (c *ChannelCache) ChannelsForGuild(guildID snowflake.ID) iter.Seq[discord.G
 	ctx, cancel := cacheCtx(c.ctx)
 	defer cancel()
 
-	channels, err := c.cache.GetGuildChannels(ctx, guildID)
+	page, err := c.cache.GetGuildChannels(ctx, guildID)
 	if err != nil {
 		slog.Error(
 			"Failed to get channels from cache",
 			slog.String("guild_id", guildID.String()),
 			slog.Any("error", err),
 		)
+		return func(fn func(discord.GuildChannel) bool) {}
 	}
 
+	channels := page.Items
+
 	return func(fn func(discord.GuildChannel) bool) {
 		for _, channel := range channels {
 			guildChannel, ok := channel.Data.(discord.GuildChannel)
@@ -329,15 +326,18 @@ func (c *RoleCache) Roles(guildID snowflake.ID) iter.Seq[discord.Role] {
 	ctx, cancel := cacheCtx(c.ctx)
 	defer cancel()
 
-	roles, err := c.cache.GetGuildRoles(ctx, guildID)
+	page, err := c.cache.GetGuildRoles(ctx, guildID)
 	if err != nil {
 		slog.Error(
 			"Failed to get roles from cache",
 			slog.String("guild_id", guildID.String()),
 			slog.Any("error", err),
 		)
+		return func(fn func(discord.Role) bool) {}
 	}
 
+	roles := page.Items
+
 	return func(fn func(discord.Role) bool) {
 		for _, role := range roles {
 			if !fn(role.Data) {
@@ -419,15 +419,18 @@ func (c *EmojiCache) Emojis(guildID snowflake.ID) iter.Seq[discord.Emoji] {
 	ctx, cancel := cacheCtx(c.ctx)
 	defer cancel()
 
-	emojis, err := c.cache.GetGuildEmojis(ctx, guildID)
+	page, err := c.cache.GetGuildEmojis(ctx, guildID)
 	if err != nil {
 		slog.Error(
 			"Failed to get guild emojis from cache",
 			slog.String("guild_id", guildID.String()),
 			slog.Any("error", err),
 		)
+		return func(fn func(discord.Emoji) bool) {}
 	}
 
+	emojis := page.Items
+
 	return func(fn func(discord.Emoji) bool) {
 		for _, emoji := range emojis {
 			if !fn(emoji.Data) {
@@ -474,15 +477,18 @@ func (c *StickerCache) Stickers(guildID snowflake.ID) iter.Seq[discord.Sticker]
 	ctx, cancel := cacheCtx(c.ctx)
 	defer cancel()
 
-	stickers, err := c.cache.GetGuildStickers(ctx, guildID)
+	page, err := c.cache.GetGuildStickers(ctx, guildID)
 	if err != nil {
 		slog.Error(
 			"Failed to get guild stickers from cache",
 			slog.String("guild_id", guildID.String()),
 			slog.Any("error", err),
 		)
+		return func(fn func(discord.Sticker) bool) {}
 	}
 
+	stickers := page.Items
+
 	return func(fn func(discord.Sticker) bool) {
 		for _, sticker := range stickers {
 			if !fn(sticker.Data) {
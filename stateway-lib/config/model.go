@@ -47,6 +47,7 @@ type AuditConfig struct {
 type DatabaseConfig struct {
 	Postgres   PostgresConfig   `toml:"postgres"`
 	Clickhouse ClickhouseConfig `toml:"clickhouse"`
+	Redis      RedisConfig      `toml:"redis"`
 }
 
 type LoggingConfig struct {
@@ -73,6 +74,14 @@ type ClickhouseConfig struct {
 	Password string `toml:"password"`
 }
 
+type RedisConfig struct {
+	Address   string `toml:"address"`
+	Username  string `toml:"username"`
+	Password  string `toml:"password"`
+	DB        int    `toml:"db"`
+	KeyPrefix string `toml:"key_prefix"`
+}
+
 type BrokerConfig struct {
 	NATS          NATSConfig `toml:"nats"`
 	NamePrefix    string     `toml:"name_prefix"`
@@ -96,6 +105,8 @@ type GatewayGroupConfig struct {
 	DisplayName string `toml:"display_name" validate:"required"`
 	MaxShards   int    `toml:"max_shards"`
 	MaxGuilds   int    `toml:"max_guilds"`
+	// Cache is the default cache config of the apps in the group.
+	Cache *GatewayCacheConfig `toml:"cache"`
 }
 
 type GatewayAppConfig struct {
@@ -105,6 +116,7 @@ type GatewayAppConfig struct {
 	GroupID          string                    `toml:"group_id"`
 	Intents          int64                     `toml:"intents"`
 	Presence         *GatewayAppPresenceConfig `toml:"presence"`
+	Cache            *GatewayCacheConfig       `toml:"cache"`
 }
 
 type GatewayAppPresenceConfig struct {
@@ -119,7 +131,41 @@ type GatewayAppPresenceActivityConfig struct {
 	URL   string `toml:"url"`
 }
 
+// GatewayCacheConfig controls which entities of an app the cache service stores.
+type GatewayCacheConfig struct {
+	// Entities are the entity types that are cached besides guilds, any of "channel", "role", "emoji" or "sticker".
+	// Leave unset to cache all entity types.
+	Entities []string `toml:"entities"`
+	// Threads is whether threads are cached together with the other channels, defaults to true.
+	Threads *bool `toml:"threads"`
+	// StripFields are the top-level fields that are removed from the data of each entity type before it's stored.
+	StripFields map[string][]string `toml:"strip_fields"`
+}
+
 type CacheConfig struct {
-	InMemory   bool  `toml:"in_memory"`
-	GatewayIDs []int `toml:"gateway_ids"`
+	// Store is the store to keep cached entities in, one of "postgres", "redis", "bolt", "map" or "memdb".
+	Store string `toml:"store" validate:"omitempty,oneof=postgres redis bolt map memdb"`
+	// BoltPath is the database file of the embedded store.
+	BoltPath string `toml:"bolt_path"`
+	// InMemory is the same as setting Store to "map".
+	//
+	// Deprecated: Use Store instead.
+	InMemory bool `toml:"in_memory"`
+	// SnapshotPath is the file the in-memory stores are periodically written to and restored from on startup.
+	// Leave empty to disable snapshots.
+	SnapshotPath string `toml:"snapshot_path"`
+	// SnapshotInterval is the number of seconds between two snapshots.
+	SnapshotInterval int   `toml:"snapshot_interval"`
+	GatewayIDs       []int `toml:"gateway_ids"`
+	// PartitionCount splits the guilds by their ID across multiple cache instances, each instance only caches
+	// and serves the guilds of its partition. Clients have to use the same count. Zero or one disables partitioning.
+	PartitionCount int `toml:"partition_count" validate:"gte=0"`
+	// PartitionID is the partition of this instance, from 0 to PartitionCount - 1.
+	PartitionID int `toml:"partition_id" validate:"gte=0"`
+	// TaintedGracePeriod is the number of seconds to wait for the guilds of a shard after READY
+	// before deleting entities that are still tainted. Zero disables the time based sweep.
+	TaintedGracePeriod int `toml:"tainted_grace_period"`
+	// WorkerConcurrency is the number of events that are handled at once per gateway, events are partitioned by
+	// their guild so the events of a guild stay in order. Zero or one handles the events one after another.
+	WorkerConcurrency int `toml:"worker_concurrency" validate:"gte=0"`
 }
@@ -14,6 +14,13 @@ type Event interface {
 	EventType() string
 }
 
+// Event types that the gateway publishes about the apps themselves, they don't come from Discord.
+// Services that keep data per app purge it when they receive them.
+const (
+	GatewayEventTypeAppDeleted  = "APP_DELETED"
+	GatewayEventTypeAppDisabled = "APP_DISABLED"
+)
+
 type GatewayEvent struct {
 	ID        snowflake.ID    `json:"id"`
 	GatewayID int             `json:"gateway_index"`
@@ -41,6 +48,16 @@ type EventHandler interface {
 	HandleEvent(event Event)
 }
 
+// eventFactories holds constructors for events that are defined outside of this package.
+// They can't be referenced here directly without creating an import cycle.
+var eventFactories = map[service.ServiceType]func() Event{}
+
+// RegisterEventType makes UnmarshalEvent aware of the events published by the given service.
+// It's meant to be called from an init function of the package that defines the event.
+func RegisterEventType(serviceType service.ServiceType, newEvent func() Event) {
+	eventFactories[serviceType] = newEvent
+}
+
 type unmarshalEvent struct {
 	EventID     snowflake.ID        `json:"event_id"`
 	ServiceType service.ServiceType `json:"service_type"`
@@ -65,6 +82,15 @@ func UnmarshalEvent(data []byte) (Event, error) {
 		return &gatewayEvent, nil
 	}
 
+	if newEvent, ok := eventFactories[event.ServiceType]; ok {
+		e := newEvent()
+		err := json.Unmarshal(event.Data, e)
+		if err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+
 	return nil, fmt.Errorf("unknown service type: %s", event.ServiceType)
 }
 
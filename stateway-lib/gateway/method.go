@@ -115,6 +115,7 @@ func (r UpsertAppRequest) Validate() error {
 		validation.Field(&r.DiscordClientID, validation.Required),
 		validation.Field(&r.DiscordBotToken, validation.Required),
 		validation.Field(&r.ShardCount, validation.Required, validation.Min(1)),
+		validation.Field(&r.Config),
 	)
 }
 
@@ -172,6 +173,7 @@ func (r UpsertGroupRequest) Validate() error {
 	return validation.ValidateStruct(&r,
 		validation.Field(&r.ID, validation.Required),
 		validation.Field(&r.DisplayName, validation.Required),
+		validation.Field(&r.DefaultConfig),
 	)
 }
 
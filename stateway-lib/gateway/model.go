@@ -1,9 +1,13 @@
 package gateway
 
 import (
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/disgoorg/snowflake/v2"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"gopkg.in/guregu/null.v4"
 )
 
@@ -54,6 +58,7 @@ type AppConfig struct {
 	ShardConcurrency null.Int           `json:"shard_concurrency,omitzero"`
 	Intents          null.Int           `json:"intents,omitzero"`
 	Presence         *AppPresenceConfig `json:"presence,omitempty"`
+	Cache            *AppCacheConfig    `json:"cache,omitempty"`
 }
 
 func (a AppConfig) Merge(other AppConfig) AppConfig {
@@ -63,9 +68,23 @@ func (a AppConfig) Merge(other AppConfig) AppConfig {
 	if other.Presence != nil {
 		a.Presence = other.Presence
 	}
+	if other.Cache != nil {
+		var cache AppCacheConfig
+		if a.Cache != nil {
+			cache = *a.Cache
+		}
+		cache = cache.Merge(*other.Cache)
+		a.Cache = &cache
+	}
 	return a
 }
 
+func (a AppConfig) Validate() error {
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.Cache),
+	)
+}
+
 type AppPresenceConfig struct {
 	Status   null.String                `json:"status"`
 	Activity *AppPresenceActivityConfig `json:"activity,omitempty"`
@@ -86,3 +105,84 @@ type Group struct {
 	CreatedAt          time.Time      `json:"created_at"`
 	UpdatedAt          time.Time      `json:"updated_at"`
 }
+
+type AppCacheEntity string
+
+const (
+	AppCacheEntityGuild   AppCacheEntity = "guild"
+	AppCacheEntityChannel AppCacheEntity = "channel"
+	AppCacheEntityRole    AppCacheEntity = "role"
+	AppCacheEntityEmoji   AppCacheEntity = "emoji"
+	AppCacheEntitySticker AppCacheEntity = "sticker"
+)
+
+// AppCacheConfig controls which entities of the app the cache service stores.
+type AppCacheConfig struct {
+	// Entities are the entity types that are cached besides guilds, which are always cached.
+	// Nil caches all entity types, an empty list only caches guilds.
+	Entities []AppCacheEntity `json:"entities"`
+	// Threads is whether threads are cached together with the other channels, defaults to true.
+	Threads null.Bool `json:"threads,omitzero"`
+	// StripFields are the top-level fields that are removed from the data of the entity types before it's stored.
+	StripFields map[AppCacheEntity][]string `json:"strip_fields,omitempty"`
+}
+
+func (c AppCacheConfig) Merge(other AppCacheConfig) AppCacheConfig {
+	if other.Entities != nil {
+		c.Entities = other.Entities
+	}
+	if other.Threads.Valid {
+		c.Threads = other.Threads
+	}
+	if other.StripFields != nil {
+		c.StripFields = other.StripFields
+	}
+	return c
+}
+
+func (c AppCacheConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Entities, validation.Each(validation.In(
+			AppCacheEntityChannel,
+			AppCacheEntityRole,
+			AppCacheEntityEmoji,
+			AppCacheEntitySticker,
+		))),
+		validation.Field(&c.StripFields, validation.By(validateStripFields)),
+	)
+}
+
+// strippableFields are the fields that can be stripped from the data of each entity type.
+// The stripped data is decoded into the typed entity again, so only nullable fields are allowed, which are then stored as null.
+// Fields that aren't nullable would be stored with a zero value, e.g. a role without permissions.
+var strippableFields = map[AppCacheEntity][]string{
+	AppCacheEntityGuild: {
+		"icon", "splash", "discovery_splash", "banner", "description",
+		"vanity_url_code", "max_presences", "incidents_data",
+	},
+	AppCacheEntityChannel: {
+		"topic", "last_message_id", "last_pin_timestamp",
+		"available_tags", "applied_tags", "default_reaction_emoji", "default_sort_order",
+	},
+	AppCacheEntityRole:    {"description", "icon", "unicode_emoji"},
+	AppCacheEntityEmoji:   {"user"},
+	AppCacheEntitySticker: {"user"},
+}
+
+// validateStripFields makes sure that only nullable fields are stripped, see strippableFields.
+func validateStripFields(value any) error {
+	stripFields, _ := value.(map[AppCacheEntity][]string)
+	for entity, fields := range stripFields {
+		allowed, ok := strippableFields[entity]
+		if !ok {
+			return fmt.Errorf("unknown entity type %q", entity)
+		}
+
+		for _, field := range fields {
+			if !slices.Contains(allowed, field) {
+				return fmt.Errorf("field %q of %s can't be stripped, only %s can", field, entity, strings.Join(allowed, ", "))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package permissions
+
+import (
+	"cmp"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// HierarchyReason explains a HierarchyVerdict.
+type HierarchyReason string
+
+const (
+	// HierarchyReasonActorIsOwner allows the guild owner to act on everyone else.
+	HierarchyReasonActorIsOwner HierarchyReason = "actor_is_owner"
+	// HierarchyReasonActorHigher allows the action because the highest role of the actor is above the target.
+	HierarchyReasonActorHigher HierarchyReason = "actor_higher"
+	// HierarchyReasonSameMember denies actions of members on themselves.
+	HierarchyReasonSameMember HierarchyReason = "same_member"
+	// HierarchyReasonTargetIsOwner denies actions on the guild owner.
+	HierarchyReasonTargetIsOwner HierarchyReason = "target_is_owner"
+	// HierarchyReasonMissingPermissions denies the action because the actor lacks the required permissions.
+	HierarchyReasonMissingPermissions HierarchyReason = "missing_permissions"
+	// HierarchyReasonTargetNotLower denies the action because the target is at or above the highest role of the actor.
+	HierarchyReasonTargetNotLower HierarchyReason = "target_not_lower"
+	// HierarchyReasonEveryoneRole denies assigning the @everyone role, which every member has implicitly.
+	HierarchyReasonEveryoneRole HierarchyReason = "everyone_role"
+	// HierarchyReasonManagedRole denies assigning roles that are managed by an integration.
+	HierarchyReasonManagedRole HierarchyReason = "managed_role"
+)
+
+// HierarchyVerdict is the result of a hierarchy check.
+type HierarchyVerdict struct {
+	Allowed bool            `json:"allowed"`
+	Reason  HierarchyReason `json:"reason"`
+	// ActorHighestRoleID is the highest role of the actor, 0 if the actor only has the @everyone role.
+	ActorHighestRoleID snowflake.ID `json:"actor_highest_role_id,omitempty"`
+	// TargetHighestRoleID is the highest role of the target member or the target role itself.
+	TargetHighestRoleID snowflake.ID `json:"target_highest_role_id,omitempty"`
+}
+
+// Role holds the state of a role that the hierarchy depends on.
+type Role struct {
+	ID       snowflake.ID
+	Position int
+	Managed  bool
+}
+
+// CompareRoles orders roles the same way Discord does.
+// Roles are ordered by position and roles with the same position by ID, where the older role is higher.
+func CompareRoles(a Role, b Role) int {
+	if a.Position != b.Position {
+		return cmp.Compare(a.Position, b.Position)
+	}
+	return cmp.Compare(b.ID, a.ID)
+}
+
+// HighestRole returns the highest of the roles, false if there are none.
+func HighestRole(roles []Role) (Role, bool) {
+	if len(roles) == 0 {
+		return Role{}, false
+	}
+
+	highest := roles[0]
+	for _, role := range roles[1:] {
+		if CompareRoles(role, highest) > 0 {
+			highest = role
+		}
+	}
+	return highest, true
+}
+
+// HierarchyMember holds the state of a member that the hierarchy depends on.
+type HierarchyMember struct {
+	UserID snowflake.ID
+	// Roles are the roles of the member, the @everyone role can be omitted.
+	Roles []Role
+}
+
+// CheckMemberHierarchy reports whether the actor can act on the target member, e.g. to kick, ban or time them out.
+// actorPermissions are the guild permissions of the actor, which must include the required permissions.
+func CheckMemberHierarchy(
+	ownerID snowflake.ID,
+	actor HierarchyMember,
+	actorPermissions discord.Permissions,
+	target HierarchyMember,
+	required discord.Permissions,
+) HierarchyVerdict {
+	actorHighest, actorHasRoles := HighestRole(actor.Roles)
+	targetHighest, targetHasRoles := HighestRole(target.Roles)
+	verdict := HierarchyVerdict{
+		ActorHighestRoleID:  actorHighest.ID,
+		TargetHighestRoleID: targetHighest.ID,
+	}
+
+	switch {
+	case actor.UserID == target.UserID:
+		verdict.Reason = HierarchyReasonSameMember
+	case actor.UserID == ownerID:
+		verdict.Allowed = true
+		verdict.Reason = HierarchyReasonActorIsOwner
+	case target.UserID == ownerID:
+		verdict.Reason = HierarchyReasonTargetIsOwner
+	case !actorPermissions.Has(required):
+		verdict.Reason = HierarchyReasonMissingPermissions
+	case actorHasRoles && (!targetHasRoles || CompareRoles(actorHighest, targetHighest) > 0):
+		verdict.Allowed = true
+		verdict.Reason = HierarchyReasonActorHigher
+	default:
+		verdict.Reason = HierarchyReasonTargetNotLower
+	}
+	return verdict
+}
+
+// CheckRoleHierarchy reports whether the actor can assign the role to members and remove it from them.
+// actorPermissions are the guild permissions of the actor, which must include MANAGE_ROLES.
+func CheckRoleHierarchy(
+	guildID snowflake.ID,
+	ownerID snowflake.ID,
+	actor HierarchyMember,
+	actorPermissions discord.Permissions,
+	role Role,
+) HierarchyVerdict {
+	actorHighest, actorHasRoles := HighestRole(actor.Roles)
+	verdict := HierarchyVerdict{
+		ActorHighestRoleID:  actorHighest.ID,
+		TargetHighestRoleID: role.ID,
+	}
+
+	switch {
+	case role.ID == guildID:
+		verdict.Reason = HierarchyReasonEveryoneRole
+	case role.Managed:
+		verdict.Reason = HierarchyReasonManagedRole
+	case actor.UserID == ownerID:
+		verdict.Allowed = true
+		verdict.Reason = HierarchyReasonActorIsOwner
+	case !actorPermissions.Has(discord.PermissionManageRoles):
+		verdict.Reason = HierarchyReasonMissingPermissions
+	case actorHasRoles && CompareRoles(actorHighest, role) > 0:
+		verdict.Allowed = true
+		verdict.Reason = HierarchyReasonActorHigher
+	default:
+		verdict.Reason = HierarchyReasonTargetNotLower
+	}
+	return verdict
+}
@@ -0,0 +1,182 @@
+package permissions
+
+import (
+	"testing"
+
+	"github.com/disgoorg/disgo/discord"
+)
+
+var (
+	testLowRole     = Role{ID: 20, Position: 1}
+	testHighRole    = Role{ID: 21, Position: 5}
+	testOldHighRole = Role{ID: 19, Position: 5}
+	testManagedRole = Role{ID: 22, Position: 2, Managed: true}
+)
+
+func TestCompareRoles(t *testing.T) {
+	tests := []struct {
+		name string
+		a    Role
+		b    Role
+		want int
+	}{
+		{name: "higher position", a: testHighRole, b: testLowRole, want: 1},
+		{name: "lower position", a: testLowRole, b: testHighRole, want: -1},
+		{name: "same position older role is higher", a: testOldHighRole, b: testHighRole, want: 1},
+		{name: "same role", a: testHighRole, b: testHighRole, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CompareRoles(tt.a, tt.b); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckMemberHierarchy(t *testing.T) {
+	tests := []struct {
+		name             string
+		actor            HierarchyMember
+		actorPermissions discord.Permissions
+		target           HierarchyMember
+		required         discord.Permissions
+		want             HierarchyVerdict
+	}{
+		{
+			name:             "higher role",
+			actor:            HierarchyMember{UserID: testUserID, Roles: []Role{testLowRole, testHighRole}},
+			actorPermissions: discord.PermissionKickMembers,
+			target:           HierarchyMember{UserID: 4, Roles: []Role{testLowRole}},
+			required:         discord.PermissionKickMembers,
+			want:             HierarchyVerdict{Allowed: true, Reason: HierarchyReasonActorHigher, ActorHighestRoleID: testHighRole.ID, TargetHighestRoleID: testLowRole.ID},
+		},
+		{
+			name:             "target without roles",
+			actor:            HierarchyMember{UserID: testUserID, Roles: []Role{testLowRole}},
+			actorPermissions: discord.PermissionKickMembers,
+			target:           HierarchyMember{UserID: 4},
+			required:         discord.PermissionKickMembers,
+			want:             HierarchyVerdict{Allowed: true, Reason: HierarchyReasonActorHigher, ActorHighestRoleID: testLowRole.ID},
+		},
+		{
+			name:             "same highest role",
+			actor:            HierarchyMember{UserID: testUserID, Roles: []Role{testHighRole}},
+			actorPermissions: discord.PermissionsAll,
+			target:           HierarchyMember{UserID: 4, Roles: []Role{testHighRole}},
+			want:             HierarchyVerdict{Reason: HierarchyReasonTargetNotLower, ActorHighestRoleID: testHighRole.ID, TargetHighestRoleID: testHighRole.ID},
+		},
+		{
+			name:             "same position but newer role",
+			actor:            HierarchyMember{UserID: testUserID, Roles: []Role{testHighRole}},
+			actorPermissions: discord.PermissionsAll,
+			target:           HierarchyMember{UserID: 4, Roles: []Role{testOldHighRole}},
+			want:             HierarchyVerdict{Reason: HierarchyReasonTargetNotLower, ActorHighestRoleID: testHighRole.ID, TargetHighestRoleID: testOldHighRole.ID},
+		},
+		{
+			name:             "actor without roles",
+			actor:            HierarchyMember{UserID: testUserID},
+			actorPermissions: discord.PermissionsAll,
+			target:           HierarchyMember{UserID: 4},
+			want:             HierarchyVerdict{Reason: HierarchyReasonTargetNotLower},
+		},
+		{
+			name:             "missing permissions",
+			actor:            HierarchyMember{UserID: testUserID, Roles: []Role{testHighRole}},
+			actorPermissions: discord.PermissionKickMembers,
+			target:           HierarchyMember{UserID: 4, Roles: []Role{testLowRole}},
+			required:         discord.PermissionBanMembers,
+			want:             HierarchyVerdict{Reason: HierarchyReasonMissingPermissions, ActorHighestRoleID: testHighRole.ID, TargetHighestRoleID: testLowRole.ID},
+		},
+		{
+			name:     "owner can act on higher members",
+			actor:    HierarchyMember{UserID: testOwnerID},
+			target:   HierarchyMember{UserID: 4, Roles: []Role{testHighRole}},
+			required: discord.PermissionBanMembers,
+			want:     HierarchyVerdict{Allowed: true, Reason: HierarchyReasonActorIsOwner, TargetHighestRoleID: testHighRole.ID},
+		},
+		{
+			name:             "nobody can act on the owner",
+			actor:            HierarchyMember{UserID: testUserID, Roles: []Role{testHighRole}},
+			actorPermissions: discord.PermissionsAll,
+			target:           HierarchyMember{UserID: testOwnerID},
+			want:             HierarchyVerdict{Reason: HierarchyReasonTargetIsOwner, ActorHighestRoleID: testHighRole.ID},
+		},
+		{
+			name:             "members can't act on themselves",
+			actor:            HierarchyMember{UserID: testUserID, Roles: []Role{testHighRole}},
+			actorPermissions: discord.PermissionsAll,
+			target:           HierarchyMember{UserID: testUserID, Roles: []Role{testHighRole}},
+			want:             HierarchyVerdict{Reason: HierarchyReasonSameMember, ActorHighestRoleID: testHighRole.ID, TargetHighestRoleID: testHighRole.ID},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckMemberHierarchy(testOwnerID, tt.actor, tt.actorPermissions, tt.target, tt.required)
+			if got != tt.want {
+				t.Errorf("got verdict %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckRoleHierarchy(t *testing.T) {
+	tests := []struct {
+		name             string
+		actor            HierarchyMember
+		actorPermissions discord.Permissions
+		role             Role
+		want             HierarchyVerdict
+	}{
+		{
+			name:             "lower role",
+			actor:            HierarchyMember{UserID: testUserID, Roles: []Role{testHighRole}},
+			actorPermissions: discord.PermissionManageRoles,
+			role:             testLowRole,
+			want:             HierarchyVerdict{Allowed: true, Reason: HierarchyReasonActorHigher, ActorHighestRoleID: testHighRole.ID, TargetHighestRoleID: testLowRole.ID},
+		},
+		{
+			name:             "own highest role",
+			actor:            HierarchyMember{UserID: testUserID, Roles: []Role{testHighRole}},
+			actorPermissions: discord.PermissionManageRoles,
+			role:             testHighRole,
+			want:             HierarchyVerdict{Reason: HierarchyReasonTargetNotLower, ActorHighestRoleID: testHighRole.ID, TargetHighestRoleID: testHighRole.ID},
+		},
+		{
+			name:             "missing manage roles",
+			actor:            HierarchyMember{UserID: testUserID, Roles: []Role{testHighRole}},
+			actorPermissions: discord.PermissionKickMembers,
+			role:             testLowRole,
+			want:             HierarchyVerdict{Reason: HierarchyReasonMissingPermissions, ActorHighestRoleID: testHighRole.ID, TargetHighestRoleID: testLowRole.ID},
+		},
+		{
+			name:  "owner can manage higher roles",
+			actor: HierarchyMember{UserID: testOwnerID},
+			role:  testHighRole,
+			want:  HierarchyVerdict{Allowed: true, Reason: HierarchyReasonActorIsOwner, TargetHighestRoleID: testHighRole.ID},
+		},
+		{
+			name:  "managed roles can't be assigned",
+			actor: HierarchyMember{UserID: testOwnerID},
+			role:  testManagedRole,
+			want:  HierarchyVerdict{Reason: HierarchyReasonManagedRole, TargetHighestRoleID: testManagedRole.ID},
+		},
+		{
+			name:  "everyone role can't be assigned",
+			actor: HierarchyMember{UserID: testOwnerID},
+			role:  Role{ID: testGuildID},
+			want:  HierarchyVerdict{Reason: HierarchyReasonEveryoneRole, TargetHighestRoleID: testGuildID},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckRoleHierarchy(testGuildID, testOwnerID, tt.actor, tt.actorPermissions, tt.role)
+			if got != tt.want {
+				t.Errorf("got verdict %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
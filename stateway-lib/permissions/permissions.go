@@ -0,0 +1,200 @@
+// Package permissions computes the permissions of guild members the same way Discord does.
+//
+// See https://discord.com/developers/docs/topics/permissions#permission-overwrites for the algorithm.
+// The package has no dependencies on the cache, so it can be used by the cache service and by clients
+// that have the guild state available locally.
+package permissions
+
+import (
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// TimeoutPermissions are the only permissions a member keeps while being timed out.
+const TimeoutPermissions = discord.PermissionViewChannel | discord.PermissionReadMessageHistory
+
+// sendMessagesDependents are implicitly denied when a member can't send messages in a channel.
+const sendMessagesDependents = discord.PermissionMentionEveryone |
+	discord.PermissionSendTTSMessages |
+	discord.PermissionAttachFiles |
+	discord.PermissionEmbedLinks
+
+// Guild holds the state of a guild that permissions depend on.
+type Guild struct {
+	ID      snowflake.ID
+	OwnerID snowflake.ID
+	// EveryonePermissions are the permissions of the @everyone role, which has the same ID as the guild.
+	EveryonePermissions discord.Permissions
+	// RolePermissions are the permissions of the member's roles by role ID.
+	// Roles that are missing, e.g. because they were deleted, grant no permissions.
+	RolePermissions map[snowflake.ID]discord.Permissions
+}
+
+// Member holds the state of a guild member that permissions depend on.
+type Member struct {
+	UserID  snowflake.ID
+	RoleIDs []snowflake.ID
+	// CommunicationDisabledUntil is the end of the member's timeout, nil if the member isn't timed out.
+	CommunicationDisabledUntil *time.Time
+}
+
+// TimedOut reports whether the member's timeout is active at the given time.
+func (m Member) TimedOut(now time.Time) bool {
+	return m.CommunicationDisabledUntil != nil && m.CommunicationDisabledUntil.After(now)
+}
+
+// Channel holds the state of a channel that permissions depend on.
+type Channel struct {
+	// Overwrites are the permission overwrites of the channel.
+	// Threads don't have overwrites of their own and use the ones of their parent channel.
+	Overwrites discord.PermissionOverwrites
+	// Thread makes SEND_MESSAGES_IN_THREADS instead of SEND_MESSAGES required for sending messages.
+	Thread bool
+}
+
+// NewChannel returns the permission state of a guild channel.
+// Threads require their parent channel, for other channels parent is ignored and can be nil.
+func NewChannel(channel discord.GuildChannel, parent discord.GuildChannel) Channel {
+	if !IsThread(channel) {
+		return Channel{Overwrites: channel.PermissionOverwrites()}
+	}
+
+	res := Channel{Thread: true}
+	if parent != nil {
+		res.Overwrites = parent.PermissionOverwrites()
+	}
+	return res
+}
+
+// IsThread reports whether the channel is a thread, which inherits the overwrites of its parent channel.
+func IsThread(channel discord.Channel) bool {
+	switch channel.Type() {
+	case discord.ChannelTypeGuildNewsThread, discord.ChannelTypeGuildPublicThread, discord.ChannelTypeGuildPrivateThread:
+		return true
+	default:
+		return false
+	}
+}
+
+// Calculator computes the permissions of one member in a guild.
+// The base permissions are computed once, so it can be reused for many channels of the guild.
+type Calculator struct {
+	guild  Guild
+	member Member
+	now    time.Time
+	base   discord.Permissions
+}
+
+// NewCalculator creates a calculator for the member at the given time, which is used to check the timeout.
+func NewCalculator(guild Guild, member Member, now time.Time) *Calculator {
+	return &Calculator{
+		guild:  guild,
+		member: member,
+		now:    now,
+		base:   computeBase(guild, member),
+	}
+}
+
+// Guild returns the guild-wide permissions of the member.
+func (c *Calculator) Guild() discord.Permissions {
+	return c.finalize(c.base)
+}
+
+// Channel returns the permissions of the member in the channel.
+func (c *Calculator) Channel(channel Channel) discord.Permissions {
+	if c.base == discord.PermissionsAll {
+		return discord.PermissionsAll
+	}
+
+	permissions := ApplyOverwrites(c.base, c.guild.ID, c.member, channel.Overwrites)
+
+	// Without VIEW_CHANNEL the channel is invisible to the member, so no other permission has any effect.
+	if !permissions.Has(discord.PermissionViewChannel) {
+		return discord.PermissionsNone
+	}
+
+	sendMessages := discord.PermissionSendMessages
+	if channel.Thread {
+		sendMessages = discord.PermissionSendMessagesInThreads
+	}
+	if !permissions.Has(sendMessages) {
+		permissions &^= sendMessagesDependents
+	}
+
+	return c.finalize(permissions)
+}
+
+// finalize applies the timeout of the member.
+// Owners and administrators are never affected by timeouts, their base permissions are always all permissions.
+func (c *Calculator) finalize(permissions discord.Permissions) discord.Permissions {
+	if c.base != discord.PermissionsAll && c.member.TimedOut(c.now) {
+		return permissions & TimeoutPermissions
+	}
+	return permissions
+}
+
+// computeBase returns the guild-wide permissions of the member before timeouts are applied.
+// Owners and administrators have all permissions.
+func computeBase(guild Guild, member Member) discord.Permissions {
+	if member.UserID == guild.OwnerID {
+		return discord.PermissionsAll
+	}
+
+	permissions := guild.EveryonePermissions
+	for _, roleID := range member.RoleIDs {
+		permissions |= guild.RolePermissions[roleID]
+	}
+
+	if permissions.Has(discord.PermissionAdministrator) {
+		return discord.PermissionsAll
+	}
+	return permissions
+}
+
+// ApplyOverwrites applies the channel overwrites to the base permissions in the order Discord does:
+// the @everyone overwrite, then the combined role overwrites and finally the member overwrite.
+// Implicit permissions and timeouts are not applied, use Calculator.Channel for the effective permissions.
+func ApplyOverwrites(
+	base discord.Permissions,
+	guildID snowflake.ID,
+	member Member,
+	overwrites discord.PermissionOverwrites,
+) discord.Permissions {
+	if base.Has(discord.PermissionAdministrator) {
+		return discord.PermissionsAll
+	}
+
+	permissions := base
+	if overwrite, ok := overwrites.Role(guildID); ok {
+		permissions &= ^overwrite.Deny
+		permissions |= overwrite.Allow
+	}
+
+	var (
+		allow discord.Permissions
+		deny  discord.Permissions
+	)
+
+	for _, roleID := range member.RoleIDs {
+		if roleID == guildID {
+			continue
+		}
+
+		if overwrite, ok := overwrites.Role(roleID); ok {
+			allow |= overwrite.Allow
+			deny |= overwrite.Deny
+		}
+	}
+
+	permissions &= ^deny
+	permissions |= allow
+
+	if overwrite, ok := overwrites.Member(member.UserID); ok {
+		permissions &= ^overwrite.Deny
+		permissions |= overwrite.Allow
+	}
+
+	return permissions
+}
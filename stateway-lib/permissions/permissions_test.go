@@ -0,0 +1,270 @@
+package permissions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+const (
+	testGuildID snowflake.ID = 1
+	testOwnerID snowflake.ID = 2
+	testUserID  snowflake.ID = 3
+	testRoleA   snowflake.ID = 10
+	testRoleB   snowflake.ID = 11
+	testAdmin   snowflake.ID = 12
+)
+
+var testNow = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const textPermissions = discord.PermissionViewChannel |
+	discord.PermissionSendMessages |
+	discord.PermissionReadMessageHistory |
+	discord.PermissionAttachFiles |
+	discord.PermissionEmbedLinks
+
+func testGuild(everyone discord.Permissions) Guild {
+	return Guild{
+		ID:                  testGuildID,
+		OwnerID:             testOwnerID,
+		EveryonePermissions: everyone,
+		RolePermissions: map[snowflake.ID]discord.Permissions{
+			testRoleA: discord.PermissionManageMessages,
+			testRoleB: discord.PermissionKickMembers,
+			testAdmin: discord.PermissionAdministrator,
+		},
+	}
+}
+
+func timeoutUntil(d time.Duration) *time.Time {
+	t := testNow.Add(d)
+	return &t
+}
+
+func TestGuildPermissions(t *testing.T) {
+	tests := []struct {
+		name     string
+		everyone discord.Permissions
+		member   Member
+		want     discord.Permissions
+	}{
+		{
+			name:     "everyone only",
+			everyone: textPermissions,
+			member:   Member{UserID: testUserID},
+			want:     textPermissions,
+		},
+		{
+			name:     "roles are combined with everyone",
+			everyone: discord.PermissionViewChannel,
+			member:   Member{UserID: testUserID, RoleIDs: []snowflake.ID{testRoleA, testRoleB}},
+			want:     discord.PermissionViewChannel | discord.PermissionManageMessages | discord.PermissionKickMembers,
+		},
+		{
+			name:     "unknown roles grant nothing",
+			everyone: discord.PermissionViewChannel,
+			member:   Member{UserID: testUserID, RoleIDs: []snowflake.ID{99}},
+			want:     discord.PermissionViewChannel,
+		},
+		{
+			name:     "owner has all permissions",
+			everyone: discord.PermissionsNone,
+			member:   Member{UserID: testOwnerID},
+			want:     discord.PermissionsAll,
+		},
+		{
+			name:     "administrator has all permissions",
+			everyone: discord.PermissionsNone,
+			member:   Member{UserID: testUserID, RoleIDs: []snowflake.ID{testAdmin}},
+			want:     discord.PermissionsAll,
+		},
+		{
+			name:     "timed out member keeps view and history",
+			everyone: textPermissions,
+			member:   Member{UserID: testUserID, RoleIDs: []snowflake.ID{testRoleA}, CommunicationDisabledUntil: timeoutUntil(time.Hour)},
+			want:     discord.PermissionViewChannel | discord.PermissionReadMessageHistory,
+		},
+		{
+			name:     "expired timeout has no effect",
+			everyone: textPermissions,
+			member:   Member{UserID: testUserID, CommunicationDisabledUntil: timeoutUntil(-time.Hour)},
+			want:     textPermissions,
+		},
+		{
+			name:     "timeout doesn't affect administrators",
+			everyone: textPermissions,
+			member:   Member{UserID: testUserID, RoleIDs: []snowflake.ID{testAdmin}, CommunicationDisabledUntil: timeoutUntil(time.Hour)},
+			want:     discord.PermissionsAll,
+		},
+		{
+			name:     "timeout doesn't affect the owner",
+			everyone: textPermissions,
+			member:   Member{UserID: testOwnerID, CommunicationDisabledUntil: timeoutUntil(time.Hour)},
+			want:     discord.PermissionsAll,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewCalculator(testGuild(tt.everyone), tt.member, testNow).Guild()
+			if got != tt.want {
+				t.Errorf("got permissions %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChannelPermissions(t *testing.T) {
+	tests := []struct {
+		name     string
+		everyone discord.Permissions
+		member   Member
+		channel  Channel
+		want     discord.Permissions
+	}{
+		{
+			name:     "no overwrites",
+			everyone: textPermissions,
+			member:   Member{UserID: testUserID},
+			channel:  Channel{},
+			want:     textPermissions,
+		},
+		{
+			name:     "everyone overwrite",
+			everyone: textPermissions,
+			member:   Member{UserID: testUserID},
+			channel: Channel{Overwrites: discord.PermissionOverwrites{
+				discord.RolePermissionOverwrite{RoleID: testGuildID, Deny: discord.PermissionEmbedLinks, Allow: discord.PermissionAddReactions},
+			}},
+			want: textPermissions&^discord.PermissionEmbedLinks | discord.PermissionAddReactions,
+		},
+		{
+			name:     "role allow overrides everyone deny",
+			everyone: textPermissions,
+			member:   Member{UserID: testUserID, RoleIDs: []snowflake.ID{testRoleA}},
+			channel: Channel{Overwrites: discord.PermissionOverwrites{
+				discord.RolePermissionOverwrite{RoleID: testGuildID, Deny: discord.PermissionAttachFiles},
+				discord.RolePermissionOverwrite{RoleID: testRoleA, Allow: discord.PermissionAttachFiles},
+			}},
+			want: textPermissions | discord.PermissionManageMessages,
+		},
+		{
+			name:     "role allow wins over deny of another role",
+			everyone: textPermissions,
+			member:   Member{UserID: testUserID, RoleIDs: []snowflake.ID{testRoleA, testRoleB}},
+			channel: Channel{Overwrites: discord.PermissionOverwrites{
+				discord.RolePermissionOverwrite{RoleID: testRoleA, Deny: discord.PermissionAttachFiles},
+				discord.RolePermissionOverwrite{RoleID: testRoleB, Allow: discord.PermissionAttachFiles},
+			}},
+			want: textPermissions | discord.PermissionManageMessages | discord.PermissionKickMembers,
+		},
+		{
+			name:     "overwrites of other roles are ignored",
+			everyone: textPermissions,
+			member:   Member{UserID: testUserID},
+			channel: Channel{Overwrites: discord.PermissionOverwrites{
+				discord.RolePermissionOverwrite{RoleID: testRoleA, Deny: discord.PermissionAttachFiles},
+			}},
+			want: textPermissions,
+		},
+		{
+			name:     "member overwrite overrides role overwrite",
+			everyone: textPermissions,
+			member:   Member{UserID: testUserID, RoleIDs: []snowflake.ID{testRoleA}},
+			channel: Channel{Overwrites: discord.PermissionOverwrites{
+				discord.RolePermissionOverwrite{RoleID: testRoleA, Allow: discord.PermissionMentionEveryone},
+				discord.MemberPermissionOverwrite{UserID: testUserID, Deny: discord.PermissionMentionEveryone},
+			}},
+			want: textPermissions | discord.PermissionManageMessages,
+		},
+		{
+			name:     "member allow overrides role deny",
+			everyone: textPermissions,
+			member:   Member{UserID: testUserID, RoleIDs: []snowflake.ID{testRoleA}},
+			channel: Channel{Overwrites: discord.PermissionOverwrites{
+				discord.RolePermissionOverwrite{RoleID: testRoleA, Deny: discord.PermissionEmbedLinks},
+				discord.MemberPermissionOverwrite{UserID: testUserID, Allow: discord.PermissionEmbedLinks},
+			}},
+			want: textPermissions | discord.PermissionManageMessages,
+		},
+		{
+			name:     "no view channel removes all permissions",
+			everyone: textPermissions,
+			member:   Member{UserID: testUserID, RoleIDs: []snowflake.ID{testRoleA}},
+			channel: Channel{Overwrites: discord.PermissionOverwrites{
+				discord.RolePermissionOverwrite{RoleID: testGuildID, Deny: discord.PermissionViewChannel},
+			}},
+			want: discord.PermissionsNone,
+		},
+		{
+			name:     "no send messages removes dependent permissions",
+			everyone: textPermissions | discord.PermissionMentionEveryone | discord.PermissionSendTTSMessages,
+			member:   Member{UserID: testUserID},
+			channel: Channel{Overwrites: discord.PermissionOverwrites{
+				discord.MemberPermissionOverwrite{UserID: testUserID, Deny: discord.PermissionSendMessages},
+			}},
+			want: discord.PermissionViewChannel | discord.PermissionReadMessageHistory,
+		},
+		{
+			name:     "threads require send messages in threads",
+			everyone: discord.PermissionViewChannel | discord.PermissionSendMessagesInThreads | discord.PermissionAttachFiles,
+			member:   Member{UserID: testUserID},
+			channel:  Channel{Thread: true},
+			want:     discord.PermissionViewChannel | discord.PermissionSendMessagesInThreads | discord.PermissionAttachFiles,
+		},
+		{
+			name:     "threads without send messages in threads",
+			everyone: textPermissions,
+			member:   Member{UserID: testUserID},
+			channel:  Channel{Thread: true},
+			want:     discord.PermissionViewChannel | discord.PermissionSendMessages | discord.PermissionReadMessageHistory,
+		},
+		{
+			name:     "owner ignores overwrites",
+			everyone: discord.PermissionsNone,
+			member:   Member{UserID: testOwnerID},
+			channel: Channel{Overwrites: discord.PermissionOverwrites{
+				discord.MemberPermissionOverwrite{UserID: testOwnerID, Deny: discord.PermissionViewChannel},
+			}},
+			want: discord.PermissionsAll,
+		},
+		{
+			name:     "administrator ignores overwrites",
+			everyone: textPermissions,
+			member:   Member{UserID: testUserID, RoleIDs: []snowflake.ID{testAdmin}},
+			channel: Channel{Overwrites: discord.PermissionOverwrites{
+				discord.RolePermissionOverwrite{RoleID: testAdmin, Deny: discord.PermissionViewChannel},
+			}},
+			want: discord.PermissionsAll,
+		},
+		{
+			name:     "timeout is applied after overwrites",
+			everyone: textPermissions,
+			member:   Member{UserID: testUserID, CommunicationDisabledUntil: timeoutUntil(time.Hour)},
+			channel: Channel{Overwrites: discord.PermissionOverwrites{
+				discord.MemberPermissionOverwrite{UserID: testUserID, Allow: discord.PermissionManageMessages},
+			}},
+			want: discord.PermissionViewChannel | discord.PermissionReadMessageHistory,
+		},
+		{
+			name:     "timed out member can't see hidden channels",
+			everyone: textPermissions,
+			member:   Member{UserID: testUserID, CommunicationDisabledUntil: timeoutUntil(time.Hour)},
+			channel: Channel{Overwrites: discord.PermissionOverwrites{
+				discord.RolePermissionOverwrite{RoleID: testGuildID, Deny: discord.PermissionViewChannel},
+			}},
+			want: discord.PermissionsNone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewCalculator(testGuild(tt.everyone), tt.member, testNow).Channel(tt.channel)
+			if got != tt.want {
+				t.Errorf("got permissions %d, want %d", got, tt.want)
+			}
+		})
+	}
+}